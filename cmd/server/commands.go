@@ -103,26 +103,59 @@ func createToken(c *cli.Context) error {
 		return err
 	}
 
+	apiKey, apiSecret, err := getAPIKeySecret(conf)
+	if err != nil {
+		return err
+	}
+
+	grant := &auth.VideoGrant{
+		RoomJoin: true,
+		Room:     room,
+	}
+	if c.Bool("recorder") {
+		grant.Hidden = true
+		grant.Recorder = true
+		grant.SetCanPublish(false)
+		grant.SetCanPublishData(false)
+	}
+
+	at := auth.NewAccessToken(apiKey, apiSecret).
+		AddGrant(grant).
+		SetIdentity(identity).
+		SetValidFor(30 * 24 * time.Hour)
+
+	token, err := at.ToJWT()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Token:", token)
+
+	return nil
+}
+
+// getAPIKeySecret returns the first API key from config, loading keys from the key file when needed
+func getAPIKeySecret(conf *config.Config) (string, string, error) {
 	// use the first API key from config
 	if len(conf.Keys) == 0 {
 		// try to load from file
 		if _, err := os.Stat(conf.KeyFile); err != nil {
-			return err
+			return "", "", err
 		}
 		f, err := os.Open(conf.KeyFile)
 		if err != nil {
-			return err
+			return "", "", err
 		}
 		defer func() {
 			_ = f.Close()
 		}()
 		decoder := yaml.NewDecoder(f)
 		if err = decoder.Decode(conf.Keys); err != nil {
-			return err
+			return "", "", err
 		}
 
 		if len(conf.Keys) == 0 {
-			return fmt.Errorf("keys are not configured")
+			return "", "", fmt.Errorf("keys are not configured")
 		}
 	}
 
@@ -134,30 +167,7 @@ func createToken(c *cli.Context) error {
 		break
 	}
 
-	grant := &auth.VideoGrant{
-		RoomJoin: true,
-		Room:     room,
-	}
-	if c.Bool("recorder") {
-		grant.Hidden = true
-		grant.Recorder = true
-		grant.SetCanPublish(false)
-		grant.SetCanPublishData(false)
-	}
-
-	at := auth.NewAccessToken(apiKey, apiSecret).
-		AddGrant(grant).
-		SetIdentity(identity).
-		SetValidFor(30 * 24 * time.Hour)
-
-	token, err := at.ToJWT()
-	if err != nil {
-		return err
-	}
-
-	fmt.Println("Token:", token)
-
-	return nil
+	return apiKey, apiSecret, nil
 }
 
 func listNodes(c *cli.Context) error {
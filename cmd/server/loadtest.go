@@ -0,0 +1,304 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pion/webrtc/v3"
+	"github.com/urfave/cli/v2"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+	testclient "github.com/livekit/livekit-server/test/client"
+)
+
+var loadTestFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "url",
+		Usage: "websocket URL of the server to test",
+		Value: "ws://localhost:7880",
+	},
+	&cli.StringFlag{
+		Name:     "room",
+		Usage:    "name of room to join",
+		Required: true,
+	},
+	&cli.IntFlag{
+		Name:  "publishers",
+		Usage: "number of participants publishing a video and an audio track",
+		Value: 1,
+	},
+	&cli.IntFlag{
+		Name:  "subscribers",
+		Usage: "number of participants subscribing to all published tracks",
+		Value: 5,
+	},
+	&cli.DurationFlag{
+		Name:  "duration",
+		Usage: "how long to run the test, stops early on interrupt",
+		Value: time.Minute,
+	},
+	&cli.IntFlag{
+		Name:  "video-bitrate",
+		Usage: "bitrate of generated video tracks in bps",
+		Value: 1_000_000,
+	},
+	&cli.IntFlag{
+		Name:  "audio-bitrate",
+		Usage: "bitrate of generated audio tracks in bps",
+		Value: 32_000,
+	},
+}
+
+type loadTestParticipant struct {
+	identity    string
+	isPublisher bool
+	client      *testclient.RTCClient
+	err         error
+}
+
+type loadTest struct {
+	lock      sync.Mutex
+	qualities map[livekit.ParticipantID]*livekit.ConnectionQualityInfo
+}
+
+func runLoadTest(c *cli.Context) error {
+	conf, err := getConfig(c)
+	if err != nil {
+		return err
+	}
+
+	apiKey, apiSecret, err := getAPIKeySecret(conf)
+	if err != nil {
+		return err
+	}
+
+	numPublishers := c.Int("publishers")
+	numSubscribers := c.Int("subscribers")
+	if numPublishers <= 0 && numSubscribers <= 0 {
+		return errors.New("at least one publisher or subscriber is required")
+	}
+
+	// test clients share transport code with the server, which records metrics
+	if err := prometheus.Init("loadtest", livekit.NodeType_SERVER); err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(c.Context, syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	lt := &loadTest{
+		qualities: make(map[livekit.ParticipantID]*livekit.ConnectionQualityInfo),
+	}
+
+	participants := make([]*loadTestParticipant, 0, numPublishers+numSubscribers)
+	for i := 0; i < numPublishers; i++ {
+		participants = append(participants, &loadTestParticipant{identity: fmt.Sprintf("loadtest_pub_%d", i), isPublisher: true})
+	}
+	for i := 0; i < numSubscribers; i++ {
+		participants = append(participants, &loadTestParticipant{identity: fmt.Sprintf("loadtest_sub_%d", i)})
+	}
+
+	fmt.Printf("connecting %d publishers and %d subscribers to room %s\n", numPublishers, numSubscribers, c.String("room"))
+	var wg sync.WaitGroup
+	for _, p := range participants {
+		wg.Add(1)
+		go func(p *loadTestParticipant) {
+			defer wg.Done()
+			p.client, p.err = lt.connect(c, p, apiKey, apiSecret)
+		}(p)
+	}
+	wg.Wait()
+
+	defer func() {
+		for _, p := range participants {
+			if p.client != nil {
+				p.client.Stop()
+			}
+		}
+	}()
+
+	for _, p := range participants {
+		if p.err != nil || !p.isPublisher {
+			continue
+		}
+		if _, err := p.client.AddSyntheticTrack(webrtc.MimeTypeVP8, "video", p.identity, c.Int("video-bitrate")); err != nil {
+			p.err = err
+			continue
+		}
+		if _, err := p.client.AddSyntheticTrack(webrtc.MimeTypeOpus, "audio", p.identity, c.Int("audio-bitrate")); err != nil {
+			p.err = err
+		}
+	}
+
+	duration := c.Duration("duration")
+	fmt.Printf("running for %s\n", duration)
+	startedAt := time.Now()
+	select {
+	case <-ctx.Done():
+	case <-time.After(duration):
+	}
+
+	lt.report(participants, time.Since(startedAt))
+	return nil
+}
+
+func (lt *loadTest) connect(c *cli.Context, p *loadTestParticipant, apiKey, apiSecret string) (*testclient.RTCClient, error) {
+	grant := &auth.VideoGrant{
+		RoomJoin: true,
+		Room:     c.String("room"),
+	}
+	if !p.isPublisher {
+		grant.SetCanPublish(false)
+	}
+	token, err := auth.NewAccessToken(apiKey, apiSecret).
+		AddGrant(grant).
+		SetIdentity(p.identity).
+		SetValidFor(c.Duration("duration") + time.Hour).
+		ToJWT()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &testclient.Options{
+		AutoSubscribe:             !p.isPublisher,
+		SignalResponseInterceptor: lt.onSignalResponse,
+	}
+	return connectTestClient(c.String("url"), token, opts)
+}
+
+func connectTestClient(url string, token string, opts *testclient.Options) (*testclient.RTCClient, error) {
+	ws, err := testclient.NewWebSocketConn(url, token, opts)
+	if err != nil {
+		return nil, err
+	}
+	client, err := testclient.NewRTCClient(ws, opts)
+	if err != nil {
+		return nil, err
+	}
+	go client.Run()
+
+	if err := client.WaitUntilConnected(); err != nil {
+		client.Stop()
+		return nil, err
+	}
+	return client, nil
+}
+
+func (lt *loadTest) onSignalResponse(msg *livekit.SignalResponse, next testclient.SignalResponseHandler) error {
+	if cq, ok := msg.Message.(*livekit.SignalResponse_ConnectionQuality); ok {
+		lt.lock.Lock()
+		for _, info := range cq.ConnectionQuality.Updates {
+			lt.qualities[livekit.ParticipantID(info.ParticipantSid)] = info
+		}
+		lt.lock.Unlock()
+	}
+	return next(msg)
+}
+
+func (lt *loadTest) report(participants []*loadTestParticipant, elapsed time.Duration) {
+	numConnected := 0
+	numPublishers := 0
+	numSubscribers := 0
+	for _, p := range participants {
+		if p.err != nil {
+			fmt.Printf("%s failed: %v\n", p.identity, p.err)
+			continue
+		}
+		numConnected++
+		if p.isPublisher {
+			numPublishers++
+		} else {
+			numSubscribers++
+		}
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Participant", "Tracks", "Received", "Bitrate", "Quality", "Score"})
+	table.SetColumnAlignment([]int{
+		tablewriter.ALIGN_LEFT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT,
+		tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_CENTER, tablewriter.ALIGN_RIGHT,
+	})
+
+	lt.lock.Lock()
+	defer lt.lock.Unlock()
+
+	var (
+		totalTracks   int
+		totalBytes    uint64
+		totalScore    float64
+		numScored     int
+		qualityCounts = make(map[livekit.ConnectionQuality]int)
+	)
+	for _, p := range participants {
+		if p.client == nil {
+			continue
+		}
+
+		tracks := 0
+		for _, pubTracks := range p.client.SubscribedTracks() {
+			tracks += len(pubTracks)
+		}
+		bytes := p.client.BytesReceived()
+		totalTracks += tracks
+		totalBytes += bytes
+
+		quality, score := "-", "-"
+		if info := lt.qualities[p.client.ID()]; info != nil {
+			quality = info.Quality.String()
+			score = fmt.Sprintf("%.2f", info.Score)
+			qualityCounts[info.Quality]++
+			totalScore += float64(info.Score)
+			numScored++
+		}
+
+		table.Append([]string{
+			p.identity,
+			fmt.Sprintf("%d", tracks),
+			humanize.Bytes(bytes),
+			humanize.SIWithDigits(float64(bytes*8)/elapsed.Seconds(), 2, "bps"),
+			quality,
+			score,
+		})
+	}
+	table.Render()
+
+	fmt.Printf("connected: %d/%d\n", numConnected, len(participants))
+	fmt.Printf("subscribed tracks: %d/%d\n", totalTracks, numPublishers*numSubscribers*2)
+	fmt.Printf("received: %s, %s\n", humanize.Bytes(totalBytes), humanize.SIWithDigits(float64(totalBytes*8)/elapsed.Seconds(), 2, "bps"))
+	for _, q := range []livekit.ConnectionQuality{
+		livekit.ConnectionQuality_EXCELLENT,
+		livekit.ConnectionQuality_GOOD,
+		livekit.ConnectionQuality_POOR,
+		livekit.ConnectionQuality_LOST,
+	} {
+		fmt.Printf("quality %s: %d\n", q, qualityCounts[q])
+	}
+	if numScored != 0 {
+		fmt.Printf("average score: %.2f\n", totalScore/float64(numScored))
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
+	"runtime/debug"
 	"runtime/pprof"
 	"syscall"
 	"time"
@@ -34,9 +35,13 @@ import (
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/utils"
 	"github.com/livekit/livekit-server/version"
 )
 
+// fraction of the cgroup memory limit used as the GC soft limit, leaving headroom for non-heap memory
+const cgroupMemoryLimitRatio = 0.9
+
 var baseFlags = []cli.Flag{
 	&cli.StringSliceFlag{
 		Name:  "bind",
@@ -173,6 +178,30 @@ func main() {
 				Usage:  "list all nodes",
 				Action: listNodes,
 			},
+			{
+				Name:   "load-test",
+				Usage:  "runs synthetic publishers and subscribers against a room and reports quality",
+				Action: runLoadTest,
+				Flags:  loadTestFlags,
+			},
+			{
+				Name:   "replay",
+				Usage:  "publishes RTP streams from a pcap or rtpdump capture into a room with their original timing",
+				Action: replayCapture,
+				Flags:  replayFlags,
+			},
+			{
+				Name:   "replay-signal",
+				Usage:  "joins a room and sends the requests of a signal recording with their original timing",
+				Action: replaySignal,
+				Flags:  replaySignalFlags,
+			},
+			{
+				Name:   "validate-config",
+				Usage:  "validates the config, optionally explains the effective config and checks reachability of redis, TURN and webhook endpoints",
+				Action: validateConfig,
+				Flags:  validateConfigFlags,
+			},
 			{
 				Name:   "help-verbose",
 				Usage:  "prints app help, including all generated configuration flags",
@@ -188,17 +217,7 @@ func main() {
 }
 
 func getConfig(c *cli.Context) (*config.Config, error) {
-	confString, err := getConfigString(c.String("config"), c.String("config-body"))
-	if err != nil {
-		return nil, err
-	}
-
-	strictMode := true
-	if c.Bool("disable-strict-config") {
-		strictMode = false
-	}
-
-	conf, err := config.NewConfig(confString, strictMode, c, baseFlags)
+	conf, placeholderKeys, err := loadConfig(c)
 	if err != nil {
 		return nil, err
 	}
@@ -207,39 +226,62 @@ func getConfig(c *cli.Context) (*config.Config, error) {
 	if conf.Development {
 		logger.Infow("starting in development mode")
 
-		if len(conf.Keys) == 0 {
+		if placeholderKeys {
 			logger.Infow("no keys provided, using placeholder keys",
 				"API Key", "devkey",
 				"API Secret", "secret",
 			)
-			conf.Keys = map[string]string{
-				"devkey": "secret",
+		}
+	}
+	return conf, nil
+}
+
+// loadConfig parses the config without initializing the logger, so that it can also be used to reload the config
+func loadConfig(c *cli.Context) (conf *config.Config, placeholderKeys bool, err error) {
+	confString, err := getConfigString(c.String("config"), c.String("config-body"))
+	if err != nil {
+		return nil, false, err
+	}
+
+	strictMode := true
+	if c.Bool("disable-strict-config") {
+		strictMode = false
+	}
+
+	conf, err = config.NewConfig(confString, strictMode, c, baseFlags)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if conf.Development && len(conf.Keys) == 0 {
+		placeholderKeys = true
+		conf.Keys = map[string]string{
+			"devkey": "secret",
+		}
+		shouldMatchRTCIP := false
+		// when dev mode and using shared keys, we'll bind to localhost by default
+		if conf.BindAddresses == nil {
+			conf.BindAddresses = []string{
+				"127.0.0.1",
+				"::1",
 			}
-			shouldMatchRTCIP := false
-			// when dev mode and using shared keys, we'll bind to localhost by default
-			if conf.BindAddresses == nil {
-				conf.BindAddresses = []string{
-					"127.0.0.1",
-					"::1",
-				}
-			} else {
-				// if non-loopback addresses are provided, then we'll match RTC IP to bind address
-				// our IP discovery ignores loopback addresses
-				for _, addr := range conf.BindAddresses {
-					ip := net.ParseIP(addr)
-					if ip != nil && !ip.IsLoopback() && !ip.IsUnspecified() {
-						shouldMatchRTCIP = true
-					}
+		} else {
+			// if non-loopback addresses are provided, then we'll match RTC IP to bind address
+			// our IP discovery ignores loopback addresses
+			for _, addr := range conf.BindAddresses {
+				ip := net.ParseIP(addr)
+				if ip != nil && !ip.IsLoopback() && !ip.IsUnspecified() {
+					shouldMatchRTCIP = true
 				}
 			}
-			if shouldMatchRTCIP {
-				for _, bindAddr := range conf.BindAddresses {
-					conf.RTC.IPs.Includes = append(conf.RTC.IPs.Includes, bindAddr+"/24")
-				}
+		}
+		if shouldMatchRTCIP {
+			for _, bindAddr := range conf.BindAddresses {
+				conf.RTC.IPs.Includes = append(conf.RTC.IPs.Includes, bindAddr+"/24")
 			}
 		}
 	}
-	return conf, nil
+	return conf, placeholderKeys, nil
 }
 
 func startServer(c *cli.Context) error {
@@ -254,6 +296,8 @@ func startServer(c *cli.Context) error {
 		return err
 	}
 
+	applyCgroupLimits()
+
 	if memProfile := c.String("memprofile"); memProfile != "" {
 		if f, err := os.Create(memProfile); err != nil {
 			return err
@@ -267,6 +311,10 @@ func startServer(c *cli.Context) error {
 		}
 	}
 
+	if err := service.LoadPluginFiles(conf.Plugins.Files); err != nil {
+		return err
+	}
+
 	currentNode, err := routing.NewLocalNode(conf)
 	if err != nil {
 		return err
@@ -293,9 +341,28 @@ func startServer(c *cli.Context) error {
 		}
 	}()
 
+	startConfigReloader(c, server)
+
 	return server.Start()
 }
 
+// applyCgroupLimits sizes the Go runtime to the container rather than the host,
+// unless GOMAXPROCS or GOMEMLIMIT have been set explicitly
+func applyCgroupLimits() {
+	limits := utils.GetCgroupLimits()
+	if limits.CPUs > 0 && os.Getenv("GOMAXPROCS") == "" {
+		if procs := utils.NumCPUCeil(); procs < runtime.GOMAXPROCS(0) {
+			runtime.GOMAXPROCS(procs)
+			logger.Infow("set GOMAXPROCS from cgroup CPU limit", "cpus", limits.CPUs, "gomaxprocs", procs)
+		}
+	}
+	if limits.Memory > 0 && os.Getenv("GOMEMLIMIT") == "" {
+		memLimit := int64(float64(limits.Memory) * cgroupMemoryLimitRatio)
+		debug.SetMemoryLimit(memLimit)
+		logger.Infow("set GC memory limit from cgroup memory limit", "memory", limits.Memory, "gomemlimit", memLimit)
+	}
+}
+
 func getConfigString(configFile string, inConfigBody string) (string, error) {
 	if inConfigBody != "" || configFile == "" {
 		return inConfigBody, nil
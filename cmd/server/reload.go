@@ -0,0 +1,135 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/urfave/cli/v2"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+// editors and config map updates write a file in several steps, wait for them to settle
+const configReloadDebounce = 500 * time.Millisecond
+
+// configReloader applies config changes on SIGHUP, and when the config file is changed.
+// TLS certificates watch their own files, SIGHUP reloads them as well
+type configReloader struct {
+	c      *cli.Context
+	server *service.LivekitServer
+
+	lock sync.Mutex
+}
+
+func startConfigReloader(c *cli.Context, server *service.LivekitServer) {
+	r := &configReloader{
+		c:      c,
+		server: server,
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+	go func() {
+		for range sigChan {
+			server.ReloadCertificates()
+			r.reload("SIGHUP")
+		}
+	}()
+
+	// a config body passed on the command line or through the environment can only be reloaded with SIGHUP
+	if configFile := c.String("config"); configFile != "" && c.String("config-body") == "" {
+		go r.watch(configFile)
+	}
+}
+
+func (r *configReloader) reload(reason string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	conf, _, err := loadConfig(r.c)
+	if err != nil {
+		logger.Errorw("could not load config, keeping current config", err, "reason", reason)
+		return
+	}
+
+	report, err := r.server.ReloadConfig(conf)
+	if err != nil {
+		logger.Errorw("could not reload config", err, "reason", reason)
+	}
+	if report == nil {
+		return
+	}
+	if !report.Changed() {
+		logger.Infow("config reloaded, nothing changed", "reason", reason)
+		return
+	}
+	logger.Infow("config reloaded", "reason", reason, "applied", report.Applied)
+	if len(report.RequiresRestart) > 0 {
+		logger.Warnw("config changes require a restart to take effect", nil, "keys", report.RequiresRestart)
+	}
+}
+
+// watch reloads when the contents of the config file change. The directory is watched,
+// as editors and kubernetes config maps replace the file instead of writing to it
+func (r *configReloader) watch(configFile string) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logger.Warnw("could not watch config file, reload with SIGHUP", err)
+		return
+	}
+	defer watcher.Close()
+
+	if err = watcher.Add(filepath.Dir(configFile)); err != nil {
+		logger.Warnw("could not watch config file, reload with SIGHUP", err, "file", configFile)
+		return
+	}
+
+	contents, _ := os.ReadFile(configFile)
+	var debounce <-chan time.Time
+	for {
+		select {
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			debounce = time.After(configReloadDebounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Warnw("config file watcher error", err)
+
+		case <-debounce:
+			debounce = nil
+			updated, err := os.ReadFile(configFile)
+			if err != nil || bytes.Equal(updated, contents) {
+				continue
+			}
+			contents = updated
+			r.reload("file changed")
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/urfave/cli/v2"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+	testclient "github.com/livekit/livekit-server/test/client"
+)
+
+var replayFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "url",
+		Usage: "websocket URL of the server",
+		Value: "ws://localhost:7880",
+	},
+	&cli.StringFlag{
+		Name:     "room",
+		Usage:    "name of room to publish into",
+		Required: true,
+	},
+	&cli.StringFlag{
+		Name:  "identity",
+		Usage: "identity of the publishing participant",
+		Value: "replay",
+	},
+	&cli.StringFlag{
+		Name:     "file",
+		Usage:    "pcap or rtpdump capture to replay",
+		Required: true,
+	},
+	&cli.StringSliceFlag{
+		Name:  "stream",
+		Usage: "stream to publish as <ssrc>:<mime type>, e.g. 0x1234abcd:video/VP8, lists streams in the capture when not set",
+	},
+}
+
+type replayStream struct {
+	mime    string
+	track   *webrtc.TrackLocalStaticRTP
+	packets int
+}
+
+func replayCapture(c *cli.Context) error {
+	packets, err := testclient.ReadRTPCapture(c.String("file"))
+	if err != nil {
+		return err
+	}
+	if len(packets) == 0 {
+		return errors.New("no RTP packets in capture")
+	}
+
+	if len(c.StringSlice("stream")) == 0 {
+		printCaptureStreams(packets)
+		return errors.New("select streams to replay with --stream")
+	}
+
+	streams := make(map[uint32]*replayStream)
+	for _, s := range c.StringSlice("stream") {
+		ssrcStr, mime, ok := strings.Cut(s, ":")
+		if !ok {
+			return fmt.Errorf("invalid stream %q, expected <ssrc>:<mime type>", s)
+		}
+		ssrc, err := strconv.ParseUint(ssrcStr, 0, 32)
+		if err != nil {
+			return fmt.Errorf("invalid stream SSRC %q: %w", ssrcStr, err)
+		}
+		streams[uint32(ssrc)] = &replayStream{mime: mime}
+	}
+
+	conf, err := getConfig(c)
+	if err != nil {
+		return err
+	}
+	apiKey, apiSecret, err := getAPIKeySecret(conf)
+	if err != nil {
+		return err
+	}
+
+	// test clients share transport code with the server, which records metrics
+	if err := prometheus.Init("replay", livekit.NodeType_SERVER); err != nil {
+		return err
+	}
+
+	identity := c.String("identity")
+	token, err := auth.NewAccessToken(apiKey, apiSecret).
+		AddGrant(&auth.VideoGrant{RoomJoin: true, Room: c.String("room")}).
+		SetIdentity(identity).
+		SetValidFor(packets[len(packets)-1].Offset + time.Hour).
+		ToJWT()
+	if err != nil {
+		return err
+	}
+
+	client, err := connectTestClient(c.String("url"), token, &testclient.Options{})
+	if err != nil {
+		return err
+	}
+	defer client.Stop()
+
+	for ssrc, stream := range streams {
+		stream.track, err = client.AddRTPTrack(stream.mime, fmt.Sprintf("replay_%d", ssrc), identity)
+		if err != nil {
+			return err
+		}
+	}
+	if err := client.WaitUntilPublisherConnected(); err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(c.Context, syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	fmt.Printf("replaying %s of %s into room %s\n", packets[len(packets)-1].Offset, c.String("file"), c.String("room"))
+	startedAt := time.Now()
+	for _, p := range packets {
+		stream := streams[p.Packet.SSRC]
+		if stream == nil {
+			continue
+		}
+
+		// keep the original timing of the capture
+		if wait := time.Until(startedAt.Add(p.Offset)); wait > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(wait):
+			}
+		}
+
+		if err := stream.track.WriteRTP(p.Packet); err != nil {
+			return err
+		}
+		stream.packets++
+	}
+
+	for ssrc, stream := range streams {
+		fmt.Printf("SSRC 0x%08x (%s): %d packets\n", ssrc, stream.mime, stream.packets)
+	}
+	return nil
+}
+
+func printCaptureStreams(packets []*testclient.CapturedRTPPacket) {
+	type captureStream struct {
+		ssrc         uint32
+		payloadTypes map[uint8]bool
+		packets      int
+	}
+	streams := make(map[uint32]*captureStream)
+	for _, p := range packets {
+		s := streams[p.Packet.SSRC]
+		if s == nil {
+			s = &captureStream{ssrc: p.Packet.SSRC, payloadTypes: make(map[uint8]bool)}
+			streams[p.Packet.SSRC] = s
+		}
+		s.payloadTypes[p.Packet.PayloadType] = true
+		s.packets++
+	}
+
+	sorted := make([]*captureStream, 0, len(streams))
+	for _, s := range streams {
+		sorted = append(sorted, s)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].packets > sorted[j].packets
+	})
+
+	fmt.Fprintln(os.Stderr, "RTP streams in capture:")
+	for _, s := range sorted {
+		payloadTypes := make([]string, 0, len(s.payloadTypes))
+		for pt := range s.payloadTypes {
+			payloadTypes = append(payloadTypes, strconv.Itoa(int(pt)))
+		}
+		sort.Strings(payloadTypes)
+		fmt.Fprintf(os.Stderr, "  SSRC 0x%08x, payload types %s, %d packets\n", s.ssrc, strings.Join(payloadTypes, ","), s.packets)
+	}
+}
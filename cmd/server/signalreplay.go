@@ -0,0 +1,214 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/olekukonko/tablewriter"
+	"github.com/urfave/cli/v2"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+	testclient "github.com/livekit/livekit-server/test/client"
+)
+
+var replaySignalFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "url",
+		Usage: "websocket URL of the server",
+		Value: "ws://localhost:7880",
+	},
+	&cli.StringFlag{
+		Name:     "file",
+		Usage:    "signal recording to replay, see signal_recording in the config",
+		Required: true,
+	},
+	&cli.StringFlag{
+		Name:  "room",
+		Usage: "room to join, defaults to the recorded room",
+	},
+	&cli.StringFlag{
+		Name:  "identity",
+		Usage: "identity to join as, defaults to the recorded identity",
+	},
+	&cli.Float64Flag{
+		Name:  "speed",
+		Usage: "playback speed relative to the recording, 0 sends requests without waiting",
+		Value: 1,
+	},
+	&cli.DurationFlag{
+		Name:  "linger",
+		Usage: "time to wait for responses after the last request",
+		Value: 2 * time.Second,
+	},
+}
+
+// replaySignal joins a room and sends the requests of a signal recording with their original timing, printing the
+// responses the server sends back. Media does not flow as the recorded SDP belongs to the original client,
+// but the server goes through the same signalling state transitions.
+func replaySignal(c *cli.Context) error {
+	header, entries, err := routing.ReadSignalRecording(c.String("file"))
+	if err != nil {
+		return err
+	}
+	room := string(header.Room)
+	if c.IsSet("room") {
+		room = c.String("room")
+	}
+	identity := string(header.Identity)
+	if c.IsSet("identity") {
+		identity = c.String("identity")
+	}
+	if header.Reconnect {
+		fmt.Println("recording is of a resumed session, replaying it as a new join")
+	}
+
+	conf, err := getConfig(c)
+	if err != nil {
+		return err
+	}
+	apiKey, apiSecret, err := getAPIKeySecret(conf)
+	if err != nil {
+		return err
+	}
+	token, err := auth.NewAccessToken(apiKey, apiSecret).
+		AddGrant(&auth.VideoGrant{RoomJoin: true, Room: room}).
+		SetIdentity(identity).
+		SetValidFor(time.Hour).
+		ToJWT()
+	if err != nil {
+		return err
+	}
+
+	protocol := header.Protocol
+	if protocol == 0 {
+		protocol = 1
+	}
+	connectURL := fmt.Sprintf("%s/rtc?protocol=%d&auto_subscribe=%t", c.String("url"), protocol, header.AutoSubscribe)
+	requestHeader := make(http.Header)
+	testclient.SetAuthorizationToken(requestHeader, token)
+	conn, _, err := websocket.DefaultDialer.Dial(connectURL, requestHeader)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	recorded := make(map[string]int)
+	replayed := make(map[string]int)
+	var lock sync.Mutex
+
+	go func() {
+		for {
+			_, payload, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			res := &livekit.SignalResponse{}
+			if err := proto.Unmarshal(payload, res); err != nil {
+				continue
+			}
+			msgType := signalMessageType(res)
+			fmt.Printf("%8.3fs <- %s\n", time.Since(start).Seconds(), msgType)
+			lock.Lock()
+			replayed[msgType]++
+			lock.Unlock()
+		}
+	}()
+
+	ctx, cancel := signal.NotifyContext(c.Context, syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	speed := c.Float64("speed")
+	for _, entry := range entries {
+		if entry.Response != nil {
+			recorded[signalMessageType(entry.Response)]++
+			continue
+		}
+
+		if speed > 0 {
+			at := time.Duration(float64(entry.Offset) / speed)
+			select {
+			case <-time.After(time.Until(start.Add(at))):
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		payload, err := proto.Marshal(entry.Request)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%8.3fs -> %s\n", time.Since(start).Seconds(), signalMessageType(entry.Request))
+		if err := conn.WriteMessage(websocket.BinaryMessage, payload); err != nil {
+			return err
+		}
+	}
+
+	select {
+	case <-time.After(c.Duration("linger")):
+	case <-ctx.Done():
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	printSignalResponseCounts(recorded, replayed)
+	return nil
+}
+
+func signalMessageType(msg proto.Message) string {
+	m := msg.ProtoReflect()
+	oneof := m.Descriptor().Oneofs().ByName("message")
+	if oneof == nil {
+		return "unknown"
+	}
+	if field := m.WhichOneof(oneof); field != nil {
+		return string(field.Name())
+	}
+	return "empty"
+}
+
+func printSignalResponseCounts(recorded map[string]int, replayed map[string]int) {
+	types := make(map[string]struct{})
+	for t := range recorded {
+		types[t] = struct{}{}
+	}
+	for t := range replayed {
+		types[t] = struct{}{}
+	}
+	sorted := make([]string, 0, len(types))
+	for t := range types {
+		sorted = append(sorted, t)
+	}
+	sort.Strings(sorted)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Response", "Recorded", "Replayed"})
+	for _, t := range sorted {
+		table.Append([]string{t, fmt.Sprint(recorded[t]), fmt.Sprint(replayed[t])})
+	}
+	table.Render()
+}
@@ -0,0 +1,293 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/pion/stun"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/exp/maps"
+
+	"github.com/livekit/protocol/redis"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+var validateConfigFlags = []cli.Flag{
+	&cli.BoolFlag{
+		Name:  "explain",
+		Usage: "print the effective config after defaults, environment variables and flags are applied. secrets are redacted",
+	},
+	&cli.BoolFlag{
+		Name:  "offline",
+		Usage: "skip reachability checks of redis, TURN servers and webhook endpoints",
+	},
+	&cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "timeout of each reachability check",
+		Value: 5 * time.Second,
+	},
+}
+
+var errInvalidConfig = errors.New("config is invalid")
+
+type endpointCheck struct {
+	kind    string
+	address string
+	check   func(timeout time.Duration) error
+}
+
+func validateConfig(c *cli.Context) error {
+	confString, err := getConfigString(c.String("config"), c.String("config-body"))
+	if err != nil {
+		return err
+	}
+	if confString == "" {
+		fmt.Println("no config given with --config or --config-body, validating defaults")
+	}
+
+	issues, err := config.LintConfig(confString)
+	if err != nil {
+		return err
+	}
+
+	// unknown keys are reported as issues, parse leniently so that the remaining settings can be checked
+	conf, err := config.NewConfig(confString, false, c, baseFlags)
+	if err != nil {
+		issues = append(issues, &config.ConfigIssue{Severity: config.IssueError, Message: err.Error()})
+	} else if err = conf.ValidateKeys(); err != nil {
+		issues = append(issues, &config.ConfigIssue{Severity: config.IssueError, Key: "keys", Message: err.Error()})
+	}
+
+	valid := true
+	if len(issues) == 0 {
+		fmt.Println("no issues found")
+	} else {
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Severity", "Line", "Key", "Issue"})
+		for _, issue := range issues {
+			line := ""
+			if issue.Line > 0 {
+				line = strconv.Itoa(issue.Line)
+			}
+			table.Append([]string{issue.Severity, line, issue.Key, issue.Message})
+			valid = valid && issue.Severity != config.IssueError
+		}
+		table.Render()
+	}
+	if conf == nil {
+		return cli.Exit(errInvalidConfig, 1)
+	}
+
+	if c.Bool("explain") {
+		if err := explainConfig(c, conf); err != nil {
+			return err
+		}
+	}
+
+	if !c.Bool("offline") {
+		valid = checkEndpoints(conf, c.Duration("timeout")) && valid
+	}
+
+	if !valid {
+		return cli.Exit(errInvalidConfig, 1)
+	}
+	return nil
+}
+
+func explainConfig(c *cli.Context, conf *config.Config) error {
+	var overrides [][]string
+	for _, flag := range c.App.Flags {
+		name := flag.Names()[0]
+		if !c.IsSet(name) || name == "config" || name == "config-body" {
+			continue
+		}
+		source := "--" + name
+		if envFlag, ok := flag.(cli.DocGenerationFlag); ok {
+			for _, envVar := range envFlag.GetEnvVars() {
+				if _, ok := os.LookupEnv(envVar); ok {
+					source = "$" + envVar
+				}
+			}
+		}
+		overrides = append(overrides, []string{name, source})
+	}
+	envKeys := config.EnvKeys(os.Environ())
+	envVars := maps.Keys(envKeys)
+	slices.Sort(envVars)
+	for _, envVar := range envVars {
+		row := []string{envKeys[envVar], "$" + envVar}
+		if !slices.ContainsFunc(overrides, func(o []string) bool { return slices.Equal(o, row) }) {
+			overrides = append(overrides, row)
+		}
+	}
+	if len(overrides) > 0 {
+		fmt.Println("\nOverrides")
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Setting", "Source"})
+		table.AppendBulk(overrides)
+		table.Render()
+	}
+
+	out, err := conf.RedactedYAML()
+	if err != nil {
+		return err
+	}
+	fmt.Println("\nEffective config")
+	fmt.Println(string(out))
+	return nil
+}
+
+func checkEndpoints(conf *config.Config, timeout time.Duration) bool {
+	var checks []endpointCheck
+	if conf.Redis.IsConfigured() {
+		address := conf.Redis.Address
+		if address == "" {
+			address = fmt.Sprint(conf.Redis.SentinelAddresses)
+		}
+		checks = append(checks, endpointCheck{kind: "redis", address: address, check: func(timeout time.Duration) error {
+			return checkRedis(&conf.Redis, timeout)
+		}})
+	}
+	for _, s := range conf.RTC.TURNServers {
+		checks = append(checks, endpointCheck{
+			kind:    "turn/" + s.Protocol,
+			address: net.JoinHostPort(s.Host, strconv.Itoa(s.Port)),
+			check: func(timeout time.Duration) error {
+				return checkTURNServer(s, timeout)
+			},
+		})
+	}
+	if conf.TURN.Enabled && conf.TURN.TLSPort > 0 && !conf.TURN.ExternalTLS && !conf.UsesTURNACME() {
+		checks = append(checks, endpointCheck{kind: "turn/cert", address: conf.TURN.CertFile, check: func(_ time.Duration) error {
+			_, err := tls.LoadX509KeyPair(conf.TURN.CertFile, conf.TURN.KeyFile)
+			return err
+		}})
+	}
+	for _, url := range conf.WebHook.URLs {
+		checks = append(checks, endpointCheck{kind: "webhook", address: url, check: func(timeout time.Duration) error {
+			return checkWebhook(url, timeout)
+		}})
+	}
+	if len(checks) == 0 {
+		return true
+	}
+
+	ok := true
+	fmt.Println("\nEndpoints")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Endpoint", "Address", "Result"})
+	for _, ec := range checks {
+		result := "ok"
+		if err := ec.check(timeout); err != nil {
+			result = err.Error()
+			ok = false
+		}
+		table.Append([]string{ec.kind, ec.address, result})
+	}
+	table.Render()
+	return ok
+}
+
+func checkRedis(conf *redis.RedisConfig, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		rc, err := redis.GetRedisClient(conf)
+		if err == nil {
+			_ = rc.Close()
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return errors.New("timed out")
+	}
+}
+
+// checkTURNServer connects to TCP and TLS servers, and sends a STUN binding request to UDP servers
+func checkTURNServer(s config.TURNServer, timeout time.Duration) error {
+	address := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	switch s.Protocol {
+	case "tls":
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", address, &tls.Config{ServerName: s.Host})
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+
+	case "udp":
+		conn, err := net.DialTimeout("udp", address, timeout)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		req, err := stun.Build(stun.TransactionID, stun.BindingRequest)
+		if err != nil {
+			return err
+		}
+		if _, err = conn.Write(req.Raw); err != nil {
+			return err
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(timeout))
+		buf := make([]byte, 1500)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return err
+		}
+		res := &stun.Message{Raw: buf[:n]}
+		if err = res.Decode(); err != nil {
+			return err
+		}
+		if res.TransactionID != req.TransactionID {
+			return errors.New("unexpected STUN response")
+		}
+		return nil
+
+	default:
+		conn, err := net.DialTimeout("tcp", address, timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// checkWebhook only checks that the endpoint responds, receivers usually reject requests that are not signed events
+func checkWebhook(url string, timeout time.Duration) error {
+	client := &http.Client{Timeout: timeout}
+	res, err := client.Head(url)
+	if err != nil {
+		return err
+	}
+	_ = res.Body.Close()
+	if res.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("responded with %s", res.Status)
+	}
+	return nil
+}
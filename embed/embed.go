@@ -0,0 +1,222 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package embed runs a single-node LiveKit server inside a Go application, without exec-ing the
+// livekit-server binary. Embedded servers keep rooms and participants in memory, Redis is not supported.
+//
+//	conf, _ := embed.DefaultConfig()
+//	conf.Keys = map[string]string{"key": "secret"}
+//	server, _ := embed.New(conf, embed.Hooks{})
+//	_ = server.Start(ctx)
+//	defer server.Stop(false)
+package embed
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+const startPollInterval = 10 * time.Millisecond
+
+var (
+	ErrRedisNotSupported  = errors.New("embedded servers use an in-memory store, redis cannot be configured")
+	ErrAlreadyStarted     = errors.New("server has already been started")
+	ErrStoppedBeforeStart = errors.New("server stopped before it started")
+)
+
+// Hooks are called as the server moves through its lifecycle, any of them may be nil
+type Hooks struct {
+	// OnStarted is called once the server is accepting connections
+	OnStarted func(s *Server)
+	// OnStopped is called after the server has shut down, with the error it stopped with, if any.
+	// Stop returns once it has completed
+	OnStopped func(err error)
+}
+
+// Server is a LiveKit server running in-process
+type Server struct {
+	hooks  Hooks
+	server *service.LivekitServer
+
+	lock    sync.Mutex
+	started bool
+	done    chan struct{}
+	err     error
+}
+
+// DefaultConfig returns the default server config, which can then be changed programmatically before calling New.
+// Like the binary, LIVEKIT_* environment variables are applied on top of the defaults
+func DefaultConfig() (*config.Config, error) {
+	return config.NewConfig("", true, nil, nil)
+}
+
+// New creates a server from conf, it does not listen until Start is called.
+// The logger is left untouched, use config.InitLoggerFromConfig or config.SetLogger to configure it
+func New(conf *config.Config, hooks Hooks) (*Server, error) {
+	if conf.Redis.IsConfigured() {
+		return nil, ErrRedisNotSupported
+	}
+	if err := conf.ValidateKeys(); err != nil {
+		return nil, err
+	}
+	if err := service.LoadPluginFiles(conf.Plugins.Files); err != nil {
+		return nil, err
+	}
+
+	currentNode, err := routing.NewLocalNode(conf)
+	if err != nil {
+		return nil, err
+	}
+	if err := prometheus.Init(currentNode.Id, currentNode.Type); err != nil {
+		return nil, err
+	}
+
+	server, err := service.InitializeServer(conf, currentNode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		hooks:  hooks,
+		server: server,
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// Start runs the server in the background, returning once it is accepting connections.
+// If ctx is done first, the server is stopped and the context error returned
+func (s *Server) Start(ctx context.Context) error {
+	s.lock.Lock()
+	if s.started {
+		s.lock.Unlock()
+		return ErrAlreadyStarted
+	}
+	s.started = true
+	s.lock.Unlock()
+
+	go func() {
+		err := s.server.Start()
+		s.lock.Lock()
+		s.err = err
+		s.lock.Unlock()
+
+		if s.hooks.OnStopped != nil {
+			s.hooks.OnStopped(err)
+		}
+		close(s.done)
+	}()
+
+	ticker := time.NewTicker(startPollInterval)
+	defer ticker.Stop()
+	for !s.server.IsRunning() {
+		select {
+		case <-ctx.Done():
+			// starting cannot be interrupted, so stop the server as soon as it is up
+			go func() {
+				if s.waitForStart() {
+					s.server.Stop(true)
+				}
+			}()
+			return ctx.Err()
+		case <-s.done:
+			if err := s.Err(); err != nil {
+				return err
+			}
+			return ErrStoppedBeforeStart
+		case <-ticker.C:
+		}
+	}
+
+	if s.hooks.OnStarted != nil {
+		s.hooks.OnStarted(s)
+	}
+	return nil
+}
+
+// Stop shuts the server down and waits for it to exit. Unless force is set, it first waits for
+// participants to leave
+func (s *Server) Stop(force bool) error {
+	s.lock.Lock()
+	started := s.started
+	s.lock.Unlock()
+	if !started {
+		return nil
+	}
+
+	if s.waitForStart() {
+		s.server.Stop(force)
+	}
+	<-s.done
+	return s.Err()
+}
+
+// waitForStart returns true once the server is running, or false if it exited instead
+func (s *Server) waitForStart() bool {
+	ticker := time.NewTicker(startPollInterval)
+	defer ticker.Stop()
+	for !s.server.IsRunning() {
+		select {
+		case <-s.done:
+			return false
+		case <-ticker.C:
+		}
+	}
+	return true
+}
+
+// Done is closed once the server has shut down
+func (s *Server) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the error the server stopped with, if any
+func (s *Server) Err() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.err
+}
+
+// IsRunning is true while the server is accepting connections
+func (s *Server) IsRunning() bool {
+	return s.server.IsRunning()
+}
+
+// Node returns this server's node, including its ID and latest stats
+func (s *Server) Node() *livekit.Node {
+	return s.server.Node()
+}
+
+// HTTPPort returns the port the API and signalling are served on
+func (s *Server) HTTPPort() int {
+	return s.server.HTTPPort()
+}
+
+// Drain stops new rooms from being placed on the server, existing rooms are unaffected
+func (s *Server) Drain() {
+	s.server.Drain()
+}
+
+// RoomManager gives access to the rooms hosted by the server
+func (s *Server) RoomManager() *service.RoomManager {
+	return s.server.RoomManager()
+}
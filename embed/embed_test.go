@@ -0,0 +1,85 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package embed
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/mediatransportutil/pkg/rtcconfig"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func testConfig(t *testing.T) *config.Config {
+	conf, err := DefaultConfig()
+	require.NoError(t, err)
+	conf.Keys = map[string]string{"embedkey": "embedsecretembedsecretembedsecret"}
+	conf.BindAddresses = []string{"127.0.0.1"}
+	conf.Port = 7990
+	conf.RTC.UDPPort = rtcconfig.PortRange{Start: 7991}
+	conf.RTC.TCPPort = 7992
+	return conf
+}
+
+func TestServer(t *testing.T) {
+	t.Run("rejects redis", func(t *testing.T) {
+		conf := testConfig(t)
+		conf.Redis.Address = "localhost:6379"
+		_, err := New(conf, Hooks{})
+		require.ErrorIs(t, err, ErrRedisNotSupported)
+	})
+
+	t.Run("start and stop", func(t *testing.T) {
+		started := make(chan struct{})
+		stopped := make(chan error, 1)
+		s, err := New(testConfig(t), Hooks{
+			OnStarted: func(*Server) { close(started) },
+			OnStopped: func(err error) { stopped <- err },
+		})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		require.NoError(t, s.Start(ctx))
+		require.True(t, s.IsRunning())
+		require.ErrorIs(t, s.Start(ctx), ErrAlreadyStarted)
+
+		select {
+		case <-started:
+		default:
+			t.Fatal("OnStarted was not called")
+		}
+
+		res, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d", s.HTTPPort()))
+		require.NoError(t, err)
+		_ = res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		require.NoError(t, s.Stop(false))
+		require.False(t, s.IsRunning())
+		select {
+		case err := <-stopped:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("OnStopped was not called")
+		}
+	})
+}
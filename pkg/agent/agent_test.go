@@ -2,19 +2,27 @@ package agent_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
 
 	"github.com/livekit/livekit-server/pkg/agent"
 	"github.com/livekit/livekit-server/pkg/agent/testutil"
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/rpc"
 	"github.com/livekit/protocol/utils/guid"
 	"github.com/livekit/protocol/utils/must"
+	"github.com/livekit/protocol/webhook"
 	"github.com/livekit/psrpc"
 )
 
@@ -34,7 +42,7 @@ func TestAgent(t *testing.T) {
 			Id:         guid.New(guid.AgentJobPrefix),
 			DispatchId: guid.New(guid.AgentDispatchPrefix),
 			Type:       livekit.JobType_JT_ROOM,
-			Room:       &livekit.Room{},
+			Room:       &livekit.Room{Name: "room"},
 			Namespace:  "test",
 		}
 		_, err := client.JobRequest(context.Background(), "test", agent.RoomAgentTopic, job)
@@ -43,6 +51,16 @@ func TestAgent(t *testing.T) {
 		select {
 		case a := <-jobAssignments.Events():
 			require.EqualValues(t, job.Id, a.Job.Id)
+
+			// the agent joins with a token scoped to the job's room
+			v, err := auth.ParseAPIToken(a.Token)
+			require.NoError(t, err)
+			grants, err := v.Verify("verysecretsecret")
+			require.NoError(t, err)
+			require.Equal(t, livekit.ParticipantInfo_AGENT, grants.GetParticipantKind())
+			require.Equal(t, "room", grants.Video.Room)
+			require.True(t, grants.Video.RoomJoin)
+			require.False(t, grants.Video.RoomAdmin)
 		case <-time.After(time.Second):
 			require.Fail(t, "job assignment timeout")
 		}
@@ -186,3 +204,407 @@ func TestAgentLoadBalancing(t *testing.T) {
 		require.Equal(t, 15, assignedJobs)
 	})
 }
+
+func TestAgentJobPriority(t *testing.T) {
+	bus := psrpc.NewLocalMessageBus()
+
+	client := must.Get(rpc.NewAgentInternalClient(bus))
+	server := testutil.NewTestServerWithConfig(bus, &config.Config{
+		Region: "test",
+		Agents: config.AgentsConfig{
+			JobPriorities: []config.AgentJobPriorityConfig{
+				{RoomPrefix: "compliance-", Priority: 10},
+			},
+		},
+	})
+	t.Cleanup(server.Close)
+
+	worker := server.SimulateAgentWorker()
+	worker.Register("", "test", livekit.JobType_JT_ROOM)
+	jobAssignments := worker.JobAssignments.Observe()
+	jobTerminations := worker.JobTerminations.Observe()
+
+	newJob := func(room string) *livekit.Job {
+		return &livekit.Job{
+			Id:         guid.New(guid.AgentJobPrefix),
+			DispatchId: guid.New(guid.AgentDispatchPrefix),
+			Type:       livekit.JobType_JT_ROOM,
+			Room:       &livekit.Room{Name: room},
+			Namespace:  "test",
+		}
+	}
+
+	lowJob := newJob("lobby")
+	_, err := client.JobRequest(context.Background(), "test", agent.RoomAgentTopic, lowJob)
+	require.NoError(t, err)
+	<-jobAssignments.Events()
+
+	full := livekit.WorkerStatus_WS_FULL
+	worker.SendUpdateWorker(&livekit.UpdateWorkerStatus{Status: &full, Load: 1})
+	require.Eventually(t, func() bool {
+		return server.Workers()[0].Status() == livekit.WorkerStatus_WS_FULL
+	}, time.Second, 10*time.Millisecond)
+
+	// jobs of the same priority are not assigned when at capacity
+	_, err = client.JobRequest(context.Background(), "test", agent.RoomAgentTopic, newJob("lobby"))
+	require.Error(t, err)
+
+	highJob := newJob("compliance-1")
+	_, err = client.JobRequest(context.Background(), "test", agent.RoomAgentTopic, highJob)
+	require.NoError(t, err)
+
+	select {
+	case termination := <-jobTerminations.Events():
+		require.Equal(t, lowJob.Id, termination.JobId)
+	case <-time.After(time.Second):
+		require.Fail(t, "job termination timeout")
+	}
+	select {
+	case a := <-jobAssignments.Events():
+		require.Equal(t, highJob.Id, a.Job.Id)
+	case <-time.After(time.Second):
+		require.Fail(t, "job assignment timeout")
+	}
+
+	// the preempted job is assigned again once the worker has capacity
+	available := livekit.WorkerStatus_WS_AVAILABLE
+	worker.SendUpdateWorker(&livekit.UpdateWorkerStatus{Status: &available, Load: 0.5})
+	select {
+	case a := <-jobAssignments.Events():
+		require.Equal(t, lowJob.Id, a.Job.Id)
+	case <-time.After(time.Second):
+		require.Fail(t, "requeued job assignment timeout")
+	}
+}
+
+func TestAgentVersions(t *testing.T) {
+	t.Run("agent names are parsed into versions", func(t *testing.T) {
+		name, versions, err := agent.ParseAgentName("transcriber")
+		require.NoError(t, err)
+		require.Equal(t, "transcriber", name)
+		require.Empty(t, versions)
+		require.True(t, versions.Matches("v1"))
+
+		name, versions, err = agent.ParseAgentName("transcriber@v2")
+		require.NoError(t, err)
+		require.Equal(t, "transcriber", name)
+		require.Equal(t, agent.VersionSelector{{Version: "v2"}}, versions)
+		require.False(t, versions.Matches("v1"))
+
+		name, versions, err = agent.ParseAgentName("transcriber@v2:10,v1:90")
+		require.NoError(t, err)
+		require.Equal(t, "transcriber", name)
+		require.Equal(t, agent.VersionSelector{{Version: "v2", Weight: 10}, {Version: "v1", Weight: 90}}, versions)
+		require.Len(t, versions.Pick(), 1)
+
+		for _, invalid := range []string{"transcriber@", "transcriber@v2,v1", "transcriber@v2:0", "transcriber@v2:10,v2:90", "transcriber@v2:x"} {
+			_, _, err = agent.ParseAgentName(invalid)
+			require.ErrorIs(t, err, agent.ErrInvalidAgentVersions, invalid)
+		}
+	})
+
+	// workers are named after their version, jobs are counted by the worker they were assigned to
+	setup := func(t *testing.T) (rpc.AgentInternalClient, chan *livekit.Job) {
+		bus := psrpc.NewLocalMessageBus()
+
+		client := must.Get(rpc.NewAgentInternalClient(bus))
+		server := testutil.NewTestServer(bus)
+		t.Cleanup(server.Close)
+
+		jobAssignments := make(chan *livekit.Job, 100)
+		for _, version := range []string{"v1", "v2"} {
+			worker := server.SimulateAgentWorker(testutil.WithJobLoad(testutil.NewStableJobLoad(0.01)))
+			worker.RegisterVersion(version, "test", version, livekit.JobType_JT_ROOM)
+			go func() {
+				for a := range worker.JobAssignments.Observe().Events() {
+					jobAssignments <- a.Job
+				}
+			}()
+		}
+		return client, jobAssignments
+	}
+
+	requestJobs := func(t *testing.T, client rpc.AgentInternalClient, jobAssignments chan *livekit.Job, agentName string, count int) map[string]int {
+		for i := 0; i < count; i++ {
+			job := &livekit.Job{
+				Id:         guid.New(guid.AgentJobPrefix),
+				DispatchId: guid.New(guid.AgentDispatchPrefix),
+				Type:       livekit.JobType_JT_ROOM,
+				Room:       &livekit.Room{},
+				Namespace:  "test",
+				AgentName:  agentName,
+			}
+			_, err := client.JobRequest(context.Background(), "test", agent.RoomAgentTopic, job)
+			require.NoError(t, err)
+		}
+
+		jobCount := make(map[string]int)
+		for i := 0; i < count; i++ {
+			select {
+			case job := <-jobAssignments:
+				jobCount[job.AgentName]++
+			case <-time.After(time.Second):
+				require.Fail(t, "job assignment timeout")
+			}
+		}
+		return jobCount
+	}
+
+	t.Run("jobs pinned to a version run on that version", func(t *testing.T) {
+		client, jobAssignments := setup(t)
+		jobCount := requestJobs(t, client, jobAssignments, "@v2", 10)
+		require.Equal(t, map[string]int{"v2": 10}, jobCount)
+	})
+
+	t.Run("jobs are split across versions", func(t *testing.T) {
+		client, jobAssignments := setup(t)
+		jobCount := requestJobs(t, client, jobAssignments, "@v1:50,v2:50", 20)
+		require.NotZero(t, jobCount["v1"])
+		require.NotZero(t, jobCount["v2"])
+	})
+
+	t.Run("jobs pinned to a missing version are not assigned", func(t *testing.T) {
+		client, _ := setup(t)
+		job := &livekit.Job{
+			Id:        guid.New(guid.AgentJobPrefix),
+			Type:      livekit.JobType_JT_ROOM,
+			Room:      &livekit.Room{},
+			Namespace: "test",
+			AgentName: "@v3",
+		}
+		_, err := client.JobRequest(context.Background(), "test", agent.RoomAgentTopic, job)
+		require.Error(t, err)
+	})
+}
+
+func TestAgentPlacement(t *testing.T) {
+	t.Run("placements match regions and node labels", func(t *testing.T) {
+		p := config.AgentPlacementConfig{Region: "eu-central", NodeLabels: map[string]string{"residency": "eu", "gpu": ""}}
+		require.True(t, agent.PlacementMatches(p, "eu-central", map[string]string{"residency": "eu", "gpu": "a100"}))
+		require.False(t, agent.PlacementMatches(p, "us-east", map[string]string{"residency": "eu", "gpu": "a100"}))
+		require.False(t, agent.PlacementMatches(p, "eu-central", map[string]string{"residency": "us", "gpu": "a100"}))
+		require.False(t, agent.PlacementMatches(p, "eu-central", map[string]string{"residency": "eu"}))
+		require.True(t, agent.PlacementMatches(config.AgentPlacementConfig{}, "us-east", nil))
+	})
+
+	// one node per region with a worker, assignments are reported with the region of the worker
+	setup := func(t *testing.T, placement config.AgentPlacementConfig) (rpc.AgentInternalClient, chan string) {
+		bus := psrpc.NewLocalMessageBus()
+		client := must.Get(rpc.NewAgentInternalClient(bus))
+		jobAssignments := make(chan string, 10)
+		for _, region := range []string{"us-east", "eu-central"} {
+			server := testutil.NewTestServerWithConfig(bus, &config.Config{
+				Region: region,
+				// test workers register without an agent name
+				Agents: config.AgentsConfig{Placements: map[string]config.AgentPlacementConfig{"": placement}},
+			})
+			t.Cleanup(server.Close)
+
+			worker := server.SimulateAgentWorker()
+			worker.Register("", "test", livekit.JobType_JT_ROOM)
+			go func() {
+				for range worker.JobAssignments.Observe().Events() {
+					jobAssignments <- region
+				}
+			}()
+		}
+		return client, jobAssignments
+	}
+
+	newJob := func() *livekit.Job {
+		return &livekit.Job{
+			Id:         guid.New(guid.AgentJobPrefix),
+			DispatchId: guid.New(guid.AgentDispatchPrefix),
+			Type:       livekit.JobType_JT_ROOM,
+			Room:       &livekit.Room{},
+			Namespace:  "test",
+		}
+	}
+
+	t.Run("jobs run in the region of their placement", func(t *testing.T) {
+		client, jobAssignments := setup(t, config.AgentPlacementConfig{Region: "eu-central"})
+		for i := 0; i < 3; i++ {
+			_, err := client.JobRequest(context.Background(), "test", agent.RoomAgentTopic, newJob())
+			require.NoError(t, err)
+
+			select {
+			case region := <-jobAssignments:
+				require.Equal(t, "eu-central", region)
+			case <-time.After(time.Second):
+				require.Fail(t, "job assignment timeout")
+			}
+		}
+	})
+
+	t.Run("jobs without a worker in their placement fail", func(t *testing.T) {
+		client, _ := setup(t, config.AgentPlacementConfig{Region: "ap-south"})
+		_, err := client.JobRequest(context.Background(), "test", agent.RoomAgentTopic, newJob())
+		var perr psrpc.Error
+		require.ErrorAs(t, err, &perr)
+		require.Equal(t, psrpc.FailedPrecondition, perr.Code())
+		require.Contains(t, err.Error(), agent.ErrNoMatchingAgentWorkers.Error())
+	})
+}
+
+func TestAgentLoadBalancingStrategies(t *testing.T) {
+	// workers are named after their version, jobs are counted by the worker they were assigned to
+	setup := func(t *testing.T, conf *config.Config, loads map[string]float32) (rpc.AgentInternalClient, chan *livekit.Job) {
+		bus := psrpc.NewLocalMessageBus()
+
+		client := must.Get(rpc.NewAgentInternalClient(bus))
+		server := testutil.NewTestServerWithConfig(bus, conf)
+		t.Cleanup(server.Close)
+
+		jobAssignments := make(chan *livekit.Job, 100)
+		for version, load := range loads {
+			worker := server.SimulateAgentWorker(
+				testutil.WithDefaultWorkerLoad(load),
+				testutil.WithJobLoad(testutil.NewStableJobLoad(load)),
+			)
+			worker.RegisterVersion(version, "test", version, livekit.JobType_JT_ROOM)
+			go func() {
+				for a := range worker.JobAssignments.Observe().Events() {
+					jobAssignments <- a.Job
+				}
+			}()
+		}
+		return client, jobAssignments
+	}
+
+	requestJob := func(t *testing.T, client rpc.AgentInternalClient, jobAssignments chan *livekit.Job, room string) string {
+		job := &livekit.Job{
+			Id:         guid.New(guid.AgentJobPrefix),
+			DispatchId: guid.New(guid.AgentDispatchPrefix),
+			Type:       livekit.JobType_JT_ROOM,
+			Room:       &livekit.Room{Name: room},
+			Namespace:  "test",
+		}
+		_, err := client.JobRequest(context.Background(), "test", agent.RoomAgentTopic, job)
+		require.NoError(t, err)
+
+		select {
+		case a := <-jobAssignments:
+			return a.AgentName
+		case <-time.After(time.Second):
+			require.Fail(t, "job assignment timeout")
+			return ""
+		}
+	}
+
+	withStrategy := func(conf config.AgentLoadBalancingConfig) *config.Config {
+		return &config.Config{
+			Region: "test",
+			Agents: config.AgentsConfig{
+				NamespaceLoadBalancing: map[string]config.AgentLoadBalancingConfig{"test": conf},
+			},
+		}
+	}
+
+	t.Run("least loaded", func(t *testing.T) {
+		client, jobAssignments := setup(t, withStrategy(config.AgentLoadBalancingConfig{Strategy: agent.BalancerLeastLoaded}), map[string]float32{"v1": 0.3, "v2": 0.05})
+		for i := 0; i < 3; i++ {
+			require.Equal(t, "v2", requestJob(t, client, jobAssignments, fmt.Sprintf("room%d", i)))
+		}
+	})
+
+	t.Run("round robin", func(t *testing.T) {
+		client, jobAssignments := setup(t, withStrategy(config.AgentLoadBalancingConfig{Strategy: agent.BalancerRoundRobin}), map[string]float32{"v1": 0.01, "v2": 0.01})
+		jobCount := make(map[string]int)
+		for i := 0; i < 4; i++ {
+			jobCount[requestJob(t, client, jobAssignments, fmt.Sprintf("room%d", i))]++
+		}
+		require.Equal(t, map[string]int{"v1": 2, "v2": 2}, jobCount)
+	})
+
+	t.Run("room affinity", func(t *testing.T) {
+		client, jobAssignments := setup(t, withStrategy(config.AgentLoadBalancingConfig{Strategy: agent.BalancerRoomAffinity}), map[string]float32{"v1": 0.01, "v2": 0.01, "v3": 0.01})
+		first := requestJob(t, client, jobAssignments, "room")
+		for i := 0; i < 2; i++ {
+			require.Equal(t, first, requestJob(t, client, jobAssignments, "room"))
+		}
+	})
+
+	t.Run("scoring", func(t *testing.T) {
+		var requests atomic.Int32
+		scoring := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Inc()
+			body, err := webhook.Receive(r, auth.NewSimpleKeyProvider("test", "verysecretsecret"))
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			req := &agent.WorkerScoringRequest{}
+			if err := json.Unmarshal(body, req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			res := &agent.WorkerScoringResponse{Scores: map[string]float64{}}
+			for _, w := range req.Workers {
+				if w.Version == "v1" {
+					res.Scores[w.ID] = 1
+				}
+			}
+			_ = json.NewEncoder(w).Encode(res)
+		}))
+		t.Cleanup(scoring.Close)
+
+		conf := withStrategy(config.AgentLoadBalancingConfig{Strategy: agent.BalancerScoring, ScoringURL: scoring.URL})
+		conf.WebHook.APIKey = "test"
+		client, jobAssignments := setup(t, conf, map[string]float32{"v1": 0.3, "v2": 0.05})
+		for i := 0; i < 3; i++ {
+			require.Equal(t, "v1", requestJob(t, client, jobAssignments, fmt.Sprintf("room%d", i)))
+		}
+		require.EqualValues(t, 3, requests.Load())
+	})
+
+	t.Run("unknown strategies are rejected", func(t *testing.T) {
+		_, err := service.NewAgentService(
+			withStrategy(config.AgentLoadBalancingConfig{Strategy: "fastest"}),
+			&livekit.Node{Id: guid.New("N_")},
+			psrpc.NewLocalMessageBus(),
+			auth.NewSimpleKeyProvider("test", "verysecretsecret"),
+			nil,
+		)
+		require.ErrorIs(t, err, agent.ErrUnsupportedBalancer)
+	})
+}
+
+func TestSimulatedAgentWorker(t *testing.T) {
+	bus := psrpc.NewLocalMessageBus()
+	client := must.Get(rpc.NewAgentInternalClient(bus))
+	server := testutil.NewTestServerWithConfig(bus, &config.Config{
+		Region: "test",
+		Keys:   map[string]string{"test": "verysecretsecret"},
+		Agents: config.AgentsConfig{
+			SimulatedWorker: config.AgentSimulatedWorkerConfig{Enabled: true, AgentName: "echo", Namespace: "test"},
+		},
+	})
+	t.Cleanup(server.Close)
+
+	require.Eventually(t, func() bool {
+		return len(server.Workers()) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	for _, jobType := range []livekit.JobType{livekit.JobType_JT_ROOM, livekit.JobType_JT_PUBLISHER} {
+		topic := agent.RoomAgentTopic
+		if jobType == livekit.JobType_JT_PUBLISHER {
+			topic = agent.PublisherAgentTopic
+		}
+		job := &livekit.Job{
+			Id:         guid.New(guid.AgentJobPrefix),
+			DispatchId: guid.New(guid.AgentDispatchPrefix),
+			Type:       jobType,
+			Room:       &livekit.Room{Name: "room"},
+			Namespace:  "test",
+			AgentName:  "echo",
+		}
+		res, err := client.JobRequest(context.Background(), agent.GetAgentTopic("echo", "test"), topic, job)
+		require.NoError(t, err)
+		require.Equal(t, "simulated-"+job.Id, res.State.ParticipantIdentity)
+	}
+
+	_, err := service.NewAgentService(&config.Config{
+		Agents: config.AgentsConfig{SimulatedWorker: config.AgentSimulatedWorkerConfig{Enabled: true}},
+	}, &livekit.Node{Id: guid.New("N_")}, bus, auth.NewSimpleKeyProvider("test", "verysecretsecret"), nil)
+	require.ErrorIs(t, err, service.ErrSimulatedAgentMissingAPIKey)
+}
@@ -0,0 +1,283 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+const (
+	BalancerLoadWeighted = "load_weighted"
+	BalancerLeastLoaded  = "least_loaded"
+	BalancerRoundRobin   = "round_robin"
+	BalancerRoomAffinity = "room_affinity"
+	BalancerScoring      = "scoring"
+
+	DefaultScoringTimeout = 500 * time.Millisecond
+)
+
+var (
+	ErrUnsupportedBalancer   = errors.New("unsupported agent load balancing strategy")
+	ErrScoringURLRequired    = errors.New("scoring load balancing requires a scoring url")
+	ErrNoWorkersWithCapacity = errors.New("no workers with sufficient capacity")
+	ErrScoringMissingAPIKey  = errors.New("api key for signing scoring requests is not configured")
+)
+
+// WorkerBalancer chooses which of the workers with spare capacity a job is assigned to
+type WorkerBalancer interface {
+	SelectWorker(ctx context.Context, job *livekit.Job, workers []*Worker) (*Worker, error)
+}
+
+func NewWorkerBalancer(conf config.AgentLoadBalancingConfig, apiKey, apiSecret string) (WorkerBalancer, error) {
+	switch conf.Strategy {
+	case "", BalancerLoadWeighted:
+		return LoadWeightedBalancer{}, nil
+	case BalancerLeastLoaded:
+		return LeastLoadedBalancer{}, nil
+	case BalancerRoundRobin:
+		return &RoundRobinBalancer{}, nil
+	case BalancerRoomAffinity:
+		return RoomAffinityBalancer{}, nil
+	case BalancerScoring:
+		return NewScoringBalancer(conf, apiKey, apiSecret)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedBalancer, conf.Strategy)
+	}
+}
+
+// LoadWeightedBalancer picks a worker at random, weighted by its spare capacity
+type LoadWeightedBalancer struct{}
+
+func (LoadWeightedBalancer) SelectWorker(_ context.Context, _ *livekit.Job, workers []*Worker) (*Worker, error) {
+	normalizeLoad := func(load float32) int {
+		if load >= 1 {
+			return 0
+		}
+		return int((1 - load) * 100)
+	}
+
+	normalizedLoads := make([]int, len(workers))
+	var availableSum int
+	for i, w := range workers {
+		normalizedLoads[i] = normalizeLoad(w.Load())
+		availableSum += normalizedLoads[i]
+	}
+
+	if availableSum == 0 {
+		return nil, ErrNoWorkersWithCapacity
+	}
+
+	threshold := rand.Intn(availableSum)
+	var currentSum int
+	for i, load := range normalizedLoads {
+		currentSum += load
+		if currentSum > threshold {
+			return workers[i], nil
+		}
+	}
+
+	return nil, ErrNoWorkersWithCapacity
+}
+
+// LeastLoadedBalancer picks the worker with the lowest load
+type LeastLoadedBalancer struct{}
+
+func (LeastLoadedBalancer) SelectWorker(_ context.Context, _ *livekit.Job, workers []*Worker) (*Worker, error) {
+	var selected *Worker
+	var minLoad float32
+	for _, w := range workers {
+		if load := w.Load(); selected == nil || load < minLoad {
+			selected, minLoad = w, load
+		}
+	}
+	if selected == nil {
+		return nil, ErrNoWorkersWithCapacity
+	}
+	return selected, nil
+}
+
+// RoundRobinBalancer assigns jobs to workers in turn
+type RoundRobinBalancer struct {
+	next atomic.Uint64
+}
+
+func (b *RoundRobinBalancer) SelectWorker(_ context.Context, _ *livekit.Job, workers []*Worker) (*Worker, error) {
+	if len(workers) == 0 {
+		return nil, ErrNoWorkersWithCapacity
+	}
+	return workers[(b.next.Inc()-1)%uint64(len(workers))], nil
+}
+
+// RoomAffinityBalancer keeps the jobs of a room on the worker already running one, and otherwise picks the least loaded worker
+type RoomAffinityBalancer struct{}
+
+func (RoomAffinityBalancer) SelectWorker(ctx context.Context, job *livekit.Job, workers []*Worker) (*Worker, error) {
+	if room := job.Room.GetName(); room != "" {
+		for _, w := range workers {
+			for _, j := range w.RunningJobs() {
+				if j.Room.GetName() == room {
+					return w, nil
+				}
+			}
+		}
+	}
+	return LeastLoadedBalancer{}.SelectWorker(ctx, job, workers)
+}
+
+// WorkerScoringRequest is posted to the scoring URL for every job
+type WorkerScoringRequest struct {
+	JobID               string               `json:"job_id"`
+	JobType             string               `json:"job_type"`
+	AgentName           string               `json:"agent_name,omitempty"`
+	Namespace           string               `json:"namespace,omitempty"`
+	Room                string               `json:"room,omitempty"`
+	ParticipantIdentity string               `json:"participant_identity,omitempty"`
+	Workers             []*WorkerScoringInfo `json:"workers"`
+}
+
+type WorkerScoringInfo struct {
+	ID      string  `json:"id"`
+	Version string  `json:"version,omitempty"`
+	Load    float32 `json:"load"`
+	Jobs    int     `json:"jobs"`
+}
+
+// WorkerScoringResponse scores workers by ID, the job goes to the highest positive score.
+// the job is not assigned when no worker has a positive score
+type WorkerScoringResponse struct {
+	Scores map[string]float64 `json:"scores"`
+}
+
+// ScoringBalancer lets an external service choose the worker. Requests are signed the same way as webhooks,
+// so receivers can verify them with webhook.Receive. When the service can't be reached, jobs are load weighted.
+type ScoringBalancer struct {
+	url       string
+	apiKey    string
+	apiSecret string
+	client    *http.Client
+}
+
+func NewScoringBalancer(conf config.AgentLoadBalancingConfig, apiKey, apiSecret string) (*ScoringBalancer, error) {
+	if conf.ScoringURL == "" {
+		return nil, ErrScoringURLRequired
+	}
+	if apiKey == "" || apiSecret == "" {
+		return nil, ErrScoringMissingAPIKey
+	}
+	timeout := conf.ScoringTimeout
+	if timeout <= 0 {
+		timeout = DefaultScoringTimeout
+	}
+
+	return &ScoringBalancer{
+		url:       conf.ScoringURL,
+		apiKey:    apiKey,
+		apiSecret: apiSecret,
+		client:    &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (b *ScoringBalancer) SelectWorker(ctx context.Context, job *livekit.Job, workers []*Worker) (*Worker, error) {
+	w, err := b.score(ctx, job, workers)
+	if err != nil {
+		logger.Warnw("failed to score agent workers, falling back to load weighted", err, "jobID", job.Id, "url", b.url)
+		return LoadWeightedBalancer{}.SelectWorker(ctx, job, workers)
+	}
+	if w == nil {
+		// none of the workers were deemed suitable
+		return nil, ErrNoWorkersWithCapacity
+	}
+	return w, nil
+}
+
+func (b *ScoringBalancer) score(ctx context.Context, job *livekit.Job, workers []*Worker) (*Worker, error) {
+	req := &WorkerScoringRequest{
+		JobID:               job.Id,
+		JobType:             job.Type.String(),
+		AgentName:           job.AgentName,
+		Namespace:           job.Namespace,
+		Room:                job.Room.GetName(),
+		ParticipantIdentity: job.Participant.GetIdentity(),
+	}
+	for _, w := range workers {
+		req.Workers = append(req.Workers, &WorkerScoringInfo{
+			ID:      w.ID(),
+			Version: w.Version(),
+			Load:    w.Load(),
+			Jobs:    len(w.RunningJobs()),
+		})
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(body)
+	token, err := auth.NewAccessToken(b.apiKey, b.apiSecret).
+		SetValidFor(5 * time.Minute).
+		SetSha256(base64.StdEncoding.EncodeToString(sum[:])).
+		ToJWT()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Authorization", token)
+	r.Header.Set("Content-Type", "application/webhook+json")
+
+	resp, err := b.client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("scoring service returned %d", resp.StatusCode)
+	}
+
+	res := &WorkerScoringResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return nil, fmt.Errorf("invalid scoring response: %w", err)
+	}
+
+	var selected *Worker
+	var maxScore float64
+	for _, w := range workers {
+		if score := res.Scores[w.ID()]; score > maxScore {
+			selected, maxScore = w, score
+		}
+	}
+	return selected, nil
+}
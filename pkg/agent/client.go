@@ -16,15 +16,18 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/gammazero/workerpool"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 	serverutils "github.com/livekit/livekit-server/pkg/utils"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/protocol/tracer"
 	"github.com/livekit/protocol/utils"
 	"github.com/livekit/psrpc"
 	"google.golang.org/protobuf/types/known/emptypb"
@@ -128,7 +131,14 @@ func (c *agentClient) LaunchJob(ctx context.Context, desc *JobRequest) *serverut
 		})
 	}()
 
-	dispatcher := c.getDispatcher(desc.AgentName, desc.JobType)
+	// the versions to route to are resolved by the worker's server, the job keeps them in its agent name
+	agentName, _, err := ParseAgentName(desc.AgentName)
+	if err != nil {
+		logger.Warnw("not dispatching agent job with invalid agent name", err, "agentName", desc.AgentName)
+		return ret
+	}
+
+	dispatcher := c.getDispatcher(agentName, desc.JobType)
 
 	if dispatcher == nil {
 		logger.Infow("not dispatching agent job since no worker is available",
@@ -140,11 +150,15 @@ func (c *agentClient) LaunchJob(ctx context.Context, desc *JobRequest) *serverut
 	}
 
 	dispatcher.ForEach(func(curNs string) {
-		topic := GetAgentTopic(desc.AgentName, curNs)
+		topic := GetAgentTopic(agentName, curNs)
 
 		wg.Add(1)
+		start := time.Now()
 		c.workers.Submit(func() {
 			defer wg.Done()
+			ctx, span := tracer.Start(context.Background(), "agentClient.JobRequest")
+			defer span.End()
+
 			// The cached agent parameters do not provide the exact combination of available job type/agent name/namespace, so some of the JobRequest RPC may not trigger any worker
 			job := &livekit.Job{
 				Id:          utils.NewGuid(utils.AgentJobPrefix),
@@ -156,11 +170,25 @@ func (c *agentClient) LaunchJob(ctx context.Context, desc *JobRequest) *serverut
 				AgentName:   desc.AgentName,
 				Metadata:    desc.Metadata,
 			}
-			resp, err := c.client.JobRequest(context.Background(), topic, jobTypeTopic, job)
+			resp, err := c.client.JobRequest(ctx, topic, jobTypeTopic, job)
 			if err != nil {
+				span.RecordError(err)
+				prometheus.RecordAgentJobFailure("request")
 				logger.Infow("failed to send job request", "error", err, "namespace", curNs, "jobType", desc.JobType, "agentName", desc.AgentName)
+
+				// jobs that can't run, like those outside their agent's placement, are kept failed in the dispatch state
+				var perr psrpc.Error
+				if errors.As(err, &perr) && perr.Code() == psrpc.FailedPrecondition {
+					job.State = &livekit.JobState{
+						Status:    livekit.JobStatus_JS_FAILED,
+						Error:     err.Error(),
+						UpdatedAt: time.Now().UnixNano(),
+					}
+					ret.Add(job)
+				}
 				return
 			}
+			prometheus.RecordAgentDispatchLatency(desc.JobType, time.Since(start))
 			job.State = resp.State
 			ret.Add(job)
 		})
@@ -170,11 +198,15 @@ func (c *agentClient) LaunchJob(ctx context.Context, desc *JobRequest) *serverut
 }
 
 func (c *agentClient) TerminateJob(ctx context.Context, jobID string, reason rpc.JobTerminateReason) (*livekit.JobState, error) {
-	resp, err := c.client.JobTerminate(context.Background(), jobID, &rpc.JobTerminateRequest{
+	ctx, span := tracer.Start(context.Background(), "agentClient.TerminateJob")
+	defer span.End()
+
+	resp, err := c.client.JobTerminate(ctx, jobID, &rpc.JobTerminateRequest{
 		JobId:  jobID,
 		Reason: reason,
 	})
 	if err != nil {
+		span.RecordError(err)
 		logger.Infow("failed to send job request", "error", err, "jobID", jobID)
 		return nil, err
 	}
@@ -0,0 +1,30 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"errors"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+)
+
+// ErrNoMatchingAgentWorkers is returned for jobs of an agent with a placement, when no worker in the placement is available
+var ErrNoMatchingAgentWorkers = errors.New("no agent workers available in the agent placement")
+
+// PlacementMatches returns true when workers connected to a node in region with labels can run jobs of an agent placed with p
+func PlacementMatches(p config.AgentPlacementConfig, region string, labels map[string]string) bool {
+	return (p.Region == "" || p.Region == region) && selector.NodeLabels(labels).Matches(p.NodeLabels)
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// participant attributes carrying a job result in job_finished webhooks
+const (
+	JobResultAttributePrefix   = "lk.agent."
+	JobResultArtifactPrefix    = JobResultAttributePrefix + "artifact."
+	JobResultJobIDAttribute    = JobResultAttributePrefix + "job_id"
+	JobResultDispatchAttribute = JobResultAttributePrefix + "dispatch_id"
+	JobResultStatusAttribute   = JobResultAttributePrefix + "status"
+	JobResultTranscriptURL     = JobResultAttributePrefix + "transcript_url"
+	JobResultSummaryAttribute  = JobResultAttributePrefix + "summary"
+	JobResultErrorAttribute    = JobResultAttributePrefix + "error"
+
+	maxJobResultSummaryLength = 16 * 1024
+	maxJobResultArtifacts     = 32
+)
+
+var (
+	ErrJobResultMissingJobID = errors.New("job result requires a job id")
+	ErrJobResultTooLarge     = errors.New("job result summary or artifacts exceed the allowed size")
+	ErrJobNotFound           = errors.New("agent job not found")
+)
+
+// JobResult is what an agent reports once its job is done. It is stored with the dispatch of the job
+type JobResult struct {
+	JobID         string `json:"job_id"`
+	DispatchID    string `json:"dispatch_id,omitempty"`
+	TranscriptURL string `json:"transcript_url,omitempty"`
+	Summary       string `json:"summary,omitempty"`
+	Error         string `json:"error,omitempty"`
+	// named artifact URLs or values, e.g. recording or notes
+	Artifacts map[string]string `json:"artifacts,omitempty"`
+	// unix nanoseconds
+	ReportedAt int64 `json:"reported_at,omitempty"`
+}
+
+func (r *JobResult) Validate() error {
+	if r.JobID == "" {
+		return ErrJobResultMissingJobID
+	}
+	if len(r.Summary) > maxJobResultSummaryLength || len(r.Artifacts) > maxJobResultArtifacts {
+		return ErrJobResultTooLarge
+	}
+	return nil
+}
+
+// Status is failed when the agent reported an error
+func (r *JobResult) Status() livekit.JobStatus {
+	if r.Error != "" {
+		return livekit.JobStatus_JS_FAILED
+	}
+	return livekit.JobStatus_JS_SUCCESS
+}
+
+// Attributes flattens the result into participant attributes
+func (r *JobResult) Attributes() map[string]string {
+	attrs := map[string]string{
+		JobResultJobIDAttribute:  r.JobID,
+		JobResultStatusAttribute: strings.ToLower(strings.TrimPrefix(r.Status().String(), "JS_")),
+	}
+	if r.DispatchID != "" {
+		attrs[JobResultDispatchAttribute] = r.DispatchID
+	}
+	if r.TranscriptURL != "" {
+		attrs[JobResultTranscriptURL] = r.TranscriptURL
+	}
+	if r.Summary != "" {
+		attrs[JobResultSummaryAttribute] = r.Summary
+	}
+	if r.Error != "" {
+		attrs[JobResultErrorAttribute] = r.Error
+	}
+	for name, value := range r.Artifacts {
+		attrs[JobResultArtifactPrefix+name] = value
+	}
+	return attrs
+}
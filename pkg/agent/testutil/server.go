@@ -30,13 +30,18 @@ type TestServer struct {
 }
 
 func NewTestServer(bus psrpc.MessageBus) *TestServer {
+	return NewTestServerWithConfig(bus, &config.Config{Region: "test"})
+}
+
+func NewTestServerWithConfig(bus psrpc.MessageBus, conf *config.Config) *TestServer {
 	keyProvider := auth.NewSimpleKeyProvider("test", "verysecretsecret")
 
 	s := must.Get(service.NewAgentService(
-		&config.Config{Region: "test"},
+		conf,
 		&livekit.Node{Id: guid.New("N_")},
 		bus,
 		keyProvider,
+		nil,
 	))
 
 	return &TestServer{
@@ -408,10 +413,15 @@ func (w *AgentWorker) sendStatus() {
 }
 
 func (w *AgentWorker) Register(name string, namespace string, jobType livekit.JobType) {
+	w.RegisterVersion(name, namespace, "", jobType)
+}
+
+func (w *AgentWorker) RegisterVersion(name string, namespace string, version string, jobType livekit.JobType) {
 	w.Name = name
 	w.SendRegister(&livekit.RegisterWorkerRequest{
 		Type:      jobType,
 		Namespace: &namespace,
+		Version:   version,
 	})
 	w.sendStatus()
 }
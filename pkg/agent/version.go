@@ -0,0 +1,92 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Dispatches route jobs to versions of an agent by suffixing the agent name with the versions workers registered with.
+// "transcriber@v2" sends every job to v2 workers, "transcriber@v2:10,v1:90" sends 10% of jobs to v2 and the rest to v1.
+const AgentVersionSeparator = "@"
+
+var ErrInvalidAgentVersions = errors.New("invalid agent versions, expected name@version or name@version:weight,...")
+
+type VersionWeight struct {
+	Version string
+	Weight  int
+}
+
+// VersionSelector is the set of versions jobs of a dispatch may run on. An empty selector allows any version.
+type VersionSelector []VersionWeight
+
+// ParseAgentName splits a dispatch agent name into the name workers registered with and the versions to route to
+func ParseAgentName(name string) (string, VersionSelector, error) {
+	agentName, spec, ok := strings.Cut(name, AgentVersionSeparator)
+	if !ok {
+		return name, nil, nil
+	}
+
+	var versions VersionSelector
+	for _, v := range strings.Split(spec, ",") {
+		version, weight, weighted := strings.Cut(v, ":")
+		if version == "" || slices.ContainsFunc(versions, func(vw VersionWeight) bool { return vw.Version == version }) {
+			return "", nil, ErrInvalidAgentVersions
+		}
+
+		vw := VersionWeight{Version: version}
+		if weighted {
+			w, err := strconv.Atoi(weight)
+			if err != nil || w <= 0 {
+				return "", nil, ErrInvalidAgentVersions
+			}
+			vw.Weight = w
+		}
+		versions = append(versions, vw)
+	}
+
+	// a split needs a weight for every version
+	if len(versions) > 1 && slices.ContainsFunc(versions, func(vw VersionWeight) bool { return vw.Weight == 0 }) {
+		return "", nil, ErrInvalidAgentVersions
+	}
+	return agentName, versions, nil
+}
+
+func (s VersionSelector) Matches(version string) bool {
+	return len(s) == 0 || slices.ContainsFunc(s, func(vw VersionWeight) bool { return vw.Version == version })
+}
+
+// Pick selects one version of a split at random according to the weights
+func (s VersionSelector) Pick() VersionSelector {
+	if len(s) <= 1 {
+		return s
+	}
+
+	var sum int
+	for _, vw := range s {
+		sum += vw.Weight
+	}
+	n := rand.Intn(sum)
+	for _, vw := range s {
+		if n -= vw.Weight; n < 0 {
+			return VersionSelector{vw}
+		}
+	}
+	return s[len(s)-1:]
+}
@@ -159,6 +159,12 @@ func (w *Worker) JobType() livekit.JobType {
 	return w.jobType
 }
 
+func (w *Worker) Version() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.version
+}
+
 func (w *Worker) Namespace() string {
 	return w.namespace
 }
@@ -21,6 +21,8 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/urfave/cli/v2"
 
+	"github.com/livekit/protocol/livekit"
+
 	"github.com/livekit/livekit-server/pkg/config/configtest"
 )
 
@@ -49,6 +51,85 @@ room:
 	require.Error(t, err)
 }
 
+func TestConfig_RoomConfigOverrides(t *testing.T) {
+	const content = `room:
+  playout_delay:
+    max: 2000
+  config_overrides:
+    townhall:
+      min_playout_delay: 500
+      max_ephemeral_messages_per_second: 5
+      twcc: false
+      max_subscribers_per_track: 100
+      rtp_interceptors:
+        - abs-send-time
+      subscribed_quality:
+        camera:
+          default: low
+          max: medium`
+	conf, err := NewConfig(content, true, nil, nil)
+	require.NoError(t, err)
+
+	overrides := conf.Room.ConfigOverrides["townhall"]
+	roomConf := conf.Room.WithOverrides(&overrides)
+	require.Equal(t, PlayoutDelayConfig{Enabled: true, Min: 500, Max: 2000}, roomConf.PlayoutDelay)
+	require.Equal(t, 5, roomConf.Ephemeral.MaxMessagesPerSecond)
+	require.Equal(t, conf.Room.EnabledCodecs, roomConf.EnabledCodecs)
+	require.EqualValues(t, 100, conf.Limit.WithOverrides(&overrides).MaxSubscribersPerTrack)
+	require.Equal(t, conf.Limit, conf.Limit.WithOverrides(nil))
+
+	defaultQuality, maxQuality := overrides.SubscribedQuality.ForSource(livekit.TrackSource_CAMERA).Qualities()
+	require.Equal(t, livekit.VideoQuality_LOW, defaultQuality)
+	require.Equal(t, livekit.VideoQuality_MEDIUM, maxQuality)
+	defaultQuality, maxQuality = overrides.SubscribedQuality.ForSource(livekit.TrackSource_SCREEN_SHARE).Qualities()
+	require.Equal(t, livekit.VideoQuality_OFF, defaultQuality)
+	require.Equal(t, livekit.VideoQuality_OFF, maxQuality)
+
+	// set fields of the request take precedence
+	enabled := true
+	merged := overrides.Merge(RoomConfigOverrides{TWCC: &enabled, MaxSubscribersPerTrack: 10})
+	require.True(t, *merged.TWCC)
+	require.EqualValues(t, 10, merged.MaxSubscribersPerTrack)
+	require.EqualValues(t, 500, merged.MinPlayoutDelay)
+	require.Equal(t, []string{"abs-send-time"}, merged.RTPInterceptors)
+	require.Equal(t, []string{"custom"}, overrides.Merge(RoomConfigOverrides{RTPInterceptors: []string{"custom"}}).RTPInterceptors)
+	require.True(t, conf.Room.WithOverrides(&RoomConfigOverrides{AVSyncCorrection: &enabled}).AVSyncCorrection)
+
+	_, err = NewConfig(`room:
+  config_overrides:
+    townhall:
+      enabled_codecs:
+        - mime: vp8`, true, nil, nil)
+	require.Error(t, err)
+
+	_, err = NewConfig(`room:
+  config_overrides:
+    townhall:
+      subscribed_quality:
+        screen_share:
+          default: high
+          max: low`, true, nil, nil)
+	require.Error(t, err)
+}
+
+func TestConfig_Projects(t *testing.T) {
+	conf, err := NewConfig(`projects:
+  a:
+    keys: [key1, key2]
+  b:
+    keys: [key3]`, true, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"key1", "key2"}, conf.Projects["a"].Keys)
+
+	// a key belongs to a single project
+	_, err = NewConfig(`projects:
+  a:
+    keys: [key1]
+  b:
+    keys: [key1]`, true, nil, nil)
+	require.Error(t, err)
+}
+
 func TestGeneratedFlags(t *testing.T) {
 	generatedFlags, err := GenerateCLIFlags(nil, false)
 	require.NoError(t, err)
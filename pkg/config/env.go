@@ -0,0 +1,172 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix prefixes the environment variables that set config keys. A key's variable is its yaml path in
+// upper case, with dots replaced by underscores, e.g. rtc.port_range_start is set by LIVEKIT_RTC_PORT_RANGE_START.
+//
+// Values are parsed as YAML, except for strings which are used as is. Lists also take comma separated values,
+// and maps and sections take a YAML or JSON object, e.g. LIVEKIT_KEYS='{key1: secret1, key2: secret2}'.
+// A section and one of its keys can both be set, the key takes precedence. Empty variables are ignored.
+//
+// Settings are applied in this order, later ones taking precedence: defaults, config file or body, environment
+// variables, command line flags
+const EnvPrefix = "LIVEKIT_"
+
+// EnvVarName is the environment variable that sets the key at a yaml path
+func EnvVarName(path string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+type envKey struct {
+	path  []string
+	isStr bool
+	isSeq bool
+}
+
+// variables shared by more than one key, only the first key in path order can be set
+var ambiguousEnvVars = map[string][]string{}
+
+var configEnvKeys = func() map[string]*envKey {
+	keys := make(map[string]*envKey)
+	collectEnvKeys(reflect.TypeOf(Config{}), nil, keys, map[reflect.Type]bool{})
+	return keys
+}()
+
+// collectEnvKeys maps the environment variable of every key, sections included, to its yaml path
+func collectEnvKeys(t reflect.Type, path []string, keys map[string]*envKey, visiting map[reflect.Type]bool) {
+	fields := yamlFields(t)
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	// sorted, so that the first of two keys with the same variable wins
+	sort.Strings(names)
+
+	for _, name := range names {
+		ft := fields[name]
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		keyPath := append(append([]string{}, path...), name)
+		envVar := EnvVarName(strings.Join(keyPath, "."))
+		if existing, ok := keys[envVar]; ok {
+			ambiguousEnvVars[envVar] = append(ambiguousEnvVars[envVar], strings.Join(existing.path, "."), strings.Join(keyPath, "."))
+		} else {
+			keys[envVar] = &envKey{
+				path:  keyPath,
+				isStr: ft.Kind() == reflect.String,
+				isSeq: ft.Kind() == reflect.Slice && ft.Elem().Kind() != reflect.Struct,
+			}
+		}
+
+		if ft.Kind() == reflect.Struct && !reflect.PointerTo(ft).Implements(yamlUnmarshalerType) && !visiting[ft] {
+			visiting[ft] = true
+			collectEnvKeys(ft, keyPath, keys, visiting)
+			delete(visiting, ft)
+		}
+	}
+}
+
+// EnvKeys returns the yaml paths of the keys set in environ, by environment variable
+func EnvKeys(environ []string) map[string]string {
+	keys := make(map[string]string)
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		if key, ok := configEnvKeys[name]; ok && value != "" {
+			keys[name] = strings.Join(key.path, ".")
+		}
+	}
+	return keys
+}
+
+// updateFromEnv applies the keys set in environ, sections before their keys
+func (conf *Config) updateFromEnv(environ []string) error {
+	type envValue struct {
+		name  string
+		key   *envKey
+		value string
+	}
+	var values []envValue
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		if key, ok := configEnvKeys[name]; ok && value != "" {
+			values = append(values, envValue{name, key, value})
+		}
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i].key.path) != len(values[j].key.path) {
+			return len(values[i].key.path) < len(values[j].key.path)
+		}
+		return values[i].name < values[j].name
+	})
+
+	for _, v := range values {
+		node, err := envValueNode(v.key, v.value)
+		if err != nil {
+			return fmt.Errorf("could not parse %s: %v", v.name, err)
+		}
+		for i := len(v.key.path) - 1; i >= 0; i-- {
+			node = &yaml.Node{
+				Kind: yaml.MappingNode,
+				Content: []*yaml.Node{
+					{Kind: yaml.ScalarNode, Value: v.key.path[i]},
+					node,
+				},
+			}
+		}
+		doc, err := yaml.Marshal(node)
+		if err != nil {
+			return fmt.Errorf("could not parse %s: %v", v.name, err)
+		}
+		decoder := yaml.NewDecoder(strings.NewReader(string(doc)))
+		decoder.KnownFields(true)
+		if err = decoder.Decode(conf); err != nil {
+			return fmt.Errorf("could not parse %s: %v", v.name, err)
+		}
+	}
+	return nil
+}
+
+func envValueNode(key *envKey, value string) (*yaml.Node, error) {
+	if key.isStr {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}, nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &doc); err != nil {
+		return nil, err
+	}
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+	if len(doc.Content) > 0 {
+		node = doc.Content[0]
+	}
+	if key.isSeq && node.Kind != yaml.SequenceNode {
+		node = &yaml.Node{Kind: yaml.SequenceNode}
+		for _, item := range strings.Split(value, ",") {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: strings.TrimSpace(item)})
+		}
+	}
+	return node, nil
+}
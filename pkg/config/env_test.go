@@ -0,0 +1,98 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Env(t *testing.T) {
+	t.Run("keys", func(t *testing.T) {
+		t.Setenv("LIVEKIT_PORT", "7881")
+		t.Setenv("LIVEKIT_DEVELOPMENT", "true")
+		t.Setenv("LIVEKIT_REGION", "123")
+		t.Setenv("LIVEKIT_RTC_PORT_RANGE_START", "50000")
+		t.Setenv("LIVEKIT_RTC_ALLOW_TCP_FALLBACK", "false")
+		t.Setenv("LIVEKIT_SOAK_INTERVAL", "1m")
+		t.Setenv("LIVEKIT_LOGGING_LEVEL", "")
+
+		conf, err := NewConfig("port: 7882\nlogging:\n  level: warn\n", true, nil, nil)
+		require.NoError(t, err)
+		// environment takes precedence over the config file
+		require.EqualValues(t, 7881, conf.Port)
+		require.True(t, conf.Development)
+		// strings are not parsed
+		require.Equal(t, "123", conf.Region)
+		require.EqualValues(t, 50000, conf.RTC.ICEPortRangeStart)
+		require.NotNil(t, conf.RTC.AllowTCPFallback)
+		require.False(t, *conf.RTC.AllowTCPFallback)
+		require.Equal(t, time.Minute, conf.Soak.Interval)
+		// empty variables are ignored
+		require.Equal(t, "warn", conf.Logging.Level)
+	})
+
+	t.Run("lists and maps", func(t *testing.T) {
+		t.Setenv("LIVEKIT_BIND_ADDRESSES", "127.0.0.1, ::1")
+		t.Setenv("LIVEKIT_RTC_STUN_SERVERS", `["stun.example.com:3478"]`)
+		t.Setenv("LIVEKIT_KEYS", "{key1: secret1, key2: secret2}")
+		t.Setenv("LIVEKIT_RTC_TURN_SERVERS", `[{host: turn.example.com, port: 443, protocol: tls}]`)
+
+		conf, err := NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, []string{"127.0.0.1", "::1"}, conf.BindAddresses)
+		require.Equal(t, []string{"stun.example.com:3478"}, conf.RTC.STUNServers)
+		require.Equal(t, map[string]string{"key1": "secret1", "key2": "secret2"}, conf.Keys)
+		require.Len(t, conf.RTC.TURNServers, 1)
+		require.Equal(t, "turn.example.com", conf.RTC.TURNServers[0].Host)
+	})
+
+	t.Run("sections", func(t *testing.T) {
+		t.Setenv("LIVEKIT_ROOM", "{empty_timeout: 10, max_participants: 5}")
+		t.Setenv("LIVEKIT_ROOM_EMPTY_TIMEOUT", "20")
+
+		conf, err := NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		// the key takes precedence over its section
+		require.EqualValues(t, 20, conf.Room.EmptyTimeout)
+		require.EqualValues(t, 5, conf.Room.MaxParticipants)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Setenv("LIVEKIT_PORT", "not a port")
+		_, err := NewConfig("", true, nil, nil)
+		require.ErrorContains(t, err, "LIVEKIT_PORT")
+
+		t.Setenv("LIVEKIT_PORT", "7880")
+		t.Setenv("LIVEKIT_ROOM", "{unknown: 1}")
+		_, err = NewConfig("", true, nil, nil)
+		require.ErrorContains(t, err, "LIVEKIT_ROOM")
+	})
+
+	t.Run("EnvKeys", func(t *testing.T) {
+		keys := EnvKeys([]string{"LIVEKIT_RTC_TCP_PORT=7881", "LIVEKIT_UNKNOWN=1", "PATH=/bin", "LIVEKIT_REGION="})
+		require.Equal(t, map[string]string{"LIVEKIT_RTC_TCP_PORT": "rtc.tcp_port"}, keys)
+	})
+
+	t.Run("unambiguous", func(t *testing.T) {
+		// every key has its own variable, except for the deprecated prometheus_port which sets the same port
+		require.Equal(t, map[string][]string{
+			"LIVEKIT_PROMETHEUS_PORT": {"prometheus.port", "prometheus_port"},
+		}, ambiguousEnvVars)
+		require.Greater(t, len(configEnvKeys), 200)
+	})
+}
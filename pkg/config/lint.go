@@ -0,0 +1,236 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	IssueError   = "error"
+	IssueWarning = "warning"
+
+	redactedValue = "<redacted>"
+)
+
+// deprecatedKeys maps deprecated settings to their replacement
+var deprecatedKeys = map[string]string{
+	"log_level":                            "logging.level",
+	"prometheus_port":                      "prometheus.port",
+	"rtc.packet_buffer_size":               "rtc.packet_buffer_size_video and rtc.packet_buffer_size_audio",
+	"room.max_metadata_size":               "limit.max_metadata_size",
+	"room.max_room_name_length":            "limit.max_room_name_length",
+	"room.max_participant_identity_length": "limit.max_participant_identity_length",
+}
+
+// settings with these words in their name are not printed
+var secretKeyWords = []string{"secret", "password", "credential", "token"}
+
+var yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// ConfigIssue is a problem found in a config file, Key is the yaml path of the setting
+type ConfigIssue struct {
+	Severity string
+	Key      string
+	Line     int
+	Message  string
+}
+
+// LintConfig reports unknown keys, with the closest known key as a suggestion, and deprecated keys
+func LintConfig(confString string) ([]*ConfigIssue, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(confString), &doc); err != nil {
+		return nil, fmt.Errorf("could not parse config: %v", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	var issues []*ConfigIssue
+	lintNode(doc.Content[0], reflect.TypeOf(Config{}), "", &issues)
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Line < issues[j].Line
+	})
+	return issues, nil
+}
+
+func lintNode(node *yaml.Node, t reflect.Type, path string, issues *[]*ConfigIssue) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	// types that decode themselves, e.g. port ranges, are not inspected
+	if reflect.PointerTo(t).Implements(yamlUnmarshalerType) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			keyPath := joinKeyPath(path, key.Value)
+			if replacement, ok := deprecatedKeys[keyPath]; ok {
+				*issues = append(*issues, &ConfigIssue{
+					Severity: IssueWarning,
+					Key:      keyPath,
+					Line:     key.Line,
+					Message:  fmt.Sprintf("deprecated, use %s", replacement),
+				})
+			}
+
+			ft, ok := fields[key.Value]
+			if !ok {
+				message := "unknown key"
+				if suggestion := closestKey(key.Value, fields); suggestion != "" {
+					message = fmt.Sprintf("unknown key, did you mean %s?", joinKeyPath(path, suggestion))
+				}
+				*issues = append(*issues, &ConfigIssue{
+					Severity: IssueError,
+					Key:      keyPath,
+					Line:     key.Line,
+					Message:  message,
+				})
+				continue
+			}
+			lintNode(value, ft, keyPath, issues)
+		}
+
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			lintNode(node.Content[i+1], t.Elem(), joinKeyPath(path, node.Content[i].Value), issues)
+		}
+
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, item := range node.Content {
+			lintNode(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), issues)
+		}
+	}
+}
+
+// yamlFields returns the types of the fields of a struct by yaml key, following the naming rules of yaml.v3
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := strings.Split(field.Tag.Get("yaml"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+		if len(tag) > 1 && tag[1] == "inline" {
+			for name, ft := range yamlFields(field.Type) {
+				fields[name] = ft
+			}
+			continue
+		}
+		name := tag[0]
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// closestKey returns the known key that is the fewest edits away, if it's close enough to be a typo
+func closestKey(key string, fields map[string]reflect.Type) string {
+	best, bestDistance := "", len(key)/3+2
+	for name := range fields {
+		if d := editDistance(key, name); d < bestDistance || (d == bestDistance && best != "" && name < best) {
+			best, bestDistance = name, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func joinKeyPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// ------------------------------------------------
+
+// RedactedYAML returns the config as yaml, with API secrets, passwords and credentials redacted
+func (conf *Config) RedactedYAML() ([]byte, error) {
+	var doc yaml.Node
+	if err := doc.Encode(conf); err != nil {
+		return nil, err
+	}
+	redactNode(&doc, false)
+	return yaml.Marshal(&doc)
+}
+
+func redactNode(node *yaml.Node, redact bool) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := strings.ToLower(node.Content[i].Value)
+			// values of the keys map are API secrets
+			valueRedacted := redact || key == "keys"
+			for _, word := range secretKeyWords {
+				valueRedacted = valueRedacted || strings.Contains(key, word)
+			}
+			redactNode(node.Content[i+1], valueRedacted)
+		}
+	case yaml.ScalarNode:
+		if redact && node.Value != "" {
+			node.Value = redactedValue
+			node.Tag = "!!str"
+			node.Style = 0
+		}
+	default:
+		for _, child := range node.Content {
+			redactNode(child, redact)
+		}
+	}
+}
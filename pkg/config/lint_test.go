@@ -0,0 +1,108 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintConfig(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		issues, err := LintConfig(`port: 7880
+rtc:
+  tcp_port: 7881
+  udp_port: 50000-60000
+  use_external_ip: true
+logging:
+  level: info
+room:
+  room_configurations:
+    meeting:
+      emptytimeout: 60
+webhook:
+  urls:
+    - http://localhost/webhook
+`)
+		require.NoError(t, err)
+		require.Empty(t, issues)
+	})
+
+	t.Run("unknown and deprecated keys", func(t *testing.T) {
+		issues, err := LintConfig(`port: 7880
+log_level: debug
+rtc:
+  tcp_prot: 7881
+room:
+  max_metadata_size: 100
+redis:
+  adress: localhost:6379
+turn_servers: []
+`)
+		require.NoError(t, err)
+		require.Len(t, issues, 5)
+
+		require.Equal(t, &ConfigIssue{Severity: IssueWarning, Key: "log_level", Line: 2, Message: "deprecated, use logging.level"}, issues[0])
+		require.Equal(t, &ConfigIssue{Severity: IssueError, Key: "rtc.tcp_prot", Line: 4, Message: "unknown key, did you mean rtc.tcp_port?"}, issues[1])
+		require.Equal(t, &ConfigIssue{Severity: IssueWarning, Key: "room.max_metadata_size", Line: 6, Message: "deprecated, use limit.max_metadata_size"}, issues[2])
+		require.Equal(t, &ConfigIssue{Severity: IssueError, Key: "redis.adress", Line: 8, Message: "unknown key, did you mean redis.address?"}, issues[3])
+		require.Equal(t, &ConfigIssue{Severity: IssueError, Key: "turn_servers", Line: 9, Message: "unknown key"}, issues[4])
+	})
+
+	t.Run("no suggestion when nothing is close", func(t *testing.T) {
+		issues, err := LintConfig(`something_else: true`)
+		require.NoError(t, err)
+		require.Len(t, issues, 1)
+		require.Equal(t, "unknown key", issues[0].Message)
+	})
+
+	t.Run("sample config", func(t *testing.T) {
+		sample, err := os.ReadFile("../../config-sample.yaml")
+		require.NoError(t, err)
+		issues, err := LintConfig(string(sample))
+		require.NoError(t, err)
+		for _, issue := range issues {
+			require.NotEqual(t, IssueError, issue.Severity, "%s: %s", issue.Key, issue.Message)
+		}
+	})
+}
+
+func TestConfig_RedactedYAML(t *testing.T) {
+	conf, err := NewConfig(`keys:
+  key1: secret1
+redis:
+  address: localhost:6379
+  password: redispass
+rtc:
+  turn_servers:
+    - host: turn.example.com
+      port: 443
+      protocol: tls
+      username: user
+      credential: turnpass
+`, true, nil, nil)
+	require.NoError(t, err)
+
+	out, err := conf.RedactedYAML()
+	require.NoError(t, err)
+	require.NotContains(t, string(out), "secret1")
+	require.NotContains(t, string(out), "redispass")
+	require.NotContains(t, string(out), "turnpass")
+	require.Contains(t, string(out), "key1: "+redactedValue)
+	require.Contains(t, string(out), "username: user")
+	require.Contains(t, string(out), "address: localhost:6379")
+}
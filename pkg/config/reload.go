@@ -0,0 +1,192 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ReloadableConfig are the settings Reload replaces at runtime. Reload swaps them as a whole, so components that read
+// them on use through Config.Reloadable see either the old or the new settings, never a partially applied reload.
+// New values take effect for new sessions and rooms
+type ReloadableConfig struct {
+	Limit        LimitConfig
+	Room         RoomConfig
+	FeatureFlags map[string]FeatureFlagConfig
+	WebHook      WebHookConfig
+	TURNServers  []TURNServer
+}
+
+// reloadableKeys are the settings applied at runtime by Reload, keyed by their yaml path.
+// They are applied to a copy of the current reloadable settings, which replaces them once every key is applied
+var reloadableKeys = map[string]func(conf *Config, dst *ReloadableConfig, src *Config) error{
+	"logging": func(conf *Config, _ *ReloadableConfig, src *Config) error {
+		// the logger observes its config, and applies updated levels and sampling
+		return conf.Logging.Config.Update(&src.Logging.Config)
+	},
+	"log_level": func(*Config, *ReloadableConfig, *Config) error {
+		// folded into logging.level when loaded
+		return nil
+	},
+	"limit": func(_ *Config, dst *ReloadableConfig, src *Config) error {
+		dst.Limit = src.Limit
+		return nil
+	},
+	"webhook": func(_ *Config, dst *ReloadableConfig, src *Config) error {
+		dst.WebHook = src.WebHook
+		return nil
+	},
+	"room": func(_ *Config, dst *ReloadableConfig, src *Config) error {
+		dst.Room = src.Room
+		return nil
+	},
+	"feature_flags": func(_ *Config, dst *ReloadableConfig, src *Config) error {
+		dst.FeatureFlags = src.FeatureFlags
+		return nil
+	},
+	"rtc.turn_servers": func(_ *Config, dst *ReloadableConfig, src *Config) error {
+		dst.TURNServers = src.RTC.TURNServers
+		return nil
+	},
+}
+
+// nestedReloadKeys are compared key by key, as only some of their settings are reloadable
+var nestedReloadKeys = map[string]bool{
+	"rtc": true,
+}
+
+type ReloadReport struct {
+	// keys that were changed and applied
+	Applied []string
+	// keys that were changed, but only take effect after a restart
+	RequiresRestart []string
+}
+
+func (r *ReloadReport) Changed() bool {
+	return len(r.Applied) > 0 || len(r.RequiresRestart) > 0
+}
+
+func (r *ReloadReport) IsApplied(key string) bool {
+	return slices.Contains(r.Applied, key)
+}
+
+// Reloadable returns the current reloadable settings. They are shared, and must not be modified
+func (conf *Config) Reloadable() *ReloadableConfig {
+	if r := conf.reloadable.Load(); r != nil {
+		return r
+	}
+	return &ReloadableConfig{
+		Limit:        conf.Limit,
+		Room:         conf.Room,
+		FeatureFlags: conf.FeatureFlags,
+		WebHook:      conf.WebHook,
+		TURNServers:  conf.RTC.TURNServers,
+	}
+}
+
+// Reload applies the reloadable settings of next, and reports the changed settings that require a restart.
+// Settings are compared with the config of the previous reload, settings that require a restart are reported once
+func (conf *Config) Reload(next *Config) (*ReloadReport, error) {
+	conf.reloadLock.Lock()
+	defer conf.reloadLock.Unlock()
+
+	prev := conf.reloaded
+	if prev == nil {
+		prev = conf
+	}
+	changed, err := changedKeys(prev, next)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReloadReport{}
+	reloadable := *conf.Reloadable()
+	defer func() {
+		// keys applied before a failure are kept
+		conf.reloadable.Store(&reloadable)
+		conf.reloaded = next
+	}()
+	for _, key := range changed {
+		if apply, ok := reloadableKeys[key]; ok {
+			if err := apply(conf, &reloadable, next); err != nil {
+				return report, fmt.Errorf("could not apply %s: %w", key, err)
+			}
+			report.Applied = append(report.Applied, key)
+		} else {
+			report.RequiresRestart = append(report.RequiresRestart, key)
+		}
+	}
+	return report, nil
+}
+
+func changedKeys(a, b *Config) ([]string, error) {
+	am, err := toYAMLMap(a)
+	if err != nil {
+		return nil, err
+	}
+	bm, err := toYAMLMap(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for key := range unionKeys(am, bm) {
+		if !nestedReloadKeys[key] {
+			if !reflect.DeepEqual(am[key], bm[key]) {
+				changed = append(changed, key)
+			}
+			continue
+		}
+
+		an, _ := am[key].(map[string]interface{})
+		bn, _ := bm[key].(map[string]interface{})
+		for nestedKey := range unionKeys(an, bn) {
+			if !reflect.DeepEqual(an[nestedKey], bn[nestedKey]) {
+				changed = append(changed, strings.Join([]string{key, nestedKey}, "."))
+			}
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+func toYAMLMap(conf *Config) (map[string]interface{}, error) {
+	marshalled, err := yaml.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]interface{})
+	if err = yaml.Unmarshal(marshalled, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func unionKeys(a, b map[string]interface{}) map[string]struct{} {
+	keys := make(map[string]struct{}, len(a)+len(b))
+	for k := range a {
+		keys[k] = struct{}{}
+	}
+	for k := range b {
+		keys[k] = struct{}{}
+	}
+	return keys
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Reload(t *testing.T) {
+	conf, err := NewConfig(`port: 7880
+logging:
+  level: info
+room:
+  empty_timeout: 300
+rtc:
+  tcp_port: 7881
+`, true, nil, nil)
+	require.NoError(t, err)
+
+	t.Run("unchanged", func(t *testing.T) {
+		next, err := NewConfig(`port: 7880
+logging:
+  level: info
+room:
+  empty_timeout: 300
+rtc:
+  tcp_port: 7881
+`, true, nil, nil)
+		require.NoError(t, err)
+
+		report, err := conf.Reload(next)
+		require.NoError(t, err)
+		require.False(t, report.Changed())
+	})
+
+	t.Run("reloadable and restart keys", func(t *testing.T) {
+		next, err := NewConfig(`port: 7890
+logging:
+  level: debug
+room:
+  empty_timeout: 60
+limit:
+  max_metadata_size: 1024
+webhook:
+  urls:
+    - http://localhost:8080/webhook
+rtc:
+  tcp_port: 7891
+  turn_servers:
+    - host: turn.example.com
+      port: 443
+      protocol: tls
+      username: user
+      credential: pass
+`, true, nil, nil)
+		require.NoError(t, err)
+
+		report, err := conf.Reload(next)
+		require.NoError(t, err)
+		require.Equal(t, []string{"limit", "logging", "room", "rtc.turn_servers", "webhook"}, report.Applied)
+		require.Equal(t, []string{"port", "rtc.tcp_port"}, report.RequiresRestart)
+
+		require.Equal(t, "debug", conf.Logging.Level)
+		reloadable := conf.Reloadable()
+		require.Equal(t, uint32(60), reloadable.Room.EmptyTimeout)
+		require.Equal(t, uint32(1024), reloadable.Limit.MaxMetadataSize)
+		require.Equal(t, []string{"http://localhost:8080/webhook"}, reloadable.WebHook.URLs)
+		require.Len(t, reloadable.TURNServers, 1)
+		require.Equal(t, "pass", reloadable.TURNServers[0].Credential)
+
+		// the settings loaded at startup are not modified, readers of the previous snapshot are unaffected
+		require.Equal(t, uint32(300), conf.Room.EmptyTimeout)
+
+		// settings that require a restart are left as they were
+		require.Equal(t, uint32(7880), conf.Port)
+		require.Equal(t, uint32(7881), conf.RTC.TCPPort)
+
+		// changes are compared with the previous reload
+		report, err = conf.Reload(next)
+		require.NoError(t, err)
+		require.False(t, report.Changed())
+	})
+}
+
+func TestConfig_ReloadConcurrentReaders(t *testing.T) {
+	conf, err := NewConfig("", true, nil, nil)
+	require.NoError(t, err)
+	next, err := NewConfig(`limit:
+  max_metadata_size: 1024
+`, true, nil, nil)
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			_ = conf.Reloadable().Limit.MaxMetadataSize
+		}
+	}()
+	_, err = conf.Reload(next)
+	require.NoError(t, err)
+	<-done
+	require.Equal(t, uint32(1024), conf.Reloadable().Limit.MaxMetadataSize)
+}
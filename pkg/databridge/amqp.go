@@ -0,0 +1,175 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package databridge
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/frostbyte73/core"
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	defaultAMQPExchange = "amq.topic"
+
+	amqpMinReconnectBackoff = time.Second
+	amqpMaxReconnectBackoff = 30 * time.Second
+)
+
+// amqpBroker publishes to and consumes from a topic exchange. when the connection is lost it reconnects
+// with backoff and restores its subscriptions, publishing fails while it is disconnected
+type amqpBroker struct {
+	url      string
+	exchange string
+	logger   logger.Logger
+	closed   core.Fuse
+
+	mu            sync.Mutex
+	conn          *amqp.Connection
+	channel       *amqp.Channel
+	subscriptions map[string]func(payload []byte)
+}
+
+func newAMQPBroker(conf config.DataBridgeConfig, logger logger.Logger) (*amqpBroker, error) {
+	exchange := conf.Exchange
+	if exchange == "" {
+		exchange = defaultAMQPExchange
+	}
+
+	b := &amqpBroker{
+		url:           conf.URL,
+		exchange:      exchange,
+		logger:        logger,
+		subscriptions: make(map[string]func(payload []byte)),
+	}
+	connClosed, err := b.connect()
+	if err != nil {
+		return nil, err
+	}
+	go b.reconnectWorker(connClosed)
+	return b, nil
+}
+
+// connect dials the broker and restores subscriptions, the returned channel reports the loss of the connection
+func (b *amqpBroker) connect() (chan *amqp.Error, error) {
+	conn, err := amqp.Dial(b.url)
+	if err != nil {
+		return nil, err
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed.IsBroken() {
+		_ = conn.Close()
+		return nil, amqp.ErrClosed
+	}
+	for topic, onMessage := range b.subscriptions {
+		if err := b.consume(ch, topic, onMessage); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	b.conn, b.channel = conn, ch
+	return connClosed, nil
+}
+
+func (b *amqpBroker) reconnectWorker(connClosed chan *amqp.Error) {
+	for {
+		// nil once the connection is closed by Close
+		lost := <-connClosed
+		if lost == nil || b.closed.IsBroken() {
+			return
+		}
+		b.logger.Infow("amqp connection lost", "error", lost)
+
+		var err error
+		for backoff := amqpMinReconnectBackoff; ; backoff = min(2*backoff, amqpMaxReconnectBackoff) {
+			select {
+			case <-b.closed.Watch():
+				return
+			case <-time.After(backoff):
+			}
+			if connClosed, err = b.connect(); err == nil {
+				break
+			}
+			b.logger.Infow("could not reconnect to amqp broker", "error", err, "backoff", backoff)
+		}
+		b.logger.Infow("amqp connection restored")
+	}
+}
+
+func (b *amqpBroker) Publish(topic string, payload []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.channel.PublishWithContext(ctx, b.exchange, topic, false, false, amqp.Publishing{
+		ContentType: "application/octet-stream",
+		Body:        payload,
+	})
+}
+
+func (b *amqpBroker) Subscribe(topic string, onMessage func(payload []byte)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscriptions[topic] = onMessage
+	return b.consume(b.channel, topic, onMessage)
+}
+
+// consume binds an exclusive queue to topic, deleted with the connection
+func (b *amqpBroker) consume(ch *amqp.Channel, topic string, onMessage func(payload []byte)) error {
+	q, err := ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		return err
+	}
+	if err = ch.QueueBind(q.Name, topic, b.exchange, false, nil); err != nil {
+		return err
+	}
+	deliveries, err := ch.Consume(q.Name, "", true, true, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for d := range deliveries {
+			onMessage(d.Body)
+		}
+	}()
+	return nil
+}
+
+func (b *amqpBroker) Close() {
+	b.closed.Break()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.conn.Close(); err != nil && err != amqp.ErrClosed {
+		b.logger.Infow("could not close amqp connection", "error", err)
+	}
+}
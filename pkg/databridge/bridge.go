@@ -0,0 +1,246 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package databridge
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/frostbyte73/core"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	ProtocolMQTT = "mqtt"
+	ProtocolAMQP = "amqp"
+
+	directionIn  = "in"
+	directionOut = "out"
+
+	// room messages waiting to be published to a broker, further messages are dropped
+	publishQueueSize = 256
+)
+
+// Room is what a bridge needs of a room
+type Room interface {
+	Name() livekit.RoomName
+	SendDataPacket(dp *livekit.DataPacket, kind livekit.DataPacket_Kind)
+	OnDataPacket(f func(kind livekit.DataPacket_Kind, dp *livekit.DataPacket))
+}
+
+// broker is a connection to an external message broker
+type broker interface {
+	Publish(topic string, payload []byte) error
+	// onMessage is called for every message published to topic, until the broker is closed
+	Subscribe(topic string, onMessage func(payload []byte)) error
+	Close()
+}
+
+// var to allow unit test override
+var newBroker = func(conf config.DataBridgeConfig, clientID string, logger logger.Logger) (broker, error) {
+	switch conf.Protocol {
+	case ProtocolMQTT:
+		return newMQTTBroker(conf, clientID, logger)
+	case ProtocolAMQP:
+		return newAMQPBroker(conf, logger)
+	default:
+		return nil, fmt.Errorf("unsupported data bridge protocol %q", conf.Protocol)
+	}
+}
+
+// Manager mirrors the data topics of rooms to the brokers configured for them
+type Manager struct {
+	configs []config.DataBridgeConfig
+	nodeID  livekit.NodeID
+}
+
+func NewManager(configs []config.DataBridgeConfig, nodeID livekit.NodeID) *Manager {
+	return &Manager{
+		configs: configs,
+		nodeID:  nodeID,
+	}
+}
+
+// AddRoom connects the bridges of a room in the background, the returned func stops them when the room closes
+func (m *Manager) AddRoom(room Room) func() {
+	var configs []config.DataBridgeConfig
+	for _, conf := range m.configs {
+		if strings.HasPrefix(string(room.Name()), conf.RoomPrefix) && len(conf.Topics) != 0 {
+			configs = append(configs, conf)
+		}
+	}
+	if len(configs) == 0 {
+		return func() {}
+	}
+
+	rb := &roomBridges{}
+	room.OnDataPacket(rb.forward)
+	go func() {
+		for _, conf := range configs {
+			b, err := newBridge(conf, room, m.nodeID)
+			if err != nil {
+				logger.Warnw("could not start data bridge", err, "room", room.Name(), "protocol", conf.Protocol)
+				continue
+			}
+			if !rb.add(b) {
+				b.close()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		room.OnDataPacket(nil)
+		rb.close()
+	}
+}
+
+type roomBridges struct {
+	mu      sync.RWMutex
+	closed  bool
+	bridges []*bridge
+}
+
+func (rb *roomBridges) add(b *bridge) bool {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	if rb.closed {
+		return false
+	}
+	rb.bridges = append(rb.bridges, b)
+	return true
+}
+
+func (rb *roomBridges) forward(_ livekit.DataPacket_Kind, dp *livekit.DataPacket) {
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
+	for _, b := range rb.bridges {
+		b.forward(dp)
+	}
+}
+
+func (rb *roomBridges) close() {
+	rb.mu.Lock()
+	bridges := rb.bridges
+	rb.bridges = nil
+	rb.closed = true
+	rb.mu.Unlock()
+
+	for _, b := range bridges {
+		b.close()
+	}
+}
+
+type bridge struct {
+	conf   config.DataBridgeConfig
+	room   Room
+	broker broker
+	logger logger.Logger
+
+	queue  chan brokerMessage
+	closed core.Fuse
+}
+
+type brokerMessage struct {
+	topic   string
+	payload []byte
+}
+
+func newBridge(conf config.DataBridgeConfig, room Room, nodeID livekit.NodeID) (*bridge, error) {
+	l := logger.GetLogger().WithValues("room", room.Name(), "protocol", conf.Protocol)
+	br, err := newBroker(conf, fmt.Sprintf("livekit-%s-%s", nodeID, room.Name()), l)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &bridge{
+		conf:   conf,
+		room:   room,
+		broker: br,
+		logger: l,
+		queue:  make(chan brokerMessage, publishQueueSize),
+	}
+	for _, topic := range conf.Topics {
+		if err := br.Subscribe(b.brokerTopic(topic, directionIn), func(payload []byte) {
+			b.receive(topic, payload)
+		}); err != nil {
+			br.Close()
+			return nil, err
+		}
+	}
+	go b.publishWorker()
+	l.Infow("data bridge started", "topics", conf.Topics)
+	return b, nil
+}
+
+// brokerTopic is <topic_prefix><room>/<data topic>/<direction>, joined with . for amqp routing keys
+func (b *bridge) brokerTopic(topic string, direction string) string {
+	sep := "/"
+	if b.conf.Protocol == ProtocolAMQP {
+		sep = "."
+	}
+	return b.conf.TopicPrefix + strings.Join([]string{string(b.room.Name()), topic, direction}, sep)
+}
+
+// forward queues messages sent to the whole room on a bridged topic for publishing,
+// it is called while the room sends data and must not block on the broker
+func (b *bridge) forward(dp *livekit.DataPacket) {
+	u := dp.GetUser()
+	if u == nil || len(dp.DestinationIdentities) != 0 || len(u.DestinationSids) != 0 || !slices.Contains(b.conf.Topics, u.GetTopic()) {
+		return
+	}
+	select {
+	case b.queue <- brokerMessage{topic: b.brokerTopic(u.GetTopic(), directionOut), payload: u.Payload}:
+	default:
+		b.logger.Debugw("data bridge queue full, dropping message", "topic", u.GetTopic())
+	}
+}
+
+func (b *bridge) publishWorker() {
+	for {
+		select {
+		case <-b.closed.Watch():
+			return
+		case m := <-b.queue:
+			if err := b.broker.Publish(m.topic, m.payload); err != nil && !b.closed.IsBroken() {
+				b.logger.Infow("could not forward data to broker", "error", err, "topic", m.topic)
+			}
+		}
+	}
+}
+
+func (b *bridge) close() {
+	b.closed.Break()
+	b.broker.Close()
+}
+
+// receive sends a broker message to the room as a reliable data message on its topic
+func (b *bridge) receive(topic string, payload []byte) {
+	b.room.SendDataPacket(&livekit.DataPacket{
+		Kind: livekit.DataPacket_RELIABLE,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload: payload,
+				Topic:   &topic,
+			},
+		},
+	}, livekit.DataPacket_RELIABLE)
+}
@@ -0,0 +1,222 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package databridge
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+type testBroker struct {
+	mu            sync.Mutex
+	published     map[string][][]byte
+	subscriptions map[string]func([]byte)
+	closed        bool
+}
+
+func (b *testBroker) Publish(topic string, payload []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.published[topic] = append(b.published[topic], payload)
+	return nil
+}
+
+func (b *testBroker) Subscribe(topic string, onMessage func(payload []byte)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscriptions[topic] = onMessage
+	return nil
+}
+
+func (b *testBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+}
+
+type testRoom struct {
+	name         livekit.RoomName
+	mu           sync.Mutex
+	sent         []*livekit.DataPacket
+	onDataPacket func(kind livekit.DataPacket_Kind, dp *livekit.DataPacket)
+}
+
+func (r *testRoom) Name() livekit.RoomName { return r.name }
+
+func (r *testRoom) SendDataPacket(dp *livekit.DataPacket, kind livekit.DataPacket_Kind) {
+	r.mu.Lock()
+	r.sent = append(r.sent, dp)
+	f := r.onDataPacket
+	r.mu.Unlock()
+	if f != nil {
+		f(kind, dp)
+	}
+}
+
+func (r *testRoom) OnDataPacket(f func(kind livekit.DataPacket_Kind, dp *livekit.DataPacket)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onDataPacket = f
+}
+
+func userPacket(topic string, payload string, dest ...string) *livekit.DataPacket {
+	return &livekit.DataPacket{
+		DestinationIdentities: dest,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{Topic: &topic, Payload: []byte(payload)},
+		},
+	}
+}
+
+func TestDataBridge(t *testing.T) {
+	brokers := make(map[string]*testBroker)
+	var mu sync.Mutex
+	newBroker = func(conf config.DataBridgeConfig, clientID string, _ logger.Logger) (broker, error) {
+		b := &testBroker{published: make(map[string][][]byte), subscriptions: make(map[string]func([]byte))}
+		mu.Lock()
+		brokers[conf.Protocol] = b
+		mu.Unlock()
+		return b, nil
+	}
+	getBroker := func(protocol string) *testBroker {
+		mu.Lock()
+		defer mu.Unlock()
+		return brokers[protocol]
+	}
+
+	m := NewManager([]config.DataBridgeConfig{
+		{RoomPrefix: "iot-", Protocol: ProtocolMQTT, Topics: []string{"telemetry", "commands"}, TopicPrefix: "livekit/"},
+		{RoomPrefix: "iot-", Protocol: ProtocolAMQP, Topics: []string{"telemetry"}},
+	}, "node")
+
+	t.Run("rooms not matching are not bridged", func(t *testing.T) {
+		room := &testRoom{name: "chat-1"}
+		m.AddRoom(room)()
+		require.Nil(t, room.onDataPacket)
+	})
+
+	room := &testRoom{name: "iot-1"}
+	stop := m.AddRoom(room)
+	require.Eventually(t, func() bool {
+		return getBroker(ProtocolMQTT) != nil && getBroker(ProtocolAMQP) != nil
+	}, time.Second, 10*time.Millisecond)
+	mqttBroker, amqpBroker := getBroker(ProtocolMQTT), getBroker(ProtocolAMQP)
+	require.Eventually(t, func() bool {
+		room.SendDataPacket(userPacket("telemetry", "probe"), livekit.DataPacket_RELIABLE)
+		amqpBroker.mu.Lock()
+		defer amqpBroker.mu.Unlock()
+		return len(amqpBroker.published["iot-1.telemetry.out"]) != 0
+	}, time.Second, 10*time.Millisecond)
+
+	t.Run("room messages are published", func(t *testing.T) {
+		room.SendDataPacket(userPacket("commands", "on"), livekit.DataPacket_RELIABLE)
+		room.SendDataPacket(userPacket("commands", "private", "p1"), livekit.DataPacket_RELIABLE)
+		room.SendDataPacket(userPacket("chat", "not bridged"), livekit.DataPacket_RELIABLE)
+
+		// published in the background
+		require.Eventually(t, func() bool {
+			mqttBroker.mu.Lock()
+			defer mqttBroker.mu.Unlock()
+			return len(mqttBroker.published["livekit/iot-1/commands/out"]) != 0
+		}, time.Second, 10*time.Millisecond)
+
+		mqttBroker.mu.Lock()
+		defer mqttBroker.mu.Unlock()
+		require.Equal(t, [][]byte{[]byte("on")}, mqttBroker.published["livekit/iot-1/commands/out"])
+		require.NotContains(t, mqttBroker.published, "livekit/iot-1/chat/out")
+		amqpBroker.mu.Lock()
+		defer amqpBroker.mu.Unlock()
+		require.NotContains(t, amqpBroker.published, "iot-1.commands.out")
+	})
+
+	t.Run("broker messages are sent to the room", func(t *testing.T) {
+		mqttBroker.mu.Lock()
+		onMessage := mqttBroker.subscriptions["livekit/iot-1/commands/in"]
+		mqttBroker.mu.Unlock()
+		require.NotNil(t, onMessage)
+		onMessage([]byte("off"))
+
+		room.mu.Lock()
+		defer room.mu.Unlock()
+		last := room.sent[len(room.sent)-1]
+		require.Equal(t, "commands", last.GetUser().GetTopic())
+		require.Equal(t, "off", string(last.GetUser().Payload))
+		require.Equal(t, livekit.DataPacket_RELIABLE, last.Kind)
+	})
+
+	stop()
+	require.True(t, mqttBroker.closed)
+	require.True(t, amqpBroker.closed)
+	require.Nil(t, room.onDataPacket)
+}
+
+type blockingBroker struct {
+	testBroker
+	unblock chan struct{}
+}
+
+func (b *blockingBroker) Publish(topic string, payload []byte) error {
+	<-b.unblock
+	return b.testBroker.Publish(topic, payload)
+}
+
+func TestDataBridgeSlowBroker(t *testing.T) {
+	br := &blockingBroker{
+		testBroker: testBroker{published: make(map[string][][]byte), subscriptions: make(map[string]func([]byte))},
+		unblock:    make(chan struct{}),
+	}
+	newBroker = func(config.DataBridgeConfig, string, logger.Logger) (broker, error) {
+		return br, nil
+	}
+
+	room := &testRoom{name: "iot-1"}
+	b, err := newBridge(config.DataBridgeConfig{Protocol: ProtocolMQTT, Topics: []string{"telemetry"}}, room, "node")
+	require.NoError(t, err)
+
+	// the room is not held up by a stalled broker, messages beyond the queue are dropped
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 2*publishQueueSize; i++ {
+			b.forward(userPacket("telemetry", "reading"))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.Fail(t, "forwarding blocked on the broker")
+	}
+
+	close(br.unblock)
+	require.Eventually(t, func() bool {
+		br.mu.Lock()
+		defer br.mu.Unlock()
+		return len(br.published["iot-1/telemetry/out"]) >= publishQueueSize
+	}, time.Second, 10*time.Millisecond)
+	b.close()
+
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	require.LessOrEqual(t, len(br.published["iot-1/telemetry/out"]), publishQueueSize+1)
+	require.True(t, br.closed)
+}
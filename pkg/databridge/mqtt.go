@@ -0,0 +1,108 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package databridge
+
+import (
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const mqttTimeout = 5 * time.Second
+
+var errMQTTTimeout = errors.New("mqtt operation timed out")
+
+type mqttBroker struct {
+	client mqtt.Client
+	logger logger.Logger
+
+	mu            sync.Mutex
+	subscriptions map[string]mqtt.MessageHandler
+}
+
+func newMQTTBroker(conf config.DataBridgeConfig, clientID string, logger logger.Logger) (*mqttBroker, error) {
+	u, err := url.Parse(conf.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(conf.URL).
+		SetClientID(clientID).
+		SetAutoReconnect(true).
+		SetCleanSession(true)
+	if u.User != nil {
+		opts.SetUsername(u.User.Username())
+		if password, ok := u.User.Password(); ok {
+			opts.SetPassword(password)
+		}
+	}
+	b := &mqttBroker{
+		logger:        logger,
+		subscriptions: make(map[string]mqtt.MessageHandler),
+	}
+	opts.SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+		logger.Infow("mqtt connection lost", "error", err)
+	})
+	opts.SetOnConnectHandler(b.resubscribe)
+
+	b.client = mqtt.NewClient(opts)
+	if err := wait(b.client.Connect()); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *mqttBroker) Publish(topic string, payload []byte) error {
+	return wait(b.client.Publish(topic, 0, false, payload))
+}
+
+func (b *mqttBroker) Subscribe(topic string, onMessage func(payload []byte)) error {
+	handler := func(_ mqtt.Client, m mqtt.Message) {
+		onMessage(m.Payload())
+	}
+	b.mu.Lock()
+	b.subscriptions[topic] = handler
+	b.mu.Unlock()
+
+	return wait(b.client.Subscribe(topic, 0, handler))
+}
+
+// resubscribe restores subscriptions after reconnecting, sessions are clean
+func (b *mqttBroker) resubscribe(client mqtt.Client) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for topic, handler := range b.subscriptions {
+		client.Subscribe(topic, 0, handler)
+	}
+}
+
+func (b *mqttBroker) Close() {
+	b.client.Disconnect(uint(mqttTimeout.Milliseconds()))
+}
+
+func wait(t mqtt.Token) error {
+	if !t.WaitTimeout(mqttTimeout) {
+		return errMQTTTimeout
+	}
+	return t.Error()
+}
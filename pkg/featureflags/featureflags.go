@@ -0,0 +1,219 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featureflags
+
+import (
+	"errors"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// Flag names an experimental behavior that can be gated per node, per room, or by percentage rollout
+type Flag string
+
+const (
+	// SendSideBWE negotiates transport-wide congestion control with subscribers instead of REMB
+	SendSideBWE Flag = "send_side_bwe"
+	// AV1SVC advertises AV1 as a scalable codec, clients publish it with scalable layers instead of simulcast
+	AV1SVC Flag = "av1_svc"
+)
+
+var ErrUnknownFlag = errors.New("unknown feature flag")
+
+const (
+	SourceDefault      = "default"
+	SourceConfig       = "config"
+	SourceRoom         = "room"
+	SourcePercentage   = "percentage"
+	SourceNodeOverride = "node_override"
+	SourceRoomOverride = "room_override"
+)
+
+type FlagStatus struct {
+	Flag    Flag   `json:"flag"`
+	Enabled bool   `json:"enabled"`
+	Source  string `json:"source"`
+}
+
+// Manager evaluates feature flags from config, with runtime overrides that are local to this node.
+// Overrides take precedence over config, and are lost on restart
+type Manager struct {
+	lock          sync.RWMutex
+	defaults      map[Flag]bool
+	flags         map[string]config.FeatureFlagConfig
+	nodeOverrides map[Flag]bool
+	roomOverrides map[Flag]map[livekit.RoomName]bool
+}
+
+func NewManager(conf *config.Config) *Manager {
+	m := &Manager{
+		defaults: map[Flag]bool{
+			SendSideBWE: conf.RTC.CongestionControl.UseSendSideBWE,
+			AV1SVC:      true,
+		},
+		flags:         conf.FeatureFlags,
+		nodeOverrides: make(map[Flag]bool),
+		roomOverrides: make(map[Flag]map[livekit.RoomName]bool),
+	}
+	m.warnUnknown(conf.FeatureFlags)
+	return m
+}
+
+// UpdateConfig replaces the configured flags, runtime overrides are kept
+func (m *Manager) UpdateConfig(flags map[string]config.FeatureFlagConfig) {
+	m.warnUnknown(flags)
+
+	m.lock.Lock()
+	m.flags = flags
+	m.lock.Unlock()
+}
+
+func (m *Manager) IsEnabled(flag Flag, roomName livekit.RoomName) bool {
+	status, err := m.Evaluate(flag, roomName)
+	return err == nil && status.Enabled
+}
+
+// Evaluate resolves a flag for a room, an empty room name evaluates the flag for the node
+func (m *Manager) Evaluate(flag Flag, roomName livekit.RoomName) (FlagStatus, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	def, ok := m.defaults[flag]
+	if !ok {
+		return FlagStatus{}, ErrUnknownFlag
+	}
+	status := func(enabled bool, source string) (FlagStatus, error) {
+		return FlagStatus{Flag: flag, Enabled: enabled, Source: source}, nil
+	}
+
+	if roomName != "" {
+		if enabled, ok := m.roomOverrides[flag][roomName]; ok {
+			return status(enabled, SourceRoomOverride)
+		}
+	}
+	if enabled, ok := m.nodeOverrides[flag]; ok {
+		return status(enabled, SourceNodeOverride)
+	}
+
+	conf, ok := m.flags[string(flag)]
+	if !ok {
+		return status(def, SourceDefault)
+	}
+	if roomName != "" {
+		if matchesRoom(conf.Rooms, roomName) {
+			return status(true, SourceRoom)
+		}
+		if conf.Percentage > 0 && inRollout(flag, roomName, conf.Percentage) {
+			return status(true, SourcePercentage)
+		}
+	}
+	if conf.Enabled != nil {
+		return status(*conf.Enabled, SourceConfig)
+	}
+	return status(def, SourceDefault)
+}
+
+// Statuses evaluates every known flag for a room, sorted by name
+func (m *Manager) Statuses(roomName livekit.RoomName) []FlagStatus {
+	m.lock.RLock()
+	flags := make([]Flag, 0, len(m.defaults))
+	for flag := range m.defaults {
+		flags = append(flags, flag)
+	}
+	m.lock.RUnlock()
+	sort.Slice(flags, func(i, j int) bool { return flags[i] < flags[j] })
+
+	statuses := make([]FlagStatus, 0, len(flags))
+	for _, flag := range flags {
+		if status, err := m.Evaluate(flag, roomName); err == nil {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// SetNodeOverride forces a flag on this node, nil clears the override
+func (m *Manager) SetNodeOverride(flag Flag, enabled *bool) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.defaults[flag]; !ok {
+		return ErrUnknownFlag
+	}
+	if enabled == nil {
+		delete(m.nodeOverrides, flag)
+	} else {
+		m.nodeOverrides[flag] = *enabled
+	}
+	return nil
+}
+
+// SetRoomOverride forces a flag for a room on this node, nil clears the override
+func (m *Manager) SetRoomOverride(flag Flag, roomName livekit.RoomName, enabled *bool) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.defaults[flag]; !ok {
+		return ErrUnknownFlag
+	}
+	if enabled == nil {
+		delete(m.roomOverrides[flag], roomName)
+		if len(m.roomOverrides[flag]) == 0 {
+			delete(m.roomOverrides, flag)
+		}
+		return nil
+	}
+	if m.roomOverrides[flag] == nil {
+		m.roomOverrides[flag] = make(map[livekit.RoomName]bool)
+	}
+	m.roomOverrides[flag][roomName] = *enabled
+	return nil
+}
+
+func (m *Manager) warnUnknown(flags map[string]config.FeatureFlagConfig) {
+	for name := range flags {
+		if _, ok := m.defaults[Flag(name)]; !ok {
+			logger.Warnw("ignoring unknown feature flag", nil, "flag", name)
+		}
+	}
+}
+
+func matchesRoom(rooms []string, roomName livekit.RoomName) bool {
+	for _, room := range rooms {
+		if prefix, ok := strings.CutSuffix(room, "*"); ok {
+			if strings.HasPrefix(string(roomName), prefix) {
+				return true
+			}
+		} else if room == string(roomName) {
+			return true
+		}
+	}
+	return false
+}
+
+// inRollout buckets rooms by flag and name, so that a room gets the same result on every node,
+// and raising the percentage only adds rooms
+func inRollout(flag Flag, roomName livekit.RoomName, percentage float64) bool {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(string(flag) + ":" + string(roomName)))
+	return float64(h.Sum32()%10000) < percentage*100
+}
@@ -0,0 +1,116 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featureflags
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestManager(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		conf := &config.Config{}
+		conf.RTC.CongestionControl.UseSendSideBWE = true
+		m := NewManager(conf)
+
+		require.True(t, m.IsEnabled(SendSideBWE, "room"))
+		require.True(t, m.IsEnabled(AV1SVC, "room"))
+		require.False(t, m.IsEnabled("unknown", "room"))
+
+		_, err := m.Evaluate("unknown", "room")
+		require.ErrorIs(t, err, ErrUnknownFlag)
+	})
+
+	t.Run("rooms", func(t *testing.T) {
+		m := NewManager(&config.Config{
+			FeatureFlags: map[string]config.FeatureFlagConfig{
+				string(SendSideBWE): {Rooms: []string{"exact", "beta-*"}},
+			},
+		})
+
+		require.True(t, m.IsEnabled(SendSideBWE, "exact"))
+		require.True(t, m.IsEnabled(SendSideBWE, "beta-1"))
+		require.False(t, m.IsEnabled(SendSideBWE, "exact-1"))
+		require.False(t, m.IsEnabled(SendSideBWE, ""))
+	})
+
+	t.Run("percentage", func(t *testing.T) {
+		m := NewManager(&config.Config{
+			FeatureFlags: map[string]config.FeatureFlagConfig{
+				string(SendSideBWE): {Percentage: 25},
+			},
+		})
+
+		enabled := 0
+		for i := 0; i < 1000; i++ {
+			roomName := livekit.RoomName(fmt.Sprintf("room-%d", i))
+			if m.IsEnabled(SendSideBWE, roomName) {
+				enabled++
+			}
+			// stable for a room
+			require.Equal(t, m.IsEnabled(SendSideBWE, roomName), m.IsEnabled(SendSideBWE, roomName))
+		}
+		require.InDelta(t, 250, enabled, 50)
+
+		// raising the percentage keeps rooms that were already enabled
+		m.UpdateConfig(map[string]config.FeatureFlagConfig{
+			string(SendSideBWE): {Percentage: 50},
+		})
+		for i := 0; i < 1000; i++ {
+			roomName := livekit.RoomName(fmt.Sprintf("room-%d", i))
+			if inRollout(SendSideBWE, roomName, 25) {
+				require.True(t, m.IsEnabled(SendSideBWE, roomName))
+			}
+		}
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		enabled, disabled := true, false
+		m := NewManager(&config.Config{
+			FeatureFlags: map[string]config.FeatureFlagConfig{
+				string(AV1SVC): {Enabled: &disabled, Rooms: []string{"room"}},
+			},
+		})
+
+		status, err := m.Evaluate(AV1SVC, "other")
+		require.NoError(t, err)
+		require.Equal(t, FlagStatus{Flag: AV1SVC, Enabled: false, Source: SourceConfig}, status)
+
+		require.NoError(t, m.SetNodeOverride(AV1SVC, &enabled))
+		status, err = m.Evaluate(AV1SVC, "other")
+		require.NoError(t, err)
+		require.Equal(t, FlagStatus{Flag: AV1SVC, Enabled: true, Source: SourceNodeOverride}, status)
+
+		require.NoError(t, m.SetRoomOverride(AV1SVC, "room", &disabled))
+		status, err = m.Evaluate(AV1SVC, "room")
+		require.NoError(t, err)
+		require.Equal(t, FlagStatus{Flag: AV1SVC, Enabled: false, Source: SourceRoomOverride}, status)
+
+		require.NoError(t, m.SetRoomOverride(AV1SVC, "room", nil))
+		require.NoError(t, m.SetNodeOverride(AV1SVC, nil))
+		status, err = m.Evaluate(AV1SVC, "room")
+		require.NoError(t, err)
+		require.Equal(t, FlagStatus{Flag: AV1SVC, Enabled: true, Source: SourceRoom}, status)
+
+		require.ErrorIs(t, m.SetNodeOverride("unknown", &enabled), ErrUnknownFlag)
+		require.Len(t, m.Statuses(""), 2)
+	})
+}
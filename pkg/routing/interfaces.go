@@ -17,6 +17,7 @@ package routing
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 	"google.golang.org/protobuf/proto"
@@ -25,6 +26,8 @@ import (
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/rpc"
+
+	"github.com/livekit/livekit-server/pkg/routing/selector"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
@@ -88,6 +91,16 @@ type Router interface {
 	Stop()
 }
 
+// NodeLabelProvider is implemented by routers that know the labels advertised by nodes
+type NodeLabelProvider interface {
+	ListNodeLabels() (map[livekit.NodeID]selector.NodeLabels, error)
+}
+
+// FailoverNotifier is implemented by routers that can detect a failover of their backing store
+type FailoverNotifier interface {
+	OnFailover(f func())
+}
+
 type StartParticipantSignalResults struct {
 	ConnectionID        livekit.ConnectionID
 	RequestSink         MessageSink
@@ -109,11 +122,17 @@ func CreateRouter(
 	signalClient SignalClient,
 	roomManagerClient RoomManagerClient,
 	kps rpc.KeepalivePubSub,
+	labels selector.NodeLabels,
+	failover *FailoverMonitor,
+	nodeLeaseTTL time.Duration,
 ) Router {
 	lr := NewLocalRouter(node, signalClient, roomManagerClient)
+	lr.labels = labels
 
 	if rc != nil {
-		return NewRedisRouter(lr, rc, kps)
+		rr := NewRedisRouter(lr, rc, kps, failover)
+		rr.SetNodeLeaseTTL(nodeLeaseTTL)
+		return rr
 	}
 
 	// local routing and store
@@ -24,6 +24,8 @@ import (
 
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/routing/selector"
 )
 
 var _ Router = (*LocalRouter)(nil)
@@ -33,6 +35,7 @@ type LocalRouter struct {
 	currentNode       LocalNode
 	signalClient      SignalClient
 	roomManagerClient RoomManagerClient
+	labels            selector.NodeLabels
 
 	lock sync.RWMutex
 	// channels for each participant
@@ -95,6 +98,12 @@ func (r *LocalRouter) ListNodes() ([]*livekit.Node, error) {
 	}, nil
 }
 
+func (r *LocalRouter) ListNodeLabels() (map[livekit.NodeID]selector.NodeLabels, error) {
+	return map[livekit.NodeID]selector.NodeLabels{
+		livekit.NodeID(r.currentNode.Id): r.labels,
+	}, nil
+}
+
 func (r *LocalRouter) CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest) (res *livekit.Room, err error) {
 	return r.CreateRoomWithNodeID(ctx, req, livekit.NodeID(r.currentNode.Id))
 }
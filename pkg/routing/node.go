@@ -15,7 +15,6 @@
 package routing
 
 import (
-	"runtime"
 	"time"
 
 	"github.com/livekit/protocol/livekit"
@@ -23,6 +22,7 @@ import (
 	"github.com/livekit/protocol/utils/guid"
 
 	"github.com/livekit/livekit-server/pkg/config"
+	sutils "github.com/livekit/livekit-server/pkg/utils"
 )
 
 type LocalNode *livekit.Node
@@ -35,7 +35,7 @@ func NewLocalNode(conf *config.Config) (LocalNode, error) {
 	node := &livekit.Node{
 		Id:      nodeID,
 		Ip:      conf.RTC.NodeIP,
-		NumCpus: uint32(runtime.NumCPU()),
+		NumCpus: uint32(sutils.NumCPUCeil()),
 		Region:  conf.Region,
 		State:   livekit.NodeState_SERVING,
 		Stats: &livekit.NodeStats{
@@ -0,0 +1,177 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package routing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+// FailoverMonitor watches a Redis client for Sentinel failovers. A failover is detected when new connections
+// reach a different master, at which point handlers are called to restore state that may have been lost with
+// unreplicated writes. It also tracks whether Redis is reachable, so that callers can keep retrying through
+// a failover instead of giving up. Methods are safe to call on a nil monitor.
+type FailoverMonitor struct {
+	lock             sync.Mutex
+	master           string
+	unavailableSince time.Time
+	handlers         []func()
+}
+
+// NewFailoverMonitor installs a FailoverMonitor on the client. Cluster clients are not monitored since
+// they connect to several masters by design.
+func NewFailoverMonitor(rc redis.UniversalClient) *FailoverMonitor {
+	if rc == nil {
+		return nil
+	}
+	if _, ok := rc.(*redis.ClusterClient); ok {
+		return nil
+	}
+
+	m := &FailoverMonitor{}
+	rc.AddHook(m)
+	return m
+}
+
+// OnFailover registers a handler called, in its own goroutine, after each failover
+func (m *FailoverMonitor) OnFailover(f func()) {
+	if m == nil {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.handlers = append(m.handlers, f)
+}
+
+// IsFailingOver returns true while connections to Redis are failing
+func (m *FailoverMonitor) IsFailingOver() bool {
+	if m == nil {
+		return false
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return !m.unavailableSince.IsZero()
+}
+
+func (m *FailoverMonitor) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			m.setUnavailable(err)
+			return nil, err
+		}
+
+		m.setMaster(conn.RemoteAddr().String())
+		return conn, nil
+	}
+}
+
+func (m *FailoverMonitor) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		m.handleResult(err)
+		return err
+	}
+}
+
+func (m *FailoverMonitor) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		m.handleResult(err)
+		return err
+	}
+}
+
+func (m *FailoverMonitor) handleResult(err error) {
+	if isConnectionError(err) {
+		m.setUnavailable(err)
+	} else {
+		m.setAvailable()
+	}
+}
+
+func (m *FailoverMonitor) setUnavailable(err error) {
+	prometheus.RecordRedisError()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.unavailableSince.IsZero() {
+		logger.Warnw("redis unavailable", err, "master", m.master)
+		m.unavailableSince = time.Now()
+		prometheus.SetRedisAvailable(false)
+	}
+}
+
+func (m *FailoverMonitor) setAvailable() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.setAvailableLocked()
+}
+
+func (m *FailoverMonitor) setAvailableLocked() {
+	if !m.unavailableSince.IsZero() {
+		logger.Infow("redis available", "master", m.master, "unavailableFor", time.Since(m.unavailableSince))
+		m.unavailableSince = time.Time{}
+		prometheus.SetRedisAvailable(true)
+	}
+}
+
+func (m *FailoverMonitor) setMaster(addr string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.setAvailableLocked()
+
+	prev := m.master
+	m.master = addr
+	if prev == "" || prev == addr {
+		return
+	}
+
+	logger.Infow("redis failover detected", "previousMaster", prev, "master", addr)
+	prometheus.RecordRedisFailover()
+	for _, f := range m.handlers {
+		go f()
+	}
+}
+
+func isConnectionError(err error) bool {
+	if err == nil || errors.Is(err, redis.Nil) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	// a demoted master rejects writes until clients reconnect to the new one
+	return strings.HasPrefix(err.Error(), "READONLY") || strings.HasPrefix(err.Error(), "LOADING")
+}
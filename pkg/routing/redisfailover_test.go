@@ -0,0 +1,66 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routing
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailoverMonitor(t *testing.T) {
+	t.Run("nil monitor is a no-op", func(t *testing.T) {
+		var m *FailoverMonitor
+		m.OnFailover(func() {})
+		require.False(t, m.IsFailingOver())
+	})
+
+	t.Run("handlers called when master changes", func(t *testing.T) {
+		m := &FailoverMonitor{}
+		called := make(chan struct{}, 1)
+		m.OnFailover(func() { called <- struct{}{} })
+
+		m.setMaster("10.0.0.1:6379")
+		m.setMaster("10.0.0.1:6379")
+		select {
+		case <-called:
+			t.Fatal("handler called without failover")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		m.handleResult(io.EOF)
+		require.True(t, m.IsFailingOver())
+
+		m.setMaster("10.0.0.2:6379")
+		require.False(t, m.IsFailingOver())
+		select {
+		case <-called:
+		case <-time.After(time.Second):
+			t.Fatal("handler not called after failover")
+		}
+	})
+
+	t.Run("connection errors", func(t *testing.T) {
+		require.False(t, isConnectionError(nil))
+		require.False(t, isConnectionError(redis.Nil))
+		require.False(t, isConnectionError(errors.New("WRONGTYPE Operation against a key")))
+		require.True(t, isConnectionError(io.EOF))
+		require.True(t, isConnectionError(errors.New("READONLY You can't write against a read only replica.")))
+	})
+}
@@ -17,6 +17,7 @@ package routing
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"runtime/pprof"
 	"sync"
 	"time"
@@ -29,6 +30,7 @@ import (
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
 
 	"github.com/livekit/livekit-server/pkg/routing/selector"
 	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
@@ -39,12 +41,24 @@ const (
 	participantMappingTTL = 24 * time.Hour
 	statsUpdateInterval   = 2 * time.Second
 	statsMaxDelaySeconds  = 30
+	// resubscribe to keepalives when none were received for this long
+	keepaliveResubscribeTimeout = 3 * statsUpdateInterval
 
 	// hash of node_id => Node proto
 	NodesKey = "nodes"
 
 	// hash of room_name => node_id
 	NodeRoomKey = "room_node_map"
+
+	// hash of node_id => labels json
+	NodeLabelsKey = "node_labels"
+
+	// hash of node_id => lease ttl, for nodes registered with a lease
+	NodeLeasesKey = "node_leases"
+	// node_lease:<node_id> expires when the node stops renewing it
+	NodeLeaseKeyPrefix = "node_lease:"
+	// the lease is renewed with stats, it has to outlive a few missed updates
+	minNodeLeaseTTL = 3 * statsUpdateInterval
 )
 
 var _ Router = (*RedisRouter)(nil)
@@ -57,25 +71,54 @@ type RedisRouter struct {
 
 	rc        redis.UniversalClient
 	kps       rpc.KeepalivePubSub
+	failover  *FailoverMonitor
 	ctx       context.Context
 	isStarted atomic.Bool
 	nodeMu    sync.RWMutex
 	// previous stats for computing averages
 	prevStats *livekit.NodeStats
+	// signaled to resubscribe to keepalives after a failover
+	resubscribe chan struct{}
+	// nodes registered with a lease are dropped when it expires, 0 disables the lease
+	nodeLeaseTTL time.Duration
 
 	cancel func()
 }
 
-func NewRedisRouter(lr *LocalRouter, rc redis.UniversalClient, kps rpc.KeepalivePubSub) *RedisRouter {
+func NewRedisRouter(lr *LocalRouter, rc redis.UniversalClient, kps rpc.KeepalivePubSub, failover *FailoverMonitor) *RedisRouter {
 	rr := &RedisRouter{
 		LocalRouter: lr,
 		rc:          rc,
 		kps:         kps,
+		failover:    failover,
+		resubscribe: make(chan struct{}, 1),
 	}
 	rr.ctx, rr.cancel = context.WithCancel(context.Background())
+	failover.OnFailover(rr.handleFailover)
 	return rr
 }
 
+// OnFailover registers a handler called after a Redis failover, to restore state that may have been lost
+func (r *RedisRouter) OnFailover(f func()) {
+	r.failover.OnFailover(f)
+}
+
+func (r *RedisRouter) handleFailover() {
+	if !r.isStarted.Load() {
+		return
+	}
+
+	// writes that were not replicated before the failover are lost, register again right away
+	if err := r.RegisterNode(); err != nil {
+		logger.Errorw("could not register node after failover", err)
+	}
+
+	select {
+	case r.resubscribe <- struct{}{}:
+	default:
+	}
+}
+
 func (r *RedisRouter) RegisterNode() error {
 	r.nodeMu.RLock()
 	data, err := proto.Marshal((*livekit.Node)(r.currentNode))
@@ -86,22 +129,90 @@ func (r *RedisRouter) RegisterNode() error {
 	if err := r.rc.HSet(r.ctx, NodesKey, r.currentNode.Id, data).Err(); err != nil {
 		return errors.Wrap(err, "could not register node")
 	}
+	if r.nodeLeaseTTL > 0 {
+		pp := r.rc.Pipeline()
+		pp.HSet(r.ctx, NodeLeasesKey, r.currentNode.Id, r.nodeLeaseTTL.String())
+		pp.Set(r.ctx, NodeLeaseKeyPrefix+r.currentNode.Id, time.Now().Unix(), r.nodeLeaseTTL)
+		if _, err := pp.Exec(r.ctx); err != nil {
+			return errors.Wrap(err, "could not register node lease")
+		}
+	}
+	if len(r.labels) != 0 {
+		labels, err := json.Marshal(r.labels)
+		if err != nil {
+			return err
+		}
+		if err := r.rc.HSet(r.ctx, NodeLabelsKey, r.currentNode.Id, labels).Err(); err != nil {
+			return errors.Wrap(err, "could not register node labels")
+		}
+	}
 	return nil
 }
 
 func (r *RedisRouter) UnregisterNode() error {
 	// could be called after Stop(), so we'd want to use an unrelated context
-	return r.rc.HDel(context.Background(), NodesKey, r.currentNode.Id).Err()
+	return r.removeNode(context.Background(), r.currentNode.Id)
+}
+
+func (r *RedisRouter) removeNode(ctx context.Context, nodeID string) error {
+	pp := r.rc.Pipeline()
+	pp.HDel(ctx, NodesKey, nodeID)
+	pp.HDel(ctx, NodeLabelsKey, nodeID)
+	pp.HDel(ctx, NodeLeasesKey, nodeID)
+	pp.Del(ctx, NodeLeaseKeyPrefix+nodeID)
+	_, err := pp.Exec(ctx)
+	return err
+}
+
+// SetNodeLeaseTTL registers this node with a lease that is renewed with its stats. Other nodes treat the node as
+// dead once the lease expires, and stop routing to it even before its stats are considered stale
+func (r *RedisRouter) SetNodeLeaseTTL(ttl time.Duration) {
+	if ttl > 0 && ttl < minNodeLeaseTTL {
+		logger.Warnw("node lease ttl too short, using minimum", nil, "ttl", ttl, "minimum", minNodeLeaseTTL)
+		ttl = minNodeLeaseTTL
+	}
+	r.nodeLeaseTTL = ttl
+}
+
+// expiredLeases returns the nodes registered with a lease that has expired
+func (r *RedisRouter) expiredLeases() (map[string]bool, error) {
+	leased, err := r.rc.HKeys(r.ctx, NodeLeasesKey).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list node leases")
+	}
+	if len(leased) == 0 {
+		return nil, nil
+	}
+
+	pp := r.rc.Pipeline()
+	exists := make([]*redis.IntCmd, len(leased))
+	for i, nodeID := range leased {
+		exists[i] = pp.Exists(r.ctx, NodeLeaseKeyPrefix+nodeID)
+	}
+	if _, err = pp.Exec(r.ctx); err != nil {
+		return nil, errors.Wrap(err, "could not list node leases")
+	}
+	expired := make(map[string]bool)
+	for i, nodeID := range leased {
+		if exists[i].Val() == 0 {
+			expired[nodeID] = true
+		}
+	}
+	return expired, nil
 }
 
 func (r *RedisRouter) RemoveDeadNodes() error {
-	nodes, err := r.ListNodes()
+	nodes, err := r.listNodes()
+	if err != nil {
+		return err
+	}
+	expired, err := r.expiredLeases()
 	if err != nil {
 		return err
 	}
 	for _, n := range nodes {
-		if !selector.IsAvailable(n) {
-			if err := r.rc.HDel(context.Background(), NodesKey, n.Id).Err(); err != nil {
+		if !selector.IsAvailable(n) || expired[n.Id] {
+			if err := r.removeNode(context.Background(), n.Id); err != nil {
 				return err
 			}
 		}
@@ -146,7 +257,29 @@ func (r *RedisRouter) GetNode(nodeID livekit.NodeID) (*livekit.Node, error) {
 	return &n, nil
 }
 
+// ListNodes returns registered nodes, except for those whose lease has expired
 func (r *RedisRouter) ListNodes() ([]*livekit.Node, error) {
+	nodes, err := r.listNodes()
+	if err != nil {
+		return nil, err
+	}
+	expired, err := r.expiredLeases()
+	if err != nil {
+		return nil, err
+	}
+	if len(expired) == 0 {
+		return nodes, nil
+	}
+	live := nodes[:0]
+	for _, n := range nodes {
+		if !expired[n.Id] {
+			live = append(live, n)
+		}
+	}
+	return live, nil
+}
+
+func (r *RedisRouter) listNodes() ([]*livekit.Node, error) {
 	items, err := r.rc.HVals(r.ctx, NodesKey).Result()
 	if err != nil {
 		return nil, errors.Wrap(err, "could not list nodes")
@@ -162,6 +295,22 @@ func (r *RedisRouter) ListNodes() ([]*livekit.Node, error) {
 	return nodes, nil
 }
 
+func (r *RedisRouter) ListNodeLabels() (map[livekit.NodeID]selector.NodeLabels, error) {
+	items, err := r.rc.HGetAll(r.ctx, NodeLabelsKey).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list node labels")
+	}
+	labels := make(map[livekit.NodeID]selector.NodeLabels, len(items))
+	for nodeID, item := range items {
+		var l selector.NodeLabels
+		if err := json.Unmarshal([]byte(item), &l); err != nil {
+			return nil, err
+		}
+		labels[livekit.NodeID(nodeID)] = l
+	}
+	return labels, nil
+}
+
 func (r *RedisRouter) CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest) (res *livekit.Room, err error) {
 	rtcNode, err := r.GetNodeForRoom(ctx, livekit.RoomName(req.Name))
 	if err != nil {
@@ -212,6 +361,16 @@ func (r *RedisRouter) Stop() {
 	r.cancel()
 }
 
+func (r *RedisRouter) resubscribeKeepalive(pings psrpc.Subscription[*rpc.KeepalivePing]) psrpc.Subscription[*rpc.KeepalivePing] {
+	resubscribed, err := r.kps.SubscribePing(r.ctx, livekit.NodeID(r.currentNode.Id))
+	if err != nil {
+		logger.Errorw("could not resubscribe to keepalive", err)
+		return pings
+	}
+	pings.Close()
+	return resubscribed
+}
+
 // update node stats and cleanup
 func (r *RedisRouter) statsWorker() {
 	goroutineDumped := false
@@ -252,7 +411,38 @@ func (r *RedisRouter) keepaliveWorker(startedChan chan error) {
 	}
 	close(startedChan)
 
-	for ping := range pings.Channel() {
+	ticker := time.NewTicker(statsUpdateInterval)
+	defer ticker.Stop()
+
+	lastPing := time.Now()
+	for {
+		var ping *rpc.KeepalivePing
+		select {
+		case <-r.ctx.Done():
+			pings.Close()
+			return
+
+		case p, ok := <-pings.Channel():
+			if !ok {
+				return
+			}
+			ping = p
+			lastPing = time.Now()
+
+		case <-ticker.C:
+			if time.Since(lastPing) > keepaliveResubscribeTimeout {
+				logger.Warnw("keepalive not received, resubscribing", nil, "lastPing", lastPing)
+				pings = r.resubscribeKeepalive(pings)
+				lastPing = time.Now()
+			}
+			continue
+
+		case <-r.resubscribe:
+			pings = r.resubscribeKeepalive(pings)
+			lastPing = time.Now()
+			continue
+		}
+
 		if time.Since(time.Unix(ping.Timestamp, 0)) > statsUpdateInterval {
 			logger.Infow("keep alive too old, skipping", "timestamp", ping.Timestamp)
 			continue
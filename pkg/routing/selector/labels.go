@@ -0,0 +1,48 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package selector
+
+import (
+	"github.com/livekit/protocol/livekit"
+)
+
+// NodeLabels are arbitrary key/values advertised by a node, such as hardware or bandwidth tier
+type NodeLabels map[string]string
+
+// Matches returns true when the node has every label in constraints with the same value.
+// A constraint with an empty value only requires the label to be present.
+func (l NodeLabels) Matches(constraints map[string]string) bool {
+	for k, v := range constraints {
+		lv, ok := l[k]
+		if !ok || (v != "" && lv != v) {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterByLabels returns nodes whose labels match constraints
+func FilterByLabels(nodes []*livekit.Node, labels map[livekit.NodeID]NodeLabels, constraints map[string]string) []*livekit.Node {
+	if len(constraints) == 0 {
+		return nodes
+	}
+
+	matching := make([]*livekit.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if labels[livekit.NodeID(node.Id)].Matches(constraints) {
+			matching = append(matching, node)
+		}
+	}
+	return matching
+}
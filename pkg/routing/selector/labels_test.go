@@ -0,0 +1,52 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package selector_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+)
+
+func TestFilterByLabels(t *testing.T) {
+	nodes := []*livekit.Node{{Id: "gpu"}, {Id: "premium"}, {Id: "unlabeled"}}
+	labels := map[livekit.NodeID]selector.NodeLabels{
+		"gpu":     {"gpu": "a100", "tier": "standard"},
+		"premium": {"tier": "premium"},
+	}
+
+	t.Run("no constraints", func(t *testing.T) {
+		require.Equal(t, nodes, selector.FilterByLabels(nodes, labels, nil))
+	})
+
+	t.Run("value must match", func(t *testing.T) {
+		matching := selector.FilterByLabels(nodes, labels, map[string]string{"tier": "premium"})
+		require.Len(t, matching, 1)
+		require.Equal(t, "premium", matching[0].Id)
+	})
+
+	t.Run("empty value requires presence", func(t *testing.T) {
+		matching := selector.FilterByLabels(nodes, labels, map[string]string{"gpu": ""})
+		require.Len(t, matching, 1)
+		require.Equal(t, "gpu", matching[0].Id)
+	})
+
+	t.Run("all constraints must match", func(t *testing.T) {
+		require.Empty(t, selector.FilterByLabels(nodes, labels, map[string]string{"gpu": "", "tier": "premium"}))
+	})
+}
@@ -45,13 +45,14 @@ type SignalClient interface {
 }
 
 type signalClient struct {
-	nodeID livekit.NodeID
-	config config.SignalRelayConfig
-	client rpc.TypedSignalClient
-	active atomic.Int32
+	nodeID   livekit.NodeID
+	config   config.SignalRelayConfig
+	failover *FailoverMonitor
+	client   rpc.TypedSignalClient
+	active   atomic.Int32
 }
 
-func NewSignalClient(nodeID livekit.NodeID, bus psrpc.MessageBus, config config.SignalRelayConfig) (SignalClient, error) {
+func NewSignalClient(nodeID livekit.NodeID, bus psrpc.MessageBus, config config.SignalRelayConfig, failover *FailoverMonitor) (SignalClient, error) {
 	c, err := rpc.NewTypedSignalClient(
 		nodeID,
 		bus,
@@ -63,9 +64,10 @@ func NewSignalClient(nodeID livekit.NodeID, bus psrpc.MessageBus, config config.
 	}
 
 	return &signalClient{
-		nodeID: nodeID,
-		config: config,
-		client: c,
+		nodeID:   nodeID,
+		config:   config,
+		failover: failover,
+		client:   c,
 	}, nil
 }
 
@@ -116,6 +118,7 @@ func (r *signalClient) StartParticipantSignal(
 		Logger:         l,
 		Stream:         stream,
 		Config:         r.config,
+		Failover:       r.failover,
 		Writer:         signalRequestMessageWriter{},
 		CloseOnFailure: true,
 		BlockOnClose:   true,
@@ -242,6 +245,7 @@ type SignalSinkParams[SendType, RecvType RelaySignalMessage] struct {
 	Stream         psrpc.Stream[SendType, RecvType]
 	Logger         logger.Logger
 	Config         config.SignalRelayConfig
+	Failover       *FailoverMonitor
 	Writer         SignalMessageWriter[SendType]
 	CloseOnFailure bool
 	BlockOnClose   bool
@@ -295,6 +299,7 @@ func (s *signalMessageSink[SendType, RecvType]) IsClosed() bool {
 func (s *signalMessageSink[SendType, RecvType]) write() {
 	interval := s.Config.MinRetryInterval
 	deadline := time.Now().Add(s.Config.RetryTimeout)
+	extended := false
 	var err error
 
 	s.mu.Lock()
@@ -308,6 +313,12 @@ func (s *signalMessageSink[SendType, RecvType]) write() {
 
 		err = s.Stream.Send(msg, psrpc.WithTimeout(interval))
 		if err != nil {
+			// keep messages queued while redis fails over, they are sent once the new master is reachable
+			if time.Now().After(deadline) && !extended && s.Failover.IsFailingOver() {
+				s.Logger.Infow("redis failing over, extending signal retry deadline")
+				deadline = deadline.Add(s.Config.FailoverRetryTimeout)
+				extended = true
+			}
 			if time.Now().After(deadline) {
 				s.Logger.Warnw("could not send signal message", err)
 
@@ -327,6 +338,7 @@ func (s *signalMessageSink[SendType, RecvType]) write() {
 		if err == nil {
 			interval = s.Config.MinRetryInterval
 			deadline = time.Now().Add(s.Config.RetryTimeout)
+			extended = false
 
 			s.seq += uint64(n)
 			s.queue = s.queue[n:]
@@ -0,0 +1,292 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routing
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+const signalRecordingRedacted = "<redacted>"
+
+var ErrInvalidSignalRecording = errors.New("invalid signal recording")
+
+// SignalRecordingHeader is the first line of a signal recording and describes the session
+type SignalRecordingHeader struct {
+	Room          livekit.RoomName            `json:"room"`
+	Identity      livekit.ParticipantIdentity `json:"identity"`
+	Protocol      int32                       `json:"protocol"`
+	AutoSubscribe bool                        `json:"auto_subscribe"`
+	Reconnect     bool                        `json:"reconnect,omitempty"`
+	StartedAt     time.Time                   `json:"started_at"`
+}
+
+// SignalRecordingEntry is a signal message received (request) or sent (response) during a session
+type SignalRecordingEntry struct {
+	Offset   time.Duration
+	Request  *livekit.SignalRequest
+	Response *livekit.SignalResponse
+}
+
+type signalRecordingLine struct {
+	OffsetMs int64           `json:"offset_ms"`
+	Request  json.RawMessage `json:"request,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// SignalRecorder writes the signal messages of a participant session to a file, one JSON object per line.
+// Tokens and TURN credentials are scrubbed from the recorded messages.
+type SignalRecorder struct {
+	lock      sync.Mutex
+	file      *os.File
+	w         *bufio.Writer
+	startedAt time.Time
+	closed    bool
+}
+
+func NewSignalRecorder(dir string, roomName livekit.RoomName, pi ParticipantInit) (*SignalRecorder, error) {
+	// recordings carry SDP and ICE candidates, only the server user may read them
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	startedAt := time.Now()
+	name := fmt.Sprintf("%s_%s_%d.jsonl", url.PathEscape(string(roomName)), url.PathEscape(string(pi.Identity)), startedAt.UnixMilli())
+	file, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &SignalRecorder{
+		file:      file,
+		w:         bufio.NewWriter(file),
+		startedAt: startedAt,
+	}
+	header, err := json.Marshal(SignalRecordingHeader{
+		Room:          roomName,
+		Identity:      pi.Identity,
+		Protocol:      pi.Client.GetProtocol(),
+		AutoSubscribe: pi.AutoSubscribe,
+		Reconnect:     pi.Reconnect,
+		StartedAt:     startedAt,
+	})
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	r.writeLine(header)
+	return r, nil
+}
+
+func (r *SignalRecorder) Path() string {
+	return r.file.Name()
+}
+
+// WrapSource records requests read from the source, the recording is closed when the source is
+func (r *SignalRecorder) WrapSource(source MessageSource) MessageSource {
+	s := &recordingMessageSource{
+		MessageSource: source,
+		msgChan:       make(chan proto.Message, DefaultMessageChannelSize),
+	}
+	go func() {
+		defer close(s.msgChan)
+		defer r.Close()
+
+		for msg := range source.ReadChan() {
+			if req, ok := msg.(*livekit.SignalRequest); ok {
+				r.record(req, true)
+			}
+			s.msgChan <- msg
+		}
+	}()
+	return s
+}
+
+// WrapSink records responses written to the sink
+func (r *SignalRecorder) WrapSink(sink MessageSink) MessageSink {
+	return &recordingMessageSink{
+		MessageSink: sink,
+		recorder:    r,
+	}
+}
+
+func (r *SignalRecorder) Close() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.closed {
+		return
+	}
+	r.closed = true
+	_ = r.w.Flush()
+	_ = r.file.Close()
+}
+
+func (r *SignalRecorder) record(msg proto.Message, isRequest bool) {
+	if res, ok := msg.(*livekit.SignalResponse); ok {
+		msg = scrubSignalResponse(res)
+	}
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		logger.Warnw("could not record signal message", err)
+		return
+	}
+
+	line := &signalRecordingLine{
+		OffsetMs: time.Since(r.startedAt).Milliseconds(),
+	}
+	if isRequest {
+		line.Request = data
+	} else {
+		line.Response = data
+	}
+	encoded, err := json.Marshal(line)
+	if err != nil {
+		logger.Warnw("could not record signal message", err)
+		return
+	}
+	r.writeLine(encoded)
+}
+
+func (r *SignalRecorder) writeLine(line []byte) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.closed {
+		return
+	}
+	_, _ = r.w.Write(line)
+	_ = r.w.WriteByte('\n')
+}
+
+// ReadSignalRecording reads a recording written by SignalRecorder
+func ReadSignalRecording(path string) (*SignalRecordingHeader, []SignalRecordingEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	if !scanner.Scan() {
+		return nil, nil, ErrInvalidSignalRecording
+	}
+	header := &SignalRecordingHeader{}
+	if err := json.Unmarshal(scanner.Bytes(), header); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSignalRecording, err)
+	}
+
+	var entries []SignalRecordingEntry
+	for scanner.Scan() {
+		var line signalRecordingLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSignalRecording, err)
+		}
+
+		entry := SignalRecordingEntry{Offset: time.Duration(line.OffsetMs) * time.Millisecond}
+		switch {
+		case line.Request != nil:
+			entry.Request = &livekit.SignalRequest{}
+			err = protojson.Unmarshal(line.Request, entry.Request)
+		case line.Response != nil:
+			entry.Response = &livekit.SignalResponse{}
+			err = protojson.Unmarshal(line.Response, entry.Response)
+		default:
+			err = errors.New("entry without message")
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSignalRecording, err)
+		}
+		entries = append(entries, entry)
+	}
+	return header, entries, scanner.Err()
+}
+
+func scrubSignalResponse(res *livekit.SignalResponse) proto.Message {
+	switch res.Message.(type) {
+	case *livekit.SignalResponse_RefreshToken, *livekit.SignalResponse_Join, *livekit.SignalResponse_Reconnect, *livekit.SignalResponse_RoomUpdate:
+	default:
+		return res
+	}
+
+	var (
+		room       *livekit.Room
+		iceServers []*livekit.ICEServer
+	)
+	scrubbed := proto.Clone(res).(*livekit.SignalResponse)
+	switch msg := scrubbed.Message.(type) {
+	case *livekit.SignalResponse_RefreshToken:
+		msg.RefreshToken = signalRecordingRedacted
+	case *livekit.SignalResponse_Join:
+		room = msg.Join.Room
+		iceServers = msg.Join.IceServers
+	case *livekit.SignalResponse_Reconnect:
+		iceServers = msg.Reconnect.IceServers
+	case *livekit.SignalResponse_RoomUpdate:
+		room = msg.RoomUpdate.Room
+	}
+	if room != nil && room.TurnPassword != "" {
+		room.TurnPassword = signalRecordingRedacted
+	}
+	for _, s := range iceServers {
+		if s.Username != "" {
+			s.Username = signalRecordingRedacted
+		}
+		if s.Credential != "" {
+			s.Credential = signalRecordingRedacted
+		}
+	}
+	return scrubbed
+}
+
+// ---------------------------------------------
+
+type recordingMessageSource struct {
+	MessageSource
+
+	msgChan chan proto.Message
+}
+
+func (s *recordingMessageSource) ReadChan() <-chan proto.Message {
+	return s.msgChan
+}
+
+type recordingMessageSink struct {
+	MessageSink
+
+	recorder *SignalRecorder
+}
+
+func (s *recordingMessageSink) WriteMessage(msg proto.Message) error {
+	err := s.MessageSink.WriteMessage(msg)
+	if res, ok := msg.(*livekit.SignalResponse); ok && err == nil {
+		s.recorder.record(res, false)
+	}
+	return err
+}
@@ -0,0 +1,84 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routing_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+)
+
+func TestSignalRecorder(t *testing.T) {
+	recorder, err := routing.NewSignalRecorder(t.TempDir(), "room/1", routing.ParticipantInit{
+		Identity:      "alice",
+		AutoSubscribe: true,
+		Client:        &livekit.ClientInfo{Protocol: 12},
+	})
+	require.NoError(t, err)
+
+	requests := routing.NewDefaultMessageChannel("test")
+	responses := routing.NewDefaultMessageChannel("test")
+	source := recorder.WrapSource(requests)
+	sink := recorder.WrapSink(responses)
+
+	require.NoError(t, sink.WriteMessage(&livekit.SignalResponse{
+		Message: &livekit.SignalResponse_Join{
+			Join: &livekit.JoinResponse{
+				Room:       &livekit.Room{Name: "room/1", TurnPassword: "secret"},
+				IceServers: []*livekit.ICEServer{{Urls: []string{"turn:turn.example.com"}, Username: "user", Credential: "secret"}},
+			},
+		},
+	}))
+	offer := &livekit.SignalRequest{
+		Message: &livekit.SignalRequest_Offer{Offer: &livekit.SessionDescription{Type: "offer", Sdp: "v=0"}},
+	}
+	require.NoError(t, requests.WriteMessage(offer))
+	require.Equal(t, "offer", (<-source.ReadChan()).(*livekit.SignalRequest).GetOffer().Type)
+	require.NoError(t, sink.WriteMessage(&livekit.SignalResponse{
+		Message: &livekit.SignalResponse_RefreshToken{RefreshToken: "token"},
+	}))
+
+	// closing the source ends the recording
+	requests.Close()
+	_, ok := <-source.ReadChan()
+	require.False(t, ok)
+
+	// recordings carry SDP, only the server user may read them
+	info, err := os.Stat(recorder.Path())
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	header, entries, err := routing.ReadSignalRecording(recorder.Path())
+	require.NoError(t, err)
+	require.Equal(t, livekit.RoomName("room/1"), header.Room)
+	require.Equal(t, livekit.ParticipantIdentity("alice"), header.Identity)
+	require.Equal(t, int32(12), header.Protocol)
+	require.True(t, header.AutoSubscribe)
+
+	require.Len(t, entries, 3)
+	join := entries[0].Response.GetJoin()
+	require.Equal(t, "room/1", join.Room.Name)
+	require.NotEqual(t, "secret", join.Room.TurnPassword)
+	require.Equal(t, []string{"turn:turn.example.com"}, join.IceServers[0].Urls)
+	require.NotEqual(t, "user", join.IceServers[0].Username)
+	require.NotEqual(t, "secret", join.IceServers[0].Credential)
+	require.Equal(t, "v=0", entries[1].Request.GetOffer().Sdp)
+	require.NotEqual(t, "token", entries[2].Response.GetRefreshToken())
+}
@@ -0,0 +1,116 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+type speakerState struct {
+	activeSince  time.Time
+	lastActiveAt time.Time
+	reportedAt   time.Time
+	level        float32
+}
+
+// speakerDetector debounces the audio activity of participants: a participant is reported once active for
+// the attack time, and stays reported through pauses shorter than the decay time
+type speakerDetector struct {
+	attack      time.Duration
+	decay       time.Duration
+	minDuration time.Duration
+	maxSpeakers int
+
+	lock   sync.Mutex
+	states map[livekit.ParticipantID]*speakerState
+}
+
+func newSpeakerDetector(conf *config.AudioConfig) *speakerDetector {
+	return &speakerDetector{
+		attack:      conf.AttackTime,
+		decay:       conf.DecayTime,
+		minDuration: conf.MinSpeakingDuration,
+		maxSpeakers: conf.MaxActiveSpeakers,
+		states:      make(map[livekit.ParticipantID]*speakerState),
+	}
+}
+
+// update takes the current audio activity of all participants and returns the speakers to report, loudest first
+func (d *speakerDetector) update(levels []*livekit.SpeakerInfo, now time.Time) []*livekit.SpeakerInfo {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	seen := make(map[livekit.ParticipantID]bool, len(levels))
+	for _, l := range levels {
+		pID := livekit.ParticipantID(l.Sid)
+		seen[pID] = true
+
+		state := d.states[pID]
+		if l.Active {
+			if state == nil {
+				state = &speakerState{activeSince: now}
+				d.states[pID] = state
+			}
+			state.lastActiveAt = now
+			state.level = l.Level
+			if state.reportedAt.IsZero() && now.Sub(state.activeSince) >= d.attack {
+				state.reportedAt = now
+			}
+		} else if state != nil && !d.holdLocked(state, now) {
+			delete(d.states, pID)
+		}
+	}
+	for pID := range d.states {
+		if !seen[pID] {
+			// left the room
+			delete(d.states, pID)
+		}
+	}
+
+	speakers := make([]*livekit.SpeakerInfo, 0, len(d.states))
+	for pID, state := range d.states {
+		if state.reportedAt.IsZero() {
+			continue
+		}
+		speakers = append(speakers, &livekit.SpeakerInfo{
+			Sid:    string(pID),
+			Level:  state.level,
+			Active: true,
+		})
+	}
+
+	sort.Slice(speakers, func(i, j int) bool {
+		return speakers[i].Level > speakers[j].Level
+	})
+	if d.maxSpeakers > 0 && len(speakers) > d.maxSpeakers {
+		speakers = speakers[:d.maxSpeakers]
+	}
+	return speakers
+}
+
+// holdLocked returns true when a speaker that went quiet is still reported
+func (d *speakerDetector) holdLocked(state *speakerState, now time.Time) bool {
+	if state.reportedAt.IsZero() {
+		// not active long enough to be reported
+		return false
+	}
+	return now.Sub(state.lastActiveAt) < d.decay || now.Sub(state.reportedAt) < d.minDuration
+}
@@ -0,0 +1,172 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	// drift between audio and video beyond which lip sync is noticeably off,
+	// with hysteresis to avoid flapping around the threshold
+	avSyncDriftThreshold        = 200 * time.Millisecond
+	avSyncDriftRecoverThreshold = 100 * time.Millisecond
+
+	// drift corrected when correction is enabled for the room, below it drift is not perceptible
+	avSyncCorrectionThreshold = 40 * time.Millisecond
+	// largest change of the correction per update, so that playout is nudged instead of jumping
+	avSyncCorrectionMaxStep = 50 * time.Millisecond
+	// bound of the correction, larger drifts are left to be reported
+	avSyncCorrectionMaxOffset = 500 * time.Millisecond
+)
+
+// avSyncDrifts returns, per camera track of a publisher, the drift of its video relative to the publisher's microphone
+// audio as forwarded on the subscribed tracks, positive when video is ahead. Media time of each track is derived from the sender reports
+// generated for the subscriber, so it is the drift the subscriber will experience when synchronising playout.
+func avSyncDrifts(subscribedTracks []types.SubscribedTrack, at time.Time) map[livekit.TrackID]time.Duration {
+	type mediaTimes struct {
+		audio        time.Time
+		video        time.Time
+		videoTrackID livekit.TrackID
+	}
+	publishers := make(map[livekit.ParticipantIdentity]*mediaTimes)
+	for _, subTrack := range subscribedTracks {
+		source := subTrack.MediaTrack().Source()
+		if source != livekit.TrackSource_MICROPHONE && source != livekit.TrackSource_CAMERA {
+			continue
+		}
+
+		mediaTime, err := subTrack.DownTrack().GetMediaTime(at)
+		if err != nil {
+			continue
+		}
+
+		mt := publishers[subTrack.PublisherIdentity()]
+		if mt == nil {
+			mt = &mediaTimes{}
+			publishers[subTrack.PublisherIdentity()] = mt
+		}
+		if source == livekit.TrackSource_MICROPHONE {
+			mt.audio = mediaTime
+		} else {
+			mt.video = mediaTime
+			mt.videoTrackID = subTrack.ID()
+		}
+	}
+
+	drifts := make(map[livekit.TrackID]time.Duration, len(publishers))
+	for _, mt := range publishers {
+		if !mt.audio.IsZero() && !mt.video.IsZero() {
+			drifts[mt.videoTrackID] = mt.video.Sub(mt.audio)
+		}
+	}
+	return drifts
+}
+
+// nextAVSyncCorrection returns the correction of a video track after measuring drift with current correction applied
+func nextAVSyncCorrection(current time.Duration, drift time.Duration) time.Duration {
+	if drift > -avSyncCorrectionThreshold && drift < avSyncCorrectionThreshold {
+		return current
+	}
+	step := max(min(drift, avSyncCorrectionMaxStep), -avSyncCorrectionMaxStep)
+	return max(min(current+step, avSyncCorrectionMaxOffset), -avSyncCorrectionMaxOffset)
+}
+
+// correctAVSync nudges the sender reports of camera tracks of publishers drifting from their microphone track, so
+// that the subscriber plays them in sync. audio is kept as the reference, listeners notice disruption of it more
+func correctAVSync(subscribedTracks []types.SubscribedTrack, drifts map[livekit.TrackID]time.Duration, logger logger.Logger) {
+	for _, subTrack := range subscribedTracks {
+		if subTrack.MediaTrack().Source() != livekit.TrackSource_CAMERA {
+			continue
+		}
+		drift, ok := drifts[subTrack.ID()]
+		if !ok {
+			continue
+		}
+
+		dt := subTrack.DownTrack()
+		current := dt.GetSyncCorrection()
+		if next := nextAVSyncCorrection(current, drift); next != current {
+			dt.SetSyncCorrection(next)
+			logger.Debugw(
+				"correcting audio/video sync drift",
+				"publisher", subTrack.PublisherIdentity(),
+				"trackID", subTrack.ID(),
+				"drift", drift,
+				"correction", next,
+			)
+		}
+	}
+}
+
+// avSyncMonitor tracks A/V sync drift of publisher camera tracks on a subscriber and notifies when it exceeds the threshold and when it recovers
+type avSyncMonitor struct {
+	lock     sync.Mutex
+	exceeded map[livekit.TrackID]bool
+	onChange func(trackID livekit.TrackID, drift time.Duration, exceeded bool)
+}
+
+func newAVSyncMonitor(onChange func(trackID livekit.TrackID, drift time.Duration, exceeded bool)) *avSyncMonitor {
+	return &avSyncMonitor{
+		exceeded: make(map[livekit.TrackID]bool),
+		onChange: onChange,
+	}
+}
+
+func (m *avSyncMonitor) update(drifts map[livekit.TrackID]time.Duration) {
+	type change struct {
+		trackID  livekit.TrackID
+		drift    time.Duration
+		exceeded bool
+	}
+	var changes []change
+
+	m.lock.Lock()
+	for trackID, drift := range drifts {
+		absDrift := drift
+		if absDrift < 0 {
+			absDrift = -absDrift
+		}
+		exceeded := m.exceeded[trackID]
+		switch {
+		case !exceeded && absDrift > avSyncDriftThreshold:
+			m.exceeded[trackID] = true
+			changes = append(changes, change{trackID, drift, true})
+		case exceeded && absDrift < avSyncDriftRecoverThreshold:
+			delete(m.exceeded, trackID)
+			changes = append(changes, change{trackID, drift, false})
+		}
+	}
+
+	// tracks of publishers without both tracks forwarding are not tracked
+	for trackID := range m.exceeded {
+		if _, ok := drifts[trackID]; !ok {
+			delete(m.exceeded, trackID)
+		}
+	}
+	m.lock.Unlock()
+
+	if m.onChange != nil {
+		for _, c := range changes {
+			m.onChange(c.trackID, c.drift, c.exceeded)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+func TestAVSyncMonitor(t *testing.T) {
+	type change struct {
+		trackID  livekit.TrackID
+		drift    time.Duration
+		exceeded bool
+	}
+	var changes []change
+	m := newAVSyncMonitor(func(trackID livekit.TrackID, drift time.Duration, exceeded bool) {
+		changes = append(changes, change{trackID, drift, exceeded})
+	})
+
+	// within threshold
+	m.update(map[livekit.TrackID]time.Duration{"a": 50 * time.Millisecond, "b": -80 * time.Millisecond})
+	require.Empty(t, changes)
+
+	// video lagging audio beyond threshold
+	m.update(map[livekit.TrackID]time.Duration{"a": 50 * time.Millisecond, "b": -250 * time.Millisecond})
+	require.Equal(t, []change{{"b", -250 * time.Millisecond, true}}, changes)
+
+	// still exceeded, hysteresis does not recover between thresholds
+	m.update(map[livekit.TrackID]time.Duration{"a": 50 * time.Millisecond, "b": -150 * time.Millisecond})
+	require.Len(t, changes, 1)
+
+	// recovered
+	m.update(map[livekit.TrackID]time.Duration{"a": 50 * time.Millisecond, "b": 20 * time.Millisecond})
+	require.Equal(t, change{"b", 20 * time.Millisecond, false}, changes[1])
+
+	// exceeded state is dropped when the track is no longer tracked
+	m.update(map[livekit.TrackID]time.Duration{"a": 300 * time.Millisecond})
+	require.Equal(t, change{"a", 300 * time.Millisecond, true}, changes[2])
+	m.update(nil)
+	m.update(map[livekit.TrackID]time.Duration{"a": 20 * time.Millisecond})
+	require.Len(t, changes, 3)
+}
+
+func TestNextAVSyncCorrection(t *testing.T) {
+	// not perceptible, not corrected
+	require.Equal(t, time.Duration(0), nextAVSyncCorrection(0, 30*time.Millisecond))
+	require.Equal(t, 100*time.Millisecond, nextAVSyncCorrection(100*time.Millisecond, -30*time.Millisecond))
+
+	// video ahead is delayed, in steps
+	require.Equal(t, 45*time.Millisecond, nextAVSyncCorrection(0, 45*time.Millisecond))
+	require.Equal(t, 50*time.Millisecond, nextAVSyncCorrection(0, 300*time.Millisecond))
+
+	// video behind reduces the correction
+	require.Equal(t, 50*time.Millisecond, nextAVSyncCorrection(100*time.Millisecond, -300*time.Millisecond))
+
+	// bounded
+	require.Equal(t, 500*time.Millisecond, nextAVSyncCorrection(480*time.Millisecond, 300*time.Millisecond))
+	require.Equal(t, -500*time.Millisecond, nextAVSyncCorrection(-500*time.Millisecond, -300*time.Millisecond))
+}
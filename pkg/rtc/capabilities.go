@@ -0,0 +1,259 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/version"
+)
+
+// The capabilities of the server are attached to the JoinResponse as JoinResponseCapabilitiesField, a length
+// delimited field holding ServerCapabilities as json. Clients that don't know the field skip it as an unknown field.
+// Optional features are enabled and disabled with reliable data messages on CapabilitiesTopic, answered on
+// CapabilitiesResponseTopic. None of these messages are forwarded to other participants.
+const (
+	JoinResponseCapabilitiesField protowire.Number = 1000
+
+	CapabilitiesTopic         = "lk.capabilities"
+	CapabilitiesResponseTopic = "lk.capabilities.response"
+
+	// default max message size of pion's SCTP transport
+	maxDataMessageSize = 65536
+)
+
+// signal features, each available from a protocol version
+const (
+	SignalFeatureSubscriberPrimary          = "subscriber_primary"
+	SignalFeatureSpeakerChanged             = "speaker_changed"
+	SignalFeatureConnectionQuality          = "connection_quality"
+	SignalFeatureSessionMigrate             = "session_migrate"
+	SignalFeatureICELite                    = "ice_lite"
+	SignalFeatureUnpublish                  = "unpublish"
+	SignalFeatureFastStart                  = "fast_start"
+	SignalFeatureSyncStreamID               = "sync_stream_id"
+	SignalFeatureIdentityBasedReconnection  = "identity_based_reconnection"
+	SignalFeatureRegionsInLeaveRequest      = "regions_in_leave_request"
+	SignalFeatureNonErrorSignalResponse     = "non_error_signal_response"
+	SignalFeatureConnectionQualityLost      = "connection_quality_lost"
+	SignalFeatureHandlesDisconnectedUpdates = "disconnected_updates"
+)
+
+// optional features, negotiated by the client after joining
+const (
+	// messages on SubscriptionStatusTopic. enabled by default when rtc.subscription_status is set
+	OptionalFeatureSubscriptionStatus = "subscription_status"
+)
+
+var optionalFeatures = []string{OptionalFeatureSubscriptionStatus}
+
+// ServerCapabilities describes what the server supports for a participant, so clients can adapt without looking
+// at the server version
+type ServerCapabilities struct {
+	ProtocolVersion    int      `json:"protocol_version"`
+	ServerVersion      string   `json:"server_version"`
+	PublishCodecs      []string `json:"publish_codecs"`
+	SubscribeCodecs    []string `json:"subscribe_codecs"`
+	SVCCodecs          []string `json:"svc_codecs"`
+	ScalabilityModes   []string `json:"scalability_modes"`
+	MaxDataMessageSize int      `json:"max_data_message_size"`
+	SignalFeatures     []string `json:"signal_features"`
+	OptionalFeatures   []string `json:"optional_features"`
+	EnabledFeatures    []string `json:"enabled_features"`
+}
+
+// CapabilitiesRequest is the payload of messages on CapabilitiesTopic
+type CapabilitiesRequest struct {
+	RequestID string   `json:"request_id,omitempty"`
+	Enable    []string `json:"enable,omitempty"`
+	Disable   []string `json:"disable,omitempty"`
+}
+
+// CapabilitiesResponse is the payload of messages on CapabilitiesResponseTopic
+type CapabilitiesResponse struct {
+	RequestID    string              `json:"request_id,omitempty"`
+	Error        string              `json:"error,omitempty"`
+	Capabilities *ServerCapabilities `json:"capabilities"`
+}
+
+func signalFeatures(v types.ProtocolVersion) []string {
+	features := []struct {
+		name      string
+		supported bool
+	}{
+		{SignalFeatureSubscriberPrimary, v.SubscriberAsPrimary()},
+		{SignalFeatureSpeakerChanged, v.SupportsSpeakerChanged()},
+		{SignalFeatureConnectionQuality, v.SupportsConnectionQuality()},
+		{SignalFeatureSessionMigrate, v.SupportsSessionMigrate()},
+		{SignalFeatureICELite, v.SupportsICELite()},
+		{SignalFeatureUnpublish, v.SupportsUnpublish()},
+		{SignalFeatureFastStart, v.SupportFastStart()},
+		{SignalFeatureSyncStreamID, v.SupportSyncStreamID()},
+		{SignalFeatureIdentityBasedReconnection, v.SupportsIdentityBasedReconnection()},
+		{SignalFeatureRegionsInLeaveRequest, v.SupportsRegionsInLeaveRequest()},
+		{SignalFeatureNonErrorSignalResponse, v.SupportsNonErrorSignalResponse()},
+		{SignalFeatureConnectionQualityLost, v.SupportsConnectionQualityLost()},
+		{SignalFeatureHandlesDisconnectedUpdates, v.SupportHandlesDisconnectedUpdate()},
+	}
+
+	supported := make([]string, 0, len(features))
+	for _, f := range features {
+		if f.supported {
+			supported = append(supported, f.name)
+		}
+	}
+	return supported
+}
+
+func codecMimeTypes(codecs []*livekit.Codec) []string {
+	mimes := make([]string, 0, len(codecs))
+	for _, c := range codecs {
+		mime := strings.ToLower(c.Mime)
+		if !slices.Contains(mimes, mime) {
+			mimes = append(mimes, mime)
+		}
+	}
+	return mimes
+}
+
+// scalabilityModes lists the L1T1 to L3T3 modes, with the _KEY variants for more than one spatial layer
+func scalabilityModes() []string {
+	var modes []string
+	for spatial := 1; spatial <= 3; spatial++ {
+		for temporal := 1; temporal <= 3; temporal++ {
+			modes = append(modes, fmt.Sprintf("L%dT%d", spatial, temporal))
+			if spatial > 1 {
+				modes = append(modes, fmt.Sprintf("L%dT%d_KEY", spatial, temporal))
+			}
+		}
+	}
+	return modes
+}
+
+// attachCapabilities adds capabilities to joinResponse as JoinResponseCapabilitiesField
+func attachCapabilities(joinResponse *livekit.JoinResponse, capabilities *ServerCapabilities) error {
+	payload, err := json.Marshal(capabilities)
+	if err != nil {
+		return err
+	}
+
+	m := joinResponse.ProtoReflect()
+	unknown := slices.Clone(m.GetUnknown())
+	unknown = protowire.AppendTag(unknown, JoinResponseCapabilitiesField, protowire.BytesType)
+	unknown = protowire.AppendBytes(unknown, payload)
+	m.SetUnknown(unknown)
+	return nil
+}
+
+// ------------------------------------------------------------
+
+func (p *ParticipantImpl) Capabilities() *ServerCapabilities {
+	c := &ServerCapabilities{
+		ProtocolVersion:    types.CurrentProtocol,
+		ServerVersion:      version.Version,
+		PublishCodecs:      codecMimeTypes(p.enabledPublishCodecs),
+		SubscribeCodecs:    codecMimeTypes(p.enabledSubscribeCodecs),
+		MaxDataMessageSize: maxDataMessageSize,
+		SignalFeatures:     signalFeatures(p.ProtocolVersion()),
+		OptionalFeatures:   optionalFeatures,
+		EnabledFeatures:    []string{},
+	}
+	for _, mime := range c.PublishCodecs {
+		if buffer.IsSvcCodec(mime) && !slices.ContainsFunc(p.params.SimulcastOnlyCodecs, func(m string) bool {
+			return strings.EqualFold(m, mime)
+		}) {
+			c.SVCCodecs = append(c.SVCCodecs, mime)
+		}
+	}
+	if len(c.SVCCodecs) != 0 {
+		c.ScalabilityModes = scalabilityModes()
+	}
+	if p.subscriptionStatus.Load() {
+		c.EnabledFeatures = append(c.EnabledFeatures, OptionalFeatureSubscriptionStatus)
+	}
+	return c
+}
+
+func (p *ParticipantImpl) setOptionalFeature(feature string, enabled bool) {
+	switch feature {
+	case OptionalFeatureSubscriptionStatus:
+		p.subscriptionStatus.Store(enabled)
+	}
+}
+
+func (p *ParticipantImpl) handleCapabilitiesRequest(payload []byte) {
+	var req CapabilitiesRequest
+	res := &CapabilitiesResponse{}
+	if err := json.Unmarshal(payload, &req); err != nil {
+		res.Error = err.Error()
+	} else {
+		res.RequestID = req.RequestID
+		// validate everything before changing anything, so that a failed request has no effect
+		for _, feature := range slices.Concat(req.Enable, req.Disable) {
+			if !slices.Contains(optionalFeatures, feature) {
+				res.Error = fmt.Sprintf("unknown optional feature %q", feature)
+				break
+			}
+		}
+		if res.Error == "" {
+			for _, feature := range req.Enable {
+				p.setOptionalFeature(feature, true)
+			}
+			for _, feature := range req.Disable {
+				p.setOptionalFeature(feature, false)
+			}
+		}
+	}
+	res.Capabilities = p.Capabilities()
+
+	if err := p.sendCapabilitiesResponse(res); err != nil {
+		p.params.Logger.Warnw("could not send capabilities response", err)
+	}
+}
+
+func (p *ParticipantImpl) sendCapabilitiesResponse(res *CapabilitiesResponse) error {
+	payload, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+
+	topic := CapabilitiesResponseTopic
+	dp := &livekit.DataPacket{
+		Kind:                  livekit.DataPacket_RELIABLE,
+		DestinationIdentities: []string{string(p.Identity())},
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload:               payload,
+				Topic:                 &topic,
+				DestinationIdentities: []string{string(p.Identity())},
+			},
+		},
+	}
+	data, err := proto.Marshal(dp)
+	if err != nil {
+		return err
+	}
+	return p.SendDataPacket(livekit.DataPacket_RELIABLE, data)
+}
@@ -0,0 +1,232 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu/compositor"
+)
+
+// ComposedVideoIdentity is the identity of the virtual participant publishing the composed video track of a room
+const ComposedVideoIdentity livekit.ParticipantIdentity = "lk.compositor"
+
+var composedVideoCodec = webrtc.RTPCodecParameters{
+	RTPCodecCapability: webrtc.RTPCodecCapability{
+		MimeType:  webrtc.MimeTypeVP8,
+		ClockRate: compositor.ClockRate,
+		RTCPFeedback: []webrtc.RTCPFeedback{
+			{Type: webrtc.TypeRTCPFBNACK},
+			{Type: webrtc.TypeRTCPFBNACK, Parameter: "pli"},
+		},
+	},
+	PayloadType: 96,
+}
+
+// ComposedVideoTrack is a track rendering camera tracks of the room. Participants may only subscribe to it when they
+// may subscribe to all the tracks it renders.
+type ComposedVideoTrack struct {
+	compositor *compositor.Compositor
+
+	lock sync.Mutex
+	// publishers of the tracks rendered
+	inputs map[livekit.TrackID]types.LocalParticipant
+
+	*GeneratedTrack
+}
+
+type composedVideo struct {
+	// identities composed, all when empty
+	participants map[livekit.ParticipantIdentity]bool
+	track        *ComposedVideoTrack
+}
+
+func (t *ComposedVideoTrack) Close(isExpectedToResume bool) {
+	t.compositor.Stop()
+	t.GeneratedTrack.Close(isExpectedToResume)
+}
+
+// newComposedVideo creates the composed video track, returns nil when composition is not possible
+func (r *Room) newComposedVideo(conf config.VideoCompositionConfig) *composedVideo {
+	t := &ComposedVideoTrack{
+		inputs: make(map[livekit.TrackID]types.LocalParticipant),
+	}
+	c, err := compositor.New(compositor.Params{
+		Codec:            composedVideoCodec,
+		Width:            conf.Width,
+		Height:           conf.Height,
+		FPS:              conf.FPS,
+		KeyframeInterval: conf.KeyframeInterval,
+		Layout:           compositor.Layout(conf.Layout),
+		SSRC:             rand.Uint32(),
+		Logger:           r.Logger,
+		OnPacket: func(pkt *rtp.Packet) {
+			t.WriteRTP(pkt)
+		},
+	})
+	if err != nil {
+		r.Logger.Warnw("could not create video compositor", err)
+		return nil
+	}
+	t.compositor = c
+	width, height := c.Size()
+	t.GeneratedTrack = r.newGeneratedTrack(generatedTrackParams{
+		Identity: ComposedVideoIdentity,
+		Name:     "Composed video",
+		TrackInfo: &livekit.TrackInfo{
+			Type:   livekit.TrackType_VIDEO,
+			Source: livekit.TrackSource_CAMERA,
+			Name:   "composed video",
+			Width:  uint32(width),
+			Height: uint32(height),
+			Layers: []*livekit.VideoLayer{
+				{Quality: livekit.VideoQuality_HIGH, Width: uint32(width), Height: uint32(height)},
+			},
+		},
+		Codec:   composedVideoCodec,
+		SendPLI: c.RequestKeyframe,
+	})
+	c.Start()
+
+	cv := &composedVideo{
+		track: t,
+	}
+	if len(conf.Participants) != 0 {
+		cv.participants = make(map[livekit.ParticipantIdentity]bool, len(conf.Participants))
+		for _, identity := range conf.Participants {
+			cv.participants[livekit.ParticipantIdentity(identity)] = true
+		}
+	}
+	return cv
+}
+
+// CheckComposedVideoCodec returns an error when video composition is enabled and the server build has no VP8 codec to
+// compose with
+func CheckComposedVideoCodec(conf config.VideoCompositionConfig) error {
+	if conf.Enabled && !compositor.HasCodec(composedVideoCodec.MimeType) {
+		return fmt.Errorf("video composition is enabled: %w, register a VP8 codec with compositor.RegisterCodec", compositor.ErrNoCodec)
+	}
+	return nil
+}
+
+// GetComposedVideoTrack returns the composed video track of the room, nil when not enabled
+func (r *Room) GetComposedVideoTrack() *ComposedVideoTrack {
+	if r.composedVideo == nil {
+		return nil
+	}
+	return r.composedVideo.track
+}
+
+func (r *Room) addComposedVideoInput(p types.LocalParticipant, track types.MediaTrack) {
+	cv := r.composedVideo
+	if cv == nil || track.Kind() != livekit.TrackType_VIDEO || track.Source() != livekit.TrackSource_CAMERA {
+		return
+	}
+	if cv.participants != nil && !cv.participants[p.Identity()] {
+		return
+	}
+
+	// first receiver matching the composition codec, when publishing multiple codecs
+	for _, receiver := range track.Receivers() {
+		err := cv.track.compositor.AddInput(receiver)
+		if errors.Is(err, compositor.ErrUnsupportedCodec) {
+			continue
+		}
+		if err != nil {
+			p.GetLogger().Infow("could not compose video track", "error", err, "trackID", track.ID())
+			return
+		}
+		cv.track.lock.Lock()
+		cv.track.inputs[track.ID()] = p
+		cv.track.lock.Unlock()
+		r.updateComposedVideoPermissions()
+		return
+	}
+	p.GetLogger().Debugw("video track codec cannot be composed", "trackID", track.ID())
+}
+
+func (r *Room) removeComposedVideoInput(trackID livekit.TrackID) {
+	if r.composedVideo == nil {
+		return
+	}
+	t := r.composedVideo.track
+	t.compositor.RemoveInput(trackID)
+
+	t.lock.Lock()
+	delete(t.inputs, trackID)
+	t.lock.Unlock()
+	r.updateComposedVideoPermissions()
+}
+
+// updateComposedVideoPermissions revokes subscriptions of participants that can no longer subscribe to a rendered
+// track, and has subscriptions resolved again
+func (r *Room) updateComposedVideoPermissions() {
+	if r.composedVideo == nil {
+		return
+	}
+	t := r.composedVideo.track
+
+	for _, subTrack := range t.getAllSubscribedTracks() {
+		if !t.hasPermission(subTrack.SubscriberIdentity()) {
+			t.params.Logger.Infow("revoking subscription",
+				"subscriber", subTrack.SubscriberIdentity(),
+				"subscriberID", subTrack.SubscriberID(),
+			)
+			t.RemoveSubscriber(subTrack.SubscriberID(), false)
+		}
+	}
+	r.trackManager.NotifyTrackChanged(t.ID())
+}
+
+// hasPermission returns whether a participant may subscribe to all the tracks rendered, its own included
+func (t *ComposedVideoTrack) hasPermission(identity livekit.ParticipantIdentity) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for trackID, pub := range t.inputs {
+		if pub.Identity() != identity && !pub.HasPermission(trackID, identity) {
+			return false
+		}
+	}
+	return true
+}
+
+// updateComposedVideoFocus shows the camera of the loudest speaker large in the speaker layout
+func (r *Room) updateComposedVideoFocus(speakers []*livekit.SpeakerInfo) {
+	if r.composedVideo == nil || len(speakers) == 0 {
+		return
+	}
+
+	p := r.GetParticipantByID(livekit.ParticipantID(speakers[0].Sid))
+	if p == nil {
+		return
+	}
+	for _, track := range p.GetPublishedTracks() {
+		if track.Kind() == livekit.TrackType_VIDEO && track.Source() == livekit.TrackSource_CAMERA {
+			r.composedVideo.track.compositor.SetFocus(track.ID())
+			return
+		}
+	}
+}
@@ -15,11 +15,15 @@
 package rtc
 
 import (
+	"fmt"
+	"slices"
+
 	"github.com/pion/sdp/v3"
 	"github.com/pion/webrtc/v3"
 
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/rtpextension"
 	dd "github.com/livekit/livekit-server/pkg/sfu/rtpextension/dependencydescriptor"
 	"github.com/livekit/mediatransportutil/pkg/rtcconfig"
 )
@@ -36,11 +40,14 @@ type WebRTCConfig struct {
 	Receiver      ReceiverConfig
 	Publisher     DirectionConfig
 	Subscriber    DirectionConfig
+	// header extensions negotiated besides those handled by the server
+	HeaderExtensions *rtpextension.Registry
 }
 
 type ReceiverConfig struct {
 	PacketBufferSizeVideo int
 	PacketBufferSizeAudio int
+	StreamTrackers        config.StreamTrackersConfig
 }
 
 type RTPHeaderExtensionConfig struct {
@@ -133,23 +140,72 @@ func NewWebRTCConfig(conf *config.Config) (*WebRTCConfig, error) {
 			},
 		},
 	}
-	if rtcConf.CongestionControl.UseSendSideBWE {
-		subscriberConfig.RTPHeaderExtension.Video = append(subscriberConfig.RTPHeaderExtension.Video, sdp.TransportCCURI)
-		subscriberConfig.RTCPFeedback.Video = append(subscriberConfig.RTCPFeedback.Video, webrtc.RTCPFeedback{Type: webrtc.TypeRTCPFBTransportCC})
-	} else {
-		subscriberConfig.RTPHeaderExtension.Video = append(subscriberConfig.RTPHeaderExtension.Video, sdp.ABSSendTimeURI)
-		subscriberConfig.RTCPFeedback.Video = append(subscriberConfig.RTCPFeedback.Video, webrtc.RTCPFeedback{Type: webrtc.TypeRTCPFBGoogREMB})
+
+	headerExtensions, err := newHeaderExtensionRegistry(rtcConf.HeaderExtensions)
+	if err != nil {
+		return nil, err
 	}
+	publisherConfig.RTPHeaderExtension.Audio = append(publisherConfig.RTPHeaderExtension.Audio, headerExtensions.URIs(webrtc.RTPCodecTypeAudio, rtpextension.DirectionPublisher)...)
+	publisherConfig.RTPHeaderExtension.Video = append(publisherConfig.RTPHeaderExtension.Video, headerExtensions.URIs(webrtc.RTPCodecTypeVideo, rtpextension.DirectionPublisher)...)
+	subscriberConfig.RTPHeaderExtension.Audio = append(subscriberConfig.RTPHeaderExtension.Audio, headerExtensions.URIs(webrtc.RTPCodecTypeAudio, rtpextension.DirectionSubscriber)...)
+	subscriberConfig.RTPHeaderExtension.Video = append(subscriberConfig.RTPHeaderExtension.Video, headerExtensions.URIs(webrtc.RTPCodecTypeVideo, rtpextension.DirectionSubscriber)...)
 
-	return &WebRTCConfig{
+	c := &WebRTCConfig{
 		WebRTCConfig: *webRTCConfig,
 		Receiver: ReceiverConfig{
 			PacketBufferSizeVideo: rtcConf.PacketBufferSizeVideo,
 			PacketBufferSizeAudio: rtcConf.PacketBufferSizeAudio,
+			StreamTrackers:        conf.Video.StreamTracker,
 		},
-		Publisher:  publisherConfig,
-		Subscriber: subscriberConfig,
-	}, nil
+		Publisher:        publisherConfig,
+		Subscriber:       subscriberConfig,
+		HeaderExtensions: headerExtensions,
+	}
+	c.SetSendSideBWE(rtcConf.CongestionControl.UseSendSideBWE)
+	return c, nil
+}
+
+func newHeaderExtensionRegistry(declared []config.HeaderExtensionConfig) (*rtpextension.Registry, error) {
+	extensions := make([]rtpextension.HeaderExtension, 0, len(declared))
+	for _, d := range declared {
+		ext := rtpextension.HeaderExtension{
+			URI:        d.URI,
+			Publisher:  rtpextension.Policy(d.Publisher),
+			Subscriber: rtpextension.Policy(d.Subscriber),
+		}
+		for _, kind := range d.Kinds {
+			switch kind {
+			case "audio":
+				ext.Audio = true
+			case "video":
+				ext.Video = true
+			default:
+				return nil, fmt.Errorf("invalid kind %q of header extension %s", kind, d.URI)
+			}
+		}
+		extensions = append(extensions, ext)
+	}
+	return rtpextension.NewRegistry(extensions)
+}
+
+// SetSendSideBWE selects the bandwidth estimation negotiated with subscribers, transport-wide congestion control or REMB.
+// Copies of the config share the subscriber slices, so they are rebuilt rather than appended to
+func (c *WebRTCConfig) SetSendSideBWE(enabled bool) {
+	video := slices.DeleteFunc(slices.Clone(c.Subscriber.RTPHeaderExtension.Video), func(uri string) bool {
+		return uri == sdp.TransportCCURI || uri == sdp.ABSSendTimeURI
+	})
+	feedback := slices.DeleteFunc(slices.Clone(c.Subscriber.RTCPFeedback.Video), func(fb webrtc.RTCPFeedback) bool {
+		return fb.Type == webrtc.TypeRTCPFBTransportCC || fb.Type == webrtc.TypeRTCPFBGoogREMB
+	})
+	if enabled {
+		video = append(video, sdp.TransportCCURI)
+		feedback = append(feedback, webrtc.RTCPFeedback{Type: webrtc.TypeRTCPFBTransportCC})
+	} else {
+		video = append(video, sdp.ABSSendTimeURI)
+		feedback = append(feedback, webrtc.RTCPFeedback{Type: webrtc.TypeRTCPFBGoogREMB})
+	}
+	c.Subscriber.RTPHeaderExtension.Video = video
+	c.Subscriber.RTCPFeedback.Video = feedback
 }
 
 func (c *WebRTCConfig) SetBufferFactory(factory *buffer.Factory) {
@@ -0,0 +1,54 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import "github.com/livekit/livekit-server/pkg/config"
+
+const (
+	DataChannelPriorityHigh   = "high"
+	DataChannelPriorityNormal = "normal"
+	DataChannelPriorityLow    = "low"
+
+	// buffer limit of high priority channels when data channels are not limited
+	defaultPriorityBufferedAmount = 256 * 1024
+)
+
+// dataChannelBufferLimit returns how much a channel of the given priority can buffer before messages are dropped, 0 when
+// it isn't limited. all data channels share the SCTP association, so limiting what lower priority channels can queue
+// keeps them from delaying messages of higher priority channels when bandwidth is constrained. high priority channels
+// get the whole limit, normal ones half and low ones a quarter. channels without a priority keep the configured limit
+func dataChannelBufferLimit(priority string, maxBufferedAmount uint64) uint64 {
+	if priority == "" {
+		return maxBufferedAmount
+	}
+	if maxBufferedAmount == 0 {
+		maxBufferedAmount = defaultPriorityBufferedAmount
+	}
+	switch priority {
+	case DataChannelPriorityHigh:
+		return maxBufferedAmount
+	case DataChannelPriorityLow:
+		return maxBufferedAmount / 4
+	default:
+		return maxBufferedAmount / 2
+	}
+}
+
+func dataChannelPriority(conf config.DataChannelPriorityConfig, label string) string {
+	if label == LossyDataChannel {
+		return conf.Lossy
+	}
+	return conf.Reliable
+}
@@ -0,0 +1,236 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	// DataCompressionAttribute lists the encodings a participant accepts for compressed data topics, in order of
+	// preference, e.g. "zstd,gzip". DataCompressionAttribute + "." + topic overrides it for a single topic
+	DataCompressionAttribute = "lk.compression"
+
+	DataEncodingGzip = "gzip"
+	DataEncodingZstd = "zstd"
+
+	// a decompressed payload has to fit in a single data channel message
+	maxDecompressedDataSize = maxDataMessageSize
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+	ErrDecompressedDataTooLarge = errors.New("decompressed data packet is too large")
+)
+
+// DataCompressor recompresses payloads of compressed data topics for recipients that don't accept their encoding,
+// using the recipient's first supported encoding, or no compression. payloads are identified as gzip or zstd by their
+// magic number, anything else is forwarded as is
+type DataCompressor struct {
+	topics  map[string]*zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+// NewDataCompressor returns nil when no topics are configured
+func NewDataCompressor(topics []config.DataCompressionTopicConfig) (*DataCompressor, error) {
+	if len(topics) == 0 {
+		return nil, nil
+	}
+
+	c := &DataCompressor{
+		topics: make(map[string]*zstd.Encoder, len(topics)),
+	}
+	var dicts [][]byte
+	for _, t := range topics {
+		var opts []zstd.EOption
+		if t.ZstdDictionary != "" {
+			dict, err := os.ReadFile(t.ZstdDictionary)
+			if err != nil {
+				return nil, fmt.Errorf("could not read zstd dictionary for topic %s: %w", t.Topic, err)
+			}
+			dicts = append(dicts, dict)
+			opts = append(opts, zstd.WithEncoderDict(dict))
+		}
+		encoder, err := zstd.NewWriter(nil, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("invalid zstd dictionary for topic %s: %w", t.Topic, err)
+		}
+		c.topics[t.Topic] = encoder
+	}
+
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderDicts(dicts...), zstd.WithDecoderMaxMemory(maxDecompressedDataSize))
+	if err != nil {
+		return nil, err
+	}
+	c.decoder = decoder
+	return c, nil
+}
+
+// SetDataCompressor enables recompression of compressed data topics
+func (r *Room) SetDataCompressor(c *DataCompressor) {
+	r.lock.Lock()
+	r.dataCompressor = c
+	r.lock.Unlock()
+}
+
+// encoder returns the per recipient encoding of dp, or nil when it doesn't need recompression
+func (c *DataCompressor) encoder(dp *livekit.DataPacket) func(op types.LocalParticipant, dpData []byte) ([]byte, error) {
+	if c == nil {
+		return nil
+	}
+	u := dp.GetUser()
+	topic := u.GetTopic()
+	encoder, ok := c.topics[topic]
+	if !ok {
+		return nil
+	}
+	encoding := detectDataEncoding(u.GetPayload())
+	if encoding == "" {
+		return nil
+	}
+
+	var (
+		lock    sync.Mutex
+		raw     []byte
+		encoded = make(map[string][]byte)
+	)
+	return func(op types.LocalParticipant, dpData []byte) ([]byte, error) {
+		accepted := acceptedDataEncodings(op, topic)
+		target := ""
+		for _, e := range accepted {
+			if e == encoding {
+				return dpData, nil
+			}
+			if target == "" && (e == DataEncodingGzip || e == DataEncodingZstd) {
+				target = e
+			}
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		if data, ok := encoded[target]; ok {
+			return data, nil
+		}
+
+		if raw == nil {
+			var err error
+			if raw, err = c.decompress(encoding, u.Payload); err != nil {
+				return nil, err
+			}
+		}
+		payload, err := c.compress(encoder, target, raw)
+		if err != nil {
+			return nil, err
+		}
+		clone := proto.Clone(dp).(*livekit.DataPacket)
+		clone.GetUser().Payload = payload
+		data, err := proto.Marshal(clone)
+		if err != nil {
+			return nil, err
+		}
+		encoded[target] = data
+		return data, nil
+	}
+}
+
+func (c *DataCompressor) decompress(encoding string, payload []byte) ([]byte, error) {
+	switch encoding {
+	case DataEncodingZstd:
+		raw, err := c.decoder.DecodeAll(payload, nil)
+		if errors.Is(err, zstd.ErrDecoderSizeExceeded) || len(raw) > maxDecompressedDataSize {
+			return nil, ErrDecompressedDataTooLarge
+		}
+		return raw, err
+	default:
+		zr, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		raw, err := io.ReadAll(io.LimitReader(zr, maxDecompressedDataSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(raw) > maxDecompressedDataSize {
+			return nil, ErrDecompressedDataTooLarge
+		}
+		return raw, nil
+	}
+}
+
+func (c *DataCompressor) compress(encoder *zstd.Encoder, encoding string, raw []byte) ([]byte, error) {
+	switch encoding {
+	case DataEncodingZstd:
+		return encoder.EncodeAll(raw, nil), nil
+	case DataEncodingGzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(raw); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return raw, nil
+	}
+}
+
+func detectDataEncoding(payload []byte) string {
+	switch {
+	case bytes.HasPrefix(payload, zstdMagic):
+		return DataEncodingZstd
+	case bytes.HasPrefix(payload, gzipMagic):
+		return DataEncodingGzip
+	default:
+		return ""
+	}
+}
+
+func acceptedDataEncodings(op types.LocalParticipant, topic string) []string {
+	grants := op.ClaimGrants()
+	if grants == nil {
+		return nil
+	}
+	value, ok := grants.Attributes[DataCompressionAttribute+"."+topic]
+	if !ok {
+		value = grants.Attributes[DataCompressionAttribute]
+	}
+	if value == "" {
+		return nil
+	}
+	encodings := strings.Split(value, ",")
+	for i, e := range encodings {
+		encodings[i] = strings.TrimSpace(e)
+	}
+	return encodings
+}
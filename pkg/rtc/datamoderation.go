@@ -0,0 +1,226 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	sutils "github.com/livekit/livekit-server/pkg/utils"
+)
+
+const (
+	DataModerationAllow  = "allow"
+	DataModerationDrop   = "drop"
+	DataModerationRedact = "redact"
+	DataModerationFlag   = "flag"
+
+	dataModerationAsync          = "async"
+	defaultDataModerationTimeout = 500 * time.Millisecond
+)
+
+// DataModerationRequest describes a user data message waiting to be moderated. Payload is only set when
+// content moderation is enabled
+type DataModerationRequest struct {
+	Room                  string   `json:"room"`
+	ParticipantIdentity   string   `json:"participant_identity"`
+	Topic                 string   `json:"topic,omitempty"`
+	ID                    string   `json:"id,omitempty"`
+	Kind                  string   `json:"kind"`
+	Size                  int      `json:"size"`
+	DestinationIdentities []string `json:"destination_identities,omitempty"`
+	Payload               []byte   `json:"payload,omitempty"`
+}
+
+// DataModerationResult is the moderator decision. Payload replaces the message payload for redact
+type DataModerationResult struct {
+	Action  string `json:"action"`
+	Payload []byte `json:"payload,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// DataModerator decides what happens to user data messages before they are sent to the room
+type DataModerator interface {
+	ModerateData(ctx context.Context, req *DataModerationRequest) (*DataModerationResult, error)
+}
+
+// DataMessageFlagged is reported when the moderator flags a data message
+type DataMessageFlagged struct {
+	Room                string `json:"room"`
+	RoomSID             string `json:"room_sid"`
+	ParticipantIdentity string `json:"participant_identity"`
+	ParticipantSID      string `json:"participant_sid"`
+	Topic               string `json:"topic,omitempty"`
+	ID                  string `json:"id,omitempty"`
+	Reason              string `json:"reason,omitempty"`
+	// unix milliseconds
+	FlaggedAt int64 `json:"flagged_at"`
+}
+
+// DataFlagNotifier reports data messages flagged by the moderator
+type DataFlagNotifier interface {
+	NotifyDataFlagged(ctx context.Context, flagged *DataMessageFlagged)
+}
+
+// SetDataModerator enables moderation of user data messages sent by participants, flagged messages are reported
+// to flagNotifier when set
+func (r *Room) SetDataModerator(moderator DataModerator, flagNotifier DataFlagNotifier, conf config.DataModerationConfig) {
+	r.lock.Lock()
+	r.dataModerator = moderator
+	r.dataFlagNotifier = flagNotifier
+	r.dataModerationConf = conf
+	r.lock.Unlock()
+}
+
+// moderateData returns false when the message is moderated. the moderator is called from a queue per sender,
+// and in sync mode the queue forwards the message once the moderator allowed it
+func (r *Room) moderateData(source types.LocalParticipant, kind livekit.DataPacket_Kind, dp *livekit.DataPacket) bool {
+	u := dp.GetUser()
+	if source == nil || u == nil {
+		return true
+	}
+
+	r.lock.RLock()
+	moderator, conf := r.dataModerator, r.dataModerationConf
+	r.lock.RUnlock()
+	if moderator == nil || (len(conf.Topics) != 0 && !slices.Contains(conf.Topics, u.GetTopic())) {
+		return true
+	}
+
+	req := &DataModerationRequest{
+		Room:                  string(r.Name()),
+		ParticipantIdentity:   string(source.Identity()),
+		Topic:                 u.GetTopic(),
+		ID:                    u.GetId(),
+		Kind:                  kind.String(),
+		Size:                  len(u.Payload),
+		DestinationIdentities: u.DestinationIdentities,
+	}
+	if conf.IncludeContent {
+		req.Payload = u.Payload
+	}
+
+	queue := r.getDataModerationQueue(source)
+	if queue == nil {
+		return false
+	}
+	if conf.Mode == dataModerationAsync {
+		queue.Enqueue(func() {
+			r.applyDataModeration(moderator, conf, source, req, dp)
+		})
+		return true
+	}
+	queue.Enqueue(func() {
+		if dp := r.applyDataModeration(moderator, conf, source, req, dp); dp != nil {
+			r.forwardData(source, kind, dp)
+		}
+	})
+	return false
+}
+
+// getDataModerationQueue returns nil once the room is closed
+func (r *Room) getDataModerationQueue(source types.LocalParticipant) *sutils.OpsQueue {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.IsClosed() {
+		return nil
+	}
+	queue := r.dataModerationQueues[source.ID()]
+	if queue == nil {
+		queue = sutils.NewOpsQueue(sutils.OpsQueueParams{
+			Name:    "data-moderation",
+			MinSize: 64,
+			Logger:  source.GetLogger(),
+		})
+		queue.Start()
+		r.dataModerationQueues[source.ID()] = queue
+	}
+	return queue
+}
+
+// stopDataModerationLocked drops the messages of a participant still waiting to be moderated
+func (r *Room) stopDataModerationLocked(participantID livekit.ParticipantID) {
+	if queue := r.dataModerationQueues[participantID]; queue != nil {
+		queue.Stop()
+		delete(r.dataModerationQueues, participantID)
+	}
+}
+
+func (r *Room) applyDataModeration(
+	moderator DataModerator,
+	conf config.DataModerationConfig,
+	source types.LocalParticipant,
+	req *DataModerationRequest,
+	dp *livekit.DataPacket,
+) *livekit.DataPacket {
+	timeout := conf.Timeout
+	if timeout <= 0 {
+		timeout = defaultDataModerationTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	res, err := moderator.ModerateData(ctx, req)
+	if err != nil {
+		source.GetLogger().Warnw("data moderation failed", err, "topic", req.Topic, "failClosed", conf.FailClosed)
+		if conf.FailClosed {
+			return nil
+		}
+		return dp
+	}
+	if conf.Mode == dataModerationAsync && res.Action != DataModerationFlag {
+		// the message has already been forwarded, only flags are actionable
+		return dp
+	}
+
+	switch res.Action {
+	case DataModerationDrop:
+		source.GetLogger().Infow("data message dropped by moderator", "topic", req.Topic, "reason", res.Reason)
+		return nil
+
+	case DataModerationRedact:
+		source.GetLogger().Infow("data message redacted by moderator", "topic", req.Topic, "reason", res.Reason)
+		dp = proto.Clone(dp).(*livekit.DataPacket)
+		dp.GetUser().Payload = res.Payload
+		return dp
+
+	case DataModerationFlag:
+		source.GetLogger().Infow("data message flagged by moderator", "topic", req.Topic, "reason", res.Reason)
+		r.lock.RLock()
+		flagNotifier := r.dataFlagNotifier
+		r.lock.RUnlock()
+		if flagNotifier != nil {
+			flagNotifier.NotifyDataFlagged(context.Background(), &DataMessageFlagged{
+				Room:                string(r.Name()),
+				RoomSID:             string(r.ID()),
+				ParticipantIdentity: string(source.Identity()),
+				ParticipantSID:      string(source.ID()),
+				Topic:               req.Topic,
+				ID:                  req.ID,
+				Reason:              res.Reason,
+				FlaggedAt:           time.Now().UnixMilli(),
+			})
+		}
+	}
+	return dp
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// SendReceiptTopic is the topic of the messages reporting which recipients a reliable data message was sent to.
+// receipts are sent to the sender for messages with an id when send receipts are enabled for the room.
+// a recipient is listed as sent once the message is queued on its data channel, recipients don't acknowledge
+// messages so a receipt doesn't mean the message reached them
+const SendReceiptTopic = "lk.send_receipt"
+
+// SendReceipt is the json payload of send receipt messages
+type SendReceipt struct {
+	ID     string   `json:"id"`
+	Sent   []string `json:"sent"`
+	Failed []string `json:"failed,omitempty"`
+}
+
+func wantsSendReceipt(source types.LocalParticipant, kind livekit.DataPacket_Kind, dp *livekit.DataPacket) bool {
+	return source != nil && kind == livekit.DataPacket_RELIABLE && dp.GetUser().GetId() != "" && dp.GetUser().GetTopic() != SendReceiptTopic
+}
+
+func replySendReceipt(source types.LocalParticipant, id string, sent []livekit.ParticipantIdentity, failed []livekit.ParticipantIdentity) error {
+	receipt := SendReceipt{
+		ID:   id,
+		Sent: make([]string, 0, len(sent)),
+	}
+	for _, identity := range sent {
+		receipt.Sent = append(receipt.Sent, string(identity))
+	}
+	for _, identity := range failed {
+		receipt.Failed = append(receipt.Failed, string(identity))
+	}
+
+	payload, err := json.Marshal(receipt)
+	if err != nil {
+		return err
+	}
+	topic := SendReceiptTopic
+	dp := &livekit.DataPacket{
+		Kind:                  livekit.DataPacket_RELIABLE,
+		DestinationIdentities: []string{string(source.Identity())},
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload:               payload,
+				Topic:                 &topic,
+				DestinationIdentities: []string{string(source.Identity())},
+			},
+		},
+	}
+	data, err := proto.Marshal(dp)
+	if err != nil {
+		return err
+	}
+	return source.SendDataPacket(livekit.DataPacket_RELIABLE, data)
+}
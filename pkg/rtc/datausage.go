@@ -0,0 +1,71 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+// DataUsage is the data channel traffic of a participant, from the participant's point of view.
+// it is also reported per participant in the data channel analytics stats
+type DataUsage struct {
+	BytesSent        uint64 `json:"bytes_sent"`
+	MessagesSent     uint32 `json:"messages_sent"`
+	BytesReceived    uint64 `json:"bytes_received"`
+	MessagesReceived uint32 `json:"messages_received"`
+}
+
+func NewDataUsage(totals *telemetry.TrafficTotals) DataUsage {
+	if totals == nil {
+		return DataUsage{}
+	}
+	return DataUsage{
+		BytesSent:        totals.RecvBytes,
+		MessagesSent:     totals.RecvMessages,
+		BytesReceived:    totals.SendBytes,
+		MessagesReceived: totals.SendMessages,
+	}
+}
+
+func (u *DataUsage) Add(other DataUsage) {
+	u.BytesSent += other.BytesSent
+	u.MessagesSent += other.MessagesSent
+	u.BytesReceived += other.BytesReceived
+	u.MessagesReceived += other.MessagesReceived
+}
+
+// RoomDataUsage is the data channel traffic of a room. Total includes participants that have left
+type RoomDataUsage struct {
+	Total        DataUsage                                 `json:"total"`
+	Participants map[livekit.ParticipantIdentity]DataUsage `json:"participants"`
+}
+
+func (r *Room) GetDataUsage() *RoomDataUsage {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	usage := &RoomDataUsage{
+		Total:        r.departedDataUsage,
+		Participants: make(map[livekit.ParticipantIdentity]DataUsage, len(r.participants)),
+	}
+	for identity, p := range r.participants {
+		pu := NewDataUsage(p.GetDataTrafficTotals())
+		usage.Participants[identity] = pu
+		usage.Total.Add(pu)
+	}
+	return usage
+}
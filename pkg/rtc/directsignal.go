@@ -0,0 +1,111 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+	"errors"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	// DirectSignalTopic relays app level signaling, e.g. to set up a side-channel file transfer, between two participants.
+	// messages must be addressed to exactly one participant, and are only sent to that participant: they are never
+	// retained, bridged, moderated or reported outside of the room
+	DirectSignalTopic = "lk.direct_signal"
+	// DirectSignalErrorTopic tells the sender why a direct signal could not be delivered
+	DirectSignalErrorTopic = "lk.direct_signal.error"
+)
+
+var (
+	ErrDirectSignalDestination = errors.New("direct signal must have exactly one destination identity")
+	ErrDirectSignalUnavailable = errors.New("direct signal destination is not in the room")
+)
+
+// DirectSignalError is the json payload of direct signal error messages
+type DirectSignalError struct {
+	ID          string `json:"id,omitempty"`
+	Destination string `json:"destination,omitempty"`
+	Error       string `json:"error"`
+}
+
+func (r *Room) relayDirectSignal(source types.LocalParticipant, dp *livekit.DataPacket) {
+	u := dp.GetUser()
+	if len(dp.DestinationIdentities) == 0 {
+		dp.DestinationIdentities = u.DestinationIdentities
+	}
+	u.DestinationIdentities = dp.DestinationIdentities
+	dp.ParticipantIdentity = string(source.Identity())
+	u.ParticipantIdentity = dp.ParticipantIdentity
+	dp.Kind = livekit.DataPacket_RELIABLE
+
+	if len(dp.DestinationIdentities) != 1 || dp.DestinationIdentities[0] == string(source.Identity()) {
+		r.sendDirectSignalError(source, u, ErrDirectSignalDestination)
+		return
+	}
+
+	dest := r.GetParticipant(livekit.ParticipantIdentity(dp.DestinationIdentities[0]))
+	if dest == nil || dest.State() != livekit.ParticipantInfo_ACTIVE {
+		r.sendDirectSignalError(source, u, ErrDirectSignalUnavailable)
+		return
+	}
+
+	data, err := proto.Marshal(dp)
+	if err != nil {
+		source.GetLogger().Errorw("failed to marshal direct signal", err)
+		return
+	}
+	if err := dest.SendDataPacket(livekit.DataPacket_RELIABLE, data); err != nil {
+		r.sendDirectSignalError(source, u, err)
+	}
+}
+
+func (r *Room) sendDirectSignalError(source types.LocalParticipant, u *livekit.UserPacket, sendErr error) {
+	signalErr := DirectSignalError{
+		ID:    u.GetId(),
+		Error: sendErr.Error(),
+	}
+	if len(u.DestinationIdentities) == 1 {
+		signalErr.Destination = u.DestinationIdentities[0]
+	}
+	payload, err := json.Marshal(signalErr)
+	if err != nil {
+		return
+	}
+
+	topic := DirectSignalErrorTopic
+	data, err := proto.Marshal(&livekit.DataPacket{
+		Kind:                  livekit.DataPacket_RELIABLE,
+		DestinationIdentities: []string{string(source.Identity())},
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload:               payload,
+				Topic:                 &topic,
+				DestinationIdentities: []string{string(source.Identity())},
+			},
+		},
+	})
+	if err != nil {
+		return
+	}
+	if err := source.SendDataPacket(livekit.DataPacket_RELIABLE, data); err != nil {
+		source.GetLogger().Infow("could not send direct signal error", "error", err)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	// DuckingTopic carries hints of which participants subscribers should attenuate while a priority source speaks,
+	// e.g. the original speaker while an interpreter talks. hints are sent to everyone when they change
+	DuckingTopic = "lk.ducking"
+	// DuckingPriorityAttribute marks a participant as a priority audio source, with a positive integer priority.
+	// while speaking, participants with a lower or no priority are ducked
+	DuckingPriorityAttribute = "lk.ducking.priority"
+	// DuckingGainAttribute sets the gain, between 0 and 1, of participants ducked by a priority source
+	DuckingGainAttribute = "lk.ducking.gain"
+
+	defaultDuckingGain = 0.25
+)
+
+// DuckingHint is the json payload of ducking messages, Active is false once priority sources stop speaking
+type DuckingHint struct {
+	Active  bool     `json:"active"`
+	Sources []string `json:"sources,omitempty"`
+	Duck    []string `json:"duck,omitempty"`
+	Gain    float64  `json:"gain,omitempty"`
+}
+
+func (h *DuckingHint) equal(other *DuckingHint) bool {
+	if h == nil || other == nil {
+		return h.isActive() == other.isActive()
+	}
+	return h.Active == other.Active && h.Gain == other.Gain && slices.Equal(h.Sources, other.Sources) && slices.Equal(h.Duck, other.Duck)
+}
+
+func (h *DuckingHint) isActive() bool {
+	return h != nil && h.Active
+}
+
+func duckingPriority(p types.LocalParticipant) (int, float64) {
+	grants := p.ClaimGrants()
+	if grants == nil {
+		return 0, 0
+	}
+	priority, err := strconv.Atoi(grants.Attributes[DuckingPriorityAttribute])
+	if err != nil || priority <= 0 {
+		return 0, 0
+	}
+	gain, err := strconv.ParseFloat(grants.Attributes[DuckingGainAttribute], 64)
+	if err != nil || gain < 0 || gain > 1 {
+		gain = defaultDuckingGain
+	}
+	return priority, gain
+}
+
+// computeDuckingHint ducks participants below the highest priority of active speakers
+func (r *Room) computeDuckingHint(speakers []*livekit.SpeakerInfo) *DuckingHint {
+	participants := r.GetParticipants()
+	active := make(map[livekit.ParticipantID]bool, len(speakers))
+	for _, s := range speakers {
+		active[livekit.ParticipantID(s.Sid)] = true
+	}
+
+	hint := &DuckingHint{}
+	maxPriority := 0
+	priorities := make(map[livekit.ParticipantIdentity]int, len(participants))
+	for _, p := range participants {
+		priority, gain := duckingPriority(p)
+		priorities[p.Identity()] = priority
+		if priority == 0 || !active[p.ID()] || priority < maxPriority {
+			continue
+		}
+		if priority > maxPriority {
+			maxPriority = priority
+			hint.Sources = hint.Sources[:0]
+			hint.Gain = gain
+		}
+		hint.Sources = append(hint.Sources, string(p.Identity()))
+		hint.Gain = min(hint.Gain, gain)
+	}
+	if maxPriority == 0 {
+		return hint
+	}
+
+	hint.Active = true
+	for _, p := range participants {
+		if !p.Hidden() && priorities[p.Identity()] < maxPriority {
+			hint.Duck = append(hint.Duck, string(p.Identity()))
+		}
+	}
+	slices.Sort(hint.Sources)
+	slices.Sort(hint.Duck)
+	return hint
+}
+
+// updateDucking sends the ducking hint for the current speakers to everyone when it changed
+func (r *Room) updateDucking(speakers []*livekit.SpeakerInfo) {
+	hint := r.computeDuckingHint(speakers)
+
+	r.lock.Lock()
+	if hint.equal(r.duckingHint) {
+		r.lock.Unlock()
+		return
+	}
+	r.duckingHint = hint
+	r.lock.Unlock()
+
+	dp, err := duckingPacket(hint)
+	if err != nil {
+		r.Logger.Errorw("failed to marshal ducking hint", err)
+		return
+	}
+	BroadcastDataPacketForRoom(r, nil, livekit.DataPacket_RELIABLE, dp, r.Logger)
+}
+
+// sendDuckingHint sends the current ducking hint to a participant that just became active, while ducking
+func (r *Room) sendDuckingHint(p types.LocalParticipant) {
+	r.lock.RLock()
+	hint := r.duckingHint
+	r.lock.RUnlock()
+	if !hint.isActive() {
+		return
+	}
+
+	dp, err := duckingPacket(hint)
+	if err != nil {
+		return
+	}
+	dp.DestinationIdentities = []string{string(p.Identity())}
+	BroadcastDataPacketForRoom(r, nil, livekit.DataPacket_RELIABLE, dp, r.Logger)
+}
+
+func duckingPacket(hint *DuckingHint) (*livekit.DataPacket, error) {
+	payload, err := json.Marshal(hint)
+	if err != nil {
+		return nil, err
+	}
+	topic := DuckingTopic
+	return &livekit.DataPacket{
+		Kind: livekit.DataPacket_RELIABLE,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload: payload,
+				Topic:   &topic,
+			},
+		},
+	}, nil
+}
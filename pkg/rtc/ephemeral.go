@@ -0,0 +1,206 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// EphemeralTopicPrefix marks data messages such as reactions and typing indicators as ephemeral, e.g. lk.ephemeral.reaction.
+// ephemeral messages are moderated like other messages, but are only fanned out over the lossy channel: they are never
+// retained or bridged, are rate limited separately, expire at their end_time and are not sent to participants with
+// poor connection quality
+const EphemeralTopicPrefix = "lk.ephemeral."
+
+const (
+	defaultEphemeralMessagesPerSecond = 20
+	defaultEphemeralTTL               = 5 * time.Second
+)
+
+var ErrEphemeralTopicInvalid = errors.New("ephemeral topic must start with " + EphemeralTopicPrefix)
+
+// IsEphemeralTopic returns true for topics of ephemeral messages, e.g. lk.ephemeral.reaction
+func IsEphemeralTopic(topic string) bool {
+	return strings.HasPrefix(topic, EphemeralTopicPrefix) && len(topic) > len(EphemeralTopicPrefix)
+}
+
+// ephemeralLimiter counts ephemeral messages per participant in one second windows
+type ephemeralLimiter struct {
+	lock    sync.Mutex
+	limit   int
+	ttl     time.Duration
+	windows map[livekit.ParticipantIdentity]*ephemeralWindow
+	// participants with poor or lost connection quality, as of the last connection quality update
+	congested map[livekit.ParticipantID]bool
+}
+
+type ephemeralWindow struct {
+	start time.Time
+	count int
+}
+
+func newEphemeralLimiter(conf config.EphemeralDataConfig) *ephemeralLimiter {
+	l := &ephemeralLimiter{
+		limit:   conf.MaxMessagesPerSecond,
+		ttl:     conf.TTL,
+		windows: make(map[livekit.ParticipantIdentity]*ephemeralWindow),
+	}
+	if l.limit == 0 {
+		l.limit = defaultEphemeralMessagesPerSecond
+	}
+	if l.ttl == 0 {
+		l.ttl = defaultEphemeralTTL
+	}
+	return l
+}
+
+func (l *ephemeralLimiter) allow(identity livekit.ParticipantIdentity) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	w := l.windows[identity]
+	if w == nil || now.Sub(w.start) >= time.Second {
+		w = &ephemeralWindow{start: now}
+		l.windows[identity] = w
+	}
+	if w.count >= l.limit {
+		return false
+	}
+	w.count++
+	return true
+}
+
+func (l *ephemeralLimiter) updateConnectionQuality(infos map[livekit.ParticipantID]*livekit.ConnectionQualityInfo) {
+	congested := make(map[livekit.ParticipantID]bool)
+	for pID, info := range infos {
+		switch info.GetQuality() {
+		case livekit.ConnectionQuality_POOR, livekit.ConnectionQuality_LOST:
+			congested[pID] = true
+		}
+	}
+
+	l.lock.Lock()
+	l.congested = congested
+	l.lock.Unlock()
+}
+
+func (l *ephemeralLimiter) isCongested(pID livekit.ParticipantID) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.congested[pID]
+}
+
+func (l *ephemeralLimiter) forget(identity livekit.ParticipantIdentity) {
+	l.lock.Lock()
+	delete(l.windows, identity)
+	l.lock.Unlock()
+}
+
+// SendEphemeralData fans out an ephemeral message from the server. it expires after ttl, or the configured default
+func (r *Room) SendEphemeralData(topic string, payload []byte, destinationIdentities []string, ttl time.Duration) error {
+	if !IsEphemeralTopic(topic) {
+		return ErrEphemeralTopicInvalid
+	}
+	if ttl <= 0 {
+		ttl = r.ephemeral.ttl
+	}
+	endTime := uint64(time.Now().Add(ttl).UnixMilli())
+	r.broadcastEphemeral(nil, &livekit.DataPacket{
+		Kind:                  livekit.DataPacket_LOSSY,
+		DestinationIdentities: destinationIdentities,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload:               payload,
+				Topic:                 &topic,
+				DestinationIdentities: destinationIdentities,
+				EndTime:               &endTime,
+			},
+		},
+	})
+	return nil
+}
+
+// admitEphemeralData applies the rate limit of ephemeral messages and sets their expiry, before they are
+// intercepted and moderated
+func (r *Room) admitEphemeralData(source types.LocalParticipant, dp *livekit.DataPacket) bool {
+	if source != nil && !r.ephemeral.allow(source.Identity()) {
+		source.GetLogger().Debugw("ephemeral data rate limited", "topic", dp.GetUser().GetTopic())
+		return false
+	}
+	u := dp.GetUser()
+	if u.EndTime == nil {
+		endTime := uint64(time.Now().Add(r.ephemeral.ttl).UnixMilli())
+		u.EndTime = &endTime
+	}
+	return true
+}
+
+func (r *Room) broadcastEphemeral(source types.LocalParticipant, dp *livekit.DataPacket) {
+	u := dp.GetUser()
+	if time.Now().UnixMilli() > int64(u.GetEndTime()) {
+		return
+	}
+
+	if len(dp.DestinationIdentities) == 0 {
+		dp.DestinationIdentities = u.DestinationIdentities
+	} else {
+		u.DestinationIdentities = dp.DestinationIdentities
+	}
+	if source != nil {
+		dp.ParticipantIdentity = string(source.Identity())
+		u.ParticipantIdentity = dp.ParticipantIdentity
+	}
+	dp.Kind = livekit.DataPacket_LOSSY
+
+	var destParticipants []types.LocalParticipant
+	for _, op := range r.GetLocalParticipants() {
+		if op.State() != livekit.ParticipantInfo_ACTIVE || (source != nil && op.ID() == source.ID()) {
+			continue
+		}
+		if len(dp.DestinationIdentities) > 0 && !slices.Contains(dp.DestinationIdentities, string(op.Identity())) {
+			continue
+		}
+		// shed ephemeral traffic before media suffers on congested connections
+		if r.ephemeral.isCongested(op.ID()) {
+			continue
+		}
+		destParticipants = append(destParticipants, op)
+	}
+	if len(destParticipants) == 0 {
+		return
+	}
+
+	data, err := proto.Marshal(dp)
+	if err != nil {
+		r.Logger.Errorw("failed to marshal ephemeral data packet", err)
+		return
+	}
+	for _, op := range destParticipants {
+		// failures, including full buffers, are dropped silently
+		_ = op.SendDataPacket(livekit.DataPacket_LOSSY, data)
+	}
+}
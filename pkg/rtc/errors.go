@@ -27,6 +27,7 @@ var (
 	ErrDataChannelUnavailable  = errors.New("data channel is not available")
 	ErrDataChannelBufferFull   = errors.New("data channel buffer is full")
 	ErrTransportFailure        = errors.New("transport failure")
+	ErrChaosNotEnabled         = errors.New("chaos is not enabled")
 	ErrEmptyIdentity           = errors.New("participant identity cannot be empty")
 	ErrEmptyParticipantID      = errors.New("participant ID cannot be empty")
 	ErrMissingGrants           = errors.New("VideoGrant is missing")
@@ -42,4 +43,5 @@ var (
 	ErrTrackNotAttached          = errors.New("track is not yet attached")
 	ErrTrackNotBound             = errors.New("track not bound")
 	ErrSubscriptionLimitExceeded = errors.New("participant has exceeded its subscription limit")
+	ErrTrackSubscribersExceeded  = errors.New("track has reached its max subscribers")
 )
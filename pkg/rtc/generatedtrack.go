@@ -0,0 +1,206 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/utils"
+	"github.com/livekit/protocol/utils/guid"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu/relay"
+)
+
+type generatedTrackParams struct {
+	Identity livekit.ParticipantIdentity
+	// generated when empty
+	ParticipantID livekit.ParticipantID
+	Name          string
+	// a track id is generated when the info has none
+	TrackInfo *livekit.TrackInfo
+	Codec     webrtc.RTPCodecParameters
+	SendPLI   func()
+}
+
+// GeneratedTrack is a track produced by the server, e.g. mixed audio, published by a virtual participant.
+// It is fed like a relayed track, with packets generated locally instead of received from another node.
+type GeneratedTrack struct {
+	lock     sync.Mutex
+	info     *livekit.ParticipantInfo
+	receiver *relay.Receiver
+
+	*MediaTrackReceiver
+}
+
+func (r *Room) newGeneratedTrack(params generatedTrackParams) *GeneratedTrack {
+	participantID := params.ParticipantID
+	if participantID == "" {
+		participantID = livekit.ParticipantID(guid.New(utils.ParticipantPrefix))
+	}
+	ti := params.TrackInfo
+	if ti.Sid == "" {
+		ti.Sid = guid.New(utils.TrackPrefix)
+	}
+	ti.MimeType = params.Codec.MimeType
+	trackLogger := LoggerWithTrack(LoggerWithParticipant(r.Logger, params.Identity, participantID, false), livekit.TrackID(ti.Sid), false)
+
+	t := &GeneratedTrack{
+		info: &livekit.ParticipantInfo{
+			Sid:      string(participantID),
+			Identity: string(params.Identity),
+			Name:     params.Name,
+			State:    livekit.ParticipantInfo_ACTIVE,
+			JoinedAt: time.Now().Unix(),
+			Tracks:   []*livekit.TrackInfo{ti},
+			Version:  1,
+		},
+	}
+	t.MediaTrackReceiver = NewMediaTrackReceiver(MediaTrackReceiverParams{
+		MediaTrack:          t,
+		IsRelayed:           true,
+		ParticipantID:       participantID,
+		ParticipantIdentity: params.Identity,
+		ParticipantVersion:  1,
+		ReceiverConfig:      r.config.Receiver,
+		SubscriberConfig:    r.config.Subscriber,
+		AudioConfig:         *r.audioConfig,
+		Telemetry:           r.telemetry,
+		Logger:              trackLogger,
+	}, ti)
+
+	var originNodeID livekit.NodeID
+	if r.serverInfo != nil {
+		originNodeID = livekit.NodeID(r.serverInfo.NodeId)
+	}
+	t.receiver = relay.NewReceiver(relay.ReceiverParams{
+		TrackInfo:      ti,
+		Codec:          params.Codec,
+		OriginNodeID:   originNodeID,
+		BufferFactory:  r.GetBufferFactory(),
+		TrackersConfig: r.config.Receiver.StreamTrackers,
+		Logger:         trackLogger,
+		SendPLI: func(_layer int32, _force bool) {
+			if params.SendPLI != nil {
+				params.SendPLI()
+			}
+		},
+	})
+
+	t.MediaTrackReceiver.SetPotentialCodecs([]webrtc.RTPCodecParameters{params.Codec}, nil)
+	t.MediaTrackReceiver.SetupReceiver(t.receiver, 0, "")
+	return t
+}
+
+func (t *GeneratedTrack) ToProto() *livekit.TrackInfo {
+	return t.MediaTrackReceiver.TrackInfoClone()
+}
+
+func (t *GeneratedTrack) OnTrackSubscribed() {}
+
+// ParticipantInfo returns the info of the virtual participant publishing the track
+func (t *GeneratedTrack) ParticipantInfo() *livekit.ParticipantInfo {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return proto.Clone(t.info).(*livekit.ParticipantInfo)
+}
+
+// WriteRTP feeds a generated packet to subscribers
+func (t *GeneratedTrack) WriteRTP(pkt *rtp.Packet) {
+	payload, err := pkt.Marshal()
+	if err != nil {
+		return
+	}
+	t.receiver.HandlePacket(&relay.Packet{
+		Type:    relay.PacketTypeRTP,
+		TrackID: t.ID(),
+		Payload: payload,
+	})
+}
+
+func (t *GeneratedTrack) Close(isExpectedToResume bool) {
+	t.receiver.Close()
+
+	t.MediaTrackReceiver.SetClosing()
+	t.MediaTrackReceiver.ClearAllReceivers(isExpectedToResume)
+	t.MediaTrackReceiver.Close(isExpectedToResume)
+}
+
+// generatedTracks returns the tracks generated by the room, they are created with the room and never change
+func (r *Room) generatedTracks() []types.MediaTrack {
+	var tracks []types.MediaTrack
+	if r.mixedAudio != nil {
+		tracks = append(tracks, r.mixedAudio.track)
+	}
+	if r.composedVideo != nil {
+		tracks = append(tracks, r.composedVideo.track)
+	}
+	return tracks
+}
+
+// resolveGeneratedTrack returns the track a subscriber receives for a generated track and whether it may subscribe to it,
+// ok is false when the track isn't generated
+func (r *Room) resolveGeneratedTrack(subIdentity livekit.ParticipantIdentity, trackID livekit.TrackID) (track types.MediaTrack, hasPermission bool, ok bool) {
+	if r.mixedAudio != nil && trackID == r.mixedAudio.track.ID() {
+		// the mix of each subscriber only has the tracks it may subscribe to
+		track = r.getMixedAudioOutput(subIdentity)
+		return track, track != nil, true
+	}
+	if r.composedVideo != nil && trackID == r.composedVideo.track.ID() {
+		return r.composedVideo.track, r.composedVideo.track.hasPermission(subIdentity), true
+	}
+	return nil, false, false
+}
+
+func (r *Room) addGeneratedTrackInputs(p types.LocalParticipant, track types.MediaTrack) {
+	r.addMixedAudioInput(p, track)
+	r.addComposedVideoInput(p, track)
+}
+
+func (r *Room) removeGeneratedTrackInputs(track types.MediaTrack) {
+	r.removeMixedAudioInput(track.ID())
+	r.removeComposedVideoInput(track.ID())
+}
+
+// updateGeneratedTrackPermissions applies changes of subscription permissions of publishers to generated tracks
+func (r *Room) updateGeneratedTrackPermissions() {
+	r.updateMixedAudioPermissions()
+	r.updateComposedVideoPermissions()
+}
+
+// assumes lock is already acquired
+func (r *Room) getGeneratedParticipantInfoLocked() []*livekit.ParticipantInfo {
+	var pis []*livekit.ParticipantInfo
+	if r.mixedAudio != nil {
+		pis = append(pis, r.mixedAudio.track.ParticipantInfo())
+	}
+	if r.composedVideo != nil {
+		pis = append(pis, r.composedVideo.track.ParticipantInfo())
+	}
+	return pis
+}
+
+func (r *Room) closeGeneratedTracks() {
+	for _, track := range r.generatedTracks() {
+		r.trackManager.RemoveTrack(track)
+		track.Close(false)
+	}
+}
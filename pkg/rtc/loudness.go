@@ -0,0 +1,101 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu/loudness"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+// TrackLoudness is the loudness of an audio track published in the room
+type TrackLoudness struct {
+	ParticipantIdentity livekit.ParticipantIdentity `json:"participant_identity"`
+	TrackID             livekit.TrackID             `json:"track_id"`
+	loudness.Loudness
+}
+
+type loudnessTrack struct {
+	identity livekit.ParticipantIdentity
+	track    *loudness.Track
+}
+
+func (r *Room) addLoudnessTrack(p types.LocalParticipant, track types.MediaTrack) {
+	if !r.audioConfig.MeasureLoudness || track.Kind() != livekit.TrackType_AUDIO {
+		return
+	}
+	receivers := track.Receivers()
+	if len(receivers) == 0 {
+		return
+	}
+
+	lt, err := loudness.NewTrack(receivers[0], p.GetLogger())
+	if err != nil {
+		p.GetLogger().Infow("could not measure loudness", "error", err, "trackID", track.ID())
+		return
+	}
+
+	r.lock.Lock()
+	existing := r.loudness[track.ID()]
+	r.loudness[track.ID()] = &loudnessTrack{identity: p.Identity(), track: lt}
+	r.lock.Unlock()
+	if existing != nil {
+		existing.track.Stop()
+	}
+}
+
+func (r *Room) removeLoudnessTrack(trackID livekit.TrackID) {
+	r.lock.Lock()
+	lt := r.loudness[trackID]
+	delete(r.loudness, trackID)
+	r.lock.Unlock()
+
+	if lt != nil {
+		lt.track.Stop()
+		l := lt.track.Loudness()
+		prometheus.RecordAudioLoudness(l.Integrated, l.SamplePeak)
+	}
+}
+
+func (r *Room) closeLoudnessTracks() {
+	r.lock.RLock()
+	trackIDs := make([]livekit.TrackID, 0, len(r.loudness))
+	for trackID := range r.loudness {
+		trackIDs = append(trackIDs, trackID)
+	}
+	r.lock.RUnlock()
+
+	for _, trackID := range trackIDs {
+		r.removeLoudnessTrack(trackID)
+	}
+}
+
+// GetLoudness returns the loudness of audio tracks published in the room, empty unless loudness measurement is enabled
+func (r *Room) GetLoudness() []*TrackLoudness {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	tracks := make([]*TrackLoudness, 0, len(r.loudness))
+	for trackID, lt := range r.loudness {
+		tracks = append(tracks, &TrackLoudness{
+			ParticipantIdentity: lt.identity,
+			TrackID:             trackID,
+			Loudness:            lt.track.Loudness(),
+		})
+	}
+	return tracks
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"github.com/pion/rtp"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// InterceptedTrack identifies the published track an intercepted packet was received on
+type InterceptedTrack struct {
+	Room                livekit.RoomName
+	ParticipantIdentity livekit.ParticipantIdentity
+	TrackID             livekit.TrackID
+	MimeType            string
+}
+
+// MediaInterceptor observes, and may modify, media and data passing through the rooms of a node,
+// and is told about room events. Packet methods are called on the forwarding path and must not block
+type MediaInterceptor interface {
+	// InterceptRTP is called for each packet received on a published track, before it is buffered and forwarded.
+	// The payload may be modified in place, returning false drops the packet
+	InterceptRTP(track InterceptedTrack, pkt *rtp.Packet) bool
+	// InterceptData is called for data messages before they are moderated and sent to the room.
+	// The packet may be modified in place, returning false drops it. sender is empty for server sent messages
+	InterceptData(room livekit.RoomName, sender livekit.ParticipantIdentity, dp *livekit.DataPacket) bool
+	// OnRoomEvent is called with every webhook event, whether or not webhooks are configured. It is called in order
+	// from a separate goroutine, events are dropped when it falls behind, and must not be modified
+	OnRoomEvent(event *livekit.WebhookEvent)
+}
+
+// MediaInterceptors runs interceptors in order, a packet dropped by one is not passed to the rest
+type MediaInterceptors []MediaInterceptor
+
+func (m MediaInterceptors) InterceptRTP(track InterceptedTrack, pkt *rtp.Packet) bool {
+	for _, interceptor := range m {
+		if !interceptor.InterceptRTP(track, pkt) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m MediaInterceptors) InterceptData(room livekit.RoomName, sender livekit.ParticipantIdentity, dp *livekit.DataPacket) bool {
+	for _, interceptor := range m {
+		if !interceptor.InterceptData(room, sender, dp) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m MediaInterceptors) OnRoomEvent(event *livekit.WebhookEvent) {
+	for _, interceptor := range m {
+		interceptor.OnRoomEvent(event)
+	}
+}
+
+// SetMediaInterceptor enables interception of data messages sent to the room.
+// Media is intercepted by the published tracks, see ParticipantParams.MediaInterceptor
+func (r *Room) SetMediaInterceptor(interceptor MediaInterceptor) {
+	r.lock.Lock()
+	r.mediaInterceptor = interceptor
+	r.lock.Unlock()
+}
+
+// interceptData returns false when the interceptor dropped the packet
+func (r *Room) interceptData(source types.LocalParticipant, dp *livekit.DataPacket) bool {
+	r.lock.RLock()
+	interceptor := r.mediaInterceptor
+	r.lock.RUnlock()
+	if interceptor == nil {
+		return true
+	}
+
+	var sender livekit.ParticipantIdentity
+	if source != nil {
+		sender = source.Identity()
+	}
+	return interceptor.InterceptData(r.Name(), sender, dp)
+}
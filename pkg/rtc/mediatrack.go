@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v3"
 	"go.uber.org/atomic"
 
@@ -34,6 +35,7 @@ import (
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
 	"github.com/livekit/livekit-server/pkg/sfu/connectionquality"
 	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 	util "github.com/livekit/mediatransportutil"
 )
 
@@ -49,6 +51,7 @@ type MediaTrack struct {
 	*MediaLossProxy
 
 	dynacastManager *DynacastManager
+	health          *trackHealth
 
 	lock sync.RWMutex
 
@@ -73,6 +76,8 @@ type MediaTrackParams struct {
 	OnRTCP                func([]rtcp.Packet)
 	ForwardStats          *sfu.ForwardStats
 	OnTrackEverSubscribed func(livekit.TrackID)
+	RoomName              livekit.RoomName
+	MediaInterceptor      MediaInterceptor
 }
 
 func NewMediaTrack(params MediaTrackParams, ti *livekit.TrackInfo) *MediaTrack {
@@ -105,6 +110,15 @@ func NewMediaTrack(params MediaTrackParams, ti *livekit.TrackInfo) *MediaTrack {
 		t.MediaTrackReceiver.OnMediaLossFeedback(t.MediaLossProxy.HandleMaxLossFeedback)
 	}
 
+	// screen shares are exempt, static content does not advance timestamps and looks frozen
+	if ti.Type == livekit.TrackType_VIDEO && ti.Source != livekit.TrackSource_SCREEN_SHARE && params.VideoConfig.Health.Enabled {
+		t.health = newTrackHealth(func(from TrackHealth, to TrackHealth) {
+			t.params.Logger.Infow("video track health changed", "from", from, "health", to)
+			prometheus.RecordVideoHealthChange(string(from), string(to))
+		})
+		prometheus.AddVideoHealthTrack(string(TrackHealthOK))
+	}
+
 	if ti.Type == livekit.TrackType_VIDEO {
 		t.dynacastManager = NewDynacastManager(DynacastManagerParams{
 			DynacastPauseDelay: params.VideoConfig.DynacastPauseDelay,
@@ -165,6 +179,21 @@ func (t *MediaTrack) SignalCid() string {
 	return t.params.SignalCid
 }
 
+// Health returns the health of a video track, ok when health detection is not enabled
+func (t *MediaTrack) Health() TrackHealth {
+	if t.health == nil {
+		return TrackHealthOK
+	}
+	return t.health.get()
+}
+
+// UpdateSnapshotHealth reports whether the latest snapshot of the track is black, or frozen
+func (t *MediaTrack) UpdateSnapshotHealth(black bool, frozen bool) {
+	if t.health != nil {
+		t.health.setSnapshotHealth(black, frozen)
+	}
+}
+
 func (t *MediaTrack) HasSdpCid(cid string) bool {
 	if t.params.SdpCid == cid {
 		return true
@@ -273,6 +302,28 @@ func (t *MediaTrack) AddReceiver(receiver *webrtc.RTPReceiver, track *webrtc.Tra
 			return false
 		}
 
+		opts := []sfu.ReceiverOpts{
+			sfu.WithPliThrottleConfig(t.params.PLIThrottleConfig),
+			sfu.WithAudioConfig(t.params.AudioConfig),
+			sfu.WithLoadBalanceThreshold(20),
+			sfu.WithStreamTrackers(),
+			sfu.WithForwardStats(t.params.ForwardStats),
+			sfu.WithEverHasDownTrackAdded(t.OnTrackSubscribed),
+		}
+		if t.health != nil {
+			opts = append(opts, sfu.WithFreezeDetection(t.params.VideoConfig.Health.FreezeTimeout))
+		}
+		if interceptor := t.params.MediaInterceptor; interceptor != nil {
+			interceptedTrack := InterceptedTrack{
+				Room:                t.params.RoomName,
+				ParticipantIdentity: t.params.ParticipantIdentity,
+				TrackID:             t.ID(),
+				MimeType:            mime,
+			}
+			opts = append(opts, sfu.WithPacketInterceptor(func(pkt *rtp.Packet) bool {
+				return interceptor.InterceptRTP(interceptedTrack, pkt)
+			}))
+		}
 		newWR := sfu.NewWebRTCReceiver(
 			receiver,
 			track,
@@ -280,12 +331,7 @@ func (t *MediaTrack) AddReceiver(receiver *webrtc.RTPReceiver, track *webrtc.Tra
 			LoggerWithCodecMime(t.params.Logger, mime),
 			t.params.OnRTCP,
 			t.params.VideoConfig.StreamTracker,
-			sfu.WithPliThrottleConfig(t.params.PLIThrottleConfig),
-			sfu.WithAudioConfig(t.params.AudioConfig),
-			sfu.WithLoadBalanceThreshold(20),
-			sfu.WithStreamTrackers(),
-			sfu.WithForwardStats(t.params.ForwardStats),
-			sfu.WithEverHasDownTrackAdded(t.OnTrackSubscribed),
+			opts...,
 		)
 		newWR.OnCloseHandler(func() {
 			t.MediaTrackReceiver.SetClosing()
@@ -304,6 +350,9 @@ func (t *MediaTrack) AddReceiver(receiver *webrtc.RTPReceiver, track *webrtc.Tra
 			})
 
 			newWR.OnMaxLayerChange(t.onMaxLayerChange)
+			if t.health != nil {
+				newWR.OnFreezeChange(t.health.setTimestampFrozen)
+			}
 		}
 		if t.PrimaryReceiver() == nil {
 			// primary codec published, set potential codecs
@@ -419,6 +468,11 @@ func (t *MediaTrack) Close(isExpectedToResume bool) {
 	if t.dynacastManager != nil {
 		t.dynacastManager.Close()
 	}
+	if t.health != nil {
+		if health, ok := t.health.close(); ok {
+			prometheus.RemoveVideoHealthTrack(string(health))
+		}
+	}
 	t.MediaTrackReceiver.ClearAllReceivers(isExpectedToResume)
 	t.MediaTrackReceiver.Close(isExpectedToResume)
 }
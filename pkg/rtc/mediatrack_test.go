@@ -20,6 +20,9 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
 )
 
 func TestTrackInfo(t *testing.T) {
@@ -172,3 +175,59 @@ func TestGetQualityForDimension(t *testing.T) {
 	})
 
 }
+
+func TestTrackHealth(t *testing.T) {
+	ti := &livekit.TrackInfo{
+		Sid:  "testsid",
+		Type: livekit.TrackType_VIDEO,
+	}
+	mt := NewMediaTrack(MediaTrackParams{
+		VideoConfig: config.VideoConfig{Health: config.VideoHealthConfig{Enabled: true}},
+		Logger:      logger.GetLogger(),
+	}, ti)
+	require.Equal(t, TrackHealthOK, mt.Health())
+
+	var changes [][2]TrackHealth
+	mt.health.onChange = func(from TrackHealth, to TrackHealth) {
+		changes = append(changes, [2]TrackHealth{from, to})
+	}
+
+	mt.health.setTimestampFrozen(true)
+	require.Equal(t, TrackHealthFrozen, mt.Health())
+	require.Equal(t, [][2]TrackHealth{{TrackHealthOK, TrackHealthFrozen}}, changes)
+
+	// black takes precedence, and is reported once
+	mt.UpdateSnapshotHealth(true, false)
+	mt.UpdateSnapshotHealth(true, false)
+	require.Equal(t, TrackHealthBlack, mt.Health())
+	require.Len(t, changes, 2)
+	require.Equal(t, [2]TrackHealth{TrackHealthFrozen, TrackHealthBlack}, changes[1])
+
+	mt.UpdateSnapshotHealth(false, false)
+	require.Equal(t, TrackHealthFrozen, mt.Health())
+	mt.health.setTimestampFrozen(false)
+	require.Equal(t, TrackHealthOK, mt.Health())
+	require.Len(t, changes, 4)
+
+	// not reported once closed
+	mt.Close(false)
+	mt.health.setTimestampFrozen(true)
+	require.Equal(t, TrackHealthOK, mt.Health())
+	require.Len(t, changes, 4)
+
+	// not detected when disabled
+	require.Equal(t, TrackHealthOK, NewMediaTrack(MediaTrackParams{}, ti).Health())
+	NewMediaTrack(MediaTrackParams{}, ti).UpdateSnapshotHealth(true, true)
+
+	// not detected on screen shares, whose static content looks frozen
+	screenShare := NewMediaTrack(MediaTrackParams{
+		VideoConfig: config.VideoConfig{Health: config.VideoHealthConfig{Enabled: true}},
+		Logger:      logger.GetLogger(),
+	}, &livekit.TrackInfo{
+		Sid:    "screensid",
+		Type:   livekit.TrackType_VIDEO,
+		Source: livekit.TrackSource_SCREEN_SHARE,
+	})
+	screenShare.UpdateSnapshotHealth(false, true)
+	require.Equal(t, TrackHealthOK, screenShare.Health())
+}
@@ -906,3 +906,17 @@ func (t *MediaTrackReceiver) GetTrackStats() *livekit.RTPStats {
 
 	return buffer.AggregateRTPStats(stats)
 }
+
+// GetTrackStatsWithLayers returns receiver stats of the track merged across its simulcast streams and codecs,
+// with a breakdown per spatial layer
+func (t *MediaTrackReceiver) GetTrackStatsWithLayers() *buffer.RTPStatsWithLayers {
+	receivers := t.loadReceivers()
+	stats := make([]*buffer.RTPStatsWithLayers, 0, len(receivers))
+	for _, receiver := range receivers {
+		if receiverStats := receiver.GetTrackStatsWithLayers(); receiverStats != nil {
+			stats = append(stats, receiverStats)
+		}
+	}
+
+	return buffer.AggregateRTPStatsWithLayers(stats)
+}
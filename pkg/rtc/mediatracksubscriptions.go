@@ -129,19 +129,23 @@ func (t *MediaTrackSubscriptions) AddSubscriber(sub types.LocalParticipant, wr *
 	}
 
 	downTrack, err := sfu.NewDownTrack(sfu.DowntrackParams{
-		Codecs:                         codecs,
-		Source:                         t.params.MediaTrack.Source(),
-		Receiver:                       wr,
-		BufferFactory:                  sub.GetBufferFactory(),
-		SubID:                          subscriberID,
-		StreamID:                       streamID,
-		MaxTrack:                       maxTrack,
-		PlayoutDelayLimit:              sub.GetPlayoutDelayConfig(),
-		Pacer:                          sub.GetPacer(),
-		Trailer:                        trailer,
-		Logger:                         LoggerWithTrack(sub.GetLogger().WithComponent(sutils.ComponentSub), trackID, t.params.IsRelayed),
-		RTCPWriter:                     sub.WriteSubscriberRTCP,
-		DisableSenderReportPassThrough: sub.GetDisableSenderReportPassThrough(),
+		Codecs:                            codecs,
+		Source:                            t.params.MediaTrack.Source(),
+		Receiver:                          wr,
+		BufferFactory:                     sub.GetBufferFactory(),
+		SubID:                             subscriberID,
+		StreamID:                          streamID,
+		MaxTrack:                          maxTrack,
+		PlayoutDelayLimit:                 sub.GetPlayoutDelayConfig(),
+		Pacer:                             sub.GetPacer(),
+		Trailer:                           trailer,
+		Logger:                            LoggerWithTrack(sub.GetLogger().WithComponent(sutils.ComponentSub), trackID, t.params.IsRelayed),
+		RTCPWriter:                        sub.WriteSubscriberRTCP,
+		DisableSenderReportPassThrough:    sub.GetDisableSenderReportPassThrough(),
+		SenderReportSmoothingWindow:       sub.GetSenderReportSmoothingWindow(),
+		SenderReportClockSkewCompensation: sub.GetSenderReportClockSkewCompensation(),
+		SenderReportClock:                 sub.GetSenderReportClock(),
+		HeaderExtensions:                  sub.GetHeaderExtensions(),
 	})
 	if err != nil {
 		return nil, err
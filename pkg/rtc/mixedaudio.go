@@ -0,0 +1,258 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu/mixer"
+)
+
+// MixedAudioIdentity is the identity of the virtual participant publishing the mixed audio track of a room
+const MixedAudioIdentity livekit.ParticipantIdentity = "lk.mixer"
+
+var mixedAudioCodec = webrtc.RTPCodecParameters{
+	RTPCodecCapability: webrtc.RTPCodecCapability{
+		MimeType:    webrtc.MimeTypeOpus,
+		ClockRate:   mixer.SampleRate,
+		Channels:    2,
+		SDPFmtpLine: "minptime=10;useinbandfec=1",
+	},
+	PayloadType: 111,
+}
+
+// MixedAudioTrack is the mixed audio track published to the room. Subscribers don't receive the track itself, each of
+// them receives its own mix of the audio tracks it may subscribe to, without its own audio.
+type MixedAudioTrack struct {
+	mixer *mixer.Mixer
+
+	lock sync.Mutex
+	// publishers of the tracks mixed
+	inputs map[livekit.TrackID]types.LocalParticipant
+	// mix received by each subscriber
+	outputs map[livekit.ParticipantIdentity]*GeneratedTrack
+
+	*GeneratedTrack
+}
+
+type mixedAudio struct {
+	// identities mixed, all when empty
+	participants map[livekit.ParticipantIdentity]bool
+	track        *MixedAudioTrack
+}
+
+func (t *MixedAudioTrack) Close(isExpectedToResume bool) {
+	t.mixer.Stop()
+
+	t.lock.Lock()
+	outputs := t.outputs
+	t.outputs = nil
+	t.lock.Unlock()
+
+	for _, output := range outputs {
+		output.Close(isExpectedToResume)
+	}
+	t.GeneratedTrack.Close(isExpectedToResume)
+}
+
+// newMixedAudio creates the mixed audio track, returns nil when mixing is not possible
+func (r *Room) newMixedAudio(conf config.MixedAudioConfig) *mixedAudio {
+	m, err := mixer.New(mixer.Params{
+		Codec:  mixedAudioCodec,
+		Logger: r.Logger,
+	})
+	if err != nil {
+		r.Logger.Warnw("could not create audio mixer", err)
+		return nil
+	}
+	t := &MixedAudioTrack{
+		mixer:   m,
+		inputs:  make(map[livekit.TrackID]types.LocalParticipant),
+		outputs: make(map[livekit.ParticipantIdentity]*GeneratedTrack),
+	}
+	t.GeneratedTrack = r.newGeneratedTrack(generatedTrackParams{
+		Identity: MixedAudioIdentity,
+		Name:     "Mixed audio",
+		TrackInfo: &livekit.TrackInfo{
+			Type:   livekit.TrackType_AUDIO,
+			Source: livekit.TrackSource_MICROPHONE,
+			Name:   "mixed audio",
+		},
+		Codec: mixedAudioCodec,
+	})
+	m.Start()
+
+	ma := &mixedAudio{
+		track: t,
+	}
+	if len(conf.Participants) != 0 {
+		ma.participants = make(map[livekit.ParticipantIdentity]bool, len(conf.Participants))
+		for _, identity := range conf.Participants {
+			ma.participants[livekit.ParticipantIdentity(identity)] = true
+		}
+	}
+	return ma
+}
+
+// CheckMixedAudioCodec returns an error when mixed audio is enabled and the server build has no Opus codec to mix with
+func CheckMixedAudioCodec(conf config.MixedAudioConfig) error {
+	if conf.Enabled && !mixer.HasCodec(mixedAudioCodec.MimeType) {
+		return fmt.Errorf("mixed audio is enabled: %w, register an Opus codec with mixer.RegisterCodec", mixer.ErrNoCodec)
+	}
+	return nil
+}
+
+// GetMixedAudioTrack returns the mixed audio track of the room, nil when not enabled
+func (r *Room) GetMixedAudioTrack() *MixedAudioTrack {
+	if r.mixedAudio == nil {
+		return nil
+	}
+	return r.mixedAudio.track
+}
+
+func (r *Room) addMixedAudioInput(p types.LocalParticipant, track types.MediaTrack) {
+	ma := r.mixedAudio
+	if ma == nil || track.Kind() != livekit.TrackType_AUDIO {
+		return
+	}
+	if ma.participants != nil && !ma.participants[p.Identity()] {
+		return
+	}
+
+	receivers := track.Receivers()
+	if len(receivers) == 0 {
+		return
+	}
+	if err := ma.track.mixer.AddInput(receivers[0]); err != nil {
+		p.GetLogger().Infow("could not mix audio track", "error", err, "trackID", track.ID())
+		return
+	}
+
+	ma.track.lock.Lock()
+	ma.track.inputs[track.ID()] = p
+	ma.track.updateOutputsLocked()
+	ma.track.lock.Unlock()
+}
+
+func (r *Room) removeMixedAudioInput(trackID livekit.TrackID) {
+	if r.mixedAudio == nil {
+		return
+	}
+	t := r.mixedAudio.track
+	t.mixer.RemoveInput(trackID)
+
+	t.lock.Lock()
+	delete(t.inputs, trackID)
+	t.lock.Unlock()
+}
+
+// updateMixedAudioPermissions applies changes of subscription permissions to the mixes
+func (r *Room) updateMixedAudioPermissions() {
+	if r.mixedAudio == nil {
+		return
+	}
+	t := r.mixedAudio.track
+
+	t.lock.Lock()
+	t.updateOutputsLocked()
+	t.lock.Unlock()
+}
+
+// getMixedAudioOutput returns the mix received by a participant, creating it on first subscription
+func (r *Room) getMixedAudioOutput(identity livekit.ParticipantIdentity) types.MediaTrack {
+	t := r.mixedAudio.track
+	if r.GetParticipant(identity) == nil {
+		return nil
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.outputs == nil {
+		// closed
+		return nil
+	}
+	if output := t.outputs[identity]; output != nil {
+		return output
+	}
+
+	output := r.newGeneratedTrack(generatedTrackParams{
+		Identity:      MixedAudioIdentity,
+		ParticipantID: t.PublisherID(),
+		TrackInfo:     t.ToProto(),
+		Codec:         mixedAudioCodec,
+	})
+	err := t.mixer.AddOutput(string(identity), mixer.OutputParams{
+		SSRC: rand.Uint32(),
+		IsActive: func() bool {
+			return output.GetNumSubscribers() != 0
+		},
+		OnPacket: output.WriteRTP,
+	})
+	if err != nil {
+		r.Logger.Warnw("could not add mixed audio output", err, "participant", identity)
+		output.Close(false)
+		return nil
+	}
+	t.outputs[identity] = output
+	t.updateOutputLocked(identity)
+	return output
+}
+
+func (r *Room) removeMixedAudioOutput(identity livekit.ParticipantIdentity) {
+	if r.mixedAudio == nil {
+		return
+	}
+	t := r.mixedAudio.track
+
+	t.lock.Lock()
+	output := t.outputs[identity]
+	delete(t.outputs, identity)
+	t.lock.Unlock()
+
+	if output != nil {
+		t.mixer.RemoveOutput(string(identity))
+		output.Close(false)
+	}
+}
+
+func (t *MixedAudioTrack) updateOutputsLocked() {
+	for identity := range t.outputs {
+		t.updateOutputLocked(identity)
+	}
+}
+
+func (t *MixedAudioTrack) updateOutputLocked(identity livekit.ParticipantIdentity) {
+	t.mixer.SetOutputInputs(string(identity), t.mixedInputsLocked(identity))
+}
+
+// mixedInputsLocked returns the tracks mixed for a subscriber, those it may subscribe to except for its own
+func (t *MixedAudioTrack) mixedInputsLocked(identity livekit.ParticipantIdentity) []livekit.TrackID {
+	var trackIDs []livekit.TrackID
+	for trackID, pub := range t.inputs {
+		if pub.Identity() != identity && pub.HasPermission(trackID, identity) {
+			trackIDs = append(trackIDs, trackID)
+		}
+	}
+	return trackIDs
+}
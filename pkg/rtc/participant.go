@@ -25,6 +25,7 @@ import (
 	"time"
 
 	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/pion/interceptor"
 	"github.com/pion/rtcp"
 	"github.com/pion/sctp"
 	"github.com/pion/sdp/v3"
@@ -47,8 +48,10 @@ import (
 	"github.com/livekit/livekit-server/pkg/rtc/types"
 	"github.com/livekit/livekit-server/pkg/sfu"
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/clocksource"
 	"github.com/livekit/livekit-server/pkg/sfu/connectionquality"
 	"github.com/livekit/livekit-server/pkg/sfu/pacer"
+	"github.com/livekit/livekit-server/pkg/sfu/rtpextension"
 	"github.com/livekit/livekit-server/pkg/sfu/streamallocator"
 	"github.com/livekit/livekit-server/pkg/telemetry"
 	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
@@ -112,39 +115,56 @@ type ParticipantParams struct {
 	PLIThrottleConfig       config.PLIThrottleConfig
 	CongestionControlConfig config.CongestionControlConfig
 	// codecs that are enabled for this room
-	PublishEnabledCodecs           []*livekit.Codec
-	SubscribeEnabledCodecs         []*livekit.Codec
-	Logger                         logger.Logger
-	SimTracks                      map[uint32]SimulcastTrackInfo
-	Grants                         *auth.ClaimGrants
-	InitialVersion                 uint32
-	ClientConf                     *livekit.ClientConfiguration
-	ClientInfo                     ClientInfo
-	Region                         string
-	Migration                      bool
-	AdaptiveStream                 bool
-	AllowTCPFallback               bool
-	TCPFallbackRTTThreshold        int
-	AllowUDPUnstableFallback       bool
-	TURNSEnabled                   bool
-	GetParticipantInfo             func(pID livekit.ParticipantID) *livekit.ParticipantInfo
-	GetRegionSettings              func(ip string) *livekit.RegionSettings
-	GetSubscriberForwarderState    func(p types.LocalParticipant) (map[livekit.TrackID]*livekit.RTPForwarderState, error)
-	DisableSupervisor              bool
-	ReconnectOnPublicationError    bool
-	ReconnectOnSubscriptionError   bool
-	ReconnectOnDataChannelError    bool
-	DataChannelMaxBufferedAmount   uint64
-	VersionGenerator               utils.TimedVersionGenerator
-	TrackResolver                  types.MediaTrackResolver
-	DisableDynacast                bool
-	SubscriberAllowPause           bool
-	SubscriptionLimitAudio         int32
-	SubscriptionLimitVideo         int32
-	PlayoutDelay                   *livekit.PlayoutDelay
-	SyncStreams                    bool
-	ForwardStats                   *sfu.ForwardStats
-	DisableSenderReportPassThrough bool
+	PublishEnabledCodecs              []*livekit.Codec
+	SubscribeEnabledCodecs            []*livekit.Codec
+	SimulcastOnlyCodecs               []string // scalable codecs clients are told to publish with simulcast
+	Logger                            logger.Logger
+	SimTracks                         map[uint32]SimulcastTrackInfo
+	Grants                            *auth.ClaimGrants
+	InitialVersion                    uint32
+	ClientConf                        *livekit.ClientConfiguration
+	ClientInfo                        ClientInfo
+	Region                            string
+	Migration                         bool
+	AdaptiveStream                    bool
+	AllowTCPFallback                  bool
+	TCPFallbackRTTThreshold           int
+	AllowUDPUnstableFallback          bool
+	TURNSEnabled                      bool
+	GetParticipantInfo                func(pID livekit.ParticipantID) *livekit.ParticipantInfo
+	GetRegionSettings                 func(ip string) *livekit.RegionSettings
+	GetSubscriberForwarderState       func(p types.LocalParticipant) (map[livekit.TrackID]*livekit.RTPForwarderState, error)
+	DisableSupervisor                 bool
+	ReconnectOnPublicationError       bool
+	ReconnectOnSubscriptionError      bool
+	ReconnectOnDataChannelError       bool
+	ReconnectOnShutdown               bool
+	SubscriptionStatus                bool
+	DataChannelMaxBufferedAmount      uint64
+	DataChannelPriority               config.DataChannelPriorityConfig
+	NetworkImpairment                 config.NetworkImpairmentConfig
+	EnableChaos                       bool
+	VersionGenerator                  utils.TimedVersionGenerator
+	TrackResolver                     types.MediaTrackResolver
+	DisableDynacast                   bool
+	SubscriberAllowPause              bool
+	SubscribedQuality                 config.SubscribedQualityConfig
+	SubscriptionLimitAudio            int32
+	SubscriptionLimitVideo            int32
+	PlayoutDelay                      *livekit.PlayoutDelay
+	SyncStreams                       bool
+	ForwardStats                      *sfu.ForwardStats
+	DisableSenderReportPassThrough    bool
+	SenderReportSmoothingWindow       time.Duration
+	SenderReportClockSkewCompensation bool
+	SenderReportClock                 clocksource.ClockSource
+	AVSyncCorrection                  bool
+	// intercepts packets of published tracks, RoomName identifies the room to the interceptor
+	MediaInterceptor MediaInterceptor
+	RoomName         livekit.RoomName
+	// pion interceptors added to the publisher and subscriber transports
+	PublisherInterceptors  []interceptor.Factory
+	SubscriberInterceptors []interceptor.Factory
 }
 
 type ParticipantImpl struct {
@@ -154,8 +174,9 @@ type ParticipantImpl struct {
 
 	params ParticipantParams
 
-	isClosed    atomic.Bool
-	closeReason atomic.Value // types.ParticipantCloseReason
+	isClosed     atomic.Bool
+	closeReason  atomic.Value // types.ParticipantCloseReason
+	removeReason atomic.Pointer[livekit.DisconnectReason]
 
 	state        atomic.Value // livekit.ParticipantInfo_State
 	disconnected chan struct{}
@@ -166,6 +187,9 @@ type ParticipantImpl struct {
 	grants      atomic.Pointer[auth.ClaimGrants]
 	isPublisher atomic.Bool
 
+	// optional features, see capabilities.go
+	subscriptionStatus atomic.Bool
+
 	sessionStartRecorded atomic.Bool
 	lastActiveAt         time.Time
 	// when first connected
@@ -237,6 +261,7 @@ type ParticipantImpl struct {
 	supervisor *supervisor.ParticipantSupervisor
 
 	tracksQuality map[livekit.TrackID]livekit.ConnectionQuality
+	avSync        *avSyncMonitor
 
 	// loggers for publisher and subscriber
 	pubLogger logger.Logger
@@ -277,12 +302,14 @@ func NewParticipant(params ParticipantParams) (*ParticipantImpl, error) {
 	if !params.DisableSupervisor {
 		p.supervisor = supervisor.NewParticipantSupervisor(supervisor.ParticipantSupervisorParams{Logger: params.Logger})
 	}
+	p.avSync = newAVSyncMonitor(p.onAVSyncDriftChanged)
 	p.closeReason.Store(types.ParticipantCloseReasonNone)
 	p.version.Store(params.InitialVersion)
 	p.timedVersion.Update(params.VersionGenerator.Next())
 	p.migrateState.Store(types.MigrateStateInit)
 	p.state.Store(livekit.ParticipantInfo_JOINING)
 	p.grants.Store(params.Grants)
+	p.subscriptionStatus.Store(params.SubscriptionStatus)
 	p.SetResponseSink(params.Sink)
 	p.setupEnabledCodecs(params.PublishEnabledCodecs, params.SubscribeEnabledCodecs, params.ClientConf.GetDisabledCodecs())
 
@@ -321,6 +348,10 @@ func (p *ParticipantImpl) GetAdaptiveStream() bool {
 	return p.params.AdaptiveStream
 }
 
+func (p *ParticipantImpl) GetSubscribedQualityLimits(source livekit.TrackSource) config.SubscribedQualityLimits {
+	return p.params.SubscribedQuality.ForSource(source)
+}
+
 func (p *ParticipantImpl) GetPacer() pacer.Pacer {
 	return p.TransportManager.GetSubscriberPacer()
 }
@@ -329,6 +360,18 @@ func (p *ParticipantImpl) GetDisableSenderReportPassThrough() bool {
 	return p.params.DisableSenderReportPassThrough
 }
 
+func (p *ParticipantImpl) GetSenderReportSmoothingWindow() time.Duration {
+	return p.params.SenderReportSmoothingWindow
+}
+
+func (p *ParticipantImpl) GetSenderReportClockSkewCompensation() bool {
+	return p.params.SenderReportClockSkewCompensation
+}
+
+func (p *ParticipantImpl) GetSenderReportClock() clocksource.ClockSource {
+	return p.params.SenderReportClock
+}
+
 func (p *ParticipantImpl) ID() livekit.ParticipantID {
 	return p.params.SID
 }
@@ -412,6 +455,10 @@ func (p *ParticipantImpl) GetClientConfiguration() *livekit.ClientConfiguration
 	return p.params.ClientConf
 }
 
+func (p *ParticipantImpl) GetHeaderExtensions() *rtpextension.Registry {
+	return p.params.Config.HeaderExtensions
+}
+
 func (p *ParticipantImpl) GetBufferFactory() *buffer.Factory {
 	return p.params.Config.BufferFactory
 }
@@ -623,7 +670,7 @@ func (p *ParticipantImpl) ToProtoWithVersion() (*livekit.ParticipantInfo, utils.
 		Region:           p.params.Region,
 		IsPublisher:      p.IsPublisher(),
 		Kind:             grants.GetParticipantKind(),
-		DisconnectReason: p.CloseReason().ToDisconnectReason(),
+		DisconnectReason: p.disconnectReason(p.CloseReason()),
 	}
 	p.lock.RUnlock()
 
@@ -904,7 +951,9 @@ func (p *ParticipantImpl) Close(sendLeave bool, reason types.ParticipantCloseRea
 	p.clearMigrationTimer()
 
 	if sendLeave {
-		p.sendLeaveRequest(reason, isExpectedToResume, false, false)
+		// on shutdown, clients may be asked to reconnect to another node
+		isExpectedToReconnect := reason == types.ParticipantCloseReasonRoomManagerStop && p.params.ReconnectOnShutdown
+		p.sendLeaveRequest(reason, isExpectedToResume, isExpectedToReconnect, false)
 	}
 
 	if p.supervisor != nil {
@@ -1067,6 +1116,11 @@ func (p *ParticipantImpl) MigrateState() types.MigrateState {
 }
 
 // ICERestart restarts subscriber ICE connections
+func (p *ParticipantImpl) SimulateTransportFault(target livekit.SignalTarget, fault types.TransportFault, duration time.Duration) error {
+	p.params.Logger.Infow("simulating transport fault", "target", target, "fault", fault, "duration", duration)
+	return p.TransportManager.SimulateFault(target, fault, duration)
+}
+
 func (p *ParticipantImpl) ICERestart(iceConfig *livekit.ICEConfig) {
 	p.clearDisconnectTimer()
 	p.clearMigrationTimer()
@@ -1086,6 +1140,11 @@ func (p *ParticipantImpl) OnICEConfigChanged(f func(participant types.LocalParti
 	p.lock.Unlock()
 }
 
+func (p *ParticipantImpl) onAVSyncDriftChanged(trackID livekit.TrackID, drift time.Duration, exceeded bool) {
+	p.subLogger.Infow("audio/video sync drift changed", "trackID", trackID, "drift", drift, "exceeded", exceeded)
+	prometheus.RecordAVSyncDriftChange(exceeded)
+}
+
 func (p *ParticipantImpl) GetConnectionQuality() *livekit.ConnectionQualityInfo {
 	numTracks := 0
 	minQuality := livekit.ConnectionQuality_EXCELLENT
@@ -1124,6 +1183,9 @@ func (p *ParticipantImpl) GetConnectionQuality() *livekit.ConnectionQualityInfo
 		numTracks++
 
 		score, quality := subTrack.DownTrack().GetConnectionScoreAndQuality()
+		if latency := subTrack.DownTrack().GetLatency(); latency != 0 {
+			prometheus.RecordLatency(latency)
+		}
 		if utils.IsConnectionQualityLower(minQuality, quality) {
 			minQuality = quality
 			minScore = score
@@ -1146,6 +1208,15 @@ func (p *ParticipantImpl) GetConnectionQuality() *livekit.ConnectionQualityInfo
 
 	prometheus.RecordQuality(minQuality, minScore, numUpDrops, numDownDrops)
 
+	drifts := avSyncDrifts(subscribedTracks, time.Now())
+	for _, drift := range drifts {
+		prometheus.RecordAVSyncDrift(drift)
+	}
+	p.avSync.update(drifts)
+	if p.params.AVSyncCorrection {
+		correctAVSync(subscribedTracks, drifts, p.subLogger)
+	}
+
 	// remove unavailable tracks from track quality cache
 	p.lock.Lock()
 	for trackID := range p.tracksQuality {
@@ -1314,6 +1385,10 @@ func (h SubscriberTransportHandler) OnStreamStateChange(update *streamallocator.
 	return h.p.onStreamStateChange(update)
 }
 
+func (h SubscriberTransportHandler) OnProbeResult(result streamallocator.ProbeResult) {
+	h.p.onProbeResult(result)
+}
+
 func (h SubscriberTransportHandler) OnInitialConnected() {
 	h.p.onSubscriberInitialConnected()
 }
@@ -1373,9 +1448,14 @@ func (p *ParticipantImpl) setupTransportManager() error {
 		TURNSEnabled:                 p.params.TURNSEnabled,
 		AllowPlayoutDelay:            p.params.PlayoutDelay.GetEnabled(),
 		DataChannelMaxBufferedAmount: p.params.DataChannelMaxBufferedAmount,
+		DataChannelPriority:          p.params.DataChannelPriority,
+		NetworkImpairment:            p.params.NetworkImpairment,
+		EnableChaos:                  p.params.EnableChaos,
 		Logger:                       p.params.Logger.WithComponent(sutils.ComponentTransport),
 		PublisherHandler:             pth,
 		SubscriberHandler:            sth,
+		PublisherInterceptors:        p.params.PublisherInterceptors,
+		SubscriberInterceptors:       p.params.SubscriberInterceptors,
 	}
 	if p.params.SyncStreams && p.params.PlayoutDelay.GetEnabled() && p.params.ClientInfo.isFirefox() {
 		// we will disable playout delay for Firefox if the user is expecting
@@ -1438,8 +1518,10 @@ func (p *ParticipantImpl) setupSubscriptionManager() {
 		OnTrackSubscribed:      p.onTrackSubscribed,
 		OnTrackUnsubscribed:    p.onTrackUnsubscribed,
 		OnSubscriptionError:    p.onSubscriptionError,
+		OnSubscriptionStalled:  p.onSubscriptionStalled,
 		SubscriptionLimitVideo: p.params.SubscriptionLimitVideo,
 		SubscriptionLimitAudio: p.params.SubscriptionLimitAudio,
+		MaxSubscribersPerTrack: p.params.LimitConfig.MaxSubscribersPerTrack,
 	})
 }
 
@@ -1556,6 +1638,10 @@ func (p *ParticipantImpl) onDataMessage(kind livekit.DataPacket_Kind, data []byt
 		return
 	}
 
+	if p.dataLimitReached(len(data)) {
+		p.pubLogger.Debugw("dropping data packet, data limit reached")
+		return
+	}
 	p.dataChannelStats.AddBytes(uint64(len(data)), false)
 
 	dp := &livekit.DataPacket{}
@@ -1595,6 +1681,10 @@ func (p *ParticipantImpl) onDataMessage(kind livekit.DataPacket_Kind, data []byt
 		} else {
 			dp.DestinationIdentities = u.DestinationIdentities
 		}
+		if u.GetTopic() == CapabilitiesTopic {
+			p.handleCapabilitiesRequest(u.Payload)
+			break
+		}
 		shouldForward = true
 	case *livekit.DataPacket_SipDtmf:
 		shouldForward = true
@@ -1617,6 +1707,23 @@ func (p *ParticipantImpl) onDataMessage(kind livekit.DataPacket_Kind, data []byt
 	p.setIsPublisher(true)
 }
 
+// dataLimitReached enforces the per session limits of data sent by the participant
+func (p *ParticipantImpl) dataLimitReached(size int) bool {
+	limits := p.params.LimitConfig
+	if limits.MaxParticipantDataBytes == 0 && limits.MaxParticipantDataMessages == 0 {
+		return false
+	}
+	totals := p.dataChannelStats.GetTrafficTotals()
+	if limits.MaxParticipantDataBytes > 0 && totals.RecvBytes+uint64(size) > limits.MaxParticipantDataBytes {
+		return true
+	}
+	return limits.MaxParticipantDataMessages > 0 && totals.RecvMessages >= limits.MaxParticipantDataMessages
+}
+
+func (p *ParticipantImpl) GetDataTrafficTotals() *telemetry.TrafficTotals {
+	return p.dataChannelStats.GetTrafficTotals()
+}
+
 func (p *ParticipantImpl) onICECandidate(c *webrtc.ICECandidate, target livekit.SignalTarget) error {
 	if p.IsDisconnected() || p.IsClosed() {
 		return nil
@@ -1759,22 +1866,52 @@ func (p *ParticipantImpl) subscriberRTCPWorker() {
 	}
 }
 
+func (p *ParticipantImpl) onProbeResult(result streamallocator.ProbeResult) {
+	p.subLogger.Debugw(
+		"bandwidth probe completed",
+		"probeClusterId", result.ClusterId,
+		"duration", result.Duration,
+		"goalBps", result.GoalBps,
+		"estimatedSentBps", result.EstimatedSentBps,
+		"achievedBps", result.AchievedBps,
+		"nackRatio", result.NackRatio,
+		"decision", result.Decision,
+	)
+	// probes run every few seconds per subscriber, results are only counted
+	prometheus.RecordProbeResult(result.Decision.String())
+}
+
 func (p *ParticipantImpl) onStreamStateChange(update *streamallocator.StreamStateUpdate) error {
 	if len(update.StreamStates) == 0 {
 		return nil
 	}
 
 	streamStateUpdate := &livekit.StreamStateUpdate{}
+	var statuses []SubscriptionStatus
 	for _, streamStateInfo := range update.StreamStates {
 		state := livekit.StreamState_ACTIVE
+		status := SubscriptionStatus{
+			TrackSid:       string(streamStateInfo.TrackID),
+			ParticipantSid: string(streamStateInfo.ParticipantID),
+			Status:         SubscriptionStatusActive,
+		}
 		if streamStateInfo.State == streamallocator.StreamStatePaused {
 			state = livekit.StreamState_PAUSED
+			status.Status = SubscriptionStatusPaused
+			status.Cause = SubscriptionCauseAllocatorPaused
 		}
 		streamStateUpdate.StreamStates = append(streamStateUpdate.StreamStates, &livekit.StreamStateInfo{
 			ParticipantSid: string(streamStateInfo.ParticipantID),
 			TrackSid:       string(streamStateInfo.TrackID),
 			State:          state,
 		})
+		statuses = append(statuses, status)
+	}
+
+	if p.subscriptionStatus.Load() {
+		if err := sendSubscriptionStatus(p, statuses...); err != nil {
+			p.subLogger.Debugw("could not send subscription status", "error", err)
+		}
 	}
 
 	return p.writeMessage(&livekit.SignalResponse{
@@ -1899,6 +2036,11 @@ func (p *ParticipantImpl) addPendingTrackLocked(req *livekit.AddTrackRequest) *l
 					mime = "video/" + mime
 				}
 				if !IsCodecEnabled(p.enabledPublishCodecs, webrtc.RTPCodecCapability{MimeType: mime}) {
+					if p.Kind() == livekit.ParticipantInfo_INGRESS {
+						// ingress may forward the encoder's layers without transcoding, it cannot switch codecs
+						p.pubLogger.Infow("codec not enabled for ingress track", "codec", mime, "trackID", ti.Sid)
+						continue
+					}
 					altCodec := selectAlternativeVideoCodec(p.enabledPublishCodecs)
 					p.pubLogger.Infow("falling back to alternative codec",
 						"codec", mime,
@@ -1929,6 +2071,12 @@ func (p *ParticipantImpl) addPendingTrackLocked(req *livekit.AddTrackRequest) *l
 		}
 	}
 
+	if req.Type == livekit.TrackType_VIDEO && len(req.SimulcastCodecs) != 0 && len(ti.Codecs) == 0 {
+		p.pubLogger.Warnw("no enabled codec for track", nil, "trackID", ti.Sid, "request", logger.Proto(req))
+		p.sendTrackRejected(req.Cid, livekit.RequestResponse_NOT_ALLOWED, "no enabled codec for track")
+		return nil
+	}
+
 	p.params.Telemetry.TrackPublishRequested(context.Background(), p.ID(), p.Identity(), ti)
 	if p.supervisor != nil {
 		p.supervisor.AddPublication(livekit.TrackID(ti.Sid))
@@ -1966,6 +2114,20 @@ func (p *ParticipantImpl) HasConnected() bool {
 	return p.TransportManager.HasSubscriberEverConnected() || p.TransportManager.HasPublisherEverConnected()
 }
 
+// sendTrackRejected tells the client a track publish request was refused. AddTrackRequest has no request id,
+// so the response carries the client track id in its message.
+func (p *ParticipantImpl) sendTrackRejected(cid string, reason livekit.RequestResponse_Reason, message string) {
+	p.pubLogger.Debugw("sending track rejected", "cid", cid, "reason", reason)
+	_ = p.writeMessage(&livekit.SignalResponse{
+		Message: &livekit.SignalResponse_RequestResponse{
+			RequestResponse: &livekit.RequestResponse{
+				Reason:  reason,
+				Message: fmt.Sprintf("track %s rejected: %s", cid, message),
+			},
+		},
+	})
+}
+
 func (p *ParticipantImpl) sendTrackPublished(cid string, ti *livekit.TrackInfo) {
 	p.pubLogger.Debugw("sending track published", "cid", cid, "trackInfo", logger.Proto(ti))
 	_ = p.writeMessage(&livekit.SignalResponse{
@@ -2176,6 +2338,8 @@ func (p *ParticipantImpl) addMediaTrack(signalCid string, sdpCid string, ti *liv
 		OnRTCP:                p.postRtcp,
 		ForwardStats:          p.params.ForwardStats,
 		OnTrackEverSubscribed: p.sendTrackHasBeenSubscribed,
+		RoomName:              p.params.RoomName,
+		MediaInterceptor:      p.params.MediaInterceptor,
 	}, ti)
 
 	mt.OnSubscribedMaxQualityChange(p.onSubscribedMaxQualityChange)
@@ -2424,6 +2588,13 @@ func (p *ParticipantImpl) DebugInfo() map[string]interface{} {
 
 	info["UpTrackManager"] = p.UpTrackManager.DebugInfo()
 
+	probeResults := p.TransportManager.GetSubscriberProbeResults()
+	probeResultsInfo := make([]map[string]interface{}, 0, len(probeResults))
+	for _, result := range probeResults {
+		probeResultsInfo = append(probeResultsInfo, result.DebugInfo())
+	}
+	info["ProbeResults"] = probeResultsInfo
+
 	return info
 }
 
@@ -2563,12 +2734,25 @@ func (p *ParticipantImpl) onSubscriptionError(trackID livekit.TrackID, fatal boo
 		},
 	})
 
+	if p.subscriptionStatus.Load() {
+		if err := sendSubscriptionStatus(p, newSubscriptionStatus(trackID, SubscriptionStatusFailed, err)); err != nil {
+			p.subLogger.Debugw("could not send subscription status", "error", err, "trackID", trackID)
+		}
+	}
+
 	if p.params.ReconnectOnSubscriptionError && fatal {
 		p.subLogger.Infow("issuing full reconnect on subscription error", "trackID", trackID)
 		p.IssueFullReconnect(types.ParticipantCloseReasonSubscriptionError)
 	}
 }
 
+func (p *ParticipantImpl) onSubscriptionStalled(trackID livekit.TrackID, err error) error {
+	if !p.subscriptionStatus.Load() {
+		return nil
+	}
+	return sendSubscriptionStatus(p, newSubscriptionStatus(trackID, SubscriptionStatusStalled, err))
+}
+
 func (p *ParticipantImpl) onAnyTransportNegotiationFailed() {
 	if p.TransportManager.SinceLastSignal() < negotiationFailedTimeout/2 {
 		p.params.Logger.Infow("negotiation failed, starting full reconnect")
@@ -15,6 +15,7 @@
 package rtc
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
@@ -23,6 +24,7 @@ import (
 	"github.com/pion/webrtc/v3"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/atomic"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
@@ -533,6 +535,38 @@ func TestDisablePublishCodec(t *testing.T) {
 	require.Eventually(t, func() bool { return publishReceived.Load() }, 5*time.Second, 10*time.Millisecond)
 }
 
+func TestIngressTrackWithoutEnabledCodec(t *testing.T) {
+	participant := newParticipantForTestWithOpts("ingress", &participantOpts{
+		publisher: true,
+		clientConf: &livekit.ClientConfiguration{
+			DisabledCodecs: &livekit.DisabledCodecs{
+				Publish: []*livekit.Codec{
+					{Mime: "video/h264"},
+				},
+			},
+		},
+	})
+	participant.grants.Load().Kind = "ingress"
+	sink := participant.params.Sink.(*routingfakes.FakeMessageSink)
+
+	// ingress can't switch codecs, the track is refused instead of falling back
+	participant.AddTrack(&livekit.AddTrackRequest{
+		Cid:  "cid1",
+		Type: livekit.TrackType_VIDEO,
+		SimulcastCodecs: []*livekit.SimulcastCodec{{
+			Codec: "h264",
+			Cid:   "cid1",
+		}},
+	})
+
+	require.Equal(t, 1, sink.WriteMessageCallCount())
+	res := sink.WriteMessageArgsForCall(0).(*livekit.SignalResponse).GetRequestResponse()
+	require.NotNil(t, res)
+	require.Equal(t, livekit.RequestResponse_NOT_ALLOWED, res.Reason)
+	require.Contains(t, res.Message, "cid1")
+	require.Nil(t, participant.GetPendingTrack(livekit.TrackID("cid1")))
+}
+
 func TestPreferVideoCodecForPublisher(t *testing.T) {
 	participant := newParticipantForTestWithOpts("123", &participantOpts{
 		publisher: true,
@@ -710,6 +744,172 @@ func TestPreferAudioCodecForRed(t *testing.T) {
 	}
 }
 
+func TestSIPDTMF(t *testing.T) {
+	dtmf, err := proto.Marshal(&livekit.DataPacket{
+		Value: &livekit.DataPacket_SipDtmf{SipDtmf: &livekit.SipDTMF{Code: 11, Digit: "#"}},
+	})
+	require.NoError(t, err)
+
+	for _, kind := range []string{"sip", "standard"} {
+		t.Run(kind, func(t *testing.T) {
+			p := newParticipantForTestWithOpts("caller", &participantOpts{
+				permissions: &livekit.ParticipantPermission{CanPublishData: true},
+			})
+			grants := p.grants.Load().Clone()
+			grants.Kind = kind
+			p.grants.Store(grants)
+
+			var forwarded *livekit.DataPacket
+			p.OnDataPacket(func(_ types.LocalParticipant, _ livekit.DataPacket_Kind, dp *livekit.DataPacket) {
+				forwarded = dp
+			})
+			p.onDataMessage(livekit.DataPacket_RELIABLE, dtmf)
+
+			require.NotNil(t, forwarded)
+			require.Equal(t, "caller", forwarded.ParticipantIdentity)
+			require.Equal(t, "#", forwarded.GetSipDtmf().GetDigit())
+		})
+	}
+}
+
+func TestDataLimits(t *testing.T) {
+	p := newParticipantForTestWithOpts("sender", &participantOpts{
+		permissions: &livekit.ParticipantPermission{CanPublishData: true},
+	})
+	p.params.LimitConfig.MaxParticipantDataMessages = 2
+
+	forwarded := 0
+	p.OnDataPacket(func(_ types.LocalParticipant, _ livekit.DataPacket_Kind, _ *livekit.DataPacket) {
+		forwarded++
+	})
+	data, err := proto.Marshal(&livekit.DataPacket{
+		Value: &livekit.DataPacket_User{User: &livekit.UserPacket{Payload: []byte("hello")}},
+	})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		p.onDataMessage(livekit.DataPacket_RELIABLE, data)
+	}
+
+	require.Equal(t, 2, forwarded)
+	totals := p.GetDataTrafficTotals()
+	require.Equal(t, uint32(2), totals.RecvMessages)
+	require.Equal(t, uint64(2*len(data)), totals.RecvBytes)
+}
+
+func TestLeaveOnShutdown(t *testing.T) {
+	leaveSent := func(p *ParticipantImpl) *livekit.LeaveRequest {
+		sink := p.params.Sink.(*routingfakes.FakeMessageSink)
+		for i := 0; i < sink.WriteMessageCallCount(); i++ {
+			if res, ok := sink.WriteMessageArgsForCall(i).(*livekit.SignalResponse); ok && res.GetLeave() != nil {
+				return res.GetLeave()
+			}
+		}
+		return nil
+	}
+
+	t.Run("disconnects by default", func(t *testing.T) {
+		p := newParticipantForTestWithOpts("p", &participantOpts{protocolVersion: 13, clientInfo: &livekit.ClientInfo{}})
+		require.NoError(t, p.Close(true, types.ParticipantCloseReasonRoomManagerStop, false))
+
+		leave := leaveSent(p)
+		require.NotNil(t, leave)
+		require.Equal(t, livekit.LeaveRequest_DISCONNECT, leave.Action)
+		require.Equal(t, livekit.DisconnectReason_SERVER_SHUTDOWN, leave.Reason)
+	})
+
+	t.Run("reconnects with region hints", func(t *testing.T) {
+		p := newParticipantForTestWithOpts("p", &participantOpts{protocolVersion: 13, clientInfo: &livekit.ClientInfo{}})
+		p.params.ReconnectOnShutdown = true
+		p.params.GetRegionSettings = func(_ string) *livekit.RegionSettings {
+			return &livekit.RegionSettings{Regions: []*livekit.RegionInfo{{Region: "us-west", Url: "wss://us-west.example.com"}}}
+		}
+		require.NoError(t, p.Close(true, types.ParticipantCloseReasonRoomManagerStop, false))
+
+		leave := leaveSent(p)
+		require.NotNil(t, leave)
+		require.Equal(t, livekit.LeaveRequest_RECONNECT, leave.Action)
+		require.Equal(t, "wss://us-west.example.com", leave.Regions.Regions[0].Url)
+	})
+}
+
+func TestCapabilities(t *testing.T) {
+	t.Run("attached to join response", func(t *testing.T) {
+		p := newParticipantForTestWithOpts("p", &participantOpts{protocolVersion: types.CurrentProtocol})
+		require.NoError(t, p.SendJoinResponse(&livekit.JoinResponse{}))
+
+		sink := p.params.Sink.(*routingfakes.FakeMessageSink)
+		data, err := proto.Marshal(sink.WriteMessageArgsForCall(0).(*livekit.SignalResponse).GetJoin())
+		require.NoError(t, err)
+
+		var payload []byte
+		for len(data) > 0 {
+			num, typ, n := protowire.ConsumeTag(data)
+			require.GreaterOrEqual(t, n, 0)
+			data = data[n:]
+			if num == JoinResponseCapabilitiesField && typ == protowire.BytesType {
+				payload, n = protowire.ConsumeBytes(data)
+			} else {
+				n = protowire.ConsumeFieldValue(num, typ, data)
+			}
+			require.GreaterOrEqual(t, n, 0)
+			data = data[n:]
+		}
+
+		var c ServerCapabilities
+		require.NoError(t, json.Unmarshal(payload, &c))
+		require.Equal(t, types.CurrentProtocol, c.ProtocolVersion)
+		require.Contains(t, c.PublishCodecs, "video/vp8")
+		require.Contains(t, c.SVCCodecs, "video/vp9")
+		require.Contains(t, c.ScalabilityModes, "L3T3_KEY")
+		require.Contains(t, c.SignalFeatures, SignalFeatureIdentityBasedReconnection)
+		require.Equal(t, []string{OptionalFeatureSubscriptionStatus}, c.OptionalFeatures)
+		require.Empty(t, c.EnabledFeatures)
+	})
+
+	t.Run("simulcast only codecs are not advertised as scalable", func(t *testing.T) {
+		p := newParticipantForTestWithOpts("p", &participantOpts{protocolVersion: types.CurrentProtocol})
+		require.Contains(t, p.Capabilities().SVCCodecs, "video/av1")
+
+		p.params.SimulcastOnlyCodecs = []string{"video/AV1"}
+		c := p.Capabilities()
+		require.Contains(t, c.PublishCodecs, "video/av1")
+		require.NotContains(t, c.SVCCodecs, "video/av1")
+		require.Contains(t, c.SVCCodecs, "video/vp9")
+	})
+
+	t.Run("optional features are negotiated", func(t *testing.T) {
+		p := newParticipantForTestWithOpts("p", &participantOpts{
+			permissions: &livekit.ParticipantPermission{CanPublishData: true},
+		})
+		forwarded := false
+		p.OnDataPacket(func(_ types.LocalParticipant, _ livekit.DataPacket_Kind, _ *livekit.DataPacket) {
+			forwarded = true
+		})
+		request := func(req CapabilitiesRequest) {
+			payload, err := json.Marshal(req)
+			require.NoError(t, err)
+			topic := CapabilitiesTopic
+			data, err := proto.Marshal(&livekit.DataPacket{
+				Value: &livekit.DataPacket_User{User: &livekit.UserPacket{Payload: payload, Topic: &topic}},
+			})
+			require.NoError(t, err)
+			p.onDataMessage(livekit.DataPacket_RELIABLE, data)
+		}
+
+		request(CapabilitiesRequest{Enable: []string{OptionalFeatureSubscriptionStatus}})
+		require.True(t, p.subscriptionStatus.Load())
+		require.Equal(t, []string{OptionalFeatureSubscriptionStatus}, p.Capabilities().EnabledFeatures)
+
+		// unknown features fail the whole request
+		request(CapabilitiesRequest{Disable: []string{OptionalFeatureSubscriptionStatus, "unknown"}})
+		require.True(t, p.subscriptionStatus.Load())
+
+		request(CapabilitiesRequest{Disable: []string{OptionalFeatureSubscriptionStatus}})
+		require.False(t, p.subscriptionStatus.Load())
+		require.False(t, forwarded)
+	})
+}
+
 type participantOpts struct {
 	permissions     *livekit.ParticipantPermission
 	protocolVersion types.ProtocolVersion
@@ -55,6 +55,10 @@ func (p *ParticipantImpl) SendJoinResponse(joinResponse *livekit.JoinResponse) e
 	}
 	p.updateLock.Unlock()
 
+	if err := attachCapabilities(joinResponse, p.Capabilities()); err != nil {
+		p.params.Logger.Warnw("could not attach capabilities to join response", err)
+	}
+
 	// send Join response
 	err := p.writeMessage(&livekit.SignalResponse{
 		Message: &livekit.SignalResponse_Join{
@@ -369,7 +373,7 @@ func (p *ParticipantImpl) sendLeaveRequest(
 	var leave *livekit.LeaveRequest
 	if p.ProtocolVersion().SupportsRegionsInLeaveRequest() {
 		leave = &livekit.LeaveRequest{
-			Reason: reason.ToDisconnectReason(),
+			Reason: p.disconnectReason(reason),
 		}
 		switch {
 		case isExpectedToResume:
@@ -387,7 +391,7 @@ func (p *ParticipantImpl) sendLeaveRequest(
 		if !sendOnlyIfSupportingLeaveRequestWithAction {
 			leave = &livekit.LeaveRequest{
 				CanReconnect: isExpectedToReconnect,
-				Reason:       reason.ToDisconnectReason(),
+				Reason:       p.disconnectReason(reason),
 			}
 		}
 	}
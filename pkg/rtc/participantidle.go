@@ -0,0 +1,192 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// ParticipantIdleTopic carries a warning to a participant before it is disconnected for being idle
+const ParticipantIdleTopic = "lk.idle_warning"
+
+const defaultParticipantIdleWarning = time.Minute
+
+// ParticipantIdleWarning is the json payload of idle warnings. Cancelled is set when the participant became
+// active again after being warned
+type ParticipantIdleWarning struct {
+	// unix time in milliseconds
+	DisconnectsAt int64 `json:"disconnects_at,omitempty"`
+	SecondsLeft   int64 `json:"seconds_left,omitempty"`
+	Cancelled     bool  `json:"cancelled,omitempty"`
+}
+
+type participantIdleAction int
+
+const (
+	participantIdleNone participantIdleAction = iota
+	participantIdleWarn
+	participantIdleCancel
+	participantIdleDisconnect
+)
+
+type participantIdleState struct {
+	activeAt time.Time
+	sentData bool
+	warned   bool
+}
+
+type participantIdleTracker struct {
+	timeout time.Duration
+	warning time.Duration
+
+	lock         sync.Mutex
+	participants map[livekit.ParticipantID]*participantIdleState
+}
+
+func newParticipantIdleTracker(conf config.ParticipantIdleConfig) *participantIdleTracker {
+	if conf.Timeout <= 0 {
+		return nil
+	}
+
+	warning := conf.Warning
+	if warning == 0 {
+		warning = min(defaultParticipantIdleWarning, conf.Timeout/2)
+	}
+	return &participantIdleTracker{
+		timeout:      conf.Timeout,
+		warning:      warning,
+		participants: make(map[livekit.ParticipantID]*participantIdleState),
+	}
+}
+
+func (t *participantIdleTracker) dataReceived(pID livekit.ParticipantID) {
+	if t == nil {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if s := t.participants[pID]; s != nil {
+		s.sentData = true
+	}
+}
+
+func (t *participantIdleTracker) forget(pID livekit.ParticipantID) {
+	if t == nil {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.participants, pID)
+}
+
+// update returns what to do with the participant and when it would be disconnected, active is set when it
+// publishes or is subscribed to tracks. Participants are considered active when first seen
+func (t *participantIdleTracker) update(pID livekit.ParticipantID, now time.Time, active bool) (participantIdleAction, time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	s := t.participants[pID]
+	if s == nil {
+		t.participants[pID] = &participantIdleState{activeAt: now}
+		return participantIdleNone, now.Add(t.timeout)
+	}
+
+	if active || s.sentData {
+		s.activeAt, s.sentData = now, false
+		if s.warned {
+			s.warned = false
+			return participantIdleCancel, now.Add(t.timeout)
+		}
+		return participantIdleNone, now.Add(t.timeout)
+	}
+
+	disconnectsAt := s.activeAt.Add(t.timeout)
+	switch left := disconnectsAt.Sub(now); {
+	case left <= 0:
+		return participantIdleDisconnect, disconnectsAt
+	case left <= t.warning && !s.warned:
+		s.warned = true
+		return participantIdleWarn, disconnectsAt
+	default:
+		return participantIdleNone, disconnectsAt
+	}
+}
+
+// DisconnectIdleParticipants warns, then disconnects standard participants that have not published, been
+// subscribed to tracks or sent data for the configured idle timeout
+func (r *Room) DisconnectIdleParticipants(now time.Time) {
+	if r.idleParticipants == nil || r.IsClosed() {
+		return
+	}
+
+	for _, p := range r.GetLocalParticipants() {
+		if p.Kind() != livekit.ParticipantInfo_STANDARD {
+			continue
+		}
+
+		active := len(p.GetPublishedTracks()) != 0 || len(p.GetSubscribedTracks()) != 0
+		action, disconnectsAt := r.idleParticipants.update(p.ID(), now, active)
+		switch action {
+		case participantIdleWarn:
+			r.sendIdleWarning(p, &ParticipantIdleWarning{
+				DisconnectsAt: disconnectsAt.UnixMilli(),
+				SecondsLeft:   int64(disconnectsAt.Sub(now).Round(time.Second) / time.Second),
+			})
+		case participantIdleCancel:
+			r.sendIdleWarning(p, &ParticipantIdleWarning{Cancelled: true})
+		case participantIdleDisconnect:
+			p.GetLogger().Infow("disconnecting idle participant")
+			r.RemoveParticipant(p.Identity(), p.ID(), types.ParticipantCloseReasonIdle)
+		}
+	}
+}
+
+func (r *Room) sendIdleWarning(p types.LocalParticipant, warning *ParticipantIdleWarning) {
+	payload, err := json.Marshal(warning)
+	if err != nil {
+		p.GetLogger().Errorw("could not marshal idle warning", err)
+		return
+	}
+	topic := ParticipantIdleTopic
+	data, err := proto.Marshal(&livekit.DataPacket{
+		Kind:                  livekit.DataPacket_RELIABLE,
+		DestinationIdentities: []string{string(p.Identity())},
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload:               payload,
+				Topic:                 &topic,
+				DestinationIdentities: []string{string(p.Identity())},
+			},
+		},
+	})
+	if err != nil {
+		p.GetLogger().Errorw("could not marshal idle warning packet", err)
+		return
+	}
+	if err := p.SendDataPacket(livekit.DataPacket_RELIABLE, data); err != nil {
+		p.GetLogger().Warnw("could not send idle warning", err)
+	}
+}
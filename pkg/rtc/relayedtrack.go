@@ -0,0 +1,155 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"sync"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu/relay"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+// RelayProvider provides media of tracks published on other nodes
+type RelayProvider interface {
+	Subscribe(params relay.SubscribeParams) (*relay.Receiver, error)
+	Unsubscribe(trackID livekit.TrackID)
+}
+
+type RelayedTrackParams struct {
+	OriginNode          *livekit.Node
+	Codec               webrtc.RTPCodecParameters
+	HeaderExtensions    []webrtc.RTPHeaderExtensionParameter
+	ParticipantID       livekit.ParticipantID
+	ParticipantIdentity livekit.ParticipantIdentity
+	ParticipantVersion  uint32
+	Relay               RelayProvider
+	ReceiverConfig      ReceiverConfig
+	SubscriberConfig    DirectionConfig
+	AudioConfig         config.AudioConfig
+	Telemetry           telemetry.TelemetryService
+	Logger              logger.Logger
+}
+
+// RelayedTrack is a track published by a participant connected to another node of the room.
+// Media is pulled from the origin node when the first local participant subscribes.
+type RelayedTrack struct {
+	params RelayedTrackParams
+
+	lock     sync.Mutex
+	receiver *relay.Receiver
+
+	*MediaTrackReceiver
+}
+
+func NewRelayedTrack(params RelayedTrackParams, ti *livekit.TrackInfo) *RelayedTrack {
+	t := &RelayedTrack{
+		params: params,
+	}
+
+	t.MediaTrackReceiver = NewMediaTrackReceiver(MediaTrackReceiverParams{
+		MediaTrack:          t,
+		IsRelayed:           true,
+		ParticipantID:       params.ParticipantID,
+		ParticipantIdentity: params.ParticipantIdentity,
+		ParticipantVersion:  params.ParticipantVersion,
+		ReceiverConfig:      params.ReceiverConfig,
+		SubscriberConfig:    params.SubscriberConfig,
+		AudioConfig:         params.AudioConfig,
+		Telemetry:           params.Telemetry,
+		Logger:              params.Logger,
+	}, ti)
+	t.MediaTrackReceiver.SetPotentialCodecs([]webrtc.RTPCodecParameters{params.Codec}, params.HeaderExtensions)
+	return t
+}
+
+func (t *RelayedTrack) OriginNodeID() livekit.NodeID {
+	return livekit.NodeID(t.params.OriginNode.Id)
+}
+
+func (t *RelayedTrack) ToProto() *livekit.TrackInfo {
+	return t.MediaTrackReceiver.TrackInfoClone()
+}
+
+func (t *RelayedTrack) OnTrackSubscribed() {}
+
+func (t *RelayedTrack) AddSubscriber(sub types.LocalParticipant) (types.SubscribedTrack, error) {
+	if err := t.ensureReceiver(); err != nil {
+		return nil, err
+	}
+
+	return t.MediaTrackReceiver.AddSubscriber(sub)
+}
+
+func (t *RelayedTrack) Close(isExpectedToResume bool) {
+	t.MediaTrackReceiver.SetClosing()
+	t.MediaTrackReceiver.ClearAllReceivers(isExpectedToResume)
+	t.MediaTrackReceiver.Close(isExpectedToResume)
+
+	t.lock.Lock()
+	receiver := t.receiver
+	t.receiver = nil
+	t.lock.Unlock()
+
+	if receiver != nil {
+		t.params.Relay.Unsubscribe(t.ID())
+	}
+}
+
+// ensureReceiver subscribes to the track on the origin node if not already subscribed
+func (t *RelayedTrack) ensureReceiver() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.receiver != nil && !t.receiver.IsClosed() {
+		return nil
+	}
+
+	var receiver *relay.Receiver
+	receiver, err := t.params.Relay.Subscribe(relay.SubscribeParams{
+		OriginNode:       t.params.OriginNode,
+		TrackInfo:        t.TrackInfo(),
+		Codec:            t.params.Codec,
+		HeaderExtensions: t.params.HeaderExtensions,
+		OnClose: func() {
+			t.onReceiverClosed(receiver)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	t.receiver = receiver
+	t.MediaTrackReceiver.SetupReceiver(receiver, 0, "")
+	return nil
+}
+
+func (t *RelayedTrack) onReceiverClosed(receiver *relay.Receiver) {
+	t.lock.Lock()
+	if t.receiver != receiver {
+		t.lock.Unlock()
+		return
+	}
+	t.receiver = nil
+	t.lock.Unlock()
+
+	t.MediaTrackReceiver.ClearReceiver(receiver.Codec().MimeType, false)
+}
@@ -0,0 +1,34 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// SetRemoveReason sets the reason reported when the participant is removed by a RemoveParticipant request, in the
+// leave request and participant info
+func (p *ParticipantImpl) SetRemoveReason(reason livekit.DisconnectReason) {
+	p.removeReason.Store(&reason)
+}
+
+func (p *ParticipantImpl) disconnectReason(reason types.ParticipantCloseReason) livekit.DisconnectReason {
+	if r := p.removeReason.Load(); r != nil && reason == types.ParticipantCloseReasonServiceRequestRemoveParticipant {
+		return *r
+	}
+	return reason.ToDisconnectReason()
+}
@@ -0,0 +1,120 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const defaultRetainedDataMessages = 100
+
+// retainedData buffers the latest messages of retained data topics, so participants joining later receive them too.
+// only messages sent to the whole room are retained
+type retainedData struct {
+	topics map[string]config.RetainedDataTopicConfig
+
+	lock    sync.Mutex
+	packets map[string][]retainedDataPacket
+}
+
+type retainedDataPacket struct {
+	kind livekit.DataPacket_Kind
+	data []byte
+	at   time.Time
+}
+
+func newRetainedData(topics []config.RetainedDataTopicConfig) *retainedData {
+	if len(topics) == 0 {
+		return nil
+	}
+
+	d := &retainedData{
+		topics:  make(map[string]config.RetainedDataTopicConfig, len(topics)),
+		packets: make(map[string][]retainedDataPacket),
+	}
+	for _, t := range topics {
+		if t.MaxMessages <= 0 {
+			t.MaxMessages = defaultRetainedDataMessages
+		}
+		d.topics[t.Topic] = t
+	}
+	return d
+}
+
+func (d *retainedData) add(kind livekit.DataPacket_Kind, dp *livekit.DataPacket) {
+	if d == nil {
+		return
+	}
+	u := dp.GetUser()
+	if u == nil || len(dp.DestinationIdentities) != 0 || len(u.DestinationSids) != 0 {
+		return
+	}
+	topic, ok := d.topics[u.GetTopic()]
+	if !ok {
+		return
+	}
+
+	data, err := proto.Marshal(dp)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	packets := append(d.packets[topic.Topic], retainedDataPacket{kind: kind, data: data, at: now})
+	if len(packets) > topic.MaxMessages {
+		packets = packets[len(packets)-topic.MaxMessages:]
+	}
+	d.packets[topic.Topic] = pruneRetainedData(packets, topic.MaxAge, now)
+}
+
+// replay returns the retained messages of all topics that haven't expired, oldest first per topic
+func (d *retainedData) replay() []retainedDataPacket {
+	if d == nil {
+		return nil
+	}
+
+	now := time.Now()
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	var replay []retainedDataPacket
+	for name, packets := range d.packets {
+		packets = pruneRetainedData(packets, d.topics[name].MaxAge, now)
+		d.packets[name] = packets
+		replay = append(replay, packets...)
+	}
+	return replay
+}
+
+func pruneRetainedData(packets []retainedDataPacket, maxAge time.Duration, now time.Time) []retainedDataPacket {
+	if maxAge <= 0 {
+		return packets
+	}
+	for i, p := range packets {
+		if now.Sub(p.at) <= maxAge {
+			return packets[i:]
+		}
+	}
+	return nil
+}
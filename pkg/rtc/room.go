@@ -21,7 +21,6 @@ import (
 	"io"
 	"math"
 	"slices"
-	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -58,6 +57,11 @@ const (
 	dataForwardLoadBalanceThreshold = 20
 
 	simulateDisconnectSignalTimeout = 5 * time.Second
+
+	// EventAgentJobRedispatched is sent when an agent job is dispatched again after its agent was lost
+	EventAgentJobRedispatched = "agent_job_redispatched"
+	// EventAgentJobFinished is sent when an agent reports the result of its job
+	EventAgentJobFinished = "job_finished"
 )
 
 var (
@@ -75,6 +79,8 @@ type AgentStore interface {
 
 	StoreAgentJob(ctx context.Context, job *livekit.Job) error
 	DeleteAgentJob(ctx context.Context, job *livekit.Job) error
+
+	StoreAgentJobResult(ctx context.Context, roomName livekit.RoomName, result *agent.JobResult) error
 }
 
 type broadcastOptions struct {
@@ -117,10 +123,39 @@ type Room struct {
 	egressLauncher  EgressLauncher
 	trackManager    *RoomTrackManager
 	agentDispatches map[string]*agentDispatch
+	retainedData    *retainedData
+	state           *RoomState
+	ephemeral       *ephemeralLimiter
+	mixedAudio      *mixedAudio
+	composedVideo   *composedVideo
+	speakers        *speakerDetector
+	duckingHint     *DuckingHint
+	loudness        map[livekit.TrackID]*loudnessTrack
+	// report which recipients reliable data messages with an id were sent to, to their sender
+	dataSendReceipts   bool
+	dataModerator      DataModerator
+	dataFlagNotifier   DataFlagNotifier
+	dataModerationConf config.DataModerationConfig
+	// messages of each sender waiting for the moderator
+	dataModerationQueues map[livekit.ParticipantID]*sutils.OpsQueue
+	mediaInterceptor     MediaInterceptor
+	dataCompressor       *DataCompressor
+	// data channel traffic of participants that have left
+	departedDataUsage DataUsage
+	// server config overridden for the room, applied to participants as they join
+	configOverrides *config.RoomConfigOverrides
+	// nil when the room has no close policy
+	closePolicy *roomClosePolicy
+	// nil when participants are not disconnected for being idle
+	idleParticipants *participantIdleTracker
 
 	// agents
 	agentClient agent.Client
 	agentStore  AgentStore
+	agentConfig config.AgentsConfig
+
+	// dispatch triggers that have fired, by index
+	firedDispatchTriggers map[int]bool
 
 	// map of identity -> Participant
 	participants              map[livekit.ParticipantIdentity]types.LocalParticipant
@@ -130,6 +165,14 @@ type Room struct {
 	agentParticpants          map[livekit.ParticipantIdentity]*agentJob
 	bufferFactory             *buffer.FactoryOfBufferFactory
 
+	// participants connected to other nodes hosting the room
+	relay               RelayProvider
+	relayedParticipants map[livekit.ParticipantIdentity]*relayedParticipant
+	// time of the last metadata update, to reconcile metadata with other nodes
+	metadataUpdatedAt int64
+	// recording indicated through the API, in addition to recorders in the room
+	manualRecording bool
+
 	// batch update participant info for non-publishers
 	batchedUpdates   map[livekit.ParticipantIdentity]*participantUpdate
 	batchedUpdatesMu sync.Mutex
@@ -141,6 +184,7 @@ type Room struct {
 	onParticipantChanged func(p types.LocalParticipant)
 	onRoomUpdated        func()
 	onClose              func()
+	onDataForwarded      func(kind livekit.DataPacket_Kind, dp *livekit.DataPacket)
 
 	simulationLock                                 sync.Mutex
 	disconnectSignalOnResumeParticipants           map[livekit.ParticipantIdentity]time.Time
@@ -239,6 +283,7 @@ func NewRoom(
 	config WebRTCConfig,
 	roomConfig config.RoomConfig,
 	audioConfig *config.AudioConfig,
+	agentConfig config.AgentsConfig,
 	serverInfo *livekit.ServerInfo,
 	telemetry telemetry.TelemetryService,
 	agentClient agent.Client,
@@ -259,6 +304,17 @@ func NewRoom(
 		egressLauncher:                       egressLauncher,
 		agentClient:                          agentClient,
 		agentStore:                           agentStore,
+		agentConfig:                          agentConfig,
+		firedDispatchTriggers:                make(map[int]bool),
+		retainedData:                         newRetainedData(roomConfig.RetainedDataTopics),
+		state:                                NewRoomState(roomConfig.StateKeys),
+		dataModerationQueues:                 make(map[livekit.ParticipantID]*sutils.OpsQueue),
+		ephemeral:                            newEphemeralLimiter(roomConfig.Ephemeral),
+		speakers:                             newSpeakerDetector(audioConfig),
+		loudness:                             make(map[livekit.TrackID]*loudnessTrack),
+		dataSendReceipts:                     roomConfig.DataSendReceipts,
+		closePolicy:                          newRoomClosePolicy(roomConfig.ClosePolicy),
+		idleParticipants:                     newParticipantIdleTracker(roomConfig.ParticipantIdle),
 		agentDispatches:                      make(map[string]*agentDispatch),
 		trackManager:                         NewRoomTrackManager(),
 		serverInfo:                           serverInfo,
@@ -267,6 +323,7 @@ func NewRoom(
 		participantRequestSources:            make(map[livekit.ParticipantIdentity]routing.MessageSource),
 		hasPublished:                         make(map[livekit.ParticipantIdentity]bool),
 		agentParticpants:                     make(map[livekit.ParticipantIdentity]*agentJob),
+		relayedParticipants:                  make(map[livekit.ParticipantIdentity]*relayedParticipant),
 		bufferFactory:                        buffer.NewFactoryOfBufferFactory(config.Receiver.PacketBufferSizeVideo, config.Receiver.PacketBufferSizeAudio),
 		batchedUpdates:                       make(map[livekit.ParticipantIdentity]*participantUpdate),
 		closed:                               make(chan struct{}),
@@ -286,6 +343,16 @@ func NewRoom(
 	}
 	r.protoProxy = utils.NewProtoProxy[*livekit.Room](roomUpdateInterval, r.updateProto)
 
+	if roomConfig.MixedAudio.Enabled {
+		r.mixedAudio = r.newMixedAudio(roomConfig.MixedAudio)
+	}
+	if roomConfig.VideoComposition.Enabled {
+		r.composedVideo = r.newComposedVideo(roomConfig.VideoComposition)
+	}
+	for _, track := range r.generatedTracks() {
+		r.trackManager.AddTrack(track, track.PublisherIdentity(), track.PublisherID())
+	}
+
 	r.createAgentDispatchesFromRoomAgent()
 
 	r.launchRoomAgents(maps.Values(r.agentDispatches))
@@ -358,22 +425,16 @@ func (r *Room) GetParticipantCount() int {
 
 func (r *Room) GetActiveSpeakers() []*livekit.SpeakerInfo {
 	participants := r.GetParticipants()
-	speakers := make([]*livekit.SpeakerInfo, 0, len(participants))
+	levels := make([]*livekit.SpeakerInfo, 0, len(participants))
 	for _, p := range participants {
 		level, active := p.GetAudioLevel()
-		if !active {
-			continue
-		}
-		speakers = append(speakers, &livekit.SpeakerInfo{
+		levels = append(levels, &livekit.SpeakerInfo{
 			Sid:    string(p.ID()),
 			Level:  float32(level),
 			Active: active,
 		})
 	}
-
-	sort.Slice(speakers, func(i, j int) bool {
-		return speakers[i].Level > speakers[j].Level
-	})
+	speakers := r.speakers.update(levels, time.Now())
 
 	// quantize to smooth out small changes
 	for _, speaker := range speakers {
@@ -383,6 +444,24 @@ func (r *Room) GetActiveSpeakers() []*livekit.SpeakerInfo {
 	return speakers
 }
 
+func (r *Room) GetAudioConfig() config.AudioConfig {
+	return *r.audioConfig
+}
+
+// SetConfigOverrides records the server config overridden for the room
+func (r *Room) SetConfigOverrides(overrides *config.RoomConfigOverrides) {
+	r.lock.Lock()
+	r.configOverrides = overrides
+	r.lock.Unlock()
+}
+
+// ConfigOverrides returns the server config overridden for the room, nil when nothing is overridden
+func (r *Room) ConfigOverrides() *config.RoomConfigOverrides {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.configOverrides
+}
+
 func (r *Room) GetBufferFactory() *buffer.Factory {
 	return r.bufferFactory.CreateBufferFactory()
 }
@@ -451,6 +530,8 @@ func (r *Room) Join(participant types.LocalParticipant, requestSource routing.Me
 		if state == livekit.ParticipantInfo_ACTIVE {
 			// subscribe participant to existing published tracks
 			r.subscribeToExistingTracks(p)
+			r.replayRetainedData(p)
+			r.sendDuckingHint(p)
 
 			meta := &livekit.AnalyticsClientMeta{
 				ClientConnectTime: uint32(time.Since(p.ConnectedAt()).Milliseconds()),
@@ -525,14 +606,8 @@ func (r *Room) Join(participant types.LocalParticipant, requestSource routing.Me
 		"numParticipants", len(r.participants),
 	)
 
-	if participant.IsRecorder() && !r.protoRoom.ActiveRecording {
-		r.protoRoom.ActiveRecording = true
-		r.protoProxy.MarkDirty(true)
-	} else {
-		r.protoProxy.MarkDirty(false)
-	}
-
 	r.participants[participant.Identity()] = participant
+	r.protoProxy.MarkDirty(participant.IsRecorder() && r.updateActiveRecordingLocked())
 	r.participantOpts[participant.Identity()] = opts
 	r.participantRequestSources[participant.Identity()] = requestSource
 
@@ -672,24 +747,17 @@ func (r *Room) RemoveParticipant(identity livekit.ParticipantIdentity, pID livek
 	delete(r.participantRequestSources, identity)
 	delete(r.hasPublished, identity)
 	delete(r.agentParticpants, identity)
+	r.ephemeral.forget(identity)
+	r.idleParticipants.forget(p.ID())
+	r.stopDataModerationLocked(p.ID())
+	r.departedDataUsage.Add(NewDataUsage(p.GetDataTrafficTotals()))
 	if !p.Hidden() {
 		r.protoRoom.NumParticipants--
 	}
 
 	immediateChange := false
 	if p.IsRecorder() {
-		activeRecording := false
-		for _, op := range r.participants {
-			if op.IsRecorder() {
-				activeRecording = true
-				break
-			}
-		}
-
-		if r.protoRoom.ActiveRecording != activeRecording {
-			r.protoRoom.ActiveRecording = activeRecording
-			immediateChange = true
-		}
+		immediateChange = r.updateActiveRecordingLocked()
 	}
 	r.lock.Unlock()
 	r.protoProxy.MarkDirty(immediateChange)
@@ -707,7 +775,10 @@ func (r *Room) RemoveParticipant(identity livekit.ParticipantIdentity, pID livek
 	// remove all published tracks
 	for _, t := range p.GetPublishedTracks() {
 		r.trackManager.RemoveTrack(t)
+		r.removeGeneratedTrackInputs(t)
+		r.removeLoudnessTrack(t.ID())
 	}
+	r.removeMixedAudioOutput(identity)
 
 	if agentJob != nil {
 		agentJob.participantLeft()
@@ -718,6 +789,10 @@ func (r *Room) RemoveParticipant(identity livekit.ParticipantIdentity, pID livek
 				r.Logger.Infow("failed sending TerminateJob RPC", "error", err, "jobID", agentJob.Id, "participant", identity)
 			}
 		}()
+
+		if agentLeftUnexpectedly(reason) {
+			go r.redispatchAgentJob(agentJob.Job, identity, reason)
+		}
 	}
 
 	p.OnTrackUpdated(nil)
@@ -829,6 +904,20 @@ func (r *Room) UpdateSubscriptionPermission(participant types.LocalParticipant,
 	for _, track := range participant.GetPublishedTracks() {
 		r.trackManager.NotifyTrackChanged(track.ID())
 	}
+	r.updateGeneratedTrackPermissions()
+	return nil
+}
+
+// UpdateAdminSubscriptionPermission restricts who can subscribe to the tracks of participant on top of the
+// permissions it sets itself, nil removes the restrictions
+func (r *Room) UpdateAdminSubscriptionPermission(participant types.LocalParticipant, subscriptionPermission *livekit.SubscriptionPermission) error {
+	if err := participant.UpdateAdminSubscriptionPermission(subscriptionPermission, r.GetParticipantByID); err != nil {
+		return err
+	}
+	for _, track := range participant.GetPublishedTracks() {
+		r.trackManager.NotifyTrackChanged(track.ID())
+	}
+	r.updateGeneratedTrackPermissions()
 	return nil
 }
 
@@ -851,6 +940,11 @@ func (r *Room) ResolveMediaTrackForSubscriber(subIdentity livekit.ParticipantIde
 	// when publisher is not found, we will assume it doesn't have permission to access
 	if pub != nil {
 		res.HasPermission = pub.HasPermission(trackID, subIdentity)
+	} else if track, hasPermission, ok := r.resolveGeneratedTrack(subIdentity, trackID); ok {
+		res.Track = track
+		res.HasPermission = hasPermission
+	} else {
+		res.HasPermission = r.hasRelayedPermission(info.PublisherIdentity, trackID, subIdentity)
 	}
 
 	return res
@@ -881,6 +975,12 @@ func (r *Room) CloseIfEmpty() {
 		}
 	}
 
+	// room is still active on other nodes
+	if len(r.relayedParticipants) != 0 {
+		r.lock.Unlock()
+		return
+	}
+
 	var timeout uint32
 	var elapsed int64
 	if r.FirstJoinedAt() > 0 && r.LastLeftAt() > 0 {
@@ -908,12 +1008,18 @@ func (r *Room) Close(reason types.ParticipantCloseReason) {
 		// fall through
 	}
 	close(r.closed)
+	for participantID := range r.dataModerationQueues {
+		r.stopDataModerationLocked(participantID)
+	}
 	r.lock.Unlock()
 
 	r.Logger.Infow("closing room")
 	for _, p := range r.GetParticipants() {
 		_ = p.Close(true, reason, false)
 	}
+	r.closeRelayedParticipants()
+	r.closeGeneratedTracks()
+	r.closeLoudnessTracks()
 
 	r.protoProxy.Stop()
 
@@ -922,6 +1028,13 @@ func (r *Room) Close(reason types.ParticipantCloseReason) {
 	}
 }
 
+// RedirectParticipants asks clients to reconnect, the node is going away and they are routed to another one
+func (r *Room) RedirectParticipants() {
+	for _, p := range r.GetParticipants() {
+		p.IssueFullReconnect(types.ParticipantCloseReasonRoomManagerStop)
+	}
+}
+
 func (r *Room) OnClose(f func()) {
 	r.onClose = f
 }
@@ -930,6 +1043,13 @@ func (r *Room) OnParticipantChanged(f func(participant types.LocalParticipant))
 	r.onParticipantChanged = f
 }
 
+// OnDataPacket is called with every data packet sent in the room, after it has been forwarded to participants
+func (r *Room) OnDataPacket(f func(kind livekit.DataPacket_Kind, dp *livekit.DataPacket)) {
+	r.lock.Lock()
+	r.onDataForwarded = f
+	r.lock.Unlock()
+}
+
 func (r *Room) SendDataPacket(dp *livekit.DataPacket, kind livekit.DataPacket_Kind) {
 	r.onDataPacket(nil, kind, dp)
 }
@@ -937,10 +1057,40 @@ func (r *Room) SendDataPacket(dp *livekit.DataPacket, kind livekit.DataPacket_Ki
 func (r *Room) SetMetadata(metadata string) <-chan struct{} {
 	r.lock.Lock()
 	r.protoRoom.Metadata = metadata
+	r.metadataUpdatedAt = time.Now().UnixNano()
 	r.lock.Unlock()
 	return r.protoProxy.MarkDirty(true)
 }
 
+// SetRecording indicates the room is being recorded by means other than egress, e.g. a recording agent.
+// Participants see the room as recorded while either this is set or a recorder is in the room.
+func (r *Room) SetRecording(active bool) <-chan struct{} {
+	r.lock.Lock()
+	r.manualRecording = active
+	changed := r.updateActiveRecordingLocked()
+	r.lock.Unlock()
+	return r.protoProxy.MarkDirty(changed)
+}
+
+// updateActiveRecordingLocked returns true when the recording state changed
+func (r *Room) updateActiveRecordingLocked() bool {
+	activeRecording := r.manualRecording
+	if !activeRecording {
+		for _, p := range r.participants {
+			if p.IsRecorder() {
+				activeRecording = true
+				break
+			}
+		}
+	}
+
+	if r.protoRoom.ActiveRecording == activeRecording {
+		return false
+	}
+	r.protoRoom.ActiveRecording = activeRecording
+	return true
+}
+
 func (r *Room) sendRoomUpdate() {
 	roomInfo := r.ToProto()
 	// Send update to participants
@@ -1117,6 +1267,11 @@ func (r *Room) getOtherParticipantInfo(identity livekit.ParticipantIdentity) []*
 		}
 	}
 
+	r.lock.RLock()
+	pi = append(pi, r.getRelayedParticipantInfoLocked()...)
+	pi = append(pi, r.getGeneratedParticipantInfoLocked()...)
+	r.lock.RUnlock()
+
 	return pi
 }
 
@@ -1138,6 +1293,8 @@ func (r *Room) createJoinResponseLocked(participant types.LocalParticipant, iceS
 			otherParticipants = append(otherParticipants, p.ToProto())
 		}
 	}
+	otherParticipants = append(otherParticipants, r.getRelayedParticipantInfoLocked()...)
+	otherParticipants = append(otherParticipants, r.getGeneratedParticipantInfoLocked()...)
 
 	return &livekit.JoinResponse{
 		Room:              r.ToProto(),
@@ -1193,6 +1350,8 @@ func (r *Room) onTrackPublished(participant types.LocalParticipant, track types.
 	}
 
 	r.trackManager.AddTrack(track, participant.Identity(), participant.ID())
+	r.addGeneratedTrackInputs(participant, track)
+	r.addLoudnessTrack(participant, track)
 
 	// launch jobs
 	r.lock.Lock()
@@ -1200,6 +1359,8 @@ func (r *Room) onTrackPublished(participant types.LocalParticipant, track types.
 	r.hasPublished[participant.Identity()] = true
 	r.lock.Unlock()
 
+	r.evaluateDispatchTriggers(participant)
+
 	if !hasPublished {
 		r.lock.RLock()
 		r.launchPublisherAgents(maps.Values(r.agentDispatches), participant)
@@ -1247,6 +1408,8 @@ func (r *Room) onTrackUpdated(p types.LocalParticipant, _ types.MediaTrack) {
 
 func (r *Room) onTrackUnpublished(p types.LocalParticipant, track types.MediaTrack) {
 	r.trackManager.RemoveTrack(track)
+	r.removeGeneratedTrackInputs(track)
+	r.removeLoudnessTrack(track.ID())
 	if !p.IsClosed() {
 		r.broadcastParticipantState(p, broadcastOptions{skipSource: true})
 	}
@@ -1262,10 +1425,74 @@ func (r *Room) onParticipantUpdate(p types.LocalParticipant) {
 	if r.onParticipantChanged != nil {
 		r.onParticipantChanged(p)
 	}
+	r.evaluateDispatchTriggers(p)
 }
 
 func (r *Room) onDataPacket(source types.LocalParticipant, kind livekit.DataPacket_Kind, dp *livekit.DataPacket) {
-	BroadcastDataPacketForRoom(r, source, kind, dp, r.Logger)
+	if source != nil {
+		r.idleParticipants.dataReceived(source.ID())
+	}
+
+	// reserved topics are handled by the room instead of being broadcast
+	switch topic := dp.GetUser().GetTopic(); {
+	case source != nil && topic == RoomStateTopic:
+		r.handleStateRequest(source, dp.GetUser())
+		return
+	case source != nil && topic == DirectSignalTopic:
+		r.relayDirectSignal(source, dp)
+		return
+	case IsEphemeralTopic(topic):
+		if !r.admitEphemeralData(source, dp) {
+			return
+		}
+	}
+	if !r.interceptData(source, dp) {
+		return
+	}
+	if !r.moderateData(source, kind, dp) {
+		return
+	}
+	r.forwardData(source, kind, dp)
+}
+
+// forwardData sends a data packet that passed interception and moderation to the room
+func (r *Room) forwardData(source types.LocalParticipant, kind livekit.DataPacket_Kind, dp *livekit.DataPacket) {
+	if IsEphemeralTopic(dp.GetUser().GetTopic()) {
+		r.broadcastEphemeral(source, dp)
+		return
+	}
+
+	r.lock.RLock()
+	dataCompressor := r.dataCompressor
+	r.lock.RUnlock()
+	sent, failed := broadcastDataPacket(r, source, kind, dp, r.Logger, dataCompressor.encoder(dp))
+	if r.dataSendReceipts && wantsSendReceipt(source, kind, dp) {
+		if err := replySendReceipt(source, dp.GetUser().GetId(), sent, failed); err != nil {
+			source.GetLogger().Infow("could not reply send receipt", "error", err)
+		}
+	}
+	r.retainedData.add(kind, dp)
+
+	r.lock.RLock()
+	onDataForwarded := r.onDataForwarded
+	r.lock.RUnlock()
+	if onDataForwarded != nil {
+		onDataForwarded(kind, dp)
+	}
+}
+
+// replayRetainedData sends the messages of retained data topics to a participant that just became active
+func (r *Room) replayRetainedData(p types.LocalParticipant) {
+	packets := r.retainedData.replay()
+	for _, packet := range packets {
+		if err := p.SendDataPacket(packet.kind, packet.data); err != nil {
+			p.GetLogger().Infow("could not replay retained data", "error", err)
+			return
+		}
+	}
+	if len(packets) > 0 {
+		p.GetLogger().Debugw("replayed retained data", "messages", len(packets))
+	}
 }
 
 func (r *Room) subscribeToExistingTracks(p types.LocalParticipant) {
@@ -1289,6 +1516,10 @@ func (r *Room) subscribeToExistingTracks(p types.LocalParticipant) {
 			p.SubscribeToTrack(track.ID())
 		}
 	}
+	for _, trackID := range r.getRelayedTrackIDs() {
+		trackIDs = append(trackIDs, trackID)
+		p.SubscribeToTrack(trackID)
+	}
 	if len(trackIDs) > 0 {
 		r.Logger.Debugw("subscribed participant to existing tracks", "trackID", trackIDs)
 	}
@@ -1476,6 +1707,8 @@ func (r *Room) audioUpdateWorker() {
 		}
 
 		activeSpeakers := r.GetActiveSpeakers()
+		r.updateDucking(activeSpeakers)
+		r.updateComposedVideoFocus(activeSpeakers)
 		changedSpeakers := make([]*livekit.SpeakerInfo, 0, len(activeSpeakers))
 		nextActiveMap := make(map[livekit.ParticipantID]*livekit.SpeakerInfo, len(activeSpeakers))
 		for _, speaker := range activeSpeakers {
@@ -1528,6 +1761,7 @@ func (r *Room) connectionQualityWorker() {
 				nowConnectionInfos[p.ID()] = q
 			}
 		}
+		r.ephemeral.updateConnectionQuality(nowConnectionInfos)
 
 		// send an update if there is a change
 		//   - new participant
@@ -1657,6 +1891,59 @@ func (r *Room) launchPublisherAgents(ads []*agentDispatch, p types.Participant)
 	}
 }
 
+// redispatchAgentJob launches a job again when its agent did not come back within the grace period
+func (r *Room) redispatchAgentJob(job *livekit.Job, identity livekit.ParticipantIdentity, reason types.ParticipantCloseReason) {
+	if r.agentConfig.RedispatchGracePeriod <= 0 {
+		return
+	}
+
+	select {
+	case <-r.closed:
+		return
+	case <-time.After(r.agentConfig.RedispatchGracePeriod):
+	}
+
+	r.lock.RLock()
+	ad := r.agentDispatches[job.DispatchId]
+	rejoined := r.participants[identity] != nil
+	var publisher types.LocalParticipant
+	if job.Type == livekit.JobType_JT_PUBLISHER {
+		publisher = r.participants[livekit.ParticipantIdentity(job.Participant.GetIdentity())]
+	}
+	r.lock.RUnlock()
+
+	if ad == nil || rejoined {
+		return
+	}
+
+	switch job.Type {
+	case livekit.JobType_JT_ROOM:
+		r.launchRoomAgents([]*agentDispatch{ad})
+	case livekit.JobType_JT_PUBLISHER:
+		if publisher == nil {
+			return
+		}
+		r.launchPublisherAgents([]*agentDispatch{ad}, publisher)
+	default:
+		return
+	}
+
+	r.Logger.Infow("redispatching agent job",
+		"jobID", job.Id,
+		"dispatchID", job.DispatchId,
+		"participant", identity,
+		"reason", reason,
+	)
+	r.telemetry.NotifyEvent(context.Background(), &livekit.WebhookEvent{
+		Event: EventAgentJobRedispatched,
+		Room:  r.ToProto(),
+		Participant: &livekit.ParticipantInfo{
+			Identity: string(identity),
+			Kind:     livekit.ParticipantInfo_AGENT,
+		},
+	})
+}
+
 func (r *Room) handleNewJobs(ad *livekit.AgentDispatch, inc *sutils.IncrementalDispatcher[*livekit.Job]) {
 	inc.ForEach(func(job *livekit.Job) {
 		r.agentStore.StoreAgentJob(context.Background(), job)
@@ -1669,6 +1956,134 @@ func (r *Room) handleNewJobs(ad *livekit.AgentDispatch, inc *sutils.IncrementalD
 	})
 }
 
+// ReportAgentJobResult records the result of a job of one of the room's dispatches.
+// When identity is set, it has to be the agent participant that took the job.
+func (r *Room) ReportAgentJobResult(ctx context.Context, identity livekit.ParticipantIdentity, result *agent.JobResult) error {
+	if err := result.Validate(); err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	var job *livekit.Job
+	for _, ad := range r.agentDispatches {
+		if ad.State == nil {
+			continue
+		}
+		for _, j := range ad.State.Jobs {
+			if j.Id == result.JobID {
+				job = j
+			}
+		}
+	}
+	if job == nil || (identity != "" && job.State.GetParticipantIdentity() != string(identity)) {
+		r.lock.Unlock()
+		return agent.ErrJobNotFound
+	}
+
+	if job.State == nil {
+		job.State = &livekit.JobState{}
+	}
+	result.DispatchID = job.DispatchId
+	result.ReportedAt = time.Now().UnixNano()
+	job.State.Status = result.Status()
+	job.State.Error = result.Error
+	job.State.EndedAt = result.ReportedAt
+	job = proto.Clone(job).(*livekit.Job)
+	r.lock.Unlock()
+
+	if r.agentStore != nil {
+		if err := r.agentStore.StoreAgentJob(ctx, job); err != nil {
+			return err
+		}
+		if err := r.agentStore.StoreAgentJobResult(ctx, r.Name(), result); err != nil {
+			return err
+		}
+	}
+
+	r.Logger.Infow("agent job finished",
+		"jobID", job.Id,
+		"dispatchID", job.DispatchId,
+		"participant", job.State.ParticipantIdentity,
+		"status", job.State.Status,
+	)
+	r.telemetry.NotifyEvent(ctx, &livekit.WebhookEvent{
+		Event: EventAgentJobFinished,
+		Room:  r.ToProto(),
+		Participant: &livekit.ParticipantInfo{
+			Identity:   job.State.ParticipantIdentity,
+			Kind:       livekit.ParticipantInfo_AGENT,
+			Attributes: result.Attributes(),
+		},
+	})
+	return nil
+}
+
+// evaluateDispatchTriggers creates the dispatches of triggers p matches that haven't fired in the room yet
+func (r *Room) evaluateDispatchTriggers(p types.LocalParticipant) {
+	if len(r.agentConfig.DispatchTriggers) == 0 || p.Kind() == livekit.ParticipantInfo_AGENT {
+		return
+	}
+
+	var fired []config.AgentDispatchTriggerConfig
+	r.lock.Lock()
+	for i, trigger := range r.agentConfig.DispatchTriggers {
+		if !r.firedDispatchTriggers[i] && dispatchTriggerMatches(trigger, p) {
+			r.firedDispatchTriggers[i] = true
+			fired = append(fired, trigger)
+		}
+	}
+	r.lock.Unlock()
+
+	for _, trigger := range fired {
+		go func() {
+			ad, err := r.AddAgentDispatch(trigger.AgentName, trigger.Metadata)
+			if err != nil {
+				r.Logger.Warnw("failed to dispatch agent from trigger", err, "agentName", trigger.AgentName, "participant", p.Identity())
+				return
+			}
+			r.Logger.Infow("dispatched agent from trigger", "agentName", trigger.AgentName, "dispatchID", ad.Id, "participant", p.Identity())
+		}()
+	}
+}
+
+func dispatchTriggerMatches(trigger config.AgentDispatchTriggerConfig, p types.LocalParticipant) bool {
+	if trigger.Attribute == "" && trigger.TrackType == "" && trigger.TrackSource == "" {
+		return false
+	}
+
+	if trigger.Attribute != "" {
+		value, ok := p.ToProto().Attributes[trigger.Attribute]
+		if !ok || (trigger.AttributeValue != "" && value != trigger.AttributeValue) {
+			return false
+		}
+	}
+
+	if trigger.TrackType != "" || trigger.TrackSource != "" {
+		return slices.ContainsFunc(p.GetPublishedTracks(), func(t types.MediaTrack) bool {
+			return (trigger.TrackType == "" || strings.EqualFold(t.Kind().String(), trigger.TrackType)) &&
+				(trigger.TrackSource == "" || strings.EqualFold(t.Source().String(), trigger.TrackSource))
+		})
+	}
+	return true
+}
+
+// agentLeftUnexpectedly is true when an agent was lost rather than asked to leave
+func agentLeftUnexpectedly(reason types.ParticipantCloseReason) bool {
+	switch reason {
+	case types.ParticipantCloseReasonStale,
+		types.ParticipantCloseReasonPeerConnectionDisconnected,
+		types.ParticipantCloseReasonSignalSourceClose,
+		types.ParticipantCloseReasonMessageBusFailed,
+		types.ParticipantCloseReasonNegotiateFailed,
+		types.ParticipantCloseReasonPublicationError,
+		types.ParticipantCloseReasonSubscriptionError,
+		types.ParticipantCloseReasonDataChannelError,
+		types.ParticipantCloseReasonSimulateNodeFailure:
+		return true
+	}
+	return false
+}
+
 func (r *Room) DebugInfo() map[string]interface{} {
 	info := map[string]interface{}{
 		"Name":      r.protoRoom.Name,
@@ -1736,7 +2151,27 @@ func (r *Room) createAgentDispatchesFromRoomAgent() {
 
 // ------------------------------------------------------------
 
-func BroadcastDataPacketForRoom(r types.Room, source types.LocalParticipant, kind livekit.DataPacket_Kind, dp *livekit.DataPacket, logger logger.Logger) {
+// BroadcastDataPacketForRoom sends dp to its destinations in the room and returns the recipients it could and couldn't be sent to
+func BroadcastDataPacketForRoom(
+	r types.Room,
+	source types.LocalParticipant,
+	kind livekit.DataPacket_Kind,
+	dp *livekit.DataPacket,
+	logger logger.Logger,
+) (sent []livekit.ParticipantIdentity, failed []livekit.ParticipantIdentity) {
+	return broadcastDataPacket(r, source, kind, dp, logger, nil)
+}
+
+// broadcastDataPacket sends dp like BroadcastDataPacketForRoom. when set, encode returns the packet to send to each
+// recipient instead of the marshalled dp, recipients it fails for are reported as failed
+func broadcastDataPacket(
+	r types.Room,
+	source types.LocalParticipant,
+	kind livekit.DataPacket_Kind,
+	dp *livekit.DataPacket,
+	logger logger.Logger,
+	encode func(op types.LocalParticipant, dpData []byte) ([]byte, error),
+) (sent []livekit.ParticipantIdentity, failed []livekit.ParticipantIdentity) {
 	dp.Kind = kind // backward compatibility
 	dest := dp.GetUser().GetDestinationSids()
 	if u := dp.GetUser(); u != nil {
@@ -1787,13 +2222,41 @@ func BroadcastDataPacketForRoom(r types.Room, source types.LocalParticipant, kin
 		destParticipants = append(destParticipants, op)
 	}
 
+	var resultsLock sync.Mutex
 	utils.ParallelExec(destParticipants, dataForwardLoadBalanceThreshold, 1, func(op types.LocalParticipant) {
-		err := op.SendDataPacket(kind, dpData)
+		var err error
+		data := dpData
+		if encode != nil {
+			// recipients may not be able to decode the original payload, don't fall back to it
+			if data, err = encode(op, dpData); err != nil {
+				op.GetLogger().Infow("could not encode data packet", "error", err)
+			}
+		}
+		if err == nil {
+			err = op.SendDataPacket(kind, data)
+		}
 		if err != nil && !errors.Is(err, io.ErrClosedPipe) && !errors.Is(err, sctp.ErrStreamClosed) &&
 			!errors.Is(err, ErrTransportFailure) && !errors.Is(err, ErrDataChannelBufferFull) {
 			op.GetLogger().Infow("send data packet error", "error", err)
 		}
+
+		resultsLock.Lock()
+		if err == nil {
+			sent = append(sent, op.Identity())
+		} else {
+			failed = append(failed, op.Identity())
+		}
+		resultsLock.Unlock()
 	})
+
+	// identities addressed that aren't active in the room
+	for _, identity := range destIdentities {
+		id := livekit.ParticipantIdentity(identity)
+		if !slices.Contains(sent, id) && !slices.Contains(failed, id) {
+			failed = append(failed, id)
+		}
+	}
+	return
 }
 
 func IsCloseNotifySkippable(closeReason types.ParticipantCloseReason) bool {
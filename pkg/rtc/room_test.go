@@ -15,26 +15,44 @@
 package rtc
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/proto"
 
+	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
 	"github.com/livekit/protocol/webhook"
 
 	"github.com/livekit/livekit-server/version"
 
+	"github.com/livekit/livekit-server/pkg/agent"
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/rtc/types"
 	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
 	"github.com/livekit/livekit-server/pkg/sfu/audio"
+	"github.com/livekit/livekit-server/pkg/sfu/compositor"
+	"github.com/livekit/livekit-server/pkg/sfu/mixer"
 	"github.com/livekit/livekit-server/pkg/telemetry"
 	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
 	"github.com/livekit/livekit-server/pkg/testutils"
+	sutils "github.com/livekit/livekit-server/pkg/utils"
 )
 
 func init() {
@@ -565,6 +583,102 @@ func TestActiveSpeakers(t *testing.T) {
 			return "speakers didn't go back to zero"
 		})
 	})
+
+	t.Run("attack and decay", func(t *testing.T) {
+		d := newSpeakerDetector(&config.AudioConfig{
+			AttackTime:          200 * time.Millisecond,
+			DecayTime:           time.Second,
+			MinSpeakingDuration: 2 * time.Second,
+		})
+		levels := func(active bool) []*livekit.SpeakerInfo {
+			return []*livekit.SpeakerInfo{{Sid: "PA_1", Level: 0.5, Active: active}}
+		}
+		start := time.Now()
+
+		// short bursts are not reported
+		require.Empty(t, d.update(levels(true), start))
+		require.Empty(t, d.update(levels(false), start.Add(100*time.Millisecond)))
+		require.Empty(t, d.update(levels(true), start.Add(200*time.Millisecond)))
+
+		speakers := d.update(levels(true), start.Add(400*time.Millisecond))
+		require.Len(t, speakers, 1)
+		require.True(t, speakers[0].Active)
+		require.Equal(t, float32(0.5), speakers[0].Level)
+
+		// reported for the minimum duration, then through the decay
+		require.Len(t, d.update(levels(false), start.Add(2*time.Second)), 1)
+		require.Len(t, d.update(levels(true), start.Add(2500*time.Millisecond)), 1)
+		require.Len(t, d.update(levels(false), start.Add(3*time.Second)), 1)
+		require.Empty(t, d.update(levels(false), start.Add(3500*time.Millisecond)))
+	})
+
+	t.Run("priority speakers duck others", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 3, protocol: 3})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+		p0 := rm.GetParticipant("p0").(*typesfakes.FakeLocalParticipant)
+		p1 := rm.GetParticipant("p1").(*typesfakes.FakeLocalParticipant)
+		p2 := rm.GetParticipant("p2").(*typesfakes.FakeLocalParticipant)
+		p0.ClaimGrantsReturns(&auth.ClaimGrants{Attributes: map[string]string{
+			DuckingPriorityAttribute: "1",
+			DuckingGainAttribute:     "0.1",
+		}})
+		// a quiet priority source doesn't duck anyone
+		p1.GetAudioLevelReturns(0.5, true)
+		time.Sleep(audioUpdateDuration)
+		require.Zero(t, p2.SendDataPacketCallCount())
+
+		p0.GetAudioLevelReturns(0.3, true)
+		lastHint := func(p *typesfakes.FakeLocalParticipant) *DuckingHint {
+			n := p.SendDataPacketCallCount()
+			if n == 0 {
+				return nil
+			}
+			_, data := p.SendDataPacketArgsForCall(n - 1)
+			var dp livekit.DataPacket
+			require.NoError(t, proto.Unmarshal(data, &dp))
+			require.Equal(t, DuckingTopic, dp.GetUser().GetTopic())
+			var hint DuckingHint
+			require.NoError(t, json.Unmarshal(dp.GetUser().Payload, &hint))
+			return &hint
+		}
+		testutils.WithTimeout(t, func() string {
+			if lastHint(p2) == nil {
+				return "no ducking hint received"
+			}
+			return ""
+		})
+		require.Equal(t, &DuckingHint{
+			Active:  true,
+			Sources: []string{"p0"},
+			Duck:    []string{"p1", "p2"},
+			Gain:    0.1,
+		}, lastHint(p2))
+
+		// hints are only sent when changed
+		time.Sleep(audioUpdateDuration)
+		require.Equal(t, 1, p2.SendDataPacketCallCount())
+
+		p0.GetAudioLevelReturns(0, false)
+		testutils.WithTimeout(t, func() string {
+			if hint := lastHint(p1); hint == nil || hint.Active {
+				return "ducking did not end"
+			}
+			return ""
+		})
+	})
+
+	t.Run("limits number of speakers", func(t *testing.T) {
+		d := newSpeakerDetector(&config.AudioConfig{MaxActiveSpeakers: 2})
+		speakers := d.update([]*livekit.SpeakerInfo{
+			{Sid: "PA_1", Level: 0.2, Active: true},
+			{Sid: "PA_2", Level: 0.6, Active: true},
+			{Sid: "PA_3", Level: 0.4, Active: true},
+			{Sid: "PA_4", Level: 0.9, Active: false},
+		}, time.Now())
+		require.Len(t, speakers, 2)
+		require.Equal(t, "PA_2", speakers[0].Sid)
+		require.Equal(t, "PA_3", speakers[1].Sid)
+	})
 }
 
 func TestDataChannel(t *testing.T) {
@@ -717,6 +831,822 @@ func TestDataChannel(t *testing.T) {
 			require.Zero(t, fp.SendDataPacketCallCount())
 		}
 	})
+
+	t.Run("senders receive send receipts", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 3, dataSendReceipts: true})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+		p0 := rm.GetParticipant("p0").(*typesfakes.FakeLocalParticipant)
+		p2 := rm.GetParticipant("p2").(*typesfakes.FakeLocalParticipant)
+		p2.SendDataPacketReturns(ErrDataChannelBufferFull)
+
+		send := func(id string, dest ...string) {
+			p0.OnDataPacketArgsForCall(0)(p0, livekit.DataPacket_RELIABLE, &livekit.DataPacket{
+				ParticipantIdentity:   "p0",
+				DestinationIdentities: dest,
+				Value: &livekit.DataPacket_User{
+					User: &livekit.UserPacket{Id: &id, Payload: []byte("hello")},
+				},
+			})
+		}
+		receipt := func(i int) SendReceipt {
+			_, data := p0.SendDataPacketArgsForCall(i)
+			var dp livekit.DataPacket
+			require.NoError(t, proto.Unmarshal(data, &dp))
+			require.Equal(t, SendReceiptTopic, dp.GetUser().GetTopic())
+			var r SendReceipt
+			require.NoError(t, json.Unmarshal(dp.GetUser().Payload, &r))
+			return r
+		}
+
+		send("m1")
+		require.Equal(t, 1, p0.SendDataPacketCallCount())
+		require.Equal(t, SendReceipt{ID: "m1", Sent: []string{"p1"}, Failed: []string{"p2"}}, receipt(0))
+
+		send("m2", "p1", "missing")
+		require.Equal(t, 2, p0.SendDataPacketCallCount())
+		require.Equal(t, SendReceipt{ID: "m2", Sent: []string{"p1"}, Failed: []string{"missing"}}, receipt(1))
+
+		send("")
+		require.Equal(t, 2, p0.SendDataPacketCallCount())
+	})
+
+	t.Run("retained topics are replayed to late joiners", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{
+			num:                2,
+			retainedDataTopics: []config.RetainedDataTopicConfig{{Topic: "chat", MaxMessages: 2}},
+		})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+		p := rm.GetParticipants()[0].(*typesfakes.FakeLocalParticipant)
+
+		send := func(topic string, payload string, dest ...string) {
+			p.OnDataPacketArgsForCall(0)(p, livekit.DataPacket_RELIABLE, &livekit.DataPacket{
+				ParticipantIdentity:   string(p.Identity()),
+				DestinationIdentities: dest,
+				Value: &livekit.DataPacket_User{
+					User: &livekit.UserPacket{Topic: &topic, Payload: []byte(payload)},
+				},
+			})
+		}
+		send("chat", "one")
+		send("chat", "two")
+		send("chat", "three")
+		send("cursor", "not retained")
+		send("chat", "private", "p1")
+
+		late := NewMockParticipant("late", types.CurrentProtocol, false, false)
+		require.NoError(t, rm.Join(late, nil, &ParticipantOptions{}, iceServersForRoom))
+		late.OnStateChangeArgsForCall(0)(late, livekit.ParticipantInfo_ACTIVE)
+
+		require.Equal(t, 2, late.SendDataPacketCallCount())
+		for i, payload := range []string{"two", "three"} {
+			kind, data := late.SendDataPacketArgsForCall(i)
+			require.Equal(t, livekit.DataPacket_RELIABLE, kind)
+			var dp livekit.DataPacket
+			require.NoError(t, proto.Unmarshal(data, &dp))
+			require.Equal(t, payload, string(dp.GetUser().Payload))
+		}
+	})
+
+	t.Run("moderator can drop, redact and flag messages", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+		p0 := rm.GetParticipant("p0").(*typesfakes.FakeLocalParticipant)
+		p1 := rm.GetParticipant("p1").(*typesfakes.FakeLocalParticipant)
+
+		var (
+			mu       sync.Mutex
+			requests []*DataModerationRequest
+			flagged  []*DataMessageFlagged
+		)
+		flagNotifier := dataFlagNotifierFunc(func(ctx context.Context, f *DataMessageFlagged) {
+			mu.Lock()
+			defer mu.Unlock()
+			flagged = append(flagged, f)
+		})
+		rm.SetDataModerator(dataModeratorFunc(func(ctx context.Context, req *DataModerationRequest) (*DataModerationResult, error) {
+			mu.Lock()
+			requests = append(requests, req)
+			mu.Unlock()
+			switch string(req.Payload) {
+			case "spam":
+				return &DataModerationResult{Action: DataModerationDrop}, nil
+			case "secret":
+				return &DataModerationResult{Action: DataModerationRedact, Payload: []byte("***")}, nil
+			case "rude":
+				return &DataModerationResult{Action: DataModerationFlag, Reason: "rude"}, nil
+			case "error":
+				return nil, errors.New("unavailable")
+			}
+			return &DataModerationResult{Action: DataModerationAllow}, nil
+		}), flagNotifier, config.DataModerationConfig{IncludeContent: true, Topics: []string{"chat"}})
+
+		send := func(topic string, payload string) {
+			p0.OnDataPacketArgsForCall(0)(p0, livekit.DataPacket_RELIABLE, &livekit.DataPacket{
+				ParticipantIdentity: "p0",
+				Value: &livekit.DataPacket_User{
+					User: &livekit.UserPacket{Topic: &topic, Payload: []byte(payload)},
+				},
+			})
+		}
+		received := func() []string {
+			var payloads []string
+			for i := 0; i < p1.SendDataPacketCallCount(); i++ {
+				_, data := p1.SendDataPacketArgsForCall(i)
+				var dp livekit.DataPacket
+				require.NoError(t, proto.Unmarshal(data, &dp))
+				payloads = append(payloads, string(dp.GetUser().Payload))
+			}
+			return payloads
+		}
+
+		// topics that aren't moderated are not held up by the moderator
+		for _, payload := range []string{"hello", "spam", "secret", "rude", "error"} {
+			send("chat", payload)
+		}
+		send("cursor", "spam")
+		require.Eventually(t, func() bool { return p1.SendDataPacketCallCount() == 5 }, time.Second, 10*time.Millisecond)
+		// the unmoderated message can be sent before or between moderated ones
+		payloads := received()
+		idx := slices.Index(payloads, "spam")
+		require.NotEqual(t, -1, idx)
+		require.Equal(t, []string{"hello", "***", "rude", "error"}, slices.Delete(payloads, idx, idx+1))
+
+		mu.Lock()
+		require.Len(t, requests, 5)
+		require.Equal(t, "p0", requests[0].ParticipantIdentity)
+		require.Equal(t, "chat", requests[0].Topic)
+		require.Len(t, flagged, 1)
+		require.Equal(t, "p0", flagged[0].ParticipantIdentity)
+		require.Equal(t, "chat", flagged[0].Topic)
+		require.Equal(t, "rude", flagged[0].Reason)
+		mu.Unlock()
+
+		// fail closed drops messages the moderator could not check
+		moderated := make(chan struct{}, 1)
+		rm.SetDataModerator(dataModeratorFunc(func(ctx context.Context, req *DataModerationRequest) (*DataModerationResult, error) {
+			moderated <- struct{}{}
+			return nil, errors.New("unavailable")
+		}), nil, config.DataModerationConfig{FailClosed: true})
+		send("chat", "hello")
+		<-moderated
+		time.Sleep(50 * time.Millisecond)
+		require.Equal(t, 5, p1.SendDataPacketCallCount())
+	})
+
+	t.Run("media interceptors can modify and drop messages", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+		p0 := rm.GetParticipant("p0").(*typesfakes.FakeLocalParticipant)
+		p1 := rm.GetParticipant("p1").(*typesfakes.FakeLocalParticipant)
+
+		var senders []livekit.ParticipantIdentity
+		rm.SetMediaInterceptor(MediaInterceptors{
+			dataInterceptorFunc(func(room livekit.RoomName, sender livekit.ParticipantIdentity, dp *livekit.DataPacket) bool {
+				require.Equal(t, rm.Name(), room)
+				senders = append(senders, sender)
+				return string(dp.GetUser().Payload) != "drop"
+			}),
+			dataInterceptorFunc(func(room livekit.RoomName, sender livekit.ParticipantIdentity, dp *livekit.DataPacket) bool {
+				dp.GetUser().Payload = append(dp.GetUser().Payload, " (watermarked)"...)
+				return true
+			}),
+		})
+
+		for _, payload := range []string{"hello", "drop"} {
+			topic := "chat"
+			p0.OnDataPacketArgsForCall(0)(p0, livekit.DataPacket_RELIABLE, &livekit.DataPacket{
+				Value: &livekit.DataPacket_User{
+					User: &livekit.UserPacket{Topic: &topic, Payload: []byte(payload)},
+				},
+			})
+		}
+		require.Equal(t, []livekit.ParticipantIdentity{"p0", "p0"}, senders)
+		require.Equal(t, 1, p1.SendDataPacketCallCount())
+		_, data := p1.SendDataPacketArgsForCall(0)
+		var dp livekit.DataPacket
+		require.NoError(t, proto.Unmarshal(data, &dp))
+		require.Equal(t, "hello (watermarked)", string(dp.GetUser().Payload))
+	})
+
+	t.Run("ephemeral messages are rate limited and skip poor connections", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 3})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+		p0 := rm.GetParticipant("p0").(*typesfakes.FakeLocalParticipant)
+		p1 := rm.GetParticipant("p1").(*typesfakes.FakeLocalParticipant)
+		p2 := rm.GetParticipant("p2").(*typesfakes.FakeLocalParticipant)
+		rm.ephemeral.updateConnectionQuality(map[livekit.ParticipantID]*livekit.ConnectionQualityInfo{
+			p1.ID(): {Quality: livekit.ConnectionQuality_GOOD},
+			p2.ID(): {Quality: livekit.ConnectionQuality_POOR},
+		})
+
+		topic := EphemeralTopicPrefix + "reaction"
+		for i := 0; i < defaultEphemeralMessagesPerSecond+5; i++ {
+			p0.OnDataPacketArgsForCall(0)(p0, livekit.DataPacket_RELIABLE, &livekit.DataPacket{
+				Value: &livekit.DataPacket_User{
+					User: &livekit.UserPacket{Topic: &topic, Payload: []byte("+1")},
+				},
+			})
+		}
+		require.Equal(t, defaultEphemeralMessagesPerSecond, p1.SendDataPacketCallCount())
+		require.Zero(t, p2.SendDataPacketCallCount())
+
+		kind, data := p1.SendDataPacketArgsForCall(0)
+		require.Equal(t, livekit.DataPacket_LOSSY, kind)
+		var dp livekit.DataPacket
+		require.NoError(t, proto.Unmarshal(data, &dp))
+		require.Equal(t, "p0", dp.GetUser().ParticipantIdentity)
+		require.NotNil(t, dp.GetUser().EndTime)
+
+		// server messages aren't limited by participants' rates
+		require.NoError(t, rm.SendEphemeralData(topic, []byte("+1"), []string{"p0"}, time.Second))
+		require.Equal(t, 1, p0.SendDataPacketCallCount())
+		require.ErrorIs(t, rm.SendEphemeralData("chat", nil, nil, 0), ErrEphemeralTopicInvalid)
+	})
+
+	t.Run("ephemeral messages are moderated", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+		p0 := rm.GetParticipant("p0").(*typesfakes.FakeLocalParticipant)
+		p1 := rm.GetParticipant("p1").(*typesfakes.FakeLocalParticipant)
+
+		moderated := make(chan string, 2)
+		rm.SetDataModerator(dataModeratorFunc(func(ctx context.Context, req *DataModerationRequest) (*DataModerationResult, error) {
+			moderated <- string(req.Payload)
+			if string(req.Payload) == "rude" {
+				return &DataModerationResult{Action: DataModerationDrop}, nil
+			}
+			return &DataModerationResult{Action: DataModerationAllow}, nil
+		}), nil, config.DataModerationConfig{IncludeContent: true})
+
+		topic := EphemeralTopicPrefix + "reaction"
+		for _, payload := range []string{"rude", "+1"} {
+			p0.OnDataPacketArgsForCall(0)(p0, livekit.DataPacket_RELIABLE, &livekit.DataPacket{
+				Value: &livekit.DataPacket_User{
+					User: &livekit.UserPacket{Topic: &topic, Payload: []byte(payload)},
+				},
+			})
+		}
+		require.Equal(t, "rude", <-moderated)
+		require.Equal(t, "+1", <-moderated)
+		require.Eventually(t, func() bool { return p1.SendDataPacketCallCount() == 1 }, time.Second, 10*time.Millisecond)
+
+		kind, data := p1.SendDataPacketArgsForCall(0)
+		require.Equal(t, livekit.DataPacket_LOSSY, kind)
+		var dp livekit.DataPacket
+		require.NoError(t, proto.Unmarshal(data, &dp))
+		require.Equal(t, "+1", string(dp.GetUser().Payload))
+	})
+
+	t.Run("compressed topics are recompressed for each recipient", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 4})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+		compressor, err := NewDataCompressor([]config.DataCompressionTopicConfig{{Topic: "doc"}})
+		require.NoError(t, err)
+		rm.SetDataCompressor(compressor)
+
+		p0 := rm.GetParticipant("p0").(*typesfakes.FakeLocalParticipant)
+		accepts := map[string]map[string]string{
+			"p1": {DataCompressionAttribute: "zstd"},
+			"p2": {DataCompressionAttribute: "zstd", DataCompressionAttribute + ".doc": "gzip"},
+			"p3": nil,
+		}
+		for identity, attributes := range accepts {
+			p := rm.GetParticipant(livekit.ParticipantIdentity(identity)).(*typesfakes.FakeLocalParticipant)
+			p.ClaimGrantsReturns(&auth.ClaimGrants{Attributes: attributes})
+		}
+
+		raw := []byte(strings.Repeat("shared document ", 20))
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err = zw.Write(raw)
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+
+		topic := "doc"
+		p0.OnDataPacketArgsForCall(0)(p0, livekit.DataPacket_RELIABLE, &livekit.DataPacket{
+			Value: &livekit.DataPacket_User{
+				User: &livekit.UserPacket{Topic: &topic, Payload: buf.Bytes()},
+			},
+		})
+
+		received := func(identity livekit.ParticipantIdentity) []byte {
+			p := rm.GetParticipant(identity).(*typesfakes.FakeLocalParticipant)
+			require.Equal(t, 1, p.SendDataPacketCallCount())
+			_, data := p.SendDataPacketArgsForCall(0)
+			var dp livekit.DataPacket
+			require.NoError(t, proto.Unmarshal(data, &dp))
+			return dp.GetUser().Payload
+		}
+
+		zstdPayload := received("p1")
+		require.Equal(t, DataEncodingZstd, detectDataEncoding(zstdPayload))
+		decoded, err := compressor.decompress(DataEncodingZstd, zstdPayload)
+		require.NoError(t, err)
+		require.Equal(t, raw, decoded)
+		require.Equal(t, buf.Bytes(), received("p2"))
+		require.Equal(t, raw, received("p3"))
+	})
+
+	t.Run("payloads too large to decompress are not sent compressed", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 3})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+		compressor, err := NewDataCompressor([]config.DataCompressionTopicConfig{{Topic: "doc"}})
+		require.NoError(t, err)
+		rm.SetDataCompressor(compressor)
+
+		p0 := rm.GetParticipant("p0").(*typesfakes.FakeLocalParticipant)
+		p1 := rm.GetParticipant("p1").(*typesfakes.FakeLocalParticipant)
+		p2 := rm.GetParticipant("p2").(*typesfakes.FakeLocalParticipant)
+		p1.ClaimGrantsReturns(&auth.ClaimGrants{Attributes: map[string]string{DataCompressionAttribute: "gzip"}})
+		p2.ClaimGrantsReturns(&auth.ClaimGrants{})
+
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err = zw.Write(make([]byte, maxDataMessageSize+1))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+		require.Less(t, buf.Len(), maxDataMessageSize)
+
+		topic := "doc"
+		p0.OnDataPacketArgsForCall(0)(p0, livekit.DataPacket_RELIABLE, &livekit.DataPacket{
+			Value: &livekit.DataPacket_User{
+				User: &livekit.UserPacket{Topic: &topic, Payload: buf.Bytes()},
+			},
+		})
+
+		require.Equal(t, 1, p1.SendDataPacketCallCount())
+		require.Zero(t, p2.SendDataPacketCallCount())
+	})
+
+	t.Run("direct signals are only relayed to their destination", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 3})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+		p0 := rm.GetParticipant("p0").(*typesfakes.FakeLocalParticipant)
+		p1 := rm.GetParticipant("p1").(*typesfakes.FakeLocalParticipant)
+		p2 := rm.GetParticipant("p2").(*typesfakes.FakeLocalParticipant)
+
+		var forwarded int
+		rm.OnDataPacket(func(kind livekit.DataPacket_Kind, dp *livekit.DataPacket) {
+			forwarded++
+		})
+
+		send := func(id string, dest ...string) {
+			topic := DirectSignalTopic
+			p0.OnDataPacketArgsForCall(0)(p0, livekit.DataPacket_LOSSY, &livekit.DataPacket{
+				DestinationIdentities: dest,
+				Value: &livekit.DataPacket_User{
+					User: &livekit.UserPacket{Id: &id, Topic: &topic, Payload: []byte("offer")},
+				},
+			})
+		}
+
+		send("s1", "p1")
+		require.Equal(t, 1, p1.SendDataPacketCallCount())
+		require.Zero(t, p2.SendDataPacketCallCount())
+		require.Zero(t, forwarded)
+		kind, data := p1.SendDataPacketArgsForCall(0)
+		require.Equal(t, livekit.DataPacket_RELIABLE, kind)
+		var dp livekit.DataPacket
+		require.NoError(t, proto.Unmarshal(data, &dp))
+		require.Equal(t, "p0", dp.GetUser().ParticipantIdentity)
+		require.Equal(t, "offer", string(dp.GetUser().Payload))
+
+		signalError := func(i int) DirectSignalError {
+			_, data := p0.SendDataPacketArgsForCall(i)
+			var dp livekit.DataPacket
+			require.NoError(t, proto.Unmarshal(data, &dp))
+			require.Equal(t, DirectSignalErrorTopic, dp.GetUser().GetTopic())
+			var e DirectSignalError
+			require.NoError(t, json.Unmarshal(dp.GetUser().Payload, &e))
+			return e
+		}
+
+		send("s2", "p1", "p2")
+		require.Equal(t, DirectSignalError{ID: "s2", Error: ErrDirectSignalDestination.Error()}, signalError(0))
+		send("s3", "missing")
+		require.Equal(t, DirectSignalError{ID: "s3", Destination: "missing", Error: ErrDirectSignalUnavailable.Error()}, signalError(1))
+		require.Equal(t, 1, p1.SendDataPacketCallCount())
+		require.Zero(t, p2.SendDataPacketCallCount())
+	})
+}
+
+// dataInterceptorFunc intercepts data messages only
+type dataInterceptorFunc func(room livekit.RoomName, sender livekit.ParticipantIdentity, dp *livekit.DataPacket) bool
+
+func (f dataInterceptorFunc) InterceptRTP(InterceptedTrack, *rtp.Packet) bool { return true }
+
+func (f dataInterceptorFunc) InterceptData(room livekit.RoomName, sender livekit.ParticipantIdentity, dp *livekit.DataPacket) bool {
+	return f(room, sender, dp)
+}
+
+func (f dataInterceptorFunc) OnRoomEvent(*livekit.WebhookEvent) {}
+
+type dataModeratorFunc func(ctx context.Context, req *DataModerationRequest) (*DataModerationResult, error)
+
+type dataFlagNotifierFunc func(ctx context.Context, flagged *DataMessageFlagged)
+
+func (f dataFlagNotifierFunc) NotifyDataFlagged(ctx context.Context, flagged *DataMessageFlagged) {
+	f(ctx, flagged)
+}
+
+func (f dataModeratorFunc) ModerateData(ctx context.Context, req *DataModerationRequest) (*DataModerationResult, error) {
+	return f(ctx, req)
+}
+
+func TestRoomDataUsage(t *testing.T) {
+	rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+	defer rm.Close(types.ParticipantCloseReasonNone)
+	p0 := rm.GetParticipant("p0").(*typesfakes.FakeLocalParticipant)
+	p1 := rm.GetParticipant("p1").(*typesfakes.FakeLocalParticipant)
+	p0.GetDataTrafficTotalsReturns(&telemetry.TrafficTotals{SendBytes: 100, SendMessages: 2, RecvBytes: 10, RecvMessages: 1})
+	p1.GetDataTrafficTotalsReturns(&telemetry.TrafficTotals{SendBytes: 10, SendMessages: 1, RecvBytes: 100, RecvMessages: 2})
+
+	usage := rm.GetDataUsage()
+	require.Equal(t, DataUsage{BytesSent: 10, MessagesSent: 1, BytesReceived: 100, MessagesReceived: 2}, usage.Participants["p0"])
+	require.Equal(t, DataUsage{BytesSent: 110, MessagesSent: 3, BytesReceived: 110, MessagesReceived: 3}, usage.Total)
+
+	// usage of participants that left stays in the room total
+	rm.RemoveParticipant("p1", p1.ID(), types.ParticipantCloseReasonClientRequestLeave)
+	usage = rm.GetDataUsage()
+	require.Len(t, usage.Participants, 1)
+	require.Equal(t, DataUsage{BytesSent: 110, MessagesSent: 3, BytesReceived: 110, MessagesReceived: 3}, usage.Total)
+}
+
+// silentOpusCodec stands in for an Opus codec, decoding every packet as a silent frame
+type silentOpusCodec struct{}
+
+func (silentOpusCodec) MimeType() string { return mixedAudioCodec.MimeType }
+
+func (silentOpusCodec) NewDecoder() (mixer.Decoder, error) { return silentOpusCodec{}, nil }
+
+func (silentOpusCodec) NewEncoder() (mixer.Encoder, error) { return silentOpusCodec{}, nil }
+
+func (silentOpusCodec) Decode(_payload []byte, pcm []int16) (int, error) {
+	clear(pcm[:mixer.FrameSamples])
+	return mixer.FrameSamples, nil
+}
+
+func (silentOpusCodec) Encode(_pcm []int16, out []byte) (int, error) {
+	out[0] = 0xf8
+	return 1, nil
+}
+
+func TestMixedAudio(t *testing.T) {
+	mixer.RegisterCodec(silentOpusCodec{})
+	rm := newRoomWithParticipants(t, testRoomOpts{num: 1, mixedAudio: true})
+	track := rm.GetMixedAudioTrack()
+	require.NotNil(t, track)
+	require.Equal(t, MixedAudioIdentity, track.PublisherIdentity())
+
+	pNew := NewMockParticipant("new", types.CurrentProtocol, false, false)
+	require.NoError(t, rm.Join(pNew, nil, &ParticipantOptions{AutoSubscribe: true}, iceServersForRoom))
+	res := pNew.SendJoinResponseArgsForCall(0)
+	idx := slices.IndexFunc(res.OtherParticipants, func(pi *livekit.ParticipantInfo) bool {
+		return pi.Identity == string(MixedAudioIdentity)
+	})
+	require.NotEqual(t, -1, idx)
+	require.Equal(t, string(track.ID()), res.OtherParticipants[idx].Tracks[0].Sid)
+
+	// each subscriber receives its own mix
+	resolved := rm.ResolveMediaTrackForSubscriber(pNew.Identity(), track.ID())
+	require.True(t, resolved.HasPermission)
+	require.NotEqual(t, track, resolved.Track)
+	require.Equal(t, track.ID(), resolved.Track.ID())
+	require.Equal(t, resolved.Track, rm.ResolveMediaTrackForSubscriber(pNew.Identity(), track.ID()).Track)
+	require.Nil(t, rm.ResolveMediaTrackForSubscriber("unknown", track.ID()).Track)
+
+	// mixes only have the tracks the subscriber may subscribe to, without its own
+	pDenied := NewMockParticipant("denied", types.CurrentProtocol, false, false)
+	require.NoError(t, rm.Join(pDenied, nil, &ParticipantOptions{AutoSubscribe: true}, iceServersForRoom))
+	require.NotNil(t, rm.ResolveMediaTrackForSubscriber(pDenied.Identity(), track.ID()).Track)
+	p0 := rm.GetParticipant("p0").(*typesfakes.FakeLocalParticipant)
+	p0.HasPermissionCalls(func(_ livekit.TrackID, identity livekit.ParticipantIdentity) bool {
+		return identity != pDenied.Identity()
+	})
+	pNew.HasPermissionReturns(true)
+	track.lock.Lock()
+	track.inputs["TR_p0"] = p0
+	track.inputs["TR_new"] = pNew
+	require.Equal(t, []livekit.TrackID{"TR_p0"}, track.mixedInputsLocked(pNew.Identity()))
+	require.Equal(t, []livekit.TrackID{"TR_new"}, track.mixedInputsLocked(pDenied.Identity()))
+	track.lock.Unlock()
+
+	// mixes of participants leaving are closed
+	output := resolved.Track
+	rm.RemoveParticipant(pNew.Identity(), pNew.ID(), types.ParticipantCloseReasonClientRequestLeave)
+	require.False(t, output.IsOpen())
+
+	rm.Close(types.ParticipantCloseReasonNone)
+	require.False(t, track.IsOpen())
+	require.Nil(t, rm.trackManager.GetTrackInfo(track.ID()))
+}
+
+// blankVP8Codec stands in for a VP8 codec, decoding and encoding blank frames
+type blankVP8Codec struct{}
+
+func (blankVP8Codec) MimeType() string { return webrtc.MimeTypeVP8 }
+
+func (blankVP8Codec) NewDepacketizer() rtp.Depacketizer { return &codecs.VP8Packet{} }
+
+func (blankVP8Codec) NewPayloader() rtp.Payloader { return &codecs.VP8Payloader{} }
+
+func (blankVP8Codec) NewDecoder() (compositor.Decoder, error) { return blankVP8Codec{}, nil }
+
+func (blankVP8Codec) NewEncoder(_width int, _height int) (compositor.Encoder, error) {
+	return blankVP8Codec{}, nil
+}
+
+func (blankVP8Codec) Decode(_frame []byte) (*image.YCbCr, error) {
+	return image.NewYCbCr(image.Rect(0, 0, 16, 16), image.YCbCrSubsampleRatio420), nil
+}
+
+func (blankVP8Codec) Encode(_img *image.YCbCr, _keyframe bool) ([]byte, error) {
+	return []byte{0}, nil
+}
+
+func TestComposedVideo(t *testing.T) {
+	compositor.RegisterCodec(blankVP8Codec{})
+	rm := newRoomWithParticipants(t, testRoomOpts{num: 1, composedVideo: true})
+	track := rm.GetComposedVideoTrack()
+	require.NotNil(t, track)
+	require.Equal(t, ComposedVideoIdentity, track.PublisherIdentity())
+	require.Equal(t, uint32(640), track.ToProto().Width)
+
+	pNew := NewMockParticipant("new", types.CurrentProtocol, false, false)
+	require.NoError(t, rm.Join(pNew, nil, &ParticipantOptions{AutoSubscribe: true}, iceServersForRoom))
+	res := pNew.SendJoinResponseArgsForCall(0)
+	require.True(t, slices.ContainsFunc(res.OtherParticipants, func(pi *livekit.ParticipantInfo) bool {
+		return pi.Identity == string(ComposedVideoIdentity) && pi.Tracks[0].Sid == string(track.ID())
+	}))
+	require.True(t, rm.ResolveMediaTrackForSubscriber(pNew.Identity(), track.ID()).HasPermission)
+
+	// subscribers must be allowed to subscribe to every rendered track
+	p0 := rm.GetParticipant("p0").(*typesfakes.FakeLocalParticipant)
+	track.lock.Lock()
+	track.inputs["TR_p0"] = p0
+	track.lock.Unlock()
+	require.False(t, rm.ResolveMediaTrackForSubscriber(pNew.Identity(), track.ID()).HasPermission)
+	require.True(t, rm.ResolveMediaTrackForSubscriber(p0.Identity(), track.ID()).HasPermission)
+	p0.HasPermissionReturns(true)
+	require.True(t, rm.ResolveMediaTrackForSubscriber(pNew.Identity(), track.ID()).HasPermission)
+
+	rm.Close(types.ParticipantCloseReasonNone)
+	require.False(t, track.IsOpen())
+}
+
+func TestRoomClosePolicy(t *testing.T) {
+	t.Run("warns before max duration and closes", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 1})
+		rm.closePolicy = newRoomClosePolicy(config.RoomClosePolicyConfig{MaxDuration: 10 * time.Minute})
+		createdAt := time.Unix(rm.ToProto().CreationTime, 0)
+		p := rm.GetParticipants()[0].(*typesfakes.FakeLocalParticipant)
+
+		rm.EnforceClosePolicy(createdAt.Add(time.Minute))
+		require.Zero(t, p.SendDataPacketCallCount())
+
+		rm.EnforceClosePolicy(createdAt.Add(6 * time.Minute))
+		require.Equal(t, 1, p.SendDataPacketCallCount())
+		_, data := p.SendDataPacketArgsForCall(0)
+		var dp livekit.DataPacket
+		require.NoError(t, proto.Unmarshal(data, &dp))
+		require.Equal(t, RoomClosingTopic, dp.GetUser().GetTopic())
+		var warning RoomClosingWarning
+		require.NoError(t, json.Unmarshal(dp.GetUser().Payload, &warning))
+		require.Equal(t, RoomCloseReasonMaxDuration, warning.Reason)
+		require.Equal(t, int64(4*60), warning.SecondsLeft)
+
+		// already warned for this threshold
+		rm.EnforceClosePolicy(createdAt.Add(7 * time.Minute))
+		require.Equal(t, 1, p.SendDataPacketCallCount())
+
+		rm.EnforceClosePolicy(createdAt.Add(10 * time.Minute))
+		require.True(t, rm.IsClosed())
+	})
+
+	t.Run("admin leave", func(t *testing.T) {
+		c := newRoomClosePolicy(config.RoomClosePolicyConfig{
+			CloseOnAdminLeave: true,
+			AdminLeaveTimeout: 30 * time.Second,
+			Warnings:          []time.Duration{10 * time.Second},
+		})
+		start := time.Now()
+
+		// no admin has joined yet
+		warning, reason := c.update(start, start, false, false)
+		require.Nil(t, warning)
+		require.Empty(t, reason)
+
+		_, _ = c.update(start, start, true, false)
+		warning, reason = c.update(start.Add(time.Second), start, false, false)
+		require.Nil(t, warning)
+		require.Empty(t, reason)
+
+		warning, _ = c.update(start.Add(25*time.Second), start, false, false)
+		require.Equal(t, RoomCloseReasonAdminLeft, warning.Reason)
+		require.False(t, warning.Cancelled)
+
+		// admin returned, closing is cancelled
+		warning, reason = c.update(start.Add(26*time.Second), start, true, false)
+		require.True(t, warning.Cancelled)
+		require.Empty(t, reason)
+
+		_, _ = c.update(start.Add(27*time.Second), start, false, false)
+		// countdown is held while participants on other nodes are connected
+		warning, reason = c.update(start.Add(time.Minute), start, false, true)
+		require.Nil(t, warning)
+		require.Empty(t, reason)
+
+		_, _ = c.update(start.Add(2*time.Minute), start, false, false)
+		_, reason = c.update(start.Add(3*time.Minute), start, false, false)
+		require.Equal(t, RoomCloseReasonAdminLeft, reason)
+	})
+}
+
+func TestIdleParticipants(t *testing.T) {
+	rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+	defer rm.Close(types.ParticipantCloseReasonNone)
+	rm.idleParticipants = newParticipantIdleTracker(config.ParticipantIdleConfig{Timeout: 10 * time.Minute})
+	p0 := rm.GetParticipant("p0").(*typesfakes.FakeLocalParticipant)
+	p1 := rm.GetParticipant("p1").(*typesfakes.FakeLocalParticipant)
+	// p1 keeps publishing
+	p0.GetPublishedTracksReturns(nil)
+
+	start := time.Now()
+	rm.DisconnectIdleParticipants(start)
+
+	decode := func(i int) ParticipantIdleWarning {
+		_, data := p0.SendDataPacketArgsForCall(i)
+		var dp livekit.DataPacket
+		require.NoError(t, proto.Unmarshal(data, &dp))
+		require.Equal(t, ParticipantIdleTopic, dp.GetUser().GetTopic())
+		var warning ParticipantIdleWarning
+		require.NoError(t, json.Unmarshal(dp.GetUser().Payload, &warning))
+		return warning
+	}
+
+	rm.DisconnectIdleParticipants(start.Add(9 * time.Minute))
+	require.Equal(t, 1, p0.SendDataPacketCallCount())
+	require.Equal(t, int64(60), decode(0).SecondsLeft)
+
+	// sending data counts as activity
+	rm.onDataPacket(p0, livekit.DataPacket_RELIABLE, &livekit.DataPacket{
+		Value: &livekit.DataPacket_User{User: &livekit.UserPacket{Payload: []byte("hi")}},
+	})
+	rm.DisconnectIdleParticipants(start.Add(9*time.Minute + time.Second))
+	require.Equal(t, 2, p0.SendDataPacketCallCount())
+	require.True(t, decode(1).Cancelled)
+
+	rm.DisconnectIdleParticipants(start.Add(20 * time.Minute))
+	require.Nil(t, rm.GetParticipant("p0"))
+	require.NotNil(t, rm.GetParticipant("p1"))
+	// only the data sent by p0, no warnings
+	require.Equal(t, 1, p1.SendDataPacketCallCount())
+}
+
+func TestRoomState(t *testing.T) {
+	t.Run("compare and swap", func(t *testing.T) {
+		s := NewRoomState(nil)
+		zero := uint64(0)
+		e, err := s.Set("slide", "1", &zero, "p0")
+		require.NoError(t, err)
+		_, err = s.Set("slide", "2", &zero, "p1")
+		require.ErrorIs(t, err, ErrRoomStateVersionMismatch)
+
+		version := e.Version
+		e, err = s.Set("slide", "2", &version, "p1")
+		require.NoError(t, err)
+		require.Greater(t, e.Version, version)
+		_, err = s.Delete("slide", &version, "p0")
+		require.ErrorIs(t, err, ErrRoomStateVersionMismatch)
+
+		_, err = s.Delete("slide", nil, "p0")
+		require.NoError(t, err)
+		_, err = s.Get("slide")
+		require.ErrorIs(t, err, ErrRoomStateKeyNotFound)
+	})
+
+	t.Run("writers by key prefix", func(t *testing.T) {
+		s := NewRoomState([]config.RoomStateKeyConfig{
+			{KeyPrefix: "config/", Writers: config.RoomStateWritersServer},
+			{KeyPrefix: "cursor/", Writers: config.RoomStateWritersCreator},
+			{KeyPrefix: "cursor/shared/", Writers: config.RoomStateWritersParticipants},
+		})
+
+		_, err := s.Set("config/layout", "grid", nil, "p0")
+		require.ErrorIs(t, err, ErrRoomStatePermission)
+		_, err = s.Set("config/layout", "grid", nil, "")
+		require.NoError(t, err)
+		_, err = s.Delete("config/layout", nil, "p0")
+		require.ErrorIs(t, err, ErrRoomStatePermission)
+
+		e, err := s.Set("cursor/p0", "1,1", nil, "p0")
+		require.NoError(t, err)
+		require.Equal(t, "p0", e.CreatedBy)
+		_, err = s.Set("cursor/p0", "2,2", nil, "p1")
+		require.ErrorIs(t, err, ErrRoomStatePermission)
+		_, err = s.Delete("cursor/p0", nil, "p1")
+		require.ErrorIs(t, err, ErrRoomStatePermission)
+		e, err = s.Set("cursor/p0", "2,2", nil, "p0")
+		require.NoError(t, err)
+		require.Equal(t, "p0", e.CreatedBy)
+
+		_, err = s.Set("cursor/shared/pointer", "1,1", nil, "p0")
+		require.NoError(t, err)
+		_, err = s.Set("cursor/shared/pointer", "2,2", nil, "p1")
+		require.NoError(t, err)
+	})
+
+	t.Run("changes are persisted and restored", func(t *testing.T) {
+		stored := make(map[string]*RoomStateEntry)
+		var version uint64
+		var failPersist bool
+		persist := func(e *RoomStateEntry) error {
+			if failPersist {
+				return errors.New("unavailable")
+			}
+			if e.Deleted {
+				delete(stored, e.Key)
+			} else {
+				stored[e.Key] = e
+			}
+			version = e.Version
+			return nil
+		}
+
+		s := NewRoomState(nil)
+		s.Restore(nil, 0, persist)
+		_, err := s.Set("slide", "1", nil, "p0")
+		require.NoError(t, err)
+		_, err = s.Set("poll", "open", nil, "p0")
+		require.NoError(t, err)
+		_, err = s.Delete("poll", nil, "p0")
+		require.NoError(t, err)
+
+		// changes that can't be saved are not applied
+		failPersist = true
+		_, err = s.Set("slide", "2", nil, "p0")
+		require.Error(t, err)
+		failPersist = false
+
+		var loaded []*RoomStateEntry
+		for _, e := range stored {
+			loaded = append(loaded, e)
+		}
+		restored := NewRoomState(nil)
+		restored.Restore(loaded, version, persist)
+		entries, err := restored.Get("")
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		require.Equal(t, "1", entries[0].Value)
+
+		// versions continue after the deleted key
+		e, err := restored.Set("slide", "2", nil, "p0")
+		require.NoError(t, err)
+		require.Equal(t, uint64(4), e.Version)
+	})
+
+	t.Run("participants change state with data messages", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+		p0 := rm.GetParticipant("p0").(*typesfakes.FakeLocalParticipant)
+		p1 := rm.GetParticipant("p1").(*typesfakes.FakeLocalParticipant)
+
+		topic := RoomStateTopic
+		payload, _ := json.Marshal(RoomStateRequest{RequestID: "r1", Op: RoomStateOpSet, Key: "slide", Value: "3"})
+		p0.OnDataPacketArgsForCall(0)(p0, livekit.DataPacket_RELIABLE, &livekit.DataPacket{
+			Value: &livekit.DataPacket_User{User: &livekit.UserPacket{Topic: &topic, Payload: payload}},
+		})
+
+		decode := func(p *typesfakes.FakeLocalParticipant, i int) (string, []byte) {
+			_, data := p.SendDataPacketArgsForCall(i)
+			var dp livekit.DataPacket
+			require.NoError(t, proto.Unmarshal(data, &dp))
+			return dp.GetUser().GetTopic(), dp.GetUser().Payload
+		}
+
+		// everyone is notified of the change, the sender also gets a response
+		require.Equal(t, 1, p1.SendDataPacketCallCount())
+		changedTopic, changed := decode(p1, 0)
+		require.Equal(t, RoomStateChangedTopic, changedTopic)
+		var e RoomStateEntry
+		require.NoError(t, json.Unmarshal(changed, &e))
+		require.Equal(t, "slide", e.Key)
+		require.Equal(t, "3", e.Value)
+		require.Equal(t, "p0", e.UpdatedBy)
+
+		require.Equal(t, 2, p0.SendDataPacketCallCount())
+		responseTopic, response := decode(p0, 1)
+		require.Equal(t, RoomStateResponseTopic, responseTopic)
+		var res RoomStateResponse
+		require.NoError(t, json.Unmarshal(response, &res))
+		require.Equal(t, "r1", res.RequestID)
+		require.Empty(t, res.Error)
+		require.Equal(t, []*RoomStateEntry{&e}, res.Entries)
+
+		entries, err := rm.GetState("")
+		require.NoError(t, err)
+		require.Equal(t, []*RoomStateEntry{&e}, entries)
+	})
 }
 
 func TestHiddenParticipants(t *testing.T) {
@@ -783,6 +1713,250 @@ func TestRoomUpdate(t *testing.T) {
 			require.GreaterOrEqual(t, fp.SendRoomUpdateCallCount(), 1)
 		}
 	})
+
+	t.Run("newer metadata from other nodes is applied", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 1})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+
+		rm.SetMetadata("local")
+		_, updatedAt := rm.GetRelayMetadata()
+
+		rm.ReconcileRelayMetadata("stale", updatedAt-1)
+		metadata, _ := rm.GetRelayMetadata()
+		require.Equal(t, "local", metadata)
+
+		rm.ReconcileRelayMetadata("remote", updatedAt+1)
+		metadata, _ = rm.GetRelayMetadata()
+		require.Equal(t, "remote", metadata)
+		require.Eventually(t, func() bool {
+			return rm.ToProto().Metadata == "remote"
+		}, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestRoomRecording(t *testing.T) {
+	rm := newRoomWithParticipants(t, testRoomOpts{num: 1})
+	defer rm.Close(types.ParticipantCloseReasonNone)
+	require.False(t, rm.ToProto().ActiveRecording)
+
+	recorder := NewMockParticipant("recorder", types.CurrentProtocol, true, false)
+	recorder.IsRecorderReturns(true)
+	require.NoError(t, rm.Join(recorder, nil, nil, iceServersForRoom))
+	require.Eventually(t, func() bool { return rm.ToProto().ActiveRecording }, time.Second, 10*time.Millisecond)
+
+	// indicated through the API, recording continues after the recorder leaves
+	<-rm.SetRecording(true)
+	rm.RemoveParticipant(recorder.Identity(), recorder.ID(), types.ParticipantCloseReasonClientRequestLeave)
+	time.Sleep(2 * defaultDelay)
+	require.True(t, rm.ToProto().ActiveRecording)
+
+	<-rm.SetRecording(false)
+	require.False(t, rm.ToProto().ActiveRecording)
+
+	// participants are notified of the change
+	p1 := rm.GetParticipants()[0].(*typesfakes.FakeLocalParticipant)
+	require.Eventually(t, func() bool {
+		return !p1.SendRoomUpdateArgsForCall(p1.SendRoomUpdateCallCount() - 1).ActiveRecording
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestAgentRedispatch(t *testing.T) {
+	setup := func(t *testing.T) (*Room, *testAgentClient) {
+		client := &testAgentClient{}
+		rm := newRoomWithParticipants(t, testRoomOpts{
+			num:         1,
+			agentClient: client,
+			agentConfig: config.AgentsConfig{RedispatchGracePeriod: 50 * time.Millisecond},
+		})
+		t.Cleanup(func() { rm.Close(types.ParticipantCloseReasonNone) })
+
+		_, err := rm.AddAgentDispatch("transcriber", "")
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			rm.lock.RLock()
+			defer rm.lock.RUnlock()
+			return rm.agentParticpants["agent"] != nil
+		}, time.Second, 10*time.Millisecond)
+
+		p := NewMockParticipant("agent", types.CurrentProtocol, false, false)
+		require.NoError(t, rm.Join(p, nil, &ParticipantOptions{}, iceServersForRoom))
+		return rm, client
+	}
+
+	t.Run("lost agent is redispatched", func(t *testing.T) {
+		rm, client := setup(t)
+		rm.RemoveParticipant("agent", "", types.ParticipantCloseReasonStale)
+		require.Eventually(t, func() bool {
+			return client.roomJobCount() == 2
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("agent leaving is not redispatched", func(t *testing.T) {
+		rm, client := setup(t)
+		rm.RemoveParticipant("agent", "", types.ParticipantCloseReasonClientRequestLeave)
+		time.Sleep(200 * time.Millisecond)
+		require.Equal(t, 1, client.roomJobCount())
+	})
+
+	t.Run("agent coming back within the grace period is not redispatched", func(t *testing.T) {
+		rm, client := setup(t)
+		rm.RemoveParticipant("agent", "", types.ParticipantCloseReasonStale)
+		p := NewMockParticipant("agent", types.CurrentProtocol, false, false)
+		require.NoError(t, rm.Join(p, nil, &ParticipantOptions{}, iceServersForRoom))
+		time.Sleep(200 * time.Millisecond)
+		require.Equal(t, 1, client.roomJobCount())
+	})
+}
+
+func TestReportAgentJobResult(t *testing.T) {
+	rm := newRoomWithParticipants(t, testRoomOpts{num: 1, agentClient: &testAgentClient{}})
+	defer rm.Close(types.ParticipantCloseReasonNone)
+
+	ad, err := rm.AddAgentDispatch("summarizer", "")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		rm.lock.RLock()
+		defer rm.lock.RUnlock()
+		return rm.agentParticpants["agent"] != nil
+	}, time.Second, 10*time.Millisecond)
+
+	err = rm.ReportAgentJobResult(context.Background(), "p0", &agent.JobResult{JobID: "job1"})
+	require.ErrorIs(t, err, agent.ErrJobNotFound)
+	err = rm.ReportAgentJobResult(context.Background(), "agent", &agent.JobResult{JobID: "unknown"})
+	require.ErrorIs(t, err, agent.ErrJobNotFound)
+	err = rm.ReportAgentJobResult(context.Background(), "agent", &agent.JobResult{})
+	require.ErrorIs(t, err, agent.ErrJobResultMissingJobID)
+
+	result := &agent.JobResult{
+		JobID:   "job1",
+		Summary: "discussed the roadmap",
+		Error:   "llm quota exceeded",
+	}
+	require.NoError(t, rm.ReportAgentJobResult(context.Background(), "agent", result))
+	require.Equal(t, ad.Id, result.DispatchID)
+
+	dispatches, err := rm.GetAgentDispatches(ad.Id)
+	require.NoError(t, err)
+	require.Len(t, dispatches, 1)
+	job := dispatches[0].State.Jobs[0]
+	require.Equal(t, livekit.JobStatus_JS_FAILED, job.State.Status)
+	require.Equal(t, "llm quota exceeded", job.State.Error)
+	require.NotZero(t, job.State.EndedAt)
+
+	attrs := result.Attributes()
+	require.Equal(t, "failed", attrs[agent.JobResultStatusAttribute])
+	require.Equal(t, "discussed the roadmap", attrs[agent.JobResultSummaryAttribute])
+}
+
+func TestAgentDispatchTriggers(t *testing.T) {
+	client := &testAgentClient{}
+	rm := newRoomWithParticipants(t, testRoomOpts{
+		num:         2,
+		agentClient: client,
+		agentConfig: config.AgentsConfig{
+			DispatchTriggers: []config.AgentDispatchTriggerConfig{
+				{AgentName: "captioner", TrackType: "audio", TrackSource: "microphone"},
+				{AgentName: "translator", Attribute: "language", AttributeValue: "fr"},
+			},
+		},
+	})
+	defer rm.Close(types.ParticipantCloseReasonNone)
+
+	dispatchedAgents := func() []string {
+		dispatches, err := rm.GetAgentDispatches("")
+		require.NoError(t, err)
+		var names []string
+		for _, ad := range dispatches {
+			names = append(names, ad.AgentName)
+		}
+		return names
+	}
+
+	p0 := rm.GetParticipant("p0").(*typesfakes.FakeLocalParticipant)
+	p1 := rm.GetParticipant("p1").(*typesfakes.FakeLocalParticipant)
+
+	// a camera doesn't match the captioning trigger
+	camera := NewMockTrack(livekit.TrackType_VIDEO, "camera")
+	camera.SourceReturns(livekit.TrackSource_CAMERA)
+	p0.GetPublishedTracksReturns([]types.MediaTrack{camera})
+	rm.onTrackPublished(p0, camera)
+	require.Empty(t, dispatchedAgents())
+
+	mic := NewMockTrack(livekit.TrackType_AUDIO, "mic")
+	mic.SourceReturns(livekit.TrackSource_MICROPHONE)
+	p0.GetPublishedTracksReturns([]types.MediaTrack{camera, mic})
+	rm.onTrackPublished(p0, mic)
+	require.Eventually(t, func() bool {
+		return slices.Equal([]string{"captioner"}, dispatchedAgents())
+	}, time.Second, 10*time.Millisecond)
+
+	// triggers fire once per room
+	p1.GetPublishedTracksReturns([]types.MediaTrack{mic})
+	rm.onTrackPublished(p1, mic)
+
+	p1.ToProtoReturns(&livekit.ParticipantInfo{Identity: "p1", Attributes: map[string]string{"language": "de"}})
+	rm.onParticipantUpdate(p1)
+	time.Sleep(defaultDelay)
+	require.Len(t, dispatchedAgents(), 1)
+
+	p1.ToProtoReturns(&livekit.ParticipantInfo{Identity: "p1", Attributes: map[string]string{"language": "fr"}})
+	rm.onParticipantUpdate(p1)
+	require.Eventually(t, func() bool {
+		names := dispatchedAgents()
+		slices.Sort(names)
+		return slices.Equal([]string{"captioner", "translator"}, names)
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, 2, client.roomJobCount())
+}
+
+type testAgentClient struct {
+	lock     sync.Mutex
+	roomJobs int
+}
+
+func (c *testAgentClient) LaunchJob(ctx context.Context, req *agent.JobRequest) *sutils.IncrementalDispatcher[*livekit.Job] {
+	inc := sutils.NewIncrementalDispatcher[*livekit.Job]()
+	if req.JobType == livekit.JobType_JT_ROOM {
+		c.lock.Lock()
+		c.roomJobs++
+		c.lock.Unlock()
+		inc.Add(&livekit.Job{
+			Id:         fmt.Sprintf("job%d", c.roomJobCount()),
+			DispatchId: req.DispatchId,
+			Type:       req.JobType,
+			Room:       req.Room,
+			State:      &livekit.JobState{ParticipantIdentity: "agent"},
+		})
+	}
+	inc.Done()
+	return inc
+}
+
+func (c *testAgentClient) TerminateJob(ctx context.Context, jobID string, reason rpc.JobTerminateReason) (*livekit.JobState, error) {
+	return &livekit.JobState{}, nil
+}
+
+func (c *testAgentClient) Stop() error {
+	return nil
+}
+
+func (c *testAgentClient) roomJobCount() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.roomJobs
+}
+
+type testAgentStore struct{}
+
+func (testAgentStore) StoreAgentDispatch(context.Context, *livekit.AgentDispatch) error  { return nil }
+func (testAgentStore) DeleteAgentDispatch(context.Context, *livekit.AgentDispatch) error { return nil }
+func (testAgentStore) ListAgentDispatches(context.Context, livekit.RoomName) ([]*livekit.AgentDispatch, error) {
+	return nil, nil
+}
+func (testAgentStore) StoreAgentJob(context.Context, *livekit.Job) error  { return nil }
+func (testAgentStore) DeleteAgentJob(context.Context, *livekit.Job) error { return nil }
+func (testAgentStore) StoreAgentJobResult(context.Context, livekit.RoomName, *agent.JobResult) error {
+	return nil
 }
 
 type testRoomOpts struct {
@@ -790,6 +1964,12 @@ type testRoomOpts struct {
 	numHidden            int
 	protocol             types.ProtocolVersion
 	audioSmoothIntervals uint32
+	agentClient          agent.Client
+	agentConfig          config.AgentsConfig
+	retainedDataTopics   []config.RetainedDataTopicConfig
+	dataSendReceipts     bool
+	mixedAudio           bool
+	composedVideo        bool
 }
 
 func newRoomWithParticipants(t *testing.T, opts testRoomOpts) *Room {
@@ -798,13 +1978,18 @@ func newRoomWithParticipants(t *testing.T, opts testRoomOpts) *Room {
 		nil,
 		WebRTCConfig{},
 		config.RoomConfig{
-			EmptyTimeout:     5 * 60,
-			DepartureTimeout: 1,
+			EmptyTimeout:       5 * 60,
+			DepartureTimeout:   1,
+			RetainedDataTopics: opts.retainedDataTopics,
+			DataSendReceipts:   opts.dataSendReceipts,
+			MixedAudio:         config.MixedAudioConfig{Enabled: opts.mixedAudio},
+			VideoComposition:   config.VideoCompositionConfig{Enabled: opts.composedVideo, Width: 640, Height: 360},
 		},
 		&config.AudioConfig{
 			UpdateInterval:  audioUpdateInterval,
 			SmoothIntervals: opts.audioSmoothIntervals,
 		},
+		opts.agentConfig,
 		&livekit.ServerInfo{
 			Edition:  livekit.ServerInfo_Standard,
 			Version:  version.Version,
@@ -813,7 +1998,7 @@ func newRoomWithParticipants(t *testing.T, opts testRoomOpts) *Room {
 			Region:   "testregion",
 		},
 		telemetry.NewTelemetryService(webhook.NewDefaultNotifier("", "", nil), &telemetryfakes.FakeAnalyticsService{}),
-		nil, nil, nil,
+		opts.agentClient, testAgentStore{}, nil,
 	)
 	for i := 0; i < opts.num+opts.numHidden; i++ {
 		identity := livekit.ParticipantIdentity(fmt.Sprintf("p%d", i))
@@ -0,0 +1,196 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// RoomClosingTopic carries warnings to all participants before the room is closed by its close policy
+const RoomClosingTopic = "lk.room_closing"
+
+const (
+	RoomCloseReasonMaxDuration = "max_duration"
+	RoomCloseReasonEndTime     = "end_time"
+	RoomCloseReasonAdminLeft   = "admin_left"
+)
+
+var defaultRoomCloseWarnings = []time.Duration{5 * time.Minute, time.Minute, 10 * time.Second}
+
+// RoomClosingWarning is the json payload of room closing messages. Cancelled is set when a closing that was
+// announced no longer applies, e.g. an admin came back
+type RoomClosingWarning struct {
+	Reason string `json:"reason"`
+	// unix time in milliseconds
+	ClosesAt    int64 `json:"closes_at,omitempty"`
+	SecondsLeft int64 `json:"seconds_left,omitempty"`
+	Cancelled   bool  `json:"cancelled,omitempty"`
+}
+
+type roomClosePolicy struct {
+	conf config.RoomClosePolicyConfig
+	// longest first
+	warnings []time.Duration
+
+	lock        sync.Mutex
+	adminJoined bool
+	adminLeftAt time.Time
+	closesAt    time.Time
+	reason      string
+	// number of warnings sent for closesAt
+	warned int
+}
+
+func newRoomClosePolicy(conf config.RoomClosePolicyConfig) *roomClosePolicy {
+	if !conf.Enabled() {
+		return nil
+	}
+
+	warnings := slices.Clone(conf.Warnings)
+	if len(warnings) == 0 {
+		warnings = slices.Clone(defaultRoomCloseWarnings)
+	}
+	slices.Sort(warnings)
+	slices.Reverse(warnings)
+	return &roomClosePolicy{
+		conf:     conf,
+		warnings: warnings,
+	}
+}
+
+// update returns the warning to send, if any, and the reason to close the room for when it is due.
+// The admin countdown is held while participants are connected to other nodes, as they may include admins
+func (c *roomClosePolicy) update(now time.Time, createdAt time.Time, hasAdmin bool, hasRemote bool) (*RoomClosingWarning, string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.conf.CloseOnAdminLeave {
+		switch {
+		case hasAdmin:
+			c.adminJoined = true
+			c.adminLeftAt = time.Time{}
+		case hasRemote:
+			c.adminLeftAt = time.Time{}
+		case c.adminJoined && c.adminLeftAt.IsZero():
+			c.adminLeftAt = now
+		}
+	}
+
+	var closesAt time.Time
+	var reason string
+	earliest := func(t time.Time, r string) {
+		if !t.IsZero() && (closesAt.IsZero() || t.Before(closesAt)) {
+			closesAt, reason = t, r
+		}
+	}
+	if c.conf.MaxDuration > 0 {
+		earliest(createdAt.Add(c.conf.MaxDuration), RoomCloseReasonMaxDuration)
+	}
+	earliest(c.conf.EndTime, RoomCloseReasonEndTime)
+	if !c.adminLeftAt.IsZero() {
+		earliest(c.adminLeftAt.Add(c.conf.AdminLeaveTimeout), RoomCloseReasonAdminLeft)
+	}
+
+	var warning *RoomClosingWarning
+	if !closesAt.Equal(c.closesAt) {
+		if c.warned > 0 && (closesAt.IsZero() || closesAt.After(c.closesAt)) {
+			warning = &RoomClosingWarning{Reason: c.reason, Cancelled: true}
+		}
+		c.closesAt, c.reason, c.warned = closesAt, reason, 0
+	}
+	if closesAt.IsZero() {
+		return warning, ""
+	}
+
+	left := closesAt.Sub(now)
+	if left <= 0 {
+		return nil, reason
+	}
+
+	crossed := 0
+	for _, w := range c.warnings {
+		if left <= w {
+			crossed++
+		}
+	}
+	if crossed > c.warned {
+		c.warned = crossed
+		warning = &RoomClosingWarning{
+			Reason:      reason,
+			ClosesAt:    closesAt.UnixMilli(),
+			SecondsLeft: int64(left.Round(time.Second) / time.Second),
+		}
+	}
+	return warning, ""
+}
+
+func isRoomAdmin(p types.LocalParticipant) bool {
+	grants := p.ClaimGrants()
+	return !p.IsDependent() && grants != nil && grants.Video != nil && grants.Video.RoomAdmin
+}
+
+// EnforceClosePolicy warns participants before the room is closed by its close policy, and closes it when due
+func (r *Room) EnforceClosePolicy(now time.Time) {
+	if r.closePolicy == nil || r.IsClosed() {
+		return
+	}
+
+	r.lock.RLock()
+	createdAt := time.Unix(r.protoRoom.CreationTime, 0)
+	hasRemote := len(r.relayedParticipants) != 0
+	hasAdmin := false
+	for _, p := range r.participants {
+		if isRoomAdmin(p) {
+			hasAdmin = true
+			break
+		}
+	}
+	r.lock.RUnlock()
+
+	warning, closeReason := r.closePolicy.update(now, createdAt, hasAdmin, hasRemote)
+	if warning != nil {
+		r.broadcastClosingWarning(warning)
+	}
+	if closeReason != "" {
+		r.Logger.Infow("closing room by close policy", "reason", closeReason)
+		r.Close(types.ParticipantCloseReasonRoomClosed)
+	}
+}
+
+func (r *Room) broadcastClosingWarning(warning *RoomClosingWarning) {
+	payload, err := json.Marshal(warning)
+	if err != nil {
+		r.Logger.Errorw("could not marshal room closing warning", err)
+		return
+	}
+	topic := RoomClosingTopic
+	BroadcastDataPacketForRoom(r, nil, livekit.DataPacket_RELIABLE, &livekit.DataPacket{
+		Kind: livekit.DataPacket_RELIABLE,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload: payload,
+				Topic:   &topic,
+			},
+		},
+	}, r.Logger)
+}
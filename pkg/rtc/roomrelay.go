@@ -0,0 +1,322 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"github.com/pion/webrtc/v3"
+	"golang.org/x/exp/maps"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// RelayedParticipant is the state of a participant connected to another node hosting the room
+type RelayedParticipant struct {
+	Info       *livekit.ParticipantInfo
+	Permission *livekit.SubscriptionPermission
+	// codec of each published track, needed to receive relayed media
+	Codecs map[livekit.TrackID]RelayedCodec
+}
+
+type RelayedCodec struct {
+	Codec            webrtc.RTPCodecParameters
+	HeaderExtensions []webrtc.RTPHeaderExtensionParameter
+}
+
+type relayedParticipant struct {
+	RelayedParticipant
+
+	node   *livekit.Node
+	tracks map[livekit.TrackID]*RelayedTrack
+}
+
+// SetRelay allows the room to include participants connected to other nodes
+func (r *Room) SetRelay(relay RelayProvider) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.relay = relay
+}
+
+// GetRelayState returns the state of participants connected to this node, to be shared with other
+// nodes hosting the room
+func (r *Room) GetRelayState() []*RelayedParticipant {
+	participants := r.GetParticipants()
+	state := make([]*RelayedParticipant, 0, len(participants))
+	for _, p := range participants {
+		if p.Hidden() || p.IsDisconnected() {
+			continue
+		}
+
+		codecs := make(map[livekit.TrackID]RelayedCodec)
+		for _, track := range p.GetPublishedTracks() {
+			// only the primary codec is relayed
+			receivers := track.Receivers()
+			if len(receivers) == 0 {
+				continue
+			}
+			codecs[track.ID()] = RelayedCodec{
+				Codec:            receivers[0].Codec(),
+				HeaderExtensions: receivers[0].HeaderExtensions(),
+			}
+		}
+
+		permission, _ := p.SubscriptionPermission()
+		state = append(state, &RelayedParticipant{
+			Info:       p.ToProto(),
+			Permission: permission,
+			Codecs:     codecs,
+		})
+	}
+	return state
+}
+
+// UpdateRelayedParticipants applies the full state of participants connected to another node
+func (r *Room) UpdateRelayedParticipants(node *livekit.Node, participants []*RelayedParticipant) {
+	r.updateRelayedParticipants(livekit.NodeID(node.Id), node, participants)
+}
+
+// RemoveRelayedNode removes all participants connected to a node, when it stops hosting the room
+func (r *Room) RemoveRelayedNode(nodeID livekit.NodeID) {
+	r.updateRelayedParticipants(nodeID, nil, nil)
+}
+
+func (r *Room) updateRelayedParticipants(nodeID livekit.NodeID, node *livekit.Node, participants []*RelayedParticipant) {
+	r.lock.Lock()
+	if r.relay == nil || r.IsClosed() {
+		r.lock.Unlock()
+		return
+	}
+
+	var updated []*livekit.ParticipantInfo
+	var addedTracks, removedTracks, permissionChanged []*RelayedTrack
+	var duplicates []types.LocalParticipant
+	seen := make(map[livekit.ParticipantIdentity]bool, len(participants))
+	for _, rp := range participants {
+		identity := livekit.ParticipantIdentity(rp.Info.Identity)
+		if p := r.participants[identity]; p != nil {
+			// the participant reconnected to another node, e.g. after a regional outage,
+			// the newer session wins. it is relayed once the session on this node is closed
+			if p.ToProto().JoinedAt < rp.Info.JoinedAt {
+				duplicates = append(duplicates, p)
+			}
+			continue
+		}
+		seen[identity] = true
+
+		existing := r.relayedParticipants[identity]
+		if existing != nil && existing.Info.Sid == rp.Info.Sid && livekit.NodeID(existing.node.Id) == nodeID {
+			if !proto.Equal(existing.Permission, rp.Permission) {
+				existing.Permission = rp.Permission
+				permissionChanged = append(permissionChanged, maps.Values(existing.tracks)...)
+			}
+			if rp.Info.Version <= existing.Info.Version {
+				continue
+			}
+		} else if existing != nil {
+			if existing.Info.JoinedAt > rp.Info.JoinedAt {
+				// a newer session of the participant exists
+				continue
+			}
+			removedTracks = append(removedTracks, maps.Values(existing.tracks)...)
+			existing = nil
+		}
+		if existing == nil {
+			existing = &relayedParticipant{
+				tracks: make(map[livekit.TrackID]*RelayedTrack),
+			}
+			r.relayedParticipants[identity] = existing
+		}
+		existing.RelayedParticipant = *rp
+		existing.node = node
+
+		published := make(map[livekit.TrackID]bool, len(rp.Info.Tracks))
+		for _, ti := range rp.Info.Tracks {
+			trackID := livekit.TrackID(ti.Sid)
+			codec, ok := rp.Codecs[trackID]
+			if !ok {
+				// not ready to be relayed yet
+				continue
+			}
+			published[trackID] = true
+
+			if track := existing.tracks[trackID]; track != nil {
+				track.UpdateTrackInfo(ti)
+				continue
+			}
+
+			track := NewRelayedTrack(RelayedTrackParams{
+				OriginNode:          node,
+				Codec:               codec.Codec,
+				HeaderExtensions:    codec.HeaderExtensions,
+				ParticipantID:       livekit.ParticipantID(rp.Info.Sid),
+				ParticipantIdentity: identity,
+				ParticipantVersion:  rp.Info.Version,
+				Relay:               r.relay,
+				ReceiverConfig:      r.config.Receiver,
+				SubscriberConfig:    r.config.Subscriber,
+				AudioConfig:         *r.audioConfig,
+				Telemetry:           r.telemetry,
+				Logger:              LoggerWithTrack(LoggerWithParticipant(r.Logger, identity, livekit.ParticipantID(rp.Info.Sid), true), trackID, true),
+			}, ti)
+			existing.tracks[trackID] = track
+			addedTracks = append(addedTracks, track)
+		}
+		for trackID, track := range existing.tracks {
+			if !published[trackID] {
+				delete(existing.tracks, trackID)
+				removedTracks = append(removedTracks, track)
+			}
+		}
+
+		updated = append(updated, rp.Info)
+	}
+
+	// participants which have left the node
+	for identity, rp := range r.relayedParticipants {
+		if livekit.NodeID(rp.node.Id) != nodeID || seen[identity] {
+			continue
+		}
+
+		delete(r.relayedParticipants, identity)
+		removedTracks = append(removedTracks, maps.Values(rp.tracks)...)
+		pi := proto.Clone(rp.Info).(*livekit.ParticipantInfo)
+		pi.State = livekit.ParticipantInfo_DISCONNECTED
+		updated = append(updated, pi)
+	}
+	r.lock.Unlock()
+
+	for _, p := range duplicates {
+		p.GetLogger().Infow("removing participant connected to another node", "nodeID", nodeID)
+		r.RemoveParticipant(p.Identity(), p.ID(), types.ParticipantCloseReasonDuplicateIdentity)
+	}
+
+	for _, track := range removedTracks {
+		r.trackManager.RemoveTrack(track)
+		track.Close(false)
+	}
+	for _, track := range addedTracks {
+		r.trackManager.AddTrack(track, track.PublisherIdentity(), track.PublisherID())
+	}
+	for _, track := range permissionChanged {
+		r.trackManager.NotifyTrackChanged(track.ID())
+	}
+
+	for _, pi := range updated {
+		r.sendParticipantUpdates(r.pushAndDequeueUpdates(pi, types.ParticipantCloseReasonNone, true))
+	}
+
+	if len(addedTracks) != 0 {
+		r.lock.RLock()
+		for _, p := range r.participants {
+			if p.State() != livekit.ParticipantInfo_ACTIVE || !r.autoSubscribe(p) {
+				continue
+			}
+			for _, track := range addedTracks {
+				p.SubscribeToTrack(track.ID())
+			}
+		}
+		r.lock.RUnlock()
+	}
+}
+
+// GetRelayMetadata returns room metadata and the time it was last updated, to be reconciled with other nodes
+func (r *Room) GetRelayMetadata() (string, int64) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.protoRoom.Metadata, r.metadataUpdatedAt
+}
+
+// ReconcileRelayMetadata applies metadata updated on another node hosting the room, when it is newer
+func (r *Room) ReconcileRelayMetadata(metadata string, updatedAt int64) {
+	r.lock.Lock()
+	if updatedAt <= r.metadataUpdatedAt {
+		r.lock.Unlock()
+		return
+	}
+	r.metadataUpdatedAt = updatedAt
+	changed := r.protoRoom.Metadata != metadata
+	r.protoRoom.Metadata = metadata
+	r.lock.Unlock()
+
+	if changed {
+		r.protoProxy.MarkDirty(true)
+	}
+}
+
+// assumes lock is already acquired
+func (r *Room) getRelayedParticipantInfoLocked() []*livekit.ParticipantInfo {
+	pis := make([]*livekit.ParticipantInfo, 0, len(r.relayedParticipants))
+	for _, rp := range r.relayedParticipants {
+		pis = append(pis, rp.Info)
+	}
+	return pis
+}
+
+func (r *Room) getRelayedTrackIDs() []livekit.TrackID {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var trackIDs []livekit.TrackID
+	for _, rp := range r.relayedParticipants {
+		trackIDs = append(trackIDs, maps.Keys(rp.tracks)...)
+	}
+	return trackIDs
+}
+
+func (r *Room) hasRelayedPermission(publisherIdentity livekit.ParticipantIdentity, trackID livekit.TrackID, subIdentity livekit.ParticipantIdentity) bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	rp := r.relayedParticipants[publisherIdentity]
+	if rp == nil {
+		return false
+	}
+
+	if rp.Permission == nil || rp.Permission.AllParticipants {
+		return true
+	}
+	for _, perm := range rp.Permission.TrackPermissions {
+		if livekit.ParticipantIdentity(perm.ParticipantIdentity) != subIdentity {
+			continue
+		}
+		if perm.AllTracks {
+			return true
+		}
+		for _, sid := range perm.TrackSids {
+			if livekit.TrackID(sid) == trackID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *Room) closeRelayedParticipants() {
+	r.lock.Lock()
+	relayedParticipants := r.relayedParticipants
+	r.relayedParticipants = make(map[livekit.ParticipantIdentity]*relayedParticipant)
+	r.lock.Unlock()
+
+	for _, rp := range relayedParticipants {
+		for _, track := range rp.tracks {
+			r.trackManager.RemoveTrack(track)
+			track.Close(false)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// Participants read and change the shared state of a room with reliable data messages on RoomStateTopic,
+// answered on RoomStateResponseTopic. Changes are sent to all participants on RoomStateChangedTopic.
+// None of these messages are forwarded to other participants.
+const (
+	RoomStateTopic         = "lk.state"
+	RoomStateResponseTopic = "lk.state.response"
+	RoomStateChangedTopic  = "lk.state.changed"
+
+	RoomStateOpGet    = "get"
+	RoomStateOpSet    = "set"
+	RoomStateOpDelete = "delete"
+
+	maxRoomStateKeys      = 256
+	maxRoomStateKeyLength = 256
+	maxRoomStateValueSize = 16 * 1024
+)
+
+var (
+	ErrRoomStateVersionMismatch = errors.New("room state version does not match")
+	ErrRoomStateKeyNotFound     = errors.New("room state key not found")
+	ErrRoomStateInvalidKey      = errors.New("room state key must be set and at most 256 characters")
+	ErrRoomStateValueTooLarge   = errors.New("room state value exceeds 16KiB")
+	ErrRoomStateFull            = errors.New("room state has too many keys")
+	ErrRoomStateInvalidOp       = errors.New("room state op must be get, set or delete")
+	ErrRoomStatePermission      = errors.New("not allowed to change this room state key")
+)
+
+// RoomStateEntry is a key of the shared state of a room. versions increase with every change of the room state
+type RoomStateEntry struct {
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	Version   uint64 `json:"version"`
+	UpdatedAt int64  `json:"updated_at"`
+	// identity of the participant that made the change, empty for server API changes
+	UpdatedBy string `json:"updated_by,omitempty"`
+	// identity of the participant that created the key, empty when created through the server API
+	CreatedBy string `json:"created_by,omitempty"`
+	Deleted   bool   `json:"deleted,omitempty"`
+}
+
+// RoomStateRequest is the payload of messages on RoomStateTopic
+type RoomStateRequest struct {
+	RequestID string `json:"request_id,omitempty"`
+	Op        string `json:"op"`
+	// all keys are returned by get when empty
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value,omitempty"`
+	// when set, the change only applies if the key is at this version. 0 requires the key not to exist
+	IfVersion *uint64 `json:"if_version,omitempty"`
+}
+
+// RoomStateResponse is the payload of messages on RoomStateResponseTopic
+type RoomStateResponse struct {
+	RequestID string            `json:"request_id,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	Entries   []*RoomStateEntry `json:"entries"`
+}
+
+// RoomStatePersister saves a change of the room state before it is applied, deleted keys have Deleted set
+type RoomStatePersister func(e *RoomStateEntry) error
+
+// RoomState is the key-value state shared by the participants of a room. it is served by the node hosting the room,
+// changes are saved with the persister so the state survives the room moving to another node
+type RoomState struct {
+	keys []config.RoomStateKeyConfig
+
+	lock    sync.Mutex
+	version uint64
+	entries map[string]*RoomStateEntry
+	persist RoomStatePersister
+}
+
+func NewRoomState(keys []config.RoomStateKeyConfig) *RoomState {
+	return &RoomState{
+		keys:    keys,
+		entries: make(map[string]*RoomStateEntry),
+	}
+}
+
+// Restore replaces the state with the entries and version loaded from storage, later changes are saved with persist
+func (s *RoomState) Restore(entries []*RoomStateEntry, version uint64, persist RoomStatePersister) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.entries = make(map[string]*RoomStateEntry, len(entries))
+	s.version = version
+	for _, e := range entries {
+		s.entries[e.Key] = copyRoomStateEntry(e)
+		s.version = max(s.version, e.Version)
+	}
+	s.persist = persist
+}
+
+// Get returns the entry of key, or all entries sorted by key when key is empty
+func (s *RoomState) Get(key string) ([]*RoomStateEntry, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if key != "" {
+		e, ok := s.entries[key]
+		if !ok {
+			return nil, ErrRoomStateKeyNotFound
+		}
+		return []*RoomStateEntry{copyRoomStateEntry(e)}, nil
+	}
+
+	entries := make([]*RoomStateEntry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, copyRoomStateEntry(e))
+	}
+	slices.SortFunc(entries, func(a, b *RoomStateEntry) int { return strings.Compare(a.Key, b.Key) })
+	return entries, nil
+}
+
+func (s *RoomState) Set(key string, value string, ifVersion *uint64, updatedBy livekit.ParticipantIdentity) (*RoomStateEntry, error) {
+	if key == "" || len(key) > maxRoomStateKeyLength {
+		return nil, ErrRoomStateInvalidKey
+	}
+	if len(value) > maxRoomStateValueSize {
+		return nil, ErrRoomStateValueTooLarge
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	prev, ok := s.entries[key]
+	if err := s.checkWriterLocked(key, prev, updatedBy); err != nil {
+		return nil, err
+	}
+	if err := checkRoomStateVersion(prev, ifVersion); err != nil {
+		return nil, err
+	}
+	if !ok && len(s.entries) >= maxRoomStateKeys {
+		return nil, ErrRoomStateFull
+	}
+
+	e := &RoomStateEntry{
+		Key:       key,
+		Value:     value,
+		Version:   s.version + 1,
+		UpdatedAt: time.Now().UnixMilli(),
+		UpdatedBy: string(updatedBy),
+		CreatedBy: string(updatedBy),
+	}
+	if ok {
+		e.CreatedBy = prev.CreatedBy
+	}
+	if err := s.persistLocked(e); err != nil {
+		return nil, err
+	}
+	s.version = e.Version
+	s.entries[key] = e
+	return copyRoomStateEntry(e), nil
+}
+
+func (s *RoomState) Delete(key string, ifVersion *uint64, updatedBy livekit.ParticipantIdentity) (*RoomStateEntry, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	prev, ok := s.entries[key]
+	if !ok {
+		return nil, ErrRoomStateKeyNotFound
+	}
+	if err := s.checkWriterLocked(key, prev, updatedBy); err != nil {
+		return nil, err
+	}
+	if err := checkRoomStateVersion(prev, ifVersion); err != nil {
+		return nil, err
+	}
+
+	e := &RoomStateEntry{
+		Key:       key,
+		Version:   s.version + 1,
+		UpdatedAt: time.Now().UnixMilli(),
+		UpdatedBy: string(updatedBy),
+		CreatedBy: prev.CreatedBy,
+		Deleted:   true,
+	}
+	if err := s.persistLocked(e); err != nil {
+		return nil, err
+	}
+	s.version = e.Version
+	delete(s.entries, key)
+	return e, nil
+}
+
+func (s *RoomState) persistLocked(e *RoomStateEntry) error {
+	if s.persist == nil {
+		return nil
+	}
+	return s.persist(copyRoomStateEntry(e))
+}
+
+// checkWriterLocked applies the writers of the longest configured prefix of key, server API changes are always allowed
+func (s *RoomState) checkWriterLocked(key string, prev *RoomStateEntry, updatedBy livekit.ParticipantIdentity) error {
+	if updatedBy == "" {
+		return nil
+	}
+
+	var match *config.RoomStateKeyConfig
+	for i := range s.keys {
+		if strings.HasPrefix(key, s.keys[i].KeyPrefix) && (match == nil || len(s.keys[i].KeyPrefix) > len(match.KeyPrefix)) {
+			match = &s.keys[i]
+		}
+	}
+	if match == nil {
+		return nil
+	}
+
+	switch match.Writers {
+	case config.RoomStateWritersServer:
+		return ErrRoomStatePermission
+	case config.RoomStateWritersCreator:
+		if prev != nil && prev.CreatedBy != string(updatedBy) {
+			return ErrRoomStatePermission
+		}
+	}
+	return nil
+}
+
+func checkRoomStateVersion(e *RoomStateEntry, ifVersion *uint64) error {
+	if ifVersion == nil {
+		return nil
+	}
+	if (e == nil && *ifVersion != 0) || (e != nil && e.Version != *ifVersion) {
+		return ErrRoomStateVersionMismatch
+	}
+	return nil
+}
+
+func copyRoomStateEntry(e *RoomStateEntry) *RoomStateEntry {
+	c := *e
+	return &c
+}
+
+// ------------------------------------------------------------
+
+func (r *Room) GetState(key string) ([]*RoomStateEntry, error) {
+	return r.state.Get(key)
+}
+
+// RestoreState loads the room state kept in storage, changes are saved with persist before they are applied
+func (r *Room) RestoreState(entries []*RoomStateEntry, version uint64, persist RoomStatePersister) {
+	r.state.Restore(entries, version, persist)
+}
+
+// SetState changes a key of the room state and notifies all participants
+func (r *Room) SetState(key string, value string, ifVersion *uint64, updatedBy livekit.ParticipantIdentity) (*RoomStateEntry, error) {
+	e, err := r.state.Set(key, value, ifVersion, updatedBy)
+	if err != nil {
+		return nil, err
+	}
+	r.broadcastStateChange(e)
+	return e, nil
+}
+
+// DeleteState removes a key of the room state and notifies all participants
+func (r *Room) DeleteState(key string, ifVersion *uint64, updatedBy livekit.ParticipantIdentity) (*RoomStateEntry, error) {
+	e, err := r.state.Delete(key, ifVersion, updatedBy)
+	if err != nil {
+		return nil, err
+	}
+	r.broadcastStateChange(e)
+	return e, nil
+}
+
+func (r *Room) broadcastStateChange(e *RoomStateEntry) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		r.Logger.Errorw("could not marshal room state change", err)
+		return
+	}
+	topic := RoomStateChangedTopic
+	BroadcastDataPacketForRoom(r, nil, livekit.DataPacket_RELIABLE, &livekit.DataPacket{
+		Kind: livekit.DataPacket_RELIABLE,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload: payload,
+				Topic:   &topic,
+			},
+		},
+	}, r.Logger)
+}
+
+// handleStateRequest answers a room state message of a participant
+func (r *Room) handleStateRequest(source types.LocalParticipant, u *livekit.UserPacket) {
+	var req RoomStateRequest
+	res := &RoomStateResponse{}
+	err := json.Unmarshal(u.Payload, &req)
+	if err == nil {
+		res.RequestID = req.RequestID
+
+		var e *RoomStateEntry
+		switch req.Op {
+		case RoomStateOpGet:
+			res.Entries, err = r.GetState(req.Key)
+		case RoomStateOpSet:
+			e, err = r.SetState(req.Key, req.Value, req.IfVersion, source.Identity())
+		case RoomStateOpDelete:
+			e, err = r.DeleteState(req.Key, req.IfVersion, source.Identity())
+		default:
+			err = ErrRoomStateInvalidOp
+		}
+		if e != nil {
+			res.Entries = []*RoomStateEntry{e}
+		}
+	}
+	if err != nil {
+		res.Error = err.Error()
+	}
+
+	payload, err := json.Marshal(res)
+	if err != nil {
+		return
+	}
+	topic := RoomStateResponseTopic
+	data, err := proto.Marshal(&livekit.DataPacket{
+		Kind:                  livekit.DataPacket_RELIABLE,
+		DestinationIdentities: []string{string(source.Identity())},
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload:               payload,
+				Topic:                 &topic,
+				DestinationIdentities: []string{string(source.Identity())},
+			},
+		},
+	})
+	if err != nil {
+		return
+	}
+	if err := source.SendDataPacket(livekit.DataPacket_RELIABLE, data); err != nil {
+		source.GetLogger().Infow("could not send room state response", "error", err)
+	}
+}
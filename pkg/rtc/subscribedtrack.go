@@ -126,7 +126,11 @@ func (t *SubscribedTrack) Bound(err error) {
 			if t.params.AdaptiveStream {
 				t.settings = &livekit.UpdateTrackSettings{Quality: livekit.VideoQuality_LOW}
 			} else {
-				t.settings = &livekit.UpdateTrackSettings{Quality: livekit.VideoQuality_HIGH}
+				quality := livekit.VideoQuality_HIGH
+				if defaultQuality, _ := t.qualityLimits(); defaultQuality != livekit.VideoQuality_OFF {
+					quality = defaultQuality
+				}
+				t.settings = &livekit.UpdateTrackSettings{Quality: quality}
 			}
 		}
 		t.settingsLock.Unlock()
@@ -257,6 +261,10 @@ func (t *SubscribedTrack) applySettings() {
 			quality = mt.GetQualityForDimension(t.settings.Width, t.settings.Height)
 		}
 
+		if _, maxQuality := t.qualityLimits(); maxQuality != livekit.VideoQuality_OFF && quality != livekit.VideoQuality_OFF && quality > maxQuality {
+			quality = maxQuality
+		}
+
 		spatial = buffer.VideoQualityToSpatialLayer(quality, mt.ToProto())
 		if t.settings.Fps > 0 {
 			temporal = mt.GetTemporalLayerForSpatialFps(spatial, t.settings.Fps, dt.Codec().MimeType)
@@ -287,6 +295,12 @@ func (t *SubscribedTrack) applySettings() {
 	t.settingsLock.Unlock()
 }
 
+// qualityLimits returns the default and maximum quality set by the room for the source of the track,
+// VideoQuality_OFF when not set
+func (t *SubscribedTrack) qualityLimits() (livekit.VideoQuality, livekit.VideoQuality) {
+	return t.params.Subscriber.GetSubscribedQualityLimits(t.MediaTrack().Source()).Qualities()
+}
+
 func (t *SubscribedTrack) NeedsNegotiation() bool {
 	return t.needsNegotiation.Load()
 }
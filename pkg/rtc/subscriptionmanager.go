@@ -56,8 +56,12 @@ type SubscriptionManagerParams struct {
 	OnTrackUnsubscribed func(subTrack types.SubscribedTrack)
 	OnSubscriptionError func(trackID livekit.TrackID, fatal bool, err error)
 	Telemetry           telemetry.TelemetryService
+	// called when a subscription keeps failing with an error it is retried on, again when the error changes.
+	// an error returned is taken as the subscriber not having been notified, it is called again on the next attempt
+	OnSubscriptionStalled func(trackID livekit.TrackID, err error) error
 
 	SubscriptionLimitVideo, SubscriptionLimitAudio int32
+	MaxSubscribersPerTrack                         uint32
 }
 
 // SubscriptionManager manages a participant's subscriptions
@@ -342,7 +346,7 @@ func (m *SubscriptionManager) reconcileSubscription(s *trackSubscription) {
 			s.recordAttempt(false)
 
 			switch err {
-			case ErrNoTrackPermission, ErrNoSubscribePermission, ErrNoReceiver, ErrNotOpen, ErrTrackNotAttached, ErrSubscriptionLimitExceeded:
+			case ErrNoTrackPermission, ErrNoSubscribePermission, ErrNoReceiver, ErrNotOpen, ErrTrackNotAttached, ErrSubscriptionLimitExceeded, ErrTrackSubscribersExceeded:
 				// these are errors that are outside of our control, so we'll keep trying
 				// - ErrNoTrackPermission: publisher did not grant subscriber permission, may change any moment
 				// - ErrNoSubscribePermission: participant was not granted canSubscribe, may change any moment
@@ -350,10 +354,12 @@ func (m *SubscriptionManager) reconcileSubscription(s *trackSubscription) {
 				// - ErrTrackNotAttached: Remote Track that is not attached, but may be attached later
 				// - ErrNotOpen: Track is closing or already closed
 				// - ErrSubscriptionLimitExceeded: the participant have reached the limit of subscriptions, wait for the other subscription to be unsubscribed
+				// - ErrTrackSubscribersExceeded: the track has reached its max subscribers, wait for another subscriber to leave
 				// We'll still log an event to reflect this in telemetry since it's been too long
 				if s.durationSinceStart() > subscriptionTimeout {
 					s.maybeRecordError(m.params.Telemetry, m.params.Participant.ID(), err, true)
 				}
+				m.maybeNotifyStalled(s, err)
 			case ErrTrackNotFound:
 				// source track was never published or closed
 				// if after timeout we'd unsubscribe from it.
@@ -364,6 +370,8 @@ func (m *SubscriptionManager) reconcileSubscription(s *trackSubscription) {
 					s.setDesired(false)
 					m.queueReconcile(s.trackID)
 					m.params.OnSubscriptionError(s.trackID, false, err)
+				} else {
+					m.maybeNotifyStalled(s, err)
 				}
 			default:
 				// all other errors
@@ -382,6 +390,7 @@ func (m *SubscriptionManager) reconcileSubscription(s *trackSubscription) {
 			}
 		} else {
 			s.recordAttempt(true)
+			s.setStalledError(nil)
 		}
 
 		return
@@ -423,6 +432,26 @@ func (m *SubscriptionManager) reconcileSubscription(s *trackSubscription) {
 	}
 }
 
+func (m *SubscriptionManager) maybeNotifyStalled(s *trackSubscription, err error) {
+	if m.params.OnSubscriptionStalled == nil || s.getStalledError() == err {
+		return
+	}
+
+	switch err {
+	case ErrNoTrackPermission, ErrNoSubscribePermission, ErrSubscriptionLimitExceeded, ErrTrackSubscribersExceeded:
+		// these wait on a change elsewhere in the room, notify right away
+	default:
+		// the track is expected to become available shortly
+		if s.durationSinceStart() <= subscriptionTimeout {
+			return
+		}
+	}
+
+	if m.params.OnSubscriptionStalled(s.trackID, err) == nil {
+		s.setStalledError(err)
+	}
+}
+
 // trigger an immediate reconciliation, when trackID is empty, will reconcile all subscriptions
 func (m *SubscriptionManager) queueReconcile(trackID livekit.TrackID) {
 	select {
@@ -525,6 +554,12 @@ func (m *SubscriptionManager) subscribe(s *trackSubscription) error {
 		return ErrNoTrackPermission
 	}
 
+	if m.params.MaxSubscribersPerTrack > 0 &&
+		track.GetNumSubscribers() >= int(m.params.MaxSubscribersPerTrack) &&
+		!track.IsSubscriber(m.params.Participant.ID()) {
+		return ErrTrackSubscribersExceeded
+	}
+
 	subTrack, err := track.AddSubscriber(m.params.Participant)
 	if err != nil && !errors.Is(err, errAlreadySubscribed) {
 		// ignore error(s): already subscribed
@@ -754,6 +789,7 @@ type trackSubscription struct {
 	numAttempts              atomic.Int32
 	bound                    bool
 	kind                     atomic.Pointer[livekit.TrackType]
+	stalledErr               error
 
 	// the later of when subscription was requested OR when the first failure was encountered OR when permission is granted
 	// this timestamp determines when failures are reported
@@ -937,6 +973,18 @@ func (s *trackSubscription) isBound() bool {
 	return s.bound
 }
 
+func (s *trackSubscription) setStalledError(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.stalledErr = err
+}
+
+func (s *trackSubscription) getStalledError() error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.stalledErr
+}
+
 func (s *trackSubscription) recordAttempt(success bool) {
 	if !success {
 		if s.numAttempts.Load() == 0 {
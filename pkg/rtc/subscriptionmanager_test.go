@@ -126,6 +126,13 @@ func TestSubscribe(t *testing.T) {
 		sm.params.OnSubscriptionError = func(trackID livekit.TrackID, fatal bool, err error) {
 			failed.Store(true)
 		}
+		stalled := atomic.Int32{}
+		sm.params.OnSubscriptionStalled = func(trackID livekit.TrackID, err error) error {
+			require.Equal(t, livekit.TrackID("track"), trackID)
+			require.Equal(t, SubscriptionCauseTrackPermissionDenied, subscriptionCause(err))
+			stalled.Inc()
+			return nil
+		}
 
 		sm.SubscribeToTrack("track")
 		s := sm.subscriptions["track"]
@@ -138,6 +145,8 @@ func TestSubscribe(t *testing.T) {
 		// should not have called failed callbacks, isDesired remains unchanged
 		require.True(t, s.isDesired())
 		require.False(t, failed.Load())
+		// subscriber notified of the stall once
+		require.Equal(t, int32(1), stalled.Load())
 		require.True(t, s.needsSubscribe())
 		require.Len(t, sm.GetSubscribedTracks(), 0)
 
@@ -156,6 +165,7 @@ func TestSubscribe(t *testing.T) {
 		}, subSettleTimeout, subCheckInterval, "should be subscribed")
 
 		require.Len(t, sm.GetSubscribedTracks(), 1)
+		require.NoError(t, s.getStalledError())
 	})
 
 	t.Run("publisher left", func(t *testing.T) {
@@ -447,9 +457,45 @@ func TestSubscriptionLimits(t *testing.T) {
 	require.Len(t, sm.GetSubscribedTracks(), 1)
 }
 
+func TestMaxSubscribersPerTrack(t *testing.T) {
+	sm := newTestSubscriptionManagerWithParams(t, testSubscriptionParams{
+		MaxSubscribersPerTrack: 1,
+	})
+	defer sm.Close(false)
+	resolver := newTestResolver(true, true, "pub", "pubID")
+	numSubscribers := atomic.Int32{}
+	numSubscribers.Store(1)
+	sm.params.TrackResolver = func(identity livekit.ParticipantIdentity, trackID livekit.TrackID) types.MediaResolverResult {
+		res := resolver.Resolve(identity, trackID)
+		res.Track.(*typesfakes.FakeMediaTrack).GetNumSubscribersCalls(func() int {
+			return int(numSubscribers.Load())
+		})
+		return res
+	}
+	subCount := atomic.Int32{}
+	sm.params.OnTrackSubscribed = func(subTrack types.SubscribedTrack) {
+		subCount.Add(1)
+	}
+
+	// track has reached its max subscribers, subscribe pending
+	sm.SubscribeToTrack("track")
+	s := sm.subscriptions["track"]
+	time.Sleep(reconcileInterval * 2)
+	require.True(t, s.needsSubscribe())
+	require.Zero(t, subCount.Load())
+
+	// subscribed once the other subscriber leaves
+	numSubscribers.Store(0)
+	require.Eventually(t, func() bool {
+		return subCount.Load() == 1
+	}, subSettleTimeout, subCheckInterval, "track was not subscribed")
+	require.False(t, s.needsSubscribe())
+}
+
 type testSubscriptionParams struct {
 	SubscriptionLimitAudio int32
 	SubscriptionLimitVideo int32
+	MaxSubscribersPerTrack uint32
 }
 
 func newTestSubscriptionManager(t *testing.T) *SubscriptionManager {
@@ -473,6 +519,7 @@ func newTestSubscriptionManagerWithParams(t *testing.T, params testSubscriptionP
 		Telemetry:              &telemetryfakes.FakeTelemetryService{},
 		SubscriptionLimitAudio: params.SubscriptionLimitAudio,
 		SubscriptionLimitVideo: params.SubscriptionLimitVideo,
+		MaxSubscribersPerTrack: params.MaxSubscribersPerTrack,
 	})
 }
 
@@ -0,0 +1,128 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/pion/webrtc/v3"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// SubscriptionStatusTopic is the topic of the messages telling a subscriber why media of a track it subscribed to
+// is not being delivered. sent when rtc.subscription_status is enabled
+const SubscriptionStatusTopic = "lk.subscription_status"
+
+const (
+	// the subscription is retried and will proceed once the cause is resolved
+	SubscriptionStatusStalled = "stalled"
+	// the subscription was given up
+	SubscriptionStatusFailed = "failed"
+	// the subscription is in place, but forwarding was paused
+	SubscriptionStatusPaused = "paused"
+	// forwarding resumed after a pause
+	SubscriptionStatusActive = "active"
+)
+
+const (
+	SubscriptionCauseTrackPermissionDenied     = "track_permission_denied"
+	SubscriptionCauseSubscribePermissionDenied = "subscribe_permission_denied"
+	SubscriptionCauseSubscriptionLimitExceeded = "subscription_limit_exceeded"
+	SubscriptionCauseTrackSubscribersExceeded  = "track_subscribers_exceeded"
+	SubscriptionCauseTrackNotFound             = "track_not_found"
+	SubscriptionCauseTrackUnavailable          = "track_unavailable"
+	SubscriptionCauseTrackNotBound             = "track_not_bound"
+	SubscriptionCauseCodecUnsupported          = "codec_unsupported"
+	SubscriptionCauseAllocatorPaused           = "allocator_paused"
+	SubscriptionCauseUnknown                   = "unknown"
+)
+
+// SubscriptionStatus is the json payload of subscription status messages
+type SubscriptionStatus struct {
+	TrackSid       string `json:"track_sid"`
+	ParticipantSid string `json:"participant_sid,omitempty"`
+	Status         string `json:"status"`
+	Cause          string `json:"cause,omitempty"`
+	Message        string `json:"message,omitempty"`
+}
+
+func subscriptionCause(err error) string {
+	switch {
+	case errors.Is(err, ErrNoTrackPermission):
+		return SubscriptionCauseTrackPermissionDenied
+	case errors.Is(err, ErrNoSubscribePermission):
+		return SubscriptionCauseSubscribePermissionDenied
+	case errors.Is(err, ErrSubscriptionLimitExceeded):
+		return SubscriptionCauseSubscriptionLimitExceeded
+	case errors.Is(err, ErrTrackSubscribersExceeded):
+		return SubscriptionCauseTrackSubscribersExceeded
+	case errors.Is(err, ErrTrackNotFound):
+		return SubscriptionCauseTrackNotFound
+	case errors.Is(err, ErrNoReceiver), errors.Is(err, ErrNotOpen), errors.Is(err, ErrTrackNotAttached):
+		return SubscriptionCauseTrackUnavailable
+	case errors.Is(err, ErrTrackNotBound):
+		return SubscriptionCauseTrackNotBound
+	case errors.Is(err, webrtc.ErrUnsupportedCodec):
+		return SubscriptionCauseCodecUnsupported
+	default:
+		return SubscriptionCauseUnknown
+	}
+}
+
+func newSubscriptionStatus(trackID livekit.TrackID, status string, err error) SubscriptionStatus {
+	s := SubscriptionStatus{
+		TrackSid: string(trackID),
+		Status:   status,
+	}
+	if err != nil {
+		s.Cause = subscriptionCause(err)
+		s.Message = err.Error()
+	}
+	return s
+}
+
+func sendSubscriptionStatus(subscriber types.LocalParticipant, statuses ...SubscriptionStatus) error {
+	topic := SubscriptionStatusTopic
+	for _, status := range statuses {
+		payload, err := json.Marshal(status)
+		if err != nil {
+			return err
+		}
+		dp := &livekit.DataPacket{
+			Kind:                  livekit.DataPacket_RELIABLE,
+			DestinationIdentities: []string{string(subscriber.Identity())},
+			Value: &livekit.DataPacket_User{
+				User: &livekit.UserPacket{
+					Payload:               payload,
+					Topic:                 &topic,
+					DestinationIdentities: []string{string(subscriber.Identity())},
+				},
+			},
+		}
+		data, err := proto.Marshal(dp)
+		if err != nil {
+			return err
+		}
+		if err := subscriber.SendDataPacket(livekit.DataPacket_RELIABLE, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"sync"
+)
+
+// TrackHealth is the health of a published video track
+type TrackHealth string
+
+const (
+	TrackHealthOK     TrackHealth = "ok"
+	TrackHealthFrozen TrackHealth = "frozen"
+	TrackHealthBlack  TrackHealth = "black"
+)
+
+// trackHealth combines freeze detection on the receiver with analysis of snapshots
+type trackHealth struct {
+	lock            sync.Mutex
+	timestampFrozen bool
+	snapshotFrozen  bool
+	black           bool
+	health          TrackHealth
+	closed          bool
+	onChange        func(from TrackHealth, to TrackHealth)
+}
+
+func newTrackHealth(onChange func(from TrackHealth, to TrackHealth)) *trackHealth {
+	return &trackHealth{
+		health:   TrackHealthOK,
+		onChange: onChange,
+	}
+}
+
+func (h *trackHealth) get() TrackHealth {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return h.health
+}
+
+// close stops reporting changes, and returns the last health the first time it is called
+func (h *trackHealth) close() (TrackHealth, bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.closed {
+		return h.health, false
+	}
+	h.closed = true
+	return h.health, true
+}
+
+func (h *trackHealth) setTimestampFrozen(frozen bool) {
+	h.update(func() {
+		h.timestampFrozen = frozen
+	})
+}
+
+func (h *trackHealth) setSnapshotHealth(black bool, frozen bool) {
+	h.update(func() {
+		h.black = black
+		h.snapshotFrozen = frozen
+	})
+}
+
+func (h *trackHealth) update(fn func()) {
+	h.lock.Lock()
+	if h.closed {
+		h.lock.Unlock()
+		return
+	}
+	fn()
+	health := TrackHealthOK
+	switch {
+	case h.black:
+		health = TrackHealthBlack
+	case h.timestampFrozen || h.snapshotFrozen:
+		health = TrackHealthFrozen
+	}
+	from := h.health
+	h.health = health
+	h.lock.Unlock()
+
+	if health != from && h.onChange != nil {
+		h.onChange(from, health)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/bep/debounce"
 	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
 	"github.com/pion/ice/v2"
@@ -219,6 +220,11 @@ type TransportParams struct {
 	IsSendSide                   bool
 	AllowPlayoutDelay            bool
 	DataChannelMaxBufferedAmount uint64
+	DataChannelPriority          config.DataChannelPriorityConfig
+	NetworkImpairment            config.NetworkImpairmentConfig
+	Chaos                        *sfuinterceptor.ChaosFactory
+	// added after the built-in interceptors, furthest from the network
+	Interceptors []interceptor.Factory
 }
 
 func newPeerConnection(params TransportParams, onBandwidthEstimator func(estimator cc.BandwidthEstimator)) (*webrtc.PeerConnection, *webrtc.MediaEngine, error) {
@@ -303,7 +309,15 @@ func newPeerConnection(params TransportParams, onBandwidthEstimator func(estimat
 	}
 
 	ir := &interceptor.Registry{}
+	if params.Chaos != nil {
+		ir.Add(params.Chaos)
+	}
 	if params.IsSendSide {
+		// added first to be closest to the network
+		if impairment, ok := params.NetworkImpairment.ForParticipant(string(params.ParticipantIdentity)); ok {
+			params.Logger.Infow("impairing network", "impairment", impairment)
+			ir.Add(sfuinterceptor.NewNetworkImpairmentFactory(impairment))
+		}
 		se.DetachDataChannels()
 		if params.CongestionControlConfig.UseSendSideBWE {
 			gf, err := cc.NewInterceptor(func() (cc.BandwidthEstimator, error) {
@@ -373,6 +387,9 @@ func newPeerConnection(params TransportParams, onBandwidthEstimator func(estimat
 		params.Logger.Debugw("rtx pair found from extension", "repair", repair, "base", base)
 		params.Config.BufferFactory.SetRTXPair(repair, base)
 	}, params.Logger))
+	for _, f := range params.Interceptors {
+		ir.Add(f)
+	}
 	api := webrtc.NewAPI(
 		webrtc.WithMediaEngine(me),
 		webrtc.WithSettingEngine(se),
@@ -405,8 +422,9 @@ func NewPCTransport(params TransportParams) (*PCTransport, error) {
 			Logger: params.Logger.WithComponent(utils.ComponentCongestionControl),
 		})
 		t.streamAllocator.OnStreamStateChange(params.Handler.OnStreamStateChange)
+		t.streamAllocator.OnProbeResult(params.Handler.OnProbeResult)
 		t.streamAllocator.Start()
-		t.pacer = pacer.NewPassThrough(params.Logger)
+		t.pacer = pacer.NewPassThrough(params.Logger, clock.New())
 	}
 
 	if err := t.createPeerConnection(); err != nil {
@@ -932,11 +950,13 @@ func (t *PCTransport) WriteRTCP(pkts []rtcp.Packet) error {
 
 func (t *PCTransport) SendDataPacket(kind livekit.DataPacket_Kind, encoded []byte) error {
 	var dc *webrtc.DataChannel
+	label := ReliableDataChannel
 	t.lock.RLock()
 	if kind == livekit.DataPacket_RELIABLE {
 		dc = t.reliableDC
 	} else {
 		dc = t.lossyDC
+		label = LossyDataChannel
 	}
 	t.lock.RUnlock()
 
@@ -948,7 +968,8 @@ func (t *PCTransport) SendDataPacket(kind livekit.DataPacket_Kind, encoded []byt
 		return ErrTransportFailure
 	}
 
-	if t.params.DataChannelMaxBufferedAmount > 0 && dc.BufferedAmount() > t.params.DataChannelMaxBufferedAmount {
+	limit := dataChannelBufferLimit(dataChannelPriority(t.params.DataChannelPriority, label), t.params.DataChannelMaxBufferedAmount)
+	if limit > 0 && dc.BufferedAmount() > limit {
 		return ErrDataChannelBufferFull
 	}
 
@@ -1042,6 +1063,23 @@ func (t *PCTransport) Negotiate(force bool) {
 	}
 }
 
+// SimulateFault injects a fault for the given duration, incoming media is not affected as it bypasses interceptors
+func (t *PCTransport) SimulateFault(fault types.TransportFault, duration time.Duration) error {
+	if t.params.Chaos == nil {
+		return ErrChaosNotEnabled
+	}
+
+	switch fault {
+	case types.TransportFaultDropRTCP:
+		t.params.Chaos.DropRTCP(duration)
+	case types.TransportFaultStall:
+		t.params.Chaos.Stall(duration)
+	default:
+		return fmt.Errorf("unknown transport fault: %s", fault)
+	}
+	return nil
+}
+
 func (t *PCTransport) ICERestart() error {
 	if t.pc.ConnectionState() == webrtc.PeerConnectionStateClosed {
 		t.params.Logger.Warnw("trying to restart ICE on closed peer connection", nil)
@@ -1094,6 +1132,22 @@ func (t *PCTransport) SetChannelCapacityOfStreamAllocator(channelCapacity int64)
 	t.streamAllocator.SetChannelCapacity(channelCapacity)
 }
 
+func (t *PCTransport) SetMaxChannelCapacityOfStreamAllocator(maxChannelCapacity int64) {
+	if t.streamAllocator == nil {
+		return
+	}
+
+	t.streamAllocator.SetMaxChannelCapacity(maxChannelCapacity)
+}
+
+func (t *PCTransport) GetProbeResultsOfStreamAllocator() []streamallocator.ProbeResult {
+	if t.streamAllocator == nil {
+		return nil
+	}
+
+	return t.streamAllocator.GetProbeResults()
+}
+
 func (t *PCTransport) preparePC(previousAnswer webrtc.SessionDescription) error {
 	// sticky data channel to first m-lines, if someday we don't send sdp without media streams to
 	// client's subscribe pc after joining, should change this step
@@ -44,6 +44,7 @@ type Handler interface {
 	OnNegotiationStateChanged(state NegotiationState)
 	OnNegotiationFailed()
 	OnStreamStateChange(update *streamallocator.StreamStateUpdate) error
+	OnProbeResult(result streamallocator.ProbeResult)
 }
 
 type UnimplementedHandler struct{}
@@ -67,3 +68,4 @@ func (h UnimplementedHandler) OnNegotiationFailed()
 func (h UnimplementedHandler) OnStreamStateChange(update *streamallocator.StreamStateUpdate) error {
 	return nil
 }
+func (h UnimplementedHandler) OnProbeResult(result streamallocator.ProbeResult) {}
@@ -73,6 +73,11 @@ type FakeHandler struct {
 	onOfferReturnsOnCall map[int]struct {
 		result1 error
 	}
+	OnProbeResultStub        func(streamallocator.ProbeResult)
+	onProbeResultMutex       sync.RWMutex
+	onProbeResultArgsForCall []struct {
+		arg1 streamallocator.ProbeResult
+	}
 	OnStreamStateChangeStub        func(*streamallocator.StreamStateUpdate) error
 	onStreamStateChangeMutex       sync.RWMutex
 	onStreamStateChangeArgsForCall []struct {
@@ -452,6 +457,38 @@ func (fake *FakeHandler) OnOfferReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeHandler) OnProbeResult(arg1 streamallocator.ProbeResult) {
+	fake.onProbeResultMutex.Lock()
+	fake.onProbeResultArgsForCall = append(fake.onProbeResultArgsForCall, struct {
+		arg1 streamallocator.ProbeResult
+	}{arg1})
+	stub := fake.OnProbeResultStub
+	fake.recordInvocation("OnProbeResult", []interface{}{arg1})
+	fake.onProbeResultMutex.Unlock()
+	if stub != nil {
+		fake.OnProbeResultStub(arg1)
+	}
+}
+
+func (fake *FakeHandler) OnProbeResultCallCount() int {
+	fake.onProbeResultMutex.RLock()
+	defer fake.onProbeResultMutex.RUnlock()
+	return len(fake.onProbeResultArgsForCall)
+}
+
+func (fake *FakeHandler) OnProbeResultCalls(stub func(streamallocator.ProbeResult)) {
+	fake.onProbeResultMutex.Lock()
+	defer fake.onProbeResultMutex.Unlock()
+	fake.OnProbeResultStub = stub
+}
+
+func (fake *FakeHandler) OnProbeResultArgsForCall(i int) streamallocator.ProbeResult {
+	fake.onProbeResultMutex.RLock()
+	defer fake.onProbeResultMutex.RUnlock()
+	argsForCall := fake.onProbeResultArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeHandler) OnStreamStateChange(arg1 *streamallocator.StreamStateUpdate) error {
 	fake.onStreamStateChangeMutex.Lock()
 	ret, specificReturn := fake.onStreamStateChangeReturnsOnCall[len(fake.onStreamStateChangeArgsForCall)]
@@ -567,6 +604,8 @@ func (fake *FakeHandler) Invocations() map[string][][]interface{} {
 	defer fake.onNegotiationStateChangedMutex.RUnlock()
 	fake.onOfferMutex.RLock()
 	defer fake.onOfferMutex.RUnlock()
+	fake.onProbeResultMutex.RLock()
+	defer fake.onProbeResultMutex.RUnlock()
 	fake.onStreamStateChangeMutex.RLock()
 	defer fake.onStreamStateChangeMutex.RUnlock()
 	fake.onTrackMutex.RLock()
@@ -21,11 +21,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pion/interceptor"
 	"github.com/pion/sdp/v3"
 	"github.com/pion/webrtc/v3"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/atomic"
 
+	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/rtc/transport"
 	"github.com/livekit/livekit-server/pkg/rtc/transport/transportfakes"
 	"github.com/livekit/livekit-server/pkg/testutils"
@@ -622,3 +624,43 @@ func TestConfigureAudioTransceiver(t *testing.T) {
 		})
 	}
 }
+
+type countingInterceptorFactory struct {
+	created atomic.Int32
+}
+
+func (f *countingInterceptorFactory) NewInterceptor(_ string) (interceptor.Interceptor, error) {
+	f.created.Inc()
+	return &interceptor.NoOp{}, nil
+}
+
+func TestCustomInterceptors(t *testing.T) {
+	factory := &countingInterceptorFactory{}
+	transport, err := NewPCTransport(TransportParams{
+		ParticipantID:       "id",
+		ParticipantIdentity: "identity",
+		Config:              &WebRTCConfig{},
+		Handler:             &transportfakes.FakeHandler{},
+		Interceptors:        []interceptor.Factory{factory},
+	})
+	require.NoError(t, err)
+	defer transport.Close()
+
+	require.EqualValues(t, 1, factory.created.Load())
+}
+
+func TestDataChannelBufferLimit(t *testing.T) {
+	priorities := config.DataChannelPriorityConfig{Reliable: DataChannelPriorityHigh, Lossy: DataChannelPriorityLow}
+	require.Equal(t, DataChannelPriorityHigh, dataChannelPriority(priorities, ReliableDataChannel))
+	require.Equal(t, DataChannelPriorityLow, dataChannelPriority(priorities, LossyDataChannel))
+
+	require.Equal(t, uint64(1000), dataChannelBufferLimit(DataChannelPriorityHigh, 1000))
+	require.Equal(t, uint64(500), dataChannelBufferLimit(DataChannelPriorityNormal, 1000))
+	require.Equal(t, uint64(1000), dataChannelBufferLimit("", 1000))
+	require.Equal(t, uint64(250), dataChannelBufferLimit(DataChannelPriorityLow, 1000))
+
+	// prioritized channels are limited even when data channels are not
+	require.Zero(t, dataChannelBufferLimit("", 0))
+	require.Equal(t, uint64(defaultPriorityBufferedAmount), dataChannelBufferLimit(DataChannelPriorityHigh, 0))
+	require.Equal(t, uint64(defaultPriorityBufferedAmount/4), dataChannelBufferLimit(DataChannelPriorityLow, 0))
+}
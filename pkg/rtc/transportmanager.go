@@ -19,6 +19,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/pion/interceptor"
 	"github.com/pion/rtcp"
 	"github.com/pion/sdp/v3"
 	"github.com/pion/webrtc/v3"
@@ -34,7 +35,9 @@ import (
 	"github.com/livekit/livekit-server/pkg/rtc/transport"
 	"github.com/livekit/livekit-server/pkg/rtc/types"
 	"github.com/livekit/livekit-server/pkg/sfu"
+	sfuinterceptor "github.com/livekit/livekit-server/pkg/sfu/interceptor"
 	"github.com/livekit/livekit-server/pkg/sfu/pacer"
+	"github.com/livekit/livekit-server/pkg/sfu/streamallocator"
 )
 
 const (
@@ -85,9 +88,14 @@ type TransportManagerParams struct {
 	TURNSEnabled                 bool
 	AllowPlayoutDelay            bool
 	DataChannelMaxBufferedAmount uint64
+	DataChannelPriority          config.DataChannelPriorityConfig
+	NetworkImpairment            config.NetworkImpairmentConfig
+	EnableChaos                  bool
 	Logger                       logger.Logger
 	PublisherHandler             transport.Handler
 	SubscriberHandler            transport.Handler
+	PublisherInterceptors        []interceptor.Factory
+	SubscriberInterceptors       []interceptor.Factory
 }
 
 type TransportManager struct {
@@ -127,6 +135,12 @@ func NewTransportManager(params TransportManagerParams) (*TransportManager, erro
 	}
 	t.mediaLossProxy.OnMediaLossUpdate(t.onMediaLossUpdate)
 
+	var publisherChaos, subscriberChaos *sfuinterceptor.ChaosFactory
+	if params.EnableChaos {
+		publisherChaos = sfuinterceptor.NewChaosFactory()
+		subscriberChaos = sfuinterceptor.NewChaosFactory()
+	}
+
 	publisher, err := NewPCTransport(TransportParams{
 		ParticipantID:           params.SID,
 		ParticipantIdentity:     params.Identity,
@@ -139,6 +153,8 @@ func NewTransportManager(params TransportManagerParams) (*TransportManager, erro
 		Logger:                  LoggerWithPCTarget(params.Logger, livekit.SignalTarget_PUBLISHER),
 		SimTracks:               params.SimTracks,
 		ClientInfo:              params.ClientInfo,
+		Chaos:                   publisherChaos,
+		Interceptors:            params.PublisherInterceptors,
 		Transport:               livekit.SignalTarget_PUBLISHER,
 		Handler:                 TransportManagerPublisherTransportHandler{TransportManagerTransportHandler{params.PublisherHandler, t}},
 	})
@@ -161,6 +177,10 @@ func NewTransportManager(params TransportManagerParams) (*TransportManager, erro
 		IsSendSide:                   true,
 		AllowPlayoutDelay:            params.AllowPlayoutDelay,
 		DataChannelMaxBufferedAmount: params.DataChannelMaxBufferedAmount,
+		DataChannelPriority:          params.DataChannelPriority,
+		NetworkImpairment:            params.NetworkImpairment,
+		Chaos:                        subscriberChaos,
+		Interceptors:                 params.SubscriberInterceptors,
 		Transport:                    livekit.SignalTarget_SUBSCRIBER,
 		Handler:                      TransportManagerTransportHandler{params.SubscriberHandler, t},
 	})
@@ -421,6 +441,13 @@ func (t *TransportManager) ICERestart(iceConfig *livekit.ICEConfig) error {
 	return t.subscriber.ICERestart()
 }
 
+func (t *TransportManager) SimulateFault(target livekit.SignalTarget, fault types.TransportFault, duration time.Duration) error {
+	if target == livekit.SignalTarget_PUBLISHER {
+		return t.publisher.SimulateFault(fault, duration)
+	}
+	return t.subscriber.SimulateFault(fault, duration)
+}
+
 func (t *TransportManager) OnICEConfigChanged(f func(iceConfig *livekit.ICEConfig)) {
 	t.lock.Lock()
 	t.onICEConfigChanged = f
@@ -726,6 +753,14 @@ func (t *TransportManager) SetSubscriberChannelCapacity(channelCapacity int64) {
 	t.subscriber.SetChannelCapacityOfStreamAllocator(channelCapacity)
 }
 
+func (t *TransportManager) SetSubscriberMaxChannelCapacity(maxChannelCapacity int64) {
+	t.subscriber.SetMaxChannelCapacityOfStreamAllocator(maxChannelCapacity)
+}
+
+func (t *TransportManager) GetSubscriberProbeResults() []streamallocator.ProbeResult {
+	return t.subscriber.GetProbeResultsOfStreamAllocator()
+}
+
 func (t *TransportManager) hasRecentSignalLocked() bool {
 	return time.Since(t.lastSignalAt) < PingTimeoutSeconds*time.Second
 }
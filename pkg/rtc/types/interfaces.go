@@ -26,10 +26,14 @@ import (
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/utils"
 
+	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/sfu"
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/clocksource"
 	"github.com/livekit/livekit-server/pkg/sfu/pacer"
+	"github.com/livekit/livekit-server/pkg/sfu/rtpextension"
+	"github.com/livekit/livekit-server/pkg/telemetry"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
@@ -107,6 +111,7 @@ const (
 	ParticipantCloseReasonMigrateCodecMismatch
 	ParticipantCloseReasonSignalSourceClose
 	ParticipantCloseReasonRoomClosed
+	ParticipantCloseReasonIdle
 )
 
 func (p ParticipantCloseReason) String() string {
@@ -161,6 +166,8 @@ func (p ParticipantCloseReason) String() string {
 		return "SIGNAL_SOURCE_CLOSE"
 	case ParticipantCloseReasonRoomClosed:
 		return "ROOM_CLOSED"
+	case ParticipantCloseReasonIdle:
+		return "IDLE"
 	default:
 		return fmt.Sprintf("%d", int(p))
 	}
@@ -193,6 +200,8 @@ func (p ParticipantCloseReason) ToDisconnectReason() livekit.DisconnectReason {
 		return livekit.DisconnectReason_SIGNAL_CLOSE
 	case ParticipantCloseReasonRoomClosed:
 		return livekit.DisconnectReason_ROOM_CLOSED
+	case ParticipantCloseReasonIdle:
+		return livekit.DisconnectReason_PARTICIPANT_REMOVED
 	default:
 		// the other types will map to unknown reason
 		return livekit.DisconnectReason_UNKNOWN_REASON
@@ -248,6 +257,28 @@ func (s SignallingCloseReason) String() string {
 
 // ---------------------------------------------
 
+type TransportFault int
+
+const (
+	// drop RTCP in both directions
+	TransportFaultDropRTCP TransportFault = iota
+	// drop outgoing media and RTCP in both directions
+	TransportFaultStall
+)
+
+func (f TransportFault) String() string {
+	switch f {
+	case TransportFaultDropRTCP:
+		return "DROP_RTCP"
+	case TransportFaultStall:
+		return "STALL"
+	default:
+		return fmt.Sprintf("%d", int(f))
+	}
+}
+
+// ---------------------------------------------
+
 //counterfeiter:generate . Participant
 type Participant interface {
 	ID() livekit.ParticipantID
@@ -306,6 +337,8 @@ type LocalParticipant interface {
 	GetTrailer() []byte
 	GetLogger() logger.Logger
 	GetAdaptiveStream() bool
+	// default and maximum quality of video tracks of the source set by the room
+	GetSubscribedQualityLimits(source livekit.TrackSource) config.SubscribedQualityLimits
 	ProtocolVersion() ProtocolVersion
 	SupportsSyncStreamID() bool
 	SupportsTransceiverReuse() bool
@@ -319,6 +352,7 @@ type LocalParticipant interface {
 	GetClientInfo() *livekit.ClientInfo
 	GetClientConfiguration() *livekit.ClientConfiguration
 	GetBufferFactory() *buffer.Factory
+	GetHeaderExtensions() *rtpextension.Registry
 	GetPlayoutDelayConfig() *livekit.PlayoutDelay
 	GetPendingTrack(trackID livekit.TrackID) *livekit.TrackInfo
 	GetICEConnectionDetails() []*ICEConnectionDetails
@@ -354,6 +388,7 @@ type LocalParticipant interface {
 	HandleAnswer(sdp webrtc.SessionDescription)
 	Negotiate(force bool)
 	ICERestart(iceConfig *livekit.ICEConfig)
+	SimulateTransportFault(target livekit.SignalTarget, fault TransportFault, duration time.Duration) error
 	AddTrackToSubscriber(trackLocal webrtc.TrackLocal, params AddTrackParams) (*webrtc.RTPSender, *webrtc.RTPTransceiver, error)
 	AddTransceiverFromTrackToSubscriber(trackLocal webrtc.TrackLocal, params AddTrackParams) (*webrtc.RTPSender, *webrtc.RTPTransceiver, error)
 	RemoveTrackFromSubscriber(sender *webrtc.RTPSender) error
@@ -376,6 +411,8 @@ type LocalParticipant interface {
 	IsSubscribedTo(sid livekit.ParticipantID) bool
 
 	GetConnectionQuality() *livekit.ConnectionQualityInfo
+	// totals of data channel traffic since the participant joined
+	GetDataTrafficTotals() *telemetry.TrafficTotals
 
 	// server sent messages
 	SendJoinResponse(joinResponse *livekit.JoinResponse) error
@@ -385,10 +422,18 @@ type LocalParticipant interface {
 	SendRoomUpdate(room *livekit.Room) error
 	SendConnectionQualityUpdate(update *livekit.ConnectionQualityUpdate) error
 	SubscriptionPermissionUpdate(publisherID livekit.ParticipantID, trackID livekit.TrackID, allowed bool)
+	// permissions set through the API, restricting the ones set by the participant
+	AdminSubscriptionPermission() *livekit.SubscriptionPermission
+	UpdateAdminSubscriptionPermission(
+		subscriptionPermission *livekit.SubscriptionPermission,
+		resolverBySid func(participantID livekit.ParticipantID) LocalParticipant,
+	) error
 	SendRefreshToken(token string) error
 	SendRequestResponse(requestResponse *livekit.RequestResponse) error
 	HandleReconnectAndSendResponse(reconnectReason livekit.ReconnectReason, reconnectResponse *livekit.ReconnectResponse) error
 	IssueFullReconnect(reason ParticipantCloseReason)
+	// SetRemoveReason sets the reason reported when removed by a RemoveParticipant request
+	SetRemoveReason(reason livekit.DisconnectReason)
 
 	// callbacks
 	OnStateChange(func(p LocalParticipant, state livekit.ParticipantInfo_State))
@@ -435,10 +480,15 @@ type LocalParticipant interface {
 	// down stream bandwidth management
 	SetSubscriberAllowPause(allowPause bool)
 	SetSubscriberChannelCapacity(channelCapacity int64)
+	// bounds the bandwidth of video forwarded to the participant, 0 to remove the bound
+	SetSubscriberMaxChannelCapacity(maxChannelCapacity int64)
 
 	GetPacer() pacer.Pacer
 
 	GetDisableSenderReportPassThrough() bool
+	GetSenderReportSmoothingWindow() time.Duration
+	GetSenderReportClockSkewCompensation() bool
+	GetSenderReportClock() clocksource.ClockSource
 }
 
 // Room is a container of participants, and can provide room-level actions
@@ -520,6 +570,7 @@ type LocalMediaTrack interface {
 
 	GetConnectionScoreAndQuality() (float32, livekit.ConnectionQuality)
 	GetTrackStats() *livekit.RTPStats
+	GetTrackStatsWithLayers() *buffer.RTPStatsWithLayers
 
 	SetRTT(rtt uint32)
 
@@ -2,6 +2,7 @@
 package typesfakes
 
 import (
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
 	"sync"
 
 	"github.com/livekit/livekit-server/pkg/rtc/types"
@@ -117,6 +118,16 @@ type FakeLocalMediaTrack struct {
 	getTrackStatsReturnsOnCall map[int]struct {
 		result1 *livekit.RTPStats
 	}
+	GetTrackStatsWithLayersStub        func() *buffer.RTPStatsWithLayers
+	getTrackStatsWithLayersMutex       sync.RWMutex
+	getTrackStatsWithLayersArgsForCall []struct {
+	}
+	getTrackStatsWithLayersReturns struct {
+		result1 *buffer.RTPStatsWithLayers
+	}
+	getTrackStatsWithLayersReturnsOnCall map[int]struct {
+		result1 *buffer.RTPStatsWithLayers
+	}
 	HasSdpCidStub        func(string) bool
 	hasSdpCidMutex       sync.RWMutex
 	hasSdpCidArgsForCall []struct {
@@ -911,6 +922,59 @@ func (fake *FakeLocalMediaTrack) GetTrackStatsReturnsOnCall(i int, result1 *live
 	}{result1}
 }
 
+func (fake *FakeLocalMediaTrack) GetTrackStatsWithLayers() *buffer.RTPStatsWithLayers {
+	fake.getTrackStatsWithLayersMutex.Lock()
+	ret, specificReturn := fake.getTrackStatsWithLayersReturnsOnCall[len(fake.getTrackStatsWithLayersArgsForCall)]
+	fake.getTrackStatsWithLayersArgsForCall = append(fake.getTrackStatsWithLayersArgsForCall, struct {
+	}{})
+	stub := fake.GetTrackStatsWithLayersStub
+	fakeReturns := fake.getTrackStatsWithLayersReturns
+	fake.recordInvocation("GetTrackStatsWithLayers", []interface{}{})
+	fake.getTrackStatsWithLayersMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalMediaTrack) GetTrackStatsWithLayersCallCount() int {
+	fake.getTrackStatsWithLayersMutex.RLock()
+	defer fake.getTrackStatsWithLayersMutex.RUnlock()
+	return len(fake.getTrackStatsWithLayersArgsForCall)
+}
+
+func (fake *FakeLocalMediaTrack) GetTrackStatsWithLayersCalls(stub func() *buffer.RTPStatsWithLayers) {
+	fake.getTrackStatsWithLayersMutex.Lock()
+	defer fake.getTrackStatsWithLayersMutex.Unlock()
+	fake.GetTrackStatsWithLayersStub = stub
+}
+
+func (fake *FakeLocalMediaTrack) GetTrackStatsWithLayersReturns(result1 *buffer.RTPStatsWithLayers) {
+	fake.getTrackStatsWithLayersMutex.Lock()
+	defer fake.getTrackStatsWithLayersMutex.Unlock()
+	fake.GetTrackStatsWithLayersStub = nil
+	fake.getTrackStatsWithLayersReturns = struct {
+		result1 *buffer.RTPStatsWithLayers
+	}{result1}
+}
+
+func (fake *FakeLocalMediaTrack) GetTrackStatsWithLayersReturnsOnCall(i int, result1 *buffer.RTPStatsWithLayers) {
+	fake.getTrackStatsWithLayersMutex.Lock()
+	defer fake.getTrackStatsWithLayersMutex.Unlock()
+	fake.GetTrackStatsWithLayersStub = nil
+	if fake.getTrackStatsWithLayersReturnsOnCall == nil {
+		fake.getTrackStatsWithLayersReturnsOnCall = make(map[int]struct {
+			result1 *buffer.RTPStatsWithLayers
+		})
+	}
+	fake.getTrackStatsWithLayersReturnsOnCall[i] = struct {
+		result1 *buffer.RTPStatsWithLayers
+	}{result1}
+}
+
 func (fake *FakeLocalMediaTrack) HasSdpCid(arg1 string) bool {
 	fake.hasSdpCidMutex.Lock()
 	ret, specificReturn := fake.hasSdpCidReturnsOnCall[len(fake.hasSdpCidArgsForCall)]
@@ -2231,6 +2295,8 @@ func (fake *FakeLocalMediaTrack) Invocations() map[string][][]interface{} {
 	defer fake.getTemporalLayerForSpatialFpsMutex.RUnlock()
 	fake.getTrackStatsMutex.RLock()
 	defer fake.getTrackStatsMutex.RUnlock()
+	fake.getTrackStatsWithLayersMutex.RLock()
+	defer fake.getTrackStatsWithLayersMutex.RUnlock()
 	fake.hasSdpCidMutex.RLock()
 	defer fake.hasSdpCidMutex.RUnlock()
 	fake.iDMutex.RLock()
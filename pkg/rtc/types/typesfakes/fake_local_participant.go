@@ -5,11 +5,15 @@ import (
 	"sync"
 	"time"
 
+	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/rtc/types"
 	"github.com/livekit/livekit-server/pkg/sfu"
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/clocksource"
 	"github.com/livekit/livekit-server/pkg/sfu/pacer"
+	"github.com/livekit/livekit-server/pkg/sfu/rtpextension"
+	"github.com/livekit/livekit-server/pkg/telemetry"
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
@@ -62,6 +66,16 @@ type FakeLocalParticipant struct {
 		result2 *webrtc.RTPTransceiver
 		result3 error
 	}
+	AdminSubscriptionPermissionStub        func() *livekit.SubscriptionPermission
+	adminSubscriptionPermissionMutex       sync.RWMutex
+	adminSubscriptionPermissionArgsForCall []struct {
+	}
+	adminSubscriptionPermissionReturns struct {
+		result1 *livekit.SubscriptionPermission
+	}
+	adminSubscriptionPermissionReturnsOnCall map[int]struct {
+		result1 *livekit.SubscriptionPermission
+	}
 	CacheDownTrackStub        func(livekit.TrackID, *webrtc.RTPTransceiver, sfu.DownTrackState)
 	cacheDownTrackMutex       sync.RWMutex
 	cacheDownTrackArgsForCall []struct {
@@ -266,6 +280,16 @@ type FakeLocalParticipant struct {
 	getConnectionQualityReturnsOnCall map[int]struct {
 		result1 *livekit.ConnectionQualityInfo
 	}
+	GetDataTrafficTotalsStub        func() *telemetry.TrafficTotals
+	getDataTrafficTotalsMutex       sync.RWMutex
+	getDataTrafficTotalsArgsForCall []struct {
+	}
+	getDataTrafficTotalsReturns struct {
+		result1 *telemetry.TrafficTotals
+	}
+	getDataTrafficTotalsReturnsOnCall map[int]struct {
+		result1 *telemetry.TrafficTotals
+	}
 	GetDisableSenderReportPassThroughStub        func() bool
 	getDisableSenderReportPassThroughMutex       sync.RWMutex
 	getDisableSenderReportPassThroughArgsForCall []struct {
@@ -276,6 +300,46 @@ type FakeLocalParticipant struct {
 	getDisableSenderReportPassThroughReturnsOnCall map[int]struct {
 		result1 bool
 	}
+	GetSenderReportClockStub        func() clocksource.ClockSource
+	getSenderReportClockMutex       sync.RWMutex
+	getSenderReportClockArgsForCall []struct {
+	}
+	getSenderReportClockReturns struct {
+		result1 clocksource.ClockSource
+	}
+	getSenderReportClockReturnsOnCall map[int]struct {
+		result1 clocksource.ClockSource
+	}
+	GetSenderReportClockSkewCompensationStub        func() bool
+	getSenderReportClockSkewCompensationMutex       sync.RWMutex
+	getSenderReportClockSkewCompensationArgsForCall []struct {
+	}
+	getSenderReportClockSkewCompensationReturns struct {
+		result1 bool
+	}
+	getSenderReportClockSkewCompensationReturnsOnCall map[int]struct {
+		result1 bool
+	}
+	GetSenderReportSmoothingWindowStub        func() time.Duration
+	getSenderReportSmoothingWindowMutex       sync.RWMutex
+	getSenderReportSmoothingWindowArgsForCall []struct {
+	}
+	getSenderReportSmoothingWindowReturns struct {
+		result1 time.Duration
+	}
+	getSenderReportSmoothingWindowReturnsOnCall map[int]struct {
+		result1 time.Duration
+	}
+	GetHeaderExtensionsStub        func() *rtpextension.Registry
+	getHeaderExtensionsMutex       sync.RWMutex
+	getHeaderExtensionsArgsForCall []struct {
+	}
+	getHeaderExtensionsReturns struct {
+		result1 *rtpextension.Registry
+	}
+	getHeaderExtensionsReturnsOnCall map[int]struct {
+		result1 *rtpextension.Registry
+	}
 	GetICEConnectionDetailsStub        func() []*types.ICEConnectionDetails
 	getICEConnectionDetailsMutex       sync.RWMutex
 	getICEConnectionDetailsArgsForCall []struct {
@@ -358,6 +422,17 @@ type FakeLocalParticipant struct {
 	getSubscribedParticipantsReturnsOnCall map[int]struct {
 		result1 []livekit.ParticipantID
 	}
+	GetSubscribedQualityLimitsStub        func(livekit.TrackSource) config.SubscribedQualityLimits
+	getSubscribedQualityLimitsMutex       sync.RWMutex
+	getSubscribedQualityLimitsArgsForCall []struct {
+		arg1 livekit.TrackSource
+	}
+	getSubscribedQualityLimitsReturns struct {
+		result1 config.SubscribedQualityLimits
+	}
+	getSubscribedQualityLimitsReturnsOnCall map[int]struct {
+		result1 config.SubscribedQualityLimits
+	}
 	GetSubscribedTracksStub        func() []types.SubscribedTrack
 	getSubscribedTracksMutex       sync.RWMutex
 	getSubscribedTracksArgsForCall []struct {
@@ -811,6 +886,11 @@ type FakeLocalParticipant struct {
 	setPermissionReturnsOnCall map[int]struct {
 		result1 bool
 	}
+	SetRemoveReasonStub        func(livekit.DisconnectReason)
+	setRemoveReasonMutex       sync.RWMutex
+	setRemoveReasonArgsForCall []struct {
+		arg1 livekit.DisconnectReason
+	}
 	SetResponseSinkStub        func(routing.MessageSink)
 	setResponseSinkMutex       sync.RWMutex
 	setResponseSinkArgsForCall []struct {
@@ -831,6 +911,11 @@ type FakeLocalParticipant struct {
 	setSubscriberChannelCapacityArgsForCall []struct {
 		arg1 int64
 	}
+	SetSubscriberMaxChannelCapacityStub        func(int64)
+	setSubscriberMaxChannelCapacityMutex       sync.RWMutex
+	setSubscriberMaxChannelCapacityArgsForCall []struct {
+		arg1 int64
+	}
 	SetTrackMutedStub        func(livekit.TrackID, bool, bool) *livekit.TrackInfo
 	setTrackMutedMutex       sync.RWMutex
 	setTrackMutedArgsForCall []struct {
@@ -844,6 +929,19 @@ type FakeLocalParticipant struct {
 	setTrackMutedReturnsOnCall map[int]struct {
 		result1 *livekit.TrackInfo
 	}
+	SimulateTransportFaultStub        func(livekit.SignalTarget, types.TransportFault, time.Duration) error
+	simulateTransportFaultMutex       sync.RWMutex
+	simulateTransportFaultArgsForCall []struct {
+		arg1 livekit.SignalTarget
+		arg2 types.TransportFault
+		arg3 time.Duration
+	}
+	simulateTransportFaultReturns struct {
+		result1 error
+	}
+	simulateTransportFaultReturnsOnCall map[int]struct {
+		result1 error
+	}
 	StateStub        func() livekit.ParticipantInfo_State
 	stateMutex       sync.RWMutex
 	stateArgsForCall []struct {
@@ -950,6 +1048,18 @@ type FakeLocalParticipant struct {
 	unsubscribeFromTrackArgsForCall []struct {
 		arg1 livekit.TrackID
 	}
+	UpdateAdminSubscriptionPermissionStub        func(*livekit.SubscriptionPermission, func(participantID livekit.ParticipantID) types.LocalParticipant) error
+	updateAdminSubscriptionPermissionMutex       sync.RWMutex
+	updateAdminSubscriptionPermissionArgsForCall []struct {
+		arg1 *livekit.SubscriptionPermission
+		arg2 func(participantID livekit.ParticipantID) types.LocalParticipant
+	}
+	updateAdminSubscriptionPermissionReturns struct {
+		result1 error
+	}
+	updateAdminSubscriptionPermissionReturnsOnCall map[int]struct {
+		result1 error
+	}
 	UpdateAudioTrackStub        func(*livekit.UpdateLocalAudioTrack) error
 	updateAudioTrackMutex       sync.RWMutex
 	updateAudioTrackArgsForCall []struct {
@@ -1219,6 +1329,8 @@ func (fake *FakeLocalParticipant) AddTransceiverFromTrackToSubscriber(arg1 webrt
 func (fake *FakeLocalParticipant) AddTransceiverFromTrackToSubscriberCallCount() int {
 	fake.addTransceiverFromTrackToSubscriberMutex.RLock()
 	defer fake.addTransceiverFromTrackToSubscriberMutex.RUnlock()
+	fake.adminSubscriptionPermissionMutex.RLock()
+	defer fake.adminSubscriptionPermissionMutex.RUnlock()
 	return len(fake.addTransceiverFromTrackToSubscriberArgsForCall)
 }
 
@@ -1264,6 +1376,59 @@ func (fake *FakeLocalParticipant) AddTransceiverFromTrackToSubscriberReturnsOnCa
 	}{result1, result2, result3}
 }
 
+func (fake *FakeLocalParticipant) AdminSubscriptionPermission() *livekit.SubscriptionPermission {
+	fake.adminSubscriptionPermissionMutex.Lock()
+	ret, specificReturn := fake.adminSubscriptionPermissionReturnsOnCall[len(fake.adminSubscriptionPermissionArgsForCall)]
+	fake.adminSubscriptionPermissionArgsForCall = append(fake.adminSubscriptionPermissionArgsForCall, struct {
+	}{})
+	stub := fake.AdminSubscriptionPermissionStub
+	fakeReturns := fake.adminSubscriptionPermissionReturns
+	fake.recordInvocation("AdminSubscriptionPermission", []interface{}{})
+	fake.adminSubscriptionPermissionMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) AdminSubscriptionPermissionCallCount() int {
+	fake.adminSubscriptionPermissionMutex.RLock()
+	defer fake.adminSubscriptionPermissionMutex.RUnlock()
+	return len(fake.adminSubscriptionPermissionArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) AdminSubscriptionPermissionCalls(stub func() *livekit.SubscriptionPermission) {
+	fake.adminSubscriptionPermissionMutex.Lock()
+	defer fake.adminSubscriptionPermissionMutex.Unlock()
+	fake.AdminSubscriptionPermissionStub = stub
+}
+
+func (fake *FakeLocalParticipant) AdminSubscriptionPermissionReturns(result1 *livekit.SubscriptionPermission) {
+	fake.adminSubscriptionPermissionMutex.Lock()
+	defer fake.adminSubscriptionPermissionMutex.Unlock()
+	fake.AdminSubscriptionPermissionStub = nil
+	fake.adminSubscriptionPermissionReturns = struct {
+		result1 *livekit.SubscriptionPermission
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) AdminSubscriptionPermissionReturnsOnCall(i int, result1 *livekit.SubscriptionPermission) {
+	fake.adminSubscriptionPermissionMutex.Lock()
+	defer fake.adminSubscriptionPermissionMutex.Unlock()
+	fake.AdminSubscriptionPermissionStub = nil
+	if fake.adminSubscriptionPermissionReturnsOnCall == nil {
+		fake.adminSubscriptionPermissionReturnsOnCall = make(map[int]struct {
+			result1 *livekit.SubscriptionPermission
+		})
+	}
+	fake.adminSubscriptionPermissionReturnsOnCall[i] = struct {
+		result1 *livekit.SubscriptionPermission
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) CacheDownTrack(arg1 livekit.TrackID, arg2 *webrtc.RTPTransceiver, arg3 sfu.DownTrackState) {
 	fake.cacheDownTrackMutex.Lock()
 	fake.cacheDownTrackArgsForCall = append(fake.cacheDownTrackArgsForCall, struct {
@@ -2326,6 +2491,59 @@ func (fake *FakeLocalParticipant) GetConnectionQualityReturnsOnCall(i int, resul
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) GetDataTrafficTotals() *telemetry.TrafficTotals {
+	fake.getDataTrafficTotalsMutex.Lock()
+	ret, specificReturn := fake.getDataTrafficTotalsReturnsOnCall[len(fake.getDataTrafficTotalsArgsForCall)]
+	fake.getDataTrafficTotalsArgsForCall = append(fake.getDataTrafficTotalsArgsForCall, struct {
+	}{})
+	stub := fake.GetDataTrafficTotalsStub
+	fakeReturns := fake.getDataTrafficTotalsReturns
+	fake.recordInvocation("GetDataTrafficTotals", []interface{}{})
+	fake.getDataTrafficTotalsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) GetDataTrafficTotalsCallCount() int {
+	fake.getDataTrafficTotalsMutex.RLock()
+	defer fake.getDataTrafficTotalsMutex.RUnlock()
+	return len(fake.getDataTrafficTotalsArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) GetDataTrafficTotalsCalls(stub func() *telemetry.TrafficTotals) {
+	fake.getDataTrafficTotalsMutex.Lock()
+	defer fake.getDataTrafficTotalsMutex.Unlock()
+	fake.GetDataTrafficTotalsStub = stub
+}
+
+func (fake *FakeLocalParticipant) GetDataTrafficTotalsReturns(result1 *telemetry.TrafficTotals) {
+	fake.getDataTrafficTotalsMutex.Lock()
+	defer fake.getDataTrafficTotalsMutex.Unlock()
+	fake.GetDataTrafficTotalsStub = nil
+	fake.getDataTrafficTotalsReturns = struct {
+		result1 *telemetry.TrafficTotals
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) GetDataTrafficTotalsReturnsOnCall(i int, result1 *telemetry.TrafficTotals) {
+	fake.getDataTrafficTotalsMutex.Lock()
+	defer fake.getDataTrafficTotalsMutex.Unlock()
+	fake.GetDataTrafficTotalsStub = nil
+	if fake.getDataTrafficTotalsReturnsOnCall == nil {
+		fake.getDataTrafficTotalsReturnsOnCall = make(map[int]struct {
+			result1 *telemetry.TrafficTotals
+		})
+	}
+	fake.getDataTrafficTotalsReturnsOnCall[i] = struct {
+		result1 *telemetry.TrafficTotals
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) GetDisableSenderReportPassThrough() bool {
 	fake.getDisableSenderReportPassThroughMutex.Lock()
 	ret, specificReturn := fake.getDisableSenderReportPassThroughReturnsOnCall[len(fake.getDisableSenderReportPassThroughArgsForCall)]
@@ -2379,6 +2597,218 @@ func (fake *FakeLocalParticipant) GetDisableSenderReportPassThroughReturnsOnCall
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) GetSenderReportClock() clocksource.ClockSource {
+	fake.getSenderReportClockMutex.Lock()
+	ret, specificReturn := fake.getSenderReportClockReturnsOnCall[len(fake.getSenderReportClockArgsForCall)]
+	fake.getSenderReportClockArgsForCall = append(fake.getSenderReportClockArgsForCall, struct {
+	}{})
+	stub := fake.GetSenderReportClockStub
+	fakeReturns := fake.getSenderReportClockReturns
+	fake.recordInvocation("GetSenderReportClock", []interface{}{})
+	fake.getSenderReportClockMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) GetSenderReportClockCallCount() int {
+	fake.getSenderReportClockMutex.RLock()
+	defer fake.getSenderReportClockMutex.RUnlock()
+	return len(fake.getSenderReportClockArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) GetSenderReportClockCalls(stub func() clocksource.ClockSource) {
+	fake.getSenderReportClockMutex.Lock()
+	defer fake.getSenderReportClockMutex.Unlock()
+	fake.GetSenderReportClockStub = stub
+}
+
+func (fake *FakeLocalParticipant) GetSenderReportClockReturns(result1 clocksource.ClockSource) {
+	fake.getSenderReportClockMutex.Lock()
+	defer fake.getSenderReportClockMutex.Unlock()
+	fake.GetSenderReportClockStub = nil
+	fake.getSenderReportClockReturns = struct {
+		result1 clocksource.ClockSource
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) GetSenderReportClockReturnsOnCall(i int, result1 clocksource.ClockSource) {
+	fake.getSenderReportClockMutex.Lock()
+	defer fake.getSenderReportClockMutex.Unlock()
+	fake.GetSenderReportClockStub = nil
+	if fake.getSenderReportClockReturnsOnCall == nil {
+		fake.getSenderReportClockReturnsOnCall = make(map[int]struct {
+			result1 clocksource.ClockSource
+		})
+	}
+	fake.getSenderReportClockReturnsOnCall[i] = struct {
+		result1 clocksource.ClockSource
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) GetSenderReportClockSkewCompensation() bool {
+	fake.getSenderReportClockSkewCompensationMutex.Lock()
+	ret, specificReturn := fake.getSenderReportClockSkewCompensationReturnsOnCall[len(fake.getSenderReportClockSkewCompensationArgsForCall)]
+	fake.getSenderReportClockSkewCompensationArgsForCall = append(fake.getSenderReportClockSkewCompensationArgsForCall, struct {
+	}{})
+	stub := fake.GetSenderReportClockSkewCompensationStub
+	fakeReturns := fake.getSenderReportClockSkewCompensationReturns
+	fake.recordInvocation("GetSenderReportClockSkewCompensation", []interface{}{})
+	fake.getSenderReportClockSkewCompensationMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) GetSenderReportClockSkewCompensationCallCount() int {
+	fake.getSenderReportClockSkewCompensationMutex.RLock()
+	defer fake.getSenderReportClockSkewCompensationMutex.RUnlock()
+	return len(fake.getSenderReportClockSkewCompensationArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) GetSenderReportClockSkewCompensationCalls(stub func() bool) {
+	fake.getSenderReportClockSkewCompensationMutex.Lock()
+	defer fake.getSenderReportClockSkewCompensationMutex.Unlock()
+	fake.GetSenderReportClockSkewCompensationStub = stub
+}
+
+func (fake *FakeLocalParticipant) GetSenderReportClockSkewCompensationReturns(result1 bool) {
+	fake.getSenderReportClockSkewCompensationMutex.Lock()
+	defer fake.getSenderReportClockSkewCompensationMutex.Unlock()
+	fake.GetSenderReportClockSkewCompensationStub = nil
+	fake.getSenderReportClockSkewCompensationReturns = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) GetSenderReportClockSkewCompensationReturnsOnCall(i int, result1 bool) {
+	fake.getSenderReportClockSkewCompensationMutex.Lock()
+	defer fake.getSenderReportClockSkewCompensationMutex.Unlock()
+	fake.GetSenderReportClockSkewCompensationStub = nil
+	if fake.getSenderReportClockSkewCompensationReturnsOnCall == nil {
+		fake.getSenderReportClockSkewCompensationReturnsOnCall = make(map[int]struct {
+			result1 bool
+		})
+	}
+	fake.getSenderReportClockSkewCompensationReturnsOnCall[i] = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) GetSenderReportSmoothingWindow() time.Duration {
+	fake.getSenderReportSmoothingWindowMutex.Lock()
+	ret, specificReturn := fake.getSenderReportSmoothingWindowReturnsOnCall[len(fake.getSenderReportSmoothingWindowArgsForCall)]
+	fake.getSenderReportSmoothingWindowArgsForCall = append(fake.getSenderReportSmoothingWindowArgsForCall, struct {
+	}{})
+	stub := fake.GetSenderReportSmoothingWindowStub
+	fakeReturns := fake.getSenderReportSmoothingWindowReturns
+	fake.recordInvocation("GetSenderReportSmoothingWindow", []interface{}{})
+	fake.getSenderReportSmoothingWindowMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) GetSenderReportSmoothingWindowCallCount() int {
+	fake.getSenderReportSmoothingWindowMutex.RLock()
+	defer fake.getSenderReportSmoothingWindowMutex.RUnlock()
+	return len(fake.getSenderReportSmoothingWindowArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) GetSenderReportSmoothingWindowCalls(stub func() time.Duration) {
+	fake.getSenderReportSmoothingWindowMutex.Lock()
+	defer fake.getSenderReportSmoothingWindowMutex.Unlock()
+	fake.GetSenderReportSmoothingWindowStub = stub
+}
+
+func (fake *FakeLocalParticipant) GetSenderReportSmoothingWindowReturns(result1 time.Duration) {
+	fake.getSenderReportSmoothingWindowMutex.Lock()
+	defer fake.getSenderReportSmoothingWindowMutex.Unlock()
+	fake.GetSenderReportSmoothingWindowStub = nil
+	fake.getSenderReportSmoothingWindowReturns = struct {
+		result1 time.Duration
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) GetSenderReportSmoothingWindowReturnsOnCall(i int, result1 time.Duration) {
+	fake.getSenderReportSmoothingWindowMutex.Lock()
+	defer fake.getSenderReportSmoothingWindowMutex.Unlock()
+	fake.GetSenderReportSmoothingWindowStub = nil
+	if fake.getSenderReportSmoothingWindowReturnsOnCall == nil {
+		fake.getSenderReportSmoothingWindowReturnsOnCall = make(map[int]struct {
+			result1 time.Duration
+		})
+	}
+	fake.getSenderReportSmoothingWindowReturnsOnCall[i] = struct {
+		result1 time.Duration
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) GetHeaderExtensions() *rtpextension.Registry {
+	fake.getHeaderExtensionsMutex.Lock()
+	ret, specificReturn := fake.getHeaderExtensionsReturnsOnCall[len(fake.getHeaderExtensionsArgsForCall)]
+	fake.getHeaderExtensionsArgsForCall = append(fake.getHeaderExtensionsArgsForCall, struct {
+	}{})
+	stub := fake.GetHeaderExtensionsStub
+	fakeReturns := fake.getHeaderExtensionsReturns
+	fake.recordInvocation("GetHeaderExtensions", []interface{}{})
+	fake.getHeaderExtensionsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) GetHeaderExtensionsCallCount() int {
+	fake.getHeaderExtensionsMutex.RLock()
+	defer fake.getHeaderExtensionsMutex.RUnlock()
+	return len(fake.getHeaderExtensionsArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) GetHeaderExtensionsCalls(stub func() *rtpextension.Registry) {
+	fake.getHeaderExtensionsMutex.Lock()
+	defer fake.getHeaderExtensionsMutex.Unlock()
+	fake.GetHeaderExtensionsStub = stub
+}
+
+func (fake *FakeLocalParticipant) GetHeaderExtensionsReturns(result1 *rtpextension.Registry) {
+	fake.getHeaderExtensionsMutex.Lock()
+	defer fake.getHeaderExtensionsMutex.Unlock()
+	fake.GetHeaderExtensionsStub = nil
+	fake.getHeaderExtensionsReturns = struct {
+		result1 *rtpextension.Registry
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) GetHeaderExtensionsReturnsOnCall(i int, result1 *rtpextension.Registry) {
+	fake.getHeaderExtensionsMutex.Lock()
+	defer fake.getHeaderExtensionsMutex.Unlock()
+	fake.GetHeaderExtensionsStub = nil
+	if fake.getHeaderExtensionsReturnsOnCall == nil {
+		fake.getHeaderExtensionsReturnsOnCall = make(map[int]struct {
+			result1 *rtpextension.Registry
+		})
+	}
+	fake.getHeaderExtensionsReturnsOnCall[i] = struct {
+		result1 *rtpextension.Registry
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) GetICEConnectionDetails() []*types.ICEConnectionDetails {
 	fake.getICEConnectionDetailsMutex.Lock()
 	ret, specificReturn := fake.getICEConnectionDetailsReturnsOnCall[len(fake.getICEConnectionDetailsArgsForCall)]
@@ -2819,6 +3249,67 @@ func (fake *FakeLocalParticipant) GetSubscribedParticipantsReturnsOnCall(i int,
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) GetSubscribedQualityLimits(arg1 livekit.TrackSource) config.SubscribedQualityLimits {
+	fake.getSubscribedQualityLimitsMutex.Lock()
+	ret, specificReturn := fake.getSubscribedQualityLimitsReturnsOnCall[len(fake.getSubscribedQualityLimitsArgsForCall)]
+	fake.getSubscribedQualityLimitsArgsForCall = append(fake.getSubscribedQualityLimitsArgsForCall, struct {
+		arg1 livekit.TrackSource
+	}{arg1})
+	stub := fake.GetSubscribedQualityLimitsStub
+	fakeReturns := fake.getSubscribedQualityLimitsReturns
+	fake.recordInvocation("GetSubscribedQualityLimits", []interface{}{arg1})
+	fake.getSubscribedQualityLimitsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) GetSubscribedQualityLimitsCallCount() int {
+	fake.getSubscribedQualityLimitsMutex.RLock()
+	defer fake.getSubscribedQualityLimitsMutex.RUnlock()
+	return len(fake.getSubscribedQualityLimitsArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) GetSubscribedQualityLimitsCalls(stub func(livekit.TrackSource) config.SubscribedQualityLimits) {
+	fake.getSubscribedQualityLimitsMutex.Lock()
+	defer fake.getSubscribedQualityLimitsMutex.Unlock()
+	fake.GetSubscribedQualityLimitsStub = stub
+}
+
+func (fake *FakeLocalParticipant) GetSubscribedQualityLimitsArgsForCall(i int) livekit.TrackSource {
+	fake.getSubscribedQualityLimitsMutex.RLock()
+	defer fake.getSubscribedQualityLimitsMutex.RUnlock()
+	argsForCall := fake.getSubscribedQualityLimitsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeLocalParticipant) GetSubscribedQualityLimitsReturns(result1 config.SubscribedQualityLimits) {
+	fake.getSubscribedQualityLimitsMutex.Lock()
+	defer fake.getSubscribedQualityLimitsMutex.Unlock()
+	fake.GetSubscribedQualityLimitsStub = nil
+	fake.getSubscribedQualityLimitsReturns = struct {
+		result1 config.SubscribedQualityLimits
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) GetSubscribedQualityLimitsReturnsOnCall(i int, result1 config.SubscribedQualityLimits) {
+	fake.getSubscribedQualityLimitsMutex.Lock()
+	defer fake.getSubscribedQualityLimitsMutex.Unlock()
+	fake.GetSubscribedQualityLimitsStub = nil
+	if fake.getSubscribedQualityLimitsReturnsOnCall == nil {
+		fake.getSubscribedQualityLimitsReturnsOnCall = make(map[int]struct {
+			result1 config.SubscribedQualityLimits
+		})
+	}
+	fake.getSubscribedQualityLimitsReturnsOnCall[i] = struct {
+		result1 config.SubscribedQualityLimits
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) GetSubscribedTracks() []types.SubscribedTrack {
 	fake.getSubscribedTracksMutex.Lock()
 	ret, specificReturn := fake.getSubscribedTracksReturnsOnCall[len(fake.getSubscribedTracksArgsForCall)]
@@ -5373,6 +5864,38 @@ func (fake *FakeLocalParticipant) SetPermissionReturnsOnCall(i int, result1 bool
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) SetRemoveReason(arg1 livekit.DisconnectReason) {
+	fake.setRemoveReasonMutex.Lock()
+	fake.setRemoveReasonArgsForCall = append(fake.setRemoveReasonArgsForCall, struct {
+		arg1 livekit.DisconnectReason
+	}{arg1})
+	stub := fake.SetRemoveReasonStub
+	fake.recordInvocation("SetRemoveReason", []interface{}{arg1})
+	fake.setRemoveReasonMutex.Unlock()
+	if stub != nil {
+		fake.SetRemoveReasonStub(arg1)
+	}
+}
+
+func (fake *FakeLocalParticipant) SetRemoveReasonCallCount() int {
+	fake.setRemoveReasonMutex.RLock()
+	defer fake.setRemoveReasonMutex.RUnlock()
+	return len(fake.setRemoveReasonArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) SetRemoveReasonCalls(stub func(livekit.DisconnectReason)) {
+	fake.setRemoveReasonMutex.Lock()
+	defer fake.setRemoveReasonMutex.Unlock()
+	fake.SetRemoveReasonStub = stub
+}
+
+func (fake *FakeLocalParticipant) SetRemoveReasonArgsForCall(i int) livekit.DisconnectReason {
+	fake.setRemoveReasonMutex.RLock()
+	defer fake.setRemoveReasonMutex.RUnlock()
+	argsForCall := fake.setRemoveReasonArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeLocalParticipant) SetResponseSink(arg1 routing.MessageSink) {
 	fake.setResponseSinkMutex.Lock()
 	fake.setResponseSinkArgsForCall = append(fake.setResponseSinkArgsForCall, struct {
@@ -5501,6 +6024,38 @@ func (fake *FakeLocalParticipant) SetSubscriberChannelCapacityArgsForCall(i int)
 	return argsForCall.arg1
 }
 
+func (fake *FakeLocalParticipant) SetSubscriberMaxChannelCapacity(arg1 int64) {
+	fake.setSubscriberMaxChannelCapacityMutex.Lock()
+	fake.setSubscriberMaxChannelCapacityArgsForCall = append(fake.setSubscriberMaxChannelCapacityArgsForCall, struct {
+		arg1 int64
+	}{arg1})
+	stub := fake.SetSubscriberMaxChannelCapacityStub
+	fake.recordInvocation("SetSubscriberMaxChannelCapacity", []interface{}{arg1})
+	fake.setSubscriberMaxChannelCapacityMutex.Unlock()
+	if stub != nil {
+		fake.SetSubscriberMaxChannelCapacityStub(arg1)
+	}
+}
+
+func (fake *FakeLocalParticipant) SetSubscriberMaxChannelCapacityCallCount() int {
+	fake.setSubscriberMaxChannelCapacityMutex.RLock()
+	defer fake.setSubscriberMaxChannelCapacityMutex.RUnlock()
+	return len(fake.setSubscriberMaxChannelCapacityArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) SetSubscriberMaxChannelCapacityCalls(stub func(int64)) {
+	fake.setSubscriberMaxChannelCapacityMutex.Lock()
+	defer fake.setSubscriberMaxChannelCapacityMutex.Unlock()
+	fake.SetSubscriberMaxChannelCapacityStub = stub
+}
+
+func (fake *FakeLocalParticipant) SetSubscriberMaxChannelCapacityArgsForCall(i int) int64 {
+	fake.setSubscriberMaxChannelCapacityMutex.RLock()
+	defer fake.setSubscriberMaxChannelCapacityMutex.RUnlock()
+	argsForCall := fake.setSubscriberMaxChannelCapacityArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeLocalParticipant) SetTrackMuted(arg1 livekit.TrackID, arg2 bool, arg3 bool) *livekit.TrackInfo {
 	fake.setTrackMutedMutex.Lock()
 	ret, specificReturn := fake.setTrackMutedReturnsOnCall[len(fake.setTrackMutedArgsForCall)]
@@ -5564,6 +6119,69 @@ func (fake *FakeLocalParticipant) SetTrackMutedReturnsOnCall(i int, result1 *liv
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) SimulateTransportFault(arg1 livekit.SignalTarget, arg2 types.TransportFault, arg3 time.Duration) error {
+	fake.simulateTransportFaultMutex.Lock()
+	ret, specificReturn := fake.simulateTransportFaultReturnsOnCall[len(fake.simulateTransportFaultArgsForCall)]
+	fake.simulateTransportFaultArgsForCall = append(fake.simulateTransportFaultArgsForCall, struct {
+		arg1 livekit.SignalTarget
+		arg2 types.TransportFault
+		arg3 time.Duration
+	}{arg1, arg2, arg3})
+	stub := fake.SimulateTransportFaultStub
+	fakeReturns := fake.simulateTransportFaultReturns
+	fake.recordInvocation("SimulateTransportFault", []interface{}{arg1, arg2, arg3})
+	fake.simulateTransportFaultMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) SimulateTransportFaultCallCount() int {
+	fake.simulateTransportFaultMutex.RLock()
+	defer fake.simulateTransportFaultMutex.RUnlock()
+	return len(fake.simulateTransportFaultArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) SimulateTransportFaultCalls(stub func(livekit.SignalTarget, types.TransportFault, time.Duration) error) {
+	fake.simulateTransportFaultMutex.Lock()
+	defer fake.simulateTransportFaultMutex.Unlock()
+	fake.SimulateTransportFaultStub = stub
+}
+
+func (fake *FakeLocalParticipant) SimulateTransportFaultArgsForCall(i int) (livekit.SignalTarget, types.TransportFault, time.Duration) {
+	fake.simulateTransportFaultMutex.RLock()
+	defer fake.simulateTransportFaultMutex.RUnlock()
+	argsForCall := fake.simulateTransportFaultArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeLocalParticipant) SimulateTransportFaultReturns(result1 error) {
+	fake.simulateTransportFaultMutex.Lock()
+	defer fake.simulateTransportFaultMutex.Unlock()
+	fake.SimulateTransportFaultStub = nil
+	fake.simulateTransportFaultReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) SimulateTransportFaultReturnsOnCall(i int, result1 error) {
+	fake.simulateTransportFaultMutex.Lock()
+	defer fake.simulateTransportFaultMutex.Unlock()
+	fake.SimulateTransportFaultStub = nil
+	if fake.simulateTransportFaultReturnsOnCall == nil {
+		fake.simulateTransportFaultReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.simulateTransportFaultReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) State() livekit.ParticipantInfo_State {
 	fake.stateMutex.Lock()
 	ret, specificReturn := fake.stateReturnsOnCall[len(fake.stateArgsForCall)]
@@ -6124,6 +6742,68 @@ func (fake *FakeLocalParticipant) UnsubscribeFromTrackArgsForCall(i int) livekit
 	return argsForCall.arg1
 }
 
+func (fake *FakeLocalParticipant) UpdateAdminSubscriptionPermission(arg1 *livekit.SubscriptionPermission, arg2 func(participantID livekit.ParticipantID) types.LocalParticipant) error {
+	fake.updateAdminSubscriptionPermissionMutex.Lock()
+	ret, specificReturn := fake.updateAdminSubscriptionPermissionReturnsOnCall[len(fake.updateAdminSubscriptionPermissionArgsForCall)]
+	fake.updateAdminSubscriptionPermissionArgsForCall = append(fake.updateAdminSubscriptionPermissionArgsForCall, struct {
+		arg1 *livekit.SubscriptionPermission
+		arg2 func(participantID livekit.ParticipantID) types.LocalParticipant
+	}{arg1, arg2})
+	stub := fake.UpdateAdminSubscriptionPermissionStub
+	fakeReturns := fake.updateAdminSubscriptionPermissionReturns
+	fake.recordInvocation("UpdateAdminSubscriptionPermission", []interface{}{arg1, arg2})
+	fake.updateAdminSubscriptionPermissionMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) UpdateAdminSubscriptionPermissionCallCount() int {
+	fake.updateAdminSubscriptionPermissionMutex.RLock()
+	defer fake.updateAdminSubscriptionPermissionMutex.RUnlock()
+	return len(fake.updateAdminSubscriptionPermissionArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) UpdateAdminSubscriptionPermissionCalls(stub func(*livekit.SubscriptionPermission, func(participantID livekit.ParticipantID) types.LocalParticipant) error) {
+	fake.updateAdminSubscriptionPermissionMutex.Lock()
+	defer fake.updateAdminSubscriptionPermissionMutex.Unlock()
+	fake.UpdateAdminSubscriptionPermissionStub = stub
+}
+
+func (fake *FakeLocalParticipant) UpdateAdminSubscriptionPermissionArgsForCall(i int) (*livekit.SubscriptionPermission, func(participantID livekit.ParticipantID) types.LocalParticipant) {
+	fake.updateAdminSubscriptionPermissionMutex.RLock()
+	defer fake.updateAdminSubscriptionPermissionMutex.RUnlock()
+	argsForCall := fake.updateAdminSubscriptionPermissionArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeLocalParticipant) UpdateAdminSubscriptionPermissionReturns(result1 error) {
+	fake.updateAdminSubscriptionPermissionMutex.Lock()
+	defer fake.updateAdminSubscriptionPermissionMutex.Unlock()
+	fake.UpdateAdminSubscriptionPermissionStub = nil
+	fake.updateAdminSubscriptionPermissionReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) UpdateAdminSubscriptionPermissionReturnsOnCall(i int, result1 error) {
+	fake.updateAdminSubscriptionPermissionMutex.Lock()
+	defer fake.updateAdminSubscriptionPermissionMutex.Unlock()
+	fake.UpdateAdminSubscriptionPermissionStub = nil
+	if fake.updateAdminSubscriptionPermissionReturnsOnCall == nil {
+		fake.updateAdminSubscriptionPermissionReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.updateAdminSubscriptionPermissionReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) UpdateAudioTrack(arg1 *livekit.UpdateLocalAudioTrack) error {
 	fake.updateAudioTrackMutex.Lock()
 	ret, specificReturn := fake.updateAudioTrackReturnsOnCall[len(fake.updateAudioTrackArgsForCall)]
@@ -6144,6 +6824,8 @@ func (fake *FakeLocalParticipant) UpdateAudioTrack(arg1 *livekit.UpdateLocalAudi
 }
 
 func (fake *FakeLocalParticipant) UpdateAudioTrackCallCount() int {
+	fake.updateAdminSubscriptionPermissionMutex.RLock()
+	defer fake.updateAdminSubscriptionPermissionMutex.RUnlock()
 	fake.updateAudioTrackMutex.RLock()
 	defer fake.updateAudioTrackMutex.RUnlock()
 	return len(fake.updateAudioTrackArgsForCall)
@@ -6772,8 +7454,18 @@ func (fake *FakeLocalParticipant) Invocations() map[string][][]interface{} {
 	defer fake.getClientInfoMutex.RUnlock()
 	fake.getConnectionQualityMutex.RLock()
 	defer fake.getConnectionQualityMutex.RUnlock()
+	fake.getDataTrafficTotalsMutex.RLock()
+	defer fake.getDataTrafficTotalsMutex.RUnlock()
 	fake.getDisableSenderReportPassThroughMutex.RLock()
 	defer fake.getDisableSenderReportPassThroughMutex.RUnlock()
+	fake.getSenderReportClockMutex.RLock()
+	defer fake.getSenderReportClockMutex.RUnlock()
+	fake.getSenderReportClockSkewCompensationMutex.RLock()
+	defer fake.getSenderReportClockSkewCompensationMutex.RUnlock()
+	fake.getSenderReportSmoothingWindowMutex.RLock()
+	defer fake.getSenderReportSmoothingWindowMutex.RUnlock()
+	fake.getHeaderExtensionsMutex.RLock()
+	defer fake.getHeaderExtensionsMutex.RUnlock()
 	fake.getICEConnectionDetailsMutex.RLock()
 	defer fake.getICEConnectionDetailsMutex.RUnlock()
 	fake.getLoggerMutex.RLock()
@@ -6790,6 +7482,8 @@ func (fake *FakeLocalParticipant) Invocations() map[string][][]interface{} {
 	defer fake.getPublishedTracksMutex.RUnlock()
 	fake.getSubscribedParticipantsMutex.RLock()
 	defer fake.getSubscribedParticipantsMutex.RUnlock()
+	fake.getSubscribedQualityLimitsMutex.RLock()
+	defer fake.getSubscribedQualityLimitsMutex.RUnlock()
 	fake.getSubscribedTracksMutex.RLock()
 	defer fake.getSubscribedTracksMutex.RUnlock()
 	fake.getTrailerMutex.RLock()
@@ -6902,6 +7596,8 @@ func (fake *FakeLocalParticipant) Invocations() map[string][][]interface{} {
 	defer fake.setNameMutex.RUnlock()
 	fake.setPermissionMutex.RLock()
 	defer fake.setPermissionMutex.RUnlock()
+	fake.setRemoveReasonMutex.RLock()
+	defer fake.setRemoveReasonMutex.RUnlock()
 	fake.setResponseSinkMutex.RLock()
 	defer fake.setResponseSinkMutex.RUnlock()
 	fake.setSignalSourceValidMutex.RLock()
@@ -6910,8 +7606,12 @@ func (fake *FakeLocalParticipant) Invocations() map[string][][]interface{} {
 	defer fake.setSubscriberAllowPauseMutex.RUnlock()
 	fake.setSubscriberChannelCapacityMutex.RLock()
 	defer fake.setSubscriberChannelCapacityMutex.RUnlock()
+	fake.setSubscriberMaxChannelCapacityMutex.RLock()
+	defer fake.setSubscriberMaxChannelCapacityMutex.RUnlock()
 	fake.setTrackMutedMutex.RLock()
 	defer fake.setTrackMutedMutex.RUnlock()
+	fake.simulateTransportFaultMutex.RLock()
+	defer fake.simulateTransportFaultMutex.RUnlock()
 	fake.stateMutex.RLock()
 	defer fake.stateMutex.RUnlock()
 	fake.stopAndGetSubscribedTracksForwarderStateMutex.RLock()
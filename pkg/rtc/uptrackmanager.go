@@ -51,6 +51,9 @@ type UpTrackManager struct {
 	subscriptionPermission *livekit.SubscriptionPermission
 	// subscriber permission for published tracks
 	subscriberPermissions map[livekit.ParticipantIdentity]*livekit.TrackPermission // subscriberIdentity => *livekit.TrackPermission
+	// permissions set by an admin, restrict the publisher's own and cannot be changed by the publisher
+	adminSubscriptionPermission *livekit.SubscriptionPermission
+	adminSubscriberPermissions  map[livekit.ParticipantIdentity]*livekit.TrackPermission
 
 	lock sync.RWMutex
 
@@ -194,20 +197,14 @@ func (u *UpTrackManager) UpdateSubscriptionPermission(
 		"permissions", logger.Proto(u.subscriptionPermission),
 		"version", u.subscriptionPermissionVersion,
 	)
-	if err := u.parseSubscriptionPermissionsLocked(subscriptionPermission, func(pID livekit.ParticipantID) types.LocalParticipant {
-		u.lock.Unlock()
-		var p types.LocalParticipant
-		if resolverBySid != nil {
-			p = resolverBySid(pID)
-		}
-		u.lock.Lock()
-		return p
-	}); err != nil {
+	subscriberPermissions, err := u.parseSubscriptionPermissionsLocked(subscriptionPermission, u.unlockedResolver(resolverBySid))
+	if err != nil {
 		// when failed, do not override previous permissions
 		u.params.Logger.Errorw("failed updating subscription permission", err)
 		u.lock.Unlock()
 		return err
 	}
+	u.subscriberPermissions = subscriberPermissions
 	u.lock.Unlock()
 
 	u.maybeRevokeSubscriptions()
@@ -226,6 +223,40 @@ func (u *UpTrackManager) SubscriptionPermission() (*livekit.SubscriptionPermissi
 	return u.subscriptionPermission, u.subscriptionPermissionVersion.Load()
 }
 
+// UpdateAdminSubscriptionPermission sets permissions that apply on top of the ones set by the publisher,
+// a subscriber needs to be allowed by both. A nil permission removes the admin restrictions
+func (u *UpTrackManager) UpdateAdminSubscriptionPermission(
+	subscriptionPermission *livekit.SubscriptionPermission,
+	resolverBySid func(participantID livekit.ParticipantID) types.LocalParticipant,
+) error {
+	u.lock.Lock()
+	var subscriberPermissions map[livekit.ParticipantIdentity]*livekit.TrackPermission
+	if subscriptionPermission != nil {
+		var err error
+		subscriberPermissions, err = u.parseSubscriptionPermissionsLocked(subscriptionPermission, u.unlockedResolver(resolverBySid))
+		if err != nil {
+			u.params.Logger.Errorw("failed updating admin subscription permission", err)
+			u.lock.Unlock()
+			return err
+		}
+	}
+	u.params.Logger.Debugw("updating admin subscription permission", "permissions", logger.Proto(subscriptionPermission))
+	u.adminSubscriptionPermission = subscriptionPermission
+	u.adminSubscriberPermissions = subscriberPermissions
+	u.lock.Unlock()
+
+	u.maybeRevokeSubscriptions()
+
+	return nil
+}
+
+func (u *UpTrackManager) AdminSubscriptionPermission() *livekit.SubscriptionPermission {
+	u.lock.RLock()
+	defer u.lock.RUnlock()
+
+	return u.adminSubscriptionPermission
+}
+
 func (u *UpTrackManager) HasPermission(trackID livekit.TrackID, subIdentity livekit.ParticipantIdentity) bool {
 	u.lock.RLock()
 	defer u.lock.RUnlock()
@@ -288,17 +319,31 @@ func (u *UpTrackManager) getPublishedTrackLocked(trackID livekit.TrackID) types.
 	return u.publishedTracks[trackID]
 }
 
+// resolves subscribers without holding the lock, as the resolver may call back into the participant
+func (u *UpTrackManager) unlockedResolver(
+	resolverBySid func(participantID livekit.ParticipantID) types.LocalParticipant,
+) func(participantID livekit.ParticipantID) types.LocalParticipant {
+	return func(pID livekit.ParticipantID) types.LocalParticipant {
+		u.lock.Unlock()
+		var p types.LocalParticipant
+		if resolverBySid != nil {
+			p = resolverBySid(pID)
+		}
+		u.lock.Lock()
+		return p
+	}
+}
+
 func (u *UpTrackManager) parseSubscriptionPermissionsLocked(
 	subscriptionPermission *livekit.SubscriptionPermission,
 	resolver func(participantID livekit.ParticipantID) types.LocalParticipant,
-) error {
+) (map[livekit.ParticipantIdentity]*livekit.TrackPermission, error) {
 	// every update overrides the existing
 
 	// all_participants takes precedence
 	if subscriptionPermission.AllParticipants {
 		// everything is allowed, nothing else to do
-		u.subscriberPermissions = nil
-		return nil
+		return nil, nil
 	}
 
 	// per participant permissions
@@ -307,7 +352,7 @@ func (u *UpTrackManager) parseSubscriptionPermissionsLocked(
 		subscriberIdentity := livekit.ParticipantIdentity(trackPerms.ParticipantIdentity)
 		if subscriberIdentity == "" {
 			if trackPerms.ParticipantSid == "" {
-				return ErrSubscriptionPermissionNeedsId
+				return nil, ErrSubscriptionPermissionNeedsId
 			}
 
 			sub := resolver(livekit.ParticipantID(trackPerms.ParticipantSid))
@@ -332,17 +377,24 @@ func (u *UpTrackManager) parseSubscriptionPermissionsLocked(
 		subscriberPermissions[subscriberIdentity] = trackPerms
 	}
 
-	u.subscriberPermissions = subscriberPermissions
-
-	return nil
+	return subscriberPermissions, nil
 }
 
 func (u *UpTrackManager) hasPermissionLocked(trackID livekit.TrackID, subscriberIdentity livekit.ParticipantIdentity) bool {
-	if u.subscriberPermissions == nil {
+	return isPermitted(u.subscriberPermissions, trackID, subscriberIdentity) &&
+		isPermitted(u.adminSubscriberPermissions, trackID, subscriberIdentity)
+}
+
+func isPermitted(
+	subscriberPermissions map[livekit.ParticipantIdentity]*livekit.TrackPermission,
+	trackID livekit.TrackID,
+	subscriberIdentity livekit.ParticipantIdentity,
+) bool {
+	if subscriberPermissions == nil {
 		return true
 	}
 
-	perms, ok := u.subscriberPermissions[subscriberIdentity]
+	perms, ok := subscriberPermissions[subscriberIdentity]
 	if !ok {
 		return false
 	}
@@ -363,22 +415,18 @@ func (u *UpTrackManager) hasPermissionLocked(trackID livekit.TrackID, subscriber
 // returns a list of participants that are allowed to subscribe to the track. if nil is returned, it means everyone is
 // allowed to subscribe to this track
 func (u *UpTrackManager) getAllowedSubscribersLocked(trackID livekit.TrackID) []livekit.ParticipantIdentity {
-	if u.subscriberPermissions == nil {
+	subscriberPermissions := u.subscriberPermissions
+	if subscriberPermissions == nil {
+		subscriberPermissions = u.adminSubscriberPermissions
+	}
+	if subscriberPermissions == nil {
 		return nil
 	}
 
 	allowed := make([]livekit.ParticipantIdentity, 0)
-	for subscriberIdentity, perms := range u.subscriberPermissions {
-		if perms.AllTracks {
+	for subscriberIdentity := range subscriberPermissions {
+		if u.hasPermissionLocked(trackID, subscriberIdentity) {
 			allowed = append(allowed, subscriberIdentity)
-			continue
-		}
-
-		for _, sid := range perms.TrackSids {
-			if livekit.TrackID(sid) == trackID {
-				allowed = append(allowed, subscriberIdentity)
-				break
-			}
 		}
 	}
 
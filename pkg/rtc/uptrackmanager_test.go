@@ -327,3 +327,53 @@ func TestSubscriptionPermission(t *testing.T) {
 		require.False(t, um.hasPermissionLocked("watch", "p3"))
 	})
 }
+
+func TestAdminSubscriptionPermission(t *testing.T) {
+	t.Run("restricts publisher permission", func(t *testing.T) {
+		um := NewUpTrackManager(defaultUptrackManagerParams)
+		vg := utils.NewDefaultTimedVersionGenerator()
+
+		tra := &typesfakes.FakeMediaTrack{}
+		tra.IDReturns("audio")
+		um.publishedTracks["audio"] = tra
+
+		err := um.UpdateAdminSubscriptionPermission(&livekit.SubscriptionPermission{
+			TrackPermissions: []*livekit.TrackPermission{
+				{
+					ParticipantIdentity: "p1",
+					AllTracks:           true,
+				},
+			},
+		}, nil)
+		require.NoError(t, err)
+		require.True(t, um.hasPermissionLocked("audio", "p1"))
+		require.False(t, um.hasPermissionLocked("audio", "p2"))
+		require.Equal(t, 1, tra.RevokeDisallowedSubscribersCallCount())
+		require.ElementsMatch(t, []livekit.ParticipantIdentity{"p1"}, tra.RevokeDisallowedSubscribersArgsForCall(0))
+
+		// publisher allowing everyone does not lift the admin restriction
+		um.UpdateSubscriptionPermission(&livekit.SubscriptionPermission{AllParticipants: true}, vg.Next(), nil)
+		require.True(t, um.hasPermissionLocked("audio", "p1"))
+		require.False(t, um.hasPermissionLocked("audio", "p2"))
+		require.NotNil(t, um.AdminSubscriptionPermission())
+
+		// both need to allow
+		um.UpdateSubscriptionPermission(&livekit.SubscriptionPermission{
+			TrackPermissions: []*livekit.TrackPermission{
+				{
+					ParticipantIdentity: "p2",
+					AllTracks:           true,
+				},
+			},
+		}, vg.Next(), nil)
+		require.False(t, um.hasPermissionLocked("audio", "p1"))
+		require.False(t, um.hasPermissionLocked("audio", "p2"))
+		require.Empty(t, um.getAllowedSubscribersLocked("audio"))
+
+		// removing the admin restriction leaves the publisher permission
+		require.NoError(t, um.UpdateAdminSubscriptionPermission(nil, nil))
+		require.Nil(t, um.AdminSubscriptionPermission())
+		require.False(t, um.hasPermissionLocked("audio", "p1"))
+		require.True(t, um.hasPermissionLocked("audio", "p2"))
+	})
+}
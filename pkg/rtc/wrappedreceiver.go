@@ -26,6 +26,7 @@ import (
 	"github.com/livekit/protocol/logger"
 
 	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
 )
 
 // wrapper around WebRTC receiver, overriding its ID
@@ -324,3 +325,10 @@ func (d *DummyReceiver) GetTrackStats() *livekit.RTPStats {
 	}
 	return nil
 }
+
+func (d *DummyReceiver) GetTrackStatsWithLayers() *buffer.RTPStatsWithLayers {
+	if r, ok := d.receiver.Load().(sfu.TrackReceiver); ok {
+		return r.GetTrackStatsWithLayers()
+	}
+	return nil
+}
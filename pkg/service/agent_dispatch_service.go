@@ -17,6 +17,9 @@ package service
 import (
 	"context"
 
+	"github.com/twitchtv/twirp"
+
+	"github.com/livekit/livekit-server/pkg/agent"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/rpc"
 )
@@ -38,6 +41,9 @@ func (ag *AgentDispatchService) CreateDispatch(ctx context.Context, req *livekit
 	if err != nil {
 		return nil, twirpAuthError(err)
 	}
+	if _, _, err := agent.ParseAgentName(req.AgentName); err != nil {
+		return nil, twirp.InvalidArgumentError("agent_name", err.Error())
+	}
 
 	return ag.agentDispatchClient.CreateDispatch(ctx, ag.topicFormatter.RoomTopic(ctx, livekit.RoomName(req.Room)), req)
 }
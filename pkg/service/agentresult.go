@@ -0,0 +1,131 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/agent"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	agentJobResultPath = "/agent/result"
+
+	agentJobResultMethod = "ReportAgentJobResult"
+)
+
+// AgentJobResultHandler lets agents report the result of their jobs, through the node hosting the room.
+// The result is stored with the dispatch of the job and sent out in a job_finished webhook.
+type AgentJobResultHandler struct {
+	roomAPI *RoomAPI
+}
+
+type agentJobResultRequest struct {
+	Room string `json:"room"`
+	agent.JobResult
+}
+
+type agentJobResultCall struct {
+	// identity of the reporting agent, empty for room admins
+	Identity string           `json:"identity,omitempty"`
+	Result   *agent.JobResult `json:"result"`
+}
+
+func NewAgentJobResultHandler(roomManager *RoomManager) *AgentJobResultHandler {
+	h := &AgentJobResultHandler{
+		roomAPI: roomManager.RoomAPI(),
+	}
+	h.roomAPI.Handle(agentJobResultMethod, h.handleReportAgentJobResult)
+	return h
+}
+
+// ReportAgentJobResult records the result of a job in roomName. When identity is set, the job must be assigned to it
+func (h *AgentJobResultHandler) ReportAgentJobResult(
+	ctx context.Context,
+	roomName livekit.RoomName,
+	identity livekit.ParticipantIdentity,
+	result *agent.JobResult,
+) (*agent.JobResult, error) {
+	if err := result.Validate(); err != nil {
+		return nil, psrpc.NewError(psrpc.InvalidArgument, err)
+	}
+
+	res := &agent.JobResult{}
+	if err := h.roomAPI.CallRoom(ctx, roomName, agentJobResultMethod, &agentJobResultCall{Identity: string(identity), Result: result}, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (h *AgentJobResultHandler) handleReportAgentJobResult(ctx context.Context, room *rtc.Room, _ types.LocalParticipant, body json.RawMessage) (any, error) {
+	var req agentJobResultCall
+	if err := json.Unmarshal(body, &req); err != nil || req.Result == nil {
+		return nil, psrpc.NewErrorf(psrpc.MalformedRequest, "invalid job result")
+	}
+
+	err := room.ReportAgentJobResult(ctx, livekit.ParticipantIdentity(req.Identity), req.Result)
+	switch {
+	case errors.Is(err, agent.ErrJobNotFound):
+		return nil, psrpc.NewError(psrpc.NotFound, err)
+	case errors.Is(err, agent.ErrJobResultMissingJobID), errors.Is(err, agent.ErrJobResultTooLarge):
+		return nil, psrpc.NewError(psrpc.InvalidArgument, err)
+	case err != nil:
+		return nil, err
+	}
+	return req.Result, nil
+}
+
+// ServeHTTP handles POST /agent/result with a json body of the room and the job result.
+// The token must be the one the agent joined with, or have roomAdmin permission for the room
+func (h *AgentJobResultHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req agentJobResultRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&req); err != nil {
+		handleError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	roomName := livekit.RoomName(req.Room)
+	var identity livekit.ParticipantIdentity
+	if err := EnsureAdminPermission(r.Context(), roomName); err != nil {
+		joinRoom, joinErr := EnsureJoinPermission(r.Context())
+		if joinErr != nil || joinRoom != roomName {
+			handleError(w, r, http.StatusUnauthorized, err)
+			return
+		}
+		identity = livekit.ParticipantIdentity(GetGrants(r.Context()).Identity)
+	}
+
+	result, err := h.ReportAgentJobResult(r.Context(), roomName, identity, &req.JobResult)
+	if err != nil {
+		handleError(w, r, roomAPIStatus(err), err, "room", roomName, "jobID", req.JobID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
@@ -17,16 +17,19 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math/rand"
 	"net/http"
 	"slices"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"golang.org/x/exp/maps"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/emptypb"
 
 	"github.com/livekit/livekit-server/pkg/agent"
@@ -34,11 +37,14 @@ import (
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/rtc"
 	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 	"github.com/livekit/livekit-server/version"
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/protocol/tracer"
 	"github.com/livekit/psrpc"
 )
 
@@ -93,6 +99,11 @@ type AgentHandler struct {
 	jobToWorker map[string]*agent.Worker
 	keyProvider auth.KeyProvider
 
+	// priority of the jobs assigned on this node, matched once when the job is requested
+	jobPriorities map[string]int32
+	// jobs terminated to make room for higher priority jobs, assigned again once a worker has capacity
+	preemptedJobs []*preemptedJob
+
 	namespaceWorkers  map[workerKey][]*agent.Worker
 	roomKeyCount      int
 	publisherKeyCount int
@@ -101,6 +112,31 @@ type AgentHandler struct {
 
 	roomTopic      string
 	publisherTopic string
+
+	conf      config.AgentsConfig
+	telemetry telemetry.TelemetryService
+
+	balancer           agent.WorkerBalancer
+	namespaceBalancers map[string]agent.WorkerBalancer
+
+	// where workers connected to this node run, for dispatch placement
+	region     string
+	nodeLabels map[string]string
+}
+
+const (
+	// EventAgentJobPreempted is sent when a job is terminated to make room for a higher priority job
+	EventAgentJobPreempted = "agent_job_preempted"
+
+	// preempted jobs that could not be assigned again within this time are dropped
+	preemptedJobTimeout = time.Minute
+
+	outsidePlacementAffinity = 0.01
+)
+
+type preemptedJob struct {
+	job         *livekit.Job
+	preemptedAt time.Time
 }
 
 type workerKey struct {
@@ -113,6 +149,7 @@ func NewAgentService(conf *config.Config,
 	currentNode routing.LocalNode,
 	bus psrpc.MessageBus,
 	keyProvider auth.KeyProvider,
+	ts telemetry.TelemetryService,
 ) (*AgentService, error) {
 	s := &AgentService{}
 
@@ -142,7 +179,34 @@ func NewAgentService(conf *config.Config,
 		serverInfo,
 		agent.RoomAgentTopic,
 		agent.PublisherAgentTopic,
+		conf.Agents,
+		ts,
 	)
+
+	s.region = conf.Region
+	s.nodeLabels = conf.NodeLabels
+
+	// scoring requests are signed like webhooks
+	apiKey := conf.WebHook.APIKey
+	apiSecret := keyProvider.GetSecret(apiKey)
+	if s.balancer, err = agent.NewWorkerBalancer(conf.Agents.LoadBalancing, apiKey, apiSecret); err != nil {
+		return nil, err
+	}
+	for ns, c := range conf.Agents.NamespaceLoadBalancing {
+		if s.namespaceBalancers[ns], err = agent.NewWorkerBalancer(c, apiKey, apiSecret); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.Agents.SimulatedWorker.Enabled {
+		keys := maps.Keys(conf.Keys)
+		slices.Sort(keys)
+		if len(keys) == 0 {
+			return nil, ErrSimulatedAgentMissingAPIKey
+		}
+		s.logger.Infow("starting simulated agent workers", "agentName", conf.Agents.SimulatedWorker.AgentName)
+		s.StartSimulatedWorkers(conf.Agents.SimulatedWorker, keys[0])
+	}
 	return s, nil
 }
 
@@ -159,17 +223,25 @@ func NewAgentHandler(
 	serverInfo *livekit.ServerInfo,
 	roomTopic string,
 	publisherTopic string,
+	conf config.AgentsConfig,
+	ts telemetry.TelemetryService,
 ) *AgentHandler {
 	return &AgentHandler{
 		agentServer:      agentServer,
 		logger:           logger,
 		workers:          make(map[string]*agent.Worker),
 		jobToWorker:      make(map[string]*agent.Worker),
+		jobPriorities:    make(map[string]int32),
 		namespaceWorkers: make(map[workerKey][]*agent.Worker),
 		serverInfo:       serverInfo,
 		keyProvider:      keyProvider,
 		roomTopic:        roomTopic,
 		publisherTopic:   publisherTopic,
+		conf:             conf,
+		telemetry:        ts,
+
+		balancer:           agent.LoadWeightedBalancer{},
+		namespaceBalancers: make(map[string]agent.WorkerBalancer),
 	}
 }
 
@@ -257,6 +329,8 @@ func (h *AgentHandler) HandleWorkerRegister(w *agent.Worker) {
 	h.namespaceWorkers[key] = append(workers, w)
 	h.mu.Unlock()
 
+	go h.requeuePreemptedJobs()
+
 	if created {
 		h.logger.Infow("initial worker registered", "namespace", w.Namespace(), "jobType", w.JobType(), "agentName", w.AgentName())
 		err := h.agentServer.PublishWorkerRegistered(context.Background(), agent.DefaultHandlerNamespace, &emptypb.Empty{})
@@ -312,28 +386,71 @@ func (h *AgentHandler) HandleWorkerDeregister(w *agent.Worker) {
 }
 
 func (h *AgentHandler) HandleWorkerJobStatus(w *agent.Worker, status *livekit.UpdateJobStatus) {
+	if status.Status == livekit.JobStatus_JS_FAILED {
+		prometheus.RecordAgentJobFailure("job_failed")
+	}
 	if agent.JobStatusIsEnded(status.Status) {
 		h.mu.Lock()
 		h.deregisterJob(status.JobId)
 		h.mu.Unlock()
+
+		go h.requeuePreemptedJobs()
+	}
+}
+
+func (h *AgentHandler) HandleWorkerStatus(w *agent.Worker, status *livekit.UpdateWorkerStatus) {
+	if w.Status() == livekit.WorkerStatus_WS_AVAILABLE && w.Load() < 1 {
+		go h.requeuePreemptedJobs()
 	}
 }
 
 func (h *AgentHandler) deregisterJob(jobID string) {
 	h.agentServer.DeregisterJobTerminateTopic(jobID)
 
+	if w, ok := h.jobToWorker[jobID]; ok {
+		prometheus.SubAgentJob(w.Namespace())
+	}
 	delete(h.jobToWorker, jobID)
+	delete(h.jobPriorities, jobID)
 
 	// TODO update dispatch state
 }
 
 func (h *AgentHandler) JobRequest(ctx context.Context, job *livekit.Job) (*rpc.JobRequestResponse, error) {
+	ctx, span := tracer.Start(ctx, "AgentHandler.JobRequest")
+	defer span.End()
+
+	agentName, versions, err := agent.ParseAgentName(job.AgentName)
+	if err != nil {
+		span.RecordError(err)
+		return nil, psrpc.NewError(psrpc.InvalidArgument, err)
+	}
+	if !agent.PlacementMatches(h.conf.Placements[agentName], h.region, h.nodeLabels) {
+		err = fmt.Errorf("%w: %s", agent.ErrNoMatchingAgentWorkers, agentName)
+		span.RecordError(err)
+		prometheus.RecordAgentJobFailure("placement")
+		return nil, psrpc.NewError(psrpc.FailedPrecondition, err)
+	}
+	// workers only know the name they registered with
+	job.AgentName = agentName
+
 	key := workerKey{job.AgentName, job.Namespace, job.Type}
+	priority := h.jobPriority(job)
+	preferred := versions.Pick()
 	attempted := make(map[*agent.Worker]struct{})
 	for {
-		selected, err := h.selectWorkerWeightedByLoad(key, attempted)
+		selected, err := h.selectWorker(ctx, key, job, preferred, attempted)
+		if err != nil && len(versions) > 1 {
+			// fall back to the other versions of a split when the picked one has no capacity
+			selected, err = h.selectWorker(ctx, key, job, versions, attempted)
+		}
 		if err != nil {
-			return nil, psrpc.NewError(psrpc.DeadlineExceeded, err)
+			// when at capacity, make room by terminating a lower priority job
+			if selected = h.preemptJob(ctx, key, versions, job, priority, attempted); selected == nil {
+				span.RecordError(err)
+				prometheus.RecordAgentJobFailure("no_worker")
+				return nil, psrpc.NewError(psrpc.DeadlineExceeded, err)
+			}
 		}
 
 		attempted[selected] = struct{}{}
@@ -343,6 +460,7 @@ func (h *AgentHandler) JobRequest(ctx context.Context, job *livekit.Job) (*rpc.J
 			"namespace", job.Namespace,
 			"agentName", job.AgentName,
 			"workerID", selected.ID(),
+			"version", selected.Version(),
 		}
 		if job.Room != nil {
 			values = append(values, "room", job.Room.Name, "roomID", job.Room.Sid)
@@ -354,13 +472,18 @@ func (h *AgentHandler) JobRequest(ctx context.Context, job *livekit.Job) (*rpc.J
 		err = selected.AssignJob(ctx, job)
 		if err != nil {
 			if errors.Is(err, agent.ErrWorkerNotAvailable) {
+				prometheus.RecordAgentJobRetry()
 				continue // Try another worker
 			}
+			span.RecordError(err)
+			prometheus.RecordAgentJobFailure("assign")
 			return nil, err
 		}
 		h.mu.Lock()
 		h.jobToWorker[job.Id] = selected
+		h.jobPriorities[job.Id] = priority
 		h.mu.Unlock()
+		prometheus.AddAgentJob(job.Namespace)
 
 		err = h.agentServer.RegisterJobTerminateTopic(job.Id)
 		if err != nil {
@@ -374,13 +497,24 @@ func (h *AgentHandler) JobRequest(ctx context.Context, job *livekit.Job) (*rpc.J
 }
 
 func (h *AgentHandler) JobRequestAffinity(ctx context.Context, job *livekit.Job) float32 {
+	agentName, versions, err := agent.ParseAgentName(job.AgentName)
+	if err != nil {
+		return 0
+	}
+	if !agent.PlacementMatches(h.conf.Placements[agentName], h.region, h.nodeLabels) {
+		// claim below any node in the placement, so that without one the job fails with ErrNoMatchingAgentWorkers
+		return outsidePlacementAffinity
+	}
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	var affinity float32
 	var maxLoad float32
+	var canPreempt bool
+	priority := h.jobPriority(job)
 	for _, w := range h.workers {
-		if w.AgentName() != job.AgentName || w.Namespace() != job.Namespace || w.JobType() != job.Type {
+		if w.AgentName() != agentName || w.Namespace() != job.Namespace || w.JobType() != job.Type || !versions.Matches(w.Version()) {
 			continue
 		}
 
@@ -392,13 +526,27 @@ func (h *AgentHandler) JobRequestAffinity(ctx context.Context, job *livekit.Job)
 			} else if affinity == 0 {
 				affinity = 0.5
 			}
+		} else if !canPreempt {
+			for _, j := range w.RunningJobs() {
+				if h.jobPriorities[j.Id] < priority {
+					canPreempt = true
+					break
+				}
+			}
 		}
 	}
 
+	// prefer nodes with capacity over preempting jobs
+	if affinity == 0 && canPreempt {
+		affinity = 0.1
+	}
 	return affinity
 }
 
 func (h *AgentHandler) JobTerminate(ctx context.Context, req *rpc.JobTerminateRequest) (*rpc.JobTerminateResponse, error) {
+	_, span := tracer.Start(ctx, "AgentHandler.JobTerminate")
+	defer span.End()
+
 	h.mu.Lock()
 	w := h.jobToWorker[req.JobId]
 	h.mu.Unlock()
@@ -409,6 +557,7 @@ func (h *AgentHandler) JobTerminate(ctx context.Context, req *rpc.JobTerminateRe
 
 	state, err := w.TerminateJob(req.JobId, req.Reason)
 	if err != nil {
+		span.RecordError(err)
 		return nil, err
 	}
 
@@ -447,43 +596,132 @@ func (h *AgentHandler) DrainConnections(interval time.Duration) {
 	}
 }
 
-func (h *AgentHandler) selectWorkerWeightedByLoad(key workerKey, ignore map[*agent.Worker]struct{}) (*agent.Worker, error) {
+func (h *AgentHandler) jobPriority(job *livekit.Job) int32 {
+	for _, p := range h.conf.JobPriorities {
+		if p.AgentName != "" && p.AgentName != job.AgentName {
+			continue
+		}
+		if p.RoomPrefix != "" && !strings.HasPrefix(job.Room.GetName(), p.RoomPrefix) {
+			continue
+		}
+		return p.Priority
+	}
+	return 0
+}
+
+// preemptJob terminates the lowest priority job below the priority of job, and returns the worker it ran on
+func (h *AgentHandler) preemptJob(ctx context.Context, key workerKey, versions agent.VersionSelector, job *livekit.Job, priority int32, ignore map[*agent.Worker]struct{}) *agent.Worker {
 	h.mu.Lock()
-	defer h.mu.Unlock()
+	var preempted *livekit.Job
+	var worker *agent.Worker
+	lowest := priority
+	for _, w := range h.namespaceWorkers[key] {
+		if _, ok := ignore[w]; ok || !versions.Matches(w.Version()) {
+			continue
+		}
+		for _, j := range w.RunningJobs() {
+			if p := h.jobPriorities[j.Id]; p < lowest {
+				preempted, worker, lowest = j, w, p
+			}
+		}
+	}
+	h.mu.Unlock()
 
-	workers, ok := h.namespaceWorkers[key]
-	if !ok {
-		return nil, errors.New("no workers available")
+	if preempted == nil {
+		return nil
 	}
 
-	normalizeLoad := func(load float32) int {
-		if load >= 1 {
-			return 0
-		}
-		return int((1 - load) * 100)
+	h.logger.Infow("preempting agent job",
+		"jobID", preempted.Id,
+		"priority", lowest,
+		"preemptingJobID", job.Id,
+		"preemptingPriority", priority,
+		"workerID", worker.ID(),
+	)
+	state, err := worker.TerminateJob(preempted.Id, rpc.JobTerminateReason_TERINATION_REQUESTED)
+	if err != nil {
+		h.logger.Warnw("failed to preempt agent job", err, "jobID", preempted.Id)
+		return nil
 	}
 
-	normalizedLoads := make(map[*agent.Worker]int)
-	var availableSum int
-	for _, w := range workers {
-		if _, ok := ignore[w]; !ok && w.Status() == livekit.WorkerStatus_WS_AVAILABLE {
-			normalizedLoads[w] = normalizeLoad(w.Load())
-			availableSum += normalizedLoads[w]
+	// the job is assigned again once a worker has capacity
+	requeued := proto.Clone(preempted).(*livekit.Job)
+	requeued.State = nil
+
+	h.mu.Lock()
+	h.deregisterJob(preempted.Id)
+	h.preemptedJobs = append(h.preemptedJobs, &preemptedJob{job: requeued, preemptedAt: time.Now()})
+	h.mu.Unlock()
+
+	if h.telemetry != nil {
+		h.telemetry.NotifyEvent(ctx, &livekit.WebhookEvent{
+			Event: EventAgentJobPreempted,
+			Room:  preempted.Room,
+			Participant: &livekit.ParticipantInfo{
+				Identity: state.GetParticipantIdentity(),
+				Kind:     livekit.ParticipantInfo_AGENT,
+			},
+		})
+	}
+	return worker
+}
+
+// requeuePreemptedJobs assigns preempted jobs again, highest priority first, while workers have capacity
+func (h *AgentHandler) requeuePreemptedJobs() {
+	h.mu.Lock()
+	queued := h.preemptedJobs
+	h.preemptedJobs = nil
+	h.mu.Unlock()
+	if len(queued) == 0 {
+		return
+	}
+
+	slices.SortStableFunc(queued, func(a, b *preemptedJob) int {
+		return int(h.jobPriority(b.job)) - int(h.jobPriority(a.job))
+	})
+
+	var remaining []*preemptedJob
+	for _, pj := range queued {
+		if time.Since(pj.preemptedAt) > preemptedJobTimeout {
+			h.logger.Infow("dropping preempted agent job", "jobID", pj.job.Id)
+			continue
+		}
+		if _, err := h.JobRequest(context.Background(), pj.job); err != nil {
+			remaining = append(remaining, pj)
+			continue
 		}
+		h.logger.Infow("requeued preempted agent job", "jobID", pj.job.Id)
 	}
 
-	if availableSum == 0 {
-		return nil, errors.New("no workers with sufficient capacity")
+	if len(remaining) != 0 {
+		h.mu.Lock()
+		h.preemptedJobs = append(remaining, h.preemptedJobs...)
+		h.mu.Unlock()
 	}
+}
 
-	threshold := rand.Intn(availableSum)
-	var currentSum int
-	for w, load := range normalizedLoads {
-		currentSum += load
-		if currentSum >= threshold {
-			return w, nil
+// selectWorker chooses among the available workers for key with the balancer configured for its namespace
+func (h *AgentHandler) selectWorker(ctx context.Context, key workerKey, job *livekit.Job, versions agent.VersionSelector, ignore map[*agent.Worker]struct{}) (*agent.Worker, error) {
+	h.mu.Lock()
+	workers, ok := h.namespaceWorkers[key]
+	var available []*agent.Worker
+	for _, w := range workers {
+		if _, ok := ignore[w]; !ok && w.Status() == livekit.WorkerStatus_WS_AVAILABLE && w.Load() < 1 && versions.Matches(w.Version()) {
+			available = append(available, w)
 		}
 	}
+	h.mu.Unlock()
 
-	return nil, errors.New("no workers available")
+	if !ok {
+		return nil, errors.New("no workers available")
+	}
+	if len(available) == 0 {
+		return nil, agent.ErrNoWorkersWithCapacity
+	}
+
+	balancer := h.balancer
+	if b, ok := h.namespaceBalancers[key.namespace]; ok {
+		balancer = b
+	}
+	return balancer.SelectWorker(ctx, job, available)
 }
@@ -0,0 +1,133 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/frostbyte73/core"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/agent"
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// simulatedAgentConn is an in-process agent worker connection that accepts every job offered to it.
+// Jobs are only logged, no agent joins the room, so dispatch can be exercised without deploying agents.
+type simulatedAgentConn struct {
+	logger         logger.Logger
+	fuse           core.Fuse
+	workerMessages chan *livekit.WorkerMessage
+
+	mu   sync.Mutex
+	jobs map[string]*livekit.Job
+}
+
+var _ agent.SignalConn = (*simulatedAgentConn)(nil)
+
+// StartSimulatedWorkers connects a simulated room and publisher worker to the handler
+func (h *AgentHandler) StartSimulatedWorkers(conf config.AgentSimulatedWorkerConfig, apiKey string) {
+	ctx := WithGrants(context.Background(), &auth.ClaimGrants{Identity: "simulated-agent-worker"}, apiKey)
+
+	for _, jobType := range []livekit.JobType{livekit.JobType_JT_ROOM, livekit.JobType_JT_PUBLISHER} {
+		conn := &simulatedAgentConn{
+			logger:         h.logger.WithValues("simulatedWorker", true, "jobType", jobType),
+			workerMessages: make(chan *livekit.WorkerMessage, 16),
+			jobs:           make(map[string]*livekit.Job),
+		}
+		conn.send(&livekit.WorkerMessage{Message: &livekit.WorkerMessage_Register{
+			Register: &livekit.RegisterWorkerRequest{
+				Type:      jobType,
+				AgentName: conf.AgentName,
+				Namespace: &conf.Namespace,
+				Version:   conf.Version,
+			},
+		}})
+		go h.HandleConnection(ctx, conn, agent.CurrentProtocol)
+	}
+}
+
+func (c *simulatedAgentConn) ReadWorkerMessage() (*livekit.WorkerMessage, int, error) {
+	select {
+	case <-c.fuse.Watch():
+		return nil, 0, io.EOF
+	case m := <-c.workerMessages:
+		return m, 0, nil
+	}
+}
+
+func (c *simulatedAgentConn) WriteServerMessage(m *livekit.ServerMessage) (int, error) {
+	if c.fuse.IsBroken() {
+		return 0, io.EOF
+	}
+
+	switch m := m.Message.(type) {
+	case *livekit.ServerMessage_Register:
+		c.logger.Infow("simulated agent worker registered", "workerID", m.Register.WorkerId)
+	case *livekit.ServerMessage_Availability:
+		job := m.Availability.Job
+		c.logger.Infow("simulated agent accepting job", "jobID", job.Id, "room", job.Room.GetName(), "agentName", job.AgentName)
+		go c.send(&livekit.WorkerMessage{Message: &livekit.WorkerMessage_Availability{
+			Availability: &livekit.AvailabilityResponse{
+				JobId:               job.Id,
+				Available:           true,
+				ParticipantIdentity: "simulated-" + job.Id,
+				ParticipantName:     "simulated agent",
+			},
+		}})
+	case *livekit.ServerMessage_Assignment:
+		job := m.Assignment.Job
+		c.mu.Lock()
+		c.jobs[job.Id] = job
+		c.mu.Unlock()
+		c.logger.Infow("simulated agent job assigned", "jobID", job.Id, "room", job.Room.GetName(), "dispatchID", job.DispatchId)
+		go c.send(&livekit.WorkerMessage{Message: &livekit.WorkerMessage_UpdateJob{
+			UpdateJob: &livekit.UpdateJobStatus{
+				JobId:  job.Id,
+				Status: livekit.JobStatus_JS_RUNNING,
+			},
+		}})
+	case *livekit.ServerMessage_Termination:
+		c.mu.Lock()
+		job := c.jobs[m.Termination.JobId]
+		delete(c.jobs, m.Termination.JobId)
+		c.mu.Unlock()
+		c.logger.Infow("simulated agent job terminated", "jobID", m.Termination.JobId, "room", job.GetRoom().GetName())
+		go c.send(&livekit.WorkerMessage{Message: &livekit.WorkerMessage_UpdateJob{
+			UpdateJob: &livekit.UpdateJobStatus{
+				JobId:  m.Termination.JobId,
+				Status: livekit.JobStatus_JS_SUCCESS,
+			},
+		}})
+	}
+	return 0, nil
+}
+
+func (c *simulatedAgentConn) Close() error {
+	c.fuse.Break()
+	return nil
+}
+
+func (c *simulatedAgentConn) send(m *livekit.WorkerMessage) {
+	select {
+	case <-c.fuse.Watch():
+	case c.workerMessages <- m:
+	}
+}
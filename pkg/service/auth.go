@@ -18,8 +18,11 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/go-jose/go-jose/v3/jwt"
 	"github.com/twitchtv/twirp"
 
 	"github.com/livekit/protocol/auth"
@@ -37,6 +40,10 @@ type grantsKey struct{}
 type grantsValue struct {
 	claims *auth.ClaimGrants
 	apiKey string
+	// jwt id of the token, set for join url tokens
+	tokenID string
+	// admin of every room, for requests authenticated outside of LiveKit
+	trusted bool
 }
 
 var (
@@ -78,29 +85,45 @@ func (m *APIKeyAuthMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request,
 	}
 
 	if authToken != "" {
-		v, err := auth.ParseAPIToken(authToken)
+		// parsed once rather than through auth.ParseAPIToken, so the jwt id is available alongside the grants
+		tok, err := jwt.ParseSigned(authToken)
 		if err != nil {
 			handleError(w, r, http.StatusUnauthorized, ErrInvalidAuthorizationToken)
 			return
 		}
+		var unverified jwt.Claims
+		if err = tok.UnsafeClaimsWithoutVerification(&unverified); err != nil {
+			handleError(w, r, http.StatusUnauthorized, ErrInvalidAuthorizationToken)
+			return
+		}
 
-		secret := m.provider.GetSecret(v.APIKey())
+		apiKey := unverified.Issuer
+		secret := m.provider.GetSecret(apiKey)
 		if secret == "" {
-			handleError(w, r, http.StatusUnauthorized, errors.New("invalid API key: "+v.APIKey()))
+			handleError(w, r, http.StatusUnauthorized, errors.New("invalid API key: "+apiKey))
 			return
 		}
 
-		grants, err := v.Verify(secret)
+		claims := jwt.Claims{}
+		grants := &auth.ClaimGrants{}
+		if err = tok.Claims([]byte(secret), &claims, grants); err == nil {
+			err = claims.Validate(jwt.Expected{Issuer: apiKey, Time: time.Now()})
+		}
 		if err != nil {
 			handleError(w, r, http.StatusUnauthorized, errors.New("invalid token: "+authToken+", error: "+err.Error()))
 			return
 		}
+		grants.Identity = claims.Subject
+		if grants.Identity == "" {
+			grants.Identity = claims.ID
+		}
 
 		// set grants in context
 		ctx := r.Context()
 		r = r.WithContext(context.WithValue(ctx, grantsKey{}, &grantsValue{
-			claims: grants,
-			apiKey: v.APIKey(),
+			claims:  grants,
+			apiKey:  apiKey,
+			tokenID: claims.ID,
 		}))
 	}
 
@@ -125,6 +148,15 @@ func GetAPIKey(ctx context.Context) string {
 	return v.apiKey
 }
 
+// GetTokenID returns the jwt id of the token the request was authenticated with
+func GetTokenID(ctx context.Context) string {
+	v, ok := ctx.Value(grantsKey{}).(*grantsValue)
+	if !ok {
+		return ""
+	}
+	return v.tokenID
+}
+
 func WithGrants(ctx context.Context, grants *auth.ClaimGrants, apiKey string) context.Context {
 	return context.WithValue(ctx, grantsKey{}, &grantsValue{
 		claims: grants,
@@ -132,6 +164,38 @@ func WithGrants(ctx context.Context, grants *auth.ClaimGrants, apiKey string) co
 	})
 }
 
+// WithTrustedGrants grants every API permission, for requests that are authenticated outside of LiveKit.
+// A token sent with the request replaces these grants
+func WithTrustedGrants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, grantsKey{}, trustedGrants(""))
+}
+
+// trustedGrants grants every API permission, scoped to the project of apiKey when set
+func trustedGrants(apiKey string) *grantsValue {
+	return &grantsValue{
+		claims: &auth.ClaimGrants{
+			Video: &auth.VideoGrant{
+				RoomCreate:   true,
+				RoomList:     true,
+				RoomRecord:   true,
+				RoomAdmin:    true,
+				IngressAdmin: true,
+			},
+			SIP: &auth.SIPGrant{
+				Admin: true,
+				Call:  true,
+			},
+		},
+		apiKey:  apiKey,
+		trusted: true,
+	}
+}
+
+func isTrusted(ctx context.Context) bool {
+	v, ok := ctx.Value(grantsKey{}).(*grantsValue)
+	return ok && v.trusted
+}
+
 func SetAuthorizationToken(r *http.Request, token string) {
 	r.Header.Set(authorizationHeader, bearerPrefix+token)
 }
@@ -151,13 +215,27 @@ func EnsureJoinPermission(ctx context.Context) (name livekit.RoomName, err error
 	return
 }
 
+// EnsureSubscribePermission checks that the token allows joining room and subscribing to its tracks
+func EnsureSubscribePermission(ctx context.Context, room livekit.RoomName) error {
+	claims := GetGrants(ctx)
+	if claims == nil || claims.Video == nil {
+		return ErrPermissionDenied
+	}
+
+	if !claims.Video.RoomJoin || room != livekit.RoomName(claims.Video.Room) || !claims.Video.GetCanSubscribe() {
+		return ErrPermissionDenied
+	}
+
+	return nil
+}
+
 func EnsureAdminPermission(ctx context.Context, room livekit.RoomName) error {
 	claims := GetGrants(ctx)
 	if claims == nil || claims.Video == nil {
 		return ErrPermissionDenied
 	}
 
-	if !claims.Video.RoomAdmin || room != livekit.RoomName(claims.Video.Room) {
+	if !claims.Video.RoomAdmin || (room != livekit.RoomName(claims.Video.Room) && !isTrusted(ctx)) {
 		return ErrPermissionDenied
 	}
 
@@ -172,6 +250,15 @@ func EnsureCreatePermission(ctx context.Context) error {
 	return nil
 }
 
+// EnsureOperatorPermission checks that the token was signed with one of the operator keys
+func EnsureOperatorPermission(ctx context.Context, operatorKeys []string) error {
+	apiKey := GetAPIKey(ctx)
+	if apiKey == "" || !slices.Contains(operatorKeys, apiKey) {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
 func GetRoomConfiguration(ctx context.Context) string {
 
 	claims := GetGrants(ctx)
@@ -15,6 +15,7 @@
 package service_test
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -72,3 +73,27 @@ func TestAuthMiddleware(t *testing.T) {
 	require.Nil(t, grants)
 	require.Equal(t, http.StatusUnauthorized, w.Code)
 }
+
+func TestTrustedGrants(t *testing.T) {
+	ctx := service.WithTrustedGrants(context.Background())
+	require.NoError(t, service.EnsureAdminPermission(ctx, "any-room"))
+	require.NoError(t, service.EnsureCreatePermission(ctx))
+	require.NoError(t, service.EnsureSIPAdminPermission(ctx))
+
+	// grants of a token are limited to its room
+	ctx = service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Video: &auth.VideoGrant{Room: "room", RoomAdmin: true},
+	}, "")
+	require.NoError(t, service.EnsureAdminPermission(ctx, "room"))
+	require.ErrorIs(t, service.EnsureAdminPermission(ctx, "any-room"), service.ErrPermissionDenied)
+}
+
+func TestOperatorPermission(t *testing.T) {
+	grants := &auth.ClaimGrants{Video: &auth.VideoGrant{RoomCreate: true}}
+	require.NoError(t, service.EnsureOperatorPermission(service.WithGrants(context.Background(), grants, "operator"), []string{"operator"}))
+	require.ErrorIs(t, service.EnsureOperatorPermission(service.WithGrants(context.Background(), grants, "customer"), []string{"operator"}), service.ErrPermissionDenied)
+
+	// trusted grants are not operator grants
+	require.ErrorIs(t, service.EnsureOperatorPermission(service.WithTrustedGrants(context.Background()), []string{"operator"}), service.ErrPermissionDenied)
+	require.ErrorIs(t, service.EnsureOperatorPermission(context.Background(), nil), service.ErrPermissionDenied)
+}
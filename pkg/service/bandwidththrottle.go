@@ -0,0 +1,182 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"slices"
+	"time"
+
+	"github.com/frostbyte73/core"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const bandwidthThrottleInterval = time.Second
+
+type subscriberDemand struct {
+	participant types.LocalParticipant
+	demand      int64
+}
+
+// BandwidthThrottler keeps video forwarded to subscribers in rooms of a project within the project's
+// max_subscribe_bitrate quota. The quota is shared between nodes in proportion to the bitrate their subscribers
+// want, as reported through the quota manager. On a node, subscribers wanting less than a fair share are not
+// throttled, the others are bounded to an equal share of what is left. The stream allocator of throttled
+// subscribers lowers video layers, and pauses video when allowed, audio is never throttled
+type BandwidthThrottler struct {
+	limits      map[string]uint64
+	roomManager *RoomManager
+	quotas      *QuotaManager
+	projects    map[string]config.ProjectConfig
+
+	// only accessed by the worker
+	bounds map[livekit.ParticipantID]int64
+
+	stopped core.Fuse
+}
+
+// NewBandwidthThrottler returns nil when no project has a subscribe bitrate quota
+func NewBandwidthThrottler(conf *config.Config, roomManager *RoomManager, quotas *QuotaManager, projects *ProjectScope) *BandwidthThrottler {
+	if projects == nil {
+		return nil
+	}
+
+	limits := make(map[string]uint64)
+	for project := range conf.Projects {
+		if limit := conf.Quotas[project].MaxSubscribeBitrate; limit > 0 {
+			limits[project] = limit
+		}
+	}
+	if len(limits) == 0 {
+		return nil
+	}
+
+	t := &BandwidthThrottler{
+		limits:      limits,
+		roomManager: roomManager,
+		quotas:      quotas,
+		projects:    conf.Projects,
+		bounds:      make(map[livekit.ParticipantID]int64),
+	}
+	go t.worker()
+	return t
+}
+
+func (t *BandwidthThrottler) Stop() {
+	if t == nil {
+		return
+	}
+	t.stopped.Break()
+}
+
+func (t *BandwidthThrottler) worker() {
+	ticker := time.NewTicker(bandwidthThrottleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.stopped.Watch():
+			return
+		case <-ticker.C:
+			t.throttle()
+		}
+	}
+}
+
+func (t *BandwidthThrottler) throttle() {
+	demands := make(map[string][]subscriberDemand, len(t.limits))
+	for _, room := range t.roomManager.Rooms() {
+		project, _ := splitProjectRoomName(t.projects, room.Name())
+		if _, ok := t.limits[project]; !ok {
+			continue
+		}
+
+		for _, p := range room.GetLocalParticipants() {
+			demands[project] = append(demands[project], subscriberDemand{
+				participant: p,
+				demand:      videoDemand(p),
+			})
+		}
+	}
+
+	participants := make(map[livekit.ParticipantID]struct{})
+	for project, limit := range t.limits {
+		subscribers := demands[project]
+		var local uint64
+		for _, s := range subscribers {
+			local += uint64(s.demand)
+		}
+		remote := t.quotas.SetSubscribeDemand(project, local)
+
+		// 0 when not throttled
+		var bound int64
+		if local != 0 && local+remote > limit {
+			budget := float64(limit) * float64(local) / float64(local+remote)
+			bound = fairShare(int64(budget), subscribers)
+		}
+
+		for _, s := range subscribers {
+			pID := s.participant.ID()
+			participants[pID] = struct{}{}
+			if t.bounds[pID] != bound {
+				s.participant.SetSubscriberMaxChannelCapacity(bound)
+				t.bounds[pID] = bound
+			}
+		}
+	}
+	for pID := range t.bounds {
+		if _, ok := participants[pID]; !ok {
+			delete(t.bounds, pID)
+		}
+	}
+}
+
+// fairShare returns the bound that splits budget between subscribers, those wanting less than the bound get what
+// they want. 0 when everyone fits in the budget
+func fairShare(budget int64, subscribers []subscriberDemand) int64 {
+	demands := make([]int64, 0, len(subscribers))
+	for _, s := range subscribers {
+		demands = append(demands, s.demand)
+	}
+	slices.Sort(demands)
+
+	for i, demand := range demands {
+		share := budget / int64(len(demands)-i)
+		if demand > share {
+			// bound of 0 would remove it
+			return max(share, 1)
+		}
+		budget -= demand
+	}
+	return 0
+}
+
+// videoDemand returns the bitrate needed to forward the desired layers of video tracks the participant is
+// subscribed to
+func videoDemand(p types.LocalParticipant) int64 {
+	var demand int64
+	for _, st := range p.GetSubscribedTracks() {
+		if st.MediaTrack().Kind() != livekit.TrackType_VIDEO {
+			continue
+		}
+		if dt := st.DownTrack(); dt != nil {
+			demand += dt.OptimalBandwidthNeeded()
+		}
+	}
+	return demand
+}
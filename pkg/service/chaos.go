@@ -0,0 +1,224 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.uber.org/atomic"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const chaosPath = "/chaos"
+
+const (
+	chaosActionDropRTCP = "drop_rtcp"
+	chaosActionStall    = "stall"
+	chaosActionKillRoom = "kill_room"
+	chaosActionDelayBus = "delay_bus"
+
+	chaosTransportFaultMethod = "ChaosTransportFault"
+	chaosKillRoomMethod       = "ChaosKillRoom"
+)
+
+type chaosTransportFaultRequest struct {
+	Fault     types.TransportFault `json:"fault"`
+	Publisher bool                 `json:"publisher,omitempty"`
+	Duration  time.Duration        `json:"duration"`
+}
+
+// ChaosHandler injects faults to exercise resume and migration. It is only registered when chaos is enabled,
+// and only accepts tokens signed with one of the operator keys. Transport faults and room kills are applied
+// through the node hosting the participant or room, which must have chaos enabled too.
+type ChaosHandler struct {
+	roomManager  *RoomManager
+	roomAPI      *RoomAPI
+	operatorKeys []string
+}
+
+func NewChaosHandler(roomManager *RoomManager, operatorKeys []string) *ChaosHandler {
+	h := &ChaosHandler{
+		roomManager:  roomManager,
+		roomAPI:      roomManager.RoomAPI(),
+		operatorKeys: operatorKeys,
+	}
+	h.roomAPI.Handle(chaosTransportFaultMethod, h.handleTransportFault)
+	h.roomAPI.Handle(chaosKillRoomMethod, h.handleKillRoom)
+	return h
+}
+
+// ServeHTTP handles POST /chaos?action=, with a token signed by an operator key
+//   - drop_rtcp and stall take ?room=&identity=&target=publisher|subscriber&duration= and roomAdmin permission for the room
+//   - kill_room takes ?room= and roomAdmin permission for the room
+//   - delay_bus takes ?delay=&duration= and roomCreate permission, it delays every message published by the node
+//     receiving the request
+func (h *ChaosHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	action := query.Get("action")
+	roomName := livekit.RoomName(query.Get("room"))
+
+	err := EnsureOperatorPermission(r.Context(), h.operatorKeys)
+	if err == nil {
+		switch action {
+		case chaosActionDropRTCP, chaosActionStall, chaosActionKillRoom:
+			err = EnsureAdminPermission(r.Context(), roomName)
+		case chaosActionDelayBus:
+			err = EnsureCreatePermission(r.Context())
+		default:
+			handleError(w, r, http.StatusBadRequest, ErrInvalidChaosAction, "action", action)
+			return
+		}
+	}
+	if err != nil {
+		handleError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	switch action {
+	case chaosActionDropRTCP:
+		err = h.simulateTransportFault(r.Context(), roomName, query, types.TransportFaultDropRTCP)
+	case chaosActionStall:
+		err = h.simulateTransportFault(r.Context(), roomName, query, types.TransportFaultStall)
+	case chaosActionKillRoom:
+		err = h.roomAPI.CallRoom(r.Context(), roomName, chaosKillRoomMethod, nil, nil)
+	case chaosActionDelayBus:
+		err = h.delayMessageBus(query)
+	}
+	if err != nil {
+		status := roomAPIStatus(err)
+		if errors.Is(err, rtc.ErrChaosNotEnabled) {
+			status = http.StatusPreconditionFailed
+		}
+		handleError(w, r, status, err, "action", action, "room", roomName)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ChaosHandler) simulateTransportFault(ctx context.Context, roomName livekit.RoomName, query url.Values, fault types.TransportFault) error {
+	duration, err := parseChaosDuration(query.Get("duration"))
+	if err != nil {
+		return err
+	}
+
+	req := &chaosTransportFaultRequest{
+		Fault:     fault,
+		Publisher: query.Get("target") == "publisher",
+		Duration:  duration,
+	}
+	return h.roomAPI.CallParticipant(ctx, roomName, livekit.ParticipantIdentity(query.Get("identity")), chaosTransportFaultMethod, req, nil)
+}
+
+func (h *ChaosHandler) handleTransportFault(_ context.Context, _ *rtc.Room, p types.LocalParticipant, body json.RawMessage) (any, error) {
+	var req chaosTransportFaultRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+
+	target := livekit.SignalTarget_SUBSCRIBER
+	if req.Publisher {
+		target = livekit.SignalTarget_PUBLISHER
+	}
+	if err := p.SimulateTransportFault(target, req.Fault, req.Duration); err != nil {
+		if errors.Is(err, rtc.ErrChaosNotEnabled) {
+			return nil, psrpc.NewError(psrpc.FailedPrecondition, err)
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (h *ChaosHandler) handleKillRoom(ctx context.Context, room *rtc.Room, _ types.LocalParticipant, _ json.RawMessage) (any, error) {
+	return nil, h.roomManager.KillRoom(ctx, room.Name())
+}
+
+func (h *ChaosHandler) delayMessageBus(query url.Values) error {
+	bus, ok := h.roomManager.bus.(*chaosMessageBus)
+	if !ok {
+		return rtc.ErrChaosNotEnabled
+	}
+	delay, err := parseChaosDuration(query.Get("delay"))
+	if err != nil {
+		return err
+	}
+	duration, err := parseChaosDuration(query.Get("duration"))
+	if err != nil {
+		return err
+	}
+
+	logger.Infow("delaying message bus", "delay", delay, "duration", duration)
+	bus.Delay(delay, duration)
+	return nil
+}
+
+func parseChaosDuration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, ErrInvalidChaosDuration
+	}
+	return d, nil
+}
+
+// ---------------------------------------------
+
+// chaosMessageBus delays messages published by this node on demand
+type chaosMessageBus struct {
+	psrpc.MessageBus
+
+	delay atomic.Duration
+	until atomic.Int64
+}
+
+func newChaosMessageBus(bus psrpc.MessageBus) *chaosMessageBus {
+	return &chaosMessageBus{
+		MessageBus: bus,
+	}
+}
+
+// Delay holds every message published in the next duration for delay before it is sent
+func (b *chaosMessageBus) Delay(delay time.Duration, duration time.Duration) {
+	b.delay.Store(delay)
+	b.until.Store(time.Now().Add(duration).UnixNano())
+}
+
+func (b *chaosMessageBus) Publish(ctx context.Context, channel psrpc.Channel, msg proto.Message) error {
+	if time.Now().UnixNano() < b.until.Load() {
+		t := time.NewTimer(b.delay.Load())
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+	return b.MessageBus.Publish(ctx, channel, msg)
+}
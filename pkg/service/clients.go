@@ -38,6 +38,12 @@ type IOClient interface {
 	UpdateIngressState(ctx context.Context, req *rpc.UpdateIngressStateRequest) (*emptypb.Empty, error)
 }
 
+// workerResultRecorder is implemented by IOClients tracking whether egress and ingress workers respond
+type workerResultRecorder interface {
+	recordEgressResult(err error)
+	recordIngressResult(err error)
+}
+
 type egressLauncher struct {
 	client rpc.EgressClient
 	io     IOClient
@@ -64,6 +70,9 @@ func (s *egressLauncher) StartEgress(ctx context.Context, req *rpc.StartEgressRe
 	}
 
 	info, err := s.client.StartEgress(ctx, "", req)
+	if r, ok := s.io.(workerResultRecorder); ok {
+		r.recordEgressResult(err)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,312 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+)
+
+const (
+	consolePath          = "/console/"
+	consoleSessionCookie = "lk_console_session"
+
+	defaultConsoleSessionTTL = 12 * time.Hour
+)
+
+var (
+	ErrConsoleNotSignedIn     = errors.New("not signed in to the console")
+	ErrConsoleInvalidSignIn   = errors.New("invalid API key or secret")
+	ErrConsoleOIDCNotEnabled  = errors.New("console OIDC sign in is not enabled")
+	ErrConsoleUserNotAllowed  = errors.New("user is not allowed to use the console")
+	ErrConsoleInvalidOIDCFlow = errors.New("invalid or expired OIDC sign in")
+)
+
+//go:embed console/index.html
+var consoleFS embed.FS
+
+// consoleSession is the signed payload of the session cookie. It is signed with the secret of the API key, so
+// sessions end when the key is removed
+type consoleSession struct {
+	APIKey string `json:"k"`
+	User   string `json:"u"`
+	// unix time in seconds
+	ExpiresAt int64 `json:"e"`
+}
+
+type ConsoleLoginRequest struct {
+	APIKey    string `json:"api_key"`
+	APISecret string `json:"api_secret"`
+}
+
+type ConsoleSessionResponse struct {
+	SignedIn    bool   `json:"signed_in"`
+	User        string `json:"user,omitempty"`
+	APIKey      string `json:"api_key,omitempty"`
+	OIDCEnabled bool   `json:"oidc_enabled"`
+}
+
+// Console serves the admin web console. Signed in users act with the permissions of an API key: the console
+// calls the room service APIs with a session cookie, which Authenticate turns into grants for the key
+type Console struct {
+	conf        config.ConsoleConfig
+	keyProvider auth.KeyProvider
+	router      routing.Router
+	oidc        *consoleOIDC
+	mux         *http.ServeMux
+}
+
+func NewConsole(conf config.ConsoleConfig, keyProvider auth.KeyProvider, router routing.Router) *Console {
+	if !conf.Enabled || keyProvider == nil {
+		return nil
+	}
+
+	c := &Console{
+		conf:        conf,
+		keyProvider: keyProvider,
+		router:      router,
+	}
+	if conf.OIDC.Enabled() {
+		c.oidc = newConsoleOIDC(conf.OIDC)
+	}
+
+	c.mux = http.NewServeMux()
+	c.mux.HandleFunc(consolePath, c.serveIndex)
+	c.mux.HandleFunc(consolePath+"login", c.login)
+	c.mux.HandleFunc(consolePath+"logout", c.logout)
+	c.mux.HandleFunc(consolePath+"session", c.session)
+	c.mux.HandleFunc(consolePath+"nodes", c.nodes)
+	c.mux.HandleFunc(consolePath+"oidc/login", c.oidcLogin)
+	c.mux.HandleFunc(consolePath+"oidc/callback", c.oidcCallback)
+	return c
+}
+
+func (c *Console) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mux.ServeHTTP(w, r)
+}
+
+// Authenticate grants requests from signed in console users the permissions of their API key. Tokens sent with
+// the request take precedence, and only API requests are authenticated with the session cookie
+func (c *Console) Authenticate(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if c != nil && GetGrants(r.Context()) == nil && strings.HasPrefix(r.URL.Path, "/twirp/") {
+		if s := c.loadSession(r); s != nil {
+			r = r.WithContext(context.WithValue(r.Context(), grantsKey{}, trustedGrants(s.APIKey)))
+		}
+	}
+	next.ServeHTTP(w, r)
+}
+
+func (c *Console) serveIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != consolePath {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	page, err := consoleFS.ReadFile("console/index.html")
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; frame-ancestors 'none'")
+	w.Header().Set("X-Frame-Options", "DENY")
+	_, _ = w.Write(page)
+}
+
+func (c *Console) login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ConsoleLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	secret := c.keyProvider.GetSecret(req.APIKey)
+	if req.APIKey == "" || secret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(req.APISecret)) != 1 {
+		handleError(w, r, http.StatusUnauthorized, ErrConsoleInvalidSignIn)
+		return
+	}
+
+	if err := c.startSession(w, r, req.APIKey, req.APIKey); err != nil {
+		handleError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	c.writeSession(w, r, &consoleSession{APIKey: req.APIKey, User: req.APIKey})
+}
+
+func (c *Console) logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     consoleSessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteStrictMode,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *Console) session(w http.ResponseWriter, r *http.Request) {
+	c.writeSession(w, r, c.loadSession(r))
+}
+
+func (c *Console) writeSession(w http.ResponseWriter, _ *http.Request, s *consoleSession) {
+	res := ConsoleSessionResponse{
+		OIDCEnabled: c.oidc != nil,
+	}
+	if s != nil {
+		res.SignedIn = true
+		res.User = s.User
+		res.APIKey = s.APIKey
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(&res)
+}
+
+// nodes lists the nodes of the cluster with their current load
+func (c *Console) nodes(w http.ResponseWriter, r *http.Request) {
+	if c.loadSession(r) == nil {
+		handleError(w, r, http.StatusUnauthorized, ErrConsoleNotSignedIn)
+		return
+	}
+	nodes, err := c.router.ListNodes()
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	res := make([]json.RawMessage, 0, len(nodes))
+	for _, node := range nodes {
+		b, err := protojson.Marshal(node)
+		if err != nil {
+			handleError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		res = append(res, b)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (c *Console) startSession(w http.ResponseWriter, r *http.Request, apiKey string, user string) error {
+	ttl := c.conf.SessionTTL
+	if ttl <= 0 {
+		ttl = defaultConsoleSessionTTL
+	}
+	s := &consoleSession{
+		APIKey:    apiKey,
+		User:      user,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}
+	value, err := c.signSession(s)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     consoleSessionCookie,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteStrictMode,
+	})
+	logger.Infow("console user signed in", "user", user, "apiKey", apiKey)
+	return nil
+}
+
+func (c *Console) signSession(s *consoleSession) (string, error) {
+	secret := c.keyProvider.GetSecret(s.APIKey)
+	if secret == "" {
+		return "", ErrInvalidAPIKey
+	}
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(consoleSessionMAC(secret, encoded)), nil
+}
+
+// loadSession returns the session of the request, or nil when it has no valid session cookie
+func (c *Console) loadSession(r *http.Request) *consoleSession {
+	cookie, err := r.Cookie(consoleSessionCookie)
+	if err != nil {
+		return nil
+	}
+	encoded, sig, ok := strings.Cut(cookie.Value, ".")
+	if !ok {
+		return nil
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return nil
+	}
+
+	var s consoleSession
+	if err = json.Unmarshal(payload, &s); err != nil {
+		return nil
+	}
+	secret := c.keyProvider.GetSecret(s.APIKey)
+	if secret == "" || !hmac.Equal(mac, consoleSessionMAC(secret, encoded)) {
+		return nil
+	}
+	if time.Now().Unix() >= s.ExpiresAt {
+		return nil
+	}
+	return &s
+}
+
+func consoleSessionMAC(secret string, payload string) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	_, _ = h.Write([]byte("lk_console:" + payload))
+	return h.Sum(nil)
+}
+
+func isSecureRequest(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
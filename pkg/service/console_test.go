@@ -0,0 +1,186 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestConsole(t *testing.T) {
+	keyProvider := auth.NewFileBasedKeyProviderFromMap(map[string]string{"key": "secret"})
+	require.Nil(t, service.NewConsole(config.ConsoleConfig{}, keyProvider, nil))
+
+	// returns the grants an API request is authenticated with
+	authenticate := func(console *service.Console, cookies []*http.Cookie) (*auth.ClaimGrants, string) {
+		r := httptest.NewRequest(http.MethodPost, "/twirp/livekit.RoomService/ListRooms", nil)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		var grants *auth.ClaimGrants
+		var apiKey string
+		console.Authenticate(httptest.NewRecorder(), r, func(w http.ResponseWriter, r *http.Request) {
+			grants = service.GetGrants(r.Context())
+			apiKey = service.GetAPIKey(r.Context())
+		})
+		return grants, apiKey
+	}
+
+	t.Run("api key sign in", func(t *testing.T) {
+		console := service.NewConsole(config.ConsoleConfig{Enabled: true}, keyProvider, nil)
+
+		login := func(body string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			console.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/console/login", strings.NewReader(body)))
+			return w
+		}
+		w := login(`{"api_key": "key", "api_secret": "wrong"}`)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		require.Empty(t, w.Result().Cookies())
+
+		w = login(`{"api_key": "key", "api_secret": "secret"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		var session service.ConsoleSessionResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &session))
+		require.True(t, session.SignedIn)
+		require.Equal(t, "key", session.APIKey)
+
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		require.True(t, cookies[0].HttpOnly)
+		require.Equal(t, http.SameSiteStrictMode, cookies[0].SameSite)
+
+		// the session acts as an admin of every room, with the key of the session
+		grants, apiKey := authenticate(console, cookies)
+		require.NotNil(t, grants)
+		require.True(t, grants.Video.RoomAdmin)
+		require.True(t, grants.Video.RoomList)
+		require.Equal(t, "key", apiKey)
+
+		// tampered cookies are ignored
+		tampered := *cookies[0]
+		tampered.Value = strings.Replace(tampered.Value, ".", "x.", 1)
+		grants, _ = authenticate(console, []*http.Cookie{&tampered})
+		require.Nil(t, grants)
+
+		// sessions end when the key is removed
+		removed := service.NewConsole(config.ConsoleConfig{Enabled: true}, auth.NewFileBasedKeyProviderFromMap(map[string]string{"other": "secret"}), nil)
+		grants, _ = authenticate(removed, cookies)
+		require.Nil(t, grants)
+	})
+
+	t.Run("oidc sign in", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: key, KeyID: "k1"}}, nil)
+		require.NoError(t, err)
+
+		var nonce, email string
+		provider := httptest.NewServer(http.NotFoundHandler())
+		defer provider.Close()
+		provider.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/.well-known/openid-configuration":
+				_ = json.NewEncoder(w).Encode(map[string]string{
+					"issuer":                 provider.URL,
+					"authorization_endpoint": provider.URL + "/authorize",
+					"token_endpoint":         provider.URL + "/token",
+					"jwks_uri":               provider.URL + "/keys",
+				})
+			case "/keys":
+				_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &key.PublicKey, KeyID: "k1", Use: "sig", Algorithm: "RS256"}}})
+			case "/token":
+				require.Equal(t, "code", r.FormValue("code"))
+				idToken, err := jwt.Signed(signer).Claims(jwt.Claims{
+					Issuer:   provider.URL,
+					Audience: jwt.Audience{"client"},
+					Expiry:   jwt.NewNumericDate(time.Now().Add(time.Minute)),
+				}).Claims(map[string]interface{}{"nonce": nonce, "email": email}).CompactSerialize()
+				require.NoError(t, err)
+				_ = json.NewEncoder(w).Encode(map[string]string{"id_token": idToken})
+			default:
+				http.NotFound(w, r)
+			}
+		})
+
+		console := service.NewConsole(config.ConsoleConfig{
+			Enabled: true,
+			OIDC: config.ConsoleOIDCConfig{
+				Issuer:         provider.URL,
+				ClientID:       "client",
+				RedirectURL:    "https://livekit.example.com/console/oidc/callback",
+				APIKey:         "key",
+				AllowedDomains: []string{"example.com"},
+			},
+		}, keyProvider, nil)
+
+		signIn := func(user string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			console.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/console/oidc/login", nil))
+			require.Equal(t, http.StatusFound, w.Code)
+			redirect, err := url.Parse(w.Header().Get("Location"))
+			require.NoError(t, err)
+			require.Equal(t, "client", redirect.Query().Get("client_id"))
+			nonce, email = redirect.Query().Get("nonce"), user
+
+			r := httptest.NewRequest(http.MethodGet, "/console/oidc/callback?code=code&state="+redirect.Query().Get("state"), nil)
+			for _, c := range w.Result().Cookies() {
+				r.AddCookie(c)
+			}
+			w = httptest.NewRecorder()
+			console.ServeHTTP(w, r)
+			return w
+		}
+
+		w := signIn("intruder@example.org")
+		require.Equal(t, http.StatusForbidden, w.Code)
+
+		w = signIn("admin@example.com")
+		require.Equal(t, http.StatusFound, w.Code)
+		var session *http.Cookie
+		for _, c := range w.Result().Cookies() {
+			if c.Name == "lk_console_session" {
+				session = c
+			}
+		}
+		require.NotNil(t, session)
+		grants, apiKey := authenticate(console, []*http.Cookie{session})
+		require.NotNil(t, grants)
+		require.Equal(t, "key", apiKey)
+
+		// the state must match the flow started by the browser
+		r := httptest.NewRequest(http.MethodGet, "/console/oidc/callback?code=code&state=forged", nil)
+		r.AddCookie(&http.Cookie{Name: "lk_console_oidc", Value: "state.nonce"})
+		w = httptest.NewRecorder()
+		console.ServeHTTP(w, r)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
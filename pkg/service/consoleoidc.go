@@ -0,0 +1,300 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	consoleOIDCCookie     = "lk_console_oidc"
+	consoleOIDCFlowMaxAge = 10 * time.Minute
+)
+
+type oidcProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcIDTokenClaims struct {
+	Nonce         string `json:"nonce"`
+	Email         string `json:"email"`
+	EmailVerified *bool  `json:"email_verified"`
+}
+
+// consoleOIDC signs console users in with the authorization code flow. Provider metadata and signing keys are
+// fetched on first use, keys are fetched again when an id token is signed with an unknown key
+type consoleOIDC struct {
+	conf   config.ConsoleOIDCConfig
+	client *http.Client
+
+	lock     sync.Mutex
+	metadata *oidcProviderMetadata
+	keys     *jose.JSONWebKeySet
+}
+
+func newConsoleOIDC(conf config.ConsoleOIDCConfig) *consoleOIDC {
+	return &consoleOIDC{
+		conf:   conf,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *Console) oidcLogin(w http.ResponseWriter, r *http.Request) {
+	if c.oidc == nil {
+		handleError(w, r, http.StatusNotFound, ErrConsoleOIDCNotEnabled)
+		return
+	}
+	metadata, err := c.oidc.discover(r.Context())
+	if err != nil {
+		handleError(w, r, http.StatusBadGateway, err)
+		return
+	}
+
+	state, nonce := randomOIDCValue(), randomOIDCValue()
+	http.SetCookie(w, &http.Cookie{
+		Name:     consoleOIDCCookie,
+		Value:    state + "." + nonce,
+		Path:     consolePath + "oidc/",
+		MaxAge:   int(consoleOIDCFlowMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		// sent on the redirect back from the provider
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	u, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		handleError(w, r, http.StatusBadGateway, err)
+		return
+	}
+	query := u.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", c.oidc.conf.ClientID)
+	query.Set("redirect_uri", c.oidc.conf.RedirectURL)
+	query.Set("scope", "openid email profile")
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	u.RawQuery = query.Encode()
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+func (c *Console) oidcCallback(w http.ResponseWriter, r *http.Request) {
+	if c.oidc == nil {
+		handleError(w, r, http.StatusNotFound, ErrConsoleOIDCNotEnabled)
+		return
+	}
+	cookie, err := r.Cookie(consoleOIDCCookie)
+	if err != nil {
+		handleError(w, r, http.StatusUnauthorized, ErrConsoleInvalidOIDCFlow)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: consoleOIDCCookie, Path: consolePath + "oidc/", MaxAge: -1})
+
+	state, nonce, _ := strings.Cut(cookie.Value, ".")
+	query := r.URL.Query()
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 {
+		handleError(w, r, http.StatusUnauthorized, ErrConsoleInvalidOIDCFlow)
+		return
+	}
+	if errCode := query.Get("error"); errCode != "" {
+		handleError(w, r, http.StatusUnauthorized, fmt.Errorf("OIDC sign in failed: %s", errCode))
+		return
+	}
+
+	email, err := c.oidc.exchange(r.Context(), query.Get("code"), nonce)
+	if err != nil {
+		handleError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+	if !c.oidc.allowed(email) {
+		handleError(w, r, http.StatusForbidden, ErrConsoleUserNotAllowed, "user", email)
+		return
+	}
+
+	if err = c.startSession(w, r, c.oidc.conf.APIKey, email); err != nil {
+		handleError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	http.Redirect(w, r, consolePath, http.StatusFound)
+}
+
+// exchange redeems an authorization code, and returns the verified email of the user
+func (o *consoleOIDC) exchange(ctx context.Context, code string, nonce string) (string, error) {
+	if code == "" {
+		return "", ErrConsoleInvalidOIDCFlow
+	}
+	metadata, err := o.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.conf.RedirectURL},
+		"client_id":     {o.conf.ClientID},
+		"client_secret": {o.conf.ClientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	var res struct {
+		IDToken string `json:"id_token"`
+	}
+	if err = o.do(req, &res); err != nil {
+		return "", err
+	}
+
+	tok, err := jwt.ParseSigned(res.IDToken)
+	if err != nil {
+		return "", err
+	}
+	if len(tok.Headers) != 1 {
+		return "", ErrConsoleInvalidOIDCFlow
+	}
+	key, err := o.signingKey(ctx, tok.Headers[0].KeyID)
+	if err != nil {
+		return "", err
+	}
+
+	var claims jwt.Claims
+	var idClaims oidcIDTokenClaims
+	if err = tok.Claims(key, &claims, &idClaims); err != nil {
+		return "", err
+	}
+	if err = claims.ValidateWithLeeway(jwt.Expected{
+		Issuer:   metadata.Issuer,
+		Audience: jwt.Audience{o.conf.ClientID},
+		Time:     time.Now(),
+	}, time.Minute); err != nil {
+		return "", err
+	}
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(nonce), []byte(idClaims.Nonce)) != 1 {
+		return "", ErrConsoleInvalidOIDCFlow
+	}
+	if idClaims.Email == "" || (idClaims.EmailVerified != nil && !*idClaims.EmailVerified) {
+		return "", ErrConsoleUserNotAllowed
+	}
+	return idClaims.Email, nil
+}
+
+func (o *consoleOIDC) allowed(email string) bool {
+	email = strings.ToLower(email)
+	for _, allowed := range o.conf.AllowedEmails {
+		if strings.ToLower(allowed) == email {
+			return true
+		}
+	}
+	if i := strings.LastIndexByte(email, '@'); i >= 0 {
+		domain := email[i+1:]
+		for _, allowed := range o.conf.AllowedDomains {
+			if strings.ToLower(allowed) == domain {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (o *consoleOIDC) discover(ctx context.Context) (*oidcProviderMetadata, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.metadata != nil {
+		return o.metadata, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(o.conf.Issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var metadata oidcProviderMetadata
+	if err = o.do(req, &metadata); err != nil {
+		return nil, err
+	}
+	if metadata.Issuer != o.conf.Issuer {
+		return nil, fmt.Errorf("OIDC issuer mismatch, expected %s, got %s", o.conf.Issuer, metadata.Issuer)
+	}
+	o.metadata = &metadata
+	return o.metadata, nil
+}
+
+// signingKey returns the provider key with keyID, fetching the provider keys when it is not known
+func (o *consoleOIDC) signingKey(ctx context.Context, keyID string) (*jose.JSONWebKey, error) {
+	metadata, err := o.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.keys != nil {
+		if keys := o.keys.Key(keyID); len(keys) > 0 {
+			return &keys[0], nil
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadata.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var keys jose.JSONWebKeySet
+	if err = o.do(req, &keys); err != nil {
+		return nil, err
+	}
+	o.keys = &keys
+	if found := keys.Key(keyID); len(found) > 0 {
+		return &found[0], nil
+	}
+	return nil, fmt.Errorf("unknown OIDC signing key %q", keyID)
+}
+
+func (o *consoleOIDC) do(req *http.Request, v interface{}) error {
+	res, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("OIDC request to %s failed with status %d", req.URL, res.StatusCode)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+func randomOIDCValue() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
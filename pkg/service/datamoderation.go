@@ -0,0 +1,125 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+const dataFlagTimeout = 5 * time.Second
+
+// DataModerationClient posts user data messages to an external moderation endpoint, and flagged messages to
+// the flag endpoint. Requests are signed the same way as webhooks, so receivers can verify them with webhook.Receive.
+type DataModerationClient struct {
+	url       string
+	flagURL   string
+	apiKey    string
+	apiSecret string
+	client    *http.Client
+}
+
+func NewDataModerationClient(conf *config.Config) (*DataModerationClient, error) {
+	secret := conf.Keys[conf.WebHook.APIKey]
+	if secret == "" {
+		return nil, ErrDataModerationMissingAPIKey
+	}
+
+	return &DataModerationClient{
+		url:       conf.DataModeration.URL,
+		flagURL:   conf.DataModeration.FlagURL,
+		apiKey:    conf.WebHook.APIKey,
+		apiSecret: secret,
+		client:    &http.Client{},
+	}, nil
+}
+
+func (c *DataModerationClient) ModerateData(ctx context.Context, req *rtc.DataModerationRequest) (*rtc.DataModerationResult, error) {
+	resp, err := c.post(ctx, c.url, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("data moderation endpoint returned %d", resp.StatusCode)
+	}
+
+	res := &rtc.DataModerationResult{}
+	if err = json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return nil, fmt.Errorf("invalid data moderation response: %w", err)
+	}
+	switch res.Action {
+	case rtc.DataModerationAllow, rtc.DataModerationDrop, rtc.DataModerationRedact, rtc.DataModerationFlag:
+		return res, nil
+	default:
+		return nil, fmt.Errorf("invalid data moderation action %q", res.Action)
+	}
+}
+
+// NotifyDataFlagged posts a flagged message in the background
+func (c *DataModerationClient) NotifyDataFlagged(_ context.Context, flagged *rtc.DataMessageFlagged) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), dataFlagTimeout)
+		defer cancel()
+
+		resp, err := c.post(ctx, c.flagURL, flagged)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				err = fmt.Errorf("data flag endpoint returned %d", resp.StatusCode)
+			}
+		}
+		if err != nil {
+			logger.Warnw("could not report flagged data message", err, "room", flagged.Room, "participant", flagged.ParticipantIdentity)
+		}
+	}()
+}
+
+func (c *DataModerationClient) post(ctx context.Context, url string, payload any) (*http.Response, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(body)
+	token, err := auth.NewAccessToken(c.apiKey, c.apiSecret).
+		SetValidFor(5 * time.Minute).
+		SetSha256(base64.StdEncoding.EncodeToString(sum[:])).
+		ToJWT()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Authorization", token)
+	r.Header.Set("Content-Type", "application/webhook+json")
+	return c.client.Do(r)
+}
@@ -17,8 +17,10 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/twitchtv/twirp"
 
@@ -162,6 +164,9 @@ func (s *EgressService) startEgress(ctx context.Context, roomName livekit.RoomNa
 	} else if s.launcher == nil {
 		return nil, ErrEgressNotConnected
 	}
+	if err := validateStreamOutputs(getStreamOutputs(req)); err != nil {
+		return nil, err
+	}
 	if roomName != "" {
 		room, _, err := s.store.LoadRoom(ctx, roomName, false)
 		if err != nil {
@@ -172,10 +177,16 @@ func (s *EgressService) startEgress(ctx context.Context, roomName livekit.RoomNa
 	return s.launcher.StartEgress(ctx, req)
 }
 
+// LayoutMetadata is set as metadata of the egress participant, room composite templates
+// switch layouts and pinned participants as it changes
 type LayoutMetadata struct {
-	Layout string `json:"layout"`
+	Layout string   `json:"layout"`
+	Pinned []string `json:"pinned,omitempty"`
 }
 
+// UpdateLayout changes the layout of an active room composite egress. The layout is either a layout
+// name, or a JSON encoded LayoutMetadata to also pin participants. Pinned participants are kept when
+// only the layout name is given.
 func (s *EgressService) UpdateLayout(ctx context.Context, req *livekit.UpdateLayoutRequest) (*livekit.EgressInfo, error) {
 	AppendLogFields(ctx, "egressID", req.EgressId, "layout", req.Layout)
 	if err := EnsureRecordPermission(ctx); err != nil {
@@ -187,15 +198,46 @@ func (s *EgressService) UpdateLayout(ctx context.Context, req *livekit.UpdateLay
 		return nil, err
 	}
 
-	metadata, err := json.Marshal(&LayoutMetadata{Layout: req.Layout})
+	switch info.Status {
+	case livekit.EgressStatus_EGRESS_STARTING,
+		livekit.EgressStatus_EGRESS_ACTIVE:
+	default:
+		return nil, twirp.NewError(twirp.FailedPrecondition,
+			fmt.Sprintf("egress with status %s cannot be updated", info.Status.String()))
+	}
+	if info.GetRoomComposite() == nil {
+		return nil, twirp.NewError(twirp.FailedPrecondition, "layout can only be updated for room composite egress")
+	}
+
+	layout, err := parseLayoutMetadata(req.Layout)
 	if err != nil {
-		return nil, err
+		return nil, twirp.InvalidArgumentError("layout", err.Error())
 	}
 
 	grants := GetGrants(ctx)
 	grants.Video.Room = info.RoomName
 	grants.Video.RoomAdmin = true
 
+	if layout.Pinned == nil {
+		// keep participants pinned previously
+		p, err := s.roomService.GetParticipant(ctx, &livekit.RoomParticipantIdentity{
+			Room:     info.RoomName,
+			Identity: info.EgressId,
+		})
+		if err != nil {
+			return nil, err
+		}
+		var current LayoutMetadata
+		if json.Unmarshal([]byte(p.Metadata), &current) == nil {
+			layout.Pinned = current.Pinned
+		}
+	}
+
+	metadata, err := json.Marshal(layout)
+	if err != nil {
+		return nil, err
+	}
+
 	_, err = s.roomService.UpdateParticipant(ctx, &livekit.UpdateParticipantRequest{
 		Room:     info.RoomName,
 		Identity: info.EgressId,
@@ -205,15 +247,34 @@ func (s *EgressService) UpdateLayout(ctx context.Context, req *livekit.UpdateLay
 		return nil, err
 	}
 
+	info.GetRoomComposite().Layout = layout.Layout
 	return info, nil
 }
 
+func parseLayoutMetadata(layout string) (*LayoutMetadata, error) {
+	if !strings.HasPrefix(strings.TrimSpace(layout), "{") {
+		return &LayoutMetadata{Layout: layout}, nil
+	}
+
+	m := &LayoutMetadata{}
+	if err := json.Unmarshal([]byte(layout), m); err != nil {
+		return nil, err
+	}
+	if m.Layout == "" {
+		return nil, errors.New("layout name is required")
+	}
+	return m, nil
+}
+
 func (s *EgressService) UpdateStream(ctx context.Context, req *livekit.UpdateStreamRequest) (*livekit.EgressInfo, error) {
 	AppendLogFields(ctx, "egressID", req.EgressId, "addUrls", req.AddOutputUrls, "removeUrls", req.RemoveOutputUrls)
 	if err := EnsureRecordPermission(ctx); err != nil {
 		return nil, twirpAuthError(err)
 	}
 
+	if err := validateStreamURLs(inferStreamProtocol(req.AddOutputUrls), req.AddOutputUrls); err != nil {
+		return nil, err
+	}
 	if s.client == nil {
 		return nil, ErrEgressNotConnected
 	}
@@ -0,0 +1,103 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+)
+
+type testLayoutRoomService struct {
+	livekit.RoomService
+	metadata string
+}
+
+func (s *testLayoutRoomService) GetParticipant(_ context.Context, req *livekit.RoomParticipantIdentity) (*livekit.ParticipantInfo, error) {
+	return &livekit.ParticipantInfo{Identity: req.Identity, Metadata: s.metadata}, nil
+}
+
+func (s *testLayoutRoomService) UpdateParticipant(_ context.Context, req *livekit.UpdateParticipantRequest) (*livekit.ParticipantInfo, error) {
+	s.metadata = req.Metadata
+	return &livekit.ParticipantInfo{Identity: req.Identity, Metadata: req.Metadata}, nil
+}
+
+func TestUpdateLayout(t *testing.T) {
+	newService := func(info *livekit.EgressInfo) (*service.EgressService, *testLayoutRoomService) {
+		io := &servicefakes.FakeIOClient{}
+		io.GetEgressReturns(info, nil)
+		rs := &testLayoutRoomService{}
+		return service.NewEgressService(nil, nil, nil, io, rs), rs
+	}
+	newContext := func() context.Context {
+		return service.WithGrants(context.Background(), &auth.ClaimGrants{Video: &auth.VideoGrant{RoomRecord: true}}, "")
+	}
+	activeRoomComposite := func() *livekit.EgressInfo {
+		return &livekit.EgressInfo{
+			EgressId: "EG_test",
+			RoomName: "room",
+			Status:   livekit.EgressStatus_EGRESS_ACTIVE,
+			Request: &livekit.EgressInfo_RoomComposite{
+				RoomComposite: &livekit.RoomCompositeEgressRequest{RoomName: "room", Layout: "grid"},
+			},
+		}
+	}
+
+	t.Run("switches layout and keeps pins", func(t *testing.T) {
+		svc, rs := newService(activeRoomComposite())
+
+		info, err := svc.UpdateLayout(newContext(), &livekit.UpdateLayoutRequest{
+			EgressId: "EG_test",
+			Layout:   `{"layout":"speaker","pinned":["alice"]}`,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "speaker", info.GetRoomComposite().Layout)
+
+		_, err = svc.UpdateLayout(newContext(), &livekit.UpdateLayoutRequest{EgressId: "EG_test", Layout: "grid"})
+		require.NoError(t, err)
+
+		var metadata service.LayoutMetadata
+		require.NoError(t, json.Unmarshal([]byte(rs.metadata), &metadata))
+		require.Equal(t, service.LayoutMetadata{Layout: "grid", Pinned: []string{"alice"}}, metadata)
+	})
+
+	t.Run("requires active room composite egress", func(t *testing.T) {
+		info := activeRoomComposite()
+		info.Status = livekit.EgressStatus_EGRESS_COMPLETE
+		svc, _ := newService(info)
+		_, err := svc.UpdateLayout(newContext(), &livekit.UpdateLayoutRequest{EgressId: "EG_test", Layout: "grid"})
+		require.Error(t, err)
+
+		info = activeRoomComposite()
+		info.Request = &livekit.EgressInfo_Web{Web: &livekit.WebEgressRequest{Url: "https://example.com"}}
+		svc, _ = newService(info)
+		_, err = svc.UpdateLayout(newContext(), &livekit.UpdateLayoutRequest{EgressId: "EG_test", Layout: "grid"})
+		require.Error(t, err)
+	})
+
+	t.Run("rejects invalid layout", func(t *testing.T) {
+		svc, _ := newService(activeRoomComposite())
+		_, err := svc.UpdateLayout(newContext(), &livekit.UpdateLayoutRequest{EgressId: "EG_test", Layout: `{"pinned":["alice"]}`})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+)
+
+// getStreamOutputs returns the stream outputs of an egress request
+func getStreamOutputs(req *rpc.StartEgressRequest) []*livekit.StreamOutput {
+	var outputs []*livekit.StreamOutput
+	switch r := req.Request.(type) {
+	case *rpc.StartEgressRequest_RoomComposite:
+		if s := r.RoomComposite.GetStream(); s != nil {
+			outputs = append(outputs, s)
+		}
+		outputs = append(outputs, r.RoomComposite.StreamOutputs...)
+	case *rpc.StartEgressRequest_Web:
+		if s := r.Web.GetStream(); s != nil {
+			outputs = append(outputs, s)
+		}
+		outputs = append(outputs, r.Web.StreamOutputs...)
+	case *rpc.StartEgressRequest_Participant:
+		outputs = append(outputs, r.Participant.StreamOutputs...)
+	case *rpc.StartEgressRequest_TrackComposite:
+		if s := r.TrackComposite.GetStream(); s != nil {
+			outputs = append(outputs, s)
+		}
+		outputs = append(outputs, r.TrackComposite.StreamOutputs...)
+	}
+	return outputs
+}
+
+// validateStreamOutputs checks stream destinations before egress is started. When the protocol
+// is not set, it is inferred from the urls so that SRT destinations are not streamed as RTMP.
+func validateStreamOutputs(outputs []*livekit.StreamOutput) error {
+	for _, o := range outputs {
+		if o.Protocol == livekit.StreamProtocol_DEFAULT_PROTOCOL {
+			o.Protocol = inferStreamProtocol(o.Urls)
+		}
+
+		if err := validateStreamURLs(o.Protocol, o.Urls); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inferStreamProtocol returns SRT when any of the urls is an SRT url, every url is then validated against it
+func inferStreamProtocol(urls []string) livekit.StreamProtocol {
+	for _, rawURL := range urls {
+		if isSRTURL(rawURL) {
+			return livekit.StreamProtocol_SRT
+		}
+	}
+	return livekit.StreamProtocol_DEFAULT_PROTOCOL
+}
+
+func validateStreamURLs(protocol livekit.StreamProtocol, urls []string) error {
+	streamIDs := make(map[string]bool, len(urls))
+	for _, rawURL := range urls {
+		if isSRTURL(rawURL) != (protocol == livekit.StreamProtocol_SRT) {
+			return psrpc.NewErrorf(psrpc.InvalidArgument, "stream url does not match protocol %s", protocol)
+		}
+		if protocol != livekit.StreamProtocol_SRT {
+			continue
+		}
+
+		streamID, err := parseSRTURL(rawURL, false)
+		if err != nil {
+			return err
+		}
+		// each destination needs its own stream id, otherwise the listener would reject all but one
+		if streamIDs[streamID] {
+			return psrpc.NewErrorf(psrpc.InvalidArgument, "duplicate srt destination")
+		}
+		streamIDs[streamID] = true
+	}
+	return nil
+}
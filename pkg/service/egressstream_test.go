@@ -0,0 +1,105 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+)
+
+type testEgressLauncher struct {
+	requests []*rpc.StartEgressRequest
+}
+
+func (l *testEgressLauncher) StartEgress(_ context.Context, req *rpc.StartEgressRequest) (*livekit.EgressInfo, error) {
+	l.requests = append(l.requests, req)
+	return &livekit.EgressInfo{EgressId: "EG_test"}, nil
+}
+
+func TestSRTEgress(t *testing.T) {
+	start := func(t *testing.T, outputs ...*livekit.StreamOutput) (*testEgressLauncher, error) {
+		launcher := &testEgressLauncher{}
+		store := &servicefakes.FakeServiceStore{}
+		store.LoadRoomReturns(&livekit.Room{Name: "room", Sid: "RM_test"}, nil, nil)
+		svc := service.NewEgressService(nil, launcher, store, nil, nil)
+
+		ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{Video: &auth.VideoGrant{RoomRecord: true}}, "")
+		_, err := svc.StartRoomCompositeEgress(ctx, &livekit.RoomCompositeEgressRequest{
+			RoomName:      "room",
+			StreamOutputs: outputs,
+		})
+		return launcher, err
+	}
+
+	t.Run("protocol is inferred from urls", func(t *testing.T) {
+		launcher, err := start(t, &livekit.StreamOutput{
+			Urls: []string{"srt://ingest.example.com:9000?streamid=a", "srt://ingest.example.com:9000?streamid=b"},
+		})
+		require.NoError(t, err)
+		require.Len(t, launcher.requests, 1)
+		require.Equal(t, livekit.StreamProtocol_SRT, launcher.requests[0].GetRoomComposite().StreamOutputs[0].Protocol)
+	})
+
+	t.Run("rtmp urls are unchanged", func(t *testing.T) {
+		launcher, err := start(t, &livekit.StreamOutput{
+			Urls: []string{"rtmp://ingest.example.com/live/key"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, livekit.StreamProtocol_DEFAULT_PROTOCOL, launcher.requests[0].GetRoomComposite().StreamOutputs[0].Protocol)
+	})
+
+	t.Run("invalid destinations are rejected", func(t *testing.T) {
+		for name, urls := range map[string][]string{
+			"mixed protocols":      {"srt://ingest.example.com:9000", "rtmp://ingest.example.com/live/key"},
+			"srt after rtmp":       {"rtmp://ingest.example.com/live/key", "srt://ingest.example.com:9000"},
+			"missing port":         {"srt://ingest.example.com?streamid=a"},
+			"duplicate stream ids": {"srt://ingest.example.com:9000?streamid=a", "srt://ingest.example.com:9000?streamid=a"},
+			"listener mode":        {"srt://ingest.example.com:9000?mode=listener"},
+		} {
+			_, err := start(t, &livekit.StreamOutput{Urls: urls})
+			require.Error(t, err, name)
+		}
+	})
+
+	t.Run("every added url is validated on update", func(t *testing.T) {
+		svc := service.NewEgressService(nil, &testEgressLauncher{}, &servicefakes.FakeServiceStore{}, nil, nil)
+		ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{Video: &auth.VideoGrant{RoomRecord: true}}, "")
+
+		for name, urls := range map[string][]string{
+			"srt after rtmp":    {"rtmp://ingest.example.com/live/key", "srt://ingest.example.com:9000"},
+			"invalid srt":       {"srt://ingest.example.com:9000?streamid=a", "srt://ingest.example.com?streamid=b"},
+			"duplicate srt ids": {"srt://ingest.example.com:9000?streamid=a", "srt://ingest.example.com:9000?streamid=a"},
+		} {
+			_, err := svc.UpdateStream(ctx, &livekit.UpdateStreamRequest{EgressId: "EG_test", AddOutputUrls: urls})
+			require.NotErrorIs(t, err, service.ErrEgressNotConnected, name)
+			require.Error(t, err, name)
+		}
+
+		_, err := svc.UpdateStream(ctx, &livekit.UpdateStreamRequest{
+			EgressId:      "EG_test",
+			AddOutputUrls: []string{"srt://ingest.example.com:9000?streamid=a", "srt://ingest.example.com:9001?streamid=a"},
+		})
+		require.ErrorIs(t, err, service.ErrEgressNotConnected)
+	})
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	ephemeralDataPath = "/room/ephemeral"
+
+	ephemeralDataMethod = "SendEphemeralData"
+)
+
+// EphemeralDataHandler sends ephemeral messages, like reactions, to rooms through the node hosting the room
+type EphemeralDataHandler struct {
+	roomAPI *RoomAPI
+}
+
+type ephemeralDataRequest struct {
+	Topic                 string   `json:"topic"`
+	Payload               string   `json:"payload"`
+	DestinationIdentities []string `json:"destination_identities,omitempty"`
+	// milliseconds, the configured default when 0
+	TTL int64 `json:"ttl,omitempty"`
+}
+
+func NewEphemeralDataHandler(roomManager *RoomManager) *EphemeralDataHandler {
+	h := &EphemeralDataHandler{
+		roomAPI: roomManager.RoomAPI(),
+	}
+	h.roomAPI.Handle(ephemeralDataMethod, h.handleSendEphemeralData)
+	return h
+}
+
+func (h *EphemeralDataHandler) SendEphemeralData(ctx context.Context, roomName livekit.RoomName, req *ephemeralDataRequest) error {
+	if !rtc.IsEphemeralTopic(req.Topic) {
+		return psrpc.NewError(psrpc.InvalidArgument, rtc.ErrEphemeralTopicInvalid)
+	}
+	return h.roomAPI.CallRoom(ctx, roomName, ephemeralDataMethod, req, nil)
+}
+
+func (h *EphemeralDataHandler) handleSendEphemeralData(_ context.Context, room *rtc.Room, _ types.LocalParticipant, body json.RawMessage) (any, error) {
+	var req ephemeralDataRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+
+	err := room.SendEphemeralData(req.Topic, []byte(req.Payload), req.DestinationIdentities, time.Duration(req.TTL)*time.Millisecond)
+	if errors.Is(err, rtc.ErrEphemeralTopicInvalid) {
+		return nil, psrpc.NewError(psrpc.InvalidArgument, err)
+	}
+	return nil, err
+}
+
+// ServeHTTP handles POST /room/ephemeral?room= {"topic", "payload", "destination_identities", "ttl"}
+// with a token that has roomAdmin permission for the room. topic must start with lk.ephemeral.
+func (h *EphemeralDataHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	roomName := livekit.RoomName(r.URL.Query().Get("room"))
+	if err := EnsureAdminPermission(r.Context(), roomName); err != nil {
+		handleError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	var req ephemeralDataRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&req); err != nil {
+		handleError(w, r, http.StatusBadRequest, err, "room", roomName)
+		return
+	}
+	if err := h.SendEphemeralData(r.Context(), roomName, &req); err != nil {
+		handleError(w, r, roomAPIStatus(err), err, "room", roomName)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
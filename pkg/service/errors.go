@@ -25,6 +25,15 @@ var (
 	ErrIngressNotConnected              = psrpc.NewErrorf(psrpc.Internal, "ingress not connected (redis required)")
 	ErrIngressNotFound                  = psrpc.NewErrorf(psrpc.NotFound, "ingress does not exist")
 	ErrIngressNonReusable               = psrpc.NewErrorf(psrpc.InvalidArgument, "ingress is not reusable and cannot be modified")
+	ErrIngressPassthroughDisabled       = psrpc.NewErrorf(psrpc.InvalidArgument, "ingress passthrough is disabled, transcoding is required")
+	ErrIngressAuthDenied                = psrpc.NewErrorf(psrpc.PermissionDenied, "ingress connection rejected by auth callback")
+	ErrIngressAuthMissingAPIKey         = psrpc.NewErrorf(psrpc.InvalidArgument, "webhook api_key is required to sign ingress auth callbacks")
+	ErrDataModerationMissingAPIKey      = psrpc.NewErrorf(psrpc.InvalidArgument, "webhook api_key is required to sign data moderation requests")
+	ErrThumbnailsMissingAPIKey          = psrpc.NewErrorf(psrpc.InvalidArgument, "webhook api_key is required to sign thumbnail requests")
+	ErrInvalidThumbnailRoomName         = psrpc.NewErrorf(psrpc.InvalidArgument, "room name cannot be used as a thumbnail directory")
+	ErrRoomHooksMissingAPIKey           = psrpc.NewErrorf(psrpc.InvalidArgument, "webhook api_key is required to sign room hook requests")
+	ErrMeteringMissingAPIKey            = psrpc.NewErrorf(psrpc.InvalidArgument, "webhook api_key is required to sign usage export requests")
+	ErrTURNAuthMissingAPIKey            = psrpc.NewErrorf(psrpc.InvalidArgument, "webhook api_key is required to sign TURN auth requests")
 	ErrNameExceedsLimits                = psrpc.NewErrorf(psrpc.InvalidArgument, "name length exceeds limits")
 	ErrMetadataExceedsLimits            = psrpc.NewErrorf(psrpc.InvalidArgument, "metadata size exceeds limits")
 	ErrAttributeExceedsLimits           = psrpc.NewErrorf(psrpc.InvalidArgument, "attribute size exceeds limits")
@@ -32,14 +41,31 @@ var (
 	ErrParticipantIdentityExceedsLimits = psrpc.NewErrorf(psrpc.InvalidArgument, "participant identity length exceeds limits")
 	ErrOperationFailed                  = psrpc.NewErrorf(psrpc.Internal, "operation cannot be completed")
 	ErrParticipantNotFound              = psrpc.NewErrorf(psrpc.NotFound, "participant does not exist")
+	ErrNotSIPParticipant                = psrpc.NewErrorf(psrpc.InvalidArgument, "participant is not a SIP participant")
+	ErrInvalidDTMF                      = psrpc.NewErrorf(psrpc.InvalidArgument, "digits must be one or more of 0-9, *, # and A-D")
+	ErrInvalidSIPTransferTarget         = psrpc.NewErrorf(psrpc.InvalidArgument, "transfer target must be a sip:, sips: or tel: URI")
+	ErrSIPCallNotOnHold                 = psrpc.NewErrorf(psrpc.FailedPrecondition, "call is not on hold")
+	ErrSIPTransferInProgress            = psrpc.NewErrorf(psrpc.FailedPrecondition, "call is already being transferred")
 	ErrRoomNotFound                     = psrpc.NewErrorf(psrpc.NotFound, "requested room does not exist")
 	ErrRoomLockFailed                   = psrpc.NewErrorf(psrpc.Internal, "could not lock room")
 	ErrRoomUnlockFailed                 = psrpc.NewErrorf(psrpc.Internal, "could not unlock room, lock token does not match")
 	ErrRemoteUnmuteNoteEnabled          = psrpc.NewErrorf(psrpc.FailedPrecondition, "remote unmute not enabled")
 	ErrTrackNotFound                    = psrpc.NewErrorf(psrpc.NotFound, "track is not found")
+	ErrSimulatedAgentMissingAPIKey      = psrpc.NewErrorf(psrpc.InvalidArgument, "an api key is required to run simulated agent workers")
 	ErrWebHookMissingAPIKey             = psrpc.NewErrorf(psrpc.InvalidArgument, "api_key is required to use webhooks")
 	ErrSIPNotConnected                  = psrpc.NewErrorf(psrpc.Internal, "sip not connected (redis required)")
 	ErrSIPTrunkNotFound                 = psrpc.NewErrorf(psrpc.NotFound, "requested sip trunk does not exist")
 	ErrSIPDispatchRuleNotFound          = psrpc.NewErrorf(psrpc.NotFound, "requested sip dispatch rule does not exist")
 	ErrSIPParticipantNotFound           = psrpc.NewErrorf(psrpc.NotFound, "requested sip participant does not exist")
+	ErrRoomQuotaExceeded                = psrpc.NewErrorf(psrpc.ResourceExhausted, "project room quota exceeded")
+	ErrParticipantQuotaExceeded         = psrpc.NewErrorf(psrpc.ResourceExhausted, "project participant quota exceeded")
+	ErrTrackQuotaExceeded               = psrpc.NewErrorf(psrpc.ResourceExhausted, "project published track quota exceeded")
+	ErrBitrateQuotaExceeded             = psrpc.NewErrorf(psrpc.ResourceExhausted, "project publish bitrate quota exceeded")
+	ErrProjectPermissionDenied          = psrpc.NewErrorf(psrpc.PermissionDenied, "resource belongs to another project")
+	ErrJoinURLNotEnabled                = psrpc.NewErrorf(psrpc.Unimplemented, "join urls are not enabled, join_url.base_url is not set")
+	ErrJoinURLUsed                      = psrpc.NewErrorf(psrpc.PermissionDenied, "join url has already been used")
+	ErrJoinURLNotSupported              = psrpc.NewErrorf(psrpc.Unimplemented, "join urls are not supported by the store")
+	ErrInvalidChaosAction               = psrpc.NewErrorf(psrpc.InvalidArgument, "action must be one of drop_rtcp, stall, kill_room or delay_bus")
+	ErrInvalidChaosDuration             = psrpc.NewErrorf(psrpc.InvalidArgument, "duration must be a positive duration such as 10s")
+	ErrNodeDraining                     = psrpc.NewErrorf(psrpc.Unavailable, "node is draining, reconnect to another node")
 )
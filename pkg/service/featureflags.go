@@ -0,0 +1,168 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/featureflags"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	featureFlagsPath = "/features"
+
+	featureFlagsMethod = "FeatureFlags"
+)
+
+// FeatureFlagsHandler reads and overrides feature flags. Overrides are kept in memory, and apply to participants
+// joining after the change. Room overrides are set on the node hosting the room and last while it hosts the room,
+// node overrides only apply to the node receiving the request, and are not shared with other nodes
+type FeatureFlagsHandler struct {
+	flags        *featureflags.Manager
+	roomAPI      *RoomAPI
+	operatorKeys []string
+}
+
+type featureFlagsOverrideRequest struct {
+	Flag featureflags.Flag `json:"flag"`
+	// overrides the flag for a room, or for the node when empty
+	Room livekit.RoomName `json:"room,omitempty"`
+	// null clears the override
+	Enabled *bool `json:"enabled"`
+}
+
+// featureFlagsRoomRequest reads the flags of a room on the node hosting it, setting an override first when Override is set
+type featureFlagsRoomRequest struct {
+	Override bool              `json:"override,omitempty"`
+	Flag     featureflags.Flag `json:"flag,omitempty"`
+	Enabled  *bool             `json:"enabled,omitempty"`
+}
+
+type featureFlagsResponse struct {
+	Flags []featureflags.FlagStatus `json:"flags"`
+}
+
+func NewFeatureFlagsHandler(roomManager *RoomManager, operatorKeys []string) *FeatureFlagsHandler {
+	h := &FeatureFlagsHandler{
+		flags:        roomManager.FeatureFlags(),
+		roomAPI:      roomManager.RoomAPI(),
+		operatorKeys: operatorKeys,
+	}
+	h.roomAPI.Handle(featureFlagsMethod, h.handleRoomFeatureFlags)
+	return h
+}
+
+// RoomFeatureFlags returns the flags of a room, through the node hosting the room. When override is set, the override
+// of flag is set to enabled first, or cleared when enabled is nil
+func (h *FeatureFlagsHandler) RoomFeatureFlags(ctx context.Context, roomName livekit.RoomName, override bool, flag featureflags.Flag, enabled *bool) ([]featureflags.FlagStatus, error) {
+	var res featureFlagsResponse
+	err := h.roomAPI.CallRoom(ctx, roomName, featureFlagsMethod, &featureFlagsRoomRequest{
+		Override: override,
+		Flag:     flag,
+		Enabled:  enabled,
+	}, &res)
+	return res.Flags, err
+}
+
+func (h *FeatureFlagsHandler) handleRoomFeatureFlags(_ context.Context, room *rtc.Room, _ types.LocalParticipant, body json.RawMessage) (any, error) {
+	var req featureFlagsRoomRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+
+	if req.Override {
+		if err := h.flags.SetRoomOverride(req.Flag, room.Name(), req.Enabled); err != nil {
+			if errors.Is(err, featureflags.ErrUnknownFlag) {
+				return nil, psrpc.NewError(psrpc.InvalidArgument, err)
+			}
+			return nil, err
+		}
+		room.Logger.Infow("feature flag overridden", "flag", req.Flag, "enabled", req.Enabled)
+	}
+	return &featureFlagsResponse{Flags: h.flags.Statuses(room.Name())}, nil
+}
+
+// ServeHTTP handles
+//
+//	GET /features?room=                            flags evaluated for the room, or the node when room is omitted
+//	POST /features {"flag", "room", "enabled"}     sets or clears an override
+//
+// room requests need roomAdmin permission for the room, node requests need roomList to read, and a token signed
+// with an operator key to override
+func (h *FeatureFlagsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var roomName livekit.RoomName
+	var req featureFlagsOverrideRequest
+	switch r.Method {
+	case http.MethodGet:
+		roomName = livekit.RoomName(r.URL.Query().Get("room"))
+	case http.MethodPost:
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&req); err != nil {
+			handleError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		roomName = req.Room
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var err error
+	switch {
+	case roomName != "":
+		err = EnsureAdminPermission(r.Context(), roomName)
+	case r.Method == http.MethodGet:
+		err = EnsureListPermission(r.Context())
+	default:
+		err = EnsureOperatorPermission(r.Context(), h.operatorKeys)
+	}
+	if err != nil {
+		handleError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	var flags []featureflags.FlagStatus
+	if roomName != "" {
+		flags, err = h.RoomFeatureFlags(r.Context(), roomName, r.Method == http.MethodPost, req.Flag, req.Enabled)
+		if err != nil {
+			handleError(w, r, roomAPIStatus(err), err, "flag", req.Flag, "room", roomName)
+			return
+		}
+	} else {
+		if r.Method == http.MethodPost {
+			if err = h.flags.SetNodeOverride(req.Flag, req.Enabled); err != nil {
+				status := http.StatusInternalServerError
+				if errors.Is(err, featureflags.ErrUnknownFlag) {
+					status = http.StatusBadRequest
+				}
+				handleError(w, r, status, err, "flag", req.Flag)
+				return
+			}
+			logger.Infow("feature flag overridden on node", "flag", req.Flag, "enabled", req.Enabled)
+		}
+		flags = h.flags.Statuses("")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(&featureFlagsResponse{Flags: flags})
+}
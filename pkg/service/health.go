@@ -0,0 +1,372 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+)
+
+const (
+	healthPath = "/healthz"
+
+	healthCheckTimeout = 2 * time.Second
+	// reports are reused for this long, so load balancers polling every node do not multiply dependency checks
+	healthReportTTL = time.Second
+	// dependencies slower than this are degraded
+	healthLatencyDegraded = 250 * time.Millisecond
+	// egress and ingress are degraded when workers failed to respond within this window
+	workerFailureWindow = 5 * time.Minute
+
+	busPingInterval = 100 * time.Millisecond
+)
+
+type HealthStatus string
+
+const (
+	HealthOK HealthStatus = "ok"
+	// the node keeps serving, with reduced functionality
+	HealthDegraded HealthStatus = "degraded"
+	// the node cannot serve sessions
+	HealthUnhealthy HealthStatus = "unhealthy"
+)
+
+func (s HealthStatus) worse(o HealthStatus) bool {
+	rank := func(s HealthStatus) int {
+		switch s {
+		case HealthDegraded:
+			return 1
+		case HealthUnhealthy:
+			return 2
+		default:
+			return 0
+		}
+	}
+	return rank(s) > rank(o)
+}
+
+// DependencyHealth is the status of a dependency. The endpoint is unauthenticated, details are logged rather than
+// returned
+type DependencyHealth struct {
+	Status HealthStatus `json:"status"`
+	// round trip of the check, in milliseconds
+	LatencyMs float64 `json:"-"`
+	Message   string  `json:"-"`
+}
+
+type HealthReport struct {
+	Status    HealthStatus                 `json:"status"`
+	CheckedAt time.Time                    `json:"-"`
+	Checks    map[string]*DependencyHealth `json:"checks"`
+}
+
+// HealthChecker reports the status of the dependencies of this node: Redis, the message bus, node registration,
+// the TURN listeners, and egress and ingress workers. Dependencies that are not configured are left out
+type HealthChecker struct {
+	conf        *config.Config
+	currentNode routing.LocalNode
+	router      routing.Router
+	rc          redis.UniversalClient
+	failover    *routing.FailoverMonitor
+	kps         rpc.KeepalivePubSub
+	io          *IOInfoService
+	isDraining  func() bool
+
+	lock   sync.Mutex
+	report *HealthReport
+}
+
+func NewHealthChecker(
+	conf *config.Config,
+	currentNode routing.LocalNode,
+	router routing.Router,
+	rc redis.UniversalClient,
+	failover *routing.FailoverMonitor,
+	kps rpc.KeepalivePubSub,
+	io *IOInfoService,
+	isDraining func() bool,
+) *HealthChecker {
+	return &HealthChecker{
+		conf:        conf,
+		currentNode: currentNode,
+		router:      router,
+		rc:          rc,
+		failover:    failover,
+		kps:         kps,
+		io:          io,
+		isDraining:  isDraining,
+	}
+}
+
+// ServeHTTP handles GET /healthz. Degraded nodes respond with 200 unless strict=true is set, unhealthy nodes
+// respond with 503. The body is a HealthReport, holding the status of each dependency
+func (c *HealthChecker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := c.Check(r.Context())
+	status := http.StatusOK
+	if report.Status == HealthUnhealthy || (report.Status == HealthDegraded && boolValue(r.URL.Query().Get("strict"))) {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// Check runs every dependency check, the status of the node is the worst status of its dependencies
+func (c *HealthChecker) Check(ctx context.Context) *HealthReport {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.report != nil && time.Since(c.report.CheckedAt) < healthReportTTL {
+		return c.report
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	checks := map[string]func(context.Context) *DependencyHealth{
+		"node": c.checkNode,
+	}
+	if c.rc != nil {
+		checks["redis"] = c.checkRedis
+	}
+	if c.kps != nil {
+		checks["message_bus"] = c.checkMessageBus
+	}
+	if c.conf.TURN.Enabled {
+		checks["turn"] = c.checkTURN
+	}
+	if c.io != nil {
+		checks["egress"] = func(context.Context) *DependencyHealth { return c.io.egressWorkers.health("egress") }
+		checks["ingress"] = func(context.Context) *DependencyHealth { return c.io.ingressWorkers.health("ingress") }
+	}
+
+	report := &HealthReport{
+		Status:    HealthOK,
+		CheckedAt: time.Now(),
+		Checks:    make(map[string]*DependencyHealth, len(checks)),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(context.Context) *DependencyHealth) {
+			defer wg.Done()
+			h := check(ctx)
+			mu.Lock()
+			report.Checks[name] = h
+			if h.Status.worse(report.Status) {
+				report.Status = h.Status
+			}
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	for name, h := range report.Checks {
+		if h.Status != HealthOK {
+			logger.Warnw("health check failed", nil, "check", name, "status", h.Status, "message", h.Message, "latencyMs", h.LatencyMs)
+		}
+	}
+
+	c.report = report
+	return report
+}
+
+// checkNode verifies the node is kept alive by the router and registered with the cluster
+func (c *HealthChecker) checkNode(_ context.Context) *DependencyHealth {
+	var updatedAt time.Time
+	if c.currentNode.Stats != nil {
+		updatedAt = time.Unix(c.currentNode.Stats.UpdatedAt, 0)
+	}
+	if age := time.Since(updatedAt); age > nodeStatsStaleAfter {
+		return &DependencyHealth{Status: HealthUnhealthy, Message: fmt.Sprintf("node stats not updated for %s", age.Truncate(time.Second))}
+	}
+
+	nodes, err := c.router.ListNodes()
+	if err != nil {
+		return &DependencyHealth{Status: HealthUnhealthy, Message: err.Error()}
+	}
+	registered := false
+	for _, node := range nodes {
+		if node.Id == c.currentNode.Id {
+			registered = true
+			break
+		}
+	}
+	if !registered {
+		return &DependencyHealth{Status: HealthUnhealthy, Message: "node is not registered"}
+	}
+	if c.isDraining != nil && c.isDraining() {
+		return &DependencyHealth{Status: HealthDegraded, Message: "draining"}
+	}
+	return &DependencyHealth{Status: HealthOK}
+}
+
+func (c *HealthChecker) checkRedis(ctx context.Context) *DependencyHealth {
+	if c.failover.IsFailingOver() {
+		return &DependencyHealth{Status: HealthUnhealthy, Message: "failing over"}
+	}
+	start := time.Now()
+	if err := c.rc.Ping(ctx).Err(); err != nil {
+		return &DependencyHealth{Status: HealthUnhealthy, Message: err.Error()}
+	}
+	return latencyHealth(time.Since(start))
+}
+
+// checkMessageBus publishes pings to this node through the message bus until one is received. Pings carry their
+// send time, as a ping published before the subscription was active is lost
+func (c *HealthChecker) checkMessageBus(ctx context.Context) *DependencyHealth {
+	topic := livekit.NodeID(c.currentNode.Id + "_health")
+	pings, err := c.kps.SubscribePing(ctx, topic)
+	if err != nil {
+		return &DependencyHealth{Status: HealthUnhealthy, Message: err.Error()}
+	}
+	defer pings.Close()
+
+	ticker := time.NewTicker(busPingInterval)
+	defer ticker.Stop()
+	for {
+		c.kps.PublishPing(ctx, topic, &rpc.KeepalivePing{Timestamp: time.Now().UnixNano()})
+
+		select {
+		case ping, ok := <-pings.Channel():
+			if !ok {
+				return &DependencyHealth{Status: HealthUnhealthy, Message: "subscription closed"}
+			}
+			return latencyHealth(time.Since(time.Unix(0, ping.Timestamp)))
+		case <-ticker.C:
+		case <-ctx.Done():
+			return &DependencyHealth{Status: HealthUnhealthy, Message: "ping not received"}
+		}
+	}
+}
+
+// checkTURN connects to the TURN listeners of this node. TURN failures degrade the node, as clients that can
+// reach it directly are not affected
+func (c *HealthChecker) checkTURN(ctx context.Context) *DependencyHealth {
+	turnConf := c.conf.TURN
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(healthCheckTimeout)
+	}
+	timeout := time.Until(deadline)
+
+	start := time.Now()
+	if turnConf.UDPPort > 0 {
+		if _, err := probeTURNServer(config.TURNServer{Host: "127.0.0.1", Port: turnConf.UDPPort, Protocol: "udp"}, timeout); err != nil {
+			return &DependencyHealth{Status: HealthDegraded, Message: "udp listener: " + err.Error()}
+		}
+	}
+	if turnConf.TLSPort > 0 {
+		addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(turnConf.TLSPort))
+		var err error
+		if turnConf.ExternalTLS {
+			// TLS is terminated by a load balancer, the listener speaks plain TURN over TCP
+			_, err = probeTURNServer(config.TURNServer{Host: "127.0.0.1", Port: turnConf.TLSPort, Protocol: "tcp"}, timeout)
+		} else {
+			var conn net.Conn
+			if conn, err = net.DialTimeout("tcp", addr, timeout); err == nil {
+				_ = conn.Close()
+			}
+		}
+		if err != nil {
+			return &DependencyHealth{Status: HealthDegraded, Message: "tls listener: " + err.Error()}
+		}
+	}
+	return &DependencyHealth{Status: HealthOK, LatencyMs: durationMs(time.Since(start))}
+}
+
+func latencyHealth(latency time.Duration) *DependencyHealth {
+	h := &DependencyHealth{Status: HealthOK, LatencyMs: durationMs(latency)}
+	if latency > healthLatencyDegraded {
+		h.Status = HealthDegraded
+		h.Message = "slow responses"
+	}
+	return h
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// workerReachability tracks whether egress or ingress workers respond. Workers are reachable when they report in,
+// or answer a request, and unreachable when requests go unanswered
+type workerReachability struct {
+	lock        sync.Mutex
+	lastSeen    time.Time
+	lastFailure time.Time
+	lastErr     error
+}
+
+func (w *workerReachability) seen() {
+	w.lock.Lock()
+	w.lastSeen = time.Now()
+	w.lock.Unlock()
+}
+
+// recordResult records the outcome of a request to workers, errors other than missing responses are ignored
+// as they come from a worker that was reached
+func (w *workerReachability) recordResult(err error) {
+	if err == nil {
+		w.seen()
+		return
+	}
+	var psrpcErr psrpc.Error
+	if !errors.As(err, &psrpcErr) || (psrpcErr.Code() != psrpc.Unavailable && psrpcErr.Code() != psrpc.DeadlineExceeded) {
+		w.seen()
+		return
+	}
+
+	w.lock.Lock()
+	w.lastFailure = time.Now()
+	w.lastErr = err
+	w.lock.Unlock()
+}
+
+func (w *workerReachability) health(service string) *DependencyHealth {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	switch {
+	case !w.lastFailure.IsZero() && w.lastFailure.After(w.lastSeen) && time.Since(w.lastFailure) < workerFailureWindow:
+		return &DependencyHealth{Status: HealthDegraded, Message: fmt.Sprintf("%s workers unreachable: %v", service, w.lastErr)}
+	case w.lastSeen.IsZero():
+		return &DependencyHealth{Status: HealthOK, Message: "no " + service + " activity"}
+	default:
+		return &DependencyHealth{Status: HealthOK, Message: fmt.Sprintf("last seen %s ago", time.Since(w.lastSeen).Truncate(time.Second))}
+	}
+}
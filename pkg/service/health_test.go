@@ -0,0 +1,105 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestHealthChecker(t *testing.T) {
+	node := &livekit.Node{Id: "node", Stats: &livekit.NodeStats{UpdatedAt: time.Now().Unix()}}
+	router := &routingfakes.FakeRouter{}
+	router.ListNodesReturns([]*livekit.Node{node}, nil)
+	kps, err := rpc.NewKeepalivePubSub(rpc.NewClientParams(rpc.PSRPCConfig{}, psrpc.NewLocalMessageBus(), logger.GetLogger(), nil))
+	require.NoError(t, err)
+
+	draining := false
+	newChecker := func() *service.HealthChecker {
+		return service.NewHealthChecker(&config.Config{}, node, router, nil, nil, kps, nil, func() bool { return draining })
+	}
+	get := func(c *service.HealthChecker, query string) (int, *service.HealthReport) {
+		w := httptest.NewRecorder()
+		c.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz"+query, nil))
+		var report service.HealthReport
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		return w.Code, &report
+	}
+
+	t.Run("healthy", func(t *testing.T) {
+		code, report := get(newChecker(), "")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, service.HealthOK, report.Status)
+		require.Equal(t, service.HealthOK, report.Checks["node"].Status)
+		// the message bus is checked with a round trip
+		require.Equal(t, service.HealthOK, report.Checks["message_bus"].Status)
+		require.NotContains(t, report.Checks, "redis")
+		require.NotContains(t, report.Checks, "turn")
+	})
+
+	t.Run("details are not exposed", func(t *testing.T) {
+		router.ListNodesReturns(nil, nil)
+		defer router.ListNodesReturns([]*livekit.Node{node}, nil)
+
+		w := httptest.NewRecorder()
+		newChecker().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		require.NotContains(t, w.Body.String(), "not registered")
+		require.NotContains(t, w.Body.String(), "node_id")
+	})
+
+	t.Run("draining is degraded", func(t *testing.T) {
+		draining = true
+		defer func() { draining = false }()
+
+		checker := newChecker()
+		code, report := get(checker, "")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, service.HealthDegraded, report.Status)
+
+		code, _ = get(checker, "?strict=true")
+		require.Equal(t, http.StatusServiceUnavailable, code)
+	})
+
+	t.Run("unregistered node is unhealthy", func(t *testing.T) {
+		router.ListNodesReturns(nil, nil)
+		defer router.ListNodesReturns([]*livekit.Node{node}, nil)
+
+		code, report := get(newChecker(), "")
+		require.Equal(t, http.StatusServiceUnavailable, code)
+		require.Equal(t, service.HealthUnhealthy, report.Status)
+		require.Equal(t, service.HealthUnhealthy, report.Checks["node"].Status)
+	})
+
+	t.Run("reports are reused", func(t *testing.T) {
+		checker := newChecker()
+		first := checker.Check(context.Background())
+		require.Same(t, first, checker.Check(context.Background()))
+	})
+}
@@ -0,0 +1,240 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	hlsPathPrefix   = "/hls/"
+	hlsPollInterval = 50 * time.Millisecond
+
+	hlsPlaylistExtension   = ".m3u8"
+	hlsPlaylistContentType = "application/vnd.apple.mpegurl"
+)
+
+// HLSHandler serves HLS written by egress to a local directory, with the output of each room in a
+// sub directory named after the room. Requests need a token allowed to subscribe in the room.
+// It implements the server side of low-latency HLS: playlist requests with _HLS_msn and _HLS_part
+// are held until the requested segment or part is in the playlist, and requests for parts announced
+// with a preload hint are held until the part is written. Partial segments and preload hints
+// themselves are produced by egress.
+type HLSHandler struct {
+	conf config.HLSConfig
+	dir  http.Dir
+}
+
+func NewHLSHandler(conf config.HLSConfig) *HLSHandler {
+	return &HLSHandler{
+		conf: conf,
+		dir:  http.Dir(conf.Directory),
+	}
+}
+
+func (h *HLSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := path.Clean("/" + strings.TrimPrefix(r.URL.Path, hlsPathPrefix))
+	roomName, _, _ := strings.Cut(strings.TrimPrefix(name, "/"), "/")
+	if roomName == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err := EnsureSubscribePermission(r.Context(), livekit.RoomName(roomName)); err != nil {
+		handleError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+	// only wait for files of rooms egress is writing
+	if stat, err := os.Stat(filepath.Join(string(h.dir), filepath.FromSlash(roomName))); err != nil || !stat.IsDir() {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.conf.BlockingTimeout)
+	defer cancel()
+
+	if path.Ext(name) == hlsPlaylistExtension {
+		h.servePlaylist(ctx, w, r, name)
+	} else {
+		h.serveSegment(ctx, w, r, name)
+	}
+}
+
+func (h *HLSHandler) servePlaylist(ctx context.Context, w http.ResponseWriter, r *http.Request, name string) {
+	query := r.URL.Query()
+
+	var msn, part uint64
+	var blocking, hasPart bool
+	var err error
+	if v := query.Get("_HLS_msn"); v != "" {
+		if msn, err = strconv.ParseUint(v, 10, 64); err != nil {
+			handleError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		blocking = true
+	}
+	if v := query.Get("_HLS_part"); v != "" {
+		if !blocking {
+			// _HLS_part is only valid with _HLS_msn
+			handleError(w, r, http.StatusBadRequest, errors.New("_HLS_part requires _HLS_msn"))
+			return
+		}
+		if part, err = strconv.ParseUint(v, 10, 64); err != nil {
+			handleError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		hasPart = true
+	}
+
+	for {
+		data, err := h.readFile(name)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			handleError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		if err == nil {
+			if !blocking {
+				writePlaylist(w, r, data)
+				return
+			}
+
+			pos := parsePlaylistPosition(data)
+			if msn > pos.nextMSN+1 {
+				// too far ahead of the playlist, as defined by the spec
+				handleError(w, r, http.StatusBadRequest, errors.New("_HLS_msn too far in the future"))
+				return
+			}
+			if pos.contains(msn, part, hasPart) {
+				writePlaylist(w, r, data)
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				w.WriteHeader(http.StatusNotFound)
+			} else {
+				// the playlist was not updated in time
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			return
+		case <-time.After(hlsPollInterval):
+		}
+	}
+}
+
+func (h *HLSHandler) serveSegment(ctx context.Context, w http.ResponseWriter, r *http.Request, name string) {
+	for {
+		f, err := h.dir.Open(name)
+		if err == nil {
+			defer f.Close()
+			stat, err := f.Stat()
+			if err != nil || stat.IsDir() {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			// segments and parts do not change once written
+			w.Header().Set("Cache-Control", "max-age=3600")
+			http.ServeContent(w, r, stat.Name(), stat.ModTime(), f)
+			return
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			handleError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		// parts announced with a preload hint are requested before they are written
+		select {
+		case <-ctx.Done():
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case <-time.After(hlsPollInterval):
+		}
+	}
+}
+
+func (h *HLSHandler) readFile(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(string(h.dir), filepath.FromSlash(name)))
+}
+
+func writePlaylist(w http.ResponseWriter, r *http.Request, data []byte) {
+	w.Header().Set("Content-Type", hlsPlaylistContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		if _, err := w.Write(data); err != nil {
+			logger.Debugw("could not write playlist", "error", err)
+		}
+	}
+}
+
+// playlistPosition is the latest segment and part in a media playlist
+type playlistPosition struct {
+	// media sequence number of the segment being written
+	nextMSN uint64
+	// number of parts of that segment in the playlist
+	parts uint64
+}
+
+func parsePlaylistPosition(data []byte) playlistPosition {
+	var mediaSequence, segments, parts uint64
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+			mediaSequence, _ = strconv.ParseUint(strings.TrimPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"), 10, 64)
+		case strings.HasPrefix(line, "#EXTINF:"):
+			segments++
+			parts = 0
+		case strings.HasPrefix(line, "#EXT-X-PART:"):
+			parts++
+		}
+	}
+	return playlistPosition{
+		nextMSN: mediaSequence + segments,
+		parts:   parts,
+	}
+}
+
+// contains returns true when the playlist has the requested segment, or the requested part of it
+func (p playlistPosition) contains(msn, part uint64, hasPart bool) bool {
+	if msn < p.nextMSN {
+		return true
+	}
+	return hasPart && msn == p.nextMSN && part < p.parts
+}
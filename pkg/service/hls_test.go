@@ -0,0 +1,135 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+const testPlaylist = `#EXTM3U
+#EXT-X-TARGETDURATION:2
+#EXT-X-PART-INF:PART-TARGET=0.5
+#EXT-X-MEDIA-SEQUENCE:10
+#EXT-X-PART:DURATION=0.5,URI="s10.0.m4s"
+#EXTINF:2.0,
+s10.m4s
+#EXT-X-PART:DURATION=0.5,URI="s11.0.m4s"
+#EXT-X-PART:DURATION=0.5,URI="s11.1.m4s"
+#EXT-X-PRELOAD-HINT:TYPE=PART,URI="s11.2.m4s"
+`
+
+func TestHLSHandler(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "room")
+	require.NoError(t, os.Mkdir(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "live.m3u8"), []byte(testPlaylist), 0644))
+	h := service.NewHLSHandler(config.HLSConfig{
+		Enabled:         true,
+		Directory:       root,
+		BlockingTimeout: 200 * time.Millisecond,
+	})
+
+	subscriber := &auth.ClaimGrants{Video: &auth.VideoGrant{RoomJoin: true, Room: "room"}}
+	getWithGrants := func(grants *auth.ClaimGrants, path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if grants != nil {
+			r = r.WithContext(service.WithGrants(r.Context(), grants, ""))
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		return getWithGrants(subscriber, "/hls/room"+path)
+	}
+
+	t.Run("serves playlist", func(t *testing.T) {
+		w := get("/live.m3u8")
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, testPlaylist, w.Body.String())
+	})
+
+	t.Run("available part is returned immediately", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, get("/live.m3u8?_HLS_msn=11&_HLS_part=1").Code)
+		require.Equal(t, http.StatusOK, get("/live.m3u8?_HLS_msn=10").Code)
+	})
+
+	t.Run("blocks until part is available", func(t *testing.T) {
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			updated := testPlaylist + "#EXT-X-PART:DURATION=0.5,URI=\"s11.2.m4s\"\n"
+			_ = os.WriteFile(filepath.Join(dir, "next.m3u8"), []byte(updated), 0644)
+		}()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "next.m3u8"), []byte(testPlaylist), 0644))
+		require.Equal(t, http.StatusOK, get("/next.m3u8?_HLS_msn=11&_HLS_part=2").Code)
+	})
+
+	t.Run("times out when playlist is not updated", func(t *testing.T) {
+		require.Equal(t, http.StatusServiceUnavailable, get("/live.m3u8?_HLS_msn=12").Code)
+	})
+
+	t.Run("rejects requests too far ahead", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, get("/live.m3u8?_HLS_msn=20").Code)
+		require.Equal(t, http.StatusBadRequest, get("/live.m3u8?_HLS_part=1").Code)
+	})
+
+	t.Run("waits for preload hinted part", func(t *testing.T) {
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			_ = os.WriteFile(filepath.Join(dir, "s11.2.m4s"), []byte("part"), 0644)
+		}()
+		w := get("/s11.2.m4s")
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "part", w.Body.String())
+	})
+
+	t.Run("does not serve outside directory", func(t *testing.T) {
+		// the cleaned path is outside of the room the token is for
+		require.Equal(t, http.StatusUnauthorized, get("/../../etc/passwd").Code)
+	})
+
+	t.Run("requires subscribe permission for the room", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, getWithGrants(nil, "/hls/room/live.m3u8").Code)
+		require.Equal(t, http.StatusUnauthorized, getWithGrants(&auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomJoin: true, Room: "other"},
+		}, "/hls/room/live.m3u8").Code)
+
+		noSubscribe := false
+		require.Equal(t, http.StatusUnauthorized, getWithGrants(&auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomJoin: true, Room: "room", CanSubscribe: &noSubscribe},
+		}, "/hls/room/live.m3u8").Code)
+	})
+
+	t.Run("unknown rooms are not found immediately", func(t *testing.T) {
+		start := time.Now()
+		w := getWithGrants(&auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomJoin: true, Room: "missing"},
+		}, "/hls/missing/live.m3u8?_HLS_msn=1")
+		require.Equal(t, http.StatusNotFound, w.Code)
+		require.Less(t, time.Since(start), 100*time.Millisecond)
+	})
+}
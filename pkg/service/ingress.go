@@ -133,7 +133,14 @@ func (s *IngressService) CreateIngressWithUrl(ctx context.Context, urlStr string
 		if err != nil {
 			return nil, psrpc.NewError(psrpc.InvalidArgument, err)
 		}
-		if urlObj.Scheme != "http" && urlObj.Scheme != "https" && urlObj.Scheme != "srt" {
+		switch urlObj.Scheme {
+		case "http", "https":
+		case srtScheme:
+			// the ingress either calls the encoder, or listens for it to connect with mode=listener
+			if _, err := parseSRTURL(req.Url, true); err != nil {
+				return nil, err
+			}
+		default:
 			return nil, ingress.ErrInvalidIngress(fmt.Sprintf("invalid url scheme %s", urlObj.Scheme))
 		}
 		// Marshall the URL again for sanitization
@@ -176,7 +183,9 @@ func (s *IngressService) CreateIngressWithUrl(ctx context.Context, urlStr string
 		return nil, ingress.ErrInvalidIngressType
 	}
 
-	updateEnableTranscoding(info)
+	if err := updateEnableTranscoding(info, !s.conf.DisableWHIPPassthrough); err != nil {
+		return nil, err
+	}
 
 	if req.InputType == livekit.IngressInput_URL_INPUT {
 		retInfo, err := s.launcher.LaunchPullIngress(ctx, info)
@@ -213,18 +222,27 @@ func (s *IngressService) LaunchPullIngress(ctx context.Context, info *livekit.In
 		Info: info,
 	}
 
-	return s.psrpcClient.StartIngress(ctx, req)
+	info, err := s.psrpcClient.StartIngress(ctx, req)
+	if r, ok := s.io.(workerResultRecorder); ok {
+		r.recordIngressResult(err)
+	}
+	return info, err
 }
 
-func updateEnableTranscoding(info *livekit.IngressInfo) {
+// updateEnableTranscoding defaults WHIP ingresses to passthrough, forwarding the publisher's
+// encoded layers as is, unless passthrough has been disabled in the config.
+func updateEnableTranscoding(info *livekit.IngressInfo, allowPassthrough bool) error {
 	// Set BypassTranscoding as well for backward compatiblity
 	if info.EnableTranscoding != nil {
+		if !*info.EnableTranscoding && !allowPassthrough {
+			return ErrIngressPassthroughDisabled
+		}
 		info.BypassTranscoding = !*info.EnableTranscoding
-		return
+		return nil
 	}
 
-	switch info.InputType {
-	case livekit.IngressInput_WHIP_INPUT:
+	switch {
+	case info.InputType == livekit.IngressInput_WHIP_INPUT && allowPassthrough:
 		f := false
 		info.EnableTranscoding = &f
 		info.BypassTranscoding = true
@@ -232,9 +250,10 @@ func updateEnableTranscoding(info *livekit.IngressInfo) {
 		t := true
 		info.EnableTranscoding = &t
 	}
+	return nil
 }
 
-func updateInfoUsingRequest(req *livekit.UpdateIngressRequest, info *livekit.IngressInfo) error {
+func updateInfoUsingRequest(req *livekit.UpdateIngressRequest, info *livekit.IngressInfo, allowPassthrough bool) error {
 	if req.Name != "" {
 		info.Name = req.Name
 	}
@@ -265,9 +284,7 @@ func updateInfoUsingRequest(req *livekit.UpdateIngressRequest, info *livekit.Ing
 		return err
 	}
 
-	updateEnableTranscoding(info)
-
-	return nil
+	return updateEnableTranscoding(info, allowPassthrough)
 }
 
 func (s *IngressService) UpdateIngress(ctx context.Context, req *livekit.UpdateIngressRequest) (*livekit.IngressInfo, error) {
@@ -312,14 +329,14 @@ func (s *IngressService) UpdateIngress(ctx context.Context, req *livekit.UpdateI
 		fallthrough
 
 	case livekit.IngressState_ENDPOINT_INACTIVE:
-		err = updateInfoUsingRequest(req, info)
+		err = updateInfoUsingRequest(req, info, !s.conf.DisableWHIPPassthrough)
 		if err != nil {
 			return nil, err
 		}
 
 	case livekit.IngressState_ENDPOINT_BUFFERING,
 		livekit.IngressState_ENDPOINT_PUBLISHING:
-		err := updateInfoUsingRequest(req, info)
+		err := updateInfoUsingRequest(req, info, !s.conf.DisableWHIPPassthrough)
 		if err != nil {
 			return nil, err
 		}
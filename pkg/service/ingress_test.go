@@ -0,0 +1,119 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+)
+
+type testIngressLauncher struct{}
+
+func (testIngressLauncher) LaunchPullIngress(_ context.Context, info *livekit.IngressInfo) (*livekit.IngressInfo, error) {
+	return info, nil
+}
+
+func TestSRTIngress(t *testing.T) {
+	create := func(url string) (*livekit.IngressInfo, error) {
+		svc := service.NewIngressServiceWithIngressLauncher(
+			&config.IngressConfig{},
+			"node",
+			nil,
+			nil,
+			&servicefakes.FakeIngressStore{},
+			&servicefakes.FakeIOClient{},
+			nil,
+			testIngressLauncher{},
+		)
+		ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{Video: &auth.VideoGrant{IngressAdmin: true}}, "")
+		return svc.CreateIngress(ctx, &livekit.CreateIngressRequest{
+			InputType:           livekit.IngressInput_URL_INPUT,
+			Url:                 url,
+			RoomName:            "room",
+			ParticipantIdentity: "encoder",
+		})
+	}
+
+	for _, url := range []string{
+		"srt://encoder.example.com:9000?streamid=live&passphrase=0123456789",
+		"srt://:9000?mode=listener",
+		"srt://0.0.0.0:9000?mode=listener&passphrase=0123456789",
+	} {
+		info, err := create(url)
+		require.NoError(t, err, url)
+		require.True(t, info.GetEnableTranscoding(), url)
+	}
+
+	for _, url := range []string{
+		"srt://encoder.example.com",
+		"srt://:9000",
+		"srt://encoder.example.com:9000?mode=rendezvous",
+		"srt://encoder.example.com:9000?passphrase=short",
+	} {
+		_, err := create(url)
+		require.Error(t, err, url)
+	}
+}
+
+func TestWHIPPassthrough(t *testing.T) {
+	create := func(conf *config.IngressConfig, enableTranscoding *bool) (*livekit.IngressInfo, error) {
+		svc := service.NewIngressServiceWithIngressLauncher(
+			conf,
+			"node",
+			nil,
+			nil,
+			&servicefakes.FakeIngressStore{},
+			&servicefakes.FakeIOClient{},
+			nil,
+			testIngressLauncher{},
+		)
+		ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{Video: &auth.VideoGrant{IngressAdmin: true}}, "")
+		return svc.CreateIngress(ctx, &livekit.CreateIngressRequest{
+			InputType:           livekit.IngressInput_WHIP_INPUT,
+			RoomName:            "room",
+			ParticipantIdentity: "publisher",
+			EnableTranscoding:   enableTranscoding,
+		})
+	}
+	f := false
+
+	t.Run("passthrough by default", func(t *testing.T) {
+		info, err := create(&config.IngressConfig{}, nil)
+		require.NoError(t, err)
+		require.False(t, info.GetEnableTranscoding())
+		require.True(t, info.BypassTranscoding)
+	})
+
+	t.Run("transcoded when passthrough is disabled", func(t *testing.T) {
+		info, err := create(&config.IngressConfig{DisableWHIPPassthrough: true}, nil)
+		require.NoError(t, err)
+		require.True(t, info.GetEnableTranscoding())
+		require.False(t, info.BypassTranscoding)
+	})
+
+	t.Run("explicit passthrough rejected when disabled", func(t *testing.T) {
+		_, err := create(&config.IngressConfig{DisableWHIPPassthrough: true}, &f)
+		require.ErrorIs(t, err, service.ErrIngressPassthroughDisabled)
+	})
+}
@@ -0,0 +1,138 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/metadata"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	// metadata keys the ingress service can attach to GetIngressInfo requests
+	ingressClientIPKey  = "client_ip"
+	ingressInputTypeKey = "input_type"
+)
+
+// IngressAuthRequest is posted to the configured auth URL when an encoder connects
+type IngressAuthRequest struct {
+	StreamKey string `json:"stream_key"`
+	ClientIP  string `json:"client_ip,omitempty"`
+	IngressID string `json:"ingress_id,omitempty"`
+	InputType string `json:"input_type,omitempty"`
+}
+
+// IngressAuthResponse decides whether the connection is accepted, and optionally where it is published
+type IngressAuthResponse struct {
+	Allow               bool   `json:"allow"`
+	RoomName            string `json:"room_name,omitempty"`
+	ParticipantIdentity string `json:"participant_identity,omitempty"`
+	ParticipantName     string `json:"participant_name,omitempty"`
+	ParticipantMetadata string `json:"participant_metadata,omitempty"`
+}
+
+// IngressAuthenticator calls out to an external service to validate ingress stream keys.
+// Requests are signed the same way as webhooks, so receivers can verify them with webhook.Receive.
+type IngressAuthenticator struct {
+	url       string
+	apiKey    string
+	apiSecret string
+	client    *http.Client
+}
+
+func NewIngressAuthenticator(conf *config.Config, provider auth.KeyProvider) (*IngressAuthenticator, error) {
+	if conf.Ingress.AuthURL == "" {
+		return nil, nil
+	}
+	secret := provider.GetSecret(conf.WebHook.APIKey)
+	if secret == "" {
+		return nil, ErrIngressAuthMissingAPIKey
+	}
+
+	return &IngressAuthenticator{
+		url:       conf.Ingress.AuthURL,
+		apiKey:    conf.WebHook.APIKey,
+		apiSecret: secret,
+		client:    &http.Client{Timeout: conf.Ingress.AuthTimeout},
+	}, nil
+}
+
+func (a *IngressAuthenticator) Authenticate(ctx context.Context, req *IngressAuthRequest) (*IngressAuthResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(body)
+	token, err := auth.NewAccessToken(a.apiKey, a.apiSecret).
+		SetValidFor(5 * time.Minute).
+		SetSha256(base64.StdEncoding.EncodeToString(sum[:])).
+		ToJWT()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Authorization", token)
+	r.Header.Set("Content-Type", "application/webhook+json")
+
+	resp, err := a.client.Do(r)
+	if err != nil {
+		return nil, psrpc.NewError(psrpc.Unavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, psrpc.NewErrorf(psrpc.Unavailable, "ingress auth callback returned %d", resp.StatusCode)
+	}
+
+	res := &IngressAuthResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return nil, psrpc.NewError(psrpc.Unavailable, fmt.Errorf("invalid ingress auth response: %w", err))
+	}
+	return res, nil
+}
+
+func newIngressAuthRequest(ctx context.Context, streamKey string, info *livekit.IngressInfo) *IngressAuthRequest {
+	req := &IngressAuthRequest{
+		StreamKey: streamKey,
+	}
+	if h := metadata.IncomingHeader(ctx); h != nil {
+		req.ClientIP = h.Metadata[ingressClientIPKey]
+		req.InputType = h.Metadata[ingressInputTypeKey]
+	}
+	if info != nil {
+		req.IngressID = info.IngressId
+		if req.InputType == "" {
+			req.InputType = info.InputType.String()
+		}
+	}
+	return req
+}
@@ -19,6 +19,8 @@ import (
 	"time"
 
 	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/agent"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
@@ -71,10 +73,26 @@ type IngressStore interface {
 	DeleteIngress(ctx context.Context, info *livekit.IngressInfo) error
 }
 
+// QuotaStore shares project usage between nodes
+type QuotaStore interface {
+	StoreQuotaUsage(ctx context.Context, nodeID livekit.NodeID, usage map[string]*QuotaUsage) error
+	LoadQuotaUsage(ctx context.Context, apiKey string) (map[livekit.NodeID]*QuotaUsage, error)
+}
+
+// JoinTokenStore records the tokens of join urls that have been used
+type JoinTokenStore interface {
+	// ReserveJoinToken records the token as used for ttl, and returns false when it was already used
+	ReserveJoinToken(ctx context.Context, tokenID string, ttl time.Duration) (bool, error)
+	// ConsumeJoinToken keeps a reserved token recorded as used for ttl
+	ConsumeJoinToken(ctx context.Context, tokenID string, ttl time.Duration) error
+	// ReleaseJoinToken makes a reserved token usable again
+	ReleaseJoinToken(ctx context.Context, tokenID string) error
+}
+
 //counterfeiter:generate . RoomAllocator
 type RoomAllocator interface {
 	CreateRoomEnabled() bool
-	SelectRoomNode(ctx context.Context, roomName livekit.RoomName, nodeID livekit.NodeID) error
+	SelectRoomNode(ctx context.Context, roomName livekit.RoomName, nodeID livekit.NodeID, configName string) error
 	CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest, isExplicit bool) (*livekit.Room, *livekit.RoomInternal, bool, error)
 	ValidateCreateRoom(ctx context.Context, roomName livekit.RoomName) error
 }
@@ -106,4 +124,6 @@ type AgentStore interface {
 
 	StoreAgentJob(ctx context.Context, job *livekit.Job) error
 	DeleteAgentJob(ctx context.Context, job *livekit.Job) error
+
+	StoreAgentJobResult(ctx context.Context, roomName livekit.RoomName, result *agent.JobResult) error
 }
@@ -35,6 +35,12 @@ type IOInfoService struct {
 	ss        SIPStore
 	telemetry telemetry.TelemetryService
 
+	ingressAuth *IngressAuthenticator
+
+	// whether workers respond, reported by the health check
+	egressWorkers  workerReachability
+	ingressWorkers workerReachability
+
 	shutdown chan struct{}
 }
 
@@ -44,13 +50,15 @@ func NewIOInfoService(
 	is IngressStore,
 	ss SIPStore,
 	ts telemetry.TelemetryService,
+	ingressAuth *IngressAuthenticator,
 ) (*IOInfoService, error) {
 	s := &IOInfoService{
-		es:        es,
-		is:        is,
-		ss:        ss,
-		telemetry: ts,
-		shutdown:  make(chan struct{}),
+		es:          es,
+		is:          is,
+		ss:          ss,
+		telemetry:   ts,
+		ingressAuth: ingressAuth,
+		shutdown:    make(chan struct{}),
 	}
 
 	if bus != nil {
@@ -103,6 +111,7 @@ func (s *IOInfoService) CreateEgress(ctx context.Context, info *livekit.EgressIn
 }
 
 func (s *IOInfoService) UpdateEgress(ctx context.Context, info *livekit.EgressInfo) (*emptypb.Empty, error) {
+	s.egressWorkers.seen()
 	err := s.es.UpdateEgress(ctx, info)
 
 	switch info.Status {
@@ -156,6 +165,7 @@ func (s *IOInfoService) ListEgress(ctx context.Context, req *livekit.ListEgressR
 }
 
 func (s *IOInfoService) UpdateMetrics(ctx context.Context, req *rpc.UpdateMetricsRequest) (*emptypb.Empty, error) {
+	s.egressWorkers.seen()
 	logger.Infow("received egress metrics",
 		"egressID", req.Info.EgressId,
 		"avgCpu", req.AvgCpuUsage,
@@ -163,3 +173,11 @@ func (s *IOInfoService) UpdateMetrics(ctx context.Context, req *rpc.UpdateMetric
 	)
 	return &emptypb.Empty{}, nil
 }
+
+func (s *IOInfoService) recordEgressResult(err error) {
+	s.egressWorkers.recordResult(err)
+}
+
+func (s *IOInfoService) recordIngressResult(err error) {
+	s.ingressWorkers.recordResult(err)
+}
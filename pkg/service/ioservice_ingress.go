@@ -21,6 +21,7 @@ import (
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/rpc"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
@@ -36,7 +37,11 @@ func (s *IOInfoService) CreateIngress(ctx context.Context, info *livekit.Ingress
 }
 
 func (s *IOInfoService) GetIngressInfo(ctx context.Context, req *rpc.GetIngressInfoRequest) (*rpc.GetIngressInfoResponse, error) {
+	s.ingressWorkers.seen()
 	info, err := s.loadIngressFromInfoRequest(req)
+	if err == nil && s.ingressAuth != nil && req.StreamKey != "" {
+		info, err = s.authenticateIngress(ctx, req.StreamKey, info)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -44,6 +49,37 @@ func (s *IOInfoService) GetIngressInfo(ctx context.Context, req *rpc.GetIngressI
 	return &rpc.GetIngressInfoResponse{Info: info}, nil
 }
 
+// authenticateIngress validates the stream key of an existing ingress against the auth callback.
+// The room and participant returned by the callback apply to this connection only, the stored
+// ingress is left unchanged.
+func (s *IOInfoService) authenticateIngress(ctx context.Context, streamKey string, info *livekit.IngressInfo) (*livekit.IngressInfo, error) {
+	authReq := newIngressAuthRequest(ctx, streamKey, info)
+	res, err := s.ingressAuth.Authenticate(ctx, authReq)
+	if err != nil {
+		logger.Warnw("ingress auth callback failed", err, "ingressID", authReq.IngressID, "clientIP", authReq.ClientIP)
+		return nil, err
+	}
+	if !res.Allow {
+		logger.Infow("ingress connection rejected", "ingressID", authReq.IngressID, "clientIP", authReq.ClientIP)
+		return nil, ErrIngressAuthDenied
+	}
+
+	info = proto.Clone(info).(*livekit.IngressInfo)
+	if res.RoomName != "" {
+		info.RoomName = res.RoomName
+	}
+	if res.ParticipantIdentity != "" {
+		info.ParticipantIdentity = res.ParticipantIdentity
+	}
+	if res.ParticipantName != "" {
+		info.ParticipantName = res.ParticipantName
+	}
+	if res.ParticipantMetadata != "" {
+		info.ParticipantMetadata = res.ParticipantMetadata
+	}
+	return info, nil
+}
+
 func (s *IOInfoService) loadIngressFromInfoRequest(req *rpc.GetIngressInfoRequest) (info *livekit.IngressInfo, err error) {
 	if req.IngressId != "" {
 		info, err = s.is.LoadIngress(context.Background(), req.IngressId)
@@ -0,0 +1,118 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/protocol/webhook"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+)
+
+func TestIngressAuthCallback(t *testing.T) {
+	provider := auth.NewSimpleKeyProvider("key", "secret")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := webhook.Receive(r, provider)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		req := &service.IngressAuthRequest{}
+		require.NoError(t, json.Unmarshal(data, req))
+
+		res := &service.IngressAuthResponse{}
+		switch req.StreamKey {
+		case "known", "dynamic":
+			res.Allow = true
+			res.RoomName = "mapped-room"
+			res.ParticipantIdentity = "streamer"
+		case "unmapped":
+			res.Allow = true
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+	defer server.Close()
+
+	conf := &config.Config{
+		WebHook: config.WebHookConfig{APIKey: "key"},
+		Ingress: config.IngressConfig{AuthURL: server.URL, AuthTimeout: config.DefaultConfig.Ingress.AuthTimeout},
+	}
+	authenticator, err := service.NewIngressAuthenticator(conf, provider)
+	require.NoError(t, err)
+
+	known := &livekit.IngressInfo{IngressId: "IN_known", StreamKey: "known", RoomName: "room"}
+	store := &servicefakes.FakeIngressStore{}
+	store.LoadIngressFromStreamKeyCalls(func(_ context.Context, streamKey string) (*livekit.IngressInfo, error) {
+		switch streamKey {
+		case "known":
+			return known, nil
+		case "rejected":
+			return &livekit.IngressInfo{IngressId: "IN_rejected", StreamKey: streamKey, RoomName: "room"}, nil
+		}
+		return nil, service.ErrIngressNotFound
+	})
+
+	svc, err := service.NewIOInfoService(nil, nil, store, nil, nil, authenticator)
+	require.NoError(t, err)
+
+	getInfo := func(streamKey string) (*livekit.IngressInfo, error) {
+		res, err := svc.GetIngressInfo(context.Background(), &rpc.GetIngressInfoRequest{StreamKey: streamKey})
+		if err != nil {
+			return nil, err
+		}
+		return res.Info, nil
+	}
+
+	t.Run("known stream key is remapped", func(t *testing.T) {
+		info, err := getInfo("known")
+		require.NoError(t, err)
+		require.Equal(t, "IN_known", info.IngressId)
+		require.Equal(t, "mapped-room", info.RoomName)
+		require.Equal(t, "streamer", info.ParticipantIdentity)
+
+		// the stored ingress is not changed by the remap
+		require.Equal(t, "room", known.RoomName)
+		require.Zero(t, store.StoreIngressCallCount())
+	})
+
+	t.Run("unknown stream keys are not provisioned", func(t *testing.T) {
+		for _, streamKey := range []string{"dynamic", "unmapped"} {
+			_, err := getInfo(streamKey)
+			require.ErrorIs(t, err, service.ErrIngressNotFound)
+		}
+		require.Zero(t, store.StoreIngressCallCount())
+	})
+
+	t.Run("rejected stream key", func(t *testing.T) {
+		_, err := getInfo("rejected")
+		var perr psrpc.Error
+		require.ErrorAs(t, err, &perr)
+		require.Equal(t, psrpc.PermissionDenied, perr.Code())
+	})
+}
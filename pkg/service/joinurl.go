@@ -0,0 +1,233 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils/guid"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+)
+
+const (
+	joinURLPath = "/join_url"
+	// jwt id prefix of join url tokens, which can only be used once
+	joinURLTokenPrefix = "JU_"
+	joinURLTokenParam  = "token"
+
+	defaultJoinURLTTL    = 15 * time.Minute
+	defaultJoinURLMaxTTL = 24 * time.Hour
+	// covers room hooks, quotas and connection attempts of a join
+	joinTokenReservationTTL = 30 * time.Second
+	joinTokenReleaseTimeout = 2 * time.Second
+)
+
+// JoinURLRequest is the json body of join url requests, ttl is in seconds
+type JoinURLRequest struct {
+	Room       string            `json:"room"`
+	Identity   string            `json:"identity"`
+	Name       string            `json:"name,omitempty"`
+	Metadata   string            `json:"metadata,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	TTL        int64             `json:"ttl,omitempty"`
+}
+
+type JoinURLResponse struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`
+	// unix time in seconds
+	ExpiresAt int64 `json:"expires_at"`
+}
+
+// JoinURLHandler mints join urls, links to the configured join page carrying a token bound to a room and
+// identity. The token is signed with the key of the request, and is rejected once it has been used to join
+func JoinURLHandler(conf config.JoinURLConfig, keyProvider auth.KeyProvider) http.HandlerFunc {
+	maxTTL := joinURLMaxTTL(conf)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if conf.BaseURL == "" {
+			handleError(w, r, http.StatusNotFound, ErrJoinURLNotEnabled)
+			return
+		}
+
+		var req JoinURLRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			handleError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		if req.Room == "" || req.Identity == "" {
+			handleError(w, r, http.StatusBadRequest, errors.New("room and identity are required"))
+			return
+		}
+		if err := EnsureAdminPermission(r.Context(), livekit.RoomName(req.Room)); err != nil {
+			handleError(w, r, http.StatusUnauthorized, err)
+			return
+		}
+
+		ttl := defaultJoinURLTTL
+		if req.TTL != 0 {
+			ttl = time.Duration(req.TTL) * time.Second
+		}
+		if ttl <= 0 || ttl > maxTTL {
+			handleError(w, r, http.StatusBadRequest, errors.New("ttl must be positive and at most "+maxTTL.String()))
+			return
+		}
+
+		apiKey := GetAPIKey(r.Context())
+		secret := keyProvider.GetSecret(apiKey)
+		if secret == "" {
+			handleError(w, r, http.StatusUnauthorized, ErrInvalidAPIKey)
+			return
+		}
+
+		expiresAt := time.Now().Add(ttl)
+		token, err := mintJoinToken(apiKey, secret, &req, expiresAt)
+		if err != nil {
+			handleError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		u, err := url.Parse(conf.BaseURL)
+		if err != nil {
+			handleError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		query := u.Query()
+		query.Set(joinURLTokenParam, token)
+		u.RawQuery = query.Encode()
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&JoinURLResponse{
+			URL:       u.String(),
+			Token:     token,
+			ExpiresAt: expiresAt.Unix(),
+		})
+	}
+}
+
+func mintJoinToken(apiKey string, secret string, req *JoinURLRequest, expiresAt time.Time) (string, error) {
+	sig, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: []byte(secret)},
+		(&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		return "", err
+	}
+
+	claims := jwt.Claims{
+		ID:        guid.New(joinURLTokenPrefix),
+		Issuer:    apiKey,
+		Subject:   req.Identity,
+		NotBefore: jwt.NewNumericDate(time.Now()),
+		Expiry:    jwt.NewNumericDate(expiresAt),
+	}
+	grants := &auth.ClaimGrants{
+		Name:       req.Name,
+		Metadata:   req.Metadata,
+		Attributes: req.Attributes,
+		Video: &auth.VideoGrant{
+			RoomJoin: true,
+			Room:     req.Room,
+		},
+	}
+	return jwt.Signed(sig).Claims(claims).Claims(grants).CompactSerialize()
+}
+
+func joinURLMaxTTL(conf config.JoinURLConfig) time.Duration {
+	if conf.MaxTTL > 0 {
+		return conf.MaxTTL
+	}
+	return defaultJoinURLMaxTTL
+}
+
+// joinTokenReservation holds a join url token while its participant joins
+type joinTokenReservation struct {
+	store     JoinTokenStore
+	tokenID   string
+	ttl       time.Duration
+	committed bool
+}
+
+// reserveJoinToken rejects join url tokens that have already been used to join, the token is reserved until the
+// join is committed or released. Only the session that joined with the token can reconnect, identified by its
+// participant id. Returns nil when the token is not a join url token
+func (s *RTCService) reserveJoinToken(ctx context.Context, roomName livekit.RoomName, pi routing.ParticipantInit) (*joinTokenReservation, error) {
+	tokenID := GetTokenID(ctx)
+	if !strings.HasPrefix(tokenID, joinURLTokenPrefix) {
+		return nil, nil
+	}
+
+	if pi.Reconnect {
+		participant, err := s.store.LoadParticipant(ctx, roomName, pi.Identity)
+		if err != nil || pi.ID == "" || livekit.ParticipantID(participant.Sid) != pi.ID {
+			return nil, ErrJoinURLUsed
+		}
+		return nil, nil
+	}
+
+	store, ok := s.store.(JoinTokenStore)
+	if !ok {
+		return nil, ErrJoinURLNotSupported
+	}
+	reserved, err := store.ReserveJoinToken(ctx, tokenID, joinTokenReservationTTL)
+	if err != nil {
+		return nil, err
+	}
+	if !reserved {
+		return nil, ErrJoinURLUsed
+	}
+	return &joinTokenReservation{
+		store:   store,
+		tokenID: tokenID,
+		// tokens cannot outlive the max ttl of join urls
+		ttl: joinURLMaxTTL(s.config.JoinURL),
+	}, nil
+}
+
+// Commit records the token as used, once the participant has joined
+func (r *joinTokenReservation) Commit(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+	r.committed = true
+	return r.store.ConsumeJoinToken(ctx, r.tokenID, r.ttl)
+}
+
+// Release makes the token usable again when the join was not committed
+func (r *joinTokenReservation) Release() {
+	if r == nil || r.committed {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), joinTokenReleaseTimeout)
+	defer cancel()
+	if err := r.store.ReleaseJoinToken(ctx, r.tokenID); err != nil {
+		logger.Warnw("could not release join url token", err)
+	}
+}
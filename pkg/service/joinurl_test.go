@@ -0,0 +1,104 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestJoinURL(t *testing.T) {
+	keyProvider := auth.NewFileBasedKeyProviderFromMap(map[string]string{"key": "secret"})
+	handler := service.JoinURLHandler(config.JoinURLConfig{BaseURL: "https://meet.example.com/join?theme=dark"}, keyProvider)
+
+	mint := func(grants *auth.ClaimGrants, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/join_url", strings.NewReader(body))
+		r = r.WithContext(service.WithGrants(r.Context(), grants, "key"))
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	t.Run("requires room admin", func(t *testing.T) {
+		w := mint(&auth.ClaimGrants{Video: &auth.VideoGrant{RoomAdmin: true, Room: "other"}}, `{"room": "room", "identity": "guest"}`)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("ttl is bounded", func(t *testing.T) {
+		w := mint(&auth.ClaimGrants{Video: &auth.VideoGrant{RoomAdmin: true, Room: "room"}}, `{"room": "room", "identity": "guest", "ttl": 100000}`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("mints a single use token", func(t *testing.T) {
+		w := mint(&auth.ClaimGrants{Video: &auth.VideoGrant{RoomAdmin: true, Room: "room"}}, `{"room": "room", "identity": "guest", "name": "Guest", "ttl": 60}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var res service.JoinURLResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		require.InDelta(t, time.Now().Add(time.Minute).Unix(), res.ExpiresAt, 2)
+
+		u, err := url.Parse(res.URL)
+		require.NoError(t, err)
+		require.Equal(t, "meet.example.com", u.Host)
+		require.Equal(t, "dark", u.Query().Get("theme"))
+		require.Equal(t, res.Token, u.Query().Get("token"))
+
+		// the token joins the room as the identity, and carries an id used to consume it
+		r := httptest.NewRequest(http.MethodGet, "/rtc", nil)
+		service.SetAuthorizationToken(r, res.Token)
+		var grants *auth.ClaimGrants
+		var tokenID string
+		service.NewAPIKeyAuthMiddleware(keyProvider).ServeHTTP(httptest.NewRecorder(), r, func(w http.ResponseWriter, r *http.Request) {
+			grants = service.GetGrants(r.Context())
+			tokenID = service.GetTokenID(r.Context())
+		})
+		require.Equal(t, "guest", grants.Identity)
+		require.Equal(t, "Guest", grants.Name)
+		require.Equal(t, "room", grants.Video.Room)
+		require.True(t, grants.Video.RoomJoin)
+		require.False(t, grants.Video.RoomAdmin)
+
+		store := service.NewLocalStore()
+		reserved, err := store.ReserveJoinToken(context.Background(), tokenID, time.Minute)
+		require.NoError(t, err)
+		require.True(t, reserved)
+		reserved, err = store.ReserveJoinToken(context.Background(), tokenID, time.Minute)
+		require.NoError(t, err)
+		require.False(t, reserved)
+
+		// a failed join releases the token
+		require.NoError(t, store.ReleaseJoinToken(context.Background(), tokenID))
+		reserved, err = store.ReserveJoinToken(context.Background(), tokenID, time.Minute)
+		require.NoError(t, err)
+		require.True(t, reserved)
+		require.NoError(t, store.ConsumeJoinToken(context.Background(), tokenID, time.Hour))
+		reserved, err = store.ReserveJoinToken(context.Background(), tokenID, time.Minute)
+		require.NoError(t, err)
+		require.False(t, reserved)
+	})
+}
@@ -0,0 +1,163 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+)
+
+const (
+	readinessPath = "/readyz"
+	drainPath     = "/drain"
+
+	// node stats are updated every 2s, older stats mean the router is not keeping the node alive
+	nodeStatsStaleAfter = 4 * time.Second
+	maxDrainWait        = 10 * time.Minute
+	drainPollInterval   = time.Second
+)
+
+type drainResponse struct {
+	Draining     bool `json:"draining"`
+	Rooms        int  `json:"rooms"`
+	Participants int  `json:"participants"`
+}
+
+// Drain stops new sessions from being routed to this node. Participants already connected are not
+// disconnected, unless shutdown.redirect_clients asks them to reconnect to another node. The node exits once
+// they have left or Stop is forced
+func (s *LivekitServer) Drain() {
+	if s.draining.Swap(true) {
+		return
+	}
+	logger.Infow("draining node")
+	s.router.Drain()
+	s.rtcService.Drain()
+	if s.config.Shutdown.RedirectClients {
+		logger.Infow("redirecting clients to other nodes")
+		s.roomManager.RedirectParticipants()
+	}
+}
+
+func (s *LivekitServer) IsDraining() bool {
+	return s.draining.Load()
+}
+
+// failedReadinessChecks returns the reasons this node should not receive new sessions
+func (s *LivekitServer) failedReadinessChecks() []string {
+	var failed []string
+	if !s.running.Load() {
+		failed = append(failed, "not running")
+	}
+	if s.draining.Load() {
+		failed = append(failed, "draining")
+	}
+	var updatedAt time.Time
+	if s.Node().Stats != nil {
+		updatedAt = time.Unix(s.Node().Stats.UpdatedAt, 0)
+	}
+	if time.Since(updatedAt) > nodeStatsStaleAfter {
+		failed = append(failed, fmt.Sprintf("node stats updated at %s", updatedAt))
+	}
+	if s.failover.IsFailingOver() {
+		failed = append(failed, "redis unavailable")
+	}
+	return failed
+}
+
+// readinessCheck is a readiness probe, it fails while the node is draining or a dependency is unhealthy.
+// Unlike the liveness check on /, a failed readiness check does not mean the node should be restarted
+func (s *LivekitServer) readinessCheck(w http.ResponseWriter, _ *http.Request) {
+	if failed := s.failedReadinessChecks(); len(failed) != 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("Not Ready\n" + strings.Join(failed, "\n")))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("OK"))
+}
+
+// drainHandler handles
+//
+//	GET /drain              drain status and remaining rooms and participants
+//	POST /drain?wait=30s    starts draining, waiting up to wait for participants to leave
+//
+// GET needs roomList permission. POST stops the node from accepting sessions, it is only accepted on the unix
+// socket or with a token signed with an operator key. A POST with wait can be used as a Kubernetes preStop hook
+func (s *LivekitServer) drainHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		err = EnsureListPermission(r.Context())
+	case http.MethodPost:
+		if !isSocketRequest(r.Context()) {
+			err = EnsureOperatorPermission(r.Context(), s.config.OperatorKeys)
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		handleError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		var wait time.Duration
+		if v := r.URL.Query().Get("wait"); v != "" {
+			if wait, err = time.ParseDuration(v); err != nil || wait < 0 {
+				handleError(w, r, http.StatusBadRequest, fmt.Errorf("invalid wait %q", v))
+				return
+			}
+		}
+		s.Drain()
+		s.waitForParticipants(r, min(wait, maxDrainWait))
+	}
+
+	rooms, participants := s.roomManager.ParticipantCounts()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(&drainResponse{
+		Draining:     s.draining.Load(),
+		Rooms:        rooms,
+		Participants: participants,
+	})
+}
+
+// waitForParticipants blocks until participants have left, wait has elapsed or the request is canceled
+func (s *LivekitServer) waitForParticipants(r *http.Request, wait time.Duration) {
+	if wait <= 0 {
+		return
+	}
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for s.roomManager.HasParticipants() {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-timeout.C:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -16,6 +16,7 @@ package service
 
 import (
 	"context"
+	"maps"
 	"sync"
 	"time"
 
@@ -23,6 +24,10 @@ import (
 	"google.golang.org/protobuf/proto"
 
 	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/agent"
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
 )
 
 // encapsulates CRUD operations for room settings
@@ -35,6 +40,14 @@ type LocalStore struct {
 
 	agentDispatches map[livekit.RoomName]map[string]*livekit.AgentDispatch
 	agentJobs       map[livekit.RoomName]map[string]*livekit.Job
+	agentResults    map[livekit.RoomName]map[string]*agent.JobResult
+
+	roomConfigOverrides map[livekit.RoomName]*config.RoomConfigOverrides
+	// rooms indicated as recorded through the API
+	roomRecordings map[livekit.RoomName]bool
+	roomStates     map[livekit.RoomName]*localRoomState
+	// map of join token id => expiry of the record
+	joinTokens map[string]time.Time
 
 	lock       sync.RWMutex
 	globalLock sync.Mutex
@@ -47,7 +60,13 @@ func NewLocalStore() *LocalStore {
 		participants:    make(map[livekit.RoomName]map[livekit.ParticipantIdentity]*livekit.ParticipantInfo),
 		agentDispatches: make(map[livekit.RoomName]map[string]*livekit.AgentDispatch),
 		agentJobs:       make(map[livekit.RoomName]map[string]*livekit.Job),
+		agentResults:    make(map[livekit.RoomName]map[string]*agent.JobResult),
 		lock:            sync.RWMutex{},
+
+		roomConfigOverrides: make(map[livekit.RoomName]*config.RoomConfigOverrides),
+		roomRecordings:      make(map[livekit.RoomName]bool),
+		roomStates:          make(map[livekit.RoomName]*localRoomState),
+		joinTokens:          make(map[string]time.Time),
 	}
 }
 
@@ -110,6 +129,10 @@ func (s *LocalStore) DeleteRoom(ctx context.Context, roomName livekit.RoomName)
 	delete(s.roomInternal, livekit.RoomName(room.Name))
 	delete(s.agentDispatches, livekit.RoomName(room.Name))
 	delete(s.agentJobs, livekit.RoomName(room.Name))
+	delete(s.agentResults, livekit.RoomName(room.Name))
+	delete(s.roomConfigOverrides, livekit.RoomName(room.Name))
+	delete(s.roomRecordings, livekit.RoomName(room.Name))
+	delete(s.roomStates, livekit.RoomName(room.Name))
 	return nil
 }
 
@@ -279,3 +302,128 @@ func (s *LocalStore) DeleteAgentJob(ctx context.Context, job *livekit.Job) error
 
 	return nil
 }
+
+func (s *LocalStore) StoreAgentJobResult(ctx context.Context, roomName livekit.RoomName, result *agent.JobResult) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	clone := *result
+	clone.Artifacts = maps.Clone(result.Artifacts)
+
+	roomResults := s.agentResults[roomName]
+	if roomResults == nil {
+		roomResults = make(map[string]*agent.JobResult)
+		s.agentResults[roomName] = roomResults
+	}
+	roomResults[clone.JobID] = &clone
+
+	return nil
+}
+
+func (s *LocalStore) StoreRoomConfigOverrides(_ context.Context, roomName livekit.RoomName, overrides *config.RoomConfigOverrides) error {
+	clone := *overrides
+	s.lock.Lock()
+	s.roomConfigOverrides[roomName] = &clone
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *LocalStore) LoadRoomConfigOverrides(_ context.Context, roomName livekit.RoomName) (*config.RoomConfigOverrides, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	overrides := s.roomConfigOverrides[roomName]
+	if overrides == nil {
+		return nil, nil
+	}
+	clone := *overrides
+	return &clone, nil
+}
+
+func (s *LocalStore) StoreRoomRecording(_ context.Context, roomName livekit.RoomName, active bool) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if active {
+		s.roomRecordings[roomName] = true
+	} else {
+		delete(s.roomRecordings, roomName)
+	}
+	return nil
+}
+
+func (s *LocalStore) LoadRoomRecording(_ context.Context, roomName livekit.RoomName) (bool, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.roomRecordings[roomName], nil
+}
+
+type localRoomState struct {
+	version uint64
+	entries map[string]rtc.RoomStateEntry
+}
+
+func (s *LocalStore) StoreRoomStateEntry(_ context.Context, roomName livekit.RoomName, e *rtc.RoomStateEntry) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	state := s.roomStates[roomName]
+	if state == nil {
+		state = &localRoomState{entries: make(map[string]rtc.RoomStateEntry)}
+		s.roomStates[roomName] = state
+	}
+	if e.Deleted {
+		delete(state.entries, e.Key)
+	} else {
+		state.entries[e.Key] = *e
+	}
+	state.version = e.Version
+	return nil
+}
+
+func (s *LocalStore) LoadRoomState(_ context.Context, roomName livekit.RoomName) ([]*rtc.RoomStateEntry, uint64, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	state := s.roomStates[roomName]
+	if state == nil {
+		return nil, 0, nil
+	}
+	entries := make([]*rtc.RoomStateEntry, 0, len(state.entries))
+	for _, e := range state.entries {
+		entries = append(entries, &e)
+	}
+	return entries, state.version, nil
+}
+
+func (s *LocalStore) ReserveJoinToken(_ context.Context, tokenID string, ttl time.Duration) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	for id, expiry := range s.joinTokens {
+		if now.After(expiry) {
+			delete(s.joinTokens, id)
+		}
+	}
+	if _, ok := s.joinTokens[tokenID]; ok {
+		return false, nil
+	}
+	s.joinTokens[tokenID] = now.Add(ttl)
+	return true, nil
+}
+
+func (s *LocalStore) ConsumeJoinToken(_ context.Context, tokenID string, ttl time.Duration) error {
+	s.lock.Lock()
+	s.joinTokens[tokenID] = time.Now().Add(ttl)
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *LocalStore) ReleaseJoinToken(_ context.Context, tokenID string) error {
+	s.lock.Lock()
+	delete(s.joinTokens, tokenID)
+	s.lock.Unlock()
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	loudnessPath = "/room/loudness"
+
+	loudnessMethod = "GetLoudness"
+)
+
+// LoudnessHandler reports the loudness of audio tracks published to rooms, through the node hosting the room
+type LoudnessHandler struct {
+	roomAPI *RoomAPI
+}
+
+func NewLoudnessHandler(roomManager *RoomManager) *LoudnessHandler {
+	h := &LoudnessHandler{
+		roomAPI: roomManager.RoomAPI(),
+	}
+	h.roomAPI.Handle(loudnessMethod, h.handleGetLoudness)
+	return h
+}
+
+func (h *LoudnessHandler) GetLoudness(ctx context.Context, roomName livekit.RoomName) ([]*rtc.TrackLoudness, error) {
+	var res []*rtc.TrackLoudness
+	if err := h.roomAPI.CallRoom(ctx, roomName, loudnessMethod, struct{}{}, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (h *LoudnessHandler) handleGetLoudness(_ context.Context, room *rtc.Room, _ types.LocalParticipant, _ json.RawMessage) (any, error) {
+	return room.GetLoudness(), nil
+}
+
+// ServeHTTP handles GET /room/loudness?room= with a token that has roomAdmin permission for the room
+func (h *LoudnessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	roomName := livekit.RoomName(r.URL.Query().Get("room"))
+	if err := EnsureAdminPermission(r.Context(), roomName); err != nil {
+		handleError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	loudness, err := h.GetLoudness(r.Context(), roomName)
+	if err != nil {
+		handleError(w, r, roomAPIStatus(err), err, "room", roomName)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(loudness)
+}
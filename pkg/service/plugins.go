@@ -0,0 +1,436 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package service
+
+import (
+	"fmt"
+	"plugin"
+	"sync"
+
+	"github.com/pion/interceptor"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+)
+
+// StoreFactory creates an ObjectStore. The store may also implement EgressStore, IngressStore,
+// SIPStore and AgentStore to back those services.
+type StoreFactory func(conf *config.Config, rc redis.UniversalClient) (ObjectStore, error)
+
+// MessageBusFactory creates the psrpc.MessageBus used for all node to node communication
+type MessageBusFactory func(conf *config.Config, rc redis.UniversalClient) (psrpc.MessageBus, error)
+
+// RouterParams holds the dependencies available to a RouterFactory
+type RouterParams struct {
+	Redis             redis.UniversalClient
+	Node              routing.LocalNode
+	SignalClient      routing.SignalClient
+	RoomManagerClient routing.RoomManagerClient
+	KeepalivePubSub   rpc.KeepalivePubSub
+	Labels            selector.NodeLabels
+	Failover          *routing.FailoverMonitor
+}
+
+// RouterFactory creates a routing.Router. Most implementations would wrap routing.NewLocalRouter
+type RouterFactory func(conf *config.Config, params RouterParams) (routing.Router, error)
+
+// DataModeratorFactory creates the rtc.DataModerator used for user data messages
+type DataModeratorFactory func(conf *config.Config) (rtc.DataModerator, error)
+
+// ThumbnailSinkFactory creates the ThumbnailSink that track thumbnails are pushed to
+type ThumbnailSinkFactory func(conf *config.Config) (ThumbnailSink, error)
+
+// RTPInterceptorParams describes the transport an RTP interceptor is created for
+type RTPInterceptorParams struct {
+	Room                livekit.RoomName
+	ParticipantIdentity livekit.ParticipantIdentity
+	// PUBLISHER receives media from the participant, SUBSCRIBER sends media to them
+	Transport livekit.SignalTarget
+}
+
+// RTPInterceptorFactory creates the pion interceptor factory for a transport, returning nil skips the transport
+type RTPInterceptorFactory func(conf *config.Config, params RTPInterceptorParams) (interceptor.Factory, error)
+
+// RoomHooksFactory creates the RoomHooks called before rooms are created, participants join and tracks are published
+type RoomHooksFactory func(conf *config.Config, provider auth.KeyProvider) (RoomHooks, error)
+
+// TURNAuthBackendFactory creates a backend verifying TURN credentials, selected with turn.auth.backends
+type TURNAuthBackendFactory func(conf *config.Config, provider auth.KeyProvider) (TURNAuthBackend, error)
+
+// MediaInterceptorFactory creates an rtc.MediaInterceptor, plugin specific settings can be read from conf.Plugins.Options
+type MediaInterceptorFactory func(conf *config.Config) (rtc.MediaInterceptor, error)
+
+var plugins = struct {
+	lock           sync.RWMutex
+	stores         map[string]StoreFactory
+	messageBus     map[string]MessageBusFactory
+	routers        map[string]RouterFactory
+	dataModerators map[string]DataModeratorFactory
+	roomHooks      map[string]RoomHooksFactory
+	thumbnailSinks map[string]ThumbnailSinkFactory
+	interceptors   map[string]MediaInterceptorFactory
+	rtpIntercepts  map[string]RTPInterceptorFactory
+	turnAuth       map[string]TURNAuthBackendFactory
+}{
+	stores:         make(map[string]StoreFactory),
+	messageBus:     make(map[string]MessageBusFactory),
+	routers:        make(map[string]RouterFactory),
+	dataModerators: make(map[string]DataModeratorFactory),
+	roomHooks:      make(map[string]RoomHooksFactory),
+	thumbnailSinks: make(map[string]ThumbnailSinkFactory),
+	interceptors:   make(map[string]MediaInterceptorFactory),
+	rtpIntercepts:  make(map[string]RTPInterceptorFactory),
+	turnAuth:       make(map[string]TURNAuthBackendFactory),
+}
+
+// RegisterStore makes a store available under name, to be selected with plugins.store.
+// It is meant to be called from init() by external builds, and panics on duplicate names.
+func RegisterStore(name string, factory StoreFactory) {
+	register(plugins.stores, "store", name, factory)
+}
+
+// RegisterMessageBus makes a message bus available under name, to be selected with plugins.message_bus
+func RegisterMessageBus(name string, factory MessageBusFactory) {
+	register(plugins.messageBus, "message bus", name, factory)
+}
+
+// RegisterRouter makes a router available under name, to be selected with plugins.router
+func RegisterRouter(name string, factory RouterFactory) {
+	register(plugins.routers, "router", name, factory)
+}
+
+// RegisterDataModerator makes a data moderator available under name, to be selected with plugins.data_moderator
+func RegisterDataModerator(name string, factory DataModeratorFactory) {
+	register(plugins.dataModerators, "data moderator", name, factory)
+}
+
+// RegisterRoomHooks makes room hooks available under name, to be selected with plugins.room_hooks
+func RegisterRoomHooks(name string, factory RoomHooksFactory) {
+	register(plugins.roomHooks, "room hooks", name, factory)
+}
+
+// RegisterThumbnailSink makes a thumbnail sink available under name, to be selected with plugins.thumbnail_sink
+func RegisterThumbnailSink(name string, factory ThumbnailSinkFactory) {
+	register(plugins.thumbnailSinks, "thumbnail sink", name, factory)
+}
+
+// RegisterMediaInterceptor makes a media interceptor available under name, to be selected with plugins.media_interceptors
+func RegisterMediaInterceptor(name string, factory MediaInterceptorFactory) {
+	register(plugins.interceptors, "media interceptor", name, factory)
+}
+
+// RegisterRTPInterceptor makes a pion interceptor available under name, to be selected per room with the
+// rtp_interceptors room config override
+func RegisterRTPInterceptor(name string, factory RTPInterceptorFactory) {
+	register(plugins.rtpIntercepts, "rtp interceptor", name, factory)
+}
+
+// RegisterTURNAuthBackend makes a TURN auth backend available under name, to be selected with turn.auth.backends.
+// Names of the built-in backends cannot be used
+func RegisterTURNAuthBackend(name string, factory TURNAuthBackendFactory) {
+	switch name {
+	case config.TURNAuthToken, config.TURNAuthREST, config.TURNAuthSharedSecret, config.TURNAuthAPIKey:
+		panic(fmt.Sprintf("TURN auth backend %q is built in", name))
+	}
+	register(plugins.turnAuth, "TURN auth backend", name, factory)
+}
+
+// LoadPluginFiles opens Go plugins built with -buildmode=plugin, which register their implementations from init().
+// Plugins have to be built with the same Go version and dependency versions as the server
+func LoadPluginFiles(files []string) error {
+	for _, file := range files {
+		if _, err := plugin.Open(file); err != nil {
+			return fmt.Errorf("could not load plugin %s: %w", file, err)
+		}
+		logger.Infow("loaded plugin", "file", file)
+	}
+	return nil
+}
+
+func register[T any](factories map[string]T, kind, name string, factory T) {
+	plugins.lock.Lock()
+	defer plugins.lock.Unlock()
+
+	if name == "" {
+		panic(fmt.Sprintf("%s plugin name cannot be empty", kind))
+	}
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("%s plugin %q already registered", kind, name))
+	}
+	factories[name] = factory
+}
+
+func lookupPlugin[T any](factories map[string]T, kind, name string) (T, error) {
+	plugins.lock.RLock()
+	defer plugins.lock.RUnlock()
+
+	factory, ok := factories[name]
+	if !ok {
+		return factory, fmt.Errorf("%s plugin %q is not registered", kind, name)
+	}
+	return factory, nil
+}
+
+func createStore(conf *config.Config, rc redis.UniversalClient) (ObjectStore, error) {
+	if name := conf.Plugins.Store; name != "" {
+		factory, err := lookupPlugin(plugins.stores, "store", name)
+		if err != nil {
+			return nil, err
+		}
+		return factory(conf, rc)
+	}
+
+	if rc != nil {
+		return NewRedisStore(rc), nil
+	}
+	return NewLocalStore(), nil
+}
+
+func getMessageBus(conf *config.Config, rc redis.UniversalClient) (psrpc.MessageBus, error) {
+	bus, err := newMessageBus(conf, rc)
+	if err != nil || !conf.Chaos.Enabled {
+		return bus, err
+	}
+	return newChaosMessageBus(bus), nil
+}
+
+func newMessageBus(conf *config.Config, rc redis.UniversalClient) (psrpc.MessageBus, error) {
+	if name := conf.Plugins.MessageBus; name != "" {
+		factory, err := lookupPlugin(plugins.messageBus, "message bus", name)
+		if err != nil {
+			return nil, err
+		}
+		return factory(conf, rc)
+	}
+
+	if rc == nil {
+		return psrpc.NewLocalMessageBus(), nil
+	}
+	return psrpc.NewRedisMessageBus(rc), nil
+}
+
+func createRouter(
+	conf *config.Config,
+	rc redis.UniversalClient,
+	node routing.LocalNode,
+	signalClient routing.SignalClient,
+	roomManagerClient routing.RoomManagerClient,
+	kps rpc.KeepalivePubSub,
+	failover *routing.FailoverMonitor,
+) (routing.Router, error) {
+	if name := conf.Plugins.Router; name != "" {
+		factory, err := lookupPlugin(plugins.routers, "router", name)
+		if err != nil {
+			return nil, err
+		}
+		return factory(conf, RouterParams{
+			Redis:             rc,
+			Node:              node,
+			SignalClient:      signalClient,
+			RoomManagerClient: roomManagerClient,
+			KeepalivePubSub:   kps,
+			Labels:            conf.NodeLabels,
+			Failover:          failover,
+		})
+	}
+
+	return routing.CreateRouter(rc, node, signalClient, roomManagerClient, kps, conf.NodeLabels, failover, conf.Lifecycle.NodeLeaseTTL), nil
+}
+
+// createDataModerator returns nil when data moderation is not configured
+func createDataModerator(conf *config.Config) (rtc.DataModerator, error) {
+	if name := conf.Plugins.DataModerator; name != "" {
+		factory, err := lookupPlugin(plugins.dataModerators, "data moderator", name)
+		if err != nil {
+			return nil, err
+		}
+		return factory(conf)
+	}
+
+	if conf.DataModeration.URL == "" {
+		return nil, nil
+	}
+	return NewDataModerationClient(conf)
+}
+
+// createDataFlagNotifier returns nil when flagged data messages are not reported
+func createDataFlagNotifier(conf *config.Config) (rtc.DataFlagNotifier, error) {
+	if conf.DataModeration.FlagURL == "" {
+		return nil, nil
+	}
+	return NewDataModerationClient(conf)
+}
+
+// createRoomHooks returns nil when room hooks are not configured
+func createRoomHooks(conf *config.Config, provider auth.KeyProvider) (RoomHooks, error) {
+	if name := conf.Plugins.RoomHooks; name != "" {
+		factory, err := lookupPlugin(plugins.roomHooks, "room hooks", name)
+		if err != nil {
+			return nil, err
+		}
+		return factory(conf, provider)
+	}
+
+	if conf.RoomHooks.URL == "" {
+		return nil, nil
+	}
+	return NewRoomHooksClient(conf, provider)
+}
+
+// createThumbnailSink returns nil when thumbnails are not configured
+func createThumbnailSink(conf *config.Config) (ThumbnailSink, error) {
+	if name := conf.Plugins.ThumbnailSink; name != "" {
+		factory, err := lookupPlugin(plugins.thumbnailSinks, "thumbnail sink", name)
+		if err != nil {
+			return nil, err
+		}
+		return factory(conf)
+	}
+
+	var sinks thumbnailSinks
+	if dir := conf.Snapshot.Thumbnails.Directory; dir != "" {
+		sinks = append(sinks, NewFileThumbnailSink(dir))
+	}
+	if conf.Snapshot.Thumbnails.URL != "" {
+		sink, err := NewWebhookThumbnailSink(conf)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	switch len(sinks) {
+	case 0:
+		return nil, nil
+	case 1:
+		return sinks[0], nil
+	default:
+		return sinks, nil
+	}
+}
+
+// createMediaInterceptor returns nil when no media interceptors are configured
+func createMediaInterceptor(conf *config.Config) (rtc.MediaInterceptor, error) {
+	var interceptors rtc.MediaInterceptors
+	for _, name := range conf.Plugins.MediaInterceptors {
+		factory, err := lookupPlugin(plugins.interceptors, "media interceptor", name)
+		if err != nil {
+			return nil, err
+		}
+		interceptor, err := factory(conf)
+		if err != nil {
+			return nil, err
+		}
+		interceptors = append(interceptors, interceptor)
+	}
+
+	switch len(interceptors) {
+	case 0:
+		return nil, nil
+	case 1:
+		return interceptors[0], nil
+	default:
+		return interceptors, nil
+	}
+}
+
+// createTURNAuthBackend returns the backends of turn.auth.backends, tried in order
+func createTURNAuthBackend(conf *config.Config, tokenAuth *TURNAuthHandler, provider auth.KeyProvider) (TURNAuthBackend, error) {
+	names := conf.TURN.Auth.Backends
+	if len(names) == 0 {
+		return tokenAuth, nil
+	}
+
+	var backends turnAuthBackends
+	for _, name := range names {
+		var backend TURNAuthBackend
+		var err error
+		switch name {
+		case config.TURNAuthToken:
+			backend = tokenAuth
+		case config.TURNAuthREST:
+			backend, err = NewTURNRESTAuth(conf, provider)
+		case config.TURNAuthSharedSecret:
+			backend, err = NewTURNSharedSecretAuth(conf.TURN.Auth.SharedSecret)
+		case config.TURNAuthAPIKey:
+			backend = NewTURNAPIKeyAuth(provider)
+		default:
+			var factory TURNAuthBackendFactory
+			if factory, err = lookupPlugin(plugins.turnAuth, "TURN auth backend", name); err == nil {
+				backend, err = factory(conf, provider)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, backend)
+	}
+
+	if len(backends) == 1 {
+		return backends[0], nil
+	}
+	return backends, nil
+}
+
+// validateRTPInterceptors checks that the named RTP interceptors are registered
+func validateRTPInterceptors(names []string) error {
+	for _, name := range names {
+		if _, err := lookupPlugin(plugins.rtpIntercepts, "rtp interceptor", name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createRTPInterceptors returns the interceptor factories for the publisher and subscriber transports of a participant
+func createRTPInterceptors(
+	conf *config.Config,
+	names []string,
+	roomName livekit.RoomName,
+	identity livekit.ParticipantIdentity,
+) (publisher []interceptor.Factory, subscriber []interceptor.Factory, err error) {
+	for _, name := range names {
+		factory, err := lookupPlugin(plugins.rtpIntercepts, "rtp interceptor", name)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, target := range []livekit.SignalTarget{livekit.SignalTarget_PUBLISHER, livekit.SignalTarget_SUBSCRIBER} {
+			f, err := factory(conf, RTPInterceptorParams{
+				Room:                roomName,
+				ParticipantIdentity: identity,
+				Transport:           target,
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+			if f == nil {
+				continue
+			}
+			if target == livekit.SignalTarget_PUBLISHER {
+				publisher = append(publisher, f)
+			} else {
+				subscriber = append(subscriber, f)
+			}
+		}
+	}
+	return publisher, subscriber, nil
+}
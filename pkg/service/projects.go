@@ -0,0 +1,306 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/twitchtv/twirp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// projectRoomSeparator separates the project from the name of the room in the server side name of rooms of a project
+const projectRoomSeparator = ":"
+
+type projectKey struct{}
+
+// GetProject returns the project of the API key making the request, or an empty string when the key does not
+// belong to a project
+func GetProject(ctx context.Context) string {
+	project, _ := ctx.Value(projectKey{}).(string)
+	return project
+}
+
+// quotaKey identifies the project of a request for quotas, keys not belonging to a project are their own project
+func quotaKey(ctx context.Context) string {
+	if project := GetProject(ctx); project != "" {
+		return project
+	}
+	return GetAPIKey(ctx)
+}
+
+// projectRoomName returns the server side name of a room of project
+func projectRoomName(project string, roomName livekit.RoomName) livekit.RoomName {
+	if project == "" || roomName == "" {
+		return roomName
+	}
+	return livekit.RoomName(project + projectRoomSeparator + string(roomName))
+}
+
+// splitProjectRoomName returns the project of a room and the name of the room in the project. The project is empty
+// for rooms outside of projects
+func splitProjectRoomName(projects map[string]config.ProjectConfig, roomName livekit.RoomName) (string, livekit.RoomName) {
+	project, name, ok := strings.Cut(string(roomName), projectRoomSeparator)
+	if !ok {
+		return "", roomName
+	}
+	if _, ok = projects[project]; !ok {
+		return "", roomName
+	}
+	return project, livekit.RoomName(name)
+}
+
+// ProjectScope separates projects sharing the cluster. Rooms of a project are named "<project>:<room>" on the
+// server, API keys of the project name them without the prefix, and cannot reach rooms of other projects.
+// Egresses and ingresses belong to the project of their room. Keys outside of projects only reach rooms outside of
+// projects, requests authenticated outside of LiveKit without a key reach every room.
+type ProjectScope struct {
+	projects     map[string]config.ProjectConfig
+	keys         map[string]string
+	egressStore  EgressStore
+	ingressStore IngressStore
+}
+
+// NewProjectScope returns nil when no projects are configured
+func NewProjectScope(conf *config.Config, egressStore EgressStore, ingressStore IngressStore) (*ProjectScope, error) {
+	if len(conf.Projects) == 0 {
+		return nil, nil
+	}
+
+	keys := make(map[string]string)
+	for name, project := range conf.Projects {
+		if name == "" || strings.Contains(name, projectRoomSeparator) {
+			return nil, fmt.Errorf("project name %q must not be empty or contain %q", name, projectRoomSeparator)
+		}
+		for _, key := range project.Keys {
+			keys[key] = name
+		}
+	}
+	return &ProjectScope{
+		projects:     conf.Projects,
+		keys:         keys,
+		egressStore:  egressStore,
+		ingressStore: ingressStore,
+	}, nil
+}
+
+// ServeHTTP sets the project of the request, and scopes the room of the token and of the room query parameter
+func (p *ProjectScope) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	ctx := r.Context()
+	project := p.keys[GetAPIKey(ctx)]
+	if project == "" && isTrusted(ctx) {
+		next.ServeHTTP(w, r)
+		return
+	}
+	if project != "" {
+		ctx = context.WithValue(ctx, projectKey{}, project)
+	}
+
+	if v, ok := ctx.Value(grantsKey{}).(*grantsValue); ok && v.claims != nil && v.claims.Video != nil && v.claims.Video.Room != "" {
+		roomName, err := p.scopeRoomName(project, livekit.RoomName(v.claims.Video.Room))
+		if err != nil {
+			handleError(w, r, http.StatusForbidden, err, "project", project, "room", v.claims.Video.Room)
+			return
+		}
+		scoped := *v
+		scoped.claims = v.claims.Clone()
+		scoped.claims.Video.Room = string(roomName)
+		ctx = context.WithValue(ctx, grantsKey{}, &scoped)
+	}
+
+	r = r.Clone(ctx)
+	query := r.URL.Query()
+	if room := query.Get("room"); room != "" {
+		roomName, err := p.scopeRoomName(project, livekit.RoomName(room))
+		if err != nil {
+			handleError(w, r, http.StatusForbidden, err, "project", project, "room", room)
+			return
+		}
+		query.Set("room", string(roomName))
+		r.URL.RawQuery = query.Encode()
+	}
+
+	next.ServeHTTP(w, r)
+}
+
+// Interceptor scopes room names of twirp requests and responses, checks egresses and ingresses referenced by
+// requests, and filters list responses to resources of the project
+func (p *ProjectScope) Interceptor() twirp.Interceptor {
+	return func(next twirp.Method) twirp.Method {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			project := GetProject(ctx)
+			if project == "" && isTrusted(ctx) {
+				return next(ctx, req)
+			}
+
+			if err := p.checkRequest(ctx, project, req); err != nil {
+				return nil, err
+			}
+
+			res, err := next(ctx, req)
+			if err != nil {
+				return res, err
+			}
+			p.scopeResponse(project, res)
+			return res, nil
+		}
+	}
+}
+
+func (p *ProjectScope) checkRequest(ctx context.Context, project string, req interface{}) error {
+	if project != "" {
+		service, _ := twirp.ServiceName(ctx)
+		method, _ := twirp.MethodName(ctx)
+		switch {
+		case service == "SIP" && method != "CreateSIPParticipant":
+			// trunks and dispatch rules are shared by the cluster
+			return ErrProjectPermissionDenied
+		case service == "Egress" && method == "StartWebEgress":
+			// web egresses have no room to belong to
+			return ErrProjectPermissionDenied
+		}
+		if r, ok := req.(*livekit.CreateIngressRequest); ok && r.RoomName == "" {
+			return ErrProjectPermissionDenied
+		}
+	}
+
+	if m, ok := req.(proto.Message); ok {
+		if err := rewriteRoomNames(m.ProtoReflect(), func(roomName livekit.RoomName) (livekit.RoomName, error) {
+			return p.scopeRoomName(project, roomName)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if r, ok := req.(interface{ GetEgressId() string }); ok && r.GetEgressId() != "" {
+		if p.egressStore == nil {
+			return ErrEgressNotConnected
+		}
+		info, err := p.egressStore.LoadEgress(ctx, r.GetEgressId())
+		if err != nil {
+			return err
+		}
+		if !p.inProject(project, livekit.RoomName(info.RoomName)) {
+			return ErrProjectPermissionDenied
+		}
+	}
+	if r, ok := req.(interface{ GetIngressId() string }); ok && r.GetIngressId() != "" {
+		if p.ingressStore == nil {
+			return ErrIngressNotConnected
+		}
+		info, err := p.ingressStore.LoadIngress(ctx, r.GetIngressId())
+		if err != nil {
+			return err
+		}
+		if !p.inProject(project, livekit.RoomName(info.RoomName)) {
+			return ErrProjectPermissionDenied
+		}
+	}
+	return nil
+}
+
+func (p *ProjectScope) scopeResponse(project string, res interface{}) {
+	switch r := res.(type) {
+	case *livekit.ListRoomsResponse:
+		r.Rooms = slices.DeleteFunc(r.Rooms, func(room *livekit.Room) bool {
+			return !p.inProject(project, livekit.RoomName(room.Name))
+		})
+	case *livekit.ListEgressResponse:
+		r.Items = slices.DeleteFunc(r.Items, func(info *livekit.EgressInfo) bool {
+			return !p.inProject(project, livekit.RoomName(info.RoomName))
+		})
+	case *livekit.ListIngressResponse:
+		r.Items = slices.DeleteFunc(r.Items, func(info *livekit.IngressInfo) bool {
+			return !p.inProject(project, livekit.RoomName(info.RoomName))
+		})
+	}
+
+	if m, ok := res.(proto.Message); ok && project != "" {
+		_ = rewriteRoomNames(m.ProtoReflect(), func(roomName livekit.RoomName) (livekit.RoomName, error) {
+			_, name := splitProjectRoomName(p.projects, roomName)
+			return name, nil
+		})
+	}
+}
+
+// scopeRoomName returns the server side name of a room named by a key of project. Keys outside of projects cannot
+// name rooms of projects
+func (p *ProjectScope) scopeRoomName(project string, roomName livekit.RoomName) (livekit.RoomName, error) {
+	if project != "" {
+		return projectRoomName(project, roomName), nil
+	}
+	if owner, _ := splitProjectRoomName(p.projects, roomName); owner != "" {
+		return "", ErrProjectPermissionDenied
+	}
+	return roomName, nil
+}
+
+func (p *ProjectScope) inProject(project string, roomName livekit.RoomName) bool {
+	owner, _ := splitProjectRoomName(p.projects, roomName)
+	return owner == project
+}
+
+// rewriteRoomNames replaces the room names set in m and in the messages it contains
+func rewriteRoomNames(m protoreflect.Message, fn func(roomName livekit.RoomName) (livekit.RoomName, error)) error {
+	var err error
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsMap():
+		case fd.Kind() == protoreflect.MessageKind && fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len() && err == nil; i++ {
+				err = rewriteRoomNames(list.Get(i).Message(), fn)
+			}
+		case fd.Kind() == protoreflect.MessageKind:
+			err = rewriteRoomNames(v.Message(), fn)
+		case fd.Kind() == protoreflect.StringKind && isRoomNameField(m.Descriptor(), fd) && fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len() && err == nil; i++ {
+				var roomName livekit.RoomName
+				if roomName, err = fn(livekit.RoomName(list.Get(i).String())); err == nil {
+					list.Set(i, protoreflect.ValueOfString(string(roomName)))
+				}
+			}
+		case fd.Kind() == protoreflect.StringKind && isRoomNameField(m.Descriptor(), fd):
+			var roomName livekit.RoomName
+			if roomName, err = fn(livekit.RoomName(v.String())); err == nil {
+				m.Set(fd, protoreflect.ValueOfString(string(roomName)))
+			}
+		}
+		return err == nil
+	})
+	return err
+}
+
+func isRoomNameField(md protoreflect.MessageDescriptor, fd protoreflect.FieldDescriptor) bool {
+	switch fd.Name() {
+	case "room", "room_name":
+		return true
+	case "name":
+		return md.FullName() == "livekit.Room" || md.FullName() == "livekit.CreateRoomRequest"
+	case "names":
+		return md.FullName() == "livekit.ListRoomsRequest"
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestProjectScope(t *testing.T) {
+	conf, err := config.NewConfig("", true, nil, nil)
+	require.NoError(t, err)
+	conf.Projects = map[string]config.ProjectConfig{
+		"a": {Keys: []string{"keyA"}},
+		"b": {Keys: []string{"keyB"}},
+	}
+	scope, err := service.NewProjectScope(conf, nil, nil)
+	require.NoError(t, err)
+
+	// serve runs the middleware with a token of apiKey, then fn with the request
+	serve := func(apiKey string, roomName string, fn func(r *http.Request)) int {
+		r := httptest.NewRequest(http.MethodGet, "/rtc?room="+roomName, nil)
+		grants := &auth.ClaimGrants{Video: &auth.VideoGrant{Room: roomName, RoomJoin: true, RoomList: true}}
+		r = r.WithContext(service.WithGrants(r.Context(), grants, apiKey))
+		w := httptest.NewRecorder()
+		scope.ServeHTTP(w, r, func(w http.ResponseWriter, r *http.Request) {
+			fn(r)
+		})
+		return w.Code
+	}
+
+	t.Run("rooms are named by project", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, serve("keyA", "room", func(r *http.Request) {
+			require.Equal(t, "a", service.GetProject(r.Context()))
+			require.Equal(t, "a:room", service.GetGrants(r.Context()).Video.Room)
+			require.Equal(t, "a:room", r.URL.Query().Get("room"))
+		}))
+		// names of other projects stay in the project of the key
+		require.Equal(t, http.StatusOK, serve("keyB", "a:room", func(r *http.Request) {
+			require.Equal(t, "b:a:room", service.GetGrants(r.Context()).Video.Room)
+		}))
+		// keys outside of projects cannot reach rooms of projects
+		require.Equal(t, http.StatusOK, serve("other", "room", func(r *http.Request) {
+			require.Empty(t, service.GetProject(r.Context()))
+			require.Equal(t, "room", service.GetGrants(r.Context()).Video.Room)
+		}))
+		require.Equal(t, http.StatusForbidden, serve("other", "a:room", func(r *http.Request) {}))
+	})
+
+	t.Run("twirp requests and responses", func(t *testing.T) {
+		listRooms := scope.Interceptor()(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return &livekit.ListRoomsResponse{Rooms: []*livekit.Room{{Name: "a:room"}, {Name: "b:room"}, {Name: "room"}}}, nil
+		})
+		var deleted string
+		deleteRoom := scope.Interceptor()(func(ctx context.Context, req interface{}) (interface{}, error) {
+			deleted = req.(*livekit.DeleteRoomRequest).Room
+			return &livekit.DeleteRoomResponse{}, nil
+		})
+		listEgress := scope.Interceptor()(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return &livekit.ListEgressResponse{Items: []*livekit.EgressInfo{
+				{
+					EgressId: "EG_a",
+					RoomName: "a:room",
+					Request: &livekit.EgressInfo_RoomComposite{
+						RoomComposite: &livekit.RoomCompositeEgressRequest{RoomName: "a:room"},
+					},
+				},
+				{EgressId: "EG_b", RoomName: "b:room"},
+			}}, nil
+		})
+
+		serve("keyA", "", func(r *http.Request) {
+			res, err := listRooms(r.Context(), &livekit.ListRoomsRequest{})
+			require.NoError(t, err)
+			rooms := res.(*livekit.ListRoomsResponse).Rooms
+			require.Len(t, rooms, 1)
+			require.Equal(t, "room", rooms[0].Name)
+
+			_, err = deleteRoom(r.Context(), &livekit.DeleteRoomRequest{Room: "room"})
+			require.NoError(t, err)
+			require.Equal(t, "a:room", deleted)
+
+			res, err = listEgress(r.Context(), &livekit.ListEgressRequest{})
+			require.NoError(t, err)
+			items := res.(*livekit.ListEgressResponse).Items
+			require.Len(t, items, 1)
+			require.Equal(t, "room", items[0].RoomName)
+			require.Equal(t, "room", items[0].GetRoomComposite().RoomName)
+		})
+
+		serve("other", "", func(r *http.Request) {
+			res, err := listRooms(r.Context(), &livekit.ListRoomsRequest{})
+			require.NoError(t, err)
+			rooms := res.(*livekit.ListRoomsResponse).Rooms
+			require.Len(t, rooms, 1)
+			require.Equal(t, "room", rooms[0].Name)
+
+			_, err = deleteRoom(r.Context(), &livekit.DeleteRoomRequest{Room: "a:room"})
+			require.ErrorIs(t, err, service.ErrProjectPermissionDenied)
+		})
+	})
+}
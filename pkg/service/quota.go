@@ -0,0 +1,484 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+)
+
+const (
+	quotaSyncInterval = 5 * time.Second
+	// usage reported by a node is ignored once it is this old, i.e. the node is gone
+	quotaUsageTTL = 3 * quotaSyncInterval
+	// a track publish request holds its reservation until published, released, or this old
+	quotaPendingTimeout = 10 * time.Second
+)
+
+// QuotaUsage is the concurrent usage of a project, identified by its name or API key
+type QuotaUsage struct {
+	Rooms           []livekit.RoomName `json:"rooms,omitempty"`
+	Participants    int32              `json:"participants"`
+	PublishedTracks int32              `json:"published_tracks"`
+	PublishBitrate  uint64             `json:"publish_bitrate"`
+	// bitrate of video wanted by subscribers of rooms hosted by the node
+	SubscribeDemand uint64 `json:"subscribe_demand,omitempty"`
+	UpdatedAt       int64  `json:"updated_at,omitempty"`
+}
+
+// QuotaStatus is returned by the quota API
+type QuotaStatus struct {
+	Limits config.QuotaConfig `json:"limits"`
+	Usage  struct {
+		Rooms           int32  `json:"rooms"`
+		Participants    int32  `json:"participants"`
+		PublishedTracks int32  `json:"published_tracks"`
+		PublishBitrate  uint64 `json:"publish_bitrate"`
+		SubscribeDemand uint64 `json:"subscribe_demand"`
+	} `json:"usage"`
+}
+
+type projectUsage struct {
+	// number of participants connected through this node, per room
+	rooms           map[livekit.RoomName]int
+	participants    int32
+	publishedTracks int32
+	publishBitrate  uint64
+	subscribeDemand uint64
+}
+
+func newProjectUsage() *projectUsage {
+	return &projectUsage{
+		rooms: make(map[livekit.RoomName]int),
+	}
+}
+
+func (u *projectUsage) isEmpty() bool {
+	return len(u.rooms) == 0 && u.participants == 0 && u.publishedTracks == 0 && u.publishBitrate == 0 && u.subscribeDemand == 0
+}
+
+func (u *projectUsage) add(other *QuotaUsage) {
+	for _, roomName := range other.Rooms {
+		if _, ok := u.rooms[roomName]; !ok {
+			u.rooms[roomName] = 0
+		}
+	}
+	u.participants += other.Participants
+	u.publishedTracks += other.PublishedTracks
+	u.publishBitrate += other.PublishBitrate
+	u.subscribeDemand += other.SubscribeDemand
+}
+
+func (u *projectUsage) toQuotaUsage(at time.Time) *QuotaUsage {
+	rooms := make([]livekit.RoomName, 0, len(u.rooms))
+	for roomName := range u.rooms {
+		rooms = append(rooms, roomName)
+	}
+	return &QuotaUsage{
+		Rooms:           rooms,
+		Participants:    u.participants,
+		PublishedTracks: u.publishedTracks,
+		PublishBitrate:  u.publishBitrate,
+		SubscribeDemand: u.subscribeDemand,
+		UpdatedAt:       at.UnixNano(),
+	}
+}
+
+// QuotaManager enforces per project quotas at join and publish time.
+// Sessions are accounted on the node handling their signal connection, and usage is shared with other nodes
+// through the store, so limits are enforced cluster wide within quotaSyncInterval.
+type QuotaManager struct {
+	quotas      map[string]config.QuotaConfig
+	currentNode routing.LocalNode
+	store       QuotaStore
+
+	lock   sync.Mutex
+	local  map[string]*projectUsage
+	remote map[string]*projectUsage
+
+	done chan struct{}
+}
+
+func NewQuotaManager(conf *config.Config, currentNode routing.LocalNode, store ObjectStore) *QuotaManager {
+	m := &QuotaManager{
+		quotas:      conf.Quotas,
+		currentNode: currentNode,
+		local:       make(map[string]*projectUsage),
+		remote:      make(map[string]*projectUsage),
+		done:        make(chan struct{}),
+	}
+	if qs, ok := store.(QuotaStore); ok {
+		m.store = qs
+		go m.worker()
+	}
+	return m
+}
+
+func (m *QuotaManager) Stop() {
+	select {
+	case <-m.done:
+	default:
+		close(m.done)
+	}
+}
+
+// StartSession checks room and participant quotas for a participant joining roomName, and accounts for it
+// until the returned session is closed. Reconnecting participants are accounted for, but never rejected.
+func (m *QuotaManager) StartSession(apiKey string, roomName livekit.RoomName, reconnect bool) (*QuotaSession, error) {
+	if m == nil || apiKey == "" {
+		return nil, nil
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if !reconnect {
+		quota := m.quotas[apiKey]
+		total := m.totalLocked(apiKey)
+		if _, ok := total.rooms[roomName]; !ok && quota.MaxRooms > 0 && int32(len(total.rooms)) >= quota.MaxRooms {
+			return nil, ErrRoomQuotaExceeded
+		}
+		if quota.MaxParticipants > 0 && total.participants >= quota.MaxParticipants {
+			return nil, ErrParticipantQuotaExceeded
+		}
+	}
+
+	usage := m.localUsageLocked(apiKey)
+	usage.rooms[roomName]++
+	usage.participants++
+
+	return &QuotaSession{
+		manager:  m,
+		apiKey:   apiKey,
+		roomName: roomName,
+		pending:  make(map[string]pendingTrack),
+		tracks:   make(map[livekit.TrackID]uint64),
+	}, nil
+}
+
+// GetStatus returns limits and cluster wide usage of a project
+func (m *QuotaManager) GetStatus(ctx context.Context, apiKey string) (*QuotaStatus, error) {
+	var remote map[livekit.NodeID]*QuotaUsage
+	if m.store != nil {
+		var err error
+		if remote, err = m.store.LoadQuotaUsage(ctx, apiKey); err != nil {
+			return nil, err
+		}
+	}
+
+	m.lock.Lock()
+	total := newProjectUsage()
+	if usage := m.local[apiKey]; usage != nil {
+		total.add(usage.toQuotaUsage(time.Now()))
+	}
+	m.lock.Unlock()
+
+	for nodeID, usage := range remote {
+		if nodeID != livekit.NodeID(m.currentNode.Id) {
+			total.add(usage)
+		}
+	}
+
+	status := &QuotaStatus{Limits: m.quotas[apiKey]}
+	status.Usage.Rooms = int32(len(total.rooms))
+	status.Usage.Participants = total.participants
+	status.Usage.PublishedTracks = total.publishedTracks
+	status.Usage.PublishBitrate = total.publishBitrate
+	status.Usage.SubscribeDemand = total.subscribeDemand
+	return status, nil
+}
+
+// ServeHTTP returns the quota status of the project making the request
+func (m *QuotaManager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := EnsureListPermission(r.Context()); err != nil {
+		handleError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	status, err := m.GetStatus(r.Context(), quotaKey(r.Context()))
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(status)
+}
+
+// SetSubscribeDemand records the bitrate wanted by subscribers of the project on this node, and returns the
+// demand reported by other nodes
+func (m *QuotaManager) SetSubscribeDemand(apiKey string, demand uint64) uint64 {
+	if m == nil {
+		return 0
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if usage := m.local[apiKey]; usage != nil || demand != 0 {
+		m.localUsageLocked(apiKey).subscribeDemand = demand
+	}
+	if usage := m.remote[apiKey]; usage != nil {
+		return usage.subscribeDemand
+	}
+	return 0
+}
+
+func (m *QuotaManager) localUsageLocked(apiKey string) *projectUsage {
+	usage := m.local[apiKey]
+	if usage == nil {
+		usage = newProjectUsage()
+		m.local[apiKey] = usage
+	}
+	return usage
+}
+
+func (m *QuotaManager) totalLocked(apiKey string) *projectUsage {
+	total := newProjectUsage()
+	if usage := m.local[apiKey]; usage != nil {
+		total.add(usage.toQuotaUsage(time.Time{}))
+	}
+	if usage := m.remote[apiKey]; usage != nil {
+		total.add(usage.toQuotaUsage(time.Time{}))
+	}
+	return total
+}
+
+func (m *QuotaManager) checkTrackLocked(apiKey string, pendingTracks int32, pendingBitrate uint64, bitrate uint64) error {
+	quota := m.quotas[apiKey]
+	total := m.totalLocked(apiKey)
+	if quota.MaxPublishedTracks > 0 && total.publishedTracks+pendingTracks >= quota.MaxPublishedTracks {
+		return ErrTrackQuotaExceeded
+	}
+	if quota.MaxPublishBitrate > 0 && bitrate > 0 && total.publishBitrate+pendingBitrate+bitrate > quota.MaxPublishBitrate {
+		return ErrBitrateQuotaExceeded
+	}
+	return nil
+}
+
+func (m *QuotaManager) worker() {
+	ticker := time.NewTicker(quotaSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			m.sync()
+		}
+	}
+}
+
+func (m *QuotaManager) sync() {
+	ctx, cancel := context.WithTimeout(context.Background(), quotaSyncInterval)
+	defer cancel()
+
+	now := time.Now()
+	m.lock.Lock()
+	usage := make(map[string]*QuotaUsage, len(m.local))
+	for apiKey, u := range m.local {
+		usage[apiKey] = u.toQuotaUsage(now)
+		// report empty usage once to clear previous reports, then forget about the project
+		if u.isEmpty() {
+			delete(m.local, apiKey)
+		}
+	}
+	m.lock.Unlock()
+
+	if err := m.store.StoreQuotaUsage(ctx, livekit.NodeID(m.currentNode.Id), usage); err != nil {
+		logger.Errorw("could not store quota usage", err)
+	}
+
+	// only projects with quotas need usage from other nodes
+	remote := make(map[string]*projectUsage, len(m.quotas))
+	for apiKey := range m.quotas {
+		nodes, err := m.store.LoadQuotaUsage(ctx, apiKey)
+		if err != nil {
+			logger.Errorw("could not load quota usage", err, "apiKey", apiKey)
+			continue
+		}
+		total := newProjectUsage()
+		for nodeID, u := range nodes {
+			if nodeID != livekit.NodeID(m.currentNode.Id) {
+				total.add(u)
+			}
+		}
+		remote[apiKey] = total
+	}
+
+	m.lock.Lock()
+	m.remote = remote
+	m.lock.Unlock()
+}
+
+// QuotaSession accounts for a single participant's signal connection, methods are safe to call on a nil session
+type QuotaSession struct {
+	manager  *QuotaManager
+	apiKey   string
+	roomName livekit.RoomName
+
+	// declared bitrate of tracks, pending by client track id and published by track id
+	pending map[string]pendingTrack
+	tracks  map[livekit.TrackID]uint64
+	closed  bool
+}
+
+type pendingTrack struct {
+	bitrate uint64
+	addedAt time.Time
+}
+
+// AddTrack checks track and bitrate quotas for a track publish request
+func (s *QuotaSession) AddTrack(req *livekit.AddTrackRequest) error {
+	if s == nil {
+		return nil
+	}
+
+	m := s.manager
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if s.closed {
+		return nil
+	}
+
+	// requests the media node refused never get a published response, drop them once they are stale
+	now := time.Now()
+	var pendingBitrate uint64
+	for cid, pending := range s.pending {
+		if cid == req.Cid || now.Sub(pending.addedAt) > quotaPendingTimeout {
+			delete(s.pending, cid)
+			continue
+		}
+		pendingBitrate += pending.bitrate
+	}
+	bitrate := layersBitrate(req.Layers)
+	if err := m.checkTrackLocked(s.apiKey, int32(len(s.pending)), pendingBitrate, bitrate); err != nil {
+		return err
+	}
+
+	s.pending[req.Cid] = pendingTrack{bitrate: bitrate, addedAt: now}
+	return nil
+}
+
+// ReleaseTrack releases the reservation of a track publish request that was not forwarded
+func (s *QuotaSession) ReleaseTrack(cid string) {
+	if s == nil {
+		return
+	}
+
+	m := s.manager
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(s.pending, cid)
+}
+
+// TrackPublished moves a pending track to published
+func (s *QuotaSession) TrackPublished(res *livekit.TrackPublishedResponse) {
+	if s == nil || res.GetTrack() == nil {
+		return
+	}
+
+	m := s.manager
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	trackID := livekit.TrackID(res.Track.Sid)
+	if s.closed {
+		return
+	}
+	if _, ok := s.tracks[trackID]; ok {
+		return
+	}
+
+	bitrate := layersBitrate(res.Track.Layers)
+	if pending, ok := s.pending[res.Cid]; ok {
+		bitrate = pending.bitrate
+		delete(s.pending, res.Cid)
+	}
+	s.tracks[trackID] = bitrate
+
+	usage := m.localUsageLocked(s.apiKey)
+	usage.publishedTracks++
+	usage.publishBitrate += bitrate
+}
+
+// TrackUnpublished releases a published track
+func (s *QuotaSession) TrackUnpublished(trackID livekit.TrackID) {
+	if s == nil {
+		return
+	}
+
+	m := s.manager
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	bitrate, ok := s.tracks[trackID]
+	if !ok || s.closed {
+		return
+	}
+	delete(s.tracks, trackID)
+
+	usage := m.localUsageLocked(s.apiKey)
+	usage.publishedTracks--
+	usage.publishBitrate -= bitrate
+}
+
+// Close releases everything accounted for by the session
+func (s *QuotaSession) Close() {
+	if s == nil {
+		return
+	}
+
+	m := s.manager
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+
+	usage := m.localUsageLocked(s.apiKey)
+	for _, bitrate := range s.tracks {
+		usage.publishedTracks--
+		usage.publishBitrate -= bitrate
+	}
+	usage.participants--
+	if usage.rooms[s.roomName]--; usage.rooms[s.roomName] <= 0 {
+		delete(usage.rooms, s.roomName)
+	}
+	if usage.isEmpty() && m.store == nil {
+		delete(m.local, s.apiKey)
+	}
+}
+
+func layersBitrate(layers []*livekit.VideoLayer) uint64 {
+	var bitrate uint64
+	for _, layer := range layers {
+		bitrate += uint64(layer.GetBitrate())
+	}
+	return bitrate
+}
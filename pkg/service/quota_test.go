@@ -0,0 +1,140 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestQuotaManager(t *testing.T) {
+	newQuotaManager := func(t *testing.T, quota config.QuotaConfig) *service.QuotaManager {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		conf.Quotas = map[string]config.QuotaConfig{"key": quota}
+
+		node, err := routing.NewLocalNode(conf)
+		require.NoError(t, err)
+
+		m := service.NewQuotaManager(conf, node, service.NewLocalStore())
+		t.Cleanup(m.Stop)
+		return m
+	}
+
+	t.Run("rooms and participants", func(t *testing.T) {
+		m := newQuotaManager(t, config.QuotaConfig{MaxRooms: 1, MaxParticipants: 2})
+
+		s1, err := m.StartSession("key", "room1", false)
+		require.NoError(t, err)
+
+		_, err = m.StartSession("key", "room2", false)
+		require.ErrorIs(t, err, service.ErrRoomQuotaExceeded)
+
+		s2, err := m.StartSession("key", "room1", false)
+		require.NoError(t, err)
+
+		_, err = m.StartSession("key", "room1", false)
+		require.ErrorIs(t, err, service.ErrParticipantQuotaExceeded)
+
+		// reconnects are never rejected
+		s3, err := m.StartSession("key", "room1", true)
+		require.NoError(t, err)
+		s3.Close()
+
+		// other projects are not affected
+		_, err = m.StartSession("other", "room2", false)
+		require.NoError(t, err)
+
+		status, err := m.GetStatus(context.Background(), "key")
+		require.NoError(t, err)
+		require.EqualValues(t, 1, status.Usage.Rooms)
+		require.EqualValues(t, 2, status.Usage.Participants)
+
+		s1.Close()
+		s2.Close()
+		_, err = m.StartSession("key", "room2", false)
+		require.NoError(t, err)
+	})
+
+	t.Run("published tracks", func(t *testing.T) {
+		m := newQuotaManager(t, config.QuotaConfig{MaxPublishedTracks: 2, MaxPublishBitrate: 1000})
+
+		s, err := m.StartSession("key", "room", false)
+		require.NoError(t, err)
+
+		publish := func(cid string, sid string, bitrate uint32) error {
+			layers := []*livekit.VideoLayer{{Bitrate: bitrate}}
+			if err := s.AddTrack(&livekit.AddTrackRequest{Cid: cid, Layers: layers}); err != nil {
+				return err
+			}
+			s.TrackPublished(&livekit.TrackPublishedResponse{
+				Cid:   cid,
+				Track: &livekit.TrackInfo{Sid: sid, Layers: layers},
+			})
+			return nil
+		}
+
+		require.NoError(t, publish("c1", "TR_1", 600))
+		require.ErrorIs(t, publish("c2", "TR_2", 600), service.ErrBitrateQuotaExceeded)
+		require.NoError(t, publish("c3", "TR_3", 0))
+		require.ErrorIs(t, publish("c4", "TR_4", 0), service.ErrTrackQuotaExceeded)
+
+		status, err := m.GetStatus(context.Background(), "key")
+		require.NoError(t, err)
+		require.EqualValues(t, 2, status.Usage.PublishedTracks)
+		require.EqualValues(t, 600, status.Usage.PublishBitrate)
+
+		s.TrackUnpublished("TR_1")
+		require.NoError(t, publish("c5", "TR_5", 900))
+
+		// a released request no longer holds its reservation
+		s.TrackUnpublished("TR_5")
+		require.NoError(t, s.AddTrack(&livekit.AddTrackRequest{Cid: "c6", Layers: []*livekit.VideoLayer{{Bitrate: 900}}}))
+		require.ErrorIs(t, s.AddTrack(&livekit.AddTrackRequest{Cid: "c7", Layers: []*livekit.VideoLayer{{Bitrate: 900}}}), service.ErrTrackQuotaExceeded)
+		s.ReleaseTrack("c6")
+		require.NoError(t, s.AddTrack(&livekit.AddTrackRequest{Cid: "c7", Layers: []*livekit.VideoLayer{{Bitrate: 900}}}))
+		s.ReleaseTrack("c7")
+
+		s.Close()
+		status, err = m.GetStatus(context.Background(), "key")
+		require.NoError(t, err)
+		require.Zero(t, status.Usage.Participants)
+		require.Zero(t, status.Usage.PublishedTracks)
+		require.Zero(t, status.Usage.PublishBitrate)
+	})
+
+	t.Run("subscribe demand", func(t *testing.T) {
+		m := newQuotaManager(t, config.QuotaConfig{MaxSubscribeBitrate: 1000})
+
+		// no other nodes
+		require.Zero(t, m.SetSubscribeDemand("key", 1500))
+
+		status, err := m.GetStatus(context.Background(), "key")
+		require.NoError(t, err)
+		require.EqualValues(t, 1500, status.Usage.SubscribeDemand)
+
+		m.SetSubscribeDemand("key", 0)
+		status, err = m.GetStatus(context.Background(), "key")
+		require.NoError(t, err)
+		require.Zero(t, status.Usage.SubscribeDemand)
+	})
+}
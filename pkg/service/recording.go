@@ -0,0 +1,123 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	recordingPath = "/recording"
+
+	recordingMethod = "SetRecording"
+)
+
+// RoomRecordingStore keeps the recording indication set through the API until the room is deleted,
+// so it survives the room moving to another node
+type RoomRecordingStore interface {
+	StoreRoomRecording(ctx context.Context, roomName livekit.RoomName, active bool) error
+	LoadRoomRecording(ctx context.Context, roomName livekit.RoomName) (bool, error)
+}
+
+type recordingRequest struct {
+	Active bool `json:"active"`
+}
+
+// RecordingHandler lets applications indicate that a room is being recorded, through the node hosting the room.
+// Egress recorders in the room set the indication automatically.
+type RecordingHandler struct {
+	roomAPI *RoomAPI
+	store   RoomRecordingStore
+}
+
+func NewRecordingHandler(roomManager *RoomManager) *RecordingHandler {
+	h := &RecordingHandler{
+		roomAPI: roomManager.RoomAPI(),
+	}
+	h.store, _ = roomManager.roomStore.(RoomRecordingStore)
+	h.roomAPI.Handle(recordingMethod, h.handleSetRecording)
+	return h
+}
+
+func (h *RecordingHandler) SetRecording(ctx context.Context, roomName livekit.RoomName, active bool) (*livekit.Room, error) {
+	res := &livekit.Room{}
+	if err := h.roomAPI.CallRoom(ctx, roomName, recordingMethod, &recordingRequest{Active: active}, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (h *RecordingHandler) handleSetRecording(ctx context.Context, room *rtc.Room, _ types.LocalParticipant, body json.RawMessage) (any, error) {
+	var req recordingRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+
+	room.Logger.Infow("api set recording", "active", req.Active)
+	if h.store != nil {
+		if err := h.store.StoreRoomRecording(ctx, room.Name(), req.Active); err != nil {
+			return nil, err
+		}
+	}
+	// wait till the update is applied
+	<-room.SetRecording(req.Active)
+	return room.ToProto(), nil
+}
+
+// ServeHTTP handles POST /recording?room=&active=true|false, the token must have roomAdmin permission for the room
+func (h *RecordingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	roomName := livekit.RoomName(query.Get("room"))
+	if err := EnsureAdminPermission(r.Context(), roomName); err != nil {
+		handleError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	active, err := strconv.ParseBool(query.Get("active"))
+	if err != nil {
+		handleError(w, r, http.StatusBadRequest, err, "room", roomName)
+		return
+	}
+
+	room, err := h.SetRecording(r.Context(), roomName, active)
+	if err != nil {
+		handleError(w, r, roomAPIStatus(err), err, "room", roomName)
+		return
+	}
+
+	data, err := protojson.Marshal(room)
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
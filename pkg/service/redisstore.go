@@ -16,6 +16,7 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -32,6 +33,9 @@ import (
 	"github.com/livekit/protocol/utils/guid"
 	"github.com/livekit/psrpc"
 
+	"github.com/livekit/livekit-server/pkg/agent"
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
 	"github.com/livekit/livekit-server/version"
 )
 
@@ -62,6 +66,24 @@ const (
 	// Agents
 	AgentDispatchPrefix = "agent_dispatch:"
 	AgentJobPrefix      = "agent_job:"
+	// AgentJobResultPrefix is hash of job_id => agent.JobResult json
+	AgentJobResultPrefix = "agent_job_result:"
+
+	// RoomConfigOverridesKey is hash of room_name => config.RoomConfigOverrides json
+	RoomConfigOverridesKey = "room_config_overrides"
+
+	// RoomRecordingKey is hash of room_name => "1" for rooms indicated as recorded through the API
+	RoomRecordingKey = "room_recording"
+
+	// RoomStatePrefix is hash of state key => rtc.RoomStateEntry json. the version of the state is kept in
+	// the empty field, which is not a valid state key
+	RoomStatePrefix = "room_state:"
+
+	// JoinTokenPrefix is a key per used join url token, expiring once the token can no longer be used
+	JoinTokenPrefix = "join_token:"
+
+	// QuotaUsagePrefix is hash of node_id => QuotaUsage json, per API key
+	QuotaUsagePrefix = "quota_usage:"
 
 	maxRetries = 5
 )
@@ -238,6 +260,10 @@ func (s *RedisStore) DeleteRoom(ctx context.Context, roomName livekit.RoomName)
 	pp.Del(s.ctx, RoomParticipantsPrefix+string(roomName))
 	pp.Del(s.ctx, AgentDispatchPrefix+string(roomName))
 	pp.Del(s.ctx, AgentJobPrefix+string(roomName))
+	pp.Del(s.ctx, AgentJobResultPrefix+string(roomName))
+	pp.HDel(s.ctx, RoomConfigOverridesKey, string(roomName))
+	pp.HDel(s.ctx, RoomRecordingKey, string(roomName))
+	pp.Del(s.ctx, RoomStatePrefix+string(roomName))
 
 	_, err = pp.Exec(s.ctx)
 	return err
@@ -922,6 +948,149 @@ func (s *RedisStore) DeleteAgentJob(_ context.Context, job *livekit.Job) error {
 	return s.rc.HDel(s.ctx, key, job.Id).Err()
 }
 
+func (s *RedisStore) StoreAgentJobResult(_ context.Context, roomName livekit.RoomName, result *agent.JobResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	return s.rc.HSet(s.ctx, AgentJobResultPrefix+string(roomName), result.JobID, data).Err()
+}
+
+func (s *RedisStore) StoreRoomConfigOverrides(_ context.Context, roomName livekit.RoomName, overrides *config.RoomConfigOverrides) error {
+	data, err := json.Marshal(overrides)
+	if err != nil {
+		return err
+	}
+
+	return s.rc.HSet(s.ctx, RoomConfigOverridesKey, string(roomName), data).Err()
+}
+
+func (s *RedisStore) LoadRoomConfigOverrides(_ context.Context, roomName livekit.RoomName) (*config.RoomConfigOverrides, error) {
+	data, err := s.rc.HGet(s.ctx, RoomConfigOverridesKey, string(roomName)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	overrides := &config.RoomConfigOverrides{}
+	if err = json.Unmarshal([]byte(data), overrides); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+func (s *RedisStore) StoreRoomRecording(_ context.Context, roomName livekit.RoomName, active bool) error {
+	if !active {
+		return s.rc.HDel(s.ctx, RoomRecordingKey, string(roomName)).Err()
+	}
+	return s.rc.HSet(s.ctx, RoomRecordingKey, string(roomName), "1").Err()
+}
+
+func (s *RedisStore) LoadRoomRecording(_ context.Context, roomName livekit.RoomName) (bool, error) {
+	return s.rc.HExists(s.ctx, RoomRecordingKey, string(roomName)).Result()
+}
+
+func (s *RedisStore) StoreRoomStateEntry(_ context.Context, roomName livekit.RoomName, e *rtc.RoomStateEntry) error {
+	key := RoomStatePrefix + string(roomName)
+	pp := s.rc.TxPipeline()
+	if e.Deleted {
+		pp.HDel(s.ctx, key, e.Key)
+	} else {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		pp.HSet(s.ctx, key, e.Key, data)
+	}
+	pp.HSet(s.ctx, key, "", e.Version)
+	_, err := pp.Exec(s.ctx)
+	return err
+}
+
+func (s *RedisStore) LoadRoomState(_ context.Context, roomName livekit.RoomName) ([]*rtc.RoomStateEntry, uint64, error) {
+	data, err := s.rc.HGetAll(s.ctx, RoomStatePrefix+string(roomName)).Result()
+	if err == redis.Nil {
+		return nil, 0, nil
+	} else if err != nil {
+		return nil, 0, err
+	}
+
+	var version uint64
+	entries := make([]*rtc.RoomStateEntry, 0, len(data))
+	for k, d := range data {
+		if k == "" {
+			if version, err = strconv.ParseUint(d, 10, 64); err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+		e := &rtc.RoomStateEntry{}
+		if err = json.Unmarshal([]byte(d), e); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, version, nil
+}
+
+func (s *RedisStore) StoreQuotaUsage(_ context.Context, nodeID livekit.NodeID, usage map[string]*QuotaUsage) error {
+	pp := s.rc.Pipeline()
+	for apiKey, u := range usage {
+		data, err := json.Marshal(u)
+		if err != nil {
+			return err
+		}
+		key := QuotaUsagePrefix + apiKey
+		pp.HSet(s.ctx, key, string(nodeID), data)
+		pp.Expire(s.ctx, key, quotaUsageTTL)
+	}
+	_, err := pp.Exec(s.ctx)
+	return err
+}
+
+func (s *RedisStore) LoadQuotaUsage(_ context.Context, apiKey string) (map[livekit.NodeID]*QuotaUsage, error) {
+	key := QuotaUsagePrefix + apiKey
+	items, err := s.rc.HGetAll(s.ctx, key).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	staleBefore := time.Now().Add(-quotaUsageTTL).UnixNano()
+	usage := make(map[livekit.NodeID]*QuotaUsage, len(items))
+	var stale []string
+	for nodeID, data := range items {
+		u := &QuotaUsage{}
+		if err := json.Unmarshal([]byte(data), u); err != nil {
+			return nil, err
+		}
+		if u.UpdatedAt < staleBefore {
+			stale = append(stale, nodeID)
+			continue
+		}
+		usage[livekit.NodeID(nodeID)] = u
+	}
+
+	if len(stale) != 0 {
+		if err := s.rc.HDel(s.ctx, key, stale...).Err(); err != nil {
+			logger.Warnw("could not delete stale quota usage", err, "apiKey", apiKey)
+		}
+	}
+	return usage, nil
+}
+func (s *RedisStore) ReserveJoinToken(_ context.Context, tokenID string, ttl time.Duration) (bool, error) {
+	return s.rc.SetNX(s.ctx, JoinTokenPrefix+tokenID, 1, ttl).Result()
+}
+
+func (s *RedisStore) ConsumeJoinToken(_ context.Context, tokenID string, ttl time.Duration) error {
+	return s.rc.Set(s.ctx, JoinTokenPrefix+tokenID, 1, ttl).Err()
+}
+
+func (s *RedisStore) ReleaseJoinToken(_ context.Context, tokenID string) error {
+	return s.rc.Del(s.ctx, JoinTokenPrefix+tokenID).Err()
+}
+
 func redisStoreOne(ctx context.Context, s *RedisStore, key, id string, p proto.Message) error {
 	if id == "" {
 		return errors.New("id is not set")
@@ -0,0 +1,95 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/psrpc/pkg/metadata"
+)
+
+// removeReasonHeader carries the reason of RemoveParticipant requests, as a JSON object
+// e.g. {"reason": "DUPLICATE_IDENTITY", "message": "signed in on another device"}. The reason is a
+// livekit.DisconnectReason and defaults to PARTICIPANT_REMOVED, it is sent to the client in the leave request and
+// to webhooks in the participant info. The message is only logged, LeaveRequest has no field for it.
+// RoomParticipantIdentity has no field for either, the header is used until the protocol carries them
+const removeReasonHeader = "X-LiveKit-Remove-Reason"
+
+const (
+	maxRemoveMessageLength = 1024
+
+	// psrpc metadata keys forwarding the reason to the node hosting the participant
+	removeReasonKey  = "remove_reason"
+	removeMessageKey = "remove_message"
+)
+
+type removeReasonHeaderKey struct{}
+
+type removeReason struct {
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// withRemoveReasonHeader makes the remove reason header available to twirp handlers
+func withRemoveReasonHeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v := r.Header.Get(removeReasonHeader); v != "" {
+			r = r.WithContext(context.WithValue(r.Context(), removeReasonHeaderKey{}, v))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withRemoveReasonMetadata validates the remove reason of the request, and adds it to the outgoing psrpc metadata
+func withRemoveReasonMetadata(ctx context.Context) (context.Context, error) {
+	v, _ := ctx.Value(removeReasonHeaderKey{}).(string)
+	if v == "" {
+		return ctx, nil
+	}
+
+	var r removeReason
+	if err := json.Unmarshal([]byte(v), &r); err != nil {
+		return nil, err
+	}
+	reason := livekit.DisconnectReason_PARTICIPANT_REMOVED
+	if r.Reason != "" {
+		value, ok := livekit.DisconnectReason_value[r.Reason]
+		if !ok {
+			return nil, fmt.Errorf("unknown reason %q", r.Reason)
+		}
+		reason = livekit.DisconnectReason(value)
+	}
+	if len(r.Message) > maxRemoveMessageLength {
+		return nil, fmt.Errorf("message cannot be longer than %d bytes", maxRemoveMessageLength)
+	}
+	return metadata.AppendMetadataToOutgoingContext(ctx, removeReasonKey, reason.String(), removeMessageKey, r.Message), nil
+}
+
+// removeReasonFromMetadata returns the reason forwarded by withRemoveReasonMetadata
+func removeReasonFromMetadata(ctx context.Context) (livekit.DisconnectReason, string, bool) {
+	h := metadata.IncomingHeader(ctx)
+	if h == nil {
+		return 0, "", false
+	}
+	value, ok := livekit.DisconnectReason_value[h.Metadata[removeReasonKey]]
+	if !ok {
+		return 0, "", false
+	}
+	return livekit.DisconnectReason(value), h.Metadata[removeMessageKey], true
+}
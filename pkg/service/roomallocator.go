@@ -37,9 +37,10 @@ type StandardRoomAllocator struct {
 	router    routing.Router
 	selector  selector.NodeSelector
 	roomStore ObjectStore
+	roomHooks RoomHooks
 }
 
-func NewRoomAllocator(conf *config.Config, router routing.Router, rs ObjectStore) (RoomAllocator, error) {
+func NewRoomAllocator(conf *config.Config, router routing.Router, rs ObjectStore, roomHooks RoomHooks) (RoomAllocator, error) {
 	ns, err := selector.CreateNodeSelector(conf)
 	if err != nil {
 		return nil, err
@@ -50,16 +51,35 @@ func NewRoomAllocator(conf *config.Config, router routing.Router, rs ObjectStore
 		router:    router,
 		selector:  ns,
 		roomStore: rs,
+		roomHooks: roomHooks,
 	}, nil
 }
 
 func (r *StandardRoomAllocator) CreateRoomEnabled() bool {
-	return r.config.Room.CreateRoomEnabled
+	return r.config.Reloadable().Room.CreateRoomEnabled
 }
 
 // CreateRoom creates a new room from a request and allocates it to a node to handle
 // it'll also monitor its state, and cleans it up when appropriate
 func (r *StandardRoomAllocator) CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest, isExplicit bool) (*livekit.Room, *livekit.RoomInternal, bool, error) {
+	req, err := r.applyNamedRoomConfiguration(req)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	// the create hook is called before taking the room lock, so a slow hook does not hold up other requests for the room
+	var hookedReq *livekit.CreateRoomRequest
+	if r.roomHooks != nil {
+		if _, _, err = r.roomStore.LoadRoom(ctx, livekit.RoomName(req.Name), false); errors.Is(err, ErrRoomNotFound) {
+			hookedReq = proto.Clone(req).(*livekit.CreateRoomRequest)
+			if err = r.roomHooks.BeforeRoomCreate(ctx, hookedReq); err != nil {
+				return nil, nil, false, err
+			}
+		} else if err != nil {
+			return nil, nil, false, err
+		}
+	}
+
 	token, err := r.roomStore.LockRoom(ctx, livekit.RoomName(req.Name), 5*time.Second)
 	if err != nil {
 		return nil, nil, false, err
@@ -68,6 +88,9 @@ func (r *StandardRoomAllocator) CreateRoom(ctx context.Context, req *livekit.Cre
 		_ = r.roomStore.UnlockRoom(ctx, livekit.RoomName(req.Name), token)
 	}()
 
+	reloadable := r.config.Reloadable()
+	roomConf := &reloadable.Room
+
 	// find existing room and update it
 	var created bool
 	rm, internal, err := r.roomStore.LoadRoom(ctx, livekit.RoomName(req.Name), true)
@@ -80,14 +103,35 @@ func (r *StandardRoomAllocator) CreateRoom(ctx context.Context, req *livekit.Cre
 			TurnPassword: utils.RandomSecret(),
 		}
 		internal = &livekit.RoomInternal{}
-		applyDefaultRoomConfig(rm, internal, &r.config.Room)
+		applyDefaultRoomConfig(rm, internal, roomConf)
 	} else if err != nil {
 		return nil, nil, false, err
 	}
 
-	req, err = r.applyNamedRoomConfiguration(req)
+	if created && r.roomHooks != nil {
+		if hookedReq == nil {
+			// the room was deleted after it was checked, the caller retries and the hook is called then
+			return nil, nil, false, psrpc.NewErrorf(psrpc.Unavailable, "room %s was deleted while it was being updated", req.Name)
+		}
+		req = hookedReq
+	}
+
+	requested, err := requestedRoomConfigOverrides(ctx)
 	if err != nil {
-		return nil, nil, false, err
+		return nil, nil, false, psrpc.NewError(psrpc.InvalidArgument, err)
+	}
+	var overridesStore RoomConfigOverridesStore
+	if requested != nil {
+		if !created {
+			return nil, nil, false, psrpc.NewErrorf(psrpc.AlreadyExists, "room %s exists, config overrides only apply when the room is created", req.Name)
+		}
+		var ok bool
+		if overridesStore, ok = r.roomStore.(RoomConfigOverridesStore); !ok {
+			return nil, nil, false, psrpc.NewErrorf(psrpc.Unimplemented, "room config overrides are not supported by the store")
+		}
+		clamped := *requested
+		clamped.ClampToLimits(reloadable.Limit, *roomConf)
+		requested = &clamped
 	}
 
 	if req.EmptyTimeout > 0 {
@@ -124,14 +168,36 @@ func (r *StandardRoomAllocator) CreateRoom(ctx context.Context, req *livekit.Cre
 		internal.SyncStreams = true
 	}
 
+	overrides, err := loadRoomConfigOverrides(ctx, r.roomStore, roomConf, livekit.RoomName(req.Name), req.ConfigName)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if requested != nil {
+		var merged config.RoomConfigOverrides
+		if overrides != nil {
+			merged = *overrides
+		}
+		merged = merged.Merge(*requested)
+		overrides = &merged
+	}
+	applyRoomConfigOverrides(rm, internal, overrides)
+
 	if err = r.roomStore.StoreRoom(ctx, rm, internal); err != nil {
 		return nil, nil, false, err
 	}
+	if requested != nil {
+		if err = overridesStore.StoreRoomConfigOverrides(ctx, livekit.RoomName(req.Name), requested); err != nil {
+			_ = r.roomStore.DeleteRoom(ctx, livekit.RoomName(req.Name))
+			return nil, nil, false, err
+		}
+	}
 
 	return rm, internal, created, nil
 }
 
-func (r *StandardRoomAllocator) SelectRoomNode(ctx context.Context, roomName livekit.RoomName, nodeID livekit.NodeID) error {
+// SelectRoomNode assigns a node to the room unless it is already hosted on an available node. When nodeID
+// is not set, the node is selected among nodes matching placement constraints of the room configuration
+func (r *StandardRoomAllocator) SelectRoomNode(ctx context.Context, roomName livekit.RoomName, nodeID livekit.NodeID, configName string) error {
 	// check if room already assigned
 	existing, err := r.router.GetNodeForRoom(ctx, roomName)
 	if !errors.Is(err, routing.ErrNotFound) && err != nil {
@@ -141,7 +207,7 @@ func (r *StandardRoomAllocator) SelectRoomNode(ctx context.Context, roomName liv
 	// if already assigned and still available, keep it on that node
 	if err == nil && selector.IsAvailable(existing) {
 		// if node hosting the room is full, deny entry
-		if selector.LimitsReached(r.config.Limit, existing.Stats) {
+		if selector.LimitsReached(r.config.Reloadable().Limit, existing.Stats) {
 			return routing.ErrNodeLimitReached
 		}
 
@@ -155,6 +221,11 @@ func (r *StandardRoomAllocator) SelectRoomNode(ctx context.Context, roomName liv
 			return err
 		}
 
+		nodes, err = r.filterByPlacement(nodes, configName)
+		if err != nil {
+			return err
+		}
+
 		node, err := r.selector.SelectNode(nodes)
 		if err != nil {
 			return err
@@ -172,9 +243,31 @@ func (r *StandardRoomAllocator) SelectRoomNode(ctx context.Context, roomName liv
 	return nil
 }
 
+func (r *StandardRoomAllocator) filterByPlacement(nodes []*livekit.Node, configName string) ([]*livekit.Node, error) {
+	constraints := r.config.Reloadable().Room.PlacementConstraints[configName]
+	if len(constraints) == 0 {
+		return nodes, nil
+	}
+
+	lp, ok := r.router.(routing.NodeLabelProvider)
+	if !ok {
+		return nil, selector.ErrNoMatchingNodes
+	}
+	labels, err := lp.ListNodeLabels()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes = selector.FilterByLabels(nodes, labels, constraints)
+	if len(nodes) == 0 {
+		return nil, selector.ErrNoMatchingNodes
+	}
+	return nodes, nil
+}
+
 func (r *StandardRoomAllocator) ValidateCreateRoom(ctx context.Context, roomName livekit.RoomName) error {
 	// when auto create is disabled, we'll check to ensure it's already created
-	if !r.config.Room.AutoCreate {
+	if !r.config.Reloadable().Room.AutoCreate {
 		_, _, err := r.roomStore.LoadRoom(ctx, roomName, false)
 		if err != nil {
 			return err
@@ -206,7 +299,7 @@ func (r *StandardRoomAllocator) applyNamedRoomConfiguration(req *livekit.CreateR
 		return req, nil
 	}
 
-	conf, ok := r.config.Room.RoomConfigurations[req.ConfigName]
+	conf, ok := r.config.Reloadable().Room.RoomConfigurations[req.ConfigName]
 	if !ok {
 		return req, psrpc.NewErrorf(psrpc.InvalidArgument, "unknown room confguration in create room request")
 	}
@@ -21,6 +21,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/psrpc"
 
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
@@ -45,6 +46,83 @@ func TestCreateRoom(t *testing.T) {
 		require.Equal(t, conf.Room.DepartureTimeout, room.DepartureTimeout)
 		require.NotEmpty(t, room.EnabledCodecs)
 	})
+
+	t.Run("room config overrides are applied", func(t *testing.T) {
+		conf, err := config.NewConfig(`room:
+  room_configurations:
+    townhall:
+      name: townhall
+  config_overrides:
+    townhall:
+      min_playout_delay: 500
+      enabled_codecs:
+        - mime: audio/opus`, true, nil, nil)
+		require.NoError(t, err)
+
+		// overrides given to CreateRoom take precedence over the room configuration
+		store := service.NewLocalStore()
+		ra, err := service.NewRoomAllocator(conf, &routingfakes.FakeRouter{}, store, nil)
+		require.NoError(t, err)
+		ctx, err := service.WithRoomConfigOverrides(context.Background(), &config.RoomConfigOverrides{
+			EnabledCodecs: []config.CodecSpec{{Mime: "video/vp8"}},
+		})
+		require.NoError(t, err)
+
+		room, internal, _, err := ra.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: "myroom", ConfigName: "townhall"}, true)
+		require.NoError(t, err)
+		require.Len(t, room.EnabledCodecs, 1)
+		require.Equal(t, "video/vp8", room.EnabledCodecs[0].Mime)
+		require.True(t, internal.PlayoutDelay.Enabled)
+		require.EqualValues(t, 500, internal.PlayoutDelay.Min)
+		require.GreaterOrEqual(t, internal.PlayoutDelay.Max, internal.PlayoutDelay.Min)
+
+		// stored with the room once it is created
+		stored, err := store.LoadRoomConfigOverrides(context.Background(), "myroom")
+		require.NoError(t, err)
+		require.Equal(t, "video/vp8", stored.EnabledCodecs[0].Mime)
+	})
+
+	t.Run("room config overrides only apply to new rooms", func(t *testing.T) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		store := service.NewLocalStore()
+		ra, err := service.NewRoomAllocator(conf, &routingfakes.FakeRouter{}, store, nil)
+		require.NoError(t, err)
+
+		_, _, _, err = ra.CreateRoom(context.Background(), &livekit.CreateRoomRequest{Name: "myroom"}, true)
+		require.NoError(t, err)
+
+		ctx, err := service.WithRoomConfigOverrides(context.Background(), &config.RoomConfigOverrides{MinPlayoutDelay: 200})
+		require.NoError(t, err)
+		_, _, _, err = ra.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: "myroom"}, true)
+		require.ErrorIs(t, err, psrpc.AlreadyExists)
+
+		stored, err := store.LoadRoomConfigOverrides(context.Background(), "myroom")
+		require.NoError(t, err)
+		require.Nil(t, stored)
+	})
+
+	t.Run("room config overrides are clamped to server limits", func(t *testing.T) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		conf.Limit.MaxSubscribersPerTrack = 100
+		store := service.NewLocalStore()
+		ra, err := service.NewRoomAllocator(conf, &routingfakes.FakeRouter{}, store, nil)
+		require.NoError(t, err)
+
+		ctx, err := service.WithRoomConfigOverrides(context.Background(), &config.RoomConfigOverrides{
+			MaxSubscribersPerTrack:  1000,
+			MaxParticipantDataBytes: 1000,
+		})
+		require.NoError(t, err)
+		_, _, _, err = ra.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: "myroom"}, true)
+		require.NoError(t, err)
+
+		stored, err := store.LoadRoomConfigOverrides(context.Background(), "myroom")
+		require.NoError(t, err)
+		require.EqualValues(t, 100, stored.MaxSubscribersPerTrack)
+		require.EqualValues(t, 1000, stored.MaxParticipantDataBytes)
+	})
 }
 
 func SelectRoomNode(t *testing.T) {
@@ -60,7 +138,7 @@ func SelectRoomNode(t *testing.T) {
 
 		ra, _ := newTestRoomAllocator(t, conf, node)
 
-		err = ra.SelectRoomNode(context.Background(), "low-limit-room", "")
+		err = ra.SelectRoomNode(context.Background(), "low-limit-room", "", "")
 		require.ErrorIs(t, err, routing.ErrNodeLimitReached)
 	})
 
@@ -76,7 +154,7 @@ func SelectRoomNode(t *testing.T) {
 
 		ra, _ := newTestRoomAllocator(t, conf, node)
 
-		err = ra.SelectRoomNode(context.Background(), "low-limit-room", "")
+		err = ra.SelectRoomNode(context.Background(), "low-limit-room", "", "")
 		require.ErrorIs(t, err, routing.ErrNodeLimitReached)
 	})
 }
@@ -88,7 +166,7 @@ func newTestRoomAllocator(t *testing.T, conf *config.Config, node *livekit.Node)
 
 	router.GetNodeForRoomReturns(node, nil)
 
-	ra, err := service.NewRoomAllocator(conf, router, store)
+	ra, err := service.NewRoomAllocator(conf, router, store, nil)
 	require.NoError(t, err)
 	return ra, conf
 }
@@ -0,0 +1,303 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	roomAPIServiceName    = "RoomAPI"
+	roomAPIRoomRPC        = "Room"
+	roomAPIParticipantRPC = "Participant"
+)
+
+// roomAPIRequest is a call to a room API method, the body is the JSON encoded request of the method
+type roomAPIRequest struct {
+	Method string          `json:"method"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// RoomAPIHandlerFunc handles a room API call on the node hosting the room. For calls to a participant,
+// participant is the local participant the call is for.
+type RoomAPIHandlerFunc func(ctx context.Context, room *rtc.Room, participant types.LocalParticipant, body json.RawMessage) (any, error)
+
+type roomAPIParticipantKey struct {
+	roomName livekit.RoomName
+	identity livekit.ParticipantIdentity
+}
+
+type roomAPIParticipant struct {
+	room        *rtc.Room
+	participant types.LocalParticipant
+}
+
+// RoomAPI routes the HTTP room APIs to the node hosting the room, or the node the participant is connected to.
+// Protocol has no RPCs for these APIs, so calls are carried as JSON over a psrpc service, like relay state.
+type RoomAPI struct {
+	client *client.RPCClient
+	server *server.RPCServer
+
+	// serializes registration of topics
+	registerLock sync.Mutex
+
+	lock         sync.RWMutex
+	handlers     map[string]RoomAPIHandlerFunc
+	rooms        map[livekit.RoomName]*rtc.Room
+	participants map[roomAPIParticipantKey]roomAPIParticipant
+}
+
+func NewRoomAPI(bus psrpc.MessageBus) (*RoomAPI, error) {
+	a := &RoomAPI{
+		handlers:     make(map[string]RoomAPIHandlerFunc),
+		rooms:        make(map[livekit.RoomName]*rtc.Room),
+		participants: make(map[roomAPIParticipantKey]roomAPIParticipant),
+	}
+
+	sd := &info.ServiceDefinition{
+		Name: roomAPIServiceName,
+		ID:   rand.NewClientID(),
+	}
+	sd.RegisterMethod(roomAPIRoomRPC, false, false, true, true)
+	sd.RegisterMethod(roomAPIParticipantRPC, false, false, true, true)
+	var err error
+	if a.client, err = client.NewRPCClient(sd, bus); err != nil {
+		return nil, err
+	}
+
+	sd = &info.ServiceDefinition{
+		Name: roomAPIServiceName,
+		ID:   rand.NewServerID(),
+	}
+	sd.RegisterMethod(roomAPIRoomRPC, false, false, true, true)
+	sd.RegisterMethod(roomAPIParticipantRPC, false, false, true, true)
+	a.server = server.NewRPCServer(sd, bus)
+
+	return a, nil
+}
+
+func (a *RoomAPI) Stop() {
+	a.server.Close(false)
+	a.client.Close()
+}
+
+// Handle registers the handler of a method, on every node
+func (a *RoomAPI) Handle(method string, h RoomAPIHandlerFunc) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.handlers[method] = h
+}
+
+// AddRoom accepts calls for a room hosted on this node, until the returned function is called
+func (a *RoomAPI) AddRoom(room *rtc.Room) (func(), error) {
+	roomName := room.Name()
+	topic := []string{string(roomName)}
+
+	a.registerLock.Lock()
+	defer a.registerLock.Unlock()
+
+	a.lock.Lock()
+	_, registered := a.rooms[roomName]
+	a.rooms[roomName] = room
+	a.lock.Unlock()
+
+	if !registered {
+		if err := server.RegisterHandler(a.server, roomAPIRoomRPC, topic, func(ctx context.Context, req *wrapperspb.BytesValue) (*wrapperspb.BytesValue, error) {
+			a.lock.RLock()
+			room := a.rooms[roomName]
+			a.lock.RUnlock()
+			if room == nil {
+				return nil, ErrRoomNotFound
+			}
+			return a.handle(ctx, room, nil, req)
+		}, nil); err != nil {
+			a.removeRoom(room)
+			return nil, err
+		}
+	}
+
+	return func() {
+		a.registerLock.Lock()
+		defer a.registerLock.Unlock()
+
+		if a.removeRoom(room) {
+			a.server.DeregisterHandler(roomAPIRoomRPC, topic)
+		}
+	}, nil
+}
+
+// AddParticipant accepts calls for a participant connected to this node, until the returned function is called
+func (a *RoomAPI) AddParticipant(room *rtc.Room, participant types.LocalParticipant) (func(), error) {
+	key := roomAPIParticipantKey{roomName: room.Name(), identity: participant.Identity()}
+	topic := []string{string(key.roomName), string(key.identity)}
+
+	a.registerLock.Lock()
+	defer a.registerLock.Unlock()
+
+	a.lock.Lock()
+	_, registered := a.participants[key]
+	a.participants[key] = roomAPIParticipant{room: room, participant: participant}
+	a.lock.Unlock()
+
+	if !registered {
+		if err := server.RegisterHandler(a.server, roomAPIParticipantRPC, topic, func(ctx context.Context, req *wrapperspb.BytesValue) (*wrapperspb.BytesValue, error) {
+			a.lock.RLock()
+			p, ok := a.participants[key]
+			a.lock.RUnlock()
+			if !ok {
+				return nil, ErrParticipantNotFound
+			}
+			return a.handle(ctx, p.room, p.participant, req)
+		}, nil); err != nil {
+			a.removeParticipant(key, participant)
+			return nil, err
+		}
+	}
+
+	return func() {
+		a.registerLock.Lock()
+		defer a.registerLock.Unlock()
+
+		if a.removeParticipant(key, participant) {
+			a.server.DeregisterHandler(roomAPIParticipantRPC, topic)
+		}
+	}, nil
+}
+
+// CallRoom calls a method on the node hosting the room
+func (a *RoomAPI) CallRoom(ctx context.Context, roomName livekit.RoomName, method string, req any, res any) error {
+	err := a.call(ctx, roomAPIRoomRPC, []string{string(roomName)}, method, req, res)
+	if errors.Is(err, psrpc.ErrNoResponse) {
+		return ErrRoomNotFound
+	}
+	return err
+}
+
+// CallParticipant calls a method on the node the participant is connected to
+func (a *RoomAPI) CallParticipant(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, method string, req any, res any) error {
+	err := a.call(ctx, roomAPIParticipantRPC, []string{string(roomName), string(identity)}, method, req, res)
+	if errors.Is(err, psrpc.ErrNoResponse) {
+		return ErrParticipantNotFound
+	}
+	return err
+}
+
+func (a *RoomAPI) call(ctx context.Context, rpc string, topic []string, method string, req any, res any) error {
+	body, err := marshalRoomAPI(req)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(&roomAPIRequest{Method: method, Body: body})
+	if err != nil {
+		return err
+	}
+
+	out, err := client.RequestSingle[*wrapperspb.BytesValue](ctx, a.client, rpc, topic, wrapperspb.Bytes(data))
+	if err != nil {
+		return err
+	}
+	if res == nil || len(out.GetValue()) == 0 {
+		return nil
+	}
+	if m, ok := res.(proto.Message); ok {
+		return protojson.Unmarshal(out.Value, m)
+	}
+	return json.Unmarshal(out.Value, res)
+}
+
+func (a *RoomAPI) handle(ctx context.Context, room *rtc.Room, participant types.LocalParticipant, req *wrapperspb.BytesValue) (*wrapperspb.BytesValue, error) {
+	var call roomAPIRequest
+	if err := json.Unmarshal(req.Value, &call); err != nil {
+		return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+
+	a.lock.RLock()
+	h := a.handlers[call.Method]
+	a.lock.RUnlock()
+	if h == nil {
+		return nil, psrpc.NewErrorf(psrpc.Unimplemented, "unknown room api method %s", call.Method)
+	}
+
+	res, err := h(ctx, room, participant, call.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return &wrapperspb.BytesValue{}, nil
+	}
+	data, err := marshalRoomAPI(res)
+	if err != nil {
+		return nil, err
+	}
+	return wrapperspb.Bytes(data), nil
+}
+
+// marshalRoomAPI encodes requests and results, protos are encoded with protojson
+func marshalRoomAPI(v any) ([]byte, error) {
+	if m, ok := v.(proto.Message); ok {
+		return protojson.Marshal(m)
+	}
+	return json.Marshal(v)
+}
+
+func (a *RoomAPI) removeRoom(room *rtc.Room) bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.rooms[room.Name()] != room {
+		return false
+	}
+	delete(a.rooms, room.Name())
+	return true
+}
+
+func (a *RoomAPI) removeParticipant(key roomAPIParticipantKey, participant types.LocalParticipant) bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.participants[key].participant != participant {
+		return false
+	}
+	delete(a.participants, key)
+	return true
+}
+
+// roomAPIStatus returns the HTTP status of an error returned by a room API call
+func roomAPIStatus(err error) int {
+	var psrpcErr psrpc.Error
+	if errors.As(err, &psrpcErr) {
+		return psrpcErr.ToHttp()
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func TestRoomAPI(t *testing.T) {
+	bus := psrpc.NewLocalMessageBus()
+	hosting, err := service.NewRoomAPI(bus)
+	require.NoError(t, err)
+	defer hosting.Stop()
+	caller, err := service.NewRoomAPI(bus)
+	require.NoError(t, err)
+	defer caller.Stop()
+
+	type echo struct {
+		Room     string `json:"room"`
+		Identity string `json:"identity"`
+		Value    string `json:"value"`
+	}
+	handler := func(_ context.Context, room *rtc.Room, p types.LocalParticipant, body json.RawMessage) (any, error) {
+		var req echo
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, err
+		}
+		req.Room = string(room.Name())
+		if p != nil {
+			req.Identity = string(p.Identity())
+		}
+		return &req, nil
+	}
+	hosting.Handle("Echo", handler)
+	caller.Handle("Echo", handler)
+
+	room := rtc.NewRoom(
+		&livekit.Room{Name: "room", Sid: "RM_room"}, nil, rtc.WebRTCConfig{}, config.RoomConfig{}, &config.AudioConfig{},
+		config.AgentsConfig{}, &livekit.ServerInfo{}, &telemetryfakes.FakeTelemetryService{}, nil, nil, nil,
+	)
+	defer room.Close(types.ParticipantCloseReasonNone)
+	p := &typesfakes.FakeLocalParticipant{}
+	p.IdentityReturns("alice")
+
+	// a call finds no node hosting the room after the psrpc affinity timeout
+	ctx := context.Background()
+
+	var res echo
+	require.ErrorIs(t, caller.CallRoom(ctx, "room", "Echo", &echo{}, &res), service.ErrRoomNotFound)
+	require.ErrorIs(t, caller.CallParticipant(ctx, "room", "alice", "Echo", &echo{}, &res), service.ErrParticipantNotFound)
+
+	killRoom, err := hosting.AddRoom(room)
+	require.NoError(t, err)
+	killParticipant, err := hosting.AddParticipant(room, p)
+	require.NoError(t, err)
+
+	require.NoError(t, caller.CallRoom(ctx, "room", "Echo", &echo{Value: "v"}, &res))
+	require.Equal(t, echo{Room: "room", Value: "v"}, res)
+
+	res = echo{}
+	require.NoError(t, caller.CallParticipant(ctx, "room", "alice", "Echo", &echo{Value: "v"}, &res))
+	require.Equal(t, echo{Room: "room", Identity: "alice", Value: "v"}, res)
+
+	var psrpcErr psrpc.Error
+	err = caller.CallRoom(ctx, "room", "Unknown", &echo{}, nil)
+	require.ErrorAs(t, err, &psrpcErr)
+	require.Equal(t, http.StatusNotImplemented, psrpcErr.ToHttp())
+
+	// a participant reconnecting replaces the previous registration, which then must not remove it
+	p2 := &typesfakes.FakeLocalParticipant{}
+	p2.IdentityReturns("alice")
+	killParticipant2, err := hosting.AddParticipant(room, p2)
+	require.NoError(t, err)
+	killParticipant()
+	require.NoError(t, caller.CallParticipant(ctx, "room", "alice", "Echo", &echo{}, &res))
+	killParticipant2()
+	require.ErrorIs(t, caller.CallParticipant(ctx, "room", "alice", "Echo", &echo{}, &res), service.ErrParticipantNotFound)
+
+	killRoom()
+	require.ErrorIs(t, caller.CallRoom(ctx, "room", "Echo", &echo{}, &res), service.ErrRoomNotFound)
+}
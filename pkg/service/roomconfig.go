@@ -0,0 +1,157 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/psrpc/pkg/metadata"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// roomConfigHeader carries config overrides of CreateRoom requests, as a JSON object with the keys of
+// room.config_overrides, e.g. {"enabled_codecs": [{"mime": "video/vp8"}], "twcc": false}.
+// CreateRoomRequest has no field for them, the header is used until the protocol carries them
+const roomConfigHeader = "X-LiveKit-Room-Config"
+
+// roomConfigOverridesMetadataKey carries the overrides of CreateRoom to the node creating the room
+const roomConfigOverridesMetadataKey = "room_config_overrides"
+
+type roomConfigHeaderKey struct{}
+
+type roomConfigOverridesKey struct{}
+
+// RoomConfigOverridesStore keeps config overrides given to CreateRoom, from the creation of the room until it is deleted
+type RoomConfigOverridesStore interface {
+	StoreRoomConfigOverrides(ctx context.Context, roomName livekit.RoomName, overrides *config.RoomConfigOverrides) error
+	// returns nil when the room has no overrides
+	LoadRoomConfigOverrides(ctx context.Context, roomName livekit.RoomName) (*config.RoomConfigOverrides, error)
+}
+
+// withRoomConfigHeader makes the room config header available to twirp handlers
+func withRoomConfigHeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v := r.Header.Get(roomConfigHeader); v != "" {
+			r = r.WithContext(context.WithValue(r.Context(), roomConfigHeaderKey{}, v))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// roomConfigOverridesFromContext returns the validated overrides of the request, or nil when there are none
+func roomConfigOverridesFromContext(ctx context.Context) (*config.RoomConfigOverrides, error) {
+	v, _ := ctx.Value(roomConfigHeaderKey{}).(string)
+	if v == "" {
+		return nil, nil
+	}
+	return decodeRoomConfigOverrides(v)
+}
+
+func decodeRoomConfigOverrides(v string) (*config.RoomConfigOverrides, error) {
+	// JSON is decoded as yaml to share the keys of the config file
+	var overrides config.RoomConfigOverrides
+	decoder := yaml.NewDecoder(strings.NewReader(v))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&overrides); err != nil {
+		return nil, err
+	}
+	if err := overrides.Validate(); err != nil {
+		return nil, err
+	}
+	return &overrides, nil
+}
+
+// WithRoomConfigOverrides attaches overrides given to CreateRoom to the context. They are applied and stored with
+// the room only when it is created, including when the room is created on another node
+func WithRoomConfigOverrides(ctx context.Context, overrides *config.RoomConfigOverrides) (context.Context, error) {
+	data, err := yaml.Marshal(overrides)
+	if err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, roomConfigOverridesKey{}, overrides)
+	return metadata.AppendMetadataToOutgoingContext(ctx, roomConfigOverridesMetadataKey, string(data)), nil
+}
+
+// requestedRoomConfigOverrides returns the overrides attached to the context, or sent by the node handling CreateRoom
+func requestedRoomConfigOverrides(ctx context.Context) (*config.RoomConfigOverrides, error) {
+	if overrides, ok := ctx.Value(roomConfigOverridesKey{}).(*config.RoomConfigOverrides); ok {
+		return overrides, nil
+	}
+	if head := metadata.IncomingHeader(ctx); head != nil {
+		if v := head.Metadata[roomConfigOverridesMetadataKey]; v != "" {
+			return decodeRoomConfigOverrides(v)
+		}
+	}
+	return nil, nil
+}
+
+// loadRoomConfigOverrides returns the overrides of the room configuration, with the overrides given to CreateRoom
+// applied, or nil when the room has neither
+func loadRoomConfigOverrides(
+	ctx context.Context,
+	store ServiceStore,
+	conf *config.RoomConfig,
+	roomName livekit.RoomName,
+	configName string,
+) (*config.RoomConfigOverrides, error) {
+	preset, hasPreset := conf.ConfigOverrides[configName]
+
+	var stored *config.RoomConfigOverrides
+	if overridesStore, ok := store.(RoomConfigOverridesStore); ok {
+		var err error
+		if stored, err = overridesStore.LoadRoomConfigOverrides(ctx, roomName); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case stored != nil:
+		merged := preset.Merge(*stored)
+		return &merged, nil
+	case hasPreset:
+		return &preset, nil
+	default:
+		return nil, nil
+	}
+}
+
+// applyRoomConfigOverrides applies the overrides stored with the room
+func applyRoomConfigOverrides(room *livekit.Room, internal *livekit.RoomInternal, overrides *config.RoomConfigOverrides) {
+	if overrides == nil {
+		return
+	}
+	if len(overrides.EnabledCodecs) != 0 {
+		room.EnabledCodecs = make([]*livekit.Codec, 0, len(overrides.EnabledCodecs))
+		for _, codec := range overrides.EnabledCodecs {
+			room.EnabledCodecs = append(room.EnabledCodecs, &livekit.Codec{
+				Mime:     codec.Mime,
+				FmtpLine: codec.FmtpLine,
+			})
+		}
+	}
+	if overrides.MinPlayoutDelay != 0 {
+		internal.PlayoutDelay = &livekit.PlayoutDelay{
+			Enabled: true,
+			Min:     overrides.MinPlayoutDelay,
+			Max:     max(internal.GetPlayoutDelay().GetMax(), overrides.MinPlayoutDelay),
+		}
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+)
+
+const (
+	RoomHookAllow  = "allow"
+	RoomHookReject = "reject"
+
+	defaultRoomHookTimeout = 2 * time.Second
+)
+
+// RoomHooks are called before rooms are created, participants join and tracks are published.
+// They may change the request in place, or reject the operation with an error created by NewRoomHookRejectedError
+type RoomHooks interface {
+	BeforeRoomCreate(ctx context.Context, req *livekit.CreateRoomRequest) error
+	BeforeParticipantJoin(ctx context.Context, roomName livekit.RoomName, pi *routing.ParticipantInit) error
+	BeforeTrackPublish(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, req *livekit.AddTrackRequest) error
+}
+
+// NewRoomHookRejectedError is returned when a hook rejects an operation, it is reported to clients as permission denied
+func NewRoomHookRejectedError(hook string, reason string) error {
+	return psrpc.NewErrorf(psrpc.PermissionDenied, "rejected by %s hook: %s", hook, reason)
+}
+
+// RoomHookRequest is posted to the hook endpoint, with the field of the hook set
+type RoomHookRequest struct {
+	Hook        string               `json:"hook"`
+	Room        string               `json:"room"`
+	CreateRoom  json.RawMessage      `json:"create_room,omitempty"`
+	Participant *RoomHookParticipant `json:"participant,omitempty"`
+	Track       json.RawMessage      `json:"track,omitempty"`
+}
+
+// RoomHookResponse is returned by the hook endpoint. Set fields replace those of the request, create_room and track
+// are livekit.CreateRoomRequest and livekit.AddTrackRequest in protobuf JSON
+type RoomHookResponse struct {
+	Action      string               `json:"action"`
+	Reason      string               `json:"reason,omitempty"`
+	CreateRoom  json.RawMessage      `json:"create_room,omitempty"`
+	Participant *RoomHookParticipant `json:"participant,omitempty"`
+	Track       json.RawMessage      `json:"track,omitempty"`
+}
+
+// RoomHookParticipant is the joining participant, the identity cannot be changed
+type RoomHookParticipant struct {
+	Identity   string            `json:"identity"`
+	Name       string            `json:"name,omitempty"`
+	Metadata   string            `json:"metadata,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// RoomHooksClient calls the room hooks endpoint. Requests are signed the same way as webhooks,
+// so receivers can verify them with webhook.Receive.
+type RoomHooksClient struct {
+	conf      config.RoomHooksConfig
+	apiKey    string
+	apiSecret string
+	client    *http.Client
+}
+
+// NewRoomHooksClient returns nil when room hooks are not configured
+func NewRoomHooksClient(conf *config.Config, provider auth.KeyProvider) (*RoomHooksClient, error) {
+	if conf.RoomHooks.URL == "" {
+		return nil, nil
+	}
+	secret := provider.GetSecret(conf.WebHook.APIKey)
+	if secret == "" {
+		return nil, ErrRoomHooksMissingAPIKey
+	}
+
+	timeout := conf.RoomHooks.Timeout
+	if timeout <= 0 {
+		timeout = defaultRoomHookTimeout
+	}
+	return &RoomHooksClient{
+		conf:      conf.RoomHooks,
+		apiKey:    conf.WebHook.APIKey,
+		apiSecret: secret,
+		client:    &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (c *RoomHooksClient) BeforeRoomCreate(ctx context.Context, req *livekit.CreateRoomRequest) error {
+	createRoom, err := protojson.Marshal(req)
+	if err != nil {
+		return err
+	}
+	res, err := c.call(ctx, &RoomHookRequest{
+		Hook:       config.RoomHookRoomCreate,
+		Room:       req.Name,
+		CreateRoom: createRoom,
+	})
+	if err != nil || res == nil || len(res.CreateRoom) == 0 {
+		return err
+	}
+
+	updated := &livekit.CreateRoomRequest{}
+	if err = protojson.Unmarshal(res.CreateRoom, updated); err != nil {
+		return c.failed(config.RoomHookRoomCreate, fmt.Errorf("invalid create_room: %w", err))
+	}
+	// the room cannot be renamed
+	updated.Name = req.Name
+	proto.Reset(req)
+	proto.Merge(req, updated)
+	return nil
+}
+
+func (c *RoomHooksClient) BeforeParticipantJoin(ctx context.Context, roomName livekit.RoomName, pi *routing.ParticipantInit) error {
+	participant := &RoomHookParticipant{
+		Identity: string(pi.Identity),
+		Name:     string(pi.Name),
+	}
+	if pi.Grants != nil {
+		participant.Metadata = pi.Grants.Metadata
+		participant.Attributes = pi.Grants.Attributes
+	}
+	res, err := c.call(ctx, &RoomHookRequest{
+		Hook:        config.RoomHookParticipantJoin,
+		Room:        string(roomName),
+		Participant: participant,
+	})
+	if err != nil || res == nil || res.Participant == nil {
+		return err
+	}
+
+	pi.Name = livekit.ParticipantName(res.Participant.Name)
+	if pi.Grants != nil {
+		pi.Grants.Name = res.Participant.Name
+		pi.Grants.Metadata = res.Participant.Metadata
+		pi.Grants.Attributes = res.Participant.Attributes
+	}
+	return nil
+}
+
+func (c *RoomHooksClient) BeforeTrackPublish(
+	ctx context.Context,
+	roomName livekit.RoomName,
+	identity livekit.ParticipantIdentity,
+	req *livekit.AddTrackRequest,
+) error {
+	track, err := protojson.Marshal(req)
+	if err != nil {
+		return err
+	}
+	res, err := c.call(ctx, &RoomHookRequest{
+		Hook:        config.RoomHookTrackPublish,
+		Room:        string(roomName),
+		Participant: &RoomHookParticipant{Identity: string(identity)},
+		Track:       track,
+	})
+	if err != nil || res == nil || len(res.Track) == 0 {
+		return err
+	}
+
+	updated := &livekit.AddTrackRequest{}
+	if err = protojson.Unmarshal(res.Track, updated); err != nil {
+		return c.failed(config.RoomHookTrackPublish, fmt.Errorf("invalid track: %w", err))
+	}
+	// the client matches the published track by cid, and negotiates the track type
+	updated.Cid, updated.Sid, updated.Type = req.Cid, req.Sid, req.Type
+	proto.Reset(req)
+	proto.Merge(req, updated)
+	return nil
+}
+
+// call returns a nil response when the hook is not enabled, or the endpoint failed and the operation is let through
+func (c *RoomHooksClient) call(ctx context.Context, req *RoomHookRequest) (*RoomHookResponse, error) {
+	if len(c.conf.Hooks) != 0 && !slices.Contains(c.conf.Hooks, req.Hook) {
+		return nil, nil
+	}
+
+	res, err := c.post(ctx, req)
+	if err != nil {
+		return nil, c.failed(req.Hook, err)
+	}
+	switch res.Action {
+	case RoomHookAllow:
+		return res, nil
+	case RoomHookReject:
+		return nil, NewRoomHookRejectedError(req.Hook, res.Reason)
+	default:
+		return nil, c.failed(req.Hook, fmt.Errorf("invalid room hook action %q", res.Action))
+	}
+}
+
+func (c *RoomHooksClient) failed(hook string, err error) error {
+	logger.Warnw("room hook failed", err, "hook", hook, "failClosed", c.conf.FailClosed)
+	if c.conf.FailClosed {
+		return NewRoomHookRejectedError(hook, "hook endpoint unavailable")
+	}
+	return nil
+}
+
+func (c *RoomHooksClient) post(ctx context.Context, req *RoomHookRequest) (*RoomHookResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(body)
+	token, err := auth.NewAccessToken(c.apiKey, c.apiSecret).
+		SetValidFor(5 * time.Minute).
+		SetSha256(base64.StdEncoding.EncodeToString(sum[:])).
+		ToJWT()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, c.conf.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Authorization", token)
+	r.Header.Set("Content-Type", "application/webhook+json")
+
+	resp, err := c.client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("room hook endpoint returned %d", resp.StatusCode)
+	}
+
+	res := &RoomHookResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return nil, fmt.Errorf("invalid room hook response: %w", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestRoomHooks(t *testing.T) {
+	provider := auth.NewSimpleKeyProvider("key", "secret")
+
+	var hooks []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := webhook.Receive(r, provider)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		req := &service.RoomHookRequest{}
+		require.NoError(t, json.Unmarshal(data, req))
+		hooks = append(hooks, req.Hook)
+
+		res := &service.RoomHookResponse{Action: service.RoomHookAllow}
+		switch req.Room {
+		case "rejected":
+			res.Action = service.RoomHookReject
+			res.Reason = "closed"
+		case "unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		case "mutated":
+			switch req.Hook {
+			case config.RoomHookRoomCreate:
+				res.CreateRoom = json.RawMessage(`{"name":"renamed","maxParticipants":2}`)
+			case config.RoomHookParticipantJoin:
+				res.Participant = &service.RoomHookParticipant{
+					Identity:   "renamed",
+					Name:       "Guest",
+					Attributes: map[string]string{"role": "guest"},
+				}
+			case config.RoomHookTrackPublish:
+				res.Track = json.RawMessage(`{"cid":"other","name":"screen","source":"SCREEN_SHARE"}`)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+	defer server.Close()
+
+	newHooks := func(t *testing.T, roomHooks config.RoomHooksConfig) *service.RoomHooksClient {
+		roomHooks.URL = server.URL
+		hooks = nil
+		client, err := service.NewRoomHooksClient(&config.Config{
+			WebHook:   config.WebHookConfig{APIKey: "key"},
+			RoomHooks: roomHooks,
+		}, provider)
+		require.NoError(t, err)
+		return client
+	}
+	requireRejected := func(t *testing.T, err error) {
+		require.Error(t, err)
+		require.True(t, errors.Is(err, psrpc.PermissionDenied))
+	}
+
+	t.Run("requires api key", func(t *testing.T) {
+		_, err := service.NewRoomHooksClient(&config.Config{RoomHooks: config.RoomHooksConfig{URL: server.URL}}, provider)
+		require.ErrorIs(t, err, service.ErrRoomHooksMissingAPIKey)
+	})
+
+	t.Run("allows and mutates", func(t *testing.T) {
+		client := newHooks(t, config.RoomHooksConfig{})
+		ctx := context.Background()
+
+		createRoom := &livekit.CreateRoomRequest{Name: "mutated", Metadata: "meta"}
+		require.NoError(t, client.BeforeRoomCreate(ctx, createRoom))
+		require.Equal(t, "mutated", createRoom.Name)
+		require.Equal(t, uint32(2), createRoom.MaxParticipants)
+		require.Empty(t, createRoom.Metadata)
+
+		pi := &routing.ParticipantInit{
+			Identity: "user",
+			Name:     "User",
+			Grants:   &auth.ClaimGrants{Identity: "user", Name: "User", Metadata: "meta"},
+		}
+		require.NoError(t, client.BeforeParticipantJoin(ctx, "mutated", pi))
+		require.Equal(t, livekit.ParticipantIdentity("user"), pi.Identity)
+		require.Equal(t, livekit.ParticipantName("Guest"), pi.Name)
+		require.Equal(t, "Guest", pi.Grants.Name)
+		require.Empty(t, pi.Grants.Metadata)
+		require.Equal(t, map[string]string{"role": "guest"}, pi.Grants.Attributes)
+
+		track := &livekit.AddTrackRequest{Cid: "cid", Name: "camera", Type: livekit.TrackType_VIDEO}
+		require.NoError(t, client.BeforeTrackPublish(ctx, "mutated", "user", track))
+		require.Equal(t, "cid", track.Cid)
+		require.Equal(t, livekit.TrackType_VIDEO, track.Type)
+		require.Equal(t, "screen", track.Name)
+		require.Equal(t, livekit.TrackSource_SCREEN_SHARE, track.Source)
+
+		require.NoError(t, client.BeforeRoomCreate(ctx, &livekit.CreateRoomRequest{Name: "room"}))
+		require.Equal(t, []string{
+			config.RoomHookRoomCreate,
+			config.RoomHookParticipantJoin,
+			config.RoomHookTrackPublish,
+			config.RoomHookRoomCreate,
+		}, hooks)
+	})
+
+	t.Run("rejects", func(t *testing.T) {
+		client := newHooks(t, config.RoomHooksConfig{})
+		err := client.BeforeRoomCreate(context.Background(), &livekit.CreateRoomRequest{Name: "rejected"})
+		requireRejected(t, err)
+		require.Contains(t, err.Error(), "closed")
+	})
+
+	t.Run("only enabled hooks", func(t *testing.T) {
+		client := newHooks(t, config.RoomHooksConfig{Hooks: []string{config.RoomHookParticipantJoin}})
+		require.NoError(t, client.BeforeRoomCreate(context.Background(), &livekit.CreateRoomRequest{Name: "rejected"}))
+		requireRejected(t, client.BeforeParticipantJoin(context.Background(), "rejected", &routing.ParticipantInit{Identity: "user"}))
+		require.Equal(t, []string{config.RoomHookParticipantJoin}, hooks)
+	})
+
+	t.Run("fail open", func(t *testing.T) {
+		client := newHooks(t, config.RoomHooksConfig{})
+		require.NoError(t, client.BeforeRoomCreate(context.Background(), &livekit.CreateRoomRequest{Name: "unavailable"}))
+	})
+
+	t.Run("fail closed", func(t *testing.T) {
+		client := newHooks(t, config.RoomHooksConfig{FailClosed: true})
+		requireRejected(t, client.BeforeRoomCreate(context.Background(), &livekit.CreateRoomRequest{Name: "unavailable"}))
+	})
+}
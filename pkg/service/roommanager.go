@@ -21,11 +21,17 @@ import (
 	"sync"
 	"time"
 
+	"github.com/pion/interceptor"
+	"github.com/pion/webrtc/v3"
 	"github.com/pkg/errors"
+	"go.uber.org/atomic"
 	"golang.org/x/exp/maps"
 
 	"github.com/livekit/livekit-server/pkg/agent"
+	"github.com/livekit/livekit-server/pkg/databridge"
+	"github.com/livekit/livekit-server/pkg/featureflags"
 	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/clocksource"
 	sutils "github.com/livekit/livekit-server/pkg/utils"
 	"github.com/livekit/mediatransportutil/pkg/rtcconfig"
 	"github.com/livekit/protocol/auth"
@@ -65,6 +71,8 @@ type iceConfigCacheKey struct {
 // It's responsible for creating, deleting rooms, as well as running sessions for participants
 type RoomManager struct {
 	lock sync.RWMutex
+	// clients are sent redirect regions only while the node drains or shuts down
+	redirecting atomic.Bool
 
 	config            *config.Config
 	rtcConfig         *rtc.WebRTCConfig
@@ -81,6 +89,7 @@ type RoomManager struct {
 	egressLauncher    rtc.EgressLauncher
 	versionGenerator  utils.TimedVersionGenerator
 	turnAuthHandler   *TURNAuthHandler
+	turnHealth        *TURNHealthChecker
 	bus               psrpc.MessageBus
 
 	rooms map[livekit.RoomName]*rtc.Room
@@ -92,6 +101,28 @@ type RoomManager struct {
 	iceConfigCache *sutils.IceConfigCache[iceConfigCacheKey]
 
 	forwardStats *sfu.ForwardStats
+	// wall clock of sender reports generated for subscribers
+	senderReportClock clocksource.ClockSource
+
+	// shares rooms with other nodes when media relay is enabled
+	roomRelay *RoomRelay
+	// routes the HTTP room APIs to the node hosting the room or participant
+	roomAPI *RoomAPI
+
+	// mirrors data topics of rooms to external brokers
+	dataBridges *databridge.Manager
+
+	// moderates user data messages before they are sent to rooms
+	dataModerator rtc.DataModerator
+	// reports data messages flagged by the moderator
+	dataFlagNotifier rtc.DataFlagNotifier
+	// observes and modifies media and data of rooms, loaded from plugins
+	mediaInterceptor rtc.MediaInterceptor
+	// recompresses data topics for participants accepting different encodings
+	dataCompressor *rtc.DataCompressor
+
+	// gates experimental behaviors per node, per room, or by percentage rollout
+	featureFlags *featureflags.Manager
 }
 
 func NewLocalRoomManager(
@@ -109,6 +140,7 @@ func NewLocalRoomManager(
 	turnAuthHandler *TURNAuthHandler,
 	bus psrpc.MessageBus,
 	forwardStats *sfu.ForwardStats,
+	mediaInterceptor rtc.MediaInterceptor,
 ) (*RoomManager, error) {
 	rtcConf, err := rtc.NewWebRTCConfig(conf)
 	if err != nil {
@@ -129,8 +161,12 @@ func NewLocalRoomManager(
 		agentStore:        agentStore,
 		versionGenerator:  versionGenerator,
 		turnAuthHandler:   turnAuthHandler,
+		turnHealth:        NewTURNHealthChecker(conf),
 		bus:               bus,
 		forwardStats:      forwardStats,
+		mediaInterceptor:  mediaInterceptor,
+		dataBridges:       databridge.NewManager(conf.DataBridges, livekit.NodeID(currentNode.Id)),
+		featureFlags:      featureflags.NewManager(conf),
 
 		rooms: make(map[livekit.RoomName]*rtc.Room),
 
@@ -154,9 +190,81 @@ func NewLocalRoomManager(
 		return nil, err
 	}
 
+	if r.roomAPI, err = NewRoomAPI(bus); err != nil {
+		return nil, err
+	}
+
+	if r.dataModerator, err = createDataModerator(conf); err != nil {
+		return nil, err
+	}
+	if r.dataFlagNotifier, err = createDataFlagNotifier(conf); err != nil {
+		return nil, err
+	}
+	for name, overrides := range conf.Room.ConfigOverrides {
+		if err := validateRTPInterceptors(overrides.RTPInterceptors); err != nil {
+			return nil, fmt.Errorf("invalid config overrides of room configuration %s: %w", name, err)
+		}
+	}
+	if err = rtc.CheckMixedAudioCodec(conf.Room.MixedAudio); err != nil {
+		return nil, err
+	}
+	if err = rtc.CheckComposedVideoCodec(conf.Room.VideoComposition); err != nil {
+		return nil, err
+	}
+	if r.dataCompressor, err = rtc.NewDataCompressor(conf.Room.DataCompression); err != nil {
+		return nil, err
+	}
+	if r.senderReportClock, err = clocksource.New(conf.RTC.SenderReportClock, logger.GetLogger()); err != nil {
+		return nil, err
+	}
+
+	if conf.Relay.Enabled {
+		r.roomRelay, err = NewRoomRelay(conf, currentNode, router, bus, r.getRelayReceiver)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if n, ok := router.(routing.FailoverNotifier); ok {
+		n.OnFailover(r.restoreRoomState)
+	}
+
 	return r, nil
 }
 
+// restoreRoomState writes routing and store state for local rooms again,
+// since writes that were not replicated before a failover are lost
+func (r *RoomManager) restoreRoomState() {
+	ctx := context.Background()
+	nodeID := livekit.NodeID(r.currentNode.Id)
+
+	r.lock.RLock()
+	rooms := maps.Values(r.rooms)
+	r.lock.RUnlock()
+
+	for _, room := range rooms {
+		if room.IsClosed() {
+			continue
+		}
+
+		if err := r.router.SetNodeForRoom(ctx, room.Name(), nodeID); err != nil {
+			room.Logger.Errorw("could not restore room node", err)
+		}
+		if err := r.roomStore.StoreRoom(ctx, room.ToProto(), room.Internal()); err != nil {
+			room.Logger.Errorw("could not restore room", err)
+		}
+		for _, p := range room.GetParticipants() {
+			if p.IsDisconnected() {
+				continue
+			}
+			if err := r.roomStore.StoreParticipant(ctx, room.Name(), p.ToProto()); err != nil {
+				room.Logger.Errorw("could not restore participant", err, "participant", p.Identity())
+			}
+		}
+	}
+	logger.Infow("restored room state after failover", "numRooms", len(rooms))
+}
+
 func (r *RoomManager) GetRoom(_ context.Context, roomName livekit.RoomName) *rtc.Room {
 	r.lock.RLock()
 	defer r.lock.RUnlock()
@@ -192,6 +300,26 @@ func (r *RoomManager) deleteRoom(ctx context.Context, roomName livekit.RoomName)
 	return err
 }
 
+// KillRoom drops a room hosted on this node without sending participants a leave, as if the node had failed,
+// so that clients go through their resume and reconnect paths
+func (r *RoomManager) FeatureFlags() *featureflags.Manager {
+	return r.featureFlags
+}
+
+func (r *RoomManager) KillRoom(ctx context.Context, roomName livekit.RoomName) error {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return ErrRoomNotFound
+	}
+
+	room.Logger.Infow("killing room")
+	for _, p := range room.GetParticipants() {
+		_ = p.Close(false, types.ParticipantCloseReasonSimulateNodeFailure, true)
+	}
+	room.Close(types.ParticipantCloseReasonSimulateNodeFailure)
+	return nil
+}
+
 // CleanupRooms cleans up after old rooms that have been around for a while
 func (r *RoomManager) CleanupRooms() error {
 	// cleanup rooms that have been left for over a day
@@ -212,12 +340,28 @@ func (r *RoomManager) CleanupRooms() error {
 	return nil
 }
 
+// Rooms returns the rooms hosted by this node
+func (r *RoomManager) Rooms() []*rtc.Room {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return maps.Values(r.rooms)
+}
+
+// RoomAPI returns the router of the HTTP room APIs
+func (r *RoomManager) RoomAPI() *RoomAPI {
+	return r.roomAPI
+}
+
 func (r *RoomManager) CloseIdleRooms() {
 	r.lock.RLock()
 	rooms := maps.Values(r.rooms)
 	r.lock.RUnlock()
 
+	now := time.Now()
 	for _, room := range rooms {
+		room.EnforceClosePolicy(now)
+		room.DisconnectIdleParticipants(now)
 		room.CloseIfEmpty()
 	}
 }
@@ -234,7 +378,21 @@ func (r *RoomManager) HasParticipants() bool {
 	return false
 }
 
+// ParticipantCounts returns the number of active rooms and participants on this node
+func (r *RoomManager) ParticipantCounts() (rooms int, participants int) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for _, room := range r.rooms {
+		rooms++
+		participants += len(room.GetParticipants())
+	}
+	return
+}
+
 func (r *RoomManager) Stop() {
+	r.redirecting.Store(true)
+
 	// disconnect all clients
 	r.lock.RLock()
 	rooms := maps.Values(r.rooms)
@@ -244,6 +402,11 @@ func (r *RoomManager) Stop() {
 		room.Close(types.ParticipantCloseReasonRoomManagerStop)
 	}
 
+	if r.roomRelay != nil {
+		r.roomRelay.Stop()
+	}
+
+	r.roomAPI.Stop()
 	r.roomManagerServer.Kill()
 	r.roomServers.Kill()
 	r.agentDispatchServers.Kill()
@@ -259,10 +422,50 @@ func (r *RoomManager) Stop() {
 	}
 
 	r.iceConfigCache.Stop()
+	r.turnHealth.Stop()
 
 	if r.forwardStats != nil {
 		r.forwardStats.Stop()
 	}
+	if r.senderReportClock != nil {
+		r.senderReportClock.Close()
+	}
+}
+
+// RedirectParticipants asks every client connected to this node to reconnect to another node
+func (r *RoomManager) RedirectParticipants() {
+	r.redirecting.Store(true)
+
+	r.lock.RLock()
+	rooms := maps.Values(r.rooms)
+	r.lock.RUnlock()
+
+	for _, room := range rooms {
+		room.RedirectParticipants()
+	}
+}
+
+// redirectRegions returns the hosts clients asked to reconnect may use instead of this node, clients reconnecting
+// for other reasons stay in this region
+func (r *RoomManager) redirectRegions(_ string) *livekit.RegionSettings {
+	if !r.redirecting.Load() {
+		return nil
+	}
+
+	var settings *livekit.RegionSettings
+	for _, region := range r.config.Shutdown.Regions {
+		if region.Name == r.config.Region {
+			continue
+		}
+		if settings == nil {
+			settings = &livekit.RegionSettings{}
+		}
+		settings.Regions = append(settings.Regions, &livekit.RegionInfo{
+			Region: region.Name,
+			Url:    region.URL,
+		})
+	}
+	return settings
 }
 
 func (r *RoomManager) CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest) (*livekit.Room, error) {
@@ -298,6 +501,16 @@ func (r *RoomManager) StartSession(
 		return nil
 	}
 
+	if r.config.SignalRecording.ShouldRecord(string(room.Name())) {
+		if recorder, err := routing.NewSignalRecorder(r.config.SignalRecording.Dir, room.Name(), pi); err != nil {
+			logger.Warnw("could not record signal messages", err, "room", room.Name(), "participant", pi.Identity)
+		} else {
+			logger.Infow("recording signal messages", "room", room.Name(), "participant", pi.Identity, "path", recorder.Path())
+			requestSource = recorder.WrapSource(requestSource)
+			responseSink = recorder.WrapSink(responseSink)
+		}
+	}
+
 	// should not error out, error is logged in iceServersForParticipant even if it fails
 	// since this is used for TURN server credentials, we don't want to fail the request even if there's no TURN for the session
 	apiKey, _, _ := r.getFirstKeyPair()
@@ -443,23 +656,51 @@ func (r *RoomManager) StartSession(
 	if pi.SubscriberAllowPause != nil {
 		subscriberAllowPause = *pi.SubscriberAllowPause
 	}
+	reloadable := r.config.Reloadable()
+	congestionControlConfig := r.config.RTC.CongestionControl
+	if audioOnly, ok := reloadable.Room.AudioOnly[pi.CreateRoom.GetConfigName()]; ok {
+		congestionControlConfig.AudioOnly = audioOnly
+	}
+	congestionControlConfig.UseSendSideBWE = r.featureFlags.IsEnabled(featureflags.SendSideBWE, room.Name())
+	overrides := room.ConfigOverrides()
+	if overrides != nil && overrides.TWCC != nil {
+		congestionControlConfig.UseSendSideBWE = *overrides.TWCC
+	}
+	rtcConf.SetSendSideBWE(congestionControlConfig.UseSendSideBWE)
+	var publisherInterceptors, subscriberInterceptors []interceptor.Factory
+	if overrides != nil && len(overrides.RTPInterceptors) != 0 {
+		publisherInterceptors, subscriberInterceptors, err = createRTPInterceptors(r.config, overrides.RTPInterceptors, room.Name(), pi.Identity)
+		if err != nil {
+			pLogger.Errorw("could not create rtp interceptors", err)
+			return err
+		}
+	}
+	var subscribedQuality config.SubscribedQualityConfig
+	if overrides != nil {
+		subscribedQuality = overrides.SubscribedQuality
+	}
+	var simulcastOnlyCodecs []string
+	if !r.featureFlags.IsEnabled(featureflags.AV1SVC, room.Name()) {
+		simulcastOnlyCodecs = append(simulcastOnlyCodecs, webrtc.MimeTypeAV1)
+	}
 	participant, err = rtc.NewParticipant(rtc.ParticipantParams{
 		Identity:                pi.Identity,
 		Name:                    pi.Name,
 		SID:                     sid,
 		Config:                  &rtcConf,
 		Sink:                    responseSink,
-		AudioConfig:             r.config.Audio,
+		AudioConfig:             room.GetAudioConfig(),
 		VideoConfig:             r.config.Video,
-		LimitConfig:             r.config.Limit,
+		LimitConfig:             reloadable.Limit.WithOverrides(overrides),
 		ProtocolVersion:         pv,
 		SessionStartTime:        sessionStartTime,
 		Telemetry:               r.telemetry,
 		Trailer:                 room.Trailer(),
 		PLIThrottleConfig:       r.config.RTC.PLIThrottle,
-		CongestionControlConfig: r.config.RTC.CongestionControl,
+		CongestionControlConfig: congestionControlConfig,
 		PublishEnabledCodecs:    protoRoom.EnabledCodecs,
 		SubscribeEnabledCodecs:  protoRoom.EnabledCodecs,
+		SimulcastOnlyCodecs:     simulcastOnlyCodecs,
 		Grants:                  pi.Grants,
 		Logger:                  pLogger,
 		ClientConf:              clientConf,
@@ -474,18 +715,33 @@ func (r *RoomManager) StartSession(
 			}
 			return nil
 		},
-		ReconnectOnPublicationError:  reconnectOnPublicationError,
-		ReconnectOnSubscriptionError: reconnectOnSubscriptionError,
-		ReconnectOnDataChannelError:  reconnectOnDataChannelError,
-		DataChannelMaxBufferedAmount: r.config.RTC.DataChannelMaxBufferedAmount,
-		VersionGenerator:             r.versionGenerator,
-		TrackResolver:                room.ResolveMediaTrackForSubscriber,
-		SubscriberAllowPause:         subscriberAllowPause,
-		SubscriptionLimitAudio:       r.config.Limit.SubscriptionLimitAudio,
-		SubscriptionLimitVideo:       r.config.Limit.SubscriptionLimitVideo,
-		PlayoutDelay:                 roomInternal.GetPlayoutDelay(),
-		SyncStreams:                  roomInternal.GetSyncStreams(),
-		ForwardStats:                 r.forwardStats,
+		ReconnectOnPublicationError:       reconnectOnPublicationError,
+		ReconnectOnSubscriptionError:      reconnectOnSubscriptionError,
+		ReconnectOnDataChannelError:       reconnectOnDataChannelError,
+		ReconnectOnShutdown:               r.config.Shutdown.RedirectClients,
+		GetRegionSettings:                 r.redirectRegions,
+		SubscriptionStatus:                r.config.RTC.SubscriptionStatus,
+		DataChannelMaxBufferedAmount:      r.config.RTC.DataChannelMaxBufferedAmount,
+		DataChannelPriority:               r.config.RTC.DataChannelPriority,
+		NetworkImpairment:                 r.config.RTC.NetworkImpairment,
+		EnableChaos:                       r.config.Chaos.Enabled,
+		VersionGenerator:                  r.versionGenerator,
+		TrackResolver:                     room.ResolveMediaTrackForSubscriber,
+		SubscriberAllowPause:              subscriberAllowPause,
+		SubscribedQuality:                 subscribedQuality,
+		SubscriptionLimitAudio:            reloadable.Limit.SubscriptionLimitAudio,
+		SubscriptionLimitVideo:            reloadable.Limit.SubscriptionLimitVideo,
+		PlayoutDelay:                      roomInternal.GetPlayoutDelay(),
+		SyncStreams:                       roomInternal.GetSyncStreams(),
+		ForwardStats:                      r.forwardStats,
+		MediaInterceptor:                  r.mediaInterceptor,
+		RoomName:                          room.Name(),
+		PublisherInterceptors:             publisherInterceptors,
+		SubscriberInterceptors:            subscriberInterceptors,
+		SenderReportSmoothingWindow:       r.config.RTC.SenderReportSmoothingWindow,
+		SenderReportClockSkewCompensation: r.config.RTC.SenderReportClockSkewCompensation,
+		SenderReportClock:                 r.senderReportClock,
+		AVSyncCorrection:                  reloadable.Room.WithOverrides(overrides).AVSyncCorrection,
 	})
 	if err != nil {
 		return err
@@ -512,6 +768,13 @@ func (r *RoomManager) StartSession(
 		_ = participant.Close(true, types.ParticipantCloseReasonMessageBusFailed, false)
 		return err
 	}
+	killParticipantAPI, err := r.roomAPI.AddParticipant(room, participant)
+	if err != nil {
+		killParticipantServer()
+		pLogger.Errorw("could not register participant api topic", err)
+		_ = participant.Close(true, types.ParticipantCloseReasonMessageBusFailed, false)
+		return err
+	}
 
 	if err = r.roomStore.StoreParticipant(ctx, room.Name(), participant.ToProto()); err != nil {
 		pLogger.Errorw("could not store participant", err)
@@ -533,6 +796,7 @@ func (r *RoomManager) StartSession(
 	r.telemetry.ParticipantJoined(ctx, protoRoom, participant.ToProto(), pi.Client, clientMeta, true)
 	participant.OnClose(func(p types.LocalParticipant) {
 		killParticipantServer()
+		killParticipantAPI()
 
 		if err := r.roomStore.DeleteParticipant(ctx, room.Name(), p.Identity()); err != nil {
 			pLogger.Errorw("could not delete participant", err)
@@ -574,6 +838,27 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, createRoom *livekit.C
 	if err != nil {
 		return nil, err
 	}
+	roomConf := &r.config.Reloadable().Room
+	overrides, err := loadRoomConfigOverrides(ctx, r.roomStore, roomConf, roomName, createRoom.ConfigName)
+	if err != nil {
+		return nil, err
+	}
+	var recording bool
+	if recordingStore, ok := r.roomStore.(RoomRecordingStore); ok {
+		if recording, err = recordingStore.LoadRoomRecording(ctx, roomName); err != nil {
+			return nil, err
+		}
+	}
+	var (
+		stateEntries []*rtc.RoomStateEntry
+		stateVersion uint64
+	)
+	stateStore, _ := r.roomStore.(RoomStateStore)
+	if stateStore != nil {
+		if stateEntries, stateVersion, err = stateStore.LoadRoomState(ctx, roomName); err != nil {
+			return nil, err
+		}
+	}
 
 	r.lock.Lock()
 
@@ -589,8 +874,22 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, createRoom *livekit.C
 		currentRoom = r.rooms[roomName]
 	}
 
+	audioConfig := r.config.Audio.WithActiveSpeakerConfig(roomConf.ActiveSpeakers[createRoom.ConfigName])
+	if silencePause, ok := roomConf.SilencePause[createRoom.ConfigName]; ok {
+		audioConfig = audioConfig.WithSilencePauseConfig(silencePause)
+	}
+
 	// construct ice servers
-	newRoom := rtc.NewRoom(ri, internal, *r.rtcConfig, r.config.Room, &r.config.Audio, r.serverInfo, r.telemetry, r.agentClient, r.agentStore, r.egressLauncher)
+	newRoom := rtc.NewRoom(ri, internal, *r.rtcConfig, roomConf.WithOverrides(overrides), &audioConfig, r.config.Agents, r.serverInfo, r.telemetry, r.agentClient, r.agentStore, r.egressLauncher)
+	newRoom.SetConfigOverrides(overrides)
+	if recording {
+		newRoom.SetRecording(true)
+	}
+	if stateStore != nil {
+		newRoom.RestoreState(stateEntries, stateVersion, func(e *rtc.RoomStateEntry) error {
+			return stateStore.StoreRoomStateEntry(context.Background(), roomName, e)
+		})
+	}
 
 	roomTopic := rpc.FormatRoomTopic(roomName)
 	roomServer := must.Get(rpc.NewTypedRoomServer(r, r.bus))
@@ -607,14 +906,49 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, createRoom *livekit.C
 		r.lock.Unlock()
 		return nil, err
 	}
+	killRoomAPI, err := r.roomAPI.AddRoom(newRoom)
+	if err != nil {
+		killRoomServer()
+		killDispServer()
+		r.lock.Unlock()
+		return nil, err
+	}
+
+	if r.mediaInterceptor != nil {
+		newRoom.SetMediaInterceptor(r.mediaInterceptor)
+	}
+	if r.dataModerator != nil {
+		newRoom.SetDataModerator(r.dataModerator, r.dataFlagNotifier, r.config.DataModeration)
+	}
+	newRoom.SetDataCompressor(r.dataCompressor)
+	stopDataBridges := r.dataBridges.AddRoom(newRoom)
 
 	newRoom.OnClose(func() {
 		killRoomServer()
 		killDispServer()
+		killRoomAPI()
+		stopDataBridges()
+		var remoteNodes []livekit.NodeID
+		if r.roomRelay != nil {
+			remoteNodes = r.roomRelay.RemoteNodes(roomName)
+			r.roomRelay.RemoveRoom(newRoom)
+		}
 
 		roomInfo := newRoom.ToProto()
-		r.telemetry.RoomEnded(ctx, roomInfo)
 		prometheus.RoomEnded(time.Unix(roomInfo.CreationTime, 0))
+		if len(remoteNodes) != 0 {
+			// the room is still hosted on other nodes, keep its state
+			r.lock.Lock()
+			if r.rooms[roomName] == newRoom {
+				delete(r.rooms, roomName)
+			}
+			r.lock.Unlock()
+			r.roomRelay.HandOver(ctx, roomName, remoteNodes[0])
+			newRoom.Logger.Infow("room closed on node", "remoteNodes", remoteNodes)
+			return
+		}
+
+		r.telemetry.RoomEnded(ctx, roomInfo)
 		if err := r.deleteRoom(ctx, roomName); err != nil {
 			newRoom.Logger.Errorw("could not delete room", err)
 		}
@@ -626,6 +960,9 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, createRoom *livekit.C
 		if err := r.roomStore.StoreRoom(ctx, newRoom.ToProto(), newRoom.Internal()); err != nil {
 			newRoom.Logger.Errorw("could not handle metadata update", err)
 		}
+		if r.roomRelay != nil {
+			go r.roomRelay.PublishState(newRoom)
+		}
 	})
 
 	newRoom.OnParticipantChanged(func(p types.LocalParticipant) {
@@ -634,6 +971,9 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, createRoom *livekit.C
 				newRoom.Logger.Errorw("could not handle participant change", err)
 			}
 		}
+		if r.roomRelay != nil {
+			go r.roomRelay.PublishState(newRoom)
+		}
 	})
 
 	r.rooms[roomName] = newRoom
@@ -642,6 +982,12 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, createRoom *livekit.C
 
 	newRoom.Hold()
 
+	if r.roomRelay != nil {
+		if err := r.roomRelay.AddRoom(newRoom); err != nil {
+			newRoom.Logger.Errorw("could not relay room", err)
+		}
+	}
+
 	r.telemetry.RoomStarted(ctx, newRoom.ToProto())
 	prometheus.RoomStarted()
 
@@ -663,6 +1009,29 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, createRoom *livekit.C
 	return newRoom, nil
 }
 
+// getRelayReceiver finds a track published to this node, to be relayed to other nodes
+func (r *RoomManager) getRelayReceiver(trackID livekit.TrackID) sfu.TrackReceiver {
+	r.lock.RLock()
+	rooms := maps.Values(r.rooms)
+	r.lock.RUnlock()
+
+	for _, room := range rooms {
+		for _, p := range room.GetLocalParticipants() {
+			track := p.GetPublishedTrack(trackID)
+			if track == nil {
+				continue
+			}
+
+			// only the primary codec is relayed
+			if receivers := track.Receivers(); len(receivers) != 0 {
+				return receivers[0]
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
 // manages an RTC session for a participant, runs on the RTC node
 func (r *RoomManager) rtcSessionWorker(room *rtc.Room, participant types.LocalParticipant, requestSource routing.MessageSource) {
 	pLogger := rtc.LoggerWithParticipant(
@@ -740,7 +1109,12 @@ func (r *RoomManager) RemoveParticipant(ctx context.Context, req *livekit.RoomPa
 		return nil, err
 	}
 
-	participant.GetLogger().Infow("removing participant")
+	if reason, message, ok := removeReasonFromMetadata(ctx); ok {
+		participant.SetRemoveReason(reason)
+		participant.GetLogger().Infow("removing participant", "reason", reason, "message", message)
+	} else {
+		participant.GetLogger().Infow("removing participant")
+	}
 	room.RemoveParticipant(livekit.ParticipantIdentity(req.Identity), "", types.ParticipantCloseReasonServiceRequestRemoveParticipant)
 	return &livekit.RemoveParticipantResponse{}, nil
 }
@@ -753,7 +1127,7 @@ func (r *RoomManager) MutePublishedTrack(ctx context.Context, req *livekit.MuteR
 
 	participant.GetLogger().Debugw("setting track muted",
 		"trackID", req.TrackSid, "muted", req.Muted)
-	if !req.Muted && !r.config.Room.EnableRemoteUnmute {
+	if !req.Muted && !r.config.Reloadable().Room.EnableRemoteUnmute {
 		participant.GetLogger().Errorw("cannot unmute track, remote unmute is disabled", nil)
 		return nil, ErrRemoteUnmuteNoteEnabled
 	}
@@ -804,6 +1178,9 @@ func (r *RoomManager) DeleteRoom(ctx context.Context, req *livekit.DeleteRoomReq
 		}
 	} else {
 		room.Logger.Infow("deleting room")
+		if r.roomRelay != nil {
+			r.roomRelay.DeleteRoom(room.Name())
+		}
 		room.Close(types.ParticipantCloseReasonServiceRequestDeleteRoom)
 	}
 	return &livekit.DeleteRoomResponse{}, nil
@@ -945,7 +1322,7 @@ func (r *RoomManager) iceServersForParticipant(apiKey string, participant types.
 
 	if len(rtcConf.TURNServers) > 0 {
 		hasSTUN = true
-		for _, s := range r.config.RTC.TURNServers {
+		for _, s := range r.turnHealth.FilterServers(r.config.Reloadable().TURNServers) {
 			scheme := "turn"
 			transport := "tcp"
 			if s.Protocol == "tls" {
@@ -983,10 +1360,13 @@ func (r *RoomManager) refreshToken(participant types.LocalParticipant) error {
 
 	grants := participant.ClaimGrants()
 	token := auth.NewAccessToken(key, secret)
+	// keep the kind and attributes, refreshed agent tokens must stay scoped like the original
 	token.SetName(grants.Name).
 		SetIdentity(string(participant.Identity())).
+		SetKind(participant.Kind()).
 		SetValidFor(tokenDefaultTTL).
 		SetMetadata(grants.Metadata).
+		SetAttributes(grants.Attributes).
 		AddGrant(grants.Video)
 	jwt, err := token.ToJWT()
 	if err == nil {
@@ -0,0 +1,412 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/frostbyte73/core"
+	"golang.org/x/exp/maps"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/relay"
+	sutils "github.com/livekit/livekit-server/pkg/utils"
+)
+
+const (
+	relayStateInterval = 2 * time.Second
+	relayNodeTimeout   = 3 * relayStateInterval
+
+	relayServiceName = "RoomRelay"
+	relayStateRPC    = "State"
+)
+
+// relayRoomState is the state of a room on a node, published to all nodes hosting the room
+type relayRoomState struct {
+	NodeID       livekit.NodeID          `json:"node_id"`
+	Participants []relayParticipantState `json:"participants,omitempty"`
+	// room metadata is reconciled between nodes, the most recent update wins
+	Metadata          string `json:"metadata,omitempty"`
+	MetadataUpdatedAt int64  `json:"metadata_updated_at,omitempty"`
+	// the room was deleted through the API, every node hosting it closes it
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+type relayParticipantState struct {
+	Info       []byte                               `json:"info"`
+	Permission []byte                               `json:"permission,omitempty"`
+	Codecs     map[livekit.TrackID]rtc.RelayedCodec `json:"codecs,omitempty"`
+}
+
+type relayedRoom struct {
+	room  *rtc.Room
+	sub   psrpc.Subscription[*wrapperspb.BytesValue]
+	nodes map[livekit.NodeID]time.Time
+	// set once the room is deleted, its state must not be handed over to other nodes
+	deleted bool
+}
+
+// RoomRelay allows a room to be hosted on multiple nodes. Nodes hosting a room share the state of
+// their participants over the message bus, while media is relayed between nodes on demand.
+type RoomRelay struct {
+	currentNode routing.LocalNode
+	router      routing.Router
+	manager     *relay.Manager
+	client      *client.RPCClient
+	server      *server.RPCServer
+
+	lock  sync.Mutex
+	rooms map[livekit.RoomName]*relayedRoom
+
+	stopped core.Fuse
+}
+
+func NewRoomRelay(
+	conf *config.Config,
+	currentNode routing.LocalNode,
+	router routing.Router,
+	bus psrpc.MessageBus,
+	receiverProvider func(trackID livekit.TrackID) sfu.TrackReceiver,
+) (*RoomRelay, error) {
+	r := &RoomRelay{
+		currentNode: currentNode,
+		router:      router,
+		rooms:       make(map[livekit.RoomName]*relayedRoom),
+	}
+
+	lgr := logger.GetLogger().WithComponent(sutils.ComponentRelay)
+	r.manager = relay.NewManager(relay.ManagerParams{
+		Config:         conf.Relay,
+		NodeID:         livekit.NodeID(currentNode.Id),
+		TrackersConfig: conf.Video.StreamTracker,
+		BufferFactory:  buffer.NewFactoryOfBufferFactory(conf.RTC.PacketBufferSizeVideo, conf.RTC.PacketBufferSizeAudio),
+		Transport: relay.NewUDPTransport(relay.UDPTransportParams{
+			BindAddress: conf.Relay.BindAddress,
+			Port:        int(conf.Relay.Port),
+			Secret:      []byte(conf.Relay.Secret),
+			Logger:      lgr,
+		}),
+		Logger:           lgr,
+		Secret:           []byte(conf.Relay.Secret),
+		GetNode:          r.getNode,
+		ReceiverProvider: receiverProvider,
+	})
+
+	sd := &info.ServiceDefinition{
+		Name: relayServiceName,
+		ID:   rand.NewClientID(),
+	}
+	sd.RegisterMethod(relayStateRPC, false, true, false, false)
+	var err error
+	if r.client, err = client.NewRPCClient(sd, bus); err != nil {
+		return nil, err
+	}
+
+	sd = &info.ServiceDefinition{
+		Name: relayServiceName,
+		ID:   rand.NewServerID(),
+	}
+	sd.RegisterMethod(relayStateRPC, false, true, false, false)
+	r.server = server.NewRPCServer(sd, bus)
+
+	if err := r.manager.Start(); err != nil {
+		r.server.Close(true)
+		r.client.Close()
+		return nil, err
+	}
+
+	go r.worker()
+	return r, nil
+}
+
+func (r *RoomRelay) Stop() {
+	r.stopped.Break()
+
+	r.lock.Lock()
+	rooms := r.rooms
+	r.rooms = make(map[livekit.RoomName]*relayedRoom)
+	r.lock.Unlock()
+
+	for roomName, rr := range rooms {
+		_ = rr.sub.Close()
+		r.publish(roomName, relayRoomState{})
+	}
+
+	r.manager.Stop()
+	r.server.Close(false)
+	r.client.Close()
+}
+
+// AddRoom starts sharing state of a room hosted on this node with other nodes hosting the room
+func (r *RoomRelay) AddRoom(room *rtc.Room) error {
+	sub, err := client.Join[*wrapperspb.BytesValue](context.Background(), r.client, relayStateRPC, []string{string(room.Name())})
+	if err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	if existing := r.rooms[room.Name()]; existing != nil {
+		_ = existing.sub.Close()
+	}
+	rr := &relayedRoom{
+		room:  room,
+		sub:   sub,
+		nodes: make(map[livekit.NodeID]time.Time),
+	}
+	r.rooms[room.Name()] = rr
+	r.lock.Unlock()
+
+	room.SetRelay(r.manager)
+	go r.stateWorker(rr)
+
+	r.PublishState(room)
+	return nil
+}
+
+// RemoveRoom stops sharing state of a room, other nodes are notified that this node no longer hosts it
+func (r *RoomRelay) RemoveRoom(room *rtc.Room) {
+	r.lock.Lock()
+	rr := r.rooms[room.Name()]
+	if rr == nil || rr.room != room {
+		r.lock.Unlock()
+		return
+	}
+	delete(r.rooms, room.Name())
+	r.lock.Unlock()
+
+	_ = rr.sub.Close()
+	r.publish(room.Name(), relayRoomState{})
+}
+
+// RemoteNodes returns the other nodes currently hosting the room, none once the room is deleted
+func (r *RoomRelay) RemoteNodes(roomName livekit.RoomName) []livekit.NodeID {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	rr := r.rooms[roomName]
+	if rr == nil || rr.deleted {
+		return nil
+	}
+	return maps.Keys(rr.nodes)
+}
+
+// DeleteRoom closes the room on every other node hosting it
+func (r *RoomRelay) DeleteRoom(roomName livekit.RoomName) {
+	r.lock.Lock()
+	if rr := r.rooms[roomName]; rr != nil {
+		rr.deleted = true
+	}
+	r.lock.Unlock()
+
+	r.publish(roomName, relayRoomState{Deleted: true})
+}
+
+// HandOver moves routing of a room to another node hosting it, when the room closes on this node
+func (r *RoomRelay) HandOver(ctx context.Context, roomName livekit.RoomName, nodeID livekit.NodeID) {
+	r.reassignRoom(ctx, roomName, livekit.NodeID(r.currentNode.Id), nodeID)
+}
+
+// reassignRoom routes a room to another node, if it is still routed to the given node
+func (r *RoomRelay) reassignRoom(ctx context.Context, roomName livekit.RoomName, from, to livekit.NodeID) {
+	node, err := r.router.GetNodeForRoom(ctx, roomName)
+	if err != nil || livekit.NodeID(node.Id) != from {
+		return
+	}
+
+	logger.Infow("reassigning room", "room", roomName, "from", from, "to", to)
+	if err := r.router.SetNodeForRoom(ctx, roomName, to); err != nil {
+		logger.Errorw("could not reassign room", err, "room", roomName)
+	}
+}
+
+// PublishState shares the current state of participants connected to this node
+func (r *RoomRelay) PublishState(room *rtc.Room) {
+	participants := room.GetRelayState()
+	state := relayRoomState{
+		Participants: make([]relayParticipantState, 0, len(participants)),
+	}
+	state.Metadata, state.MetadataUpdatedAt = room.GetRelayMetadata()
+	for _, p := range participants {
+		pi, err := proto.Marshal(p.Info)
+		if err != nil {
+			room.Logger.Errorw("could not marshal participant info", err)
+			continue
+		}
+		var perm []byte
+		if p.Permission != nil {
+			if perm, err = proto.Marshal(p.Permission); err != nil {
+				room.Logger.Errorw("could not marshal subscription permission", err)
+				continue
+			}
+		}
+		state.Participants = append(state.Participants, relayParticipantState{
+			Info:       pi,
+			Permission: perm,
+			Codecs:     p.Codecs,
+		})
+	}
+	r.publish(room.Name(), state)
+}
+
+func (r *RoomRelay) publish(roomName livekit.RoomName, state relayRoomState) {
+	state.NodeID = livekit.NodeID(r.currentNode.Id)
+	data, err := json.Marshal(&state)
+	if err != nil {
+		logger.Errorw("could not marshal relay room state", err, "room", roomName)
+		return
+	}
+	if err := r.server.Publish(context.Background(), relayStateRPC, []string{string(roomName)}, wrapperspb.Bytes(data)); err != nil {
+		logger.Warnw("could not publish relay room state", err, "room", roomName)
+	}
+}
+
+func (r *RoomRelay) stateWorker(rr *relayedRoom) {
+	for msg := range rr.sub.Channel() {
+		var state relayRoomState
+		if err := json.Unmarshal(msg.Value, &state); err != nil {
+			rr.room.Logger.Warnw("could not unmarshal relay room state", err)
+			continue
+		}
+		if state.NodeID == livekit.NodeID(r.currentNode.Id) {
+			continue
+		}
+		if state.Deleted {
+			r.lock.Lock()
+			rr.deleted = true
+			r.lock.Unlock()
+			rr.room.Logger.Infow("room deleted on another node", "nodeID", state.NodeID)
+			// closing the room closes this subscription
+			go rr.room.Close(types.ParticipantCloseReasonServiceRequestDeleteRoom)
+			continue
+		}
+		if state.MetadataUpdatedAt != 0 {
+			rr.room.ReconcileRelayMetadata(state.Metadata, state.MetadataUpdatedAt)
+		}
+
+		if len(state.Participants) == 0 {
+			// node no longer hosts the room
+			r.lock.Lock()
+			delete(rr.nodes, state.NodeID)
+			r.lock.Unlock()
+			rr.room.RemoveRelayedNode(state.NodeID)
+			continue
+		}
+
+		node, err := r.getNode(state.NodeID)
+		if err != nil || node == nil {
+			rr.room.Logger.Debugw("relay room state from unknown node", "nodeID", state.NodeID)
+			continue
+		}
+
+		participants := make([]*rtc.RelayedParticipant, 0, len(state.Participants))
+		for _, ps := range state.Participants {
+			p := &rtc.RelayedParticipant{
+				Info:   &livekit.ParticipantInfo{},
+				Codecs: ps.Codecs,
+			}
+			if err := proto.Unmarshal(ps.Info, p.Info); err != nil {
+				continue
+			}
+			if len(ps.Permission) != 0 {
+				p.Permission = &livekit.SubscriptionPermission{}
+				if err := proto.Unmarshal(ps.Permission, p.Permission); err != nil {
+					continue
+				}
+			}
+			participants = append(participants, p)
+		}
+
+		r.lock.Lock()
+		rr.nodes[state.NodeID] = time.Now()
+		r.lock.Unlock()
+		rr.room.UpdateRelayedParticipants(node, participants)
+	}
+}
+
+func (r *RoomRelay) worker() {
+	ticker := time.NewTicker(relayStateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stopped.Watch():
+			return
+
+		case <-ticker.C:
+			r.lock.Lock()
+			rooms := make([]*relayedRoom, 0, len(r.rooms))
+			expired := make(map[*relayedRoom][]livekit.NodeID)
+			for _, rr := range r.rooms {
+				rooms = append(rooms, rr)
+				for nodeID, lastSeen := range rr.nodes {
+					if time.Since(lastSeen) > relayNodeTimeout {
+						delete(rr.nodes, nodeID)
+						expired[rr] = append(expired[rr], nodeID)
+					}
+				}
+			}
+			r.lock.Unlock()
+
+			for rr, nodeIDs := range expired {
+				for _, nodeID := range nodeIDs {
+					rr.room.Logger.Infow("relay node expired", "nodeID", nodeID)
+					rr.room.RemoveRelayedNode(nodeID)
+					// the node, or its whole region, is gone. keep the room reachable through this node
+					r.reassignRoom(context.Background(), rr.room.Name(), nodeID, livekit.NodeID(r.currentNode.Id))
+				}
+			}
+			for _, rr := range rooms {
+				r.PublishState(rr.room)
+			}
+		}
+	}
+}
+
+func (r *RoomRelay) getNode(nodeID livekit.NodeID) (*livekit.Node, error) {
+	if getter, ok := r.router.(interface {
+		GetNode(nodeID livekit.NodeID) (*livekit.Node, error)
+	}); ok {
+		return getter.GetNode(nodeID)
+	}
+
+	nodes, err := r.router.ListNodes()
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range nodes {
+		if livekit.NodeID(node.Id) == nodeID {
+			return node, nil
+		}
+	}
+	return nil, routing.ErrNotFound
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"sync/atomic"
 
 	"github.com/pkg/errors"
 	"github.com/twitchtv/twirp"
@@ -31,10 +32,11 @@ import (
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
 )
 
 type RoomService struct {
-	limitConf         config.LimitConfig
+	limitConf         atomic.Pointer[config.LimitConfig]
 	apiConf           config.APIConfig
 	router            routing.MessageRouter
 	roomAllocator     RoomAllocator
@@ -59,7 +61,6 @@ func NewRoomService(
 	participantClient rpc.TypedParticipantClient,
 ) (svc *RoomService, err error) {
 	svc = &RoomService{
-		limitConf:         limitConf,
 		apiConf:           apiConf,
 		router:            router,
 		roomAllocator:     roomAllocator,
@@ -70,9 +71,15 @@ func NewRoomService(
 		roomClient:        roomClient,
 		participantClient: participantClient,
 	}
+	svc.limitConf.Store(&limitConf)
 	return
 }
 
+// UpdateLimitConfig replaces the limits checked on requests, used when the config is reloaded
+func (s *RoomService) UpdateLimitConfig(limitConf config.LimitConfig) {
+	s.limitConf.Store(&limitConf)
+}
+
 func (s *RoomService) CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest) (*livekit.Room, error) {
 	AppendLogFields(ctx, "room", req.Name, "request", logger.Proto(redactCreateRoomRequest(req)))
 	if err := EnsureCreatePermission(ctx); err != nil {
@@ -81,12 +88,34 @@ func (s *RoomService) CreateRoom(ctx context.Context, req *livekit.CreateRoomReq
 		return nil, ErrEgressNotConnected
 	}
 
-	if !s.limitConf.CheckRoomNameLength(req.Name) {
-		return nil, fmt.Errorf("%w: max length %d", ErrRoomNameExceedsLimits, s.limitConf.MaxRoomNameLength)
+	if limitConf := s.limitConf.Load(); !limitConf.CheckRoomNameLength(req.Name) {
+		return nil, fmt.Errorf("%w: max length %d", ErrRoomNameExceedsLimits, limitConf.MaxRoomNameLength)
+	}
+
+	// overrides are stored by the node creating the room, once it is created
+	overrides, err := roomConfigOverridesFromContext(ctx)
+	if err != nil {
+		return nil, twirp.InvalidArgumentError(roomConfigHeader, err.Error())
+	}
+	if overrides != nil {
+		if err = validateRTPInterceptors(overrides.RTPInterceptors); err != nil {
+			return nil, twirp.InvalidArgumentError(roomConfigHeader, err.Error())
+		}
+		if _, ok := s.roomStore.(RoomConfigOverridesStore); !ok {
+			return nil, twirp.NewError(twirp.Unimplemented, "room config overrides are not supported by the store")
+		}
+		if _, _, err = s.roomStore.LoadRoom(ctx, livekit.RoomName(req.Name), false); err == nil {
+			return nil, twirp.NewError(twirp.AlreadyExists, "config overrides only apply when the room is created")
+		} else if !errors.Is(err, ErrRoomNotFound) {
+			return nil, err
+		}
+		if ctx, err = WithRoomConfigOverrides(ctx, overrides); err != nil {
+			return nil, err
+		}
 	}
 
 	if s.roomAllocator.CreateRoomEnabled() {
-		err := s.roomAllocator.SelectRoomNode(ctx, livekit.RoomName(req.Name), livekit.NodeID(req.NodeId))
+		err := s.roomAllocator.SelectRoomNode(ctx, livekit.RoomName(req.Name), livekit.NodeID(req.NodeId), req.ConfigName)
 		if err != nil {
 			return nil, err
 		}
@@ -96,10 +125,17 @@ func (s *RoomService) CreateRoom(ctx context.Context, req *livekit.CreateRoomReq
 
 	rm, _, created, err := s.roomAllocator.CreateRoom(ctx, req, true)
 	if err != nil {
+		if errors.Is(err, psrpc.PermissionDenied) {
+			// rejected by the room create hook
+			return nil, twirp.NewError(twirp.PermissionDenied, err.Error())
+		}
+		if errors.Is(err, psrpc.AlreadyExists) {
+			return nil, twirp.NewError(twirp.AlreadyExists, err.Error())
+		}
 		err = errors.Wrap(err, "could not create room")
 		return nil, err
 	}
-	err = s.roomAllocator.SelectRoomNode(ctx, livekit.RoomName(req.Name), livekit.NodeID(req.NodeId))
+	err = s.roomAllocator.SelectRoomNode(ctx, livekit.RoomName(req.Name), livekit.NodeID(req.NodeId), req.ConfigName)
 	if err != nil {
 		return nil, err
 	}
@@ -223,6 +259,11 @@ func (s *RoomService) RemoveParticipant(ctx context.Context, req *livekit.RoomPa
 		return nil, twirpAuthError(err)
 	}
 
+	ctx, err := withRemoveReasonMetadata(ctx)
+	if err != nil {
+		return nil, twirp.InvalidArgumentError(removeReasonHeader, err.Error())
+	}
+
 	if _, err := s.roomStore.LoadParticipant(ctx, livekit.RoomName(req.Room), livekit.ParticipantIdentity(req.Identity)); err == ErrParticipantNotFound {
 		return nil, twirp.NotFoundError("participant not found")
 	}
@@ -242,16 +283,17 @@ func (s *RoomService) MutePublishedTrack(ctx context.Context, req *livekit.MuteR
 func (s *RoomService) UpdateParticipant(ctx context.Context, req *livekit.UpdateParticipantRequest) (*livekit.ParticipantInfo, error) {
 	AppendLogFields(ctx, "room", req.Room, "participant", req.Identity)
 
-	if !s.limitConf.CheckParticipantNameLength(req.Name) {
-		return nil, twirp.InvalidArgumentError(ErrNameExceedsLimits.Error(), strconv.Itoa(s.limitConf.MaxParticipantNameLength))
+	limitConf := s.limitConf.Load()
+	if !limitConf.CheckParticipantNameLength(req.Name) {
+		return nil, twirp.InvalidArgumentError(ErrNameExceedsLimits.Error(), strconv.Itoa(limitConf.MaxParticipantNameLength))
 	}
 
-	if !s.limitConf.CheckMetadataSize(req.Metadata) {
-		return nil, twirp.InvalidArgumentError(ErrMetadataExceedsLimits.Error(), strconv.Itoa(int(s.limitConf.MaxMetadataSize)))
+	if !limitConf.CheckMetadataSize(req.Metadata) {
+		return nil, twirp.InvalidArgumentError(ErrMetadataExceedsLimits.Error(), strconv.Itoa(int(limitConf.MaxMetadataSize)))
 	}
 
-	if !s.limitConf.CheckAttributesSize(req.Attributes) {
-		return nil, twirp.InvalidArgumentError(ErrAttributeExceedsLimits.Error(), strconv.Itoa(int(s.limitConf.MaxAttributesSize)))
+	if !limitConf.CheckAttributesSize(req.Attributes) {
+		return nil, twirp.InvalidArgumentError(ErrAttributeExceedsLimits.Error(), strconv.Itoa(int(limitConf.MaxAttributesSize)))
 	}
 
 	if err := EnsureAdminPermission(ctx, livekit.RoomName(req.Room)); err != nil {
@@ -287,7 +329,7 @@ func (s *RoomService) SendData(ctx context.Context, req *livekit.SendDataRequest
 
 func (s *RoomService) UpdateRoomMetadata(ctx context.Context, req *livekit.UpdateRoomMetadataRequest) (*livekit.Room, error) {
 	AppendLogFields(ctx, "room", req.Room, "size", len(req.Metadata))
-	maxMetadataSize := int(s.limitConf.MaxMetadataSize)
+	maxMetadataSize := int(s.limitConf.Load().MaxMetadataSize)
 	if maxMetadataSize > 0 && len(req.Metadata) > maxMetadataSize {
 		return nil, twirp.InvalidArgumentError(ErrMetadataExceedsLimits.Error(), strconv.Itoa(maxMetadataSize))
 	}
@@ -124,7 +124,7 @@ func newTestRoomService(limitConf config.LimitConfig) *TestRoomService {
 		panic(err)
 	}
 	return &TestRoomService{
-		RoomService: *svc,
+		RoomService: svc,
 		router:      router,
 		allocator:   allocator,
 		store:       store,
@@ -132,7 +132,7 @@ func newTestRoomService(limitConf config.LimitConfig) *TestRoomService {
 }
 
 type TestRoomService struct {
-	service.RoomService
+	*service.RoomService
 	router    *routingfakes.FakeRouter
 	allocator *servicefakes.FakeRoomAllocator
 	store     *servicefakes.FakeServiceStore
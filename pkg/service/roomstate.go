@@ -0,0 +1,164 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	roomStatePath = "/room/state"
+
+	roomStateMethod = "RoomState"
+)
+
+// RoomStateStore keeps the shared state of rooms until they are deleted, so it survives the room moving to another node
+type RoomStateStore interface {
+	StoreRoomStateEntry(ctx context.Context, roomName livekit.RoomName, e *rtc.RoomStateEntry) error
+	LoadRoomState(ctx context.Context, roomName livekit.RoomName) ([]*rtc.RoomStateEntry, uint64, error)
+}
+
+// RoomStateHandler reads and changes the shared key-value state of rooms, through the node hosting the room
+type RoomStateHandler struct {
+	roomAPI *RoomAPI
+}
+
+type roomStateSetRequest struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// compare-and-swap, 0 requires the key not to exist
+	IfVersion *uint64 `json:"if_version,omitempty"`
+}
+
+func NewRoomStateHandler(roomManager *RoomManager) *RoomStateHandler {
+	h := &RoomStateHandler{
+		roomAPI: roomManager.RoomAPI(),
+	}
+	h.roomAPI.Handle(roomStateMethod, h.handleRoomState)
+	return h
+}
+
+// UpdateRoomState runs a get, set or delete of the room state as the server API
+func (h *RoomStateHandler) UpdateRoomState(ctx context.Context, roomName livekit.RoomName, req *rtc.RoomStateRequest) (*rtc.RoomStateResponse, error) {
+	res := &rtc.RoomStateResponse{}
+	if err := h.roomAPI.CallRoom(ctx, roomName, roomStateMethod, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (h *RoomStateHandler) handleRoomState(_ context.Context, room *rtc.Room, _ types.LocalParticipant, body json.RawMessage) (any, error) {
+	var req rtc.RoomStateRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+
+	var (
+		res = &rtc.RoomStateResponse{}
+		e   *rtc.RoomStateEntry
+		err error
+	)
+	switch req.Op {
+	case rtc.RoomStateOpGet:
+		res.Entries, err = room.GetState(req.Key)
+	case rtc.RoomStateOpSet:
+		e, err = room.SetState(req.Key, req.Value, req.IfVersion, "")
+	case rtc.RoomStateOpDelete:
+		e, err = room.DeleteState(req.Key, req.IfVersion, "")
+	default:
+		err = rtc.ErrRoomStateInvalidOp
+	}
+	if err != nil {
+		return nil, roomStateError(err)
+	}
+	if e != nil {
+		res.Entries = []*rtc.RoomStateEntry{e}
+	}
+	return res, nil
+}
+
+// ServeHTTP handles, with a token that has roomAdmin permission for the room
+//
+//	GET /room/state?room=&key=                   one key, or all keys when key is omitted
+//	POST /room/state?room= {"key", "value", "if_version"}
+//	DELETE /room/state?room=&key=&if_version=
+func (h *RoomStateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	roomName := livekit.RoomName(query.Get("room"))
+	if err := EnsureAdminPermission(r.Context(), roomName); err != nil {
+		handleError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	req := &rtc.RoomStateRequest{Key: query.Get("key")}
+	switch r.Method {
+	case http.MethodGet:
+		req.Op = rtc.RoomStateOpGet
+	case http.MethodPost:
+		var body roomStateSetRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&body); err != nil {
+			handleError(w, r, http.StatusBadRequest, err, "room", roomName)
+			return
+		}
+		req.Op = rtc.RoomStateOpSet
+		req.Key, req.Value, req.IfVersion = body.Key, body.Value, body.IfVersion
+	case http.MethodDelete:
+		req.Op = rtc.RoomStateOpDelete
+		if v := query.Get("if_version"); v != "" {
+			version, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				handleError(w, r, http.StatusBadRequest, err, "room", roomName)
+				return
+			}
+			req.IfVersion = &version
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	res, err := h.UpdateRoomState(r.Context(), roomName, req)
+	if err != nil {
+		handleError(w, r, roomAPIStatus(err), err, "room", roomName)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func roomStateError(err error) error {
+	switch {
+	case errors.Is(err, rtc.ErrRoomStateKeyNotFound):
+		return psrpc.NewError(psrpc.NotFound, err)
+	case errors.Is(err, rtc.ErrRoomStateVersionMismatch):
+		return psrpc.NewError(psrpc.Aborted, err)
+	case errors.Is(err, rtc.ErrRoomStateInvalidKey), errors.Is(err, rtc.ErrRoomStateValueTooLarge),
+		errors.Is(err, rtc.ErrRoomStateFull), errors.Is(err, rtc.ErrRoomStateInvalidOp):
+		return psrpc.NewError(psrpc.InvalidArgument, err)
+	default:
+		return err
+	}
+}
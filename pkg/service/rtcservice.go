@@ -39,9 +39,13 @@ import (
 	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 	"github.com/livekit/livekit-server/pkg/utils"
 	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
 	"github.com/livekit/psrpc"
 )
 
+// requests read from a websocket while earlier ones are forwarded, before reading stops
+const signalRequestQueueSize = 100
+
 type RTCService struct {
 	router        routing.MessageRouter
 	roomAllocator RoomAllocator
@@ -50,13 +54,15 @@ type RTCService struct {
 	currentNode   routing.LocalNode
 	config        *config.Config
 	isDev         bool
-	limits        config.LimitConfig
 	parser        *uaparser.Parser
 	agentClient   agent.Client
 	telemetry     telemetry.TelemetryService
+	quotas        *QuotaManager
+	roomHooks     RoomHooks
 
 	mu          sync.Mutex
 	connections map[*websocket.Conn]struct{}
+	draining    atomic.Bool
 }
 
 func NewRTCService(
@@ -67,6 +73,8 @@ func NewRTCService(
 	currentNode routing.LocalNode,
 	agentClient agent.Client,
 	telemetry telemetry.TelemetryService,
+	quotas *QuotaManager,
+	roomHooks RoomHooks,
 ) *RTCService {
 	s := &RTCService{
 		router:        router,
@@ -76,10 +84,11 @@ func NewRTCService(
 		currentNode:   currentNode,
 		config:        conf,
 		isDev:         conf.Development,
-		limits:        conf.Limit,
 		parser:        uaparser.NewFromSaved(),
 		agentClient:   agentClient,
 		telemetry:     telemetry,
+		quotas:        quotas,
+		roomHooks:     roomHooks,
 		connections:   map[*websocket.Conn]struct{}{},
 	}
 
@@ -118,7 +127,7 @@ func (s *RTCService) validate(r *http.Request) (livekit.RoomName, routing.Partic
 	if claims.Identity == "" {
 		return "", pi, http.StatusBadRequest, ErrIdentityEmpty
 	}
-	if limit := s.config.Limit.MaxParticipantIdentityLength; limit > 0 && len(claims.Identity) > limit {
+	if limit := s.config.Reloadable().Limit.MaxParticipantIdentityLength; limit > 0 && len(claims.Identity) > limit {
 		return "", pi, http.StatusBadRequest, fmt.Errorf("%w: max length %d", ErrParticipantIdentityExceedsLimits, limit)
 	}
 
@@ -135,7 +144,7 @@ func (s *RTCService) validate(r *http.Request) (livekit.RoomName, routing.Partic
 	if onlyName != "" {
 		roomName = onlyName
 	}
-	if limit := s.config.Limit.MaxRoomNameLength; limit > 0 && len(roomName) > limit {
+	if limit := s.config.Reloadable().Limit.MaxRoomNameLength; limit > 0 && len(roomName) > limit {
 		return "", pi, http.StatusBadRequest, fmt.Errorf("%w: max length %d", ErrRoomNameExceedsLimits, limit)
 	}
 
@@ -164,7 +173,7 @@ func (s *RTCService) validate(r *http.Request) (livekit.RoomName, routing.Partic
 	if router, ok := s.router.(routing.Router); ok {
 		region = router.GetRegion()
 		if foundNode, err := router.GetNodeForRoom(r.Context(), roomName); err == nil {
-			if selector.LimitsReached(s.limits, foundNode.Stats) {
+			if selector.LimitsReached(s.config.Reloadable().Limit, foundNode.Stats) {
 				return "", pi, http.StatusServiceUnavailable, rtc.ErrLimitExceeded
 			}
 		}
@@ -226,6 +235,42 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	pLogger := utils.GetLogger(r.Context()).WithValues(loggerFields...)
 
+	// clients are redirected off a draining node, new rooms are rejected. rooms still hosted here keep accepting
+	// joins, redirected clients rejoin them until they close
+	if s.draining.Load() && s.config.Shutdown.RedirectClients && !pi.Reconnect {
+		if _, _, err := s.store.LoadRoom(r.Context(), roomName, false); err != nil {
+			setRetryAfter(w, s.config.Shutdown.RetryAfter)
+			handleError(w, r, http.StatusServiceUnavailable, ErrNodeDraining, loggerFields...)
+			return
+		}
+	}
+
+	joinToken, err := s.reserveJoinToken(r.Context(), roomName, pi)
+	if err != nil {
+		prometheus.IncrementParticipantJoinFail(1)
+		handleError(w, r, http.StatusForbidden, err, loggerFields...)
+		return
+	}
+	defer joinToken.Release()
+
+	if s.roomHooks != nil && !pi.Reconnect {
+		// grants are shared with the request context, hooks may change name, metadata and attributes
+		pi.Grants = pi.Grants.Clone()
+		if err = s.roomHooks.BeforeParticipantJoin(r.Context(), roomName, &pi); err != nil {
+			prometheus.IncrementParticipantJoinFail(1)
+			handleError(w, r, http.StatusForbidden, err, loggerFields...)
+			return
+		}
+	}
+
+	quotaSession, err := s.quotas.StartSession(quotaKey(r.Context()), roomName, pi.Reconnect)
+	if err != nil {
+		prometheus.IncrementParticipantJoinFail(1)
+		handleError(w, r, http.StatusTooManyRequests, err, loggerFields...)
+		return
+	}
+	defer quotaSession.Close()
+
 	// give it a few attempts to start session
 	var cr connectionResult
 	var initialResponse *livekit.SignalResponse
@@ -233,7 +278,7 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		connectionTimeout := 3 * time.Second * time.Duration(i+1)
 		ctx := utils.ContextWithAttempt(r.Context(), i)
 		cr, initialResponse, err = s.startConnection(ctx, roomName, pi, connectionTimeout)
-		if err == nil || errors.Is(err, context.Canceled) {
+		if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, psrpc.PermissionDenied) {
 			break
 		}
 		if i < 2 {
@@ -243,10 +288,19 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		prometheus.IncrementParticipantJoinFail(1)
-		handleError(w, r, http.StatusInternalServerError, err, loggerFields...)
+		code := http.StatusInternalServerError
+		if errors.Is(err, psrpc.PermissionDenied) {
+			// rejected by the room create hook
+			code = http.StatusForbidden
+		}
+		handleError(w, r, code, err, loggerFields...)
 		return
 	}
 
+	if err = joinToken.Commit(r.Context()); err != nil {
+		pLogger.Warnw("could not consume join url token", err)
+	}
+
 	prometheus.IncrementParticipantJoin(1)
 
 	if !pi.Reconnect && initialResponse.GetJoin() != nil {
@@ -311,10 +365,11 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// handle responses
 	go func() {
+		var leave *livekit.LeaveRequest
 		defer func() {
 			// when the source is terminated, this means Participant.Close had been called and RTC connection is done
 			// we would terminate the signal connection as well
-			closeMsg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+			closeMsg := signalCloseMessage(leave, s.config.Shutdown.RetryAfter)
 			_ = conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
 			_ = conn.Close()
 		}()
@@ -350,6 +405,12 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 					signalStats.ResolveParticipant(m.Join.GetParticipant())
 				case *livekit.SignalResponse_RoomUpdate:
 					signalStats.ResolveRoom(m.RoomUpdate.GetRoom())
+				case *livekit.SignalResponse_TrackPublished:
+					quotaSession.TrackPublished(m.TrackPublished)
+				case *livekit.SignalResponse_TrackUnpublished:
+					quotaSession.TrackUnpublished(livekit.TrackID(m.TrackUnpublished.GetTrackSid()))
+				case *livekit.SignalResponse_Leave:
+					leave = m.Leave
 				}
 
 				if count, err := sigConn.WriteResponse(res); err != nil {
@@ -362,6 +423,24 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
+	// requests are forwarded from a separate goroutine, in order, so room hooks do not hold up pings
+	requests := make(chan *livekit.SignalRequest, signalRequestQueueSize)
+	forwardDone := make(chan struct{})
+	go func() {
+		defer close(forwardDone)
+		for req := range requests {
+			if !s.forwardSignalRequest(r.Context(), req, roomName, pi.Identity, cr, sigConn, quotaSession, signalStats, pLogger) {
+				// closing the websocket ends the read loop
+				_ = conn.Close()
+				return
+			}
+		}
+	}()
+	defer func() {
+		close(requests)
+		<-forwardDone
+	}()
+
 	// handle incoming requests from websocket
 	for {
 		req, count, err := sigConn.ReadRequest()
@@ -403,21 +482,72 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		switch m := req.Message.(type) {
-		case *livekit.SignalRequest_Offer:
-			pLogger.Debugw("received offer", "offer", m)
-		case *livekit.SignalRequest_Answer:
-			pLogger.Debugw("received answer", "answer", m)
+		select {
+		case requests <- req:
+		case <-forwardDone:
+			return
+		}
+	}
+}
+
+// forwardSignalRequest runs room hooks and quotas of a request and forwards it to the participant, returning false
+// when the signal connection to the participant is broken
+func (s *RTCService) forwardSignalRequest(
+	ctx context.Context,
+	req *livekit.SignalRequest,
+	roomName livekit.RoomName,
+	identity livekit.ParticipantIdentity,
+	cr connectionResult,
+	sigConn *WSSignalConnection,
+	quotaSession *QuotaSession,
+	signalStats *telemetry.BytesSignalStats,
+	pLogger logger.Logger,
+) bool {
+	rejectTrack := func(cid string, reason livekit.RequestResponse_Reason, err error) {
+		pLogger.Warnw("rejecting track publish", err, "cid", cid)
+		// AddTrackRequest has no request id, the client matches the rejection by the cid in the message
+		count, perr := sigConn.WriteResponse(&livekit.SignalResponse{
+			Message: &livekit.SignalResponse_RequestResponse{
+				RequestResponse: &livekit.RequestResponse{
+					Reason:  reason,
+					Message: fmt.Sprintf("track %s rejected: %s", cid, err),
+				},
+			},
+		})
+		if perr == nil {
+			signalStats.AddBytes(uint64(count), true)
 		}
+	}
 
-		if err := cr.RequestSink.WriteMessage(req); err != nil {
-			pLogger.Warnw("error writing to request sink", err, "connID", cr.ConnectionID)
-			if errors.Is(err, psrpc.ErrStreamClosed) {
-				// disconnect the participant WS since the signal proxy has been broken
-				return
+	switch m := req.Message.(type) {
+	case *livekit.SignalRequest_Offer:
+		pLogger.Debugw("received offer", "offer", m)
+	case *livekit.SignalRequest_Answer:
+		pLogger.Debugw("received answer", "answer", m)
+	case *livekit.SignalRequest_AddTrack:
+		if s.roomHooks != nil {
+			if err := s.roomHooks.BeforeTrackPublish(ctx, roomName, identity, m.AddTrack); err != nil {
+				rejectTrack(m.AddTrack.Cid, livekit.RequestResponse_NOT_ALLOWED, err)
+				return true
 			}
 		}
+		if err := quotaSession.AddTrack(m.AddTrack); err != nil {
+			rejectTrack(m.AddTrack.Cid, livekit.RequestResponse_LIMIT_EXCEEDED, err)
+			return true
+		}
+	}
+
+	if err := cr.RequestSink.WriteMessage(req); err != nil {
+		pLogger.Warnw("error writing to request sink", err, "connID", cr.ConnectionID)
+		if m, ok := req.Message.(*livekit.SignalRequest_AddTrack); ok {
+			quotaSession.ReleaseTrack(m.AddTrack.Cid)
+		}
+		if errors.Is(err, psrpc.ErrStreamClosed) {
+			// disconnect the participant WS since the signal proxy has been broken
+			return false
+		}
 	}
+	return true
 }
 
 func (s *RTCService) ParseClientInfo(r *http.Request) *livekit.ClientInfo {
@@ -483,6 +613,11 @@ func (s *RTCService) ParseClientInfo(r *http.Request) *livekit.ClientInfo {
 	return ci
 }
 
+// Drain rejects sessions creating rooms when clients are redirected off draining nodes
+func (s *RTCService) Drain() {
+	s.draining.Store(true)
+}
+
 func (s *RTCService) DrainConnections(interval time.Duration) {
 	s.mu.Lock()
 	conns := maps.Clone(s.connections)
@@ -521,14 +656,29 @@ func (s *RTCService) startConnection(
 		}
 	}
 
-	if err := s.roomAllocator.SelectRoomNode(ctx, roomName, ""); err != nil {
-		return cr, nil, err
-	}
+	configName := pi.CreateRoom.GetConfigName()
+	if s.canRelayLocally(configName) {
+		// with relay enabled, the room can span nodes. serve the participant from
+		// this node and relay tracks to and from the node hosting the room
+		err = s.roomAllocator.SelectRoomNode(ctx, roomName, livekit.NodeID(s.currentNode.Id), configName)
+		if err != nil && !errors.Is(err, routing.ErrNodeLimitReached) {
+			return cr, nil, err
+		}
 
-	// this needs to be started first *before* using router functions on this node
-	cr.StartParticipantSignalResults, err = s.router.StartParticipantSignal(ctx, roomName, pi)
-	if err != nil {
-		return cr, nil, err
+		cr.StartParticipantSignalResults, err = s.router.(localSignalStarter).StartParticipantSignalWithNodeID(ctx, roomName, pi, livekit.NodeID(s.currentNode.Id))
+		if err != nil {
+			return cr, nil, err
+		}
+	} else {
+		if err := s.roomAllocator.SelectRoomNode(ctx, roomName, "", configName); err != nil {
+			return cr, nil, err
+		}
+
+		// this needs to be started first *before* using router functions on this node
+		cr.StartParticipantSignalResults, err = s.router.StartParticipantSignal(ctx, roomName, pi)
+		if err != nil {
+			return cr, nil, err
+		}
 	}
 
 	// wait for the first message before upgrading to websocket. If no one is
@@ -545,6 +695,24 @@ func (s *RTCService) startConnection(
 	return cr, initialResponse, nil
 }
 
+type localSignalStarter interface {
+	StartParticipantSignalWithNodeID(ctx context.Context, roomName livekit.RoomName, pi routing.ParticipantInit, nodeID livekit.NodeID) (routing.StartParticipantSignalResults, error)
+}
+
+// canRelayLocally returns true when participants can join on this node regardless of where the room is hosted
+func (s *RTCService) canRelayLocally(configName string) bool {
+	if !s.config.Relay.Enabled {
+		return false
+	}
+	if !selector.NodeLabels(s.config.NodeLabels).Matches(s.config.Reloadable().Room.PlacementConstraints[configName]) {
+		return false
+	}
+	if _, ok := s.router.(localSignalStarter); !ok {
+		return false
+	}
+	return selector.IsAvailable(s.currentNode) && !selector.LimitsReached(s.config.Reloadable().Limit, s.currentNode.Stats)
+}
+
 func readInitialResponse(source routing.MessageSource, timeout time.Duration) (*livekit.SignalResponse, error) {
 	responseTimer := time.NewTimer(timeout)
 	defer responseTimer.Stop()
@@ -29,6 +29,7 @@ import (
 
 	"github.com/pion/turn/v2"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
 	"github.com/rs/cors"
 	"github.com/twitchtv/twirp"
 	"github.com/urfave/negroni/v3"
@@ -41,27 +42,40 @@ import (
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
 )
 
 type LivekitServer struct {
 	config       *config.Config
+	roomService  *RoomService
 	ioService    *IOInfoService
 	rtcService   *RTCService
 	agentService *AgentService
 	httpServer   *http.Server
+	socketServer *http.Server
 	promServer   *http.Server
 	router       routing.Router
+	failover     *routing.FailoverMonitor
 	roomManager  *RoomManager
 	signalServer *SignalServer
+	quotas       *QuotaManager
+	usage        *UsageMeter
+	throttler    *BandwidthThrottler
+	snapshots    *SnapshotService
+	soak         *SoakMonitor
 	turnServer   *turn.Server
+	certs        *TLSCertificates
 	currentNode  routing.LocalNode
+	health       *HealthChecker
+	webhooks     *WebhookNotifier
 	running      atomic.Bool
+	draining     atomic.Bool
 	doneChan     chan struct{}
 	closedChan   chan struct{}
 }
 
 func NewLivekitServer(conf *config.Config,
-	roomService livekit.RoomService,
+	roomService *RoomService,
 	agentDispatchService *AgentDispatchService,
 	egressService *EgressService,
 	ingressService *IngressService,
@@ -71,24 +85,48 @@ func NewLivekitServer(conf *config.Config,
 	agentService *AgentService,
 	keyProvider auth.KeyProvider,
 	router routing.Router,
+	failover *routing.FailoverMonitor,
+	rc redis.UniversalClient,
+	kps rpc.KeepalivePubSub,
 	roomManager *RoomManager,
 	signalServer *SignalServer,
+	quotas *QuotaManager,
+	projects *ProjectScope,
+	usage *UsageMeter,
 	turnServer *turn.Server,
+	certs *TLSCertificates,
 	currentNode routing.LocalNode,
+	webhooks *WebhookNotifier,
+	snapshots *SnapshotService,
 ) (s *LivekitServer, err error) {
+	soak, err := NewSoakMonitor(conf, roomManager, snapshots)
+	if err != nil {
+		return nil, err
+	}
+
 	s = &LivekitServer{
 		config:       conf,
+		roomService:  roomService,
 		ioService:    ioService,
 		rtcService:   rtcService,
 		agentService: agentService,
 		router:       router,
+		failover:     failover,
 		roomManager:  roomManager,
 		signalServer: signalServer,
+		quotas:       quotas,
+		usage:        usage,
+		throttler:    NewBandwidthThrottler(conf, roomManager, quotas, projects),
+		snapshots:    snapshots,
+		soak:         soak,
 		// turn server starts automatically
 		turnServer:  turnServer,
+		certs:       certs,
 		currentNode: currentNode,
+		webhooks:    webhooks,
 		closedChan:  make(chan struct{}),
 	}
+	s.health = NewHealthChecker(conf, currentNode, router, rc, failover, kps, ioService, s.IsDraining)
 
 	middlewares := []negroni.Handler{
 		// always first
@@ -106,19 +144,29 @@ func NewLivekitServer(conf *config.Config,
 	if keyProvider != nil {
 		middlewares = append(middlewares, NewAPIKeyAuthMiddleware(keyProvider))
 	}
+	console := NewConsole(conf.Console, keyProvider, router)
+	if console != nil {
+		middlewares = append(middlewares, negroni.HandlerFunc(console.Authenticate))
+	}
+	var twirpInterceptors []twirp.Interceptor
+	if projects != nil {
+		middlewares = append(middlewares, projects)
+		twirpInterceptors = append(twirpInterceptors, projects.Interceptor())
+	}
 
 	twirpLoggingHook := TwirpLogger()
 	twirpRequestStatusHook := TwirpRequestStatusReporter()
-	roomServer := livekit.NewRoomServiceServer(roomService, twirpLoggingHook)
-	agentDispatchServer := livekit.NewAgentDispatchServiceServer(agentDispatchService, twirpLoggingHook)
+	twirpProjectScope := twirp.WithServerInterceptors(twirpInterceptors...)
+	roomServer := livekit.NewRoomServiceServer(roomService, twirpLoggingHook, twirpProjectScope)
+	agentDispatchServer := livekit.NewAgentDispatchServiceServer(agentDispatchService, twirpLoggingHook, twirpProjectScope)
 	egressServer := livekit.NewEgressServer(egressService, twirp.WithServerHooks(
 		twirp.ChainHooks(
 			twirpLoggingHook,
 			twirpRequestStatusHook,
 		),
-	))
-	ingressServer := livekit.NewIngressServer(ingressService, twirpLoggingHook)
-	sipServer := livekit.NewSIPServer(sipService, twirpLoggingHook)
+	), twirpProjectScope)
+	ingressServer := livekit.NewIngressServer(ingressService, twirpLoggingHook, twirpProjectScope)
+	sipServer := livekit.NewSIPServer(sipService, twirpLoggingHook, twirpProjectScope)
 
 	mux := http.NewServeMux()
 	if conf.Development {
@@ -128,19 +176,61 @@ func NewLivekitServer(conf *config.Config,
 		mux.HandleFunc("/debug/rooms", s.debugInfo)
 	}
 
-	mux.Handle(roomServer.PathPrefix(), roomServer)
+	mux.Handle(roomServer.PathPrefix(), withRoomConfigHeader(withRemoveReasonHeader(roomServer)))
 	mux.Handle(agentDispatchServer.PathPrefix(), agentDispatchServer)
 	mux.Handle(egressServer.PathPrefix(), egressServer)
 	mux.Handle(ingressServer.PathPrefix(), ingressServer)
 	mux.Handle(sipServer.PathPrefix(), sipServer)
 	mux.Handle("/rtc", rtcService)
 	mux.Handle("/agent", agentService)
+	mux.Handle(agentJobResultPath, NewAgentJobResultHandler(roomManager))
 	mux.HandleFunc("/rtc/validate", rtcService.Validate)
+	mux.Handle("/quota", quotas)
+	mux.Handle(joinURLPath, JoinURLHandler(conf.JoinURL, keyProvider))
+	if console != nil {
+		mux.Handle(consolePath, console)
+	}
+	mux.Handle("/snapshot", s.snapshots)
+	mux.Handle(recordingPath, NewRecordingHandler(roomManager))
+	mux.Handle(roomStatePath, NewRoomStateHandler(roomManager))
+	mux.Handle(ephemeralDataPath, NewEphemeralDataHandler(roomManager))
+	mux.Handle(dataUsagePath, NewDataUsageHandler(roomManager))
+	mux.Handle(loudnessPath, NewLoudnessHandler(roomManager))
+	mux.Handle(subscriptionPermissionPath, NewSubscriptionPermissionHandler(roomManager))
+	mux.Handle(sipDTMFPath, NewSIPDTMFHandler(roomManager))
+	sipCalls := NewSIPCallHandler(roomManager)
+	mux.Handle(sipTransferPath, sipCalls)
+	mux.Handle(sipHoldPath, sipCalls)
+	mux.Handle(sipResumePath, sipCalls)
+	mux.Handle(featureFlagsPath, NewFeatureFlagsHandler(roomManager, conf.OperatorKeys))
+	mux.HandleFunc(readinessPath, s.readinessCheck)
+	mux.Handle(healthPath, s.health)
+	mux.HandleFunc(drainPath, s.drainHandler)
+	if conf.HLS.Enabled {
+		mux.Handle(hlsPathPrefix, NewHLSHandler(conf.HLS))
+	}
+	if conf.Chaos.Enabled {
+		logger.Warnw("chaos endpoints enabled, do not use in production", nil)
+		if len(conf.OperatorKeys) == 0 {
+			logger.Warnw("chaos endpoints reject every request without operator_keys", nil)
+		}
+		mux.Handle(chaosPath, NewChaosHandler(roomManager, conf.OperatorKeys))
+	}
+	if conf.Soak.Interval > 0 {
+		mux.Handle(soakPath, s.soak)
+	}
 	mux.HandleFunc("/", s.defaultHandler)
 
 	s.httpServer = &http.Server{
 		Handler: configureMiddlewares(mux, middlewares...),
 	}
+	// certificates are served by TLSConfig.GetCertificate
+	s.httpServer.TLSConfig = certs.APIConfig()
+	if conf.UnixSocket.Path != "" {
+		s.socketServer = &http.Server{
+			Handler: trustSocketRequests(s.httpServer.Handler),
+		}
+	}
 
 	if conf.PrometheusPort > 0 {
 		logger.Warnw("prometheus_port is deprecated, please switch prometheus.port instead", nil)
@@ -230,6 +320,15 @@ func (s *LivekitServer) Start() error {
 		}
 	}
 
+	var socketListener net.Listener
+	if s.socketServer != nil {
+		ln, err := listenUnixSocket(s.config.UnixSocket)
+		if err != nil {
+			return err
+		}
+		socketListener = ln
+	}
+
 	values := []interface{}{
 		"portHttp", s.config.Port,
 		"nodeID", s.currentNode.Id,
@@ -255,6 +354,9 @@ func (s *LivekitServer) Start() error {
 	if s.config.Region != "" {
 		values = append(values, "region", s.config.Region)
 	}
+	if socketListener != nil {
+		values = append(values, "unixSocket", s.config.UnixSocket.Path)
+	}
 	logger.Infow("starting LiveKit server", values...)
 	if runtime.GOOS == "windows" {
 		logger.Infow("Windows detected, capacity management is unavailable")
@@ -272,9 +374,17 @@ func (s *LivekitServer) Start() error {
 	for _, ln := range listeners {
 		l := ln
 		httpGroup.Go(func() error {
+			if s.httpServer.TLSConfig != nil {
+				return s.httpServer.ServeTLS(l, "", "")
+			}
 			return s.httpServer.Serve(l)
 		})
 	}
+	if socketListener != nil {
+		httpGroup.Go(func() error {
+			return s.socketServer.Serve(socketListener)
+		})
+	}
 	go func() {
 		if err := httpGroup.Wait(); err != http.ErrServerClosed {
 			logger.Errorw("could not start server", err)
@@ -295,14 +405,23 @@ func (s *LivekitServer) Start() error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
 	_ = s.httpServer.Shutdown(ctx)
+	if s.socketServer != nil {
+		_ = s.socketServer.Shutdown(ctx)
+	}
 
 	if s.turnServer != nil {
 		_ = s.turnServer.Close()
 	}
+	s.certs.Stop()
 
 	s.roomManager.Stop()
 	s.signalServer.Stop()
 	s.ioService.Stop()
+	s.quotas.Stop()
+	s.usage.Stop()
+	s.throttler.Stop()
+	s.snapshots.Stop()
+	s.soak.Stop()
 
 	close(s.closedChan)
 	return nil
@@ -310,7 +429,7 @@ func (s *LivekitServer) Start() error {
 
 func (s *LivekitServer) Stop(force bool) {
 	// wait for all participants to exit
-	s.router.Drain()
+	s.Drain()
 	partTicker := time.NewTicker(5 * time.Second)
 	waitingForParticipants := !force && s.roomManager.HasParticipants()
 	for waitingForParticipants {
@@ -331,6 +450,39 @@ func (s *LivekitServer) Stop(force bool) {
 	<-s.closedChan
 }
 
+// ReloadConfig applies the reloadable settings of next at runtime, without dropping connections.
+// Changed settings that are not reloadable are reported and take effect after a restart
+func (s *LivekitServer) ReloadConfig(next *config.Config) (*config.ReloadReport, error) {
+	// validate before applying anything, webhooks are the only reloadable settings that can be rejected
+	if err := s.webhooks.Validate(next.WebHook); err != nil {
+		return nil, err
+	}
+
+	report, err := s.config.Reload(next)
+	if report == nil {
+		return nil, err
+	}
+	// settings copied by components are updated for the keys that were applied, even when a later key failed
+	reloadable := s.config.Reloadable()
+	if report.IsApplied("limit") {
+		s.roomService.UpdateLimitConfig(reloadable.Limit)
+	}
+	if report.IsApplied("feature_flags") {
+		s.roomManager.FeatureFlags().UpdateConfig(reloadable.FeatureFlags)
+	}
+	if report.IsApplied("webhook") {
+		if updateErr := s.webhooks.Update(reloadable.WebHook); updateErr != nil && err == nil {
+			err = updateErr
+		}
+	}
+	return report, err
+}
+
+// ReloadCertificates reloads TLS certificates whose files have changed, for changes the file watcher did not observe
+func (s *LivekitServer) ReloadCertificates() {
+	s.certs.Reload()
+}
+
 func (s *LivekitServer) RoomManager() *RoomManager {
 	return s.roomManager
 }
@@ -5,6 +5,7 @@ import (
 	"context"
 	"sync"
 
+	"github.com/livekit/livekit-server/pkg/agent"
 	"github.com/livekit/livekit-server/pkg/service"
 	"github.com/livekit/protocol/livekit"
 )
@@ -72,6 +73,19 @@ type FakeAgentStore struct {
 	storeAgentJobReturnsOnCall map[int]struct {
 		result1 error
 	}
+	StoreAgentJobResultStub        func(context.Context, livekit.RoomName, *agent.JobResult) error
+	storeAgentJobResultMutex       sync.RWMutex
+	storeAgentJobResultArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 *agent.JobResult
+	}
+	storeAgentJobResultReturns struct {
+		result1 error
+	}
+	storeAgentJobResultReturnsOnCall map[int]struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -389,6 +403,69 @@ func (fake *FakeAgentStore) StoreAgentJobReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeAgentStore) StoreAgentJobResult(arg1 context.Context, arg2 livekit.RoomName, arg3 *agent.JobResult) error {
+	fake.storeAgentJobResultMutex.Lock()
+	ret, specificReturn := fake.storeAgentJobResultReturnsOnCall[len(fake.storeAgentJobResultArgsForCall)]
+	fake.storeAgentJobResultArgsForCall = append(fake.storeAgentJobResultArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 *agent.JobResult
+	}{arg1, arg2, arg3})
+	stub := fake.StoreAgentJobResultStub
+	fakeReturns := fake.storeAgentJobResultReturns
+	fake.recordInvocation("StoreAgentJobResult", []interface{}{arg1, arg2, arg3})
+	fake.storeAgentJobResultMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeAgentStore) StoreAgentJobResultCallCount() int {
+	fake.storeAgentJobResultMutex.RLock()
+	defer fake.storeAgentJobResultMutex.RUnlock()
+	return len(fake.storeAgentJobResultArgsForCall)
+}
+
+func (fake *FakeAgentStore) StoreAgentJobResultCalls(stub func(context.Context, livekit.RoomName, *agent.JobResult) error) {
+	fake.storeAgentJobResultMutex.Lock()
+	defer fake.storeAgentJobResultMutex.Unlock()
+	fake.StoreAgentJobResultStub = stub
+}
+
+func (fake *FakeAgentStore) StoreAgentJobResultArgsForCall(i int) (context.Context, livekit.RoomName, *agent.JobResult) {
+	fake.storeAgentJobResultMutex.RLock()
+	defer fake.storeAgentJobResultMutex.RUnlock()
+	argsForCall := fake.storeAgentJobResultArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeAgentStore) StoreAgentJobResultReturns(result1 error) {
+	fake.storeAgentJobResultMutex.Lock()
+	defer fake.storeAgentJobResultMutex.Unlock()
+	fake.StoreAgentJobResultStub = nil
+	fake.storeAgentJobResultReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAgentStore) StoreAgentJobResultReturnsOnCall(i int, result1 error) {
+	fake.storeAgentJobResultMutex.Lock()
+	defer fake.storeAgentJobResultMutex.Unlock()
+	fake.StoreAgentJobResultStub = nil
+	if fake.storeAgentJobResultReturnsOnCall == nil {
+		fake.storeAgentJobResultReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeAgentJobResultReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeAgentStore) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -402,6 +479,8 @@ func (fake *FakeAgentStore) Invocations() map[string][][]interface{} {
 	defer fake.storeAgentDispatchMutex.RUnlock()
 	fake.storeAgentJobMutex.RLock()
 	defer fake.storeAgentJobMutex.RUnlock()
+	fake.storeAgentJobResultMutex.RLock()
+	defer fake.storeAgentJobResultMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -39,12 +39,13 @@ type FakeRoomAllocator struct {
 	createRoomEnabledReturnsOnCall map[int]struct {
 		result1 bool
 	}
-	SelectRoomNodeStub        func(context.Context, livekit.RoomName, livekit.NodeID) error
+	SelectRoomNodeStub        func(context.Context, livekit.RoomName, livekit.NodeID, string) error
 	selectRoomNodeMutex       sync.RWMutex
 	selectRoomNodeArgsForCall []struct {
 		arg1 context.Context
 		arg2 livekit.RoomName
 		arg3 livekit.NodeID
+		arg4 string
 	}
 	selectRoomNodeReturns struct {
 		result1 error
@@ -193,20 +194,21 @@ func (fake *FakeRoomAllocator) CreateRoomEnabledReturnsOnCall(i int, result1 boo
 	}{result1}
 }
 
-func (fake *FakeRoomAllocator) SelectRoomNode(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.NodeID) error {
+func (fake *FakeRoomAllocator) SelectRoomNode(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.NodeID, arg4 string) error {
 	fake.selectRoomNodeMutex.Lock()
 	ret, specificReturn := fake.selectRoomNodeReturnsOnCall[len(fake.selectRoomNodeArgsForCall)]
 	fake.selectRoomNodeArgsForCall = append(fake.selectRoomNodeArgsForCall, struct {
 		arg1 context.Context
 		arg2 livekit.RoomName
 		arg3 livekit.NodeID
-	}{arg1, arg2, arg3})
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
 	stub := fake.SelectRoomNodeStub
 	fakeReturns := fake.selectRoomNodeReturns
-	fake.recordInvocation("SelectRoomNode", []interface{}{arg1, arg2, arg3})
+	fake.recordInvocation("SelectRoomNode", []interface{}{arg1, arg2, arg3, arg4})
 	fake.selectRoomNodeMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2, arg3)
+		return stub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
 		return ret.result1
@@ -220,17 +222,17 @@ func (fake *FakeRoomAllocator) SelectRoomNodeCallCount() int {
 	return len(fake.selectRoomNodeArgsForCall)
 }
 
-func (fake *FakeRoomAllocator) SelectRoomNodeCalls(stub func(context.Context, livekit.RoomName, livekit.NodeID) error) {
+func (fake *FakeRoomAllocator) SelectRoomNodeCalls(stub func(context.Context, livekit.RoomName, livekit.NodeID, string) error) {
 	fake.selectRoomNodeMutex.Lock()
 	defer fake.selectRoomNodeMutex.Unlock()
 	fake.SelectRoomNodeStub = stub
 }
 
-func (fake *FakeRoomAllocator) SelectRoomNodeArgsForCall(i int) (context.Context, livekit.RoomName, livekit.NodeID) {
+func (fake *FakeRoomAllocator) SelectRoomNodeArgsForCall(i int) (context.Context, livekit.RoomName, livekit.NodeID, string) {
 	fake.selectRoomNodeMutex.RLock()
 	defer fake.selectRoomNodeMutex.RUnlock()
 	argsForCall := fake.selectRoomNodeArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *FakeRoomAllocator) SelectRoomNodeReturns(result1 error) {
@@ -0,0 +1,56 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// signalCloseMessage is the websocket close frame ending a signal connection. Connections ended by a server
+// shutdown are closed with 1012 (service restart) when the client was asked to reconnect, or 1001 (going away).
+// The reason carries the retry-after hint in seconds
+func signalCloseMessage(leave *livekit.LeaveRequest, retryAfter time.Duration) []byte {
+	if leave.GetReason() != livekit.DisconnectReason_SERVER_SHUTDOWN {
+		return websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	}
+
+	code := websocket.CloseGoingAway
+	if leave.GetAction() == livekit.LeaveRequest_RECONNECT || leave.GetCanReconnect() {
+		code = websocket.CloseServiceRestart
+	}
+	var reason string
+	if retryAfter > 0 {
+		reason = fmt.Sprintf("retry-after=%d", retryAfterSeconds(retryAfter))
+	}
+	return websocket.FormatCloseMessage(code, reason)
+}
+
+func setRetryAfter(w http.ResponseWriter, retryAfter time.Duration) {
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(retryAfter)))
+	}
+}
+
+func retryAfterSeconds(retryAfter time.Duration) int {
+	return int(math.Ceil(retryAfter.Seconds()))
+}
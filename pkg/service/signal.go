@@ -57,11 +57,12 @@ func NewSignalServer(
 	region string,
 	bus psrpc.MessageBus,
 	config config.SignalRelayConfig,
+	failover *routing.FailoverMonitor,
 	sessionHandler SessionHandler,
 ) (*SignalServer, error) {
 	s, err := rpc.NewTypedSignalServer(
 		nodeID,
-		&signalService{region, sessionHandler, config},
+		&signalService{region, sessionHandler, config, failover},
 		bus,
 		middleware.WithServerMetrics(rpc.PSRPCMetricsObserver{}),
 		psrpc.WithServerChannelSize(config.StreamBufferSize),
@@ -76,10 +77,11 @@ func NewDefaultSignalServer(
 	currentNode routing.LocalNode,
 	bus psrpc.MessageBus,
 	config config.SignalRelayConfig,
+	failover *routing.FailoverMonitor,
 	router routing.Router,
 	roomManager *RoomManager,
 ) (r *SignalServer, err error) {
-	return NewSignalServer(livekit.NodeID(currentNode.Id), currentNode.Region, bus, config, &defaultSessionHandler{currentNode, router, roomManager})
+	return NewSignalServer(livekit.NodeID(currentNode.Id), currentNode.Region, bus, config, failover, &defaultSessionHandler{currentNode, router, roomManager})
 }
 
 type defaultSessionHandler struct {
@@ -131,6 +133,7 @@ type signalService struct {
 	region         string
 	sessionHandler SessionHandler
 	config         config.SignalRelayConfig
+	failover       *routing.FailoverMonitor
 }
 
 func (r *signalService) RelaySignal(stream psrpc.ServerStream[*rpc.RelaySignalResponse, *rpc.RelaySignalRequest]) (err error) {
@@ -160,6 +163,7 @@ func (r *signalService) RelaySignal(stream psrpc.ServerStream[*rpc.RelaySignalRe
 		Logger:       l,
 		Stream:       stream,
 		Config:       r.config,
+		Failover:     r.failover,
 		Writer:       signalResponseMessageWriter{},
 		ConnectionID: livekit.ConnectionID(ss.ConnectionId),
 	})
@@ -60,7 +60,7 @@ func TestSignal(t *testing.T) {
 		var resErr error
 		done := make(chan struct{})
 
-		client, err := routing.NewSignalClient(livekit.NodeID("node0"), bus, cfg)
+		client, err := routing.NewSignalClient(livekit.NodeID("node0"), bus, cfg, nil)
 		require.NoError(t, err)
 
 		handler := &servicefakes.FakeSessionHandler{
@@ -82,7 +82,7 @@ func TestSignal(t *testing.T) {
 				return nil
 			},
 		}
-		server, err := service.NewSignalServer(livekit.NodeID("node1"), "region", bus, cfg, handler)
+		server, err := service.NewSignalServer(livekit.NodeID("node1"), "region", bus, cfg, nil, handler)
 		require.NoError(t, err)
 
 		err = server.Start()
@@ -117,7 +117,7 @@ func TestSignal(t *testing.T) {
 		var resErr error
 		done := make(chan struct{})
 
-		client, err := routing.NewSignalClient(livekit.NodeID("node0"), bus, cfg)
+		client, err := routing.NewSignalClient(livekit.NodeID("node0"), bus, cfg, nil)
 		require.NoError(t, err)
 
 		handler := &servicefakes.FakeSessionHandler{
@@ -135,7 +135,7 @@ func TestSignal(t *testing.T) {
 				return errors.New("start session failed")
 			},
 		}
-		server, err := service.NewSignalServer(livekit.NodeID("node1"), "region", bus, cfg, handler)
+		server, err := service.NewSignalServer(livekit.NodeID("node1"), "region", bus, cfg, nil, handler)
 		require.NoError(t, err)
 
 		err = server.Start()
@@ -0,0 +1,240 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	sipTransferPath = "/sip/transfer"
+	sipHoldPath     = "/sip/hold"
+	sipResumePath   = "/sip/resume"
+
+	// SIPControlTopic is the data topic the SIP bridge listens on for call control requests
+	SIPControlTopic = "lk.sip.control"
+
+	// AttrSIPCallStatus reflects the state of the call, so the room sees hold and transfer as participant updates.
+	// It is set by the SIP bridge once it has confirmed a change, not when a request is sent.
+	AttrSIPCallStatus = livekit.AttrSIPPrefix + "callStatus"
+
+	SIPCallStatusActive       = "active"
+	SIPCallStatusOnHold       = "on_hold"
+	SIPCallStatusTransferring = "transferring"
+
+	sipCallMethod = "SIPCallControl"
+)
+
+type SIPCallAction string
+
+const (
+	SIPCallActionTransfer SIPCallAction = "transfer"
+	SIPCallActionHold     SIPCallAction = "hold"
+	SIPCallActionResume   SIPCallAction = "resume"
+)
+
+// SIPCallControl is delivered to the SIP participant on SIPControlTopic.
+// A transfer is sent as a REFER to TransferTo. For attended transfers, ReplacesCallID is the call
+// the transferee is connected to instead, and TransferTo is that call's remote party.
+type SIPCallControl struct {
+	Action         SIPCallAction `json:"action"`
+	TransferTo     string        `json:"transfer_to,omitempty"`
+	ReplacesCallID string        `json:"replaces_call_id,omitempty"`
+}
+
+type sipCallRequest struct {
+	Action     SIPCallAction               `json:"action"`
+	TransferTo string                      `json:"transfer_to,omitempty"`
+	Replaces   livekit.ParticipantIdentity `json:"replaces,omitempty"`
+}
+
+// SIPCallHandler manages calls of SIP participants, through the node the participant is connected to
+type SIPCallHandler struct {
+	roomAPI *RoomAPI
+}
+
+func NewSIPCallHandler(roomManager *RoomManager) *SIPCallHandler {
+	h := &SIPCallHandler{
+		roomAPI: roomManager.RoomAPI(),
+	}
+	h.roomAPI.Handle(sipCallMethod, h.handleCallControl)
+	return h
+}
+
+// Transfer makes a blind transfer of the call to transferTo, or an attended transfer to the call of
+// the SIP participant identified by replaces, which must be in the same room.
+func (h *SIPCallHandler) Transfer(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, transferTo string, replaces livekit.ParticipantIdentity) error {
+	if replaces == "" && !isSIPTransferTarget(transferTo) {
+		return ErrInvalidSIPTransferTarget
+	}
+	return h.roomAPI.CallParticipant(ctx, roomName, identity, sipCallMethod, &sipCallRequest{
+		Action:     SIPCallActionTransfer,
+		TransferTo: transferTo,
+		Replaces:   replaces,
+	}, nil)
+}
+
+func (h *SIPCallHandler) Hold(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) error {
+	return h.roomAPI.CallParticipant(ctx, roomName, identity, sipCallMethod, &sipCallRequest{Action: SIPCallActionHold}, nil)
+}
+
+func (h *SIPCallHandler) Resume(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) error {
+	return h.roomAPI.CallParticipant(ctx, roomName, identity, sipCallMethod, &sipCallRequest{Action: SIPCallActionResume}, nil)
+}
+
+func (h *SIPCallHandler) handleCallControl(_ context.Context, room *rtc.Room, p types.LocalParticipant, body json.RawMessage) (any, error) {
+	var req sipCallRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+	if p.Kind() != livekit.ParticipantInfo_SIP {
+		return nil, ErrNotSIPParticipant
+	}
+
+	status := p.ToProto().Attributes[AttrSIPCallStatus]
+	switch req.Action {
+	case SIPCallActionTransfer:
+		if status == SIPCallStatusTransferring {
+			return nil, ErrSIPTransferInProgress
+		}
+		control, err := sipTransferControl(room, req.TransferTo, req.Replaces)
+		if err != nil {
+			return nil, err
+		}
+		return nil, sendSIPCallControl(room, p, control)
+	case SIPCallActionHold:
+		if status == SIPCallStatusOnHold {
+			return nil, nil
+		}
+		return nil, sendSIPCallControl(room, p, &SIPCallControl{Action: SIPCallActionHold})
+	case SIPCallActionResume:
+		if status != SIPCallStatusOnHold {
+			return nil, ErrSIPCallNotOnHold
+		}
+		return nil, sendSIPCallControl(room, p, &SIPCallControl{Action: SIPCallActionResume})
+	default:
+		return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "unknown sip call action %s", req.Action)
+	}
+}
+
+func sipTransferControl(room *rtc.Room, transferTo string, replaces livekit.ParticipantIdentity) (*SIPCallControl, error) {
+	req := &SIPCallControl{Action: SIPCallActionTransfer}
+	if replaces != "" {
+		consult := room.GetParticipant(replaces)
+		if consult == nil {
+			return nil, ErrParticipantNotFound
+		}
+		attrs := consult.ToProto().Attributes
+		if consult.Kind() != livekit.ParticipantInfo_SIP || attrs[livekit.AttrSIPCallID] == "" {
+			return nil, ErrNotSIPParticipant
+		}
+		req.ReplacesCallID = attrs[livekit.AttrSIPCallID]
+		if transferTo == "" {
+			transferTo = attrs[livekit.AttrSIPPhoneNumber]
+		}
+	}
+	if !isSIPTransferTarget(transferTo) {
+		return nil, ErrInvalidSIPTransferTarget
+	}
+	req.TransferTo = transferTo
+	return req, nil
+}
+
+// ServeHTTP handles POST /sip/transfer?room=&identity=&to=&replaces=, /sip/hold and /sip/resume,
+// the token must have roomAdmin permission for the room
+func (h *SIPCallHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	roomName := livekit.RoomName(query.Get("room"))
+	identity := livekit.ParticipantIdentity(query.Get("identity"))
+	if err := EnsureAdminPermission(r.Context(), roomName); err != nil {
+		handleError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	var err error
+	switch r.URL.Path {
+	case sipTransferPath:
+		err = h.Transfer(r.Context(), roomName, identity, query.Get("to"), livekit.ParticipantIdentity(query.Get("replaces")))
+	case sipHoldPath:
+		err = h.Hold(r.Context(), roomName, identity)
+	case sipResumePath:
+		err = h.Resume(r.Context(), roomName, identity)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		handleSIPError(w, r, err, "room", roomName, "participant", identity)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func sendSIPCallControl(room *rtc.Room, p types.LocalParticipant, req *SIPCallControl) error {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	room.Logger.Infow("api sip call control", "participant", p.Identity(), "action", req.Action)
+	room.SendDataPacket(&livekit.DataPacket{
+		Kind:                  livekit.DataPacket_RELIABLE,
+		DestinationIdentities: []string{string(p.Identity())},
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload:               payload,
+				DestinationIdentities: []string{string(p.Identity())},
+				Topic:                 proto.String(SIPControlTopic),
+			},
+		},
+	}, livekit.DataPacket_RELIABLE)
+	return nil
+}
+
+func handleSIPError(w http.ResponseWriter, r *http.Request, err error, keysAndValues ...interface{}) {
+	// errors from the participant's node only keep their code
+	status := roomAPIStatus(err)
+	var psrpcErr psrpc.Error
+	if errors.As(err, &psrpcErr) && psrpcErr.Code() == psrpc.FailedPrecondition {
+		status = http.StatusConflict
+	}
+	handleError(w, r, status, err, keysAndValues...)
+}
+
+func isSIPTransferTarget(to string) bool {
+	for _, scheme := range []string{"sip:", "sips:", "tel:"} {
+		if len(to) > len(scheme) && strings.HasPrefix(strings.ToLower(to), scheme) {
+			return true
+		}
+	}
+	return false
+}
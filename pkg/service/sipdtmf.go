@@ -0,0 +1,136 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	sipDTMFPath = "/sip/dtmf"
+
+	sipDTMFMethod = "SIPSendDTMF"
+)
+
+type sipDTMFRequest struct {
+	Digits string `json:"digits"`
+}
+
+// SIPDTMFHandler sends DTMF tones toward the SIP leg of a participant, through the node the participant is connected to.
+// The SIP bridge receives them as data packets and plays them out on the call.
+type SIPDTMFHandler struct {
+	roomAPI *RoomAPI
+}
+
+func NewSIPDTMFHandler(roomManager *RoomManager) *SIPDTMFHandler {
+	h := &SIPDTMFHandler{
+		roomAPI: roomManager.RoomAPI(),
+	}
+	h.roomAPI.Handle(sipDTMFMethod, h.handleSendDTMF)
+	return h
+}
+
+func (h *SIPDTMFHandler) SendDTMF(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, digits string) error {
+	if _, err := sipDTMFTones(digits); err != nil {
+		return err
+	}
+	return h.roomAPI.CallParticipant(ctx, roomName, identity, sipDTMFMethod, &sipDTMFRequest{Digits: digits}, nil)
+}
+
+func (h *SIPDTMFHandler) handleSendDTMF(_ context.Context, room *rtc.Room, p types.LocalParticipant, body json.RawMessage) (any, error) {
+	var req sipDTMFRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+	tones, err := sipDTMFTones(req.Digits)
+	if err != nil {
+		return nil, err
+	}
+	if p.Kind() != livekit.ParticipantInfo_SIP {
+		return nil, ErrNotSIPParticipant
+	}
+
+	room.Logger.Debugw("api send dtmf", "participant", p.Identity(), "digits", len(tones))
+	for _, tone := range tones {
+		room.SendDataPacket(&livekit.DataPacket{
+			Kind:                  livekit.DataPacket_RELIABLE,
+			DestinationIdentities: []string{string(p.Identity())},
+			Value:                 &livekit.DataPacket_SipDtmf{SipDtmf: tone},
+		}, livekit.DataPacket_RELIABLE)
+	}
+	return nil, nil
+}
+
+// ServeHTTP sends ?digits= to the SIP participant ?identity= in ?room=, the token must have roomAdmin permission for the room
+func (h *SIPDTMFHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	roomName := livekit.RoomName(query.Get("room"))
+	identity := livekit.ParticipantIdentity(query.Get("identity"))
+	if err := EnsureAdminPermission(r.Context(), roomName); err != nil {
+		handleError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	if err := h.SendDTMF(r.Context(), roomName, identity, strings.ToUpper(query.Get("digits"))); err != nil {
+		handleSIPError(w, r, err, "room", roomName, "participant", identity)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func sipDTMFTones(digits string) ([]*livekit.SipDTMF, error) {
+	tones := make([]*livekit.SipDTMF, 0, len(digits))
+	for _, d := range digits {
+		code, ok := sipDTMFCode(d)
+		if !ok {
+			return nil, ErrInvalidDTMF
+		}
+		tones = append(tones, &livekit.SipDTMF{Code: code, Digit: string(d)})
+	}
+	if len(tones) == 0 {
+		return nil, ErrInvalidDTMF
+	}
+	return tones, nil
+}
+
+// sipDTMFCode returns the RFC 4733 event code of a DTMF digit
+func sipDTMFCode(d rune) (uint32, bool) {
+	switch {
+	case d >= '0' && d <= '9':
+		return uint32(d - '0'), true
+	case d == '*':
+		return 10, true
+	case d == '#':
+		return 11, true
+	case d >= 'A' && d <= 'D':
+		return uint32(d-'A') + 12, true
+	default:
+		return 0, false
+	}
+}
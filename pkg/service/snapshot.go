@@ -0,0 +1,327 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/frostbyte73/core"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/snapshot"
+	"github.com/livekit/livekit-server/pkg/sfu/videohealth"
+)
+
+type trackSnapshot struct {
+	image      image.Image
+	capturedAt time.Time
+}
+
+// SnapshotService captures still images of video tracks published to this node, on demand or on an interval
+type SnapshotService struct {
+	conf          config.SnapshotConfig
+	roomManager   *RoomManager
+	thumbnailSink ThumbnailSink
+	videoHealth   config.VideoHealthConfig
+
+	lock      sync.Mutex
+	latest    map[livekit.TrackID]*trackSnapshot
+	analyzers map[livekit.TrackID]*videohealth.FrameAnalyzer
+
+	stopped core.Fuse
+}
+
+func NewSnapshotService(conf *config.Config, roomManager *RoomManager) (*SnapshotService, error) {
+	thumbnailSink, err := createThumbnailSink(conf)
+	if err != nil {
+		return nil, err
+	}
+	if thumbnailSink != nil {
+		switch snapshot.Format(conf.Snapshot.Thumbnails.Format) {
+		case snapshot.FormatJPEG, snapshot.FormatPNG:
+		default:
+			return nil, snapshot.ErrUnknownFormat
+		}
+		if conf.Snapshot.Interval <= 0 {
+			logger.Warnw("thumbnails require snapshot interval capture", nil)
+		}
+	}
+
+	s := &SnapshotService{
+		conf:          conf.Snapshot,
+		roomManager:   roomManager,
+		thumbnailSink: thumbnailSink,
+		videoHealth:   conf.Video.Health,
+		latest:        make(map[livekit.TrackID]*trackSnapshot),
+		analyzers:     make(map[livekit.TrackID]*videohealth.FrameAnalyzer),
+	}
+	if s.conf.Interval > 0 {
+		go s.worker()
+	}
+	return s, nil
+}
+
+func (s *SnapshotService) Stop() {
+	s.stopped.Break()
+}
+
+// Capture returns an image of the latest keyframe of a track. With interval capture enabled,
+// the latest periodic snapshot is returned when available.
+func (s *SnapshotService) Capture(ctx context.Context, roomName livekit.RoomName, trackID livekit.TrackID) (image.Image, error) {
+	if s.conf.Interval > 0 {
+		s.lock.Lock()
+		latest := s.latest[trackID]
+		s.lock.Unlock()
+		if latest != nil && time.Since(latest.capturedAt) < 2*s.conf.Interval {
+			return latest.image, nil
+		}
+	}
+
+	room := s.roomManager.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+	receiver := getSnapshotReceiver(room, trackID)
+	if receiver == nil {
+		return nil, ErrTrackNotFound
+	}
+	return s.capture(ctx, receiver, true, s.conf.Timeout)
+}
+
+// capture waits for a keyframe of the track. Keyframes are only requested from the publisher for
+// on demand captures, interval captures wait for the next keyframe the publisher sends anyway.
+func (s *SnapshotService) capture(ctx context.Context, receiver sfu.TrackReceiver, requestKeyFrame bool, timeout time.Duration) (image.Image, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	img, err := snapshot.Capture(ctx, receiver, requestKeyFrame)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.conf.Interval > 0 {
+		s.lock.Lock()
+		s.latest[receiver.TrackID()] = &trackSnapshot{image: img, capturedAt: time.Now()}
+		s.lock.Unlock()
+	}
+	return img, nil
+}
+
+// ServeHTTP returns a snapshot of a track, the token must have roomAdmin permission for the room
+func (s *SnapshotService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	roomName := livekit.RoomName(query.Get("room"))
+	trackID := livekit.TrackID(query.Get("track"))
+	if err := EnsureAdminPermission(r.Context(), roomName); err != nil {
+		handleError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	format := snapshot.Format(strings.ToLower(query.Get("format")))
+	switch format {
+	case "":
+		format = snapshot.FormatJPEG
+	case snapshot.FormatJPEG, snapshot.FormatPNG:
+	default:
+		handleError(w, r, http.StatusBadRequest, snapshot.ErrUnknownFormat)
+		return
+	}
+
+	img, err := s.Capture(r.Context(), roomName, trackID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrRoomNotFound), errors.Is(err, ErrTrackNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, snapshot.ErrUnsupportedCodec):
+			status = http.StatusUnprocessableEntity
+		case errors.Is(err, snapshot.ErrTimeout):
+			status = http.StatusGatewayTimeout
+		}
+		handleError(w, r, status, err, "room", roomName, "trackID", trackID)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := snapshot.Encode(&buf, img, format); err != nil {
+		handleError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(buf.Bytes())
+}
+
+// cachedCount returns the number of tracks holding a cached snapshot or health analyzer
+func (s *SnapshotService) cachedCount() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return max(len(s.latest), len(s.analyzers))
+}
+
+func (s *SnapshotService) worker() {
+	ticker := time.NewTicker(s.conf.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopped.Watch():
+			return
+		case <-ticker.C:
+			s.captureAll()
+		}
+	}
+}
+
+func (s *SnapshotService) captureAll() {
+	rooms := s.roomManager.Rooms()
+
+	published := make(map[livekit.TrackID]bool)
+	var wg sync.WaitGroup
+	for _, room := range rooms {
+		for _, p := range room.GetLocalParticipants() {
+			for _, track := range p.GetPublishedTracks() {
+				if track.Kind() != livekit.TrackType_VIDEO || track.IsMuted() {
+					continue
+				}
+				receiver := getSnapshotReceiver(room, track.ID())
+				if receiver == nil {
+					continue
+				}
+
+				published[track.ID()] = true
+				thumbnail := s.thumbnailSink != nil && wantsThumbnail(s.conf.Thumbnails, room.Name(), track.Source())
+				identity := p.Identity()
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					img, err := s.capture(context.Background(), receiver, false, s.conf.Interval)
+					if err != nil {
+						if !errors.Is(err, snapshot.ErrUnsupportedCodec) {
+							logger.Debugw("could not capture snapshot", "error", err, "room", room.Name(), "trackID", receiver.TrackID())
+						}
+						return
+					}
+					if thumbnail {
+						s.pushThumbnail(room.Name(), identity, receiver.TrackID(), img)
+					}
+					if s.videoHealth.Enabled {
+						s.analyzeHealth(track, img)
+					}
+				}()
+			}
+		}
+	}
+	wg.Wait()
+
+	s.lock.Lock()
+	for trackID := range s.latest {
+		if !published[trackID] {
+			delete(s.latest, trackID)
+		}
+	}
+	for trackID := range s.analyzers {
+		if !published[trackID] {
+			delete(s.analyzers, trackID)
+		}
+	}
+	s.lock.Unlock()
+}
+
+func (s *SnapshotService) pushThumbnail(roomName livekit.RoomName, identity livekit.ParticipantIdentity, trackID livekit.TrackID, img image.Image) {
+	format := snapshot.Format(s.conf.Thumbnails.Format)
+	var buf bytes.Buffer
+	if err := snapshot.Encode(&buf, snapshot.Resize(img, s.conf.Thumbnails.Width), format); err != nil {
+		logger.Warnw("could not encode thumbnail", err, "room", roomName, "trackID", trackID)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), thumbnailTimeout)
+	defer cancel()
+	if err := s.thumbnailSink.StoreThumbnail(ctx, &Thumbnail{
+		RoomName:            roomName,
+		ParticipantIdentity: identity,
+		TrackID:             trackID,
+		Format:              format,
+		CapturedAt:          time.Now(),
+		Data:                buf.Bytes(),
+	}); err != nil {
+		logger.Warnw("could not store thumbnail", err, "room", roomName, "trackID", trackID)
+	}
+}
+
+// snapshotHealthReporter is implemented by published tracks that track their health
+type snapshotHealthReporter interface {
+	UpdateSnapshotHealth(black bool, frozen bool)
+}
+
+func (s *SnapshotService) analyzeHealth(track types.MediaTrack, img image.Image) {
+	reporter, ok := track.(snapshotHealthReporter)
+	if !ok {
+		return
+	}
+
+	s.lock.Lock()
+	analyzer := s.analyzers[track.ID()]
+	if analyzer == nil {
+		analyzer = videohealth.NewFrameAnalyzer(videohealth.FrameAnalyzerParams{
+			BlackLevel:   s.videoHealth.BlackLevel,
+			FrozenFrames: s.videoHealth.FrozenSnapshots,
+		})
+		s.analyzers[track.ID()] = analyzer
+	}
+	s.lock.Unlock()
+
+	// each track is captured by one goroutine at a time
+	black, frozen := analyzer.Analyze(img)
+
+	reporter.UpdateSnapshotHealth(black, frozen)
+}
+
+// getSnapshotReceiver returns the VP8 receiver of a track published by a participant on this node
+func getSnapshotReceiver(room *rtc.Room, trackID livekit.TrackID) sfu.TrackReceiver {
+	for _, p := range room.GetLocalParticipants() {
+		track := p.GetPublishedTrack(trackID)
+		if track == nil {
+			continue
+		}
+
+		receivers := track.Receivers()
+		for _, receiver := range receivers {
+			if strings.EqualFold(receiver.Codec().MimeType, webrtc.MimeTypeVP8) {
+				return receiver
+			}
+		}
+		if len(receivers) != 0 {
+			return receivers[0]
+		}
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,365 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/frostbyte73/core"
+	"golang.org/x/exp/maps"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+const (
+	soakPath       = "/debug/soak"
+	soakSampleFile = "soak.jsonl"
+
+	// background workers come and go, a handful of goroutines is not a trend
+	soakMinGoroutineGrowth = 10
+	// heap objects fluctuate with allocation patterns, only sustained growth beyond this ratio is flagged
+	soakHeapGrowthRatio = 0.05
+)
+
+const (
+	SoakFindingGoroutines        = "goroutines_growing"
+	SoakFindingHeapObjects       = "heap_objects_growing"
+	SoakFindingParticipants      = "participants_not_released"
+	SoakFindingPublishedTracks   = "published_tracks_not_released"
+	SoakFindingSubscribedTracks  = "subscribed_tracks_not_released"
+	SoakFindingSnapshots         = "snapshots_growing"
+	SoakFindingRoomSubscriptions = "room_subscriptions_growing"
+)
+
+type SoakRoomCounts struct {
+	Participants     int `json:"participants"`
+	PublishedTracks  int `json:"published_tracks"`
+	SubscribedTracks int `json:"subscribed_tracks"`
+}
+
+type SoakSample struct {
+	At               time.Time `json:"at"`
+	Goroutines       int       `json:"goroutines"`
+	HeapAlloc        uint64    `json:"heap_alloc"`
+	HeapObjects      uint64    `json:"heap_objects"`
+	Participants     int       `json:"participants"`
+	PublishedTracks  int       `json:"published_tracks"`
+	VideoTracks      int       `json:"video_tracks"`
+	SubscribedTracks int       `json:"subscribed_tracks"`
+	Snapshots        int       `json:"snapshots"`
+	// counters maintained by telemetry, these should follow the live objects above
+	TelemetryParticipants     int32 `json:"telemetry_participants"`
+	TelemetryPublishedTracks  int32 `json:"telemetry_published_tracks"`
+	TelemetrySubscribedTracks int32 `json:"telemetry_subscribed_tracks"`
+
+	Rooms map[livekit.RoomName]*SoakRoomCounts `json:"rooms"`
+}
+
+// load is what legitimately drives goroutine and heap growth
+func (s *SoakSample) load() int {
+	return s.Participants + s.PublishedTracks + s.SubscribedTracks
+}
+
+type SoakFinding struct {
+	Kind      string           `json:"kind"`
+	Room      livekit.RoomName `json:"room,omitempty"`
+	Detail    string           `json:"detail"`
+	FirstSeen time.Time        `json:"first_seen"`
+}
+
+func (f *SoakFinding) key() string {
+	return f.Kind + "/" + string(f.Room)
+}
+
+type soakReport struct {
+	Samples  []*SoakSample  `json:"samples"`
+	Findings []*SoakFinding `json:"findings"`
+}
+
+// SoakMonitor samples resource usage for multi-day soak tests and flags counts that keep growing
+// while load doesn't, or telemetry that isn't released with the objects it accounts for
+type SoakMonitor struct {
+	conf         config.SoakConfig
+	operatorKeys []string
+	roomManager  *RoomManager
+	snapshots    *SnapshotService
+
+	lock     sync.Mutex
+	samples  []*SoakSample
+	findings map[string]*SoakFinding
+	sampled  int
+
+	stopped core.Fuse
+}
+
+func NewSoakMonitor(conf *config.Config, roomManager *RoomManager, snapshots *SnapshotService) (*SoakMonitor, error) {
+	if conf.Soak.Directory != "" {
+		if err := os.MkdirAll(conf.Soak.Directory, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	m := &SoakMonitor{
+		conf:         conf.Soak,
+		operatorKeys: conf.OperatorKeys,
+		roomManager:  roomManager,
+		snapshots:    snapshots,
+		findings:     make(map[string]*SoakFinding),
+	}
+	if m.conf.Window < 2 {
+		m.conf.Window = 2
+	}
+	if m.conf.Interval > 0 {
+		logger.Infow("soak mode enabled", "interval", m.conf.Interval, "window", m.conf.Window)
+		go m.worker()
+	}
+	return m, nil
+}
+
+func (m *SoakMonitor) Stop() {
+	m.stopped.Break()
+}
+
+// ServeHTTP returns the retained samples and the current findings. Samples cover the rooms of every project
+// on the node, so the token must be signed with an operator key
+func (m *SoakMonitor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := EnsureOperatorPermission(r.Context(), m.operatorKeys); err != nil {
+		handleError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(m.report())
+}
+
+func (m *SoakMonitor) report() *soakReport {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	findings := maps.Values(m.findings)
+	sort.Slice(findings, func(i, j int) bool {
+		return findings[i].key() < findings[j].key()
+	})
+	return &soakReport{
+		Samples:  append([]*SoakSample(nil), m.samples...),
+		Findings: findings,
+	}
+}
+
+func (m *SoakMonitor) worker() {
+	ticker := time.NewTicker(m.conf.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stopped.Watch():
+			return
+		case <-ticker.C:
+			m.record(m.sample())
+		}
+	}
+}
+
+func (m *SoakMonitor) sample() *SoakSample {
+	// garbage waiting to be collected is included, growth is judged over the whole window
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	s := &SoakSample{
+		At:          time.Now(),
+		Goroutines:  runtime.NumGoroutine(),
+		HeapAlloc:   mem.HeapAlloc,
+		HeapObjects: mem.HeapObjects,
+		Rooms:       make(map[livekit.RoomName]*SoakRoomCounts),
+	}
+
+	for _, room := range m.roomManager.Rooms() {
+		counts := &SoakRoomCounts{}
+		for _, p := range room.GetLocalParticipants() {
+			counts.Participants++
+			for _, track := range p.GetPublishedTracks() {
+				counts.PublishedTracks++
+				if track.Kind() == livekit.TrackType_VIDEO {
+					s.VideoTracks++
+				}
+			}
+			counts.SubscribedTracks += len(p.GetSubscribedTracks())
+		}
+		s.Participants += counts.Participants
+		s.PublishedTracks += counts.PublishedTracks
+		s.SubscribedTracks += counts.SubscribedTracks
+		s.Rooms[room.Name()] = counts
+	}
+	if m.snapshots != nil {
+		s.Snapshots = m.snapshots.cachedCount()
+	}
+	s.TelemetryParticipants, s.TelemetryPublishedTracks, s.TelemetrySubscribedTracks = prometheus.CurrentCounts()
+	return s
+}
+
+func (m *SoakMonitor) record(s *SoakSample) {
+	m.lock.Lock()
+	m.samples = append(m.samples, s)
+	if len(m.samples) > m.conf.Window {
+		m.samples = m.samples[len(m.samples)-m.conf.Window:]
+	}
+	m.sampled++
+	writeProfile := m.conf.Directory != "" && m.conf.HeapProfileEvery > 0 && m.sampled%m.conf.HeapProfileEvery == 0
+
+	detected := make(map[string]*SoakFinding)
+	if len(m.samples) == m.conf.Window {
+		for _, f := range DetectSoakLeaks(m.samples) {
+			if existing := m.findings[f.key()]; existing != nil {
+				f.FirstSeen = existing.FirstSeen
+			} else {
+				f.FirstSeen = s.At
+				logger.Warnw("soak: suspected leak", nil, "kind", f.Kind, "room", f.Room, "detail", f.Detail)
+			}
+			detected[f.key()] = f
+		}
+	}
+	for key, f := range m.findings {
+		if detected[key] == nil {
+			logger.Infow("soak: suspected leak cleared", "kind", f.Kind, "room", f.Room, "since", f.FirstSeen)
+		}
+	}
+	m.findings = detected
+	m.lock.Unlock()
+
+	if m.conf.Directory == "" {
+		return
+	}
+	if err := m.appendSample(s); err != nil {
+		logger.Warnw("soak: could not write sample", err)
+	}
+	if writeProfile {
+		if err := m.writeHeapProfile(s.At); err != nil {
+			logger.Warnw("soak: could not write heap profile", err)
+		}
+	}
+}
+
+func (m *SoakMonitor) appendSample(s *SoakSample) error {
+	f, err := os.OpenFile(filepath.Join(m.conf.Directory, soakSampleFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(s)
+}
+
+func (m *SoakMonitor) writeHeapProfile(at time.Time) error {
+	f, err := os.Create(filepath.Join(m.conf.Directory, fmt.Sprintf("heap_%d.pprof", at.Unix())))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return pprof.WriteHeapProfile(f)
+}
+
+// ------------------------------------------------
+
+// DetectSoakLeaks flags counts that grew over every sample of the window while load did not,
+// and telemetry counters that stayed above the live objects they account for
+func DetectSoakLeaks(samples []*SoakSample) []*SoakFinding {
+	first, last := samples[0], samples[len(samples)-1]
+	loadSteady := last.load() <= first.load()
+
+	var findings []*SoakFinding
+	add := func(kind string, room livekit.RoomName, format string, args ...any) {
+		findings = append(findings, &SoakFinding{Kind: kind, Room: room, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	if loadSteady {
+		if growing(samples, soakMinGoroutineGrowth, func(s *SoakSample) int64 { return int64(s.Goroutines) }) {
+			add(SoakFindingGoroutines, "", "%d -> %d", first.Goroutines, last.Goroutines)
+		}
+		minHeapGrowth := int64(float64(first.HeapObjects) * soakHeapGrowthRatio)
+		if growing(samples, minHeapGrowth, func(s *SoakSample) int64 { return int64(s.HeapObjects) }) {
+			add(SoakFindingHeapObjects, "", "%d -> %d", first.HeapObjects, last.HeapObjects)
+		}
+	}
+
+	if always(samples, func(s *SoakSample) bool { return int(s.TelemetryParticipants) > s.Participants }) {
+		add(SoakFindingParticipants, "", "%d accounted, %d live", last.TelemetryParticipants, last.Participants)
+	}
+	if always(samples, func(s *SoakSample) bool { return int(s.TelemetryPublishedTracks) > s.PublishedTracks }) {
+		add(SoakFindingPublishedTracks, "", "%d accounted, %d live", last.TelemetryPublishedTracks, last.PublishedTracks)
+	}
+	if always(samples, func(s *SoakSample) bool { return int(s.TelemetrySubscribedTracks) > s.SubscribedTracks }) {
+		add(SoakFindingSubscribedTracks, "", "%d accounted, %d live", last.TelemetrySubscribedTracks, last.SubscribedTracks)
+	}
+	// cached snapshots are pruned on every capture, so they should never outnumber video tracks for long
+	if always(samples, func(s *SoakSample) bool { return s.Snapshots > s.VideoTracks }) {
+		add(SoakFindingSnapshots, "", "%d cached, %d video tracks", last.Snapshots, last.VideoTracks)
+	}
+
+	for roomName, lastCounts := range last.Rooms {
+		firstCounts := first.Rooms[roomName]
+		if firstCounts == nil || lastCounts.Participants > firstCounts.Participants || lastCounts.PublishedTracks > firstCounts.PublishedTracks {
+			continue
+		}
+		if growing(samples, 1, func(s *SoakSample) int64 {
+			if counts := s.Rooms[roomName]; counts != nil {
+				return int64(counts.SubscribedTracks)
+			}
+			return -1
+		}) {
+			add(SoakFindingRoomSubscriptions, roomName, "%d -> %d with %d participants", firstCounts.SubscribedTracks, lastCounts.SubscribedTracks, lastCounts.Participants)
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		return findings[i].key() < findings[j].key()
+	})
+	return findings
+}
+
+// growing returns true when the value never decreased over the samples and ended at least minGrowth higher than it started
+func growing(samples []*SoakSample, minGrowth int64, value func(s *SoakSample) int64) bool {
+	prev := value(samples[0])
+	if prev < 0 {
+		return false
+	}
+	for _, s := range samples[1:] {
+		v := value(s)
+		if v < prev {
+			return false
+		}
+		prev = v
+	}
+	return prev-value(samples[0]) >= max(minGrowth, 1)
+}
+
+func always(samples []*SoakSample, cond func(s *SoakSample) bool) bool {
+	for _, s := range samples {
+		if !cond(s) {
+			return false
+		}
+	}
+	return true
+}
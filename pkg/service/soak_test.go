@@ -0,0 +1,114 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func soakSamples(n int, fill func(i int, s *service.SoakSample)) []*service.SoakSample {
+	samples := make([]*service.SoakSample, 0, n)
+	for i := 0; i < n; i++ {
+		s := &service.SoakSample{
+			At:               time.Unix(int64(i*60), 0),
+			Goroutines:       100,
+			HeapObjects:      10000,
+			Participants:     2,
+			PublishedTracks:  2,
+			SubscribedTracks: 2,
+			Rooms: map[livekit.RoomName]*service.SoakRoomCounts{
+				"room": {Participants: 2, PublishedTracks: 2, SubscribedTracks: 2},
+			},
+			TelemetryParticipants:     2,
+			TelemetryPublishedTracks:  2,
+			TelemetrySubscribedTracks: 2,
+		}
+		fill(i, s)
+		samples = append(samples, s)
+	}
+	return samples
+}
+
+func soakFindingKinds(findings []*service.SoakFinding) []string {
+	var kinds []string
+	for _, f := range findings {
+		kinds = append(kinds, f.Kind)
+	}
+	return kinds
+}
+
+func TestDetectSoakLeaks(t *testing.T) {
+	t.Run("steady", func(t *testing.T) {
+		samples := soakSamples(6, func(i int, s *service.SoakSample) {
+			s.Goroutines += i % 2
+			s.HeapObjects += uint64(i * 10)
+		})
+		require.Empty(t, service.DetectSoakLeaks(samples))
+	})
+
+	t.Run("goroutines growing with steady load", func(t *testing.T) {
+		samples := soakSamples(6, func(i int, s *service.SoakSample) {
+			s.Goroutines += i * 10
+			s.HeapObjects += uint64(i * 200)
+		})
+		require.Equal(t, []string{service.SoakFindingGoroutines, service.SoakFindingHeapObjects}, soakFindingKinds(service.DetectSoakLeaks(samples)))
+	})
+
+	t.Run("goroutines growing with load", func(t *testing.T) {
+		samples := soakSamples(6, func(i int, s *service.SoakSample) {
+			s.Goroutines += i * 10
+			s.Participants += i
+			s.TelemetryParticipants += int32(i)
+		})
+		require.Empty(t, service.DetectSoakLeaks(samples))
+	})
+
+	t.Run("tracks not released", func(t *testing.T) {
+		samples := soakSamples(6, func(i int, s *service.SoakSample) {
+			s.TelemetryPublishedTracks = 3
+		})
+		findings := service.DetectSoakLeaks(samples)
+		require.Equal(t, []string{service.SoakFindingPublishedTracks}, soakFindingKinds(findings))
+		require.Equal(t, "3 accounted, 2 live", findings[0].Detail)
+
+		// catching up within the window is not a leak
+		samples[3].TelemetryPublishedTracks = 2
+		require.Empty(t, service.DetectSoakLeaks(samples))
+	})
+
+	t.Run("snapshots growing", func(t *testing.T) {
+		samples := soakSamples(6, func(i int, s *service.SoakSample) {
+			s.VideoTracks = 1
+			s.Snapshots = 2 + i
+		})
+		require.Equal(t, []string{service.SoakFindingSnapshots}, soakFindingKinds(service.DetectSoakLeaks(samples)))
+	})
+
+	t.Run("room subscriptions growing", func(t *testing.T) {
+		samples := soakSamples(6, func(i int, s *service.SoakSample) {
+			s.Rooms["room"].SubscribedTracks += i
+		})
+		findings := service.DetectSoakLeaks(samples)
+		require.Equal(t, []string{service.SoakFindingRoomSubscriptions}, soakFindingKinds(findings))
+		require.Equal(t, livekit.RoomName("room"), findings[0].Room)
+	})
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/livekit/psrpc"
+)
+
+const (
+	srtScheme = "srt"
+	// stream ids longer than this are rejected by the SRT handshake
+	srtMaxStreamIDLength = 512
+	// passphrase length limits of SRT encryption
+	srtMinPassphraseLength = 10
+	srtMaxPassphraseLength = 79
+
+	srtModeCaller   = "caller"
+	srtModeListener = "listener"
+)
+
+// parseSRTURL validates an srt://host:port?streamid=...&passphrase=... url, and returns a key identifying
+// the destination. In listener mode, the host is the local address to bind to and may be empty.
+func parseSRTURL(rawURL string, allowListener bool) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != srtScheme {
+		return "", psrpc.NewErrorf(psrpc.InvalidArgument, "invalid srt url")
+	}
+
+	query := u.Query()
+	switch mode := query.Get("mode"); mode {
+	case "", srtModeCaller:
+		if u.Hostname() == "" {
+			return "", psrpc.NewErrorf(psrpc.InvalidArgument, "srt url requires host and port")
+		}
+	case srtModeListener:
+		if !allowListener {
+			return "", psrpc.NewErrorf(psrpc.InvalidArgument, "unsupported srt mode %s", mode)
+		}
+	default:
+		return "", psrpc.NewErrorf(psrpc.InvalidArgument, "unsupported srt mode %s", mode)
+	}
+	if u.Port() == "" {
+		return "", psrpc.NewErrorf(psrpc.InvalidArgument, "srt url requires a port")
+	}
+
+	streamID := query.Get("streamid")
+	if len(streamID) > srtMaxStreamIDLength {
+		return "", psrpc.NewErrorf(psrpc.InvalidArgument, "srt stream id exceeds %d characters", srtMaxStreamIDLength)
+	}
+	if passphrase := query.Get("passphrase"); passphrase != "" {
+		if len(passphrase) < srtMinPassphraseLength || len(passphrase) > srtMaxPassphraseLength {
+			return "", psrpc.NewErrorf(psrpc.InvalidArgument, "srt passphrase must be %d to %d characters", srtMinPassphraseLength, srtMaxPassphraseLength)
+		}
+	}
+	return u.Host + "/" + streamID, nil
+}
+
+func isSRTURL(rawURL string) bool {
+	return strings.HasPrefix(strings.ToLower(rawURL), srtScheme+"://")
+}
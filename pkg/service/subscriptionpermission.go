@@ -0,0 +1,176 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	subscriptionPermissionPath = "/room/subscription_permission"
+
+	getSubscriptionPermissionMethod    = "GetSubscriptionPermission"
+	updateSubscriptionPermissionMethod = "UpdateSubscriptionPermission"
+)
+
+// SubscriptionPermissionHandler reads and restricts which identities may subscribe to which tracks of a participant,
+// through the node hosting the participant. Permissions set through the API are kept apart from the ones the
+// publisher sets with the SubscriptionPermission signal message, a subscriber needs to be allowed by both, so the
+// publisher cannot lift them. Subscribers losing or gaining permission are notified with SubscriptionPermissionUpdate
+type SubscriptionPermissionHandler struct {
+	roomAPI *RoomAPI
+}
+
+type subscriptionPermissionResponse struct {
+	// set by the publisher, null when all participants are allowed
+	Publisher json.RawMessage `json:"publisher"`
+	// set through the API, null when not restricted
+	Admin json.RawMessage `json:"admin"`
+}
+
+func NewSubscriptionPermissionHandler(roomManager *RoomManager) *SubscriptionPermissionHandler {
+	h := &SubscriptionPermissionHandler{
+		roomAPI: roomManager.RoomAPI(),
+	}
+	h.roomAPI.Handle(getSubscriptionPermissionMethod, h.handleGetSubscriptionPermission)
+	h.roomAPI.Handle(updateSubscriptionPermissionMethod, h.handleUpdateSubscriptionPermission)
+	return h
+}
+
+// GetSubscriptionPermission returns the permissions set by the publisher and through the API
+func (h *SubscriptionPermissionHandler) GetSubscriptionPermission(
+	ctx context.Context,
+	roomName livekit.RoomName,
+	identity livekit.ParticipantIdentity,
+) (*subscriptionPermissionResponse, error) {
+	var res subscriptionPermissionResponse
+	err := h.roomAPI.CallParticipant(ctx, roomName, identity, getSubscriptionPermissionMethod, nil, &res)
+	return &res, err
+}
+
+// UpdateSubscriptionPermission replaces the permissions set through the API, they last while the participant is
+// connected. allParticipants removes the restrictions. Subscriptions no longer allowed are revoked
+func (h *SubscriptionPermissionHandler) UpdateSubscriptionPermission(
+	ctx context.Context,
+	roomName livekit.RoomName,
+	identity livekit.ParticipantIdentity,
+	permission *livekit.SubscriptionPermission,
+) (*subscriptionPermissionResponse, error) {
+	var res subscriptionPermissionResponse
+	err := h.roomAPI.CallParticipant(ctx, roomName, identity, updateSubscriptionPermissionMethod, permission, &res)
+	return &res, err
+}
+
+func (h *SubscriptionPermissionHandler) handleGetSubscriptionPermission(_ context.Context, _ *rtc.Room, participant types.LocalParticipant, _ json.RawMessage) (any, error) {
+	return newSubscriptionPermissionResponse(participant)
+}
+
+func (h *SubscriptionPermissionHandler) handleUpdateSubscriptionPermission(_ context.Context, room *rtc.Room, participant types.LocalParticipant, body json.RawMessage) (any, error) {
+	permission := &livekit.SubscriptionPermission{}
+	if err := protojson.Unmarshal(body, permission); err != nil {
+		return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+	if permission.AllParticipants {
+		permission = nil
+	}
+
+	room.Logger.Infow("api update subscription permission", "participant", participant.Identity(), "permission", logger.Proto(permission))
+	if err := room.UpdateAdminSubscriptionPermission(participant, permission); err != nil {
+		return nil, psrpc.NewError(psrpc.InvalidArgument, err)
+	}
+	return newSubscriptionPermissionResponse(participant)
+}
+
+func newSubscriptionPermissionResponse(participant types.LocalParticipant) (*subscriptionPermissionResponse, error) {
+	res := &subscriptionPermissionResponse{}
+	publisher, _ := participant.SubscriptionPermission()
+	for _, p := range []struct {
+		permission *livekit.SubscriptionPermission
+		data       *json.RawMessage
+	}{
+		{publisher, &res.Publisher},
+		{participant.AdminSubscriptionPermission(), &res.Admin},
+	} {
+		if p.permission == nil {
+			continue
+		}
+		data, err := protojson.Marshal(p.permission)
+		if err != nil {
+			return nil, err
+		}
+		*p.data = data
+	}
+	return res, nil
+}
+
+// ServeHTTP handles, with a token that has roomAdmin permission for the room
+//
+//	GET /room/subscription_permission?room=&identity=
+//	POST /room/subscription_permission?room=&identity= with a JSON livekit.SubscriptionPermission,
+//	  e.g. {"trackPermissions": [{"participantIdentity": "agent", "trackSids": ["TR_xxx"]}]}
+//
+// both respond with {"publisher", "admin"} permissions
+func (h *SubscriptionPermissionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	roomName := livekit.RoomName(query.Get("room"))
+	identity := livekit.ParticipantIdentity(query.Get("identity"))
+	if err := EnsureAdminPermission(r.Context(), roomName); err != nil {
+		handleError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	var (
+		res *subscriptionPermissionResponse
+		err error
+	)
+	switch r.Method {
+	case http.MethodGet:
+		res, err = h.GetSubscriptionPermission(r.Context(), roomName, identity)
+	case http.MethodPost:
+		body, rerr := io.ReadAll(http.MaxBytesReader(w, r.Body, 64*1024))
+		if rerr != nil {
+			handleError(w, r, http.StatusBadRequest, rerr, "room", roomName, "participant", identity)
+			return
+		}
+		permission := &livekit.SubscriptionPermission{}
+		if rerr = protojson.Unmarshal(body, permission); rerr != nil {
+			handleError(w, r, http.StatusBadRequest, rerr, "room", roomName, "participant", identity)
+			return
+		}
+		res, err = h.UpdateSubscriptionPermission(r.Context(), roomName, identity, permission)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		handleError(w, r, roomAPIStatus(err), err, "room", roomName, "participant", identity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
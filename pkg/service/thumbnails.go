@@ -0,0 +1,176 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/sfu/snapshot"
+)
+
+const thumbnailTimeout = 10 * time.Second
+
+// Thumbnail is a scaled down, encoded snapshot of a track
+type Thumbnail struct {
+	RoomName            livekit.RoomName
+	ParticipantIdentity livekit.ParticipantIdentity
+	TrackID             livekit.TrackID
+	Format              snapshot.Format
+	CapturedAt          time.Time
+	Data                []byte
+}
+
+// ThumbnailSink stores thumbnails pushed by the snapshot service
+type ThumbnailSink interface {
+	StoreThumbnail(ctx context.Context, thumbnail *Thumbnail) error
+}
+
+type thumbnailSinks []ThumbnailSink
+
+func (s thumbnailSinks) StoreThumbnail(ctx context.Context, thumbnail *Thumbnail) error {
+	var errs []error
+	for _, sink := range s {
+		if err := sink.StoreThumbnail(ctx, thumbnail); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FileThumbnailSink writes thumbnails to <directory>/<room>/<track_sid>.<format>, replacing the previous one.
+// room names are path escaped, so they can't refer to another directory
+type FileThumbnailSink struct {
+	directory string
+}
+
+func NewFileThumbnailSink(directory string) *FileThumbnailSink {
+	return &FileThumbnailSink{directory: directory}
+}
+
+func (s *FileThumbnailSink) StoreThumbnail(_ context.Context, thumbnail *Thumbnail) error {
+	roomDir := url.PathEscape(string(thumbnail.RoomName))
+	if roomDir == "" || roomDir == "." || roomDir == ".." {
+		return ErrInvalidThumbnailRoomName
+	}
+	dir := filepath.Join(s.directory, roomDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so readers never see a partial image
+	name := filepath.Join(dir, url.PathEscape(fmt.Sprintf("%s.%s", thumbnail.TrackID, thumbnail.Format)))
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, thumbnail.Data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+// WebhookThumbnailSink posts thumbnails to a URL. Requests are signed the same way as webhooks,
+// with the sha256 of the image in the token
+type WebhookThumbnailSink struct {
+	url       string
+	apiKey    string
+	apiSecret string
+	client    *http.Client
+}
+
+func NewWebhookThumbnailSink(conf *config.Config) (*WebhookThumbnailSink, error) {
+	secret := conf.Keys[conf.WebHook.APIKey]
+	if secret == "" {
+		return nil, ErrThumbnailsMissingAPIKey
+	}
+
+	return &WebhookThumbnailSink{
+		url:       conf.Snapshot.Thumbnails.URL,
+		apiKey:    conf.WebHook.APIKey,
+		apiSecret: secret,
+		client:    &http.Client{},
+	}, nil
+}
+
+func (s *WebhookThumbnailSink) StoreThumbnail(ctx context.Context, thumbnail *Thumbnail) error {
+	sum := sha256.Sum256(thumbnail.Data)
+	token, err := auth.NewAccessToken(s.apiKey, s.apiSecret).
+		SetValidFor(5 * time.Minute).
+		SetSha256(base64.StdEncoding.EncodeToString(sum[:])).
+		ToJWT()
+	if err != nil {
+		return err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(thumbnail.Data))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Authorization", token)
+	r.Header.Set("Content-Type", thumbnail.Format.ContentType())
+	r.Header.Set("X-LiveKit-Room", string(thumbnail.RoomName))
+	r.Header.Set("X-LiveKit-Participant", string(thumbnail.ParticipantIdentity))
+	r.Header.Set("X-LiveKit-Track", string(thumbnail.TrackID))
+	r.Header.Set("X-LiveKit-Captured-At", thumbnail.CapturedAt.UTC().Format(time.RFC3339Nano))
+
+	resp, err := s.client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("thumbnail endpoint returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// wantsThumbnail returns true when thumbnails are configured for the track
+func wantsThumbnail(conf config.ThumbnailConfig, roomName livekit.RoomName, source livekit.TrackSource) bool {
+	if len(conf.RoomPrefixes) != 0 {
+		matched := false
+		for _, prefix := range conf.RoomPrefixes {
+			if strings.HasPrefix(string(roomName), prefix) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(conf.Sources) == 0 {
+		return true
+	}
+	for _, s := range conf.Sources {
+		if strings.EqualFold(s, source.String()) {
+			return true
+		}
+	}
+	return false
+}
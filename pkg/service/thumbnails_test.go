@@ -0,0 +1,63 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/sfu/snapshot"
+)
+
+func TestFileThumbnailSink(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "thumbnails")
+	sink := service.NewFileThumbnailSink(dir)
+
+	store := func(roomName string) error {
+		return sink.StoreThumbnail(context.Background(), &service.Thumbnail{
+			RoomName: livekit.RoomName(roomName),
+			TrackID:  "TR_a",
+			Format:   snapshot.FormatJPEG,
+			Data:     []byte{1},
+		})
+	}
+
+	t.Run("stores per room", func(t *testing.T) {
+		require.NoError(t, store("room"))
+		data, err := os.ReadFile(filepath.Join(dir, "room", "TR_a.jpeg"))
+		require.NoError(t, err)
+		require.Equal(t, []byte{1}, data)
+	})
+
+	t.Run("room names stay in the directory", func(t *testing.T) {
+		for _, name := range []string{"", ".", ".."} {
+			require.ErrorIs(t, store(name), service.ErrInvalidThumbnailRoomName, name)
+		}
+
+		require.NoError(t, store("../escaped"))
+		_, err := os.Stat(filepath.Join(dir, "..%2Fescaped", "TR_a.jpeg"))
+		require.NoError(t, err)
+		_, err = os.Stat(filepath.Join(base, "escaped"))
+		require.True(t, os.IsNotExist(err))
+	})
+}
@@ -0,0 +1,191 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	sutils "github.com/livekit/livekit-server/pkg/utils"
+)
+
+// TLSCertificates are the certificates served by this node's TLS listeners. Certificates loaded from files are
+// reloaded when the files change, or on SIGHUP. ACME certificates are renewed before they expire
+type TLSCertificates struct {
+	api  *sutils.CertReloader
+	turn *sutils.CertReloader
+
+	acme       *autocert.Manager
+	apiDomain  string
+	turnDomain string
+	// answers HTTP-01 challenges
+	challengeServer *http.Server
+}
+
+func NewTLSCertificates(conf *config.Config) (*TLSCertificates, error) {
+	c := &TLSCertificates{}
+	if conf.TLS.CertFile != "" || conf.TLS.KeyFile != "" {
+		cert, err := sutils.NewCertReloader("api", conf.TLS.CertFile, conf.TLS.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not load tls cert")
+		}
+		c.api = cert
+	} else if conf.UsesAPIACME() {
+		c.apiDomain = conf.ACME.Domain
+	}
+
+	if conf.UsesTURNACME() {
+		c.turnDomain = conf.TURN.Domain
+	} else if conf.TURN.Enabled && conf.TURN.TLSPort > 0 && !conf.TURN.ExternalTLS {
+		cert, err := sutils.NewCertReloader("turn", conf.TURN.CertFile, conf.TURN.KeyFile)
+		if err != nil {
+			c.Stop()
+			return nil, errors.Wrap(err, "TURN tls cert required")
+		}
+		c.turn = cert
+	}
+
+	if c.apiDomain != "" || c.turnDomain != "" {
+		if err := c.startACME(conf.ACME); err != nil {
+			c.Stop()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// APIConfig is the TLS config of the API and WebSocket listeners, nil when TLS is terminated elsewhere
+func (c *TLSCertificates) APIConfig() *tls.Config {
+	switch {
+	case c.api != nil:
+		return &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: c.api.GetCertificate,
+		}
+	case c.apiDomain != "":
+		return c.acmeConfig(c.apiDomain)
+	default:
+		return nil
+	}
+}
+
+// TURNConfig is the TLS config of the TURN/TLS listener, nil when TURN/TLS is disabled or external
+func (c *TLSCertificates) TURNConfig() *tls.Config {
+	switch {
+	case c.turn != nil:
+		return &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: c.turn.GetCertificate,
+		}
+	case c.turnDomain != "":
+		return c.acmeConfig(c.turnDomain)
+	default:
+		return nil
+	}
+}
+
+// Reload reloads certificates whose files have changed, keeping the current certificate when loading fails
+func (c *TLSCertificates) Reload() {
+	for _, cert := range []*sutils.CertReloader{c.api, c.turn} {
+		if cert == nil {
+			continue
+		}
+		if _, err := cert.Reload(); err != nil {
+			logger.Warnw("could not reload certificate, keeping current certificate", err, "cert", cert.Name())
+		}
+	}
+}
+
+func (c *TLSCertificates) Stop() {
+	for _, cert := range []*sutils.CertReloader{c.api, c.turn} {
+		if cert != nil {
+			cert.Stop()
+		}
+	}
+	if c.challengeServer != nil {
+		_ = c.challengeServer.Close()
+	}
+}
+
+func (c *TLSCertificates) startACME(conf config.ACMEConfig) error {
+	cacheDir := conf.CacheDir
+	if cacheDir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return errors.Wrap(err, "acme cache_dir required")
+		}
+		cacheDir = filepath.Join(userCacheDir, "livekit", "acme")
+	}
+
+	var domains []string
+	for _, domain := range []string{c.apiDomain, c.turnDomain} {
+		if domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	c.acme = &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cacheDir),
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Email:      conf.Email,
+	}
+	if conf.DirectoryURL != "" {
+		c.acme.Client = &acme.Client{DirectoryURL: conf.DirectoryURL}
+	}
+
+	if conf.HTTPPort > 0 {
+		ln, err := net.Listen("tcp", ":"+strconv.Itoa(conf.HTTPPort))
+		if err != nil {
+			return errors.Wrap(err, "could not listen on acme http port")
+		}
+		c.challengeServer = &http.Server{
+			Handler:           c.acme.HTTPHandler(nil),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			if err := c.challengeServer.Serve(ln); err != http.ErrServerClosed {
+				logger.Errorw("acme challenge server failed", err)
+			}
+		}()
+	}
+	logger.Infow("acquiring certificates with ACME", "domains", domains, "cacheDir", cacheDir, "httpPort", conf.HTTPPort)
+	return nil
+}
+
+// acmeConfig serves the certificate of domain, also to clients that do not send SNI,
+// as TURN clients often connect by address
+func (c *TLSCertificates) acmeConfig(domain string) *tls.Config {
+	tlsConfig := c.acme.TLSConfig()
+	tlsConfig.MinVersion = tls.VersionTLS12
+	tlsConfig.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if hello.ServerName == "" {
+			hello.ServerName = domain
+		}
+		return c.acme.GetCertificate(hello)
+	}
+	return tlsConfig
+}
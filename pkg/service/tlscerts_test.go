@@ -0,0 +1,65 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestTLSCertificates(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		certs, err := service.NewTLSCertificates(&config.Config{})
+		require.NoError(t, err)
+		defer certs.Stop()
+
+		require.Nil(t, certs.APIConfig())
+		require.Nil(t, certs.TURNConfig())
+	})
+
+	t.Run("missing cert", func(t *testing.T) {
+		conf := &config.Config{}
+		conf.TLS.CertFile = "/nonexistent/cert.pem"
+		conf.TLS.KeyFile = "/nonexistent/key.pem"
+		_, err := service.NewTLSCertificates(conf)
+		require.Error(t, err)
+	})
+
+	t.Run("acme", func(t *testing.T) {
+		conf := &config.Config{}
+		conf.ACME = config.ACMEConfig{
+			Enabled:  true,
+			Domain:   "livekit.example.com",
+			CacheDir: t.TempDir(),
+		}
+		conf.TURN.Enabled = true
+		conf.TURN.Domain = "turn.example.com"
+		conf.TURN.TLSPort = 5349
+		certs, err := service.NewTLSCertificates(conf)
+		require.NoError(t, err)
+		defer certs.Stop()
+
+		require.NotNil(t, certs.APIConfig())
+		require.NotNil(t, certs.TURNConfig())
+		// TLS-ALPN-01 challenges are answered by the listeners
+		require.Contains(t, certs.APIConfig().NextProtos, acme.ALPNProto)
+		require.Contains(t, certs.TURNConfig().NextProtos, acme.ALPNProto)
+	})
+}
@@ -44,7 +44,7 @@ const (
 	turnMaxPort     = 30000
 )
 
-func NewTurnServer(conf *config.Config, authHandler turn.AuthHandler, standalone bool) (*turn.Server, error) {
+func NewTurnServer(conf *config.Config, authHandler turn.AuthHandler, certs *TLSCertificates, standalone bool) (*turn.Server, error) {
 	turnConf := conf.TURN
 	if !turnConf.Enabled {
 		return nil, nil
@@ -84,16 +84,15 @@ func NewTurnServer(conf *config.Config, authHandler turn.AuthHandler, standalone
 		}
 
 		if !turnConf.ExternalTLS {
-			cert, err := tls.LoadX509KeyPair(turnConf.CertFile, turnConf.KeyFile)
-			if err != nil {
-				return nil, errors.Wrap(err, "TURN tls cert required")
+			var tlsConfig *tls.Config
+			if certs != nil {
+				tlsConfig = certs.TURNConfig()
+			}
+			if tlsConfig == nil {
+				return nil, errors.New("TURN tls cert required")
 			}
 
-			tlsListener, err := tls.Listen("tcp4", "0.0.0.0:"+strconv.Itoa(turnConf.TLSPort),
-				&tls.Config{
-					MinVersion:   tls.VersionTLS12,
-					Certificates: []tls.Certificate{cert},
-				})
+			tlsListener, err := tls.Listen("tcp4", "0.0.0.0:"+strconv.Itoa(turnConf.TLSPort), tlsConfig)
 			if err != nil {
 				return nil, errors.Wrap(err, "could not listen on TURN TCP port")
 			}
@@ -146,8 +145,12 @@ func NewTurnServer(conf *config.Config, authHandler turn.AuthHandler, standalone
 	return turn.NewServer(serverConfig)
 }
 
-func getTURNAuthHandlerFunc(handler *TURNAuthHandler) turn.AuthHandler {
-	return handler.HandleAuth
+func getTURNAuthHandlerFunc(conf *config.Config, handler *TURNAuthHandler, keyProvider auth.KeyProvider) (turn.AuthHandler, error) {
+	backend, err := createTURNAuthBackend(conf, handler, keyProvider)
+	if err != nil {
+		return nil, err
+	}
+	return backend.HandleAuth, nil
 }
 
 type TURNAuthHandler struct {
@@ -0,0 +1,378 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/turn/v2"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	defaultTURNAuthTimeout  = 2 * time.Second
+	defaultTURNAuthCacheTTL = time.Minute
+	maxTURNAuthCacheSize    = 10000
+	// rejected rest credentials are cached for this long, so unknown usernames do not cause a callback per packet
+	turnAuthRejectedCacheTTL = 10 * time.Second
+	// longest a TURN request waits for a rest callback, the listener does not read other packets meanwhile.
+	// A slower callback completes in the background, and its result is cached for the retry of the client
+	maxTURNAuthWait = 200 * time.Millisecond
+	// rest callbacks started per second, usernames beyond this are rejected until the next second
+	maxTURNAuthLookupsPerSecond = 100
+	// STUN USERNAME is less than 509 bytes, RFC 8489
+	maxTURNUsernameLength = 508
+)
+
+var (
+	ErrTURNSharedSecretNotSet  = errors.New("turn.auth.shared_secret is required for shared_secret")
+	ErrTURNAuthURLNotSet       = errors.New("turn.auth.url is required for rest")
+	ErrTURNCredentialsExpired  = errors.New("TURN credentials expired")
+	ErrTURNCredentialsRejected = errors.New("TURN credentials rejected")
+	ErrTURNUsernameTooLong     = errors.New("TURN username exceeds 508 bytes")
+	ErrTURNAuthRateLimited     = errors.New("too many TURN auth lookups")
+)
+
+// TURNAuthBackend verifies the username of a TURN request, returning its long-term credential key,
+// see turn.GenerateAuthKey
+type TURNAuthBackend interface {
+	HandleAuth(username, realm string, srcAddr net.Addr) (key []byte, ok bool)
+}
+
+// turnAuthBackends accepts usernames accepted by any backend, in order
+type turnAuthBackends []TURNAuthBackend
+
+func (b turnAuthBackends) HandleAuth(username, realm string, srcAddr net.Addr) ([]byte, bool) {
+	for _, backend := range b {
+		if key, ok := backend.HandleAuth(username, realm, srcAddr); ok {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// TURNSharedSecretAuth verifies time limited credentials of the TURN REST API, as issued by coturn compatible
+// services: the username is "<unix expiry>:<user>", the password base64(HMAC-SHA1(secret, username))
+type TURNSharedSecretAuth struct {
+	secret []byte
+}
+
+func NewTURNSharedSecretAuth(secret string) (*TURNSharedSecretAuth, error) {
+	if secret == "" {
+		return nil, ErrTURNSharedSecretNotSet
+	}
+	return &TURNSharedSecretAuth{secret: []byte(secret)}, nil
+}
+
+// CreateCredentials issues credentials for user, valid for ttl
+func (a *TURNSharedSecretAuth) CreateCredentials(user string, ttl time.Duration) (username string, password string) {
+	username = strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	if user != "" {
+		username += ":" + user
+	}
+	return username, a.password(username)
+}
+
+func (a *TURNSharedSecretAuth) HandleAuth(username, realm string, srcAddr net.Addr) ([]byte, bool) {
+	expiry, _, _ := strings.Cut(username, ":")
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	if time.Now().Unix() > expiresAt {
+		logger.Debugw("rejecting TURN credentials", ErrTURNCredentialsExpired, "username", username, "remote", srcAddr)
+		return nil, false
+	}
+	return turn.GenerateAuthKey(username, LivekitRealm, a.password(username)), true
+}
+
+func (a *TURNSharedSecretAuth) password(username string) string {
+	mac := hmac.New(sha1.New, a.secret)
+	mac.Write([]byte(username))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// TURNAPIKeyAuth verifies ephemeral credentials issued by a service holding an API key, without a callback:
+// the username is "<unix expiry>:<api key>:<user>", the password base64(HMAC-SHA256(secret, username)).
+// These are long-term credentials, pion's TURN server does not support RFC 7635 access tokens
+type TURNAPIKeyAuth struct {
+	keyProvider auth.KeyProvider
+}
+
+func NewTURNAPIKeyAuth(keyProvider auth.KeyProvider) *TURNAPIKeyAuth {
+	return &TURNAPIKeyAuth{keyProvider: keyProvider}
+}
+
+// CreateTURNAPIKeyCredentials issues credentials for user valid for ttl, for the api_key TURN auth backend
+func CreateTURNAPIKeyCredentials(apiKey, apiSecret, user string, ttl time.Duration) (username string, password string, err error) {
+	username = strconv.FormatInt(time.Now().Add(ttl).Unix(), 10) + ":" + apiKey + ":" + user
+	if len(username) > maxTURNUsernameLength {
+		return "", "", ErrTURNUsernameTooLong
+	}
+	return username, turnAPIKeyPassword(apiSecret, username), nil
+}
+
+func (a *TURNAPIKeyAuth) HandleAuth(username, realm string, srcAddr net.Addr) ([]byte, bool) {
+	parts := strings.SplitN(username, ":", 3)
+	if len(parts) != 3 {
+		return nil, false
+	}
+	expiresAt, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	if time.Now().Unix() > expiresAt {
+		logger.Debugw("rejecting TURN credentials", ErrTURNCredentialsExpired, "username", username, "remote", srcAddr)
+		return nil, false
+	}
+	secret := a.keyProvider.GetSecret(parts[1])
+	if secret == "" {
+		logger.Debugw("rejecting TURN credentials", ErrInvalidAPIKey, "apiKey", parts[1], "remote", srcAddr)
+		return nil, false
+	}
+	return turn.GenerateAuthKey(username, LivekitRealm, turnAPIKeyPassword(secret, username)), true
+}
+
+func turnAPIKeyPassword(secret, username string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(username))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// TURNAuthRequest is posted to the rest backend for usernames that are not cached
+type TURNAuthRequest struct {
+	Username string `json:"username"`
+	Realm    string `json:"realm"`
+	Remote   string `json:"remote"`
+}
+
+// TURNAuthResponse accepts the username when password is set
+type TURNAuthResponse struct {
+	Password string `json:"password,omitempty"`
+	// how long the credentials can be cached, defaults to turn.auth.cache_ttl
+	TTLSeconds int64 `json:"ttl_seconds,omitempty"`
+}
+
+// turnAuthCacheEntry caches an accepted username with its key, or a rejected one with no key
+type turnAuthCacheEntry struct {
+	key       []byte
+	expiresAt time.Time
+}
+
+// TURNRESTAuth verifies credentials with a REST callback. Requests are signed like webhooks. Accepted usernames are
+// cached since TURN clients authenticate every allocation, permission and refresh, and rejected ones briefly
+type TURNRESTAuth struct {
+	url       string
+	apiKey    string
+	apiSecret string
+	cacheTTL  time.Duration
+	client    *http.Client
+
+	lock          sync.Mutex
+	cache         map[string]turnAuthCacheEntry
+	lookups       map[string]chan struct{}
+	windowStart   time.Time
+	windowLookups int
+}
+
+func NewTURNRESTAuth(conf *config.Config, provider auth.KeyProvider) (*TURNRESTAuth, error) {
+	authConf := conf.TURN.Auth
+	if authConf.URL == "" {
+		return nil, ErrTURNAuthURLNotSet
+	}
+	secret := provider.GetSecret(conf.WebHook.APIKey)
+	if secret == "" {
+		return nil, ErrTURNAuthMissingAPIKey
+	}
+
+	timeout := authConf.Timeout
+	if timeout <= 0 {
+		timeout = defaultTURNAuthTimeout
+	}
+	cacheTTL := authConf.CacheTTL
+	if cacheTTL <= 0 {
+		cacheTTL = defaultTURNAuthCacheTTL
+	}
+	return &TURNRESTAuth{
+		url:       authConf.URL,
+		apiKey:    conf.WebHook.APIKey,
+		apiSecret: secret,
+		cacheTTL:  cacheTTL,
+		client:    &http.Client{Timeout: timeout},
+		cache:     make(map[string]turnAuthCacheEntry),
+		lookups:   make(map[string]chan struct{}),
+	}, nil
+}
+
+func (a *TURNRESTAuth) HandleAuth(username, realm string, srcAddr net.Addr) ([]byte, bool) {
+	if len(username) > maxTURNUsernameLength {
+		return nil, false
+	}
+
+	a.lock.Lock()
+	entry, ok := a.cache[username]
+	if ok && time.Now().Before(entry.expiresAt) {
+		a.lock.Unlock()
+		return entry.key, entry.key != nil
+	}
+	done, err := a.startLookupLocked(username, realm, srcAddr)
+	a.lock.Unlock()
+	if err != nil {
+		logger.Debugw("could not verify TURN credentials", err, "username", username, "remote", srcAddr)
+		return nil, false
+	}
+
+	select {
+	case <-done:
+	case <-time.After(maxTURNAuthWait):
+		return nil, false
+	}
+
+	a.lock.Lock()
+	entry, ok = a.cache[username]
+	a.lock.Unlock()
+	return entry.key, ok && entry.key != nil
+}
+
+// startLookupLocked calls the rest endpoint for username in the background, unless it is already being looked up.
+// The returned channel is closed once the result is cached
+func (a *TURNRESTAuth) startLookupLocked(username, realm string, srcAddr net.Addr) (chan struct{}, error) {
+	if done, ok := a.lookups[username]; ok {
+		return done, nil
+	}
+
+	now := time.Now()
+	if now.Sub(a.windowStart) >= time.Second {
+		a.windowStart = now
+		a.windowLookups = 0
+	}
+	if a.windowLookups >= maxTURNAuthLookupsPerSecond {
+		return nil, ErrTURNAuthRateLimited
+	}
+	a.windowLookups++
+
+	done := make(chan struct{})
+	a.lookups[username] = done
+	go a.lookup(username, realm, srcAddr, done)
+	return done, nil
+}
+
+func (a *TURNRESTAuth) lookup(username, realm string, srcAddr net.Addr, done chan struct{}) {
+	res, err := a.post(&TURNAuthRequest{
+		Username: username,
+		Realm:    realm,
+		Remote:   srcAddr.String(),
+	})
+
+	now := time.Now()
+	var entry turnAuthCacheEntry
+	switch {
+	case err == nil:
+		ttl := a.cacheTTL
+		if res.TTLSeconds > 0 {
+			ttl = time.Duration(res.TTLSeconds) * time.Second
+		}
+		entry = turnAuthCacheEntry{
+			key:       turn.GenerateAuthKey(username, LivekitRealm, res.Password),
+			expiresAt: now.Add(ttl),
+		}
+	case errors.Is(err, ErrTURNCredentialsRejected):
+		logger.Debugw("rejecting TURN credentials", err, "username", username, "remote", srcAddr)
+		entry = turnAuthCacheEntry{expiresAt: now.Add(turnAuthRejectedCacheTTL)}
+	default:
+		// not cached, the endpoint may recover
+		logger.Warnw("could not verify TURN credentials", err, "username", username, "remote", srcAddr)
+	}
+
+	a.lock.Lock()
+	if !entry.expiresAt.IsZero() {
+		if len(a.cache) >= maxTURNAuthCacheSize {
+			for k, e := range a.cache {
+				if !now.Before(e.expiresAt) {
+					delete(a.cache, k)
+				}
+			}
+		}
+		if len(a.cache) < maxTURNAuthCacheSize {
+			a.cache[username] = entry
+		}
+	}
+	delete(a.lookups, username)
+	a.lock.Unlock()
+	close(done)
+}
+
+func (a *TURNRESTAuth) post(req *TURNAuthRequest) (*TURNAuthResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(body)
+	token, err := auth.NewAccessToken(a.apiKey, a.apiSecret).
+		SetValidFor(5 * time.Minute).
+		SetSha256(base64.StdEncoding.EncodeToString(sum[:])).
+		ToJWT()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := http.NewRequestWithContext(context.Background(), http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Authorization", token)
+	r.Header.Set("Content-Type", "application/webhook+json")
+
+	resp, err := a.client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, ErrTURNCredentialsRejected
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("TURN auth endpoint returned %d", resp.StatusCode)
+	}
+
+	res := &TURNAuthResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return nil, fmt.Errorf("invalid TURN auth response: %w", err)
+	}
+	if res.Password == "" {
+		return nil, ErrTURNCredentialsRejected
+	}
+	return res, nil
+}
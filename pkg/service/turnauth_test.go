@@ -0,0 +1,140 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pion/turn/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestTURNAuth(t *testing.T) {
+	remote := &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 5000}
+
+	t.Run("shared secret", func(t *testing.T) {
+		backend, err := service.NewTURNSharedSecretAuth("shared")
+		require.NoError(t, err)
+
+		username, password := backend.CreateCredentials("user", time.Minute)
+		key, ok := backend.HandleAuth(username, service.LivekitRealm, remote)
+		require.True(t, ok)
+		require.Equal(t, turn.GenerateAuthKey(username, service.LivekitRealm, password), key)
+
+		other, _ := service.NewTURNSharedSecretAuth("other")
+		key, ok = other.HandleAuth(username, service.LivekitRealm, remote)
+		require.True(t, ok)
+		require.NotEqual(t, turn.GenerateAuthKey(username, service.LivekitRealm, password), key)
+
+		expired, _ := backend.CreateCredentials("user", -time.Minute)
+		_, ok = backend.HandleAuth(expired, service.LivekitRealm, remote)
+		require.False(t, ok)
+
+		_, ok = backend.HandleAuth("user", service.LivekitRealm, remote)
+		require.False(t, ok)
+	})
+
+	t.Run("api key", func(t *testing.T) {
+		backend := service.NewTURNAPIKeyAuth(auth.NewSimpleKeyProvider("key", "secret"))
+
+		username, password, err := service.CreateTURNAPIKeyCredentials("key", "secret", "user", time.Minute)
+		require.NoError(t, err)
+		key, ok := backend.HandleAuth(username, service.LivekitRealm, remote)
+		require.True(t, ok)
+		require.Equal(t, turn.GenerateAuthKey(username, service.LivekitRealm, password), key)
+
+		// a password signed with another secret does not match the key
+		forged, forgedPassword, err := service.CreateTURNAPIKeyCredentials("key", "forged", "user", time.Minute)
+		require.NoError(t, err)
+		key, ok = backend.HandleAuth(forged, service.LivekitRealm, remote)
+		require.True(t, ok)
+		require.NotEqual(t, turn.GenerateAuthKey(forged, service.LivekitRealm, forgedPassword), key)
+
+		unknown, _, err := service.CreateTURNAPIKeyCredentials("unknown", "secret", "user", time.Minute)
+		require.NoError(t, err)
+		_, ok = backend.HandleAuth(unknown, service.LivekitRealm, remote)
+		require.False(t, ok)
+
+		expired, _, err := service.CreateTURNAPIKeyCredentials("key", "secret", "user", -time.Minute)
+		require.NoError(t, err)
+		_, ok = backend.HandleAuth(expired, service.LivekitRealm, remote)
+		require.False(t, ok)
+
+		_, _, err = service.CreateTURNAPIKeyCredentials("key", "secret", strings.Repeat("u", 500), time.Minute)
+		require.ErrorIs(t, err, service.ErrTURNUsernameTooLong)
+	})
+
+	t.Run("rest", func(t *testing.T) {
+		provider := auth.NewSimpleKeyProvider("key", "secret")
+
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			data, err := webhook.Receive(r, provider)
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			requests.Add(1)
+			req := &service.TURNAuthRequest{}
+			require.NoError(t, json.Unmarshal(data, req))
+			require.Equal(t, remote.String(), req.Remote)
+
+			if req.Username != "known" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(&service.TURNAuthResponse{Password: "password"})
+		}))
+		defer server.Close()
+
+		_, err := service.NewTURNRESTAuth(&config.Config{
+			TURN: config.TURNConfig{Auth: config.TURNAuthConfig{URL: server.URL}},
+		}, provider)
+		require.ErrorIs(t, err, service.ErrTURNAuthMissingAPIKey)
+
+		backend, err := service.NewTURNRESTAuth(&config.Config{
+			WebHook: config.WebHookConfig{APIKey: "key"},
+			TURN:    config.TURNConfig{Auth: config.TURNAuthConfig{URL: server.URL}},
+		}, provider)
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			key, ok := backend.HandleAuth("known", service.LivekitRealm, remote)
+			require.True(t, ok)
+			require.Equal(t, turn.GenerateAuthKey("known", service.LivekitRealm, "password"), key)
+		}
+		require.EqualValues(t, 1, requests.Load(), "accepted credentials should be cached")
+
+		// rejections are cached too
+		for i := 0; i < 3; i++ {
+			_, ok := backend.HandleAuth("unknown", service.LivekitRealm, remote)
+			require.False(t, ok)
+		}
+		require.EqualValues(t, 2, requests.Load())
+	})
+}
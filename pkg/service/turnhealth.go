@@ -0,0 +1,269 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"cmp"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/frostbyte73/core"
+	"github.com/pion/stun"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+const (
+	defaultTURNHealthCheckInterval  = 10 * time.Second
+	defaultTURNHealthCheckTimeout   = 2 * time.Second
+	defaultTURNHealthCheckThreshold = 2
+
+	stunHeaderSize = 20
+)
+
+var errUnexpectedSTUNResponse = errors.New("unexpected STUN response")
+
+type turnServerHealth struct {
+	healthy  bool
+	failures int
+	rtt      time.Duration
+}
+
+// TURNHealthChecker probes the external TURN servers of rtc.turn_servers, so dead servers can be left out of
+// the ICE servers sent to clients. Servers are healthy until probes fail failure_threshold times in a row
+type TURNHealthChecker struct {
+	conf    *config.Config
+	stopped core.Fuse
+
+	lock    sync.RWMutex
+	servers map[string]*turnServerHealth
+}
+
+func NewTURNHealthChecker(conf *config.Config) *TURNHealthChecker {
+	c := &TURNHealthChecker{
+		conf:    conf,
+		servers: make(map[string]*turnServerHealth),
+	}
+	if conf.RTC.TURNHealthCheck.Enabled {
+		go c.worker()
+	}
+	return c
+}
+
+func (c *TURNHealthChecker) Stop() {
+	c.stopped.Break()
+}
+
+// FilterServers returns the healthy servers, fastest first. When no server is healthy all are returned,
+// as clients are better off trying them than going without relays
+func (c *TURNHealthChecker) FilterServers(servers []config.TURNServer) []config.TURNServer {
+	if !c.conf.RTC.TURNHealthCheck.Enabled || len(servers) == 0 {
+		return servers
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	healthy := make([]config.TURNServer, 0, len(servers))
+	for _, s := range servers {
+		if h := c.servers[turnServerKey(s)]; h == nil || h.healthy {
+			healthy = append(healthy, s)
+		}
+	}
+	if len(healthy) == 0 {
+		return servers
+	}
+
+	// servers that were not probed yet go last
+	rtt := func(s config.TURNServer) time.Duration {
+		if h := c.servers[turnServerKey(s)]; h != nil && h.rtt > 0 {
+			return h.rtt
+		}
+		return math.MaxInt64
+	}
+	slices.SortStableFunc(healthy, func(a, b config.TURNServer) int {
+		return cmp.Compare(rtt(a), rtt(b))
+	})
+	return healthy
+}
+
+func (c *TURNHealthChecker) worker() {
+	interval := c.conf.RTC.TURNHealthCheck.Interval
+	if interval <= 0 {
+		interval = defaultTURNHealthCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		c.probeAll()
+
+		select {
+		case <-c.stopped.Watch():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *TURNHealthChecker) probeAll() {
+	checkConf := c.conf.RTC.TURNHealthCheck
+	timeout := checkConf.Timeout
+	if timeout <= 0 {
+		timeout = defaultTURNHealthCheckTimeout
+	}
+	threshold := checkConf.FailureThreshold
+	if threshold <= 0 {
+		threshold = defaultTURNHealthCheckThreshold
+	}
+
+	// turn_servers can be reloaded
+	servers := slices.Clone(c.conf.Reloadable().TURNServers)
+
+	var wg sync.WaitGroup
+	for _, s := range servers {
+		wg.Add(1)
+		go func(s config.TURNServer) {
+			defer wg.Done()
+			rtt, err := probeTURNServer(s, timeout)
+			c.update(s, rtt, err, threshold)
+		}(s)
+	}
+	wg.Wait()
+
+	c.lock.Lock()
+	for key := range c.servers {
+		if !slices.ContainsFunc(servers, func(s config.TURNServer) bool { return turnServerKey(s) == key }) {
+			delete(c.servers, key)
+			prometheus.RemoveTURNServer(key)
+		}
+	}
+	c.lock.Unlock()
+}
+
+func (c *TURNHealthChecker) update(s config.TURNServer, rtt time.Duration, err error, threshold int) {
+	key := turnServerKey(s)
+
+	c.lock.Lock()
+	h := c.servers[key]
+	if h == nil {
+		h = &turnServerHealth{healthy: true}
+		c.servers[key] = h
+	}
+	wasHealthy := h.healthy
+	if err == nil {
+		h.healthy = true
+		h.failures = 0
+		h.rtt = rtt
+	} else {
+		h.failures++
+		if h.failures >= threshold {
+			h.healthy = false
+		}
+	}
+	healthy := h.healthy
+	c.lock.Unlock()
+
+	prometheus.RecordTURNServerProbe(key, healthy, rtt, err)
+	switch {
+	case wasHealthy && !healthy:
+		logger.Warnw("TURN server is unhealthy, leaving it out of client configuration", err, "server", key)
+	case !wasHealthy && healthy:
+		logger.Infow("TURN server is healthy again", "server", key, "rtt", rtt)
+	}
+}
+
+func turnServerKey(s config.TURNServer) string {
+	protocol := s.Protocol
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	return fmt.Sprintf("%s/%s", net.JoinHostPort(s.Host, strconv.Itoa(s.Port)), protocol)
+}
+
+// probeTURNServer sends a STUN binding request over the protocol clients use, any STUN response proves the
+// server is up
+func probeTURNServer(s config.TURNServer, timeout time.Duration) (time.Duration, error) {
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	start := time.Now()
+	deadline := start.Add(timeout)
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Deadline: deadline}
+	switch s.Protocol {
+	case "udp":
+		conn, err = dialer.Dial("udp", addr)
+	case "tls":
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: s.Host})
+	default:
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if err = conn.SetDeadline(deadline); err != nil {
+		return 0, err
+	}
+
+	req, err := stun.Build(stun.TransactionID, stun.BindingRequest)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = conn.Write(req.Raw); err != nil {
+		return 0, err
+	}
+
+	res := &stun.Message{}
+	if s.Protocol == "udp" {
+		buf := make([]byte, 1500)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return 0, err
+		}
+		res.Raw = buf[:n]
+	} else {
+		// STUN over streams is framed by the length in its header
+		header := make([]byte, stunHeaderSize)
+		if _, err = io.ReadFull(conn, header); err != nil {
+			return 0, err
+		}
+		res.Raw = make([]byte, stunHeaderSize+int(binary.BigEndian.Uint16(header[2:4])))
+		copy(res.Raw, header)
+		if _, err = io.ReadFull(conn, res.Raw[stunHeaderSize:]); err != nil {
+			return 0, err
+		}
+	}
+	if err = res.Decode(); err != nil {
+		return 0, err
+	}
+	if res.TransactionID != req.TransactionID || res.Type.Method != stun.MethodBinding {
+		return 0, errUnexpectedSTUNResponse
+	}
+	return time.Since(start), nil
+}
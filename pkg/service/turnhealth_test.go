@@ -0,0 +1,82 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pion/turn/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestTURNHealthChecker(t *testing.T) {
+	udpConn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	tcpListener, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	relay := &turn.RelayAddressGeneratorStatic{RelayAddress: net.ParseIP("127.0.0.1"), Address: "127.0.0.1"}
+	server, err := turn.NewServer(turn.ServerConfig{
+		Realm:             service.LivekitRealm,
+		PacketConnConfigs: []turn.PacketConnConfig{{PacketConn: udpConn, RelayAddressGenerator: relay}},
+		ListenerConfigs:   []turn.ListenerConfig{{Listener: tcpListener, RelayAddressGenerator: relay}},
+	})
+	require.NoError(t, err)
+	defer server.Close()
+
+	// nothing is listening after the listener is closed
+	dead, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, dead.Close())
+
+	udpServer := config.TURNServer{Host: "127.0.0.1", Port: udpConn.LocalAddr().(*net.UDPAddr).Port, Protocol: "udp"}
+	tcpServer := config.TURNServer{Host: "127.0.0.1", Port: tcpListener.Addr().(*net.TCPAddr).Port, Protocol: "tcp"}
+	deadServer := config.TURNServer{Host: "127.0.0.1", Port: dead.Addr().(*net.TCPAddr).Port, Protocol: "tcp"}
+	servers := []config.TURNServer{deadServer, udpServer, tcpServer}
+
+	t.Run("disabled", func(t *testing.T) {
+		conf := &config.Config{RTC: config.RTCConfig{TURNServers: servers}}
+		checker := service.NewTURNHealthChecker(conf)
+		defer checker.Stop()
+		require.Equal(t, servers, checker.FilterServers(servers))
+	})
+
+	t.Run("drops dead servers", func(t *testing.T) {
+		conf := &config.Config{RTC: config.RTCConfig{
+			TURNServers: servers,
+			TURNHealthCheck: config.TURNHealthCheckConfig{
+				Enabled:          true,
+				Interval:         50 * time.Millisecond,
+				Timeout:          time.Second,
+				FailureThreshold: 2,
+			},
+		}}
+		checker := service.NewTURNHealthChecker(conf)
+		defer checker.Stop()
+
+		require.Eventually(t, func() bool {
+			filtered := checker.FilterServers(servers)
+			return len(filtered) == 2 && !slices.Contains(filtered, deadServer)
+		}, 5*time.Second, 20*time.Millisecond)
+
+		// with no healthy server, clients still get the full list
+		require.Equal(t, []config.TURNServer{deadServer}, checker.FilterServers([]config.TURNServer{deadServer}))
+	})
+}
@@ -0,0 +1,104 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const defaultUnixSocketMode = 0660
+
+// listenUnixSocket replaces a socket left behind by a previous run, and restricts the socket to conf.Mode
+func listenUnixSocket(conf config.UnixSocketConfig) (net.Listener, error) {
+	mode := fs.FileMode(defaultUnixSocketMode)
+	if conf.Mode != "" {
+		m, err := strconv.ParseUint(conf.Mode, 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unix socket mode %q: %w", conf.Mode, err)
+		}
+		mode = fs.FileMode(m)
+	}
+
+	if st, err := os.Lstat(conf.Path); err == nil {
+		if st.Mode()&fs.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", conf.Path)
+		}
+		if err = os.Remove(conf.Path); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	// bind inside a private directory and move the socket into place once its mode is set, so it is never
+	// reachable with the permissions of the umask
+	dir, err := os.MkdirTemp(filepath.Dir(conf.Path), ".livekit-socket-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	tmpPath := filepath.Join(dir, "sock")
+	ln, err := net.Listen("unix", tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err = os.Chmod(tmpPath, mode); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	if err = os.Rename(tmpPath, conf.Path); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	return &unixSocketListener{Listener: ln, path: conf.Path}, nil
+}
+
+// unixSocketListener removes the socket it was moved to when closed
+type unixSocketListener struct {
+	net.Listener
+	path string
+}
+
+func (l *unixSocketListener) Close() error {
+	err := l.Listener.Close()
+	_ = os.Remove(l.path)
+	return err
+}
+
+// trustSocketRequests grants every API permission to requests on the unix socket, as only processes that
+// may open the socket can connect
+func trustSocketRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(WithTrustedGrants(r.Context())))
+	})
+}
+
+// isSocketRequest reports whether the request arrived on the unix socket, and did not replace its grants with a token
+func isSocketRequest(ctx context.Context) bool {
+	v, ok := ctx.Value(grantsKey{}).(*grantsValue)
+	return ok && v.trusted && v.apiKey == ""
+}
@@ -0,0 +1,334 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/frostbyte73/core"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+const (
+	usageExportTimeout = 30 * time.Second
+	// records kept per sink while it is failing, older records are dropped past this
+	maxPendingUsageRecords = 10000
+)
+
+var (
+	dataTrackPrefix   = string(telemetry.BytesTrackIDForParticipantID(telemetry.BytesTrackTypeData, ""))
+	signalTrackPrefix = string(telemetry.BytesTrackIDForParticipantID(telemetry.BytesTrackTypeSignal, ""))
+)
+
+// UsageRecord is the usage of a room on a node over a metering interval.
+// Participant minutes are recorded when participants leave, and egress minutes when egresses end.
+type UsageRecord struct {
+	// empty for rooms not belonging to a project
+	Project string    `json:"project,omitempty"`
+	Room    string    `json:"room,omitempty"`
+	NodeID  string    `json:"node_id"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+
+	ParticipantMinutes float64 `json:"participant_minutes"`
+	// media received from publishers and sent to subscribers
+	PublishedBytes  uint64  `json:"published_bytes"`
+	SubscribedBytes uint64  `json:"subscribed_bytes"`
+	EgressMinutes   float64 `json:"egress_minutes"`
+	// data channel traffic in both directions
+	DataBytes uint64 `json:"data_bytes"`
+}
+
+type roomUsage struct {
+	participantTime time.Duration
+	publishedBytes  uint64
+	subscribedBytes uint64
+	dataBytes       uint64
+}
+
+type egressUsage struct {
+	roomName livekit.RoomName
+	duration time.Duration
+}
+
+type usageKey struct {
+	project  string
+	roomName livekit.RoomName
+}
+
+// UsageMeter accumulates usage from the stats and events of the analytics pipeline, and exports it every interval
+type UsageMeter struct {
+	interval time.Duration
+	nodeID   livekit.NodeID
+	sinks    []UsageSink
+	projects map[string]config.ProjectConfig
+
+	lock        sync.Mutex
+	periodStart time.Time
+	rooms       map[livekit.RoomName]*roomUsage
+	egress      map[string]*egressUsage
+	pending     [][]*UsageRecord
+
+	stopped core.Fuse
+	done    chan struct{}
+}
+
+// NewUsageMeter returns nil when metering is disabled
+func NewUsageMeter(conf *config.Config, currentNode routing.LocalNode) (*UsageMeter, error) {
+	if conf.Metering.Interval <= 0 {
+		return nil, nil
+	}
+	if s3 := conf.Metering.S3; s3.Bucket != "" && (s3.Region == "" || s3.AccessKey == "" || s3.Secret == "") {
+		return nil, errors.New("metering s3 requires region, access_key and secret")
+	}
+
+	sinks, err := createUsageSinks(conf)
+	if err != nil {
+		return nil, err
+	}
+	if len(sinks) == 0 {
+		return nil, errors.New("metering requires a file, url or s3 sink")
+	}
+
+	m := &UsageMeter{
+		interval:    conf.Metering.Interval,
+		nodeID:      livekit.NodeID(currentNode.Id),
+		sinks:       sinks,
+		projects:    conf.Projects,
+		periodStart: time.Now(),
+		rooms:       make(map[livekit.RoomName]*roomUsage),
+		egress:      make(map[string]*egressUsage),
+		pending:     make([][]*UsageRecord, len(sinks)),
+		done:        make(chan struct{}),
+	}
+	go m.worker()
+	return m, nil
+}
+
+// Stop exports usage accumulated so far
+func (m *UsageMeter) Stop() {
+	if m == nil || m.stopped.IsBroken() {
+		return
+	}
+	m.stopped.Break()
+	<-m.done
+}
+
+// RecordStats accounts media and data traffic of track stats
+func (m *UsageMeter) RecordStats(stats []*livekit.AnalyticsStat) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, stat := range stats {
+		if stat.RoomName == "" || strings.HasPrefix(stat.TrackId, signalTrackPrefix) {
+			continue
+		}
+
+		var bytes uint64
+		for _, stream := range stat.Streams {
+			bytes += stream.PrimaryBytes + stream.PaddingBytes + stream.RetransmitBytes
+		}
+
+		usage := m.roomUsageLocked(livekit.RoomName(stat.RoomName))
+		switch {
+		case strings.HasPrefix(stat.TrackId, dataTrackPrefix):
+			usage.dataBytes += bytes
+		case stat.Kind == livekit.StreamType_UPSTREAM:
+			usage.publishedBytes += bytes
+		default:
+			usage.subscribedBytes += bytes
+		}
+	}
+}
+
+// RecordEvent accounts sessions of participants leaving and egresses ending
+func (m *UsageMeter) RecordEvent(event *livekit.AnalyticsEvent) {
+	switch event.Type {
+	case livekit.AnalyticsEventType_PARTICIPANT_LEFT:
+		joinedAt := event.GetParticipant().GetJoinedAt()
+		if event.GetRoom().GetName() == "" || joinedAt == 0 {
+			return
+		}
+		duration := event.Timestamp.AsTime().Sub(time.Unix(joinedAt, 0))
+		if duration <= 0 {
+			return
+		}
+
+		m.lock.Lock()
+		m.roomUsageLocked(livekit.RoomName(event.Room.Name)).participantTime += duration
+		m.lock.Unlock()
+
+	case livekit.AnalyticsEventType_EGRESS_ENDED:
+		info := event.GetEgress()
+		if info.GetStartedAt() == 0 || info.GetEndedAt() <= info.GetStartedAt() {
+			return
+		}
+
+		m.lock.Lock()
+		m.egress[info.EgressId] = &egressUsage{
+			roomName: livekit.RoomName(info.RoomName),
+			duration: time.Duration(info.EndedAt - info.StartedAt),
+		}
+		m.lock.Unlock()
+	}
+}
+
+func (m *UsageMeter) roomUsageLocked(roomName livekit.RoomName) *roomUsage {
+	usage := m.rooms[roomName]
+	if usage == nil {
+		usage = &roomUsage{}
+		m.rooms[roomName] = usage
+	}
+	return usage
+}
+
+func (m *UsageMeter) worker() {
+	defer close(m.done)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stopped.Watch():
+			m.export(time.Now())
+			return
+		case now := <-ticker.C:
+			m.export(now)
+		}
+	}
+}
+
+func (m *UsageMeter) export(now time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), usageExportTimeout)
+	defer cancel()
+
+	records := m.collect(now)
+
+	m.lock.Lock()
+	batches := make([][]*UsageRecord, len(m.sinks))
+	for i := range m.sinks {
+		batches[i] = append(m.pending[i], records...)
+		if len(batches[i]) > maxPendingUsageRecords {
+			logger.Warnw("dropping usage records", nil, "count", len(batches[i])-maxPendingUsageRecords)
+			batches[i] = batches[i][len(batches[i])-maxPendingUsageRecords:]
+		}
+	}
+	m.lock.Unlock()
+
+	for i, sink := range m.sinks {
+		if len(batches[i]) == 0 {
+			continue
+		}
+		if err := sink.ExportUsage(ctx, batches[i]); err != nil {
+			logger.Errorw("could not export usage", err, "records", len(batches[i]))
+			continue
+		}
+		batches[i] = nil
+	}
+
+	m.lock.Lock()
+	m.pending = batches
+	m.lock.Unlock()
+}
+
+// collect returns the usage since the previous call, one record per project and room
+func (m *UsageMeter) collect(now time.Time) []*UsageRecord {
+	m.lock.Lock()
+	start := m.periodStart
+	rooms := m.rooms
+	egress := m.egress
+	m.periodStart = now
+	m.rooms = make(map[livekit.RoomName]*roomUsage)
+	m.egress = make(map[string]*egressUsage)
+	m.lock.Unlock()
+
+	byKey := make(map[usageKey]*UsageRecord)
+	record := func(key usageKey) *UsageRecord {
+		r := byKey[key]
+		if r == nil {
+			r = &UsageRecord{
+				Project: key.project,
+				Room:    string(key.roomName),
+				NodeID:  string(m.nodeID),
+				Start:   start,
+				End:     now,
+			}
+			byKey[key] = r
+		}
+		return r
+	}
+
+	for roomName, usage := range rooms {
+		r := record(m.usageKey(roomName))
+		r.ParticipantMinutes += usage.participantTime.Minutes()
+		r.PublishedBytes += usage.publishedBytes
+		r.SubscribedBytes += usage.subscribedBytes
+		r.DataBytes += usage.dataBytes
+	}
+	for _, usage := range egress {
+		r := record(m.usageKey(usage.roomName))
+		r.EgressMinutes += usage.duration.Minutes()
+	}
+
+	records := make([]*UsageRecord, 0, len(byKey))
+	for _, r := range byKey {
+		records = append(records, r)
+	}
+	return records
+}
+
+// usageKey records rooms of projects by their name in the project
+func (m *UsageMeter) usageKey(roomName livekit.RoomName) usageKey {
+	project, name := splitProjectRoomName(m.projects, roomName)
+	return usageKey{project: project, roomName: name}
+}
+
+// meteredAnalyticsService feeds stats and events to the usage meter before forwarding them
+type meteredAnalyticsService struct {
+	telemetry.AnalyticsService
+	meter *UsageMeter
+}
+
+func (a *meteredAnalyticsService) SendStats(ctx context.Context, stats []*livekit.AnalyticsStat) {
+	a.meter.RecordStats(stats)
+	a.AnalyticsService.SendStats(ctx, stats)
+}
+
+func (a *meteredAnalyticsService) SendEvent(ctx context.Context, event *livekit.AnalyticsEvent) {
+	a.meter.RecordEvent(event)
+	a.AnalyticsService.SendEvent(ctx, event)
+}
+
+func createAnalyticsService(conf *config.Config, currentNode routing.LocalNode, meter *UsageMeter) telemetry.AnalyticsService {
+	analytics := telemetry.NewAnalyticsService(conf, currentNode)
+	if meter == nil {
+		return analytics
+	}
+	return &meteredAnalyticsService{
+		AnalyticsService: analytics,
+		meter:            meter,
+	}
+}
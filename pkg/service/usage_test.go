@@ -0,0 +1,144 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+func TestUsageMeter(t *testing.T) {
+	newUsageMeter := func(t *testing.T, metering config.MeteringConfig) *service.UsageMeter {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		conf.Metering = metering
+
+		node, err := routing.NewLocalNode(conf)
+		require.NoError(t, err)
+
+		m, err := service.NewUsageMeter(conf, node)
+		require.NoError(t, err)
+		return m
+	}
+
+	record := func(m *service.UsageMeter) {
+		m.RecordStats([]*livekit.AnalyticsStat{
+			{RoomName: "room1", TrackId: "TR_video", Kind: livekit.StreamType_UPSTREAM, Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 1000}}},
+			{RoomName: "room1", TrackId: "TR_video", Kind: livekit.StreamType_DOWNSTREAM, Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 2000, RetransmitBytes: 100}}},
+			{RoomName: "room1", TrackId: string(telemetry.BytesTrackIDForParticipantID(telemetry.BytesTrackTypeData, "PA_1")), Kind: livekit.StreamType_UPSTREAM, Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 50}}},
+			{RoomName: "room1", TrackId: string(telemetry.BytesTrackIDForParticipantID(telemetry.BytesTrackTypeSignal, "PA_1")), Kind: livekit.StreamType_UPSTREAM, Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 70}}},
+		})
+
+		now := time.Now()
+		m.RecordEvent(&livekit.AnalyticsEvent{
+			Type:        livekit.AnalyticsEventType_PARTICIPANT_LEFT,
+			Timestamp:   timestamppb.New(now),
+			Room:        &livekit.Room{Name: "room1"},
+			Participant: &livekit.ParticipantInfo{JoinedAt: now.Add(-3 * time.Minute).Unix()},
+		})
+		m.RecordEvent(&livekit.AnalyticsEvent{
+			Type: livekit.AnalyticsEventType_EGRESS_ENDED,
+			Egress: &livekit.EgressInfo{
+				EgressId:  "EG_1",
+				RoomName:  "room1",
+				StartedAt: now.Add(-2 * time.Minute).UnixNano(),
+				EndedAt:   now.UnixNano(),
+			},
+		})
+	}
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "usage", "usage.jsonl")
+		m := newUsageMeter(t, config.MeteringConfig{Interval: time.Hour, File: path})
+		record(m)
+		m.Stop()
+
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		defer f.Close()
+
+		var records []*service.UsageRecord
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			r := &service.UsageRecord{}
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), r))
+			records = append(records, r)
+		}
+		require.Len(t, records, 1)
+		r := records[0]
+		require.Equal(t, "room1", r.Room)
+		require.InDelta(t, 3, r.ParticipantMinutes, 0.1)
+		require.EqualValues(t, 1000, r.PublishedBytes)
+		require.EqualValues(t, 2100, r.SubscribedBytes)
+		require.EqualValues(t, 50, r.DataBytes)
+		require.InDelta(t, 2, r.EgressMinutes, 0.01)
+	})
+
+	t.Run("s3", func(t *testing.T) {
+		var uploads []*http.Request
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			uploads = append(uploads, r)
+		}))
+		defer server.Close()
+
+		m := newUsageMeter(t, config.MeteringConfig{
+			Interval: time.Hour,
+			S3: config.MeteringS3Config{
+				Bucket:    "billing",
+				Region:    "us-east-1",
+				Endpoint:  server.URL,
+				AccessKey: "key",
+				Secret:    "secret",
+				Prefix:    "usage/",
+			},
+		})
+		record(m)
+		m.Stop()
+
+		require.Len(t, uploads, 1)
+		require.Equal(t, http.MethodPut, uploads[0].Method)
+		require.True(t, strings.HasPrefix(uploads[0].URL.Path, "/billing/usage/"))
+		require.True(t, strings.HasPrefix(uploads[0].Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/"))
+	})
+
+	t.Run("requires a sink", func(t *testing.T) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		conf.Metering.Interval = time.Minute
+
+		node, err := routing.NewLocalNode(conf)
+		require.NoError(t, err)
+
+		_, err = service.NewUsageMeter(conf, node)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,249 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/auth"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// UsageSink receives batches of usage records. A batch that fails is retried with the next one
+type UsageSink interface {
+	ExportUsage(ctx context.Context, records []*UsageRecord) error
+}
+
+// createUsageSinks returns the sinks configured for metering
+func createUsageSinks(conf *config.Config) ([]UsageSink, error) {
+	var sinks []UsageSink
+	if conf.Metering.File != "" {
+		sinks = append(sinks, NewFileUsageSink(conf.Metering.File))
+	}
+	if conf.Metering.URL != "" {
+		sink, err := NewWebhookUsageSink(conf)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if conf.Metering.S3.Bucket != "" {
+		sinks = append(sinks, NewS3UsageSink(conf.Metering.S3))
+	}
+	return sinks, nil
+}
+
+func marshalUsageRecords(records []*UsageRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// FileUsageSink appends records to a file as JSON lines
+type FileUsageSink struct {
+	path string
+}
+
+func NewFileUsageSink(path string) *FileUsageSink {
+	return &FileUsageSink{path: path}
+}
+
+func (s *FileUsageSink) ExportUsage(_ context.Context, records []*UsageRecord) error {
+	data, err := marshalUsageRecords(records)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WebhookUsageSink posts records to a URL as a JSON array. Requests are signed the same way as webhooks
+type WebhookUsageSink struct {
+	url       string
+	apiKey    string
+	apiSecret string
+	client    *http.Client
+}
+
+func NewWebhookUsageSink(conf *config.Config) (*WebhookUsageSink, error) {
+	secret := conf.Keys[conf.WebHook.APIKey]
+	if secret == "" {
+		return nil, ErrMeteringMissingAPIKey
+	}
+
+	return &WebhookUsageSink{
+		url:       conf.Metering.URL,
+		apiKey:    conf.WebHook.APIKey,
+		apiSecret: secret,
+		client:    &http.Client{},
+	}, nil
+}
+
+func (s *WebhookUsageSink) ExportUsage(ctx context.Context, records []*UsageRecord) error {
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+	token, err := auth.NewAccessToken(s.apiKey, s.apiSecret).
+		SetValidFor(5 * time.Minute).
+		SetSha256(base64.StdEncoding.EncodeToString(sum[:])).
+		ToJWT()
+	if err != nil {
+		return err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Authorization", token)
+	r.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("usage endpoint returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// S3UsageSink uploads each batch as a JSON lines object, with requests signed using AWS signature version 4
+type S3UsageSink struct {
+	conf   config.MeteringS3Config
+	client *http.Client
+}
+
+func NewS3UsageSink(conf config.MeteringS3Config) *S3UsageSink {
+	return &S3UsageSink{
+		conf:   conf,
+		client: &http.Client{},
+	}
+}
+
+func (s *S3UsageSink) ExportUsage(ctx context.Context, records []*UsageRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	data, err := marshalUsageRecords(records)
+	if err != nil {
+		return err
+	}
+
+	last := records[len(records)-1]
+	key := fmt.Sprintf("%s%s/%d.jsonl", s.conf.Prefix, last.NodeID, last.End.Unix())
+	var url string
+	if s.conf.Endpoint != "" {
+		url = fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(s.conf.Endpoint, "/"), s.conf.Bucket, key)
+	} else {
+		url = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.conf.Bucket, s.conf.Region, key)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/x-ndjson")
+	s.sign(r, data, time.Now())
+
+	resp, err := s.client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("s3 upload returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *S3UsageSink) sign(r *http.Request, payload []byte, now time.Time) {
+	payloadHash := sha256.Sum256(payload)
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	r.Header.Set("X-Amz-Date", amzDate)
+	r.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	signedHeaders := "content-type;host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		"",
+		"content-type:" + r.Header.Get("Content-Type"),
+		"host:" + r.URL.Host,
+		"x-amz-content-sha256:" + r.Header.Get("X-Amz-Content-Sha256"),
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := date + "/" + s.conf.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := []byte("AWS4" + s.conf.Secret)
+	for _, part := range []string{date, s.conf.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	r.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.conf.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
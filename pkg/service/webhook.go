@@ -0,0 +1,113 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+// WebhookNotifier queues webhooks to the configured URLs, which can be changed when the config is reloaded
+type WebhookNotifier struct {
+	provider auth.KeyProvider
+	// sees every event, even when no URLs are configured
+	interceptor rtc.MediaInterceptor
+	events      chan *livekit.WebhookEvent
+
+	lock     sync.RWMutex
+	notifier *webhook.DefaultNotifier
+}
+
+func NewWebhookNotifier(conf config.WebHookConfig, provider auth.KeyProvider, interceptor rtc.MediaInterceptor) (*WebhookNotifier, error) {
+	n := &WebhookNotifier{
+		provider:    provider,
+		interceptor: interceptor,
+	}
+	if err := n.Update(conf); err != nil {
+		return nil, err
+	}
+	if interceptor != nil {
+		n.events = make(chan *livekit.WebhookEvent, interceptorEventQueueSize)
+		go n.interceptorWorker()
+	}
+	return n, nil
+}
+
+const interceptorEventQueueSize = 1000
+
+// interceptorWorker passes events to the interceptor in order, off the paths queueing them
+func (n *WebhookNotifier) interceptorWorker() {
+	for event := range n.events {
+		n.interceptor.OnRoomEvent(event)
+	}
+}
+
+// Validate checks that webhooks can be signed with the configured key
+func (n *WebhookNotifier) Validate(conf config.WebHookConfig) error {
+	if len(conf.URLs) != 0 && n.provider.GetSecret(conf.APIKey) == "" {
+		return ErrWebHookMissingAPIKey
+	}
+	return nil
+}
+
+// Update replaces the notifier, webhooks queued to the previous URLs are still delivered
+func (n *WebhookNotifier) Update(conf config.WebHookConfig) error {
+	if err := n.Validate(conf); err != nil {
+		return err
+	}
+
+	var notifier *webhook.DefaultNotifier
+	if len(conf.URLs) != 0 {
+		secret := n.provider.GetSecret(conf.APIKey)
+		notifier = webhook.NewDefaultNotifier(conf.APIKey, secret, conf.URLs).(*webhook.DefaultNotifier)
+	}
+
+	n.lock.Lock()
+	prev := n.notifier
+	n.notifier = notifier
+	n.lock.Unlock()
+
+	if prev != nil {
+		go prev.Stop(false)
+	}
+	return nil
+}
+
+func (n *WebhookNotifier) QueueNotify(ctx context.Context, event *livekit.WebhookEvent) error {
+	if n.events != nil {
+		select {
+		case n.events <- event:
+		default:
+			logger.Warnw("room event queue full, dropping event", nil, "event", event.Event)
+		}
+	}
+
+	n.lock.RLock()
+	notifier := n.notifier
+	n.lock.RUnlock()
+
+	if notifier == nil {
+		return nil
+	}
+	return notifier.QueueNotify(ctx, event)
+}
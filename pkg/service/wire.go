@@ -31,6 +31,7 @@ import (
 	"github.com/livekit/livekit-server/pkg/clientconfiguration"
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
 	"github.com/livekit/livekit-server/pkg/sfu"
 	"github.com/livekit/livekit-server/pkg/telemetry"
 	"github.com/livekit/protocol/auth"
@@ -47,20 +48,26 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 	wire.Build(
 		getNodeID,
 		createRedisClient,
+		routing.NewFailoverMonitor,
 		createStore,
 		wire.Bind(new(ServiceStore), new(ObjectStore)),
 		createKeyProvider,
+		createRoomHooks,
+		createMediaInterceptor,
 		createWebhookNotifier,
+		wire.Bind(new(webhook.QueuedNotifier), new(*WebhookNotifier)),
 		createClientConfiguration,
 		createForwardStats,
-		routing.CreateRouter,
+		createRouter,
 		getLimitConf,
 		config.DefaultAPIConfig,
 		wire.Bind(new(routing.MessageRouter), new(routing.Router)),
 		wire.Bind(new(livekit.RoomService), new(*RoomService)),
-		telemetry.NewAnalyticsService,
+		NewUsageMeter,
+		createAnalyticsService,
 		telemetry.NewTelemetryService,
 		getMessageBus,
+		NewIngressAuthenticator,
 		NewIOInfoService,
 		wire.Bind(new(IOClient), new(*IOInfoService)),
 		rpc.NewEgressClient,
@@ -77,6 +84,8 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 		NewSIPService,
 		NewRoomAllocator,
 		NewRoomService,
+		NewQuotaManager,
+		NewProjectScope,
 		NewRTCService,
 		NewAgentService,
 		NewAgentDispatchService,
@@ -97,8 +106,10 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 		NewLocalRoomManager,
 		NewTURNAuthHandler,
 		getTURNAuthHandlerFunc,
+		NewTLSCertificates,
 		newInProcessTurnServer,
 		utils.NewDefaultTimedVersionGenerator,
+		NewSnapshotService,
 		NewLivekitServer,
 	)
 	return &LivekitServer{}, nil
@@ -107,6 +118,7 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 func InitializeRouter(conf *config.Config, currentNode routing.LocalNode) (routing.Router, error) {
 	wire.Build(
 		createRedisClient,
+		routing.NewFailoverMonitor,
 		getNodeID,
 		getMessageBus,
 		getSignalRelayConfig,
@@ -116,7 +128,7 @@ func InitializeRouter(conf *config.Config, currentNode routing.LocalNode) (routi
 		getRoomConfig,
 		routing.NewRoomManagerClient,
 		rpc.NewKeepalivePubSub,
-		routing.CreateRouter,
+		createRouter,
 	)
 
 	return nil, nil
@@ -155,17 +167,8 @@ func createKeyProvider(conf *config.Config) (auth.KeyProvider, error) {
 	return auth.NewFileBasedKeyProviderFromMap(conf.Keys), nil
 }
 
-func createWebhookNotifier(conf *config.Config, provider auth.KeyProvider) (webhook.QueuedNotifier, error) {
-	wc := conf.WebHook
-	if len(wc.URLs) == 0 {
-		return nil, nil
-	}
-	secret := provider.GetSecret(wc.APIKey)
-	if secret == "" {
-		return nil, ErrWebHookMissingAPIKey
-	}
-
-	return webhook.NewDefaultNotifier(wc.APIKey, secret, wc.URLs), nil
+func createWebhookNotifier(conf *config.Config, provider auth.KeyProvider, interceptor rtc.MediaInterceptor) (*WebhookNotifier, error) {
+	return NewWebhookNotifier(conf.WebHook, provider, interceptor)
 }
 
 func createRedisClient(conf *config.Config) (redis.UniversalClient, error) {
@@ -175,47 +178,25 @@ func createRedisClient(conf *config.Config) (redis.UniversalClient, error) {
 	return redisLiveKit.GetRedisClient(&conf.Redis)
 }
 
-func createStore(rc redis.UniversalClient) ObjectStore {
-	if rc != nil {
-		return NewRedisStore(rc)
-	}
-	return NewLocalStore()
-}
-
-func getMessageBus(rc redis.UniversalClient) psrpc.MessageBus {
-	if rc == nil {
-		return psrpc.NewLocalMessageBus()
-	}
-	return psrpc.NewRedisMessageBus(rc)
-}
-
 func getEgressStore(s ObjectStore) EgressStore {
-	switch store := s.(type) {
-	case *RedisStore:
+	if store, ok := s.(EgressStore); ok {
 		return store
-	default:
-		return nil
 	}
+	return nil
 }
 
 func getIngressStore(s ObjectStore) IngressStore {
-	switch store := s.(type) {
-	case *RedisStore:
+	if store, ok := s.(IngressStore); ok {
 		return store
-	default:
-		return nil
 	}
+	return nil
 }
 
 func getAgentStore(s ObjectStore) AgentStore {
-	switch store := s.(type) {
-	case *RedisStore:
+	if store, ok := s.(AgentStore); ok {
 		return store
-	case *LocalStore:
-		return store
-	default:
-		return nil
 	}
+	return nil
 }
 
 func getIngressConfig(conf *config.Config) *config.IngressConfig {
@@ -223,12 +204,10 @@ func getIngressConfig(conf *config.Config) *config.IngressConfig {
 }
 
 func getSIPStore(s ObjectStore) SIPStore {
-	switch store := s.(type) {
-	case *RedisStore:
+	if store, ok := s.(SIPStore); ok {
 		return store
-	default:
-		return nil
 	}
+	return nil
 }
 
 func getSIPConfig(conf *config.Config) *config.SIPConfig {
@@ -266,6 +245,6 @@ func createForwardStats(conf *config.Config) *sfu.ForwardStats {
 	return sfu.NewForwardStats(conf.RTC.ForwardStats.SummaryInterval, conf.RTC.ForwardStats.ReportInterval, conf.RTC.ForwardStats.ReportWindow)
 }
 
-func newInProcessTurnServer(conf *config.Config, authHandler turn.AuthHandler) (*turn.Server, error) {
-	return NewTurnServer(conf, authHandler, false)
+func newInProcessTurnServer(conf *config.Config, authHandler turn.AuthHandler, certs *TLSCertificates) (*turn.Server, error) {
+	return NewTurnServer(conf, authHandler, certs, false)
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/livekit/livekit-server/pkg/clientconfiguration"
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
 	"github.com/livekit/livekit-server/pkg/sfu"
 	"github.com/livekit/livekit-server/pkg/telemetry"
 	"github.com/livekit/protocol/auth"
@@ -20,7 +21,6 @@ import (
 	redis2 "github.com/livekit/protocol/redis"
 	"github.com/livekit/protocol/rpc"
 	"github.com/livekit/protocol/utils"
-	"github.com/livekit/protocol/webhook"
 	"github.com/livekit/psrpc"
 	"github.com/pion/turn/v2"
 	"github.com/pkg/errors"
@@ -42,10 +42,14 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 	if err != nil {
 		return nil, err
 	}
+	failoverMonitor := routing.NewFailoverMonitor(universalClient)
 	nodeID := getNodeID(currentNode)
-	messageBus := getMessageBus(universalClient)
+	messageBus, err := getMessageBus(conf, universalClient)
+	if err != nil {
+		return nil, err
+	}
 	signalRelayConfig := getSignalRelayConfig(conf)
-	signalClient, err := routing.NewSignalClient(nodeID, messageBus, signalRelayConfig)
+	signalClient, err := routing.NewSignalClient(nodeID, messageBus, signalRelayConfig, failoverMonitor)
 	if err != nil {
 		return nil, err
 	}
@@ -60,9 +64,23 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 	if err != nil {
 		return nil, err
 	}
-	router := routing.CreateRouter(universalClient, currentNode, signalClient, roomManagerClient, keepalivePubSub)
-	objectStore := createStore(universalClient)
-	roomAllocator, err := NewRoomAllocator(conf, router, objectStore)
+	router, err := createRouter(conf, universalClient, currentNode, signalClient, roomManagerClient, keepalivePubSub, failoverMonitor)
+	if err != nil {
+		return nil, err
+	}
+	objectStore, err := createStore(conf, universalClient)
+	if err != nil {
+		return nil, err
+	}
+	keyProvider, err := createKeyProvider(conf)
+	if err != nil {
+		return nil, err
+	}
+	roomHooks, err := createRoomHooks(conf, keyProvider)
+	if err != nil {
+		return nil, err
+	}
+	roomAllocator, err := NewRoomAllocator(conf, router, objectStore, roomHooks)
 	if err != nil {
 		return nil, err
 	}
@@ -77,17 +95,25 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 	egressStore := getEgressStore(objectStore)
 	ingressStore := getIngressStore(objectStore)
 	sipStore := getSIPStore(objectStore)
-	keyProvider, err := createKeyProvider(conf)
+	mediaInterceptor, err := createMediaInterceptor(conf)
+	if err != nil {
+		return nil, err
+	}
+	webhookNotifier, err := createWebhookNotifier(conf, keyProvider, mediaInterceptor)
+	if err != nil {
+		return nil, err
+	}
+	usageMeter, err := NewUsageMeter(conf, currentNode)
 	if err != nil {
 		return nil, err
 	}
-	queuedNotifier, err := createWebhookNotifier(conf, keyProvider)
+	analyticsService := createAnalyticsService(conf, currentNode, usageMeter)
+	telemetryService := telemetry.NewTelemetryService(webhookNotifier, analyticsService)
+	ingressAuthenticator, err := NewIngressAuthenticator(conf, keyProvider)
 	if err != nil {
 		return nil, err
 	}
-	analyticsService := telemetry.NewAnalyticsService(conf, currentNode)
-	telemetryService := telemetry.NewTelemetryService(queuedNotifier, analyticsService)
-	ioInfoService, err := NewIOInfoService(messageBus, egressStore, ingressStore, sipStore, telemetryService)
+	ioInfoService, err := NewIOInfoService(messageBus, egressStore, ingressStore, sipStore, telemetryService, ingressAuthenticator)
 	if err != nil {
 		return nil, err
 	}
@@ -123,8 +149,9 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 		return nil, err
 	}
 	sipService := NewSIPService(sipConfig, nodeID, messageBus, sipClient, sipStore, roomService, telemetryService)
-	rtcService := NewRTCService(conf, roomAllocator, objectStore, router, currentNode, client, telemetryService)
-	agentService, err := NewAgentService(conf, currentNode, messageBus, keyProvider)
+	quotaManager := NewQuotaManager(conf, currentNode, objectStore)
+	rtcService := NewRTCService(conf, roomAllocator, objectStore, router, currentNode, client, telemetryService, quotaManager, roomHooks)
+	agentService, err := NewAgentService(conf, currentNode, messageBus, keyProvider, telemetryService)
 	if err != nil {
 		return nil, err
 	}
@@ -133,20 +160,35 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 	timedVersionGenerator := utils.NewDefaultTimedVersionGenerator()
 	turnAuthHandler := NewTURNAuthHandler(keyProvider)
 	forwardStats := createForwardStats(conf)
-	roomManager, err := NewLocalRoomManager(conf, objectStore, currentNode, router, roomAllocator, telemetryService, clientConfigurationManager, client, agentStore, rtcEgressLauncher, timedVersionGenerator, turnAuthHandler, messageBus, forwardStats)
+	roomManager, err := NewLocalRoomManager(conf, objectStore, currentNode, router, roomAllocator, telemetryService, clientConfigurationManager, client, agentStore, rtcEgressLauncher, timedVersionGenerator, turnAuthHandler, messageBus, forwardStats, mediaInterceptor)
+	if err != nil {
+		return nil, err
+	}
+	signalServer, err := NewDefaultSignalServer(currentNode, messageBus, signalRelayConfig, failoverMonitor, router, roomManager)
+	if err != nil {
+		return nil, err
+	}
+	authHandler, err := getTURNAuthHandlerFunc(conf, turnAuthHandler, keyProvider)
+	if err != nil {
+		return nil, err
+	}
+	tlsCertificates, err := NewTLSCertificates(conf)
+	if err != nil {
+		return nil, err
+	}
+	server, err := newInProcessTurnServer(conf, authHandler, tlsCertificates)
 	if err != nil {
 		return nil, err
 	}
-	signalServer, err := NewDefaultSignalServer(currentNode, messageBus, signalRelayConfig, router, roomManager)
+	projectScope, err := NewProjectScope(conf, egressStore, ingressStore)
 	if err != nil {
 		return nil, err
 	}
-	authHandler := getTURNAuthHandlerFunc(turnAuthHandler)
-	server, err := newInProcessTurnServer(conf, authHandler)
+	snapshotService, err := NewSnapshotService(conf, roomManager)
 	if err != nil {
 		return nil, err
 	}
-	livekitServer, err := NewLivekitServer(conf, roomService, agentDispatchService, egressService, ingressService, sipService, ioInfoService, rtcService, agentService, keyProvider, router, roomManager, signalServer, server, currentNode)
+	livekitServer, err := NewLivekitServer(conf, roomService, agentDispatchService, egressService, ingressService, sipService, ioInfoService, rtcService, agentService, keyProvider, router, failoverMonitor, universalClient, keepalivePubSub, roomManager, signalServer, quotaManager, projectScope, usageMeter, server, tlsCertificates, currentNode, webhookNotifier, snapshotService)
 	if err != nil {
 		return nil, err
 	}
@@ -158,10 +200,14 @@ func InitializeRouter(conf *config.Config, currentNode routing.LocalNode) (routi
 	if err != nil {
 		return nil, err
 	}
+	failoverMonitor := routing.NewFailoverMonitor(universalClient)
 	nodeID := getNodeID(currentNode)
-	messageBus := getMessageBus(universalClient)
+	messageBus, err := getMessageBus(conf, universalClient)
+	if err != nil {
+		return nil, err
+	}
 	signalRelayConfig := getSignalRelayConfig(conf)
-	signalClient, err := routing.NewSignalClient(nodeID, messageBus, signalRelayConfig)
+	signalClient, err := routing.NewSignalClient(nodeID, messageBus, signalRelayConfig, failoverMonitor)
 	if err != nil {
 		return nil, err
 	}
@@ -176,7 +222,10 @@ func InitializeRouter(conf *config.Config, currentNode routing.LocalNode) (routi
 	if err != nil {
 		return nil, err
 	}
-	router := routing.CreateRouter(universalClient, currentNode, signalClient, roomManagerClient, keepalivePubSub)
+	router, err := createRouter(conf, universalClient, currentNode, signalClient, roomManagerClient, keepalivePubSub, failoverMonitor)
+	if err != nil {
+		return nil, err
+	}
 	return router, nil
 }
 
@@ -215,17 +264,8 @@ func createKeyProvider(conf *config.Config) (auth.KeyProvider, error) {
 	return auth.NewFileBasedKeyProviderFromMap(conf.Keys), nil
 }
 
-func createWebhookNotifier(conf *config.Config, provider auth.KeyProvider) (webhook.QueuedNotifier, error) {
-	wc := conf.WebHook
-	if len(wc.URLs) == 0 {
-		return nil, nil
-	}
-	secret := provider.GetSecret(wc.APIKey)
-	if secret == "" {
-		return nil, ErrWebHookMissingAPIKey
-	}
-
-	return webhook.NewDefaultNotifier(wc.APIKey, secret, wc.URLs), nil
+func createWebhookNotifier(conf *config.Config, provider auth.KeyProvider, interceptor rtc.MediaInterceptor) (*WebhookNotifier, error) {
+	return NewWebhookNotifier(conf.WebHook, provider, interceptor)
 }
 
 func createRedisClient(conf *config.Config) (redis.UniversalClient, error) {
@@ -235,47 +275,25 @@ func createRedisClient(conf *config.Config) (redis.UniversalClient, error) {
 	return redis2.GetRedisClient(&conf.Redis)
 }
 
-func createStore(rc redis.UniversalClient) ObjectStore {
-	if rc != nil {
-		return NewRedisStore(rc)
-	}
-	return NewLocalStore()
-}
-
-func getMessageBus(rc redis.UniversalClient) psrpc.MessageBus {
-	if rc == nil {
-		return psrpc.NewLocalMessageBus()
-	}
-	return psrpc.NewRedisMessageBus(rc)
-}
-
 func getEgressStore(s ObjectStore) EgressStore {
-	switch store := s.(type) {
-	case *RedisStore:
+	if store, ok := s.(EgressStore); ok {
 		return store
-	default:
-		return nil
 	}
+	return nil
 }
 
 func getIngressStore(s ObjectStore) IngressStore {
-	switch store := s.(type) {
-	case *RedisStore:
+	if store, ok := s.(IngressStore); ok {
 		return store
-	default:
-		return nil
 	}
+	return nil
 }
 
 func getAgentStore(s ObjectStore) AgentStore {
-	switch store := s.(type) {
-	case *RedisStore:
+	if store, ok := s.(AgentStore); ok {
 		return store
-	case *LocalStore:
-		return store
-	default:
-		return nil
 	}
+	return nil
 }
 
 func getIngressConfig(conf *config.Config) *config.IngressConfig {
@@ -283,12 +301,10 @@ func getIngressConfig(conf *config.Config) *config.IngressConfig {
 }
 
 func getSIPStore(s ObjectStore) SIPStore {
-	switch store := s.(type) {
-	case *RedisStore:
+	if store, ok := s.(SIPStore); ok {
 		return store
-	default:
-		return nil
 	}
+	return nil
 }
 
 func getSIPConfig(conf *config.Config) *config.SIPConfig {
@@ -326,6 +342,6 @@ func createForwardStats(conf *config.Config) *sfu.ForwardStats {
 	return sfu.NewForwardStats(conf.RTC.ForwardStats.SummaryInterval, conf.RTC.ForwardStats.ReportInterval, conf.RTC.ForwardStats.ReportWindow)
 }
 
-func newInProcessTurnServer(conf *config.Config, authHandler turn.AuthHandler) (*turn.Server, error) {
-	return NewTurnServer(conf, authHandler, false)
+func newInProcessTurnServer(conf *config.Config, authHandler turn.AuthHandler, certs *TLSCertificates) (*turn.Server, error) {
+	return NewTurnServer(conf, authHandler, certs, false)
 }
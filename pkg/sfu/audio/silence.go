@@ -0,0 +1,75 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audio
+
+import (
+	"time"
+)
+
+// Opus DTX and comfort noise frames are a few bytes
+const dtxMaxPayloadSize = 3
+
+type SilenceDetectorParams struct {
+	// audio level at or above which a packet is silent, 0-127, where 0 is loudest
+	SilentLevel uint8
+	// audio level below which a paused track resumes. lower (louder) than SilentLevel,
+	// so that noise around the silent level does not toggle forwarding
+	ResumeLevel uint8
+	// duration of continuous silence before pausing
+	PauseAfter time.Duration
+}
+
+// SilenceDetector detects prolonged silence on an audio track from audio levels and DTX frames.
+// It is not safe for concurrent use, packets are observed from the forwarding goroutine.
+type SilenceDetector struct {
+	params SilenceDetectorParams
+
+	silentSince int64
+	paused      bool
+}
+
+func NewSilenceDetector(params SilenceDetectorParams) *SilenceDetector {
+	return &SilenceDetector{
+		params: params,
+	}
+}
+
+// Observe records a packet with its audio level, and returns whether the track is paused
+// and if that changed with this packet
+func (s *SilenceDetector) Observe(level uint8, payloadSize int, arrivalTime int64) (bool, bool) {
+	dtx := payloadSize <= dtxMaxPayloadSize
+	if s.paused {
+		if dtx || level >= s.params.ResumeLevel {
+			return true, false
+		}
+		s.paused = false
+		s.silentSince = 0
+		return false, true
+	}
+
+	if !dtx && level < s.params.SilentLevel {
+		s.silentSince = 0
+		return false, false
+	}
+
+	if s.silentSince == 0 {
+		s.silentSince = arrivalTime
+	}
+	if time.Duration(arrivalTime-s.silentSince) < s.params.PauseAfter {
+		return false, false
+	}
+	s.paused = true
+	return true, true
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSilenceDetector(t *testing.T) {
+	params := SilenceDetectorParams{
+		SilentLevel: 60,
+		ResumeLevel: 50,
+		PauseAfter:  time.Second,
+	}
+
+	observe := func(s *SilenceDetector, level uint8, payloadSize int, count int, clock time.Time) (bool, time.Time) {
+		var paused bool
+		for i := 0; i < count; i++ {
+			paused, _ = s.Observe(level, payloadSize, clock.UnixNano())
+			clock = clock.Add(20 * time.Millisecond)
+		}
+		return paused, clock
+	}
+
+	t.Run("pauses after continuous silence", func(t *testing.T) {
+		s := NewSilenceDetector(params)
+		clock := time.Now()
+
+		paused, clock := observe(s, 70, 40, 50, clock)
+		require.False(t, paused)
+
+		paused, changed := s.Observe(70, 40, clock.UnixNano())
+		require.True(t, paused)
+		require.True(t, changed)
+	})
+
+	t.Run("speech restarts silence duration", func(t *testing.T) {
+		s := NewSilenceDetector(params)
+		clock := time.Now()
+
+		paused, clock := observe(s, 70, 40, 40, clock)
+		require.False(t, paused)
+		paused, clock = observe(s, 30, 40, 1, clock)
+		require.False(t, paused)
+		paused, _ = observe(s, 70, 40, 40, clock)
+		require.False(t, paused)
+	})
+
+	t.Run("DTX frames are silent", func(t *testing.T) {
+		s := NewSilenceDetector(params)
+		clock := time.Now()
+
+		// DTX frames are sent every 400ms
+		for i := 0; i < 3; i++ {
+			s.Observe(0, 1, clock.UnixNano())
+			clock = clock.Add(400 * time.Millisecond)
+		}
+		paused, changed := s.Observe(0, 1, clock.UnixNano())
+		require.True(t, paused)
+		require.True(t, changed)
+	})
+
+	t.Run("resumes below resume level only", func(t *testing.T) {
+		s := NewSilenceDetector(params)
+		clock := time.Now()
+
+		paused, clock := observe(s, 70, 40, 51, clock)
+		require.True(t, paused)
+
+		// between resume and silent levels
+		paused, clock = observe(s, 55, 40, 10, clock)
+		require.True(t, paused)
+
+		paused, changed := s.Observe(40, 40, clock.UnixNano())
+		require.False(t, paused)
+		require.True(t, changed)
+	})
+}
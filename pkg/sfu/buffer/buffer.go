@@ -66,7 +66,10 @@ type ExtPacket struct {
 	RawPacket            []byte
 	DependencyDescriptor *ExtDependencyDescriptor
 	AbsCaptureTimeExt    *act.AbsCaptureTime
+	AudioLevelExt        *rtp.AudioLevelExtension
 	IsOutOfOrder         bool
+	// estimated time from capture to arrival, sampled on the first packet after a sender report, 0 if not sampled
+	CaptureDelay time.Duration
 }
 
 // Buffer contains all packets
@@ -79,6 +82,7 @@ type Buffer struct {
 	maxAudioPkts    int
 	codecType       webrtc.RTPCodecType
 	payloadType     uint8
+	clockRates      map[uint8]uint32
 	extPackets      deque.Deque[*ExtPacket]
 	pPackets        []pendingPacket
 	closeOnce       sync.Once
@@ -100,6 +104,7 @@ type Buffer struct {
 	audioLevelParams        audio.AudioLevelParams
 	audioLevel              *audio.AudioLevel
 	enableAudioLossProxying bool
+	packetInterceptor       func(pkt *rtp.Packet) bool
 
 	lastPacketRead int
 
@@ -135,6 +140,9 @@ type Buffer struct {
 	extPacketTooMuchCount atomic.Uint32
 	invalidPacketCount    atomic.Uint32
 
+	// capture delay is sampled once per sender report to keep locking off the per packet path
+	captureDelayPending atomic.Bool
+
 	primaryBufferForRTX *Buffer
 	rtxPktBuf           []byte
 
@@ -196,6 +204,15 @@ func (b *Buffer) SetAudioLossProxying(enable bool) {
 	b.enableAudioLossProxying = enable
 }
 
+// SetPacketInterceptor calls f with every packet before it is stored. f may modify the packet in place, the stored
+// packet is the modified one, so retransmissions match what was forwarded. Returning false drops the packet
+func (b *Buffer) SetPacketInterceptor(f func(pkt *rtp.Packet) bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.packetInterceptor = f
+}
+
 func (b *Buffer) Bind(params webrtc.RTPParameters, codec webrtc.RTPCodecCapability, bitrates int) {
 	b.Lock()
 	defer b.Unlock()
@@ -214,6 +231,10 @@ func (b *Buffer) Bind(params webrtc.RTPParameters, codec webrtc.RTPCodecCapabili
 	b.clockRate = codec.ClockRate
 	b.lastReport = time.Now().UnixNano()
 	b.mime = strings.ToLower(codec.MimeType)
+	b.clockRates = make(map[uint8]uint32, len(params.Codecs))
+	for _, codecParameter := range params.Codecs {
+		b.clockRates[uint8(codecParameter.PayloadType)] = codecParameter.ClockRate
+	}
 	for _, codecParameter := range params.Codecs {
 		if strings.EqualFold(codecParameter.MimeType, codec.MimeType) {
 			b.payloadType = uint8(codecParameter.PayloadType)
@@ -360,13 +381,29 @@ func (b *Buffer) Write(pkt []byte) (n int, err error) {
 		return
 	}
 
-	b.payloadType = rtpPacket.PayloadType
+	if b.payloadType != rtpPacket.PayloadType {
+		b.updatePayloadType(rtpPacket.PayloadType)
+	}
 	b.calc(pkt, &rtpPacket, now, false)
 	b.Unlock()
 	b.readCond.Broadcast()
 	return
 }
 
+func (b *Buffer) updatePayloadType(payloadType uint8) {
+	b.payloadType = payloadType
+
+	clockRate, ok := b.clockRates[payloadType]
+	if !ok || clockRate == 0 || clockRate == b.clockRate {
+		return
+	}
+
+	b.logger.Infow("payload type changed clock rate", "payloadType", payloadType, "from", b.clockRate, "to", clockRate)
+	b.clockRate = clockRate
+	b.latestTSForAudioLevelInitialized = false
+	b.rtpStats.SetClockRate(clockRate)
+}
+
 func (b *Buffer) SetPrimaryBufferForRTX(primaryBuffer *Buffer) {
 	b.Lock()
 	b.primaryBufferForRTX = primaryBuffer
@@ -589,6 +626,25 @@ func (b *Buffer) calc(rawPkt []byte, rtpPacket *rtp.Packet, arrivalTime int64, i
 		return
 	}
 
+	if b.packetInterceptor != nil {
+		if !b.packetInterceptor(rtpPacket) {
+			// in-order drops are excluded like padding, so subscribers do not see a gap and NACK the packet
+			if !flowState.IsOutOfOrder {
+				if err := b.snRangeMap.ExcludeRange(flowState.ExtSequenceNumber, flowState.ExtSequenceNumber+1); err != nil {
+					b.logger.Errorw("could not exclude range", err, "sn", rtpPacket.SequenceNumber, "esn", flowState.ExtSequenceNumber)
+				}
+			}
+			return
+		}
+
+		intercepted, err := rtpPacket.Marshal()
+		if err != nil {
+			b.logger.Errorw("could not marshal intercepted RTP packet", err)
+			return
+		}
+		rawPkt = intercepted
+	}
+
 	// add to RTX buffer using sequence number after accounting for dropped padding only packets
 	snAdjustment, err := b.snRangeMap.GetValue(flowState.ExtSequenceNumber)
 	if err != nil {
@@ -839,6 +895,21 @@ func (b *Buffer) getExtPacket(rtpPacket *rtp.Packet, arrivalTime int64, flowStat
 		}
 	}
 
+	if b.audioLevelExtID != 0 {
+		var alExt rtp.AudioLevelExtension
+		if err := alExt.Unmarshal(rtpPacket.GetExtension(b.audioLevelExtID)); err == nil {
+			ep.AudioLevelExt = &alExt
+		}
+	}
+
+	if b.rtpStats != nil && b.captureDelayPending.Swap(false) {
+		var captureTime mediatransportutil.NtpTime
+		if ep.AbsCaptureTimeExt != nil {
+			captureTime = ep.AbsCaptureTimeExt.CaptureTime()
+		}
+		ep.CaptureDelay = b.rtpStats.CaptureDelay(arrivalTime, ep.ExtTimestamp, captureTime)
+	}
+
 	return ep
 }
 
@@ -941,6 +1012,7 @@ func (b *Buffer) SetSenderReportData(rtpTime uint32, ntpTime uint64, packets uin
 	b.RUnlock()
 
 	if didSet {
+		b.captureDelayPending.Store(true)
 		if cb := b.getOnRtcpSenderReport(); cb != nil {
 			cb()
 		}
@@ -1053,6 +1125,28 @@ func (b *Buffer) GetStats() *livekit.RTPStats {
 	return b.rtpStats.ToProto()
 }
 
+func (b *Buffer) GetRTCPReports() []RTCPReport {
+	b.RLock()
+	defer b.RUnlock()
+
+	if b.rtpStats == nil {
+		return nil
+	}
+
+	return b.rtpStats.RTCPReports()
+}
+
+func (b *Buffer) GetInterArrivalHistogram() map[int32]uint32 {
+	b.RLock()
+	defer b.RUnlock()
+
+	if b.rtpStats == nil {
+		return nil
+	}
+
+	return b.rtpStats.InterArrivalHistogram()
+}
+
 func (b *Buffer) GetDeltaStats() *StreamStatsWithLayers {
 	b.RLock()
 	defer b.RUnlock()
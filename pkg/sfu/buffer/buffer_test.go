@@ -314,6 +314,59 @@ func TestFractionLostReport(t *testing.T) {
 	wg.Wait()
 }
 
+func TestPacketInterceptor(t *testing.T) {
+	buff := NewBuffer(123, 1, 1)
+	require.NotNil(t, buff)
+	buff.SetPacketInterceptor(func(pkt *rtp.Packet) bool {
+		if pkt.SequenceNumber == 2 {
+			return false
+		}
+		pkt.Payload[0] = 0
+		return true
+	})
+	buff.Bind(webrtc.RTPParameters{
+		HeaderExtensions: nil,
+		Codecs:           []webrtc.RTPCodecParameters{opusCodec},
+	}, opusCodec.RTPCodecCapability, 0)
+
+	for i := 0; i < 5; i++ {
+		pkt := rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    111,
+				SequenceNumber: uint16(i),
+				Timestamp:      uint32(i),
+				SSRC:           123,
+			},
+			Payload: []byte{0xff, 0xff, 0xff, 0xfd},
+		}
+		b, err := pkt.Marshal()
+		require.NoError(t, err)
+		_, err = buff.Write(b)
+		require.NoError(t, err)
+	}
+
+	// the dropped packet leaves no gap, and retransmissions carry the modified payload
+	buf := make([]byte, 1500)
+	var lastSN uint64
+	for i := 0; i < 4; i++ {
+		ep, err := buff.ReadExtended(buf)
+		require.NoError(t, err)
+		require.EqualValues(t, 0, ep.Packet.Payload[0])
+		if i > 0 {
+			require.Equal(t, lastSN+1, ep.ExtSequenceNumber)
+		}
+		lastSN = ep.ExtSequenceNumber
+
+		storedBuf := make([]byte, 1500)
+		n, err := buff.GetPacket(storedBuf, ep.ExtSequenceNumber)
+		require.NoError(t, err)
+		var stored rtp.Packet
+		require.NoError(t, stored.Unmarshal(storedBuf[:n]))
+		require.EqualValues(t, 0, stored.Payload[0])
+	}
+}
+
 func BenchmarkMemcpu(b *testing.B) {
 	buf := make([]byte, 1500*1500*10)
 	buf2 := make([]byte, 1500*1500*20)
@@ -0,0 +1,76 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buffer
+
+import (
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+const (
+	cRTCPReportHistorySize = 32
+)
+
+type RTCPReportDirection string
+
+const (
+	RTCPReportDirectionReceived RTCPReportDirection = "received"
+	RTCPReportDirectionSent     RTCPReportDirection = "sent"
+)
+
+// RTCPReport is a sender or receiver report of a stream, kept to analyze timestamp and sync issues after the fact
+type RTCPReport struct {
+	At             time.Time             `json:"at"`
+	Direction      RTCPReportDirection   `json:"direction"`
+	SenderReport   *RTCPSenderReportData `json:"senderReport,omitempty"`
+	ReceiverReport *rtcp.ReceptionReport `json:"receiverReport,omitempty"`
+	// report was not applied, for example a sender report out of order
+	Dropped bool `json:"dropped,omitempty"`
+}
+
+// rtcpReportHistory is a ring buffer of the most recent reports
+type rtcpReportHistory struct {
+	reports [cRTCPReportHistorySize]RTCPReport
+	next    int
+	count   int
+}
+
+func (h *rtcpReportHistory) add(report RTCPReport) {
+	if report.SenderReport != nil {
+		sr := *report.SenderReport
+		report.SenderReport = &sr
+	}
+	if report.ReceiverReport != nil {
+		rr := *report.ReceiverReport
+		report.ReceiverReport = &rr
+	}
+
+	h.reports[h.next] = report
+	h.next = (h.next + 1) % cRTCPReportHistorySize
+	if h.count < cRTCPReportHistorySize {
+		h.count++
+	}
+}
+
+// get returns the reports, oldest first
+func (h *rtcpReportHistory) get() []RTCPReport {
+	reports := make([]RTCPReport, 0, h.count)
+	start := (h.next - h.count + cRTCPReportHistorySize) % cRTCPReportHistorySize
+	for i := 0; i < h.count; i++ {
+		reports = append(reports, h.reports[(start+i)%cRTCPReportHistorySize])
+	}
+	return reports
+}
@@ -0,0 +1,43 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buffer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRTCPReportHistory(t *testing.T) {
+	var h rtcpReportHistory
+	require.Empty(t, h.get())
+
+	now := time.Now()
+	for i := 0; i < cRTCPReportHistorySize+5; i++ {
+		h.add(RTCPReport{
+			At:             now.Add(time.Duration(i) * time.Second),
+			Direction:      RTCPReportDirectionReceived,
+			ReceiverReport: &rtcp.ReceptionReport{LastSequenceNumber: uint32(i)},
+		})
+	}
+
+	reports := h.get()
+	require.Len(t, reports, cRTCPReportHistorySize)
+	for i, report := range reports {
+		require.Equal(t, uint32(i+5), report.ReceiverReport.LastSequenceNumber)
+	}
+}
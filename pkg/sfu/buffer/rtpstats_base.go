@@ -20,6 +20,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
@@ -27,6 +28,8 @@ import (
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/sfu/clocksource"
 )
 
 const (
@@ -68,15 +71,21 @@ type RTPDeltaInfo struct {
 	PacketsPadding       uint32
 	BytesPadding         uint64
 	HeaderBytesPadding   uint64
-	PacketsLost          uint32
-	PacketsMissing       uint32
-	PacketsOutOfOrder    uint32
-	Frames               uint32
-	RttMax               uint32
-	JitterMax            float64
-	Nacks                uint32
-	Plis                 uint32
-	Firs                 uint32
+	// retransmissions in response to NACKs, only on the send side
+	PacketsRetransmitted     uint32
+	BytesRetransmitted       uint64
+	HeaderBytesRetransmitted uint64
+	PacketsLost              uint32
+	PacketsMissing           uint32
+	PacketsOutOfOrder        uint32
+	Frames                   uint32
+	RttMax                   uint32
+	JitterMax                float64
+	Nacks                    uint32
+	Plis                     uint32
+	Firs                     uint32
+	// estimated glass to glass latency in ms, 0 if unknown
+	LatencyMax uint32
 }
 
 type snapshot struct {
@@ -120,6 +129,8 @@ type RTCPSenderReportData struct {
 	AtAdjusted      time.Time
 	Packets         uint32
 	Octets          uint32
+	// RTP clock rate of the sender measured against local time, 0 if not measured
+	ClockRate float64
 }
 
 func (r *RTCPSenderReportData) PropagationDelay(passThrough bool) time.Duration {
@@ -158,6 +169,7 @@ func (r *RTCPSenderReportData) MarshalLogObject(e zapcore.ObjectEncoder) error {
 	e.AddTime("AtAdjusted", r.AtAdjusted)
 	e.AddUint32("Packets", r.Packets)
 	e.AddUint32("Octets", r.Octets)
+	e.AddFloat64("ClockRate", r.ClockRate)
 	return nil
 }
 
@@ -165,12 +177,21 @@ func (r *RTCPSenderReportData) MarshalLogObject(e zapcore.ObjectEncoder) error {
 
 type RTPStatsParams struct {
 	ClockRate uint32
-	Logger    logger.Logger
+	// window over which a jump in the RTP/NTP mapping of sender reports is smoothed, 0 to propagate jumps, sender only
+	SenderReportSmoothingWindow time.Duration
+	// extrapolate sender reports using the measured clock rate of the publisher, sender only
+	SenderReportClockSkewCompensation bool
+	// wall clock of the NTP timestamps of generated sender reports, defaults to the system clock, sender only
+	SenderReportClock clocksource.ClockSource
+	// source of current time, defaults to the system clock, can be mocked in tests
+	Clock  clock.Clock
+	Logger logger.Logger
 }
 
 type rtpStatsBase struct {
 	params RTPStatsParams
 	logger logger.Logger
+	clock  clock.Clock
 
 	lock sync.RWMutex
 
@@ -229,14 +250,23 @@ type rtpStatsBase struct {
 	srFirst  *RTCPSenderReportData
 	srNewest *RTCPSenderReportData
 
+	reportHistory rtcpReportHistory
+
 	nextSnapshotID uint32
 	snapshots      []snapshot
 }
 
 func newRTPStatsBase(params RTPStatsParams) *rtpStatsBase {
+	if params.Clock == nil {
+		params.Clock = clock.New()
+	}
+	if params.SenderReportClock == nil {
+		params.SenderReportClock = clocksource.System
+	}
 	return &rtpStatsBase{
 		params:         params,
 		logger:         params.Logger,
+		clock:          params.Clock,
 		nextSnapshotID: cFirstSnapshotID,
 		snapshots:      make([]snapshot, 2),
 	}
@@ -311,6 +341,8 @@ func (r *rtpStatsBase) seed(from *rtpStatsBase) bool {
 		r.srNewest = nil
 	}
 
+	r.reportHistory = from.reportHistory
+
 	r.nextSnapshotID = from.nextSnapshotID
 	r.snapshots = make([]snapshot, cap(from.snapshots))
 	copy(r.snapshots, from.snapshots)
@@ -325,7 +357,7 @@ func (r *rtpStatsBase) Stop() {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
-	r.endTime = time.Now()
+	r.endTime = r.clock.Now()
 }
 
 func (r *rtpStatsBase) newSnapshotID(extStartSN uint64) uint32 {
@@ -339,7 +371,7 @@ func (r *rtpStatsBase) newSnapshotID(extStartSN uint64) uint32 {
 	}
 
 	if r.initialized {
-		r.snapshots[id-cFirstSnapshotID] = r.initSnapshot(time.Now(), extStartSN)
+		r.snapshots[id-cFirstSnapshotID] = r.initSnapshot(r.clock.Now(), extStartSN)
 	}
 	return id
 }
@@ -379,7 +411,7 @@ func (r *rtpStatsBase) CheckAndUpdatePli(throttle int64, force bool) bool {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
-	if !r.endTime.IsZero() || (!force && time.Now().UnixNano()-r.lastPli.UnixNano() < throttle) {
+	if !r.endTime.IsZero() || (!force && r.clock.Now().UnixNano()-r.lastPli.UnixNano() < throttle) {
 		return false
 	}
 	r.updatePliLocked(1)
@@ -426,7 +458,15 @@ func (r *rtpStatsBase) UpdatePliTime() {
 }
 
 func (r *rtpStatsBase) updatePliTimeLocked() {
-	r.lastPli = time.Now()
+	r.lastPli = r.clock.Now()
+}
+
+// RTCPReports returns the most recent sender and receiver reports of the stream, oldest first
+func (r *rtpStatsBase) RTCPReports() []RTCPReport {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.reportHistory.get()
 }
 
 func (r *rtpStatsBase) LastPli() time.Time {
@@ -445,7 +485,7 @@ func (r *rtpStatsBase) UpdateLayerLockPliAndTime(pliCount uint32) {
 	}
 
 	r.layerLockPlis += pliCount
-	r.lastLayerLockPli = time.Now()
+	r.lastLayerLockPli = r.clock.Now()
 }
 
 func (r *rtpStatsBase) UpdateFir(firCount uint32) {
@@ -467,7 +507,7 @@ func (r *rtpStatsBase) UpdateFirTime() {
 		return
 	}
 
-	r.lastFir = time.Now()
+	r.lastFir = r.clock.Now()
 }
 
 func (r *rtpStatsBase) UpdateKeyFrame(kfCount uint32) {
@@ -479,7 +519,7 @@ func (r *rtpStatsBase) UpdateKeyFrame(kfCount uint32) {
 	}
 
 	r.keyFrames += kfCount
-	r.lastKeyFrame = time.Now()
+	r.lastKeyFrame = r.clock.Now()
 }
 
 func (r *rtpStatsBase) UpdateRtt(rtt uint32) {
@@ -511,7 +551,7 @@ func (r *rtpStatsBase) GetRtt() uint32 {
 }
 
 func (r *rtpStatsBase) maybeAdjustFirstPacketTime(srData *RTCPSenderReportData, tsOffset uint64, extStartTS uint64) (err error, loggingFields []interface{}) {
-	if time.Since(r.startTime) > cFirstPacketTimeAdjustWindow {
+	if r.clock.Since(r.startTime) > cFirstPacketTimeAdjustWindow {
 		return
 	}
 
@@ -521,7 +561,7 @@ func (r *rtpStatsBase) maybeAdjustFirstPacketTime(srData *RTCPSenderReportData,
 	// abnormal delay (maybe due to pacing or maybe due to queuing
 	// in some network element along the way), push back first time
 	// to an earlier instance.
-	timeSinceReceive := time.Since(srData.AtAdjusted)
+	timeSinceReceive := r.clock.Since(srData.AtAdjusted)
 	extNowTS := srData.RTPTimestampExt - tsOffset + uint64(timeSinceReceive.Nanoseconds()*int64(r.params.ClockRate)/1e9)
 	samplesDiff := int64(extNowTS - extStartTS)
 	if samplesDiff < 0 {
@@ -530,7 +570,7 @@ func (r *rtpStatsBase) maybeAdjustFirstPacketTime(srData *RTCPSenderReportData,
 	}
 
 	samplesDuration := time.Duration(float64(samplesDiff) / float64(r.params.ClockRate) * float64(time.Second))
-	timeSinceFirst := time.Since(time.Unix(0, r.firstTime))
+	timeSinceFirst := r.clock.Since(time.Unix(0, r.firstTime))
 	now := r.firstTime + timeSinceFirst.Nanoseconds()
 	firstTime := now - samplesDuration.Nanoseconds()
 
@@ -586,6 +626,24 @@ func (r *rtpStatsBase) deltaInfo(snapshotID uint32, extStartSN uint64, extHighes
 		return
 	}
 
+	return r.deltaInfoFromSnapshots(then, now, extHighestSN)
+}
+
+// deltaInfoBetween computes the delta between two snapshots without resetting either of them.
+// Snapshots are ordered by time, so the IDs can be given in any order.
+func (r *rtpStatsBase) deltaInfoBetween(fromSnapshotID uint32, toSnapshotID uint32, extStartSN uint64, extHighestSN uint64) (deltaInfo *RTPDeltaInfo, err error, loggingFields []interface{}) {
+	then, now := r.getSnapshotByID(fromSnapshotID, extStartSN), r.getSnapshotByID(toSnapshotID, extStartSN)
+	if now == nil || then == nil {
+		return
+	}
+
+	if then.startTime.After(now.startTime) {
+		then, now = now, then
+	}
+	return r.deltaInfoFromSnapshots(then, now, extHighestSN)
+}
+
+func (r *rtpStatsBase) deltaInfoFromSnapshots(then *snapshot, now *snapshot, extHighestSN uint64) (deltaInfo *RTPDeltaInfo, err error, loggingFields []interface{}) {
 	startTime := then.startTime
 	endTime := now.startTime
 
@@ -815,7 +873,7 @@ func (r *rtpStatsBase) toProto(
 
 	endTime := r.endTime
 	if endTime.IsZero() {
-		endTime = time.Now()
+		endTime = r.clock.Now()
 	}
 	elapsed := endTime.Sub(r.startTime).Seconds()
 	if elapsed == 0.0 {
@@ -963,11 +1021,23 @@ func (r *rtpStatsBase) getAndResetSnapshot(snapshotID uint32, extStartSN uint64,
 	}
 
 	// snapshot now
-	now := r.getSnapshot(time.Now(), extHighestSN+1)
+	now := r.getSnapshot(r.clock.Now(), extHighestSN+1)
 	r.snapshots[idx] = now
 	return &then, &now
 }
 
+func (r *rtpStatsBase) getSnapshotByID(snapshotID uint32, extStartSN uint64) *snapshot {
+	if !r.initialized || snapshotID < cFirstSnapshotID || snapshotID >= r.nextSnapshotID {
+		return nil
+	}
+
+	s := r.snapshots[snapshotID-cFirstSnapshotID]
+	if !s.isValid {
+		s = r.initSnapshot(r.startTime, extStartSN)
+	}
+	return &s
+}
+
 func (r *rtpStatsBase) getDrift(extStartTS, extHighestTS uint64) (packetDrift *livekit.RTPDrift, ntpReportDrift *livekit.RTPDrift, rebasedReportDrift *livekit.RTPDrift) {
 	if r.firstTime != 0 {
 		elapsed := r.highestTime - r.firstTime
@@ -1098,6 +1168,10 @@ func AggregateRTPDeltaInfo(deltaInfoList []*RTPDeltaInfo) *RTPDeltaInfo {
 	bytesPadding := uint64(0)
 	headerBytesPadding := uint64(0)
 
+	packetsRetransmitted := uint32(0)
+	bytesRetransmitted := uint64(0)
+	headerBytesRetransmitted := uint64(0)
+
 	packetsLost := uint32(0)
 	packetsMissing := uint32(0)
 	packetsOutOfOrder := uint32(0)
@@ -1106,6 +1180,7 @@ func AggregateRTPDeltaInfo(deltaInfoList []*RTPDeltaInfo) *RTPDeltaInfo {
 
 	maxRtt := uint32(0)
 	maxJitter := float64(0)
+	maxLatency := uint32(0)
 
 	nacks := uint32(0)
 	plis := uint32(0)
@@ -1136,6 +1211,10 @@ func AggregateRTPDeltaInfo(deltaInfoList []*RTPDeltaInfo) *RTPDeltaInfo {
 		bytesPadding += deltaInfo.BytesPadding
 		headerBytesPadding += deltaInfo.HeaderBytesPadding
 
+		packetsRetransmitted += deltaInfo.PacketsRetransmitted
+		bytesRetransmitted += deltaInfo.BytesRetransmitted
+		headerBytesRetransmitted += deltaInfo.HeaderBytesRetransmitted
+
 		packetsLost += deltaInfo.PacketsLost
 		packetsMissing += deltaInfo.PacketsMissing
 		packetsOutOfOrder += deltaInfo.PacketsOutOfOrder
@@ -1150,6 +1229,10 @@ func AggregateRTPDeltaInfo(deltaInfoList []*RTPDeltaInfo) *RTPDeltaInfo {
 			maxJitter = deltaInfo.JitterMax
 		}
 
+		if deltaInfo.LatencyMax > maxLatency {
+			maxLatency = deltaInfo.LatencyMax
+		}
+
 		nacks += deltaInfo.Nacks
 		plis += deltaInfo.Plis
 		firs += deltaInfo.Firs
@@ -1159,26 +1242,30 @@ func AggregateRTPDeltaInfo(deltaInfoList []*RTPDeltaInfo) *RTPDeltaInfo {
 	}
 
 	return &RTPDeltaInfo{
-		StartTime:            startTime,
-		EndTime:              endTime,
-		Packets:              packets,
-		Bytes:                bytes,
-		HeaderBytes:          headerBytes,
-		PacketsDuplicate:     packetsDuplicate,
-		BytesDuplicate:       bytesDuplicate,
-		HeaderBytesDuplicate: headerBytesDuplicate,
-		PacketsPadding:       packetsPadding,
-		BytesPadding:         bytesPadding,
-		HeaderBytesPadding:   headerBytesPadding,
-		PacketsLost:          packetsLost,
-		PacketsMissing:       packetsMissing,
-		PacketsOutOfOrder:    packetsOutOfOrder,
-		Frames:               frames,
-		RttMax:               maxRtt,
-		JitterMax:            maxJitter,
-		Nacks:                nacks,
-		Plis:                 plis,
-		Firs:                 firs,
+		StartTime:                startTime,
+		EndTime:                  endTime,
+		Packets:                  packets,
+		Bytes:                    bytes,
+		HeaderBytes:              headerBytes,
+		PacketsDuplicate:         packetsDuplicate,
+		BytesDuplicate:           bytesDuplicate,
+		HeaderBytesDuplicate:     headerBytesDuplicate,
+		PacketsPadding:           packetsPadding,
+		BytesPadding:             bytesPadding,
+		HeaderBytesPadding:       headerBytesPadding,
+		PacketsRetransmitted:     packetsRetransmitted,
+		BytesRetransmitted:       bytesRetransmitted,
+		HeaderBytesRetransmitted: headerBytesRetransmitted,
+		PacketsLost:              packetsLost,
+		PacketsMissing:           packetsMissing,
+		PacketsOutOfOrder:        packetsOutOfOrder,
+		Frames:                   frames,
+		RttMax:                   maxRtt,
+		JitterMax:                maxJitter,
+		Nacks:                    nacks,
+		Plis:                     plis,
+		Firs:                     firs,
+		LatencyMax:               maxLatency,
 	}
 }
 
@@ -23,6 +23,7 @@ import (
 	"go.uber.org/zap/zapcore"
 
 	"github.com/livekit/livekit-server/pkg/sfu/utils"
+	"github.com/livekit/mediatransportutil"
 	"github.com/livekit/protocol/livekit"
 	protoutils "github.com/livekit/protocol/utils"
 )
@@ -60,6 +61,14 @@ const (
 
 	// number of seconds the current report RTP timestamp can be off from expected RTP timestamp
 	cReportSlack = float64(60.0)
+
+	// clock rate of the sender is measured across sender reports spanning at least this duration
+	// and is discarded if it deviates more than the given fraction from the nominal clock rate
+	cClockRateMeasureMinDuration  = 10 * time.Second
+	cClockRateMeasureMaxDeviation = 0.05
+
+	// inter-arrival time histogram has 1 ms bins, the last bin holds all larger deltas
+	cInterArrivalHistogramNumBins = 101
 )
 
 // ---------------------------------------------------------------------
@@ -106,12 +115,20 @@ type RTPStatsReceiver struct {
 
 	history *protoutils.Bitmap[uint64]
 
+	lastArrivalTime       int64
+	interArrivalHistogram [cInterArrivalHistogramNumBins]uint32
+
 	propagationDelay                   time.Duration
 	longTermDeltaPropagationDelay      time.Duration
 	propagationDelayDeltaHighCount     int
 	propagationDelayDeltaHighStartTime time.Time
 	propagationDelaySpike              time.Duration
 
+	// number of mid-stream clock rate changes, references taken before
+	// a change cannot be used for skew checks against the current clock rate
+	clockRateChangeCount           int
+	isSenderReportReferenceInvalid bool
+
 	clockSkewCount              int
 	clockSkewMediaPathCount     int
 	outOfOrderSenderReportCount int
@@ -137,6 +154,33 @@ func (r *RTPStatsReceiver) NewSnapshotId() uint32 {
 	return r.newSnapshotID(r.sequenceNumber.GetExtendedHighest())
 }
 
+// SetClockRate handles a change of clock rate mid-stream, for example on a codec switch on the same transceiver.
+// Jitter tracking is restarted and sender report based checks are re-referenced at the new clock rate.
+func (r *RTPStatsReceiver) SetClockRate(clockRate uint32) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if clockRate == 0 || clockRate == r.params.ClockRate {
+		return
+	}
+
+	r.logger.Infow(
+		"clock rate changed",
+		"from", r.params.ClockRate,
+		"to", clockRate,
+		"rtpStats", lockedRTPStatsReceiverLogEncoder{r},
+	)
+	r.params.ClockRate = clockRate
+	r.tsRolloverThreshold = (1 << 31) * 1e9 / int64(clockRate)
+
+	r.jitter = 0
+	r.lastTransit = 0
+	r.lastJitterExtTimestamp = 0
+
+	r.clockRateChangeCount++
+	r.isSenderReportReferenceInvalid = r.srNewest != nil
+}
+
 func (r *RTPStatsReceiver) getTSRolloverCount(diffNano int64, ts uint32) int {
 	if diffNano < r.tsRolloverThreshold {
 		// time not more than rollover threshold
@@ -205,7 +249,7 @@ func (r *RTPStatsReceiver) Update(
 
 		r.initialized = true
 
-		r.startTime = time.Now()
+		r.startTime = r.clock.Now()
 
 		r.firstTime = packetTime
 		r.highestTime = packetTime
@@ -357,6 +401,8 @@ func (r *RTPStatsReceiver) Update(
 	flowState.ExtTimestamp = resTS.ExtendedVal
 
 	if !flowState.IsDuplicate {
+		r.updateInterArrivalHistogram(packetTime)
+
 		if payloadSize == 0 {
 			r.packetsPadding++
 			r.bytesPadding += pktSize
@@ -375,6 +421,32 @@ func (r *RTPStatsReceiver) Update(
 	return
 }
 
+func (r *RTPStatsReceiver) updateInterArrivalHistogram(packetTime int64) {
+	if r.lastArrivalTime != 0 && packetTime >= r.lastArrivalTime {
+		bin := int((packetTime - r.lastArrivalTime) / 1e6)
+		if bin >= len(r.interArrivalHistogram) {
+			bin = len(r.interArrivalHistogram) - 1
+		}
+		r.interArrivalHistogram[bin]++
+	}
+	r.lastArrivalTime = packetTime
+}
+
+// InterArrivalHistogram returns the count of inter-arrival times of packets keyed by ms, only non-zero bins are included.
+// The largest key holds all deltas at or above it.
+func (r *RTPStatsReceiver) InterArrivalHistogram() map[int32]uint32 {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	histogram := make(map[int32]uint32)
+	for ms, count := range r.interArrivalHistogram {
+		if count != 0 {
+			histogram[int32(ms)] = count
+		}
+	}
+	return histogram
+}
+
 func (r *RTPStatsReceiver) getExtendedSenderReport(srData *RTCPSenderReportData) *RTCPSenderReportData {
 	tsCycles := uint64(0)
 	if r.srNewest != nil {
@@ -478,16 +550,20 @@ func (r *RTPStatsReceiver) checkRTPClockSkewAgainstMediaPathForSenderReport(srDa
 		return
 	}
 
-	timeSinceSR := time.Since(srData.AtAdjusted)
+	timeSinceSR := r.clock.Since(srData.AtAdjusted)
 	extNowTSSR := srData.RTPTimestampExt + uint64(timeSinceSR.Nanoseconds()*int64(r.params.ClockRate)/1e9)
 
-	timeSinceHighest := time.Since(time.Unix(0, r.highestTime))
+	timeSinceHighest := r.clock.Since(time.Unix(0, r.highestTime))
 	extNowTSHighest := r.timestamp.GetExtendedHighest() + uint64(timeSinceHighest.Nanoseconds()*int64(r.params.ClockRate)/1e9)
 	diffHighest := extNowTSSR - extNowTSHighest
 
-	timeSinceFirst := time.Since(time.Unix(0, r.firstTime))
+	timeSinceFirst := r.clock.Since(time.Unix(0, r.firstTime))
 	extNowTSFirst := r.timestamp.GetExtendedStart() + uint64(timeSinceFirst.Nanoseconds()*int64(r.params.ClockRate)/1e9)
 	diffFirst := extNowTSSR - extNowTSFirst
+	if r.clockRateChangeCount != 0 {
+		// first packet was at a different clock rate, check only against the most recent packet
+		diffFirst = 0
+	}
 
 	// is it more than 5 seconds off?
 	if uint32(math.Abs(float64(int64(diffHighest)))) > 5*r.params.ClockRate || uint32(math.Abs(float64(int64(diffFirst)))) > 5*r.params.ClockRate {
@@ -519,7 +595,7 @@ func (r *RTPStatsReceiver) updatePropagationDelayAndRecordSenderReport(srData *R
 			"receivedPropagationDelay", propagationDelay.String(),
 			"receivedDeltaPropagationDelay", deltaPropagationDelay.String(),
 			"deltaHighCount", r.propagationDelayDeltaHighCount,
-			"sinceDeltaHighStart", time.Since(r.propagationDelayDeltaHighStartTime).String(),
+			"sinceDeltaHighStart", r.clock.Since(r.propagationDelayDeltaHighStartTime).String(),
 			"propagationDelaySpike", r.propagationDelaySpike.String(),
 			"current", srData,
 			"rtpStats", lockedRTPStatsReceiverLogEncoder{r},
@@ -552,7 +628,7 @@ func (r *RTPStatsReceiver) updatePropagationDelayAndRecordSenderReport(srData *R
 				r.logger.Debugw("sharp increase in propagation delay", getPropagationFields()...)
 				r.propagationDelayDeltaHighCount++
 				if r.propagationDelayDeltaHighStartTime.IsZero() {
-					r.propagationDelayDeltaHighStartTime = time.Now()
+					r.propagationDelayDeltaHighStartTime = r.clock.Now()
 				}
 				if r.propagationDelaySpike == 0 {
 					r.propagationDelaySpike = propagationDelay
@@ -560,7 +636,7 @@ func (r *RTPStatsReceiver) updatePropagationDelayAndRecordSenderReport(srData *R
 					r.propagationDelaySpike += time.Duration(cPropagationDelaySpikeAdaptationFactor * float64(propagationDelay-r.propagationDelaySpike))
 				}
 
-				if r.propagationDelayDeltaHighCount >= cPropagationDelayDeltaHighResetNumReports && time.Since(r.propagationDelayDeltaHighStartTime) >= cPropagationDelayDeltaHighResetWait {
+				if r.propagationDelayDeltaHighCount >= cPropagationDelayDeltaHighResetNumReports && r.clock.Since(r.propagationDelayDeltaHighStartTime) >= cPropagationDelayDeltaHighResetWait {
 					r.logger.Debugw("re-initializing propagation delay", append(getPropagationFields(), "newPropagationDelay", r.propagationDelaySpike.String())...)
 					initPropagationDelay(r.propagationDelaySpike)
 				}
@@ -612,25 +688,66 @@ func (r *RTPStatsReceiver) SetRtcpSenderReportData(srData *RTCPSenderReportData)
 			"current", srData,
 			"rtpStats", lockedRTPStatsReceiverLogEncoder{r},
 		)
+		r.recordReceivedSenderReport(srData, true)
 		return false
 	}
 
 	srDataExt := r.getExtendedSenderReport(srData)
 
 	if r.checkOutOfOrderSenderReport(srDataExt) {
+		r.recordReceivedSenderReport(srDataExt, true)
 		return false
 	}
 
-	r.checkRTPClockSkewForSenderReport(srDataExt)
+	if !r.isSenderReportReferenceInvalid {
+		r.checkRTPClockSkewForSenderReport(srDataExt)
+	}
 	r.updatePropagationDelayAndRecordSenderReport(srDataExt)
+	if r.isSenderReportReferenceInvalid {
+		// first report after a clock rate change becomes the reference for skew checks
+		r.srFirst = r.srNewest
+		r.isSenderReportReferenceInvalid = false
+	}
 	r.checkRTPClockSkewAgainstMediaPathForSenderReport(srDataExt)
+	r.updateMeasuredClockRate()
+	r.recordReceivedSenderReport(r.srNewest, false)
 
-	if err, loggingFields := r.maybeAdjustFirstPacketTime(r.srNewest, 0, r.timestamp.GetExtendedStart()); err != nil {
-		r.logger.Infow(err.Error(), append(loggingFields, "rtpStats", lockedRTPStatsReceiverLogEncoder{r})...)
+	if r.clockRateChangeCount == 0 {
+		// start of stream is at a different clock rate after a change, so no adjustment
+		if err, loggingFields := r.maybeAdjustFirstPacketTime(r.srNewest, 0, r.timestamp.GetExtendedStart()); err != nil {
+			r.logger.Infow(err.Error(), append(loggingFields, "rtpStats", lockedRTPStatsReceiverLogEncoder{r})...)
+		}
 	}
 	return true
 }
 
+func (r *RTPStatsReceiver) updateMeasuredClockRate() {
+	if r.srFirst == nil || r.srNewest == nil || r.srNewest.RTPTimestampExt <= r.srFirst.RTPTimestampExt {
+		return
+	}
+
+	elapsed := r.srNewest.AtAdjusted.Sub(r.srFirst.AtAdjusted)
+	if elapsed < cClockRateMeasureMinDuration {
+		return
+	}
+
+	clockRate := float64(r.srNewest.RTPTimestampExt-r.srFirst.RTPTimestampExt) / elapsed.Seconds()
+	if math.Abs(clockRate-float64(r.params.ClockRate)) > cClockRateMeasureMaxDeviation*float64(r.params.ClockRate) {
+		return
+	}
+
+	r.srNewest.ClockRate = clockRate
+}
+
+func (r *RTPStatsReceiver) recordReceivedSenderReport(srData *RTCPSenderReportData, dropped bool) {
+	r.reportHistory.add(RTCPReport{
+		At:           srData.At,
+		Direction:    RTCPReportDirectionReceived,
+		SenderReport: srData,
+		Dropped:      dropped,
+	})
+}
+
 func (r *RTPStatsReceiver) GetRtcpSenderReportData() *RTCPSenderReportData {
 	r.lock.RLock()
 	defer r.lock.RUnlock()
@@ -643,6 +760,33 @@ func (r *RTPStatsReceiver) GetRtcpSenderReportData() *RTCPSenderReportData {
 	return &srNewestCopy
 }
 
+// CaptureDelay estimates the time from capture of a packet to its arrival, returns 0 if it cannot be estimated.
+// Capture time is taken from abs-capture-time if available, else from the RTP timestamp, and mapped to
+// SFU time base using the latest sender report. As the mapping absorbs the one way delay of the sender report,
+// half the RTT is added back as the network delay from the publisher.
+func (r *RTPStatsReceiver) CaptureDelay(arrival int64, extTimestamp uint64, captureTime mediatransportutil.NtpTime) time.Duration {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if r.srNewest == nil || r.params.ClockRate == 0 {
+		return 0
+	}
+
+	var sinceSenderReport time.Duration
+	if captureTime != 0 {
+		sinceSenderReport = captureTime.Time().Sub(r.srNewest.NTPTimestamp.Time())
+	} else {
+		sinceSenderReport = time.Duration(int64(extTimestamp-r.srNewest.RTPTimestampExt) * 1e9 / int64(r.params.ClockRate))
+	}
+	capturedAt := r.srNewest.AtAdjusted.Add(sinceSenderReport)
+
+	captureDelay := time.Unix(0, arrival).Sub(capturedAt) + time.Duration(r.rtt)*time.Millisecond/2
+	if captureDelay < 0 {
+		return 0
+	}
+	return captureDelay
+}
+
 func (r *RTPStatsReceiver) LastSenderReportTime() time.Time {
 	r.lock.RLock()
 	defer r.lock.RUnlock()
@@ -697,12 +841,12 @@ func (r *RTPStatsReceiver) GetRtcpReceptionReport(ssrc uint32, proxyFracLost uin
 	if r.srNewest != nil {
 		lastSR = uint32(r.srNewest.NTPTimestamp >> 16)
 		if !r.srNewest.At.IsZero() {
-			delayUS := time.Since(r.srNewest.At).Microseconds()
+			delayUS := r.clock.Since(r.srNewest.At).Microseconds()
 			dlsr = uint32(delayUS * 65536 / 1e6)
 		}
 	}
 
-	return &rtcp.ReceptionReport{
+	rr := &rtcp.ReceptionReport{
 		SSRC:               ssrc,
 		FractionLost:       fracLost,
 		TotalLost:          uint32(totalLost),
@@ -711,6 +855,12 @@ func (r *RTPStatsReceiver) GetRtcpReceptionReport(ssrc uint32, proxyFracLost uin
 		LastSenderReport:   lastSR,
 		Delay:              dlsr,
 	}
+	r.reportHistory.add(RTCPReport{
+		At:             r.clock.Now(),
+		Direction:      RTCPReportDirectionSent,
+		ReceiverReport: rr,
+	})
+	return rr
 }
 
 func (r *RTPStatsReceiver) DeltaInfo(snapshotID uint32) *RTPDeltaInfo {
@@ -729,6 +879,25 @@ func (r *RTPStatsReceiver) DeltaInfo(snapshotID uint32) *RTPDeltaInfo {
 	return deltaInfo
 }
 
+// DeltaInfoBetween returns the delta between two snapshots without resetting them,
+// allowing consumers with different windows to share snapshots.
+func (r *RTPStatsReceiver) DeltaInfoBetween(fromSnapshotID uint32, toSnapshotID uint32) *RTPDeltaInfo {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	deltaInfo, err, loggingFields := r.deltaInfoBetween(
+		fromSnapshotID,
+		toSnapshotID,
+		r.sequenceNumber.GetExtendedStart(),
+		r.sequenceNumber.GetExtendedHighest(),
+	)
+	if err != nil {
+		r.logger.Infow(err.Error(), append(loggingFields, "rtpStats", lockedRTPStatsReceiverLogEncoder{r})...)
+	}
+
+	return deltaInfo
+}
+
 func (r *RTPStatsReceiver) MarshalLogObject(e zapcore.ObjectEncoder) error {
 	if r == nil {
 		return nil
@@ -794,6 +963,25 @@ func (r lockedRTPStatsReceiverLogEncoder) MarshalLogObject(e zapcore.ObjectEncod
 
 	e.AddDuration("propagationDelay", r.propagationDelay)
 	e.AddDuration("longTermDeltaPropagationDelay", r.longTermDeltaPropagationDelay)
+	e.AddInt("clockRateChangeCount", r.clockRateChangeCount)
+
+	hasArrivals := false
+	str := "["
+	for ms, count := range r.interArrivalHistogram {
+		if count == 0 {
+			continue
+		}
+
+		if hasArrivals {
+			str += ", "
+		}
+		hasArrivals = true
+		str += fmt.Sprintf("%d:%d", ms, count)
+	}
+	str += "]"
+	if hasArrivals {
+		e.AddString("interArrivalHistogram", str)
+	}
 	return nil
 }
 
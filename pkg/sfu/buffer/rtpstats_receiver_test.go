@@ -20,9 +20,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/pion/rtp"
 	"github.com/stretchr/testify/require"
 
+	"github.com/livekit/mediatransportutil"
 	"github.com/livekit/protocol/logger"
 )
 
@@ -38,8 +40,11 @@ func getPacket(sn uint16, ts uint32, payloadSize int) *rtp.Packet {
 
 func Test_RTPStatsReceiver(t *testing.T) {
 	clockRate := uint32(90000)
+	clk := clock.NewMock()
+	clk.Set(time.Now())
 	r := NewRTPStatsReceiver(RTPStatsParams{
 		ClockRate: clockRate,
+		Clock:     clk,
 		Logger:    logger.GetLogger(),
 	})
 
@@ -53,7 +58,7 @@ func Test_RTPStatsReceiver(t *testing.T) {
 
 	sequenceNumber := uint16(rand.Float64() * float64(1<<16))
 	timestamp := uint32(rand.Float64() * float64(1<<32))
-	now := time.Now()
+	now := clk.Now()
 	startTime := now
 	lastFrameTime := now
 	for now.Sub(startTime) < totalDuration {
@@ -61,7 +66,7 @@ func Test_RTPStatsReceiver(t *testing.T) {
 		for i := 0; i < packetsPerFrame; i++ {
 			packet := getPacket(sequenceNumber, timestamp, packetSize)
 			r.Update(
-				time.Now().UnixNano(),
+				clk.Now().UnixNano(),
 				packet.Header.SequenceNumber,
 				packet.Header.Timestamp,
 				packet.Header.Marker,
@@ -78,8 +83,8 @@ func Test_RTPStatsReceiver(t *testing.T) {
 		}
 
 		lastFrameTime = now
-		time.Sleep(time.Duration(sleep) * time.Millisecond)
-		now = time.Now()
+		clk.Add(time.Duration(sleep) * time.Millisecond)
+		now = clk.Now()
 	}
 
 	r.Stop()
@@ -286,3 +291,243 @@ func Test_RTPStatsReceiver_Update(t *testing.T) {
 
 	r.Stop()
 }
+
+func Test_RTPStatsReceiver_CaptureDelay(t *testing.T) {
+	clockRate := uint32(90000)
+	r := NewRTPStatsReceiver(RTPStatsParams{
+		ClockRate: clockRate,
+		Logger:    logger.GetLogger(),
+	})
+
+	now := time.Now()
+	timestamp := uint32(1000)
+	r.Update(now.UnixNano(), 1, timestamp, true, 12, 1000, 0)
+	require.Zero(t, r.CaptureDelay(now.UnixNano(), uint64(timestamp), 0))
+
+	// sender report with publisher clock 5 seconds behind
+	r.SetRtcpSenderReportData(&RTCPSenderReportData{
+		RTPTimestamp: timestamp,
+		NTPTimestamp: mediatransportutil.ToNtpTime(now.Add(-5 * time.Second)),
+		At:           now,
+		AtAdjusted:   now,
+	})
+	r.UpdateRtt(100)
+
+	// frame captured 100 ms after sender report, arriving 40 ms after capture
+	extTimestamp := uint64(timestamp) + uint64(clockRate)/10
+	arrival := now.Add(140 * time.Millisecond).UnixNano()
+	require.Equal(t, 90*time.Millisecond, r.CaptureDelay(arrival, extTimestamp, 0))
+
+	// abs-capture-time, captured 20 ms before the RTP timestamp indicates
+	captureTime := mediatransportutil.ToNtpTime(now.Add(-5*time.Second + 80*time.Millisecond))
+	require.InDelta(t, 110*time.Millisecond, r.CaptureDelay(arrival, extTimestamp, captureTime), float64(time.Millisecond))
+}
+
+func Test_RTPStatsReceiver_DeltaInfoBetween(t *testing.T) {
+	r := NewRTPStatsReceiver(RTPStatsParams{
+		ClockRate: 90000,
+		Logger:    logger.GetLogger(),
+	})
+
+	shortWindowID := r.NewSnapshotId()
+	longWindowID := r.NewSnapshotId()
+	require.Nil(t, r.DeltaInfoBetween(shortWindowID, longWindowID))
+
+	sequenceNumber := uint16(rand.Float64() * float64(1<<16))
+	timestamp := uint32(rand.Float64() * float64(1<<32))
+	sendPackets := func(count int) {
+		for i := 0; i < count; i++ {
+			packet := getPacket(sequenceNumber, timestamp, 1000)
+			r.Update(
+				time.Now().UnixNano(),
+				packet.Header.SequenceNumber,
+				packet.Header.Timestamp,
+				packet.Header.Marker,
+				packet.Header.MarshalSize(),
+				len(packet.Payload),
+				0,
+			)
+			sequenceNumber++
+			timestamp += 3000
+		}
+	}
+
+	sendPackets(10)
+	deltaInfo := r.DeltaInfo(shortWindowID)
+	require.NotNil(t, deltaInfo)
+	require.Equal(t, uint32(10), deltaInfo.Packets)
+
+	sendPackets(5)
+	deltaInfo = r.DeltaInfo(shortWindowID)
+	require.NotNil(t, deltaInfo)
+	require.Equal(t, uint32(5), deltaInfo.Packets)
+
+	// long window has not been read, delta between the snapshots covers all packets up to the last short window read
+	sendPackets(3)
+	deltaInfo = r.DeltaInfoBetween(longWindowID, shortWindowID)
+	require.NotNil(t, deltaInfo)
+	require.Equal(t, uint32(15), deltaInfo.Packets)
+
+	// order of snapshot IDs does not matter and snapshots are not reset
+	deltaInfo = r.DeltaInfoBetween(shortWindowID, longWindowID)
+	require.NotNil(t, deltaInfo)
+	require.Equal(t, uint32(15), deltaInfo.Packets)
+
+	deltaInfo = r.DeltaInfo(longWindowID)
+	require.NotNil(t, deltaInfo)
+	require.Equal(t, uint32(18), deltaInfo.Packets)
+
+	// unknown snapshot ID
+	require.Nil(t, r.DeltaInfoBetween(shortWindowID, longWindowID+1))
+}
+
+func Test_RTPStatsReceiver_SetClockRate(t *testing.T) {
+	r := NewRTPStatsReceiver(RTPStatsParams{
+		ClockRate: 90000,
+		Logger:    logger.GetLogger(),
+	})
+
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		r.Update(now.Add(time.Duration(i)*20*time.Millisecond).UnixNano(), uint16(i+1), uint32(1000+i*1800+(i%3)*100), true, 12, 1000, 0)
+	}
+	require.NotZero(t, r.jitter)
+
+	require.True(t, r.SetRtcpSenderReportData(&RTCPSenderReportData{
+		NTPTimestamp: mediatransportutil.ToNtpTime(now),
+		RTPTimestamp: 1000,
+		At:           now,
+	}))
+	srFirst := r.srFirst
+
+	r.SetClockRate(48000)
+	require.Equal(t, uint32(48000), r.params.ClockRate)
+	require.Equal(t, int64(1<<31)*1e9/48000, r.tsRolloverThreshold)
+	require.Zero(t, r.jitter)
+	require.Equal(t, 1, r.clockRateChangeCount)
+	require.True(t, r.isSenderReportReferenceInvalid)
+
+	// same clock rate is not a change
+	r.SetClockRate(48000)
+	require.Equal(t, 1, r.clockRateChangeCount)
+
+	// next sender report becomes the new reference
+	require.True(t, r.SetRtcpSenderReportData(&RTCPSenderReportData{
+		NTPTimestamp: mediatransportutil.ToNtpTime(now.Add(time.Second)),
+		RTPTimestamp: 500000,
+		At:           now.Add(time.Second),
+	}))
+	require.False(t, r.isSenderReportReferenceInvalid)
+	require.NotSame(t, srFirst, r.srFirst)
+	require.Same(t, r.srNewest, r.srFirst)
+	require.Zero(t, r.clockSkewCount)
+}
+
+func Test_RTPStatsReceiver_MeasuredClockRate(t *testing.T) {
+	r := NewRTPStatsReceiver(RTPStatsParams{
+		ClockRate: 90000,
+		Logger:    logger.GetLogger(),
+	})
+
+	now := time.Now()
+	r.Update(now.UnixNano(), 1, 1000, true, 12, 1000, 0)
+
+	// publisher clock running 0.1% fast
+	for i := 0; i <= 20; i++ {
+		elapsed := time.Duration(i) * time.Second
+		require.True(t, r.SetRtcpSenderReportData(&RTCPSenderReportData{
+			NTPTimestamp: mediatransportutil.ToNtpTime(now.Add(elapsed)),
+			RTPTimestamp: uint32(1000 + i*90090),
+			At:           now.Add(elapsed),
+		}))
+
+		srData := r.GetRtcpSenderReportData()
+		if elapsed < cClockRateMeasureMinDuration {
+			require.Zero(t, srData.ClockRate)
+		} else {
+			require.InDelta(t, 90090, srData.ClockRate, 1)
+		}
+	}
+}
+
+func Test_RTPStatsReceiver_InterArrivalHistogram(t *testing.T) {
+	r := NewRTPStatsReceiver(RTPStatsParams{
+		ClockRate: 48000,
+		Logger:    logger.GetLogger(),
+	})
+
+	arrival := time.Now()
+	arrivals := []time.Duration{0, 20 * time.Millisecond, 20 * time.Millisecond, 500 * time.Microsecond, 250 * time.Millisecond}
+	for i, delta := range arrivals {
+		arrival = arrival.Add(delta)
+		r.Update(arrival.UnixNano(), uint16(i+1), uint32(1000+i*960), true, 12, 100, 0)
+	}
+
+	// duplicate is not counted
+	r.Update(arrival.Add(time.Millisecond).UnixNano(), uint16(len(arrivals)), uint32(1000+(len(arrivals)-1)*960), true, 12, 100, 0)
+
+	require.Equal(t, map[int32]uint32{
+		0:                                 1,
+		20:                                2,
+		cInterArrivalHistogramNumBins - 1: 1,
+	}, r.InterArrivalHistogram())
+}
+
+func Test_RTPStatsReceiver_MockClock(t *testing.T) {
+	clk := clock.NewMock()
+	clk.Set(time.Now())
+	clockRate := uint32(90000)
+	r := NewRTPStatsReceiver(RTPStatsParams{
+		ClockRate: clockRate,
+		Clock:     clk,
+		Logger:    logger.GetLogger(),
+	})
+
+	// two hours at 10 packets per second starting close to timestamp rollover,
+	// with receiver reports every 5 seconds and delta stats every minute
+	numPackets := 2 * 3600 * 10
+	sequenceNumber := uint16(65000)
+	startTimestamp := uint64(1<<32) - 10*uint64(clockRate)
+	timestamp := uint32(startTimestamp)
+	arrival := clk.Now()
+	var (
+		flowState     RTPFlowState
+		snapshotID    uint32
+		rrSnapshotID  uint32
+		totalDuration time.Duration
+		totalPackets  uint32
+	)
+	for i := 0; i < numPackets; i++ {
+		flowState = r.Update(arrival.UnixNano(), sequenceNumber, timestamp, true, 12, 100, 0)
+		require.False(t, flowState.HasLoss)
+		if i == 0 {
+			snapshotID = r.NewSnapshotId()
+			rrSnapshotID = r.NewSnapshotId()
+		}
+
+		sequenceNumber++
+		timestamp += clockRate / 10
+		arrival = arrival.Add(100 * time.Millisecond)
+
+		if i%50 == 49 {
+			clk.Set(arrival)
+			rr := r.GetRtcpReceptionReport(1234, 0, rrSnapshotID)
+			require.NotNil(t, rr)
+			require.Zero(t, rr.TotalLost)
+			require.Zero(t, rr.FractionLost)
+		}
+
+		if i%600 == 599 {
+			deltaInfo := r.DeltaInfo(snapshotID)
+			require.NotNil(t, deltaInfo)
+			require.Equal(t, time.Minute, deltaInfo.EndTime.Sub(deltaInfo.StartTime))
+			require.Zero(t, deltaInfo.PacketsLost)
+			totalDuration += deltaInfo.EndTime.Sub(deltaInfo.StartTime)
+			totalPackets += deltaInfo.Packets
+		}
+	}
+	require.Equal(t, uint64(65000+numPackets-1), flowState.ExtSequenceNumber)
+	require.Equal(t, startTimestamp+uint64(numPackets-1)*uint64(clockRate/10), flowState.ExtTimestamp)
+	require.Equal(t, 2*time.Hour, totalDuration)
+	require.Equal(t, uint32(numPackets), totalPackets)
+}
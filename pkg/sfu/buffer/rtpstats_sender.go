@@ -32,6 +32,12 @@ const (
 	cSnInfoMask = cSnInfoSize - 1
 
 	cSenderReportInitialWait = time.Second
+
+	// a deviation of the RTP/NTP mapping from the previous sender report larger than this is considered a jump
+	cSenderReportJumpThreshold = 20 * time.Millisecond
+
+	// media time is not available if nothing was sent for longer than this, for example when muted
+	cMediaTimeMaxStaleness = time.Second
 )
 
 // -------------------------------------------------------------------
@@ -120,6 +126,10 @@ type senderSnapshot struct {
 	bytesDuplicate       uint64
 	headerBytesDuplicate uint64
 
+	packetsRetransmitted     uint64
+	bytesRetransmitted       uint64
+	headerBytesRetransmitted uint64
+
 	packetsOutOfOrder uint64
 
 	packetsLostFeed uint64
@@ -131,9 +141,10 @@ type senderSnapshot struct {
 	plis  uint32
 	firs  uint32
 
-	maxRtt        uint32
-	maxJitterFeed float64
-	maxJitter     float64
+	maxRtt          uint32
+	maxJitterFeed   float64
+	maxJitter       float64
+	maxCaptureDelay time.Duration
 
 	extLastRRSN   uint64
 	intervalStats intervalStats
@@ -157,6 +168,24 @@ type RTPStatsSender struct {
 	jitterFromRR    float64
 	maxJitterFromRR float64
 
+	// retransmissions are accounted separately from the original stream
+	packetsRetransmitted     uint64
+	bytesRetransmitted       uint64
+	headerBytesRetransmitted uint64
+
+	captureDelay time.Duration
+
+	// unsmoothed mapping of the last sender report and correction applied to smooth a jump,
+	// decaying from srCorrection at srCorrectionAt to 0 over the smoothing window
+	srRawNTPTimestamp    mediatransportutil.NtpTime
+	srRawRTPTimestampExt uint64
+	srCorrection         int64
+	srCorrectionAt       time.Time
+
+	// shift of the RTP/NTP mapping of sender reports to correct A/V sync drift,
+	// positive values make media of the track play later
+	syncCorrection time.Duration
+
 	snInfos [cSnInfoSize]snInfo
 
 	nextSenderSnapshotID uint32
@@ -200,6 +229,18 @@ func (r *RTPStatsSender) Seed(from *RTPStatsSender) {
 	r.jitterFromRR = from.jitterFromRR
 	r.maxJitterFromRR = from.maxJitterFromRR
 
+	r.packetsRetransmitted = from.packetsRetransmitted
+	r.bytesRetransmitted = from.bytesRetransmitted
+	r.headerBytesRetransmitted = from.headerBytesRetransmitted
+
+	r.captureDelay = from.captureDelay
+
+	r.srRawNTPTimestamp = from.srRawNTPTimestamp
+	r.srRawRTPTimestampExt = from.srRawRTPTimestampExt
+	r.srCorrection = from.srCorrection
+	r.srCorrectionAt = from.srCorrectionAt
+	r.syncCorrection = from.syncCorrection
+
 	r.snInfos = from.snInfos
 
 	r.nextSenderSnapshotID = from.nextSenderSnapshotID
@@ -228,7 +269,7 @@ func (r *RTPStatsSender) NewSenderSnapshotId() uint32 {
 	}
 
 	if r.initialized {
-		r.senderSnapshots[id-cFirstSnapshotID] = r.initSenderSnapshot(time.Now(), r.extHighestSN)
+		r.senderSnapshots[id-cFirstSnapshotID] = r.initSenderSnapshot(r.clock.Now(), r.extHighestSN)
 	}
 	return id
 }
@@ -257,7 +298,7 @@ func (r *RTPStatsSender) Update(
 
 		r.initialized = true
 
-		r.startTime = time.Now()
+		r.startTime = r.clock.Now()
 
 		r.firstTime = packetTime
 		r.highestTime = packetTime
@@ -436,6 +477,39 @@ func (r *RTPStatsSender) Update(
 	}
 }
 
+// UpdateRetransmission records a packet re-sent in response to a NACK,
+// it is not counted as part of the original stream.
+func (r *RTPStatsSender) UpdateRetransmission(hdrSize int, payloadSize int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if !r.initialized || !r.endTime.IsZero() {
+		return
+	}
+
+	r.packetsRetransmitted++
+	r.bytesRetransmitted += uint64(hdrSize + payloadSize)
+	r.headerBytesRetransmitted += uint64(hdrSize)
+}
+
+// UpdateCaptureDelay records the time from capture of a packet at the publisher to it being sent
+func (r *RTPStatsSender) UpdateCaptureDelay(captureDelay time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if !r.initialized || !r.endTime.IsZero() {
+		return
+	}
+
+	r.captureDelay = captureDelay
+	for i := uint32(0); i < r.nextSenderSnapshotID-cFirstSnapshotID; i++ {
+		s := &r.senderSnapshots[i]
+		if captureDelay > s.maxCaptureDelay {
+			s.maxCaptureDelay = captureDelay
+		}
+	}
+}
+
 func (r *RTPStatsSender) GetTotalPacketsPrimary() uint64 {
 	r.lock.RLock()
 	defer r.lock.RUnlock()
@@ -460,16 +534,18 @@ func (r *RTPStatsSender) UpdateFromReceiverReport(rr rtcp.ReceptionReport) (rtt
 	if (extHighestSNFromRR + (r.extStartSN & 0xFFFF_FFFF_FFFF_0000)) < r.extStartSN {
 		// it is possible that the `LastSequenceNumber` in the receiver report is before the starting
 		// sequence number when dummy packets are used to trigger Pion's OnTrack path.
+		r.recordReceivedReceiverReport(&rr, true)
 		return
 	}
 
 	if !r.lastRRTime.IsZero() && r.extHighestSNFromRR > extHighestSNFromRR {
 		r.logger.Debugw(
 			fmt.Sprintf("receiver report potentially out of order, highestSN: existing: %d, received: %d", r.extHighestSNFromRR, extHighestSNFromRR),
-			"sinceLastRR", time.Since(r.lastRRTime).String(),
+			"sinceLastRR", r.clock.Since(r.lastRRTime).String(),
 			"receivedRR", rr,
 			"rtpStats", lockedRTPStatsSenderLogEncoder{r},
 		)
+		r.recordReceivedReceiverReport(&rr, true)
 		return
 	}
 
@@ -524,9 +600,9 @@ func (r *RTPStatsSender) UpdateFromReceiverReport(rr rtcp.ReceptionReport) (rtt
 		}
 
 		if int64(extReceivedRRSN-s.extLastRRSN) < 0 || (extReceivedRRSN-s.extLastRRSN) > (1<<15) {
-			timeSinceLastRR := time.Since(r.lastRRTime)
+			timeSinceLastRR := r.clock.Since(r.lastRRTime)
 			if r.lastRRTime.IsZero() {
-				timeSinceLastRR = time.Since(r.startTime)
+				timeSinceLastRR = r.clock.Since(r.startTime)
 			}
 			r.logger.Infow(
 				"rr interval too big, skipping",
@@ -544,9 +620,9 @@ func (r *RTPStatsSender) UpdateFromReceiverReport(rr rtcp.ReceptionReport) (rtt
 		eis := &s.intervalStats
 		eis.aggregate(&is)
 		if is.packetsNotFound != 0 {
-			timeSinceLastRR := time.Since(r.lastRRTime)
+			timeSinceLastRR := r.clock.Since(r.lastRRTime)
 			if r.lastRRTime.IsZero() {
-				timeSinceLastRR = time.Since(r.startTime)
+				timeSinceLastRR = r.clock.Since(r.startTime)
 			}
 			r.metadataCacheOverflowCount++
 			if (r.metadataCacheOverflowCount-1)%10 == 0 {
@@ -566,11 +642,21 @@ func (r *RTPStatsSender) UpdateFromReceiverReport(rr rtcp.ReceptionReport) (rtt
 		s.extLastRRSN = extReceivedRRSN
 	}
 
-	r.lastRRTime = time.Now()
+	r.lastRRTime = r.clock.Now()
 	r.lastRR = rr
+	r.recordReceivedReceiverReport(&rr, false)
 	return
 }
 
+func (r *RTPStatsSender) recordReceivedReceiverReport(rr *rtcp.ReceptionReport, dropped bool) {
+	r.reportHistory.add(RTCPReport{
+		At:             r.clock.Now(),
+		Direction:      RTCPReportDirectionReceived,
+		ReceiverReport: rr,
+		Dropped:        dropped,
+	})
+}
+
 func (r *RTPStatsSender) LastReceiverReportTime() time.Time {
 	r.lock.RLock()
 	defer r.lock.RUnlock()
@@ -606,6 +692,49 @@ func (r *RTPStatsSender) GetExpectedRTPTimestamp(at time.Time) (expectedTSExt ui
 	return
 }
 
+// SetSyncCorrection shifts the RTP/NTP mapping of the sender reports generated from now on,
+// so that receivers synchronising playout on sender reports play media of the track later by correction
+func (r *RTPStatsSender) SetSyncCorrection(correction time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.syncCorrection = correction
+}
+
+func (r *RTPStatsSender) GetSyncCorrection() time.Duration {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.syncCorrection
+}
+
+// GetMediaTime returns the NTP time of the media being sent at the given time, derived from the last sender report
+// and the highest sent timestamp. Comparing media time across tracks synchronised via sender reports, gives the drift between them.
+func (r *RTPStatsSender) GetMediaTime(at time.Time) (mediaTime time.Time, err error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if !r.initialized {
+		err = errors.New("uninitialized")
+		return
+	}
+
+	if r.srNewest == nil {
+		err = errors.New("no sender report")
+		return
+	}
+
+	sinceHighest := at.Sub(time.Unix(0, r.highestTime))
+	if sinceHighest > cMediaTimeMaxStaleness {
+		err = errors.New("not sending")
+		return
+	}
+
+	sinceSenderReport := time.Duration(int64(r.extHighestTS-r.srNewest.RTPTimestampExt) * 1e9 / int64(r.params.ClockRate))
+	mediaTime = r.srNewest.NTPTimestamp.Time().Add(sinceSenderReport + sinceHighest)
+	return
+}
+
 func (r *RTPStatsSender) GetRtcpSenderReport(ssrc uint32, publisherSRData *RTCPSenderReportData, tsOffset uint64, passThrough bool) *rtcp.SenderReport {
 	r.lock.Lock()
 	defer r.lock.Unlock()
@@ -614,7 +743,7 @@ func (r *RTPStatsSender) GetRtcpSenderReport(ssrc uint32, publisherSRData *RTCPS
 		return nil
 	}
 
-	timeSincePublisherSRAdjusted := time.Since(publisherSRData.AtAdjusted)
+	timeSincePublisherSRAdjusted := r.clock.Since(publisherSRData.AtAdjusted)
 	now := publisherSRData.AtAdjusted.Add(timeSincePublisherSRAdjusted)
 	var (
 		nowNTP    mediatransportutil.NtpTime
@@ -624,12 +753,24 @@ func (r *RTPStatsSender) GetRtcpSenderReport(ssrc uint32, publisherSRData *RTCPS
 		nowNTP = publisherSRData.NTPTimestamp
 		nowRTPExt = publisherSRData.RTPTimestampExt - tsOffset
 	} else {
-		nowNTP = mediatransportutil.ToNtpTime(now)
-		nowRTPExt = publisherSRData.RTPTimestampExt - tsOffset + uint64(timeSincePublisherSRAdjusted.Nanoseconds()*int64(r.params.ClockRate)/1e9)
+		nowNTP = mediatransportutil.ToNtpTime(r.params.SenderReportClock.WallClock(now))
+		if r.params.SenderReportClockSkewCompensation && publisherSRData.ClockRate != 0 {
+			// extrapolate at the rate the publisher clock is actually running at, so that long gaps
+			// between publisher sender reports do not accumulate the skew
+			nowRTPExt = publisherSRData.RTPTimestampExt - tsOffset + uint64(timeSincePublisherSRAdjusted.Seconds()*publisherSRData.ClockRate)
+		} else {
+			nowRTPExt = publisherSRData.RTPTimestampExt - tsOffset + uint64(timeSincePublisherSRAdjusted.Nanoseconds()*int64(r.params.ClockRate)/1e9)
+		}
+	}
+	if r.params.SenderReportSmoothingWindow > 0 {
+		nowRTPExt = r.smoothSenderReport(nowNTP, nowRTPExt, now)
+	}
+	if r.syncCorrection != 0 {
+		nowRTPExt = uint64(int64(nowRTPExt) + r.syncCorrection.Nanoseconds()*int64(r.params.ClockRate)/1e9)
 	}
 
-	packetCount := uint32(r.getTotalPacketsPrimary(r.extStartSN, r.extHighestSN) + r.packetsDuplicate + r.packetsPadding)
-	octetCount := uint32(r.bytes + r.bytesDuplicate + r.bytesPadding)
+	packetCount := uint32(r.getTotalPacketsPrimary(r.extStartSN, r.extHighestSN) + r.packetsDuplicate + r.packetsRetransmitted + r.packetsPadding)
+	octetCount := uint32(r.bytes + r.bytesDuplicate + r.bytesRetransmitted + r.bytesPadding)
 	srData := &RTCPSenderReportData{
 		NTPTimestamp:    nowNTP,
 		RTPTimestamp:    uint32(nowRTPExt),
@@ -645,12 +786,12 @@ func (r *RTPStatsSender) GetRtcpSenderReport(ssrc uint32, publisherSRData *RTCPS
 			"curr", srData,
 			"feed", publisherSRData,
 			"tsOffset", tsOffset,
-			"timeNow", time.Now().String(),
+			"timeNow", r.clock.Now().String(),
 			"now", now.String(),
 			"timeSinceHighest", now.Sub(time.Unix(0, r.highestTime)).String(),
 			"timeSinceFirst", now.Sub(time.Unix(0, r.firstTime)).String(),
 			"timeSincePublisherSRAdjusted", timeSincePublisherSRAdjusted.String(),
-			"timeSincePublisherSR", time.Since(publisherSRData.At).String(),
+			"timeSincePublisherSR", r.clock.Since(publisherSRData.At).String(),
 			"nowRTPExt", nowRTPExt,
 			"rtpStats", lockedRTPStatsSenderLogEncoder{r},
 		}
@@ -685,6 +826,11 @@ func (r *RTPStatsSender) GetRtcpSenderReport(ssrc uint32, publisherSRData *RTCPS
 	if r.srFirst == nil {
 		r.srFirst = r.srNewest
 	}
+	r.reportHistory.add(RTCPReport{
+		At:           now,
+		Direction:    RTCPReportDirectionSent,
+		SenderReport: srData,
+	})
 
 	return &rtcp.SenderReport{
 		SSRC:        ssrc,
@@ -695,6 +841,39 @@ func (r *RTPStatsSender) GetRtcpSenderReport(ssrc uint32, publisherSRData *RTCPS
 	}
 }
 
+// smoothSenderReport returns the RTP timestamp of a sender report with a jump of the RTP/NTP mapping,
+// for example due to a publisher replacing its track, spread over the smoothing window
+func (r *RTPStatsSender) smoothSenderReport(nowNTP mediatransportutil.NtpTime, nowRTPExt uint64, now time.Time) uint64 {
+	if r.srRawNTPTimestamp != 0 {
+		elapsed := nowNTP.Time().Sub(r.srRawNTPTimestamp.Time())
+		expectedRTPExt := int64(r.srRawRTPTimestampExt) + elapsed.Nanoseconds()*int64(r.params.ClockRate)/1e9
+		jump := int64(nowRTPExt) - expectedRTPExt
+		if time.Duration(math.Abs(float64(jump))*1e9/float64(r.params.ClockRate)) > cSenderReportJumpThreshold {
+			r.srCorrection = r.getSenderReportCorrection(now) - jump
+			r.srCorrectionAt = now
+			r.logger.Infow(
+				"sender report jump, smoothing",
+				"jump", jump,
+				"correction", r.srCorrection,
+				"window", r.params.SenderReportSmoothingWindow,
+			)
+		}
+	}
+	r.srRawNTPTimestamp = nowNTP
+	r.srRawRTPTimestampExt = nowRTPExt
+
+	return uint64(int64(nowRTPExt) + r.getSenderReportCorrection(now))
+}
+
+func (r *RTPStatsSender) getSenderReportCorrection(at time.Time) int64 {
+	elapsed := max(at.Sub(r.srCorrectionAt), 0)
+	if r.srCorrection == 0 || elapsed >= r.params.SenderReportSmoothingWindow {
+		return 0
+	}
+
+	return int64(float64(r.srCorrection) * (1.0 - float64(elapsed)/float64(r.params.SenderReportSmoothingWindow)))
+}
+
 func (r *RTPStatsSender) DeltaInfo(snapshotID uint32) *RTPDeltaInfo {
 	r.lock.Lock()
 	defer r.lock.Unlock()
@@ -711,6 +890,25 @@ func (r *RTPStatsSender) DeltaInfo(snapshotID uint32) *RTPDeltaInfo {
 	return deltaInfo
 }
 
+// DeltaInfoBetween returns the delta between two snapshots without resetting them,
+// allowing consumers with different windows to share snapshots.
+func (r *RTPStatsSender) DeltaInfoBetween(fromSnapshotID uint32, toSnapshotID uint32) *RTPDeltaInfo {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	deltaInfo, err, loggingFields := r.deltaInfoBetween(
+		fromSnapshotID,
+		toSnapshotID,
+		r.extStartSN,
+		r.extHighestSN,
+	)
+	if err != nil {
+		r.logger.Infow(err.Error(), append(loggingFields, "rtpStats", lockedRTPStatsSenderLogEncoder{r})...)
+	}
+
+	return deltaInfo
+}
+
 func (r *RTPStatsSender) DeltaInfoSender(senderSnapshotID uint32) *RTPDeltaInfo {
 	r.lock.Lock()
 	defer r.lock.Unlock()
@@ -776,27 +974,38 @@ func (r *RTPStatsSender) DeltaInfoSender(senderSnapshotID uint32) *RTPDeltaInfo
 	}
 	maxJitterTime := maxJitter / float64(r.params.ClockRate) * 1e6
 
+	// glass to glass latency is capture to send, network delay to subscriber and
+	// subscriber jitter buffer, approximated as twice the jitter
+	var maxLatency uint32
+	if then.maxCaptureDelay > 0 {
+		maxLatency = uint32(then.maxCaptureDelay.Milliseconds()) + then.maxRtt/2 + uint32(maxJitterTime*2/1e3)
+	}
+
 	return &RTPDeltaInfo{
-		StartTime:            startTime,
-		EndTime:              endTime,
-		Packets:              packetsExpected - uint32(now.packetsPadding-then.packetsPadding),
-		Bytes:                now.bytes - then.bytes,
-		HeaderBytes:          now.headerBytes - then.headerBytes,
-		PacketsDuplicate:     uint32(now.packetsDuplicate - then.packetsDuplicate),
-		BytesDuplicate:       now.bytesDuplicate - then.bytesDuplicate,
-		HeaderBytesDuplicate: now.headerBytesDuplicate - then.headerBytesDuplicate,
-		PacketsPadding:       uint32(now.packetsPadding - then.packetsPadding),
-		BytesPadding:         now.bytesPadding - then.bytesPadding,
-		HeaderBytesPadding:   now.headerBytesPadding - then.headerBytesPadding,
-		PacketsLost:          packetsLost,
-		PacketsMissing:       packetsLostFeed,
-		PacketsOutOfOrder:    uint32(now.packetsOutOfOrder - then.packetsOutOfOrder),
-		Frames:               now.frames - then.frames,
-		RttMax:               then.maxRtt,
-		JitterMax:            maxJitterTime,
-		Nacks:                now.nacks - then.nacks,
-		Plis:                 now.plis - then.plis,
-		Firs:                 now.firs - then.firs,
+		StartTime:                startTime,
+		EndTime:                  endTime,
+		Packets:                  packetsExpected - uint32(now.packetsPadding-then.packetsPadding),
+		Bytes:                    now.bytes - then.bytes,
+		HeaderBytes:              now.headerBytes - then.headerBytes,
+		PacketsDuplicate:         uint32(now.packetsDuplicate - then.packetsDuplicate),
+		BytesDuplicate:           now.bytesDuplicate - then.bytesDuplicate,
+		HeaderBytesDuplicate:     now.headerBytesDuplicate - then.headerBytesDuplicate,
+		PacketsPadding:           uint32(now.packetsPadding - then.packetsPadding),
+		BytesPadding:             now.bytesPadding - then.bytesPadding,
+		HeaderBytesPadding:       now.headerBytesPadding - then.headerBytesPadding,
+		PacketsRetransmitted:     uint32(now.packetsRetransmitted - then.packetsRetransmitted),
+		BytesRetransmitted:       now.bytesRetransmitted - then.bytesRetransmitted,
+		HeaderBytesRetransmitted: now.headerBytesRetransmitted - then.headerBytesRetransmitted,
+		PacketsLost:              packetsLost,
+		PacketsMissing:           packetsLostFeed,
+		PacketsOutOfOrder:        uint32(now.packetsOutOfOrder - then.packetsOutOfOrder),
+		Frames:                   now.frames - then.frames,
+		RttMax:                   then.maxRtt,
+		JitterMax:                maxJitterTime,
+		Nacks:                    now.nacks - then.nacks,
+		Plis:                     now.plis - then.plis,
+		Firs:                     now.firs - then.firs,
+		LatencyMax:               maxLatency,
 	}
 }
 
@@ -826,11 +1035,25 @@ func (r *RTPStatsSender) ToProto() *livekit.RTPStats {
 	r.lock.RLock()
 	defer r.lock.RUnlock()
 
-	return r.toProto(
+	p := r.toProto(
 		r.extStartSN, r.extHighestSN, r.extStartTS, r.extHighestTS,
 		r.packetsLostFromRR,
 		r.jitterFromRR, r.maxJitterFromRR,
 	)
+	if p == nil || r.packetsRetransmitted == 0 {
+		return p
+	}
+
+	// there are no separate retransmission fields in the protocol,
+	// on the send side, duplicates are retransmissions, so report them together
+	p.PacketsDuplicate += uint32(r.packetsRetransmitted)
+	p.BytesDuplicate += r.bytesRetransmitted
+	p.HeaderBytesDuplicate += r.headerBytesRetransmitted
+	if p.Duration != 0 {
+		p.PacketDuplicateRate = float64(p.PacketsDuplicate) / p.Duration
+		p.BitrateDuplicate = float64(p.BytesDuplicate) * 8.0 / p.Duration
+	}
+	return p
 }
 
 func (r *RTPStatsSender) getAndResetSenderSnapshot(senderSnapshotID uint32) (*senderSnapshot, *senderSnapshot) {
@@ -866,27 +1089,31 @@ func (r *RTPStatsSender) getSenderSnapshot(startTime time.Time, s *senderSnapsho
 	}
 
 	return senderSnapshot{
-		isValid:              true,
-		startTime:            startTime,
-		extStartSN:           s.extLastRRSN + 1,
-		bytes:                s.bytes + s.intervalStats.bytes,
-		headerBytes:          s.headerBytes + s.intervalStats.headerBytes,
-		packetsPadding:       s.packetsPadding + s.intervalStats.packetsPadding,
-		bytesPadding:         s.bytesPadding + s.intervalStats.bytesPadding,
-		headerBytesPadding:   s.headerBytesPadding + s.intervalStats.headerBytesPadding,
-		packetsDuplicate:     r.packetsDuplicate,
-		bytesDuplicate:       r.bytesDuplicate,
-		headerBytesDuplicate: r.headerBytesDuplicate,
-		packetsLostFeed:      r.packetsLost,
-		packetsOutOfOrder:    s.packetsOutOfOrder + s.intervalStats.packetsOutOfOrder,
-		frames:               s.frames + s.intervalStats.frames,
-		nacks:                r.nacks,
-		plis:                 r.plis,
-		firs:                 r.firs,
-		maxRtt:               r.rtt,
-		maxJitterFeed:        r.jitter,
-		maxJitter:            r.jitterFromRR,
-		extLastRRSN:          s.extLastRRSN,
+		isValid:                  true,
+		startTime:                startTime,
+		extStartSN:               s.extLastRRSN + 1,
+		bytes:                    s.bytes + s.intervalStats.bytes,
+		headerBytes:              s.headerBytes + s.intervalStats.headerBytes,
+		packetsPadding:           s.packetsPadding + s.intervalStats.packetsPadding,
+		bytesPadding:             s.bytesPadding + s.intervalStats.bytesPadding,
+		headerBytesPadding:       s.headerBytesPadding + s.intervalStats.headerBytesPadding,
+		packetsDuplicate:         r.packetsDuplicate,
+		bytesDuplicate:           r.bytesDuplicate,
+		headerBytesDuplicate:     r.headerBytesDuplicate,
+		packetsRetransmitted:     r.packetsRetransmitted,
+		bytesRetransmitted:       r.bytesRetransmitted,
+		headerBytesRetransmitted: r.headerBytesRetransmitted,
+		packetsLostFeed:          r.packetsLost,
+		packetsOutOfOrder:        s.packetsOutOfOrder + s.intervalStats.packetsOutOfOrder,
+		frames:                   s.frames + s.intervalStats.frames,
+		nacks:                    r.nacks,
+		plis:                     r.plis,
+		firs:                     r.firs,
+		maxRtt:                   r.rtt,
+		maxJitterFeed:            r.jitter,
+		maxJitter:                r.jitterFromRR,
+		maxCaptureDelay:          r.captureDelay,
+		extLastRRSN:              s.extLastRRSN,
 	}
 }
 
@@ -1019,5 +1246,8 @@ func (r lockedRTPStatsSenderLogEncoder) MarshalLogObject(e zapcore.ObjectEncoder
 	e.AddUint64("packetsLostFromRR", r.packetsLostFromRR)
 	e.AddFloat64("jitterFromRR", r.jitterFromRR)
 	e.AddFloat64("maxJitterFromRR", r.maxJitterFromRR)
+	e.AddUint64("packetsRetransmitted", r.packetsRetransmitted)
+	e.AddUint64("bytesRetransmitted", r.bytesRetransmitted)
+	e.AddUint64("headerBytesRetransmitted", r.headerBytesRetransmitted)
 	return nil
 }
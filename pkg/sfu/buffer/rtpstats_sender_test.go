@@ -0,0 +1,232 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buffer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/mediatransportutil"
+	"github.com/livekit/protocol/logger"
+)
+
+func Test_RTPStatsSender_SenderReportSmoothing(t *testing.T) {
+	clockRate := uint32(90000)
+	window := 200 * time.Millisecond
+	r := NewRTPStatsSender(RTPStatsParams{
+		ClockRate:                   clockRate,
+		SenderReportSmoothingWindow: window,
+		Logger:                      logger.GetLogger(),
+	})
+	r.Update(time.Now().UnixNano(), 1, 1000, true, 12, 1000, 0)
+
+	now := time.Now()
+	publisherSR := func(elapsed time.Duration, rtpTimestampExt uint64) *RTCPSenderReportData {
+		return &RTCPSenderReportData{
+			NTPTimestamp:    mediatransportutil.ToNtpTime(now.Add(elapsed)),
+			RTPTimestamp:    uint32(rtpTimestampExt),
+			RTPTimestampExt: rtpTimestampExt,
+			At:              time.Now(),
+			AtAdjusted:      time.Now(),
+		}
+	}
+
+	sr := r.GetRtcpSenderReport(1, publisherSR(0, 1000), 0, true)
+	require.NotNil(t, sr)
+	require.Equal(t, uint32(1000), sr.RTPTime)
+
+	// steady mapping is not changed
+	sr = r.GetRtcpSenderReport(1, publisherSR(time.Second, 1000+90000), 0, true)
+	require.NotNil(t, sr)
+	require.Equal(t, uint32(1000+90000), sr.RTPTime)
+
+	// a jump of 100 ms is held back at first
+	sr = r.GetRtcpSenderReport(1, publisherSR(2*time.Second, 1000+2*90000+9000), 0, true)
+	require.NotNil(t, sr)
+	require.InDelta(t, 1000+2*90000, sr.RTPTime, 500)
+
+	// and fully applied after the smoothing window
+	time.Sleep(window)
+	sr = r.GetRtcpSenderReport(1, publisherSR(3*time.Second, 1000+3*90000+9000), 0, true)
+	require.NotNil(t, sr)
+	require.Equal(t, uint32(1000+3*90000+9000), sr.RTPTime)
+}
+
+func Test_RTPStatsSender_Retransmission(t *testing.T) {
+	r := NewRTPStatsSender(RTPStatsParams{
+		ClockRate: 90000,
+		Logger:    logger.GetLogger(),
+	})
+
+	// not counted before start
+	r.UpdateRetransmission(12, 1000)
+
+	for sn := uint64(1); sn <= 10; sn++ {
+		r.Update(time.Now().UnixNano(), sn, 1000+sn*3000, true, 12, 1000, 0)
+	}
+	r.UpdateRetransmission(12, 1000)
+	r.UpdateRetransmission(12, 500)
+
+	sr := r.GetRtcpSenderReport(1, &RTCPSenderReportData{
+		NTPTimestamp:    mediatransportutil.ToNtpTime(time.Now()),
+		RTPTimestampExt: 1000,
+		At:              time.Now(),
+		AtAdjusted:      time.Now(),
+	}, 0, true)
+	require.NotNil(t, sr)
+	require.Equal(t, uint32(12), sr.PacketCount)
+	require.Equal(t, uint32(10*1012+1012+512), sr.OctetCount)
+
+	p := r.ToProto()
+	require.NotNil(t, p)
+	require.Equal(t, uint32(10), p.Packets)
+	require.Equal(t, uint64(10*1012), p.Bytes)
+	require.Equal(t, uint32(2), p.PacketsDuplicate)
+	require.Equal(t, uint64(1012+512), p.BytesDuplicate)
+	require.Equal(t, uint64(24), p.HeaderBytesDuplicate)
+}
+
+func Test_RTPStatsSender_SenderReportClockSkewCompensation(t *testing.T) {
+	clockRate := uint32(90000)
+	at := time.Now().Add(-30 * time.Second)
+	publisherSR := &RTCPSenderReportData{
+		NTPTimestamp:    mediatransportutil.ToNtpTime(at),
+		RTPTimestamp:    1000,
+		RTPTimestampExt: 1000,
+		At:              at,
+		AtAdjusted:      at,
+		ClockRate:       90090,
+	}
+
+	for _, compensate := range []bool{false, true} {
+		r := NewRTPStatsSender(RTPStatsParams{
+			ClockRate:                         clockRate,
+			SenderReportClockSkewCompensation: compensate,
+			Logger:                            logger.GetLogger(),
+		})
+		r.Update(time.Now().UnixNano(), 1, 1000, true, 12, 1000, 0)
+
+		sr := r.GetRtcpSenderReport(1, publisherSR, 0, false)
+		require.NotNil(t, sr)
+		if compensate {
+			require.InDelta(t, 1000+30*90090, sr.RTPTime, 500)
+		} else {
+			require.InDelta(t, 1000+30*90000, sr.RTPTime, 500)
+		}
+	}
+}
+
+type offsetClockSource time.Duration
+
+func (o offsetClockSource) WallClock(at time.Time) time.Time {
+	return at.Add(time.Duration(o))
+}
+
+func (o offsetClockSource) Close() {}
+
+func Test_RTPStatsSender_SenderReportClock(t *testing.T) {
+	now := time.Now()
+	publisherSR := &RTCPSenderReportData{
+		NTPTimestamp:    mediatransportutil.ToNtpTime(now),
+		RTPTimestamp:    1000,
+		RTPTimestampExt: 1000,
+		At:              now,
+		AtAdjusted:      now,
+	}
+
+	r := NewRTPStatsSender(RTPStatsParams{
+		ClockRate:         90000,
+		SenderReportClock: offsetClockSource(time.Hour),
+		Logger:            logger.GetLogger(),
+	})
+	r.Update(now.UnixNano(), 1, 1000, true, 12, 1000, 0)
+
+	// passed through reports keep the publisher time
+	sr := r.GetRtcpSenderReport(1, publisherSR, 0, true)
+	require.NotNil(t, sr)
+	require.Equal(t, uint64(publisherSR.NTPTimestamp), sr.NTPTime)
+
+	// generated reports use the clock source
+	sr = r.GetRtcpSenderReport(1, publisherSR, 0, false)
+	require.NotNil(t, sr)
+	require.InDelta(t, now.Add(time.Hour).UnixNano(), mediatransportutil.NtpTime(sr.NTPTime).Time().UnixNano(), float64(100*time.Millisecond))
+}
+
+func Test_RTPStatsSender_SyncCorrection(t *testing.T) {
+	now := time.Now()
+	publisherSR := func(elapsed time.Duration) *RTCPSenderReportData {
+		return &RTCPSenderReportData{
+			NTPTimestamp:    mediatransportutil.ToNtpTime(now.Add(elapsed)),
+			RTPTimestamp:    uint32(1000 + elapsed.Milliseconds()*90),
+			RTPTimestampExt: uint64(1000 + elapsed.Milliseconds()*90),
+			At:              now.Add(elapsed),
+			AtAdjusted:      now.Add(elapsed),
+		}
+	}
+
+	r := NewRTPStatsSender(RTPStatsParams{
+		ClockRate: 90000,
+		Logger:    logger.GetLogger(),
+	})
+	r.Update(now.UnixNano(), 1, 1000, true, 12, 1000, 0)
+
+	sr := r.GetRtcpSenderReport(1, publisherSR(0), 0, true)
+	require.NotNil(t, sr)
+	require.Equal(t, uint32(1000), sr.RTPTime)
+
+	// delaying playout by 100ms maps the same NTP time to a later RTP timestamp
+	r.SetSyncCorrection(100 * time.Millisecond)
+	require.Equal(t, 100*time.Millisecond, r.GetSyncCorrection())
+	sr = r.GetRtcpSenderReport(1, publisherSR(time.Second), 0, true)
+	require.NotNil(t, sr)
+	require.Equal(t, uint32(1000+90000+9000), sr.RTPTime)
+}
+
+func Test_RTPStatsSender_GetMediaTime(t *testing.T) {
+	r := NewRTPStatsSender(RTPStatsParams{
+		ClockRate: 90000,
+		Logger:    logger.GetLogger(),
+	})
+
+	now := time.Now()
+	_, err := r.GetMediaTime(now)
+	require.Error(t, err)
+
+	r.Update(now.UnixNano(), 1, 1000, true, 12, 1000, 0)
+	_, err = r.GetMediaTime(now)
+	require.Error(t, err)
+
+	// sender report maps RTP timestamp 1000 to publisher NTP time
+	ntpTime := now.Add(-time.Minute)
+	require.NotNil(t, r.GetRtcpSenderReport(1, &RTCPSenderReportData{
+		NTPTimestamp:    mediatransportutil.ToNtpTime(ntpTime),
+		RTPTimestamp:    1000,
+		RTPTimestampExt: 1000,
+		At:              now,
+		AtAdjusted:      now,
+	}, 0, true))
+
+	// 100 ms of media sent, 50 ms ago
+	r.Update(now.Add(100*time.Millisecond).UnixNano(), 2, 1000+9000, true, 12, 1000, 0)
+	mediaTime, err := r.GetMediaTime(now.Add(150 * time.Millisecond))
+	require.NoError(t, err)
+	require.InDelta(t, ntpTime.Add(150*time.Millisecond).UnixNano(), mediaTime.UnixNano(), float64(time.Millisecond))
+
+	// stale when not sending
+	_, err = r.GetMediaTime(now.Add(5 * time.Second))
+	require.Error(t, err)
+}
@@ -14,7 +14,53 @@
 
 package buffer
 
+import (
+	"github.com/livekit/protocol/livekit"
+)
+
 type StreamStatsWithLayers struct {
 	RTPStats *RTPDeltaInfo
 	Layers   map[int32]*RTPDeltaInfo
 }
+
+// RTPStatsWithLayers is the receiver stats of a track aggregated across its streams with a per layer breakdown
+type RTPStatsWithLayers struct {
+	RTPStats *livekit.RTPStats
+	Layers   map[int32]*livekit.RTPStats
+}
+
+func NewRTPStatsWithLayers(layers map[int32]*livekit.RTPStats) *RTPStatsWithLayers {
+	if len(layers) == 0 {
+		return nil
+	}
+
+	stats := make([]*livekit.RTPStats, 0, len(layers))
+	for _, layerStats := range layers {
+		stats = append(stats, layerStats)
+	}
+	return &RTPStatsWithLayers{
+		RTPStats: AggregateRTPStats(stats),
+		Layers:   layers,
+	}
+}
+
+// AggregateRTPStatsWithLayers merges stats of a track from multiple receivers, for example one per codec, layer by layer
+func AggregateRTPStatsWithLayers(statsList []*RTPStatsWithLayers) *RTPStatsWithLayers {
+	layerStats := make(map[int32][]*livekit.RTPStats)
+	for _, stats := range statsList {
+		if stats == nil {
+			continue
+		}
+		for layer, s := range stats.Layers {
+			layerStats[layer] = append(layerStats[layer], s)
+		}
+	}
+
+	layers := make(map[int32]*livekit.RTPStats, len(layerStats))
+	for layer, stats := range layerStats {
+		if agg := AggregateRTPStats(stats); agg != nil {
+			layers[layer] = agg
+		}
+	}
+	return NewRTPStatsWithLayers(layers)
+}
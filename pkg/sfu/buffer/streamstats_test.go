@@ -0,0 +1,62 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buffer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+func TestAggregateRTPStatsWithLayers(t *testing.T) {
+	require.Nil(t, NewRTPStatsWithLayers(nil))
+	require.Nil(t, AggregateRTPStatsWithLayers([]*RTPStatsWithLayers{nil}))
+
+	now := time.Now()
+	newStats := func(packets uint32, bytes uint64) *livekit.RTPStats {
+		return &livekit.RTPStats{
+			StartTime: timestamppb.New(now),
+			EndTime:   timestamppb.New(now.Add(10 * time.Second)),
+			Duration:  10,
+			Packets:   packets,
+			Bytes:     bytes,
+		}
+	}
+
+	primary := NewRTPStatsWithLayers(map[int32]*livekit.RTPStats{
+		0: newStats(100, 10000),
+		1: newStats(200, 40000),
+	})
+	require.NotNil(t, primary)
+	require.Equal(t, uint32(300), primary.RTPStats.Packets)
+	require.Equal(t, uint64(50000), primary.RTPStats.Bytes)
+
+	backup := NewRTPStatsWithLayers(map[int32]*livekit.RTPStats{
+		0: newStats(50, 5000),
+	})
+
+	agg := AggregateRTPStatsWithLayers([]*RTPStatsWithLayers{primary, nil, backup})
+	require.NotNil(t, agg)
+	require.Len(t, agg.Layers, 2)
+	require.Equal(t, uint32(150), agg.Layers[0].Packets)
+	require.Equal(t, uint64(15000), agg.Layers[0].Bytes)
+	require.Equal(t, uint32(200), agg.Layers[1].Packets)
+	require.Equal(t, uint32(350), agg.RTPStats.Packets)
+	require.Equal(t, uint64(55000), agg.RTPStats.Bytes)
+}
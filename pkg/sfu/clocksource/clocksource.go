@@ -0,0 +1,241 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clocksource
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	defaultNTPSyncInterval = time.Minute
+	defaultPTPSyncInterval = time.Second
+
+	// each sync keeps the sample with the lowest round trip, its offset is the least affected by queuing delays
+	ntpSyncSamples = 4
+	// spaced like the iburst of ntpd, servers rate limit faster queries
+	ntpSampleSpacing = 2 * time.Second
+	ptpSyncSamples   = 5
+
+	// offset changes are slewed at up to 500 ppm, like the kernel clock discipline, larger changes are stepped
+	maxSlewRate   = 0.0005
+	maxSlewOffset = 128 * time.Millisecond
+)
+
+var errClockClosed = errors.New("clock source closed")
+
+// ClockSource is the wall clock of the NTP timestamps of generated sender reports
+type ClockSource interface {
+	// WallClock returns the wall clock time at local time at
+	WallClock(at time.Time) time.Time
+	Close()
+}
+
+// System uses the system clock as is
+var System ClockSource = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) WallClock(at time.Time) time.Time {
+	return at
+}
+
+func (systemClock) Close() {}
+
+// New returns the clock source of conf. ntp and ptp clocks fall back to the system clock until the first
+// successful sync
+func New(conf config.SenderReportClockConfig, logger logger.Logger) (ClockSource, error) {
+	logger = logger.WithValues("clockSource", conf.Source)
+	switch conf.Source {
+	case "", config.SenderReportClockSystem:
+		return System, nil
+	case config.SenderReportClockNTP:
+		return newOffsetClock(offsetClockParams{
+			Measure:       func() (time.Duration, time.Duration, error) { return queryNTP(conf.NTPServer) },
+			Interval:      syncInterval(conf.SyncInterval, defaultNTPSyncInterval),
+			Samples:       ntpSyncSamples,
+			SampleSpacing: ntpSampleSpacing,
+			Logger:        logger,
+		}), nil
+	case config.SenderReportClockPTP:
+		return newOffsetClock(offsetClockParams{
+			Measure: func() (time.Duration, time.Duration, error) {
+				offset, rtt, err := readPTPOffset(conf.PTPDevice)
+				return offset - conf.PTPUTCOffset, rtt, err
+			},
+			Interval: syncInterval(conf.SyncInterval, defaultPTPSyncInterval),
+			Samples:  ptpSyncSamples,
+			Logger:   logger,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown clock source %q", conf.Source)
+	}
+}
+
+func syncInterval(interval time.Duration, defaultInterval time.Duration) time.Duration {
+	if interval <= 0 {
+		return defaultInterval
+	}
+	return interval
+}
+
+type offsetClockParams struct {
+	// Measure returns the offset of the system clock to the reference clock, and the round trip of the measurement
+	Measure       func() (offset time.Duration, rtt time.Duration, err error)
+	Interval      time.Duration
+	Samples       int
+	SampleSpacing time.Duration
+	Logger        logger.Logger
+}
+
+// offsetClock follows a reference clock by periodically measuring the offset of the system clock to it. The first
+// sync runs in the background, the system clock is used until it succeeds
+type offsetClock struct {
+	params offsetClockParams
+
+	lock      sync.RWMutex
+	slew      offsetSlew
+	synced    bool
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newOffsetClock(params offsetClockParams) *offsetClock {
+	if params.Samples <= 0 {
+		params.Samples = 1
+	}
+	c := &offsetClock{
+		params: params,
+		done:   make(chan struct{}),
+	}
+	go c.worker()
+	return c
+}
+
+func (c *offsetClock) WallClock(at time.Time) time.Time {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return at.Add(c.slew.offsetAt(at))
+}
+
+func (c *offsetClock) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+func (c *offsetClock) worker() {
+	c.sync()
+
+	ticker := time.NewTicker(c.params.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.sync()
+		}
+	}
+}
+
+func (c *offsetClock) sync() {
+	offset, err := c.measure()
+	if err != nil {
+		// keep the last offset, it drifts slowly
+		c.params.Logger.Warnw("could not sync clock source", err)
+		return
+	}
+
+	now := time.Now()
+	c.lock.Lock()
+	current := c.slew.offsetAt(now)
+	stepped := !c.synced || (offset-current).Abs() > maxSlewOffset
+	if stepped {
+		c.slew = offsetSlew{from: offset, to: offset, start: now}
+	} else {
+		c.slew = offsetSlew{from: current, to: offset, start: now}
+	}
+	synced := c.synced
+	c.synced = true
+	c.lock.Unlock()
+
+	switch {
+	case !synced:
+		c.params.Logger.Infow("clock source synced", "offset", offset)
+	case stepped:
+		c.params.Logger.Infow("clock source stepped", "offset", offset, "previous", current)
+	}
+}
+
+// measure returns the offset of the sample with the lowest round trip
+func (c *offsetClock) measure() (time.Duration, error) {
+	var offset time.Duration
+	minRTT := time.Duration(math.MaxInt64)
+	var lastErr error
+	for i := 0; i < c.params.Samples; i++ {
+		if i > 0 && c.params.SampleSpacing > 0 {
+			select {
+			case <-c.done:
+				return 0, errClockClosed
+			case <-time.After(c.params.SampleSpacing):
+			}
+		}
+
+		sampleOffset, rtt, err := c.params.Measure()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if rtt < minRTT {
+			offset, minRTT = sampleOffset, rtt
+		}
+	}
+	if minRTT == time.Duration(math.MaxInt64) {
+		return 0, lastErr
+	}
+	return offset, nil
+}
+
+// offsetSlew moves the offset from one measurement to the next at the max slew rate, so the wall clock never jumps
+type offsetSlew struct {
+	from  time.Duration
+	to    time.Duration
+	start time.Time
+}
+
+func (s offsetSlew) offsetAt(at time.Time) time.Duration {
+	elapsed := at.Sub(s.start)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	correction := time.Duration(float64(elapsed) * maxSlewRate)
+	switch delta := s.to - s.from; {
+	case delta.Abs() <= correction:
+		return s.to
+	case delta > 0:
+		return s.from + correction
+	default:
+		return s.from - correction
+	}
+}
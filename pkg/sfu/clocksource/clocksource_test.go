@@ -0,0 +1,145 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clocksource
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/mediatransportutil"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// serveNTP answers one SNTP request with a clock running offset ahead of the system clock
+func serveNTP(t *testing.T, offset time.Duration, stratum byte) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		req := make([]byte, ntpPacketSize)
+		_, addr, err := conn.ReadFrom(req)
+		if err != nil {
+			return
+		}
+		res := make([]byte, ntpPacketSize)
+		res[0] = 0x24 // LI 0, version 4, mode 4 (server)
+		res[1] = stratum
+		copy(res[24:32], req[40:48])
+		binary.BigEndian.PutUint64(res[32:], uint64(mediatransportutil.ToNtpTime(time.Now().Add(offset))))
+		binary.BigEndian.PutUint64(res[40:], uint64(mediatransportutil.ToNtpTime(time.Now().Add(offset))))
+		_, _ = conn.WriteTo(res, addr)
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestQueryNTP(t *testing.T) {
+	offset, rtt, err := queryNTP(serveNTP(t, 3*time.Second, 2))
+	require.NoError(t, err)
+	require.InDelta(t, 3*time.Second, offset, float64(50*time.Millisecond))
+	require.Less(t, rtt, 50*time.Millisecond)
+
+	_, _, err = queryNTP(serveNTP(t, 0, 0))
+	require.ErrorIs(t, err, ErrNTPUnsynchronized)
+}
+
+func TestOffsetClock(t *testing.T) {
+	t.Run("first sync steps, in the background", func(t *testing.T) {
+		offsets := make(chan time.Duration, 1)
+		c := newOffsetClock(offsetClockParams{
+			Measure: func() (time.Duration, time.Duration, error) {
+				select {
+				case offset := <-offsets:
+					return offset, 0, nil
+				default:
+					return 0, 0, errors.New("unavailable")
+				}
+			},
+			Interval: 10 * time.Millisecond,
+			Logger:   logger.GetLogger(),
+		})
+		defer c.Close()
+
+		// the system clock is used until the first sync
+		now := time.Now()
+		require.Equal(t, now, c.WallClock(now))
+
+		offsets <- time.Second
+		require.Eventually(t, func() bool {
+			return c.WallClock(now).Equal(now.Add(time.Second))
+		}, time.Second, 10*time.Millisecond)
+
+		// failed syncs keep the last offset
+		time.Sleep(50 * time.Millisecond)
+		require.Equal(t, now.Add(time.Second), c.WallClock(now))
+
+		// large changes are stepped
+		offsets <- 2 * time.Second
+		require.Eventually(t, func() bool {
+			now := time.Now()
+			return c.WallClock(now).Equal(now.Add(2 * time.Second))
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("lowest round trip sample", func(t *testing.T) {
+		samples := []struct{ offset, rtt time.Duration }{
+			{offset: 30 * time.Millisecond, rtt: 40 * time.Millisecond},
+			{offset: 10 * time.Millisecond, rtt: 5 * time.Millisecond},
+			{offset: -20 * time.Millisecond, rtt: 60 * time.Millisecond},
+		}
+		c := &offsetClock{
+			params: offsetClockParams{
+				Measure: func() (time.Duration, time.Duration, error) {
+					s := samples[0]
+					samples = samples[1:]
+					return s.offset, s.rtt, nil
+				},
+				Samples: 3,
+			},
+			done: make(chan struct{}),
+		}
+		offset, err := c.measure()
+		require.NoError(t, err)
+		require.Equal(t, 10*time.Millisecond, offset)
+	})
+}
+
+func TestOffsetSlew(t *testing.T) {
+	start := time.Now()
+	s := offsetSlew{from: 10 * time.Millisecond, to: 20 * time.Millisecond, start: start}
+	require.Equal(t, 10*time.Millisecond, s.offsetAt(start.Add(-time.Second)))
+	require.Equal(t, 10*time.Millisecond+500*time.Microsecond, s.offsetAt(start.Add(time.Second)))
+	require.Equal(t, 20*time.Millisecond, s.offsetAt(start.Add(time.Minute)))
+
+	s = offsetSlew{from: 20 * time.Millisecond, to: 10 * time.Millisecond, start: start}
+	require.Equal(t, 20*time.Millisecond-500*time.Microsecond, s.offsetAt(start.Add(time.Second)))
+	require.Equal(t, 10*time.Millisecond, s.offsetAt(start.Add(time.Minute)))
+}
+
+func TestNew(t *testing.T) {
+	c, err := New(config.SenderReportClockConfig{}, logger.GetLogger())
+	require.NoError(t, err)
+	require.Equal(t, System, c)
+
+	_, err = New(config.SenderReportClockConfig{Source: "gps"}, logger.GetLogger())
+	require.Error(t, err)
+}
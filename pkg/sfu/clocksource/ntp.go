@@ -0,0 +1,84 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clocksource
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/livekit/mediatransportutil"
+)
+
+const (
+	ntpPort    = "123"
+	ntpTimeout = 5 * time.Second
+
+	ntpPacketSize = 48
+	// LI 0, version 4, mode 3 (client)
+	ntpClientHeader = 0x23
+	ntpModeServer   = 4
+)
+
+var (
+	ErrNTPInvalidResponse = errors.New("invalid NTP response")
+	ErrNTPUnsynchronized  = errors.New("NTP server is not synchronized")
+)
+
+// queryNTP returns the offset of the clock of server to the system clock and the round trip, with a single SNTP
+// request
+func queryNTP(server string) (time.Duration, time.Duration, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, ntpPort)
+	}
+	conn, err := net.DialTimeout("udp", server, ntpTimeout)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(ntpTimeout)); err != nil {
+		return 0, 0, err
+	}
+
+	req := make([]byte, ntpPacketSize)
+	req[0] = ntpClientHeader
+	sentAt := time.Now()
+	// the transmit timestamp is echoed as originate timestamp in the response
+	binary.BigEndian.PutUint64(req[40:], uint64(mediatransportutil.ToNtpTime(sentAt)))
+	if _, err := conn.Write(req); err != nil {
+		return 0, 0, err
+	}
+
+	res := make([]byte, ntpPacketSize)
+	n, err := conn.Read(res)
+	receivedAt := time.Now()
+	if err != nil {
+		return 0, 0, err
+	}
+	if n < ntpPacketSize || res[0]&0x7 != ntpModeServer || binary.BigEndian.Uint64(res[24:]) != binary.BigEndian.Uint64(req[40:]) {
+		return 0, 0, ErrNTPInvalidResponse
+	}
+	// stratum 0 is a kiss-o'-death, leap indicator 3 is an unsynchronized server
+	if res[1] == 0 || res[0]>>6 == 3 {
+		return 0, 0, ErrNTPUnsynchronized
+	}
+
+	serverReceivedAt := mediatransportutil.NtpTime(binary.BigEndian.Uint64(res[32:])).Time()
+	serverSentAt := mediatransportutil.NtpTime(binary.BigEndian.Uint64(res[40:])).Time()
+	offset := (serverReceivedAt.Sub(sentAt) + serverSentAt.Sub(receivedAt)) / 2
+	rtt := receivedAt.Sub(sentAt) - serverSentAt.Sub(serverReceivedAt)
+	return offset, rtt, nil
+}
@@ -0,0 +1,46 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package clocksource
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// readPTPOffset returns the offset of the PTP hardware clock device to the system clock, and the duration of the read
+func readPTPOffset(device string) (time.Duration, time.Duration, error) {
+	f, err := os.Open(device)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	// dynamic posix clock of the device, FD_TO_CLOCKID in the kernel
+	clockID := int32((^int(f.Fd()) << 3) | 3)
+
+	var ts unix.Timespec
+	before := time.Now()
+	err = unix.ClockGettime(clockID, &ts)
+	after := time.Now()
+	if err != nil {
+		return 0, 0, err
+	}
+	rtt := after.Sub(before)
+	return time.Unix(ts.Unix()).Sub(before.Add(rtt / 2)), rtt, nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compositor
+
+import (
+	"errors"
+	"image"
+	"strings"
+	"sync"
+
+	"github.com/pion/rtp"
+)
+
+var ErrNoCodec = errors.New("no codec registered for video composition")
+
+// Decoder decodes complete frames of a track, decoders are expected to keep state between frames
+type Decoder interface {
+	Decode(frame []byte) (*image.YCbCr, error)
+}
+
+// Encoder encodes composed frames, images are 4:2:0 and of the size the encoder was created with
+type Encoder interface {
+	Encode(img *image.YCbCr, keyframe bool) ([]byte, error)
+}
+
+// Codec creates the components to decode tracks and encode the composition for a mime type.
+// No codec is built in, since video encoders require cgo bindings: servers composing video register one with RegisterCodec.
+type Codec interface {
+	MimeType() string
+	NewDepacketizer() rtp.Depacketizer
+	NewPayloader() rtp.Payloader
+	NewDecoder() (Decoder, error)
+	NewEncoder(width int, height int) (Encoder, error)
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = make(map[string]Codec)
+)
+
+// RegisterCodec makes a codec available for composition, replacing any codec registered for the same mime type
+func RegisterCodec(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	codecs[strings.ToLower(c.MimeType())] = c
+}
+
+func getCodec(mimeType string) (Codec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	c, ok := codecs[strings.ToLower(mimeType)]
+	if !ok {
+		return nil, ErrNoCodec
+	}
+	return c, nil
+}
+
+// HasCodec returns whether a codec is registered for the mime type
+func HasCodec(mimeType string) bool {
+	_, err := getCodec(mimeType)
+	return err == nil
+}
@@ -0,0 +1,548 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compositor
+
+import (
+	"errors"
+	"image"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/frostbyte73/core"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils/guid"
+
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/utils"
+)
+
+const (
+	ClockRate = 90000
+
+	subscriberIDPrefix = "CP_"
+	mtu                = 1200
+	// keyframes are requested from inputs on this interval until one is decoded
+	pliInterval = 500 * time.Millisecond
+	// packets buffered per input while the previous frame is decoded
+	maxQueuedPackets = 256
+
+	defaultWidth            = 1280
+	defaultHeight           = 720
+	defaultFPS              = 15
+	defaultKeyframeInterval = 5 * time.Second
+)
+
+var ErrUnsupportedCodec = errors.New("track codec does not match compositor codec")
+
+type Params struct {
+	Codec            webrtc.RTPCodecParameters
+	Width            int
+	Height           int
+	FPS              int
+	KeyframeInterval time.Duration
+	Layout           Layout
+	SSRC             uint32
+	Logger           logger.Logger
+	OnPacket         func(pkt *rtp.Packet)
+}
+
+// Compositor decodes video tracks and renders them into a single track on an interval.
+// Inputs are attached to their receivers like down tracks.
+type Compositor struct {
+	params    Params
+	codec     Codec
+	encoder   Encoder
+	payloader rtp.Payloader
+
+	lock   sync.Mutex
+	inputs map[livekit.TrackID]*input
+	// inputs in the order they were added, so tiles don't move around
+	order []livekit.TrackID
+	focus livekit.TrackID
+	// contributing sources of the last composed frame
+	csrcs []uint32
+
+	canvas            *image.YCbCr
+	sequenceNumber    uint16
+	startedAt         time.Time
+	lastKeyframeAt    time.Time
+	keyframeRequested atomic.Bool
+
+	stopped core.Fuse
+}
+
+func New(params Params) (*Compositor, error) {
+	if params.Width <= 0 || params.Height <= 0 {
+		params.Width, params.Height = defaultWidth, defaultHeight
+	}
+	// 4:2:0 requires even dimensions
+	params.Width, params.Height = params.Width&^1, params.Height&^1
+	if params.FPS <= 0 {
+		params.FPS = defaultFPS
+	}
+	if params.KeyframeInterval <= 0 {
+		params.KeyframeInterval = defaultKeyframeInterval
+	}
+	if params.Layout == "" {
+		params.Layout = LayoutGrid
+	}
+
+	codec, err := getCodec(params.Codec.MimeType)
+	if err != nil {
+		return nil, err
+	}
+	encoder, err := codec.NewEncoder(params.Width, params.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Compositor{
+		params:    params,
+		codec:     codec,
+		encoder:   encoder,
+		payloader: codec.NewPayloader(),
+		inputs:    make(map[livekit.TrackID]*input),
+		canvas:    image.NewYCbCr(image.Rect(0, 0, params.Width, params.Height), image.YCbCrSubsampleRatio420),
+		startedAt: time.Now(),
+	}, nil
+}
+
+// Size returns the dimensions of the rendered video
+func (c *Compositor) Size() (int, int) {
+	return c.params.Width, c.params.Height
+}
+
+func (c *Compositor) Start() {
+	go c.worker()
+}
+
+func (c *Compositor) Stop() {
+	c.stopped.Once(func() {
+		c.lock.Lock()
+		inputs := c.inputs
+		c.inputs = make(map[livekit.TrackID]*input)
+		c.order = nil
+		c.lock.Unlock()
+
+		for _, in := range inputs {
+			in.detach()
+		}
+	})
+}
+
+// AddInput renders the video of a track until it is removed or its receiver is closed
+func (c *Compositor) AddInput(receiver sfu.TrackReceiver) error {
+	if c.stopped.IsBroken() {
+		return nil
+	}
+	if !strings.EqualFold(receiver.Codec().MimeType, c.codec.MimeType()) {
+		return ErrUnsupportedCodec
+	}
+
+	decoder, err := c.codec.NewDecoder()
+	if err != nil {
+		return err
+	}
+
+	in := newInput(receiver.TrackID(), c.codec.NewDepacketizer(), decoder, c.params.Logger)
+	in.receiver = receiver
+	c.lock.Lock()
+	existing := c.inputs[in.trackID]
+	c.inputs[in.trackID] = in
+	if existing == nil {
+		c.order = append(c.order, in.trackID)
+	}
+	c.lock.Unlock()
+	if existing != nil {
+		existing.detach()
+	}
+
+	if err := receiver.AddDownTrack(in); err != nil {
+		c.RemoveInput(in.trackID)
+		return err
+	}
+	go in.worker()
+	return nil
+}
+
+func (c *Compositor) RemoveInput(trackID livekit.TrackID) {
+	c.lock.Lock()
+	in := c.removeInputLocked(trackID)
+	c.lock.Unlock()
+
+	if in != nil {
+		in.detach()
+	}
+}
+
+func (c *Compositor) removeInputLocked(trackID livekit.TrackID) *input {
+	in := c.inputs[trackID]
+	delete(c.inputs, trackID)
+	c.order = slices.DeleteFunc(c.order, func(id livekit.TrackID) bool { return id == trackID })
+	return in
+}
+
+func (c *Compositor) NumInputs() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return len(c.inputs)
+}
+
+// SetFocus selects the input shown large in the speaker layout
+func (c *Compositor) SetFocus(trackID livekit.TrackID) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.focus = trackID
+}
+
+// RequestKeyframe makes the next rendered frame a keyframe, e.g. when a subscriber lost packets
+func (c *Compositor) RequestKeyframe() {
+	c.keyframeRequested.Store(true)
+}
+
+func (c *Compositor) worker() {
+	ticker := time.NewTicker(time.Second / time.Duration(c.params.FPS))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stopped.Watch():
+			return
+		case now := <-ticker.C:
+			packets, err := c.render(now)
+			if err != nil {
+				c.params.Logger.Warnw("could not render composition", err)
+				continue
+			}
+			if c.params.OnPacket != nil {
+				for _, pkt := range packets {
+					c.params.OnPacket(pkt)
+				}
+			}
+		}
+	}
+}
+
+// compose draws the latest frame of each input, returns false when no input has a frame yet
+func (c *Compositor) compose() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, trackID := range slices.Clone(c.order) {
+		if c.inputs[trackID].IsClosed() {
+			// receiver closed, e.g. the track was unpublished
+			c.removeInputLocked(trackID)
+		}
+	}
+
+	frames := make([]*image.YCbCr, 0, len(c.order))
+	// contributing sources in layout order, the focus first
+	contributors := make([]utils.Contributor, 0, len(c.order))
+	focus := -1
+	for _, trackID := range c.order {
+		frame := c.inputs[trackID].latestFrame()
+		if frame == nil {
+			continue
+		}
+		contributor := utils.Contributor{CSRC: utils.TrackCSRC(trackID)}
+		if trackID == c.focus {
+			focus = len(frames)
+			contributor.Level = 1
+		}
+		frames = append(frames, frame)
+		contributors = append(contributors, contributor)
+	}
+	if len(frames) == 0 {
+		return false
+	}
+	c.csrcs = utils.CSRCList(contributors)
+
+	// black background
+	fill(c.canvas, 16, 128, 128)
+	for i, tile := range c.params.Layout.tiles(c.canvas.Rect, len(frames), focus) {
+		draw(c.canvas, fit(tile, frames[i].Rect), frames[i])
+	}
+	return true
+}
+
+// render composes and encodes the next frame into RTP packets
+func (c *Compositor) render(now time.Time) ([]*rtp.Packet, error) {
+	if !c.compose() {
+		return nil, nil
+	}
+
+	keyframe := c.keyframeRequested.Swap(false) || now.Sub(c.lastKeyframeAt) >= c.params.KeyframeInterval
+	frame, err := c.encoder.Encode(c.canvas, keyframe)
+	if err != nil {
+		return nil, err
+	}
+	if keyframe {
+		c.lastKeyframeAt = now
+	}
+
+	timestamp := uint32(now.Sub(c.startedAt) * ClockRate / time.Second)
+	payloads := c.payloader.Payload(mtu, frame)
+	packets := make([]*rtp.Packet, 0, len(payloads))
+	for i, payload := range payloads {
+		c.sequenceNumber++
+		packets = append(packets, &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				Marker:         i == len(payloads)-1,
+				PayloadType:    uint8(c.params.Codec.PayloadType),
+				SequenceNumber: c.sequenceNumber,
+				Timestamp:      timestamp,
+				SSRC:           c.params.SSRC,
+				CSRC:           c.csrcs,
+			},
+			Payload: payload,
+		})
+	}
+	return packets, nil
+}
+
+// inputPacket is what an input needs of a packet to assemble frames
+type inputPacket struct {
+	payload        []byte
+	layer          int32
+	keyFrame       bool
+	marker         bool
+	sequenceNumber uint64
+	timestamp      uint64
+}
+
+// input queues the packets of a track, they are assembled into frames and decoded by its worker
+type input struct {
+	trackID      livekit.TrackID
+	subscriberID livekit.ParticipantID
+	receiver     sfu.TrackReceiver
+	depacketizer rtp.Depacketizer
+	decoder      Decoder
+	logger       logger.Logger
+	packets      chan inputPacket
+	dropped      atomic.Bool
+	closed       core.Fuse
+
+	// only accessed by the worker
+	layer         int32
+	assembling    bool
+	timestamp     uint64
+	nextSN        uint64
+	frame         []byte
+	lastPLIAt     time.Time
+	needsKeyframe bool
+
+	lock         sync.Mutex
+	decodedFrame *image.YCbCr
+}
+
+func newInput(trackID livekit.TrackID, depacketizer rtp.Depacketizer, decoder Decoder, logger logger.Logger) *input {
+	return &input{
+		trackID:       trackID,
+		subscriberID:  livekit.ParticipantID(guid.New(subscriberIDPrefix)),
+		depacketizer:  depacketizer,
+		decoder:       decoder,
+		logger:        logger,
+		packets:       make(chan inputPacket, maxQueuedPackets),
+		layer:         -1,
+		needsKeyframe: true,
+	}
+}
+
+func (in *input) detach() {
+	if in.receiver != nil {
+		in.receiver.DeleteDownTrack(in.subscriberID)
+	}
+	in.Close()
+}
+
+func (in *input) latestFrame() *image.YCbCr {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+
+	return in.decodedFrame
+}
+
+func (in *input) worker() {
+	for {
+		select {
+		case <-in.closed.Watch():
+			return
+		case pkt := <-in.packets:
+			in.handlePacket(pkt)
+		}
+	}
+}
+
+func (in *input) handlePacket(pkt inputPacket) {
+	frame := in.assemble(pkt)
+	if frame == nil {
+		return
+	}
+
+	img, err := in.decoder.Decode(frame)
+	if err != nil || img == nil {
+		in.needsKeyframe = true
+		if err != nil {
+			in.logger.Debugw("could not decode frame for composition", "error", err, "trackID", in.trackID)
+		}
+		return
+	}
+	in.lock.Lock()
+	in.decodedFrame = img
+	in.lock.Unlock()
+}
+
+// requestKeyframe sends a PLI for the rendered layer, throttled
+func (in *input) requestKeyframe() {
+	if in.receiver == nil || time.Since(in.lastPLIAt) < pliInterval {
+		return
+	}
+	in.lastPLIAt = time.Now()
+	in.receiver.SendPLI(max(in.layer, 0), true)
+}
+
+// assemble returns the frame completed by a packet
+func (in *input) assemble(pkt inputPacket) []byte {
+	if in.dropped.Swap(false) {
+		// decoder is behind, frames depend on each other so decode from the next keyframe
+		in.assembling = false
+		in.needsKeyframe = true
+	}
+
+	// render the lowest layer, tiles are small
+	if pkt.keyFrame && (in.layer < 0 || pkt.layer < in.layer) {
+		in.layer = pkt.layer
+		in.assembling = false
+	}
+	if pkt.layer != in.layer {
+		if in.layer < 0 {
+			in.requestKeyframe()
+		}
+		return nil
+	}
+
+	if in.depacketizer.IsPartitionHead(pkt.payload) && (!in.assembling || pkt.timestamp != in.timestamp) {
+		if in.nextSN != 0 && (in.assembling || pkt.sequenceNumber != in.nextSN) {
+			// end of the previous frame or a whole frame was lost
+			in.needsKeyframe = true
+		}
+		if in.needsKeyframe && !pkt.keyFrame {
+			in.assembling = false
+			in.requestKeyframe()
+			return nil
+		}
+		in.assembling = true
+		in.timestamp = pkt.timestamp
+		in.nextSN = pkt.sequenceNumber
+		in.frame = in.frame[:0]
+	}
+	if !in.assembling {
+		return nil
+	}
+	if pkt.timestamp != in.timestamp || pkt.sequenceNumber != in.nextSN {
+		// lost or reordered, wait for the next keyframe
+		in.assembling = false
+		in.needsKeyframe = true
+		in.requestKeyframe()
+		return nil
+	}
+	in.nextSN++
+
+	payload, err := in.depacketizer.Unmarshal(pkt.payload)
+	if err != nil {
+		in.assembling = false
+		return nil
+	}
+	in.frame = append(in.frame, payload...)
+
+	if !pkt.marker {
+		return nil
+	}
+	in.assembling = false
+	in.needsKeyframe = false
+	return in.frame
+}
+
+func (in *input) WriteRTP(p *buffer.ExtPacket, layer int32) error {
+	if in.closed.IsBroken() || len(p.Packet.Payload) == 0 {
+		return nil
+	}
+
+	select {
+	case in.packets <- inputPacket{
+		payload:        append([]byte(nil), p.Packet.Payload...),
+		layer:          layer,
+		keyFrame:       p.KeyFrame,
+		marker:         p.Packet.Marker,
+		sequenceNumber: p.ExtSequenceNumber,
+		timestamp:      p.ExtTimestamp,
+	}:
+	default:
+		in.dropped.Store(true)
+	}
+	return nil
+}
+
+func (in *input) UpTrackLayersChange() {}
+
+func (in *input) UpTrackBitrateAvailabilityChange() {}
+
+func (in *input) UpTrackMaxPublishedLayerChange(_maxPublishedLayer int32) {}
+
+func (in *input) UpTrackMaxTemporalLayerSeenChange(_maxTemporalLayerSeen int32) {}
+
+func (in *input) UpTrackSilencePause(_paused bool) {}
+
+func (in *input) UpTrackBitrateReport(_availableLayers []int32, _bitrates sfu.Bitrates) {}
+
+func (in *input) Close() {
+	in.closed.Break()
+}
+
+func (in *input) IsClosed() bool {
+	return in.closed.IsBroken()
+}
+
+func (in *input) ID() string {
+	return string(in.subscriberID) + "_" + string(in.trackID)
+}
+
+func (in *input) SubscriberID() livekit.ParticipantID {
+	return in.subscriberID
+}
+
+func (in *input) TrackInfoAvailable() {}
+
+func (in *input) HandleRTCPSenderReportData(
+	_payloadType webrtc.PayloadType,
+	_isSVC bool,
+	_layer int32,
+	_publisherSRData *buffer.RTCPSenderReportData,
+) error {
+	return nil
+}
+
+func (in *input) Resync() {}
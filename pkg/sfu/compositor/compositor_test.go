@@ -0,0 +1,244 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compositor
+
+import (
+	"errors"
+	"image"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/utils"
+)
+
+const (
+	mimeTypeRaw = "video/raw"
+	frameSize   = 16
+)
+
+// rawCodec frames are a single luma value, the first byte of packets is 1 at the start of a frame.
+// encoded frames are the luma plane of the canvas, prefixed with 1 for keyframes
+type rawCodec struct{}
+
+func (rawCodec) MimeType() string { return mimeTypeRaw }
+
+func (rawCodec) NewDepacketizer() rtp.Depacketizer { return rawCodec{} }
+
+func (rawCodec) NewPayloader() rtp.Payloader { return rawCodec{} }
+
+func (rawCodec) NewDecoder() (Decoder, error) { return rawCodec{}, nil }
+
+func (rawCodec) NewEncoder(_width int, _height int) (Encoder, error) { return rawCodec{}, nil }
+
+func (rawCodec) Unmarshal(packet []byte) ([]byte, error) { return packet[1:], nil }
+
+func (rawCodec) IsPartitionHead(payload []byte) bool { return payload[0] == 1 }
+
+func (rawCodec) IsPartitionTail(marker bool, _payload []byte) bool { return marker }
+
+func (rawCodec) Payload(_mtu uint16, payload []byte) [][]byte { return [][]byte{payload} }
+
+func (rawCodec) Decode(frame []byte) (*image.YCbCr, error) {
+	if len(frame) != 1 {
+		return nil, errors.New("invalid frame")
+	}
+	img := image.NewYCbCr(image.Rect(0, 0, frameSize, frameSize), image.YCbCrSubsampleRatio420)
+	fill(img, frame[0], 128, 128)
+	return img, nil
+}
+
+func (rawCodec) Encode(img *image.YCbCr, keyframe bool) ([]byte, error) {
+	flag := byte(0)
+	if keyframe {
+		flag = 1
+	}
+	return append([]byte{flag}, img.Y...), nil
+}
+
+func newTestCompositor(t *testing.T, layout Layout, trackIDs ...livekit.TrackID) (*Compositor, []*input) {
+	RegisterCodec(rawCodec{})
+	c, err := New(Params{
+		Codec:  webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: mimeTypeRaw}, PayloadType: 96},
+		Width:  2 * frameSize,
+		Height: frameSize,
+		Layout: layout,
+		Logger: logger.GetLogger(),
+	})
+	require.NoError(t, err)
+
+	// inputs are added without a receiver or worker, queued packets are handled by the tests
+	var inputs []*input
+	for _, trackID := range trackIDs {
+		in := newInput(trackID, rawCodec{}, rawCodec{}, logger.GetLogger())
+		c.inputs[trackID] = in
+		c.order = append(c.order, trackID)
+		inputs = append(inputs, in)
+	}
+	return c, inputs
+}
+
+// writeFrame writes a single packet frame and handles it
+func writeFrame(t *testing.T, in *input, sn uint64, ts uint64, keyframe bool, luma byte) {
+	require.NoError(t, in.WriteRTP(&buffer.ExtPacket{
+		ExtSequenceNumber: sn,
+		ExtTimestamp:      ts,
+		KeyFrame:          keyframe,
+		Packet: &rtp.Packet{
+			Header:  rtp.Header{Marker: true},
+			Payload: []byte{1, luma},
+		},
+	}, 0))
+	handleQueued(in)
+}
+
+func handleQueued(in *input) {
+	for len(in.packets) != 0 {
+		in.handlePacket(<-in.packets)
+	}
+}
+
+func TestLayout(t *testing.T) {
+	bounds := image.Rect(0, 0, 1280, 720)
+
+	t.Run("grid", func(t *testing.T) {
+		tiles := LayoutGrid.tiles(bounds, 3, -1)
+		require.Equal(t, []image.Rectangle{
+			image.Rect(0, 0, 640, 360),
+			image.Rect(640, 0, 1280, 360),
+			// last row is centered
+			image.Rect(320, 360, 960, 720),
+		}, tiles)
+	})
+
+	t.Run("speaker", func(t *testing.T) {
+		tiles := LayoutSpeaker.tiles(bounds, 3, 1)
+		require.Equal(t, []image.Rectangle{
+			image.Rect(0, 540, 640, 720),
+			image.Rect(0, 0, 1280, 540),
+			image.Rect(640, 540, 1280, 720),
+		}, tiles)
+
+		// single input fills the canvas
+		require.Equal(t, []image.Rectangle{bounds}, LayoutSpeaker.tiles(bounds, 1, 0))
+	})
+
+	t.Run("fit keeps aspect ratio", func(t *testing.T) {
+		require.Equal(t, image.Rect(280, 0, 1000, 720), fit(bounds, image.Rect(0, 0, 100, 100)))
+		require.Equal(t, image.Rect(0, 0, 640, 360), fit(image.Rect(0, 0, 640, 480), image.Rect(0, 0, 1920, 1080)).Sub(image.Pt(0, 60)))
+	})
+}
+
+func TestCompositor(t *testing.T) {
+	t.Run("requires registered codec", func(t *testing.T) {
+		_, err := New(Params{
+			Codec:  webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: "video/unknown"}},
+			Logger: logger.GetLogger(),
+		})
+		require.ErrorIs(t, err, ErrNoCodec)
+	})
+
+	t.Run("renders inputs in tiles", func(t *testing.T) {
+		c, inputs := newTestCompositor(t, LayoutGrid, "TR_a", "TR_b")
+		now := time.Now()
+		packets, err := c.render(now)
+		require.NoError(t, err)
+		require.Empty(t, packets, "nothing to render before a frame is decoded")
+
+		writeFrame(t, inputs[0], 10, 3000, true, 50)
+		writeFrame(t, inputs[1], 20, 3000, true, 200)
+
+		packets, err = c.render(now)
+		require.NoError(t, err)
+		require.Len(t, packets, 1)
+		require.True(t, packets[0].Marker)
+		require.Equal(t, uint8(96), packets[0].PayloadType)
+		encoded := packets[0].Payload
+		require.Equal(t, byte(1), encoded[0], "first frame is a keyframe")
+		row := encoded[1 : 1+2*frameSize]
+		require.Equal(t, byte(50), row[0])
+		require.Equal(t, byte(200), row[2*frameSize-1])
+		// contributing sources in layout order
+		require.Equal(t, []uint32{utils.TrackCSRC("TR_a"), utils.TrackCSRC("TR_b")}, packets[0].CSRC)
+
+		packets, err = c.render(now.Add(time.Second))
+		require.NoError(t, err)
+		require.Equal(t, byte(0), packets[0].Payload[0])
+
+		c.RequestKeyframe()
+		packets, err = c.render(now.Add(2 * time.Second))
+		require.NoError(t, err)
+		require.Equal(t, byte(1), packets[0].Payload[0])
+	})
+
+	t.Run("waits for keyframe after loss", func(t *testing.T) {
+		_, inputs := newTestCompositor(t, LayoutGrid, "TR_a")
+		in := inputs[0]
+
+		// delta frames are dropped until a keyframe is received
+		writeFrame(t, in, 1, 1000, false, 10)
+		require.Nil(t, in.latestFrame())
+
+		writeFrame(t, in, 2, 2000, true, 20)
+		require.Equal(t, byte(20), in.latestFrame().Y[0])
+		writeFrame(t, in, 3, 3000, false, 30)
+		require.Equal(t, byte(30), in.latestFrame().Y[0])
+
+		// sequence number gap
+		require.NoError(t, in.WriteRTP(&buffer.ExtPacket{
+			ExtSequenceNumber: 5,
+			ExtTimestamp:      4000,
+			Packet:            &rtp.Packet{Payload: []byte{0, 40}},
+		}, 0))
+		writeFrame(t, in, 6, 5000, false, 50)
+		require.Equal(t, byte(30), in.latestFrame().Y[0])
+	})
+
+	t.Run("waits for keyframe after dropping packets", func(t *testing.T) {
+		_, inputs := newTestCompositor(t, LayoutGrid, "TR_a")
+		in := inputs[0]
+		writeFrame(t, in, 1, 1000, true, 10)
+
+		// worker is behind, the queue is full
+		for i := range maxQueuedPackets + 1 {
+			require.NoError(t, in.WriteRTP(&buffer.ExtPacket{
+				ExtSequenceNumber: uint64(2 + i),
+				ExtTimestamp:      uint64(2000 + i),
+				Packet:            &rtp.Packet{Header: rtp.Header{Marker: true}, Payload: []byte{1, 20}},
+			}, 0))
+		}
+		require.True(t, in.dropped.Load())
+		handleQueued(in)
+		require.Equal(t, byte(10), in.latestFrame().Y[0])
+
+		writeFrame(t, in, uint64(2+maxQueuedPackets+1), 5000, true, 30)
+		require.Equal(t, byte(30), in.latestFrame().Y[0])
+	})
+
+	t.Run("removes closed inputs", func(t *testing.T) {
+		c, inputs := newTestCompositor(t, LayoutSpeaker, "TR_a", "TR_b")
+		inputs[0].Close()
+		require.False(t, c.compose())
+		require.Equal(t, 1, c.NumInputs())
+		require.Equal(t, []livekit.TrackID{"TR_b"}, c.order)
+	})
+}
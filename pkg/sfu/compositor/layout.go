@@ -0,0 +1,130 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compositor
+
+import (
+	"image"
+	"math"
+)
+
+type Layout string
+
+const (
+	// LayoutGrid tiles all inputs in equally sized cells
+	LayoutGrid Layout = "grid"
+	// LayoutSpeaker shows the focused input large, with other inputs in a strip below
+	LayoutSpeaker Layout = "speaker"
+)
+
+// tiles returns the area of each input on a canvas, focus is the index of the focused input or -1
+func (l Layout) tiles(bounds image.Rectangle, n int, focus int) []image.Rectangle {
+	if n == 0 {
+		return nil
+	}
+	if l == LayoutSpeaker && n > 1 {
+		if focus < 0 || focus >= n {
+			focus = 0
+		}
+		return speakerTiles(bounds, n, focus)
+	}
+	return gridTiles(bounds, n)
+}
+
+func gridTiles(bounds image.Rectangle, n int) []image.Rectangle {
+	cols := int(math.Ceil(math.Sqrt(float64(n))))
+	rows := (n + cols - 1) / cols
+	w, h := bounds.Dx()/cols, bounds.Dy()/rows
+
+	tiles := make([]image.Rectangle, n)
+	for i := range tiles {
+		row, col := i/cols, i%cols
+		// center the last row when it isn't full
+		offset := 0
+		if row == rows-1 {
+			offset = (cols*rows - n) * w / 2
+		}
+		origin := bounds.Min.Add(image.Pt(offset+col*w, row*h))
+		tiles[i] = image.Rectangle{Min: origin, Max: origin.Add(image.Pt(w, h))}
+	}
+	return tiles
+}
+
+func speakerTiles(bounds image.Rectangle, n int, focus int) []image.Rectangle {
+	stripHeight := bounds.Dy() / 4
+	tiles := make([]image.Rectangle, n)
+	tiles[focus] = image.Rect(bounds.Min.X, bounds.Min.Y, bounds.Max.X, bounds.Max.Y-stripHeight)
+
+	others := n - 1
+	w := bounds.Dx() / others
+	i := 0
+	for idx := range tiles {
+		if idx == focus {
+			continue
+		}
+		origin := image.Pt(bounds.Min.X+i*w, bounds.Max.Y-stripHeight)
+		tiles[idx] = image.Rectangle{Min: origin, Max: origin.Add(image.Pt(w, stripHeight))}
+		i++
+	}
+	return tiles
+}
+
+// fit returns the largest area of tile with the aspect ratio of src, centered
+func fit(tile image.Rectangle, src image.Rectangle) image.Rectangle {
+	if src.Dx() == 0 || src.Dy() == 0 {
+		return image.Rectangle{}
+	}
+	w, h := tile.Dx(), tile.Dx()*src.Dy()/src.Dx()
+	if h > tile.Dy() {
+		w, h = tile.Dy()*src.Dx()/src.Dy(), tile.Dy()
+	}
+	origin := tile.Min.Add(image.Pt((tile.Dx()-w)/2, (tile.Dy()-h)/2))
+	return image.Rectangle{Min: origin, Max: origin.Add(image.Pt(w, h))}
+}
+
+// draw scales src into dst nearest neighbor, dst must be 4:2:0
+func draw(dst *image.YCbCr, rect image.Rectangle, src *image.YCbCr) {
+	rect = rect.Intersect(dst.Rect)
+	if rect.Empty() {
+		return
+	}
+	sb := src.Rect
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		sy := sb.Min.Y + (y-rect.Min.Y)*sb.Dy()/rect.Dy()
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			sx := sb.Min.X + (x-rect.Min.X)*sb.Dx()/rect.Dx()
+			dst.Y[dst.YOffset(x, y)] = src.Y[src.YOffset(sx, sy)]
+		}
+	}
+	// chroma at half resolution
+	for y := rect.Min.Y &^ 1; y < rect.Max.Y; y += 2 {
+		sy := sb.Min.Y + max(y-rect.Min.Y, 0)*sb.Dy()/rect.Dy()
+		for x := rect.Min.X &^ 1; x < rect.Max.X; x += 2 {
+			sx := sb.Min.X + max(x-rect.Min.X, 0)*sb.Dx()/rect.Dx()
+			di, si := dst.COffset(x, y), src.COffset(sx, sy)
+			dst.Cb[di] = src.Cb[si]
+			dst.Cr[di] = src.Cr[si]
+		}
+	}
+}
+
+func fill(dst *image.YCbCr, y, cb, cr uint8) {
+	for i := range dst.Y {
+		dst.Y[i] = y
+	}
+	for i := range dst.Cb {
+		dst.Cb[i] = cb
+		dst.Cr[i] = cr
+	}
+}
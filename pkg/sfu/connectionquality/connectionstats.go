@@ -69,6 +69,7 @@ type ConnectionStats struct {
 	lock               sync.RWMutex
 	packetsSent        uint64
 	streamingStartedAt time.Time
+	latency            uint32
 
 	scorer *qualityScorer
 
@@ -203,6 +204,14 @@ func (cs *ConnectionStats) GetScoreAndQuality() (float32, livekit.ConnectionQual
 	return cs.scorer.GetMOSAndQuality()
 }
 
+// GetLatency returns the latest estimate of glass to glass latency, 0 if unknown
+func (cs *ConnectionStats) GetLatency() time.Duration {
+	cs.lock.RLock()
+	defer cs.lock.RUnlock()
+
+	return time.Duration(cs.latency) * time.Millisecond
+}
+
 func (cs *ConnectionStats) updateScoreWithAggregate(agg *buffer.RTPDeltaInfo, lastRTCPAt time.Time, at time.Time) float32 {
 	var stat windowStat
 	if agg != nil {
@@ -215,8 +224,15 @@ func (cs *ConnectionStats) updateScoreWithAggregate(agg *buffer.RTPDeltaInfo, la
 		stat.bytes = agg.Bytes - agg.HeaderBytes // only use media payload size
 		stat.rttMax = agg.RttMax
 		stat.jitterMax = agg.JitterMax
+		stat.latencyMax = agg.LatencyMax
 
 		stat.lastRTCPAt = lastRTCPAt
+
+		if agg.LatencyMax != 0 {
+			cs.lock.Lock()
+			cs.latency = agg.LatencyMax
+			cs.lock.Unlock()
+		}
 	}
 	if at.IsZero() {
 		cs.scorer.Update(&stat)
@@ -436,8 +452,8 @@ func toAnalyticsStream(ssrc uint32, deltaStats *buffer.RTPDeltaInfo) *livekit.An
 		Ssrc:              ssrc,
 		PrimaryPackets:    deltaStats.Packets,
 		PrimaryBytes:      deltaStats.Bytes,
-		RetransmitPackets: deltaStats.PacketsDuplicate,
-		RetransmitBytes:   deltaStats.BytesDuplicate,
+		RetransmitPackets: deltaStats.PacketsDuplicate + deltaStats.PacketsRetransmitted,
+		RetransmitBytes:   deltaStats.BytesDuplicate + deltaStats.BytesRetransmitted,
 		PaddingPackets:    deltaStats.PacketsPadding,
 		PaddingBytes:      deltaStats.BytesPadding,
 		PacketsLost:       packetsLost,
@@ -453,8 +469,8 @@ func toAnalyticsStream(ssrc uint32, deltaStats *buffer.RTPDeltaInfo) *livekit.An
 func toAnalyticsVideoLayer(layer int32, layerStats *buffer.RTPDeltaInfo) *livekit.AnalyticsVideoLayer {
 	avl := &livekit.AnalyticsVideoLayer{
 		Layer:   layer,
-		Packets: layerStats.Packets + layerStats.PacketsDuplicate + layerStats.PacketsPadding,
-		Bytes:   layerStats.Bytes + layerStats.BytesDuplicate + layerStats.BytesPadding,
+		Packets: layerStats.Packets + layerStats.PacketsDuplicate + layerStats.PacketsRetransmitted + layerStats.PacketsPadding,
+		Bytes:   layerStats.Bytes + layerStats.BytesDuplicate + layerStats.BytesRetransmitted + layerStats.BytesPadding,
 		Frames:  layerStats.Frames,
 	}
 	if avl.Packets == 0 || avl.Bytes == 0 || avl.Frames == 0 {
@@ -61,6 +61,7 @@ type windowStat struct {
 	bytes             uint64
 	rttMax            uint32
 	jitterMax         float64
+	latencyMax        uint32
 	lastRTCPAt        time.Time
 }
 
@@ -148,7 +149,7 @@ func (w *windowStat) calculateBitrateScore(expectedBits int64, isEnabled bool) f
 }
 
 func (w *windowStat) String() string {
-	return fmt.Sprintf("start: %+v, dur: %+v, pe: %d, pl: %d, pm: %d, pooo: %d, b: %d, rtt: %d, jitter: %0.2f, lastRTCP: %+v",
+	return fmt.Sprintf("start: %+v, dur: %+v, pe: %d, pl: %d, pm: %d, pooo: %d, b: %d, rtt: %d, jitter: %0.2f, latency: %d, lastRTCP: %+v",
 		w.startedAt,
 		w.duration,
 		w.packetsExpected,
@@ -158,6 +159,7 @@ func (w *windowStat) String() string {
 		w.bytes,
 		w.rttMax,
 		w.jitterMax,
+		w.latencyMax,
 		w.lastRTCPAt,
 	)
 }
@@ -176,6 +178,7 @@ func (w *windowStat) MarshalLogObject(e zapcore.ObjectEncoder) error {
 	e.AddUint64("bytes", w.bytes)
 	e.AddUint32("rttMax", w.rttMax)
 	e.AddFloat64("jitterMax", w.jitterMax)
+	e.AddUint32("latencyMax", w.latencyMax)
 	e.AddTime("lastRTCPAt", w.lastRTCPAt)
 	return nil
 }
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -35,8 +36,10 @@ import (
 	"github.com/livekit/protocol/logger"
 
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/clocksource"
 	"github.com/livekit/livekit-server/pkg/sfu/connectionquality"
 	"github.com/livekit/livekit-server/pkg/sfu/pacer"
+	"github.com/livekit/livekit-server/pkg/sfu/rtpextension"
 	act "github.com/livekit/livekit-server/pkg/sfu/rtpextension/abscapturetime"
 	dd "github.com/livekit/livekit-server/pkg/sfu/rtpextension/dependencydescriptor"
 	pd "github.com/livekit/livekit-server/pkg/sfu/rtpextension/playoutdelay"
@@ -50,6 +53,8 @@ type TrackSender interface {
 	UpTrackMaxPublishedLayerChange(maxPublishedLayer int32)
 	UpTrackMaxTemporalLayerSeenChange(maxTemporalLayerSeen int32)
 	UpTrackBitrateReport(availableLayers []int32, bitrates Bitrates)
+	// forwarding of an audio track paused or resumed due to prolonged silence
+	UpTrackSilencePause(paused bool)
 	WriteRTP(p *buffer.ExtPacket, layer int32) error
 	Close()
 	IsClosed() bool
@@ -202,19 +207,23 @@ type DownTrackStreamAllocatorListener interface {
 type ReceiverReportListener func(dt *DownTrack, report *rtcp.ReceiverReport)
 
 type DowntrackParams struct {
-	Codecs                         []webrtc.RTPCodecParameters
-	Source                         livekit.TrackSource
-	Receiver                       TrackReceiver
-	BufferFactory                  *buffer.Factory
-	SubID                          livekit.ParticipantID
-	StreamID                       string
-	MaxTrack                       int
-	PlayoutDelayLimit              *livekit.PlayoutDelay
-	Pacer                          pacer.Pacer
-	Logger                         logger.Logger
-	Trailer                        []byte
-	RTCPWriter                     func([]rtcp.Packet) error
-	DisableSenderReportPassThrough bool
+	Codecs                            []webrtc.RTPCodecParameters
+	Source                            livekit.TrackSource
+	Receiver                          TrackReceiver
+	BufferFactory                     *buffer.Factory
+	SubID                             livekit.ParticipantID
+	StreamID                          string
+	MaxTrack                          int
+	PlayoutDelayLimit                 *livekit.PlayoutDelay
+	Pacer                             pacer.Pacer
+	Logger                            logger.Logger
+	Trailer                           []byte
+	RTCPWriter                        func([]rtcp.Packet) error
+	DisableSenderReportPassThrough    bool
+	SenderReportSmoothingWindow       time.Duration
+	SenderReportClockSkewCompensation bool
+	SenderReportClock                 clocksource.ClockSource
+	HeaderExtensions                  *rtpextension.Registry
 }
 
 // DownTrack implements TrackLocal, is the track used to write packets
@@ -249,6 +258,7 @@ type DownTrack struct {
 	dependencyDescriptorExtID int
 	playoutDelayExtID         int
 	absCaptureTimeExtID       int
+	forwardedExtensions       []forwardedExtension
 	transceiver               atomic.Pointer[webrtc.RTPTransceiver]
 	writeStream               webrtc.TrackLocalWriter
 	rtcpReader                *buffer.RTCPReader
@@ -265,6 +275,7 @@ type DownTrack struct {
 	bindAndConnectedOnce atomic.Bool
 	writable             atomic.Bool
 	writeStopped         atomic.Bool
+	silencePaused        atomic.Bool
 
 	rtpStats *buffer.RTPStatsSender
 
@@ -345,8 +356,11 @@ func NewDownTrack(params DowntrackParams) (*DownTrack, error) {
 	)
 
 	d.rtpStats = buffer.NewRTPStatsSender(buffer.RTPStatsParams{
-		ClockRate: d.codec.ClockRate,
-		Logger:    d.params.Logger,
+		ClockRate:                         d.codec.ClockRate,
+		SenderReportSmoothingWindow:       d.params.SenderReportSmoothingWindow,
+		SenderReportClockSkewCompensation: d.params.SenderReportClockSkewCompensation,
+		SenderReportClock:                 d.params.SenderReportClock,
+		Logger:                            d.params.Logger,
 	})
 	d.deltaStatsSenderSnapshotId = d.rtpStats.NewSenderSnapshotId()
 
@@ -616,6 +630,77 @@ func (d *DownTrack) SetRTPHeaderExtensions(rtpHeaderExtensions []webrtc.RTPHeade
 			d.absCaptureTimeExtID = ext.ID
 		}
 	}
+	d.forwardedExtensions = getForwardedExtensions(
+		d.params.HeaderExtensions.Forwarded(d.kind),
+		d.params.Receiver.HeaderExtensions(),
+		rtpHeaderExtensions,
+	)
+}
+
+func getHeaderExtensionID(extensions []webrtc.RTPHeaderExtensionParameter, uri string) int {
+	for _, ext := range extensions {
+		if ext.URI == uri {
+			return ext.ID
+		}
+	}
+	return 0
+}
+
+// forwardedExtension is a header extension passed from the publisher, with the ids negotiated on both sides
+type forwardedExtension struct {
+	publisherID  uint8
+	subscriberID uint8
+	rewrite      rtpextension.RewriteFunc
+}
+
+func getForwardedExtensions(
+	forwarded []rtpextension.HeaderExtension,
+	publisherExtensions []webrtc.RTPHeaderExtensionParameter,
+	subscriberExtensions []webrtc.RTPHeaderExtensionParameter,
+) []forwardedExtension {
+	var extensions []forwardedExtension
+	for _, ext := range forwarded {
+		publisherID := getHeaderExtensionID(publisherExtensions, ext.URI)
+		subscriberID := getHeaderExtensionID(subscriberExtensions, ext.URI)
+		if publisherID == 0 || subscriberID == 0 {
+			continue
+		}
+
+		fe := forwardedExtension{
+			publisherID:  uint8(publisherID),
+			subscriberID: uint8(subscriberID),
+		}
+		if ext.Policy(rtpextension.DirectionSubscriber) == rtpextension.PolicyRewrite {
+			fe.rewrite = ext.Rewrite
+		}
+		extensions = append(extensions, fe)
+	}
+	return extensions
+}
+
+// appendForwardedExtensions appends the values of the forwarded extensions of a packet received from the publisher
+func (d *DownTrack) appendForwardedExtensions(extensions []pacer.ExtensionData, pkt *rtp.Packet) []pacer.ExtensionData {
+	for _, fe := range d.forwardedExtensions {
+		val := pkt.GetExtension(fe.publisherID)
+		if val == nil {
+			continue
+		}
+		if fe.rewrite != nil {
+			rewritten, err := fe.rewrite(val)
+			if err != nil || rewritten == nil {
+				continue
+			}
+			val = rewritten
+		}
+		extensions = append(
+			extensions,
+			pacer.ExtensionData{
+				ID:      fe.subscriberID,
+				Payload: val,
+			},
+		)
+	}
+	return extensions
 }
 
 // Kind controls if this TrackLocal is audio or video
@@ -749,6 +834,9 @@ func (d *DownTrack) WriteRTP(extPkt *buffer.ExtPacket, layer int32) error {
 		// do not start on an out-of-order packet
 		return nil
 	}
+	if d.silencePaused.Load() {
+		return nil
+	}
 
 	tp, err := d.forwarder.GetTranslationParams(extPkt, layer)
 	if tp.shouldDrop {
@@ -805,6 +893,7 @@ func (d *DownTrack) WriteRTP(extPkt *buffer.ExtPacket, layer int32) error {
 			// retransmitted sequence numbers. But, that is highly improbable, if not impossible.
 		}
 	}
+	extensions = d.appendForwardedExtensions(extensions, extPkt.Packet)
 	var actBytes []byte
 	if extPkt.AbsCaptureTimeExt != nil && d.absCaptureTimeExtID != 0 {
 		// normalize capture time to SFU clock.
@@ -855,6 +944,7 @@ func (d *DownTrack) WriteRTP(extPkt *buffer.ExtPacket, layer int32) error {
 			extSequenceNumber: tp.rtp.extSequenceNumber,
 			extTimestamp:      tp.rtp.extTimestamp,
 			isKeyFrame:        extPkt.KeyFrame,
+			captureDelay:      extPkt.CaptureDelay,
 			tp:                &tp,
 		},
 	)
@@ -1200,6 +1290,24 @@ func (d *DownTrack) UpTrackMaxTemporalLayerSeenChange(maxTemporalLayerSeen int32
 	}
 }
 
+// UpTrackSilencePause stops forwarding to the subscriber while the track is silent, other senders of the track, e.g.
+// relays and the mixer, keep receiving its packets
+func (d *DownTrack) UpTrackSilencePause(paused bool) {
+	if d.silencePaused.Swap(paused) == paused {
+		return
+	}
+	// not receiving packets while paused is expected, do not count it against connection quality
+	d.connectionStats.UpdatePause(paused)
+
+	// resync so that sequence numbers do not jump on resume, and flush the decoder
+	// with silence frames like on mute
+	d.blankFramesGeneration.Inc()
+	if paused {
+		d.forwarder.Resync()
+		d.writeBlankFrameRTP(RTPBlankFramesMuteSeconds, d.blankFramesGeneration.Load())
+	}
+}
+
 func (d *DownTrack) maybeAddTransition(bitrate int64, distance float64, pauseReason VideoPauseReason) {
 	if d.kind == webrtc.RTPCodecTypeAudio {
 		return
@@ -1302,6 +1410,12 @@ func (d *DownTrack) BandwidthRequested() int64 {
 	return d.forwarder.BandwidthRequested(brs)
 }
 
+// OptimalBandwidthNeeded returns the bandwidth needed to forward the desired layers, regardless of allocation
+func (d *DownTrack) OptimalBandwidthNeeded() int64 {
+	_, brs := d.params.Receiver.GetLayeredBitrate()
+	return d.forwarder.GetOptimalBandwidthNeeded(brs)
+}
+
 func (d *DownTrack) DistanceToDesired() float64 {
 	al, brs := d.params.Receiver.GetLayeredBitrate()
 	return d.forwarder.DistanceToDesired(al, brs)
@@ -1848,6 +1962,12 @@ func (d *DownTrack) retransmitPackets(nacks []uint16) {
 				},
 			)
 		}
+		// forwarded extensions are not cached in sequencer, they are read from the retransmitted packet.
+		// Values are copied as the packet buffer is reused
+		for _, ext := range d.appendForwardedExtensions(nil, &pkt) {
+			ext.Payload = slices.Clone(ext.Payload)
+			extensions = append(extensions, ext)
+		}
 
 		d.sendingPacket(
 			&pkt.Header,
@@ -1921,6 +2041,7 @@ func (d *DownTrack) DebugInfo() map[string]interface{} {
 		"LastPli": d.rtpStats.LastPli(),
 	}
 	stats["RTPMunger"] = d.forwarder.RTPMungerDebugInfo()
+	stats["RTCPReports"] = d.rtpStats.RTCPReports()
 
 	senderReport := d.CreateSenderReport()
 	if senderReport != nil {
@@ -1951,6 +2072,26 @@ func (d *DownTrack) GetConnectionScoreAndQuality() (float32, livekit.ConnectionQ
 	return d.connectionStats.GetScoreAndQuality()
 }
 
+// GetLatency returns the estimated glass to glass latency from publisher capture to subscriber playout, 0 if unknown
+func (d *DownTrack) GetLatency() time.Duration {
+	return d.connectionStats.GetLatency()
+}
+
+// GetMediaTime returns the time of media being forwarded as per the sender reports sent to the subscriber
+func (d *DownTrack) GetMediaTime(at time.Time) (time.Time, error) {
+	return d.rtpStats.GetMediaTime(at)
+}
+
+// SetSyncCorrection shifts the sender reports sent to the subscriber to delay playout of the track by correction,
+// to correct A/V sync drift against another track of the publisher
+func (d *DownTrack) SetSyncCorrection(correction time.Duration) {
+	d.rtpStats.SetSyncCorrection(correction)
+}
+
+func (d *DownTrack) GetSyncCorrection() time.Duration {
+	return d.rtpStats.GetSyncCorrection()
+}
+
 func (d *DownTrack) GetTrackStats() *livekit.RTPStats {
 	return d.rtpStats.ToProto()
 }
@@ -2125,6 +2266,7 @@ type sendPacketMetadata struct {
 	isRTX                bool
 	isPadding            bool
 	shouldDisableCounter bool
+	captureDelay         time.Duration
 	tp                   *TranslationParams
 }
 
@@ -2144,11 +2286,18 @@ func (d *DownTrack) sendingPacket(hdr *rtp.Header, payloadSize int, spmd *sendPa
 	}
 
 	// update RTPStats
-	if spmd.isPadding {
+	if spmd.isRTX {
+		d.rtpStats.UpdateRetransmission(hdrSize, payloadSize)
+	} else if spmd.isPadding {
 		d.rtpStats.Update(spmd.packetTime, spmd.extSequenceNumber, spmd.extTimestamp, hdr.Marker, hdrSize, 0, payloadSize)
 	} else {
 		d.rtpStats.Update(spmd.packetTime, spmd.extSequenceNumber, spmd.extTimestamp, hdr.Marker, hdrSize, payloadSize, 0)
 	}
+	if spmd.captureDelay > 0 {
+		// set only on packets sampled once per sender report of the publisher,
+		// include time spent in the SFU since arrival
+		d.rtpStats.UpdateCaptureDelay(spmd.captureDelay + time.Duration(time.Now().UnixNano()-spmd.packetTime))
+	}
 
 	if spmd.isKeyFrame {
 		d.isNACKThrottled.Store(false)
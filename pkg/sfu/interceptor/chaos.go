@@ -0,0 +1,94 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interceptor
+
+import (
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"go.uber.org/atomic"
+)
+
+// ChaosFactory creates interceptors which drop packets on demand to simulate faults for resilience testing.
+// All interceptors of a factory share its state, so a fault applies to the whole peer connection.
+type ChaosFactory struct {
+	dropRTCPUntil atomic.Int64
+	stallUntil    atomic.Int64
+}
+
+func NewChaosFactory() *ChaosFactory {
+	return &ChaosFactory{}
+}
+
+func (f *ChaosFactory) NewInterceptor(id string) (interceptor.Interceptor, error) {
+	return &Chaos{
+		factory: f,
+	}, nil
+}
+
+// DropRTCP drops RTCP packets in both directions for the given duration
+func (f *ChaosFactory) DropRTCP(duration time.Duration) {
+	f.dropRTCPUntil.Store(time.Now().Add(duration).UnixNano())
+}
+
+// Stall drops RTP packets sent and RTCP packets in both directions for the given duration
+func (f *ChaosFactory) Stall(duration time.Duration) {
+	f.stallUntil.Store(time.Now().Add(duration).UnixNano())
+}
+
+func (f *ChaosFactory) isStalled() bool {
+	return time.Now().UnixNano() < f.stallUntil.Load()
+}
+
+func (f *ChaosFactory) isDroppingRTCP() bool {
+	return f.isStalled() || time.Now().UnixNano() < f.dropRTCPUntil.Load()
+}
+
+type Chaos struct {
+	interceptor.NoOp
+
+	factory *ChaosFactory
+}
+
+func (c *Chaos) BindRTCPReader(reader interceptor.RTCPReader) interceptor.RTCPReader {
+	return interceptor.RTCPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		for {
+			n, attr, err := reader.Read(b, a)
+			if err != nil || !c.factory.isDroppingRTCP() {
+				return n, attr, err
+			}
+		}
+	})
+}
+
+func (c *Chaos) BindRTCPWriter(writer interceptor.RTCPWriter) interceptor.RTCPWriter {
+	return interceptor.RTCPWriterFunc(func(pkts []rtcp.Packet, attributes interceptor.Attributes) (int, error) {
+		if c.factory.isDroppingRTCP() {
+			return 0, nil
+		}
+		return writer.Write(pkts, attributes)
+	})
+}
+
+func (c *Chaos) BindLocalStream(_ *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+		if c.factory.isStalled() {
+			return header.MarshalSize() + len(payload), nil
+		}
+		return writer.Write(header, payload, attributes)
+	})
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interceptor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+type countingRTCPWriter struct {
+	count int
+}
+
+func (c *countingRTCPWriter) Write(pkts []rtcp.Packet, _ interceptor.Attributes) (int, error) {
+	c.count++
+	return 0, nil
+}
+
+func TestChaos(t *testing.T) {
+	f := NewChaosFactory()
+	i, err := f.NewInterceptor("")
+	require.NoError(t, err)
+
+	rw := &recordingWriter{}
+	w := i.BindLocalStream(&interceptor.StreamInfo{}, rw)
+	cw := &countingRTCPWriter{}
+	rtcpWriter := i.BindRTCPWriter(cw)
+
+	write := func(sn uint16) {
+		_, err := w.Write(&rtp.Header{SequenceNumber: sn}, nil, nil)
+		require.NoError(t, err)
+		_, err = rtcpWriter.Write([]rtcp.Packet{&rtcp.PictureLossIndication{}}, nil)
+		require.NoError(t, err)
+	}
+
+	write(0)
+	require.Equal(t, []uint16{0}, rw.sequenceNumbers())
+	require.Equal(t, 1, cw.count)
+
+	// RTCP only
+	f.DropRTCP(time.Minute)
+	write(1)
+	require.Equal(t, []uint16{0, 1}, rw.sequenceNumbers())
+	require.Equal(t, 1, cw.count)
+
+	// stalls drop media too, and expire
+	f.DropRTCP(0)
+	f.Stall(50 * time.Millisecond)
+	write(2)
+	require.Equal(t, []uint16{0, 1}, rw.sequenceNumbers())
+	require.Equal(t, 1, cw.count)
+
+	time.Sleep(60 * time.Millisecond)
+	write(3)
+	require.Equal(t, []uint16{0, 1, 3}, rw.sequenceNumbers())
+	require.Equal(t, 2, cw.count)
+}
@@ -0,0 +1,241 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interceptor
+
+import (
+	"container/heap"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	// packets that would wait longer than this behind the bandwidth cap are dropped, like a full bottleneck queue
+	impairmentMaxQueueDelay = 300 * time.Millisecond
+	// additional delay of reordered packets
+	impairmentReorderDelay = 20 * time.Millisecond
+)
+
+type NetworkImpairmentFactory struct {
+	impairment config.NetworkImpairment
+}
+
+// NewNetworkImpairmentFactory creates interceptors impairing RTP written to the peer connection,
+// interceptor should be added first to the registry so that it is closest to the network
+func NewNetworkImpairmentFactory(impairment config.NetworkImpairment) *NetworkImpairmentFactory {
+	return &NetworkImpairmentFactory{
+		impairment: impairment,
+	}
+}
+
+func (f *NetworkImpairmentFactory) NewInterceptor(id string) (interceptor.Interceptor, error) {
+	n := &NetworkImpairment{
+		impairment: f.impairment,
+		wake:       make(chan struct{}, 1),
+		closed:     make(chan struct{}),
+	}
+	if n.isDelaying() {
+		go n.worker()
+	}
+	return n, nil
+}
+
+type impairedPacket struct {
+	at         time.Time
+	seq        uint64
+	writer     interceptor.RTPWriter
+	header     rtp.Header
+	payload    []byte
+	attributes interceptor.Attributes
+}
+
+type impairedPacketQueue []*impairedPacket
+
+func (q impairedPacketQueue) Len() int { return len(q) }
+
+func (q impairedPacketQueue) Less(i, j int) bool {
+	if q[i].at.Equal(q[j].at) {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].at.Before(q[j].at)
+}
+
+func (q impairedPacketQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *impairedPacketQueue) Push(x any) { *q = append(*q, x.(*impairedPacket)) }
+
+func (q *impairedPacketQueue) Pop() any {
+	old := *q
+	n := len(old)
+	p := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return p
+}
+
+// NetworkImpairment drops and delays outgoing RTP packets. The bandwidth cap is shared by all streams
+// of the peer connection as they would share a bottleneck link.
+type NetworkImpairment struct {
+	interceptor.NoOp
+
+	impairment config.NetworkImpairment
+
+	lock             sync.Mutex
+	queue            impairedPacketQueue
+	seq              uint64
+	lastAt           time.Time
+	bottleneckFreeAt time.Time
+	isClosed         bool
+
+	wake   chan struct{}
+	closed chan struct{}
+}
+
+func (n *NetworkImpairment) BindLocalStream(_ *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+		size := header.MarshalSize() + len(payload)
+		at, ok := n.schedule(time.Now(), size)
+		if !ok {
+			// dropped packets are reported as sent, the loss happens on the network
+			return size, nil
+		}
+
+		if !n.isDelaying() {
+			return writer.Write(header, payload, attributes)
+		}
+
+		n.enqueue(&impairedPacket{
+			at:         at,
+			writer:     writer,
+			header:     header.Clone(),
+			payload:    append([]byte(nil), payload...),
+			attributes: attributes,
+		})
+		return size, nil
+	})
+}
+
+func (n *NetworkImpairment) Close() error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.isClosed {
+		return nil
+	}
+	n.isClosed = true
+	n.queue = nil
+	close(n.closed)
+	return nil
+}
+
+func (n *NetworkImpairment) isDelaying() bool {
+	return n.impairment.Jitter > 0 || n.impairment.Reorder > 0 || n.impairment.Bandwidth != 0
+}
+
+// schedule returns the time a packet of the given size sent at now leaves the impaired link, false if it is lost
+func (n *NetworkImpairment) schedule(now time.Time, size int) (time.Time, bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.impairment.PacketLoss > 0 && rand.Float64()*100 < n.impairment.PacketLoss {
+		return time.Time{}, false
+	}
+
+	at := now
+	if n.impairment.Bandwidth != 0 {
+		start := now
+		if n.bottleneckFreeAt.After(start) {
+			start = n.bottleneckFreeAt
+		}
+		if start.Sub(now) > impairmentMaxQueueDelay {
+			return time.Time{}, false
+		}
+		n.bottleneckFreeAt = start.Add(time.Duration(uint64(size*8) * uint64(time.Second) / n.impairment.Bandwidth))
+		at = n.bottleneckFreeAt
+	}
+
+	if n.impairment.Jitter > 0 {
+		at = at.Add(time.Duration(rand.Int63n(int64(n.impairment.Jitter))))
+	}
+
+	if n.impairment.Reorder > 0 && rand.Float64()*100 < n.impairment.Reorder {
+		// held back packet does not hold back the packets following it
+		return at.Add(impairmentReorderDelay), true
+	}
+
+	// jitter alone does not reorder packets
+	if n.lastAt.After(at) {
+		at = n.lastAt
+	}
+	n.lastAt = at
+	return at, true
+}
+
+func (n *NetworkImpairment) enqueue(p *impairedPacket) {
+	n.lock.Lock()
+	if n.isClosed {
+		n.lock.Unlock()
+		return
+	}
+	p.seq = n.seq
+	n.seq++
+	heap.Push(&n.queue, p)
+	isHead := n.queue[0] == p
+	n.lock.Unlock()
+
+	if isHead {
+		select {
+		case n.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (n *NetworkImpairment) worker() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		wait := time.Hour
+		var due []*impairedPacket
+		now := time.Now()
+		n.lock.Lock()
+		for n.queue.Len() != 0 {
+			if p := n.queue[0]; p.at.After(now) {
+				wait = p.at.Sub(now)
+				break
+			}
+			due = append(due, heap.Pop(&n.queue).(*impairedPacket))
+		}
+		n.lock.Unlock()
+
+		for _, p := range due {
+			_, _ = p.writer.Write(&p.header, p.payload, p.attributes)
+		}
+
+		timer.Reset(wait)
+		select {
+		case <-n.closed:
+			return
+		case <-n.wake:
+		case <-timer.C:
+		}
+	}
+}
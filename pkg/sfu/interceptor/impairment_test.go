@@ -0,0 +1,101 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interceptor
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+type recordingWriter struct {
+	lock    sync.Mutex
+	packets []uint16
+}
+
+func (r *recordingWriter) Write(header *rtp.Header, payload []byte, _ interceptor.Attributes) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.packets = append(r.packets, header.SequenceNumber)
+	return header.MarshalSize() + len(payload), nil
+}
+
+func (r *recordingWriter) sequenceNumbers() []uint16 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]uint16(nil), r.packets...)
+}
+
+func newImpairedWriter(t *testing.T, impairment config.NetworkImpairment) (interceptor.RTPWriter, *recordingWriter) {
+	i, err := NewNetworkImpairmentFactory(impairment).NewInterceptor("")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = i.Close() })
+
+	rw := &recordingWriter{}
+	return i.BindLocalStream(&interceptor.StreamInfo{}, rw), rw
+}
+
+func TestNetworkImpairment(t *testing.T) {
+	t.Run("loss", func(t *testing.T) {
+		w, rw := newImpairedWriter(t, config.NetworkImpairment{PacketLoss: 100})
+		for sn := uint16(0); sn < 10; sn++ {
+			_, err := w.Write(&rtp.Header{SequenceNumber: sn}, make([]byte, 100), nil)
+			require.NoError(t, err)
+		}
+		require.Empty(t, rw.sequenceNumbers())
+	})
+
+	t.Run("jitter keeps order", func(t *testing.T) {
+		w, rw := newImpairedWriter(t, config.NetworkImpairment{Jitter: 20 * time.Millisecond})
+		for sn := uint16(0); sn < 50; sn++ {
+			_, err := w.Write(&rtp.Header{SequenceNumber: sn}, make([]byte, 100), nil)
+			require.NoError(t, err)
+		}
+		require.Eventually(t, func() bool { return len(rw.sequenceNumbers()) == 50 }, time.Second, 10*time.Millisecond)
+		require.IsIncreasing(t, rw.sequenceNumbers())
+	})
+
+	t.Run("reorder", func(t *testing.T) {
+		n := &NetworkImpairment{impairment: config.NetworkImpairment{Reorder: 100}}
+		now := time.Now()
+		at, ok := n.schedule(now, 100)
+		require.True(t, ok)
+		require.Equal(t, now.Add(impairmentReorderDelay), at)
+
+		// held back packets are not used to order the packets following them
+		at, ok = n.schedule(now, 100)
+		require.True(t, ok)
+		require.Equal(t, now.Add(impairmentReorderDelay), at)
+		require.True(t, n.lastAt.IsZero())
+	})
+
+	t.Run("bandwidth", func(t *testing.T) {
+		// each packet takes ~81ms at 100 kbps, packets that would wait more than 300ms are dropped
+		w, rw := newImpairedWriter(t, config.NetworkImpairment{Bandwidth: 100_000})
+		for sn := uint16(0); sn < 10; sn++ {
+			_, err := w.Write(&rtp.Header{SequenceNumber: sn}, make([]byte, 1000), nil)
+			require.NoError(t, err)
+		}
+		require.Eventually(t, func() bool { return len(rw.sequenceNumbers()) == 4 }, time.Second, 10*time.Millisecond)
+		time.Sleep(100 * time.Millisecond)
+		require.Equal(t, []uint16{0, 1, 2, 3}, rw.sequenceNumbers())
+	})
+}
@@ -0,0 +1,173 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loudness
+
+import (
+	"math"
+)
+
+const (
+	SampleRate = 48000
+
+	// loudness reported for silence, and the absolute gate of integrated loudness
+	Floor = -70.0
+
+	// momentary loudness is measured over 400ms blocks, overlapping by 75%
+	stepSamples   = SampleRate / 10
+	stepsPerBlock = 4
+
+	relativeGate = -10.0
+
+	// gated blocks are kept in a histogram of 0.1 LU bins between the absolute gate and +10 LUFS
+	histogramStep = 0.1
+	histogramBins = 800
+)
+
+// K-weighting filters of ITU-R BS.1770 at 48kHz
+var (
+	preFilter = biquad{b0: 1.53512485958697, b1: -2.69169618940638, b2: 1.19839281085285, a1: -1.69065929318241, a2: 0.73248077421585}
+	rlbFilter = biquad{b0: 1, b1: -2, b2: 1, a1: -1.99004745483398, a2: 0.99007225036621}
+)
+
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	z1, z2             float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.z1
+	f.z1 = f.b1*x - f.a1*y + f.z2
+	f.z2 = f.b2*x - f.a2*y
+	return y
+}
+
+type histogramBin struct {
+	count  int
+	energy float64
+}
+
+// Meter measures momentary and integrated loudness of 48kHz mono PCM, as specified by EBU R128
+type Meter struct {
+	pre biquad
+	rlb biquad
+
+	stepEnergy float64
+	stepCount  int
+	steps      [stepsPerBlock]float64
+	numSteps   int
+	momentary  float64
+
+	histogram [histogramBins]histogramBin
+	peak      int32
+}
+
+func NewMeter() *Meter {
+	return &Meter{
+		pre: preFilter,
+		rlb: rlbFilter,
+	}
+}
+
+func (m *Meter) Write(pcm []int16) {
+	for _, s := range pcm {
+		if a := abs(int32(s)); a > m.peak {
+			m.peak = a
+		}
+
+		y := m.rlb.process(m.pre.process(float64(s) / 32768))
+		m.stepEnergy += y * y
+		if m.stepCount++; m.stepCount == stepSamples {
+			m.endStep()
+		}
+	}
+}
+
+func (m *Meter) endStep() {
+	m.steps[m.numSteps%stepsPerBlock] = m.stepEnergy / stepSamples
+	m.numSteps++
+	m.stepEnergy = 0
+	m.stepCount = 0
+	if m.numSteps < stepsPerBlock {
+		return
+	}
+
+	m.momentary = 0
+	for _, e := range m.steps {
+		m.momentary += e
+	}
+	m.momentary /= stepsPerBlock
+
+	l := toLoudness(m.momentary)
+	if l <= Floor {
+		return
+	}
+	bin := &m.histogram[min(int((l-Floor)/histogramStep), histogramBins-1)]
+	bin.count++
+	bin.energy += m.momentary
+}
+
+// Momentary returns the loudness of the last 400ms, in LUFS
+func (m *Meter) Momentary() float64 {
+	if m.numSteps < stepsPerBlock {
+		return Floor
+	}
+	return max(Floor, toLoudness(m.momentary))
+}
+
+// Integrated returns the gated loudness since the start of the measurement, in LUFS
+func (m *Meter) Integrated() float64 {
+	count, energy := m.sumFrom(0)
+	if count == 0 {
+		return Floor
+	}
+
+	gate := toLoudness(energy/float64(count)) + relativeGate
+	count, energy = m.sumFrom(max(0, int(math.Ceil((gate-Floor)/histogramStep))))
+	if count == 0 {
+		return Floor
+	}
+	return toLoudness(energy / float64(count))
+}
+
+// SamplePeak returns the highest sample level since the start of the measurement, in dBFS
+func (m *Meter) SamplePeak() float64 {
+	if m.peak == 0 {
+		return Floor
+	}
+	return max(Floor, 20*math.Log10(float64(m.peak)/32768))
+}
+
+func (m *Meter) sumFrom(first int) (int, float64) {
+	count, energy := 0, 0.0
+	for _, bin := range m.histogram[min(first, histogramBins):] {
+		count += bin.count
+		energy += bin.energy
+	}
+	return count, energy
+}
+
+func toLoudness(meanSquare float64) float64 {
+	if meanSquare <= 0 {
+		return math.Inf(-1)
+	}
+	return -0.691 + 10*math.Log10(meanSquare)
+}
+
+func abs(s int32) int32 {
+	if s < 0 {
+		return -s
+	}
+	return s
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loudness
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sine(frequency float64, dBFS float64, seconds float64) []int16 {
+	amplitude := math.Pow(10, dBFS/20) * 32767
+	pcm := make([]int16, int(seconds*SampleRate))
+	for i := range pcm {
+		pcm[i] = int16(amplitude * math.Sin(2*math.Pi*frequency*float64(i)/SampleRate))
+	}
+	return pcm
+}
+
+func TestMeter(t *testing.T) {
+	t.Run("silence", func(t *testing.T) {
+		m := NewMeter()
+		m.Write(make([]int16, SampleRate))
+		require.Equal(t, Floor, m.Momentary())
+		require.Equal(t, Floor, m.Integrated())
+		require.Equal(t, Floor, m.SamplePeak())
+	})
+
+	t.Run("reference tone", func(t *testing.T) {
+		// a 1kHz tone at -20 dBFS in one channel measures -23 LUFS
+		m := NewMeter()
+		m.Write(sine(1000, -20, 5))
+		require.InDelta(t, -23.0, m.Momentary(), 0.1)
+		require.InDelta(t, -23.0, m.Integrated(), 0.1)
+		require.InDelta(t, -20.0, m.SamplePeak(), 0.1)
+	})
+
+	t.Run("integrated loudness gates quiet blocks", func(t *testing.T) {
+		m := NewMeter()
+		m.Write(sine(1000, -20, 5))
+		// well below the relative gate
+		m.Write(sine(1000, -50, 5))
+		require.InDelta(t, -53.0, m.Momentary(), 0.1)
+		require.InDelta(t, -23.0, m.Integrated(), 0.2)
+	})
+
+	t.Run("momentary needs a full block", func(t *testing.T) {
+		m := NewMeter()
+		m.Write(sine(1000, -20, 0.3))
+		require.Equal(t, Floor, m.Momentary())
+	})
+}
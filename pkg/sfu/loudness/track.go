@@ -0,0 +1,190 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loudness
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/frostbyte73/core"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils/guid"
+
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/mixer"
+)
+
+const (
+	subscriberIDPrefix = "LM_"
+	// enough for 120ms Opus packets
+	maxDecodedSamples = 6 * SampleRate / 50
+	// packets queued while decoding, newer packets are dropped when the decoder is behind
+	maxQueuedPackets = 50
+)
+
+var ErrUnsupportedCodec = errors.New("loudness can only be measured for Opus tracks")
+
+// Loudness is a measurement of an audio track, in LUFS and dBFS
+type Loudness struct {
+	Momentary  float64 `json:"momentary"`
+	Integrated float64 `json:"integrated"`
+	SamplePeak float64 `json:"sample_peak"`
+}
+
+// Track measures the loudness of an audio track. Packets forwarded to it are decoded by its worker, with the Opus codec
+// registered with the mixer package.
+type Track struct {
+	trackID      livekit.TrackID
+	subscriberID livekit.ParticipantID
+	logger       logger.Logger
+	receiver     sfu.TrackReceiver
+	payloads     chan []byte
+	closed       core.Fuse
+
+	// only accessed by the worker
+	decoder mixer.Decoder
+	decoded []int16
+
+	lock  sync.Mutex
+	meter *Meter
+}
+
+func NewTrack(receiver sfu.TrackReceiver, logger logger.Logger) (*Track, error) {
+	if strings.EqualFold(receiver.Codec().MimeType, sfu.MimeTypeAudioRed) {
+		receiver = receiver.GetPrimaryReceiverForRed()
+	}
+	if !strings.EqualFold(receiver.Codec().MimeType, webrtc.MimeTypeOpus) {
+		return nil, ErrUnsupportedCodec
+	}
+
+	decoder, err := mixer.NewDecoder(webrtc.MimeTypeOpus)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Track{
+		trackID:      receiver.TrackID(),
+		subscriberID: livekit.ParticipantID(guid.New(subscriberIDPrefix)),
+		logger:       logger,
+		receiver:     receiver,
+		payloads:     make(chan []byte, maxQueuedPackets),
+		decoder:      decoder,
+		decoded:      make([]int16, maxDecodedSamples),
+		meter:        NewMeter(),
+	}
+	if err := receiver.AddDownTrack(t); err != nil {
+		return nil, err
+	}
+	go t.worker()
+	return t, nil
+}
+
+// Stop detaches the track from its receiver
+func (t *Track) Stop() {
+	t.receiver.DeleteDownTrack(t.subscriberID)
+	t.Close()
+}
+
+func (t *Track) Loudness() Loudness {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return Loudness{
+		Momentary:  t.meter.Momentary(),
+		Integrated: t.meter.Integrated(),
+		SamplePeak: t.meter.SamplePeak(),
+	}
+}
+
+func (t *Track) worker() {
+	for {
+		select {
+		case <-t.closed.Watch():
+			return
+		case payload := <-t.payloads:
+			t.measure(payload)
+		}
+	}
+}
+
+func (t *Track) measure(payload []byte) {
+	n, err := t.decoder.Decode(payload, t.decoded)
+	if err != nil {
+		t.logger.Debugw("could not decode audio for loudness", "error", err, "trackID", t.trackID)
+		return
+	}
+
+	t.lock.Lock()
+	t.meter.Write(t.decoded[:n])
+	t.lock.Unlock()
+}
+
+func (t *Track) WriteRTP(p *buffer.ExtPacket, _layer int32) error {
+	if t.closed.IsBroken() || len(p.Packet.Payload) == 0 {
+		return nil
+	}
+
+	select {
+	case t.payloads <- append([]byte(nil), p.Packet.Payload...):
+	default:
+	}
+	return nil
+}
+
+func (t *Track) UpTrackLayersChange() {}
+
+func (t *Track) UpTrackBitrateAvailabilityChange() {}
+
+func (t *Track) UpTrackMaxPublishedLayerChange(_maxPublishedLayer int32) {}
+
+func (t *Track) UpTrackMaxTemporalLayerSeenChange(_maxTemporalLayerSeen int32) {}
+
+func (t *Track) UpTrackSilencePause(_paused bool) {}
+
+func (t *Track) UpTrackBitrateReport(_availableLayers []int32, _bitrates sfu.Bitrates) {}
+
+func (t *Track) Close() {
+	t.closed.Break()
+}
+
+func (t *Track) IsClosed() bool {
+	return t.closed.IsBroken()
+}
+
+func (t *Track) ID() string {
+	return string(t.subscriberID) + "_" + string(t.trackID)
+}
+
+func (t *Track) SubscriberID() livekit.ParticipantID {
+	return t.subscriberID
+}
+
+func (t *Track) TrackInfoAvailable() {}
+
+func (t *Track) HandleRTCPSenderReportData(
+	_payloadType webrtc.PayloadType,
+	_isSVC bool,
+	_layer int32,
+	_publisherSRData *buffer.RTCPSenderReportData,
+) error {
+	return nil
+}
+
+func (t *Track) Resync() {}
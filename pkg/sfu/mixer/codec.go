@@ -0,0 +1,80 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mixer
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+var ErrNoCodec = errors.New("no codec registered for audio mixing")
+
+// Decoder decodes a single RTP payload into 48kHz mono PCM, returning the number of samples written
+type Decoder interface {
+	Decode(payload []byte, pcm []int16) (int, error)
+}
+
+// Encoder encodes one frame of 48kHz mono PCM into an RTP payload, returning the number of bytes written
+type Encoder interface {
+	Encode(pcm []int16, out []byte) (int, error)
+}
+
+// Codec creates decoders and encoders for a mime type.
+// No codec is built in, since Opus requires cgo bindings: servers mixing audio register one with RegisterCodec.
+type Codec interface {
+	MimeType() string
+	NewDecoder() (Decoder, error)
+	NewEncoder() (Encoder, error)
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = make(map[string]Codec)
+)
+
+// RegisterCodec makes a codec available for mixing, replacing any codec registered for the same mime type
+func RegisterCodec(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	codecs[strings.ToLower(c.MimeType())] = c
+}
+
+func getCodec(mimeType string) (Codec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	c, ok := codecs[strings.ToLower(mimeType)]
+	if !ok {
+		return nil, ErrNoCodec
+	}
+	return c, nil
+}
+
+// HasCodec returns whether a codec is registered for the mime type
+func HasCodec(mimeType string) bool {
+	_, err := getCodec(mimeType)
+	return err == nil
+}
+
+// NewDecoder creates a decoder with the codec registered for the mime type
+func NewDecoder(mimeType string) (Decoder, error) {
+	c, err := getCodec(mimeType)
+	if err != nil {
+		return nil, err
+	}
+	return c.NewDecoder()
+}
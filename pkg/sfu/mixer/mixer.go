@@ -0,0 +1,471 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mixer
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/frostbyte73/core"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils/guid"
+
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/audio"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/utils"
+)
+
+const (
+	SampleRate    = 48000
+	FrameDuration = 20 * time.Millisecond
+	FrameSamples  = SampleRate / 50
+
+	subscriberIDPrefix = "MX_"
+	// longest Opus packet is 120ms
+	maxDecodedSamples = 6 * FrameSamples
+	// packets buffered per input to absorb jitter, older packets are dropped
+	maxQueuedPackets = 5
+	// large enough for an uncompressed frame
+	maxPayloadSize = 2 * FrameSamples
+	// inputs quieter than this level, in -dBov, are mixed but not listed as contributing sources
+	audibleLevel = 50
+)
+
+// RMS of the quietest audible frame
+var audibleRMS = audio.ConvertAudioLevel(audibleLevel) * math.MaxInt16
+
+var (
+	ErrUnsupportedCodec = errors.New("track codec does not match mixer codec")
+	ErrOutputExists     = errors.New("mixer output already exists")
+)
+
+type Params struct {
+	Codec  webrtc.RTPCodecParameters
+	Logger logger.Logger
+}
+
+type OutputParams struct {
+	SSRC uint32
+	// audio is only mixed for the output while it returns true
+	IsActive func() bool
+	OnPacket func(pkt *rtp.Packet)
+}
+
+// Mixer decodes audio tracks and mixes them every frame into outputs, each encoded into its own RTP stream.
+// An output only mixes the inputs it is set to, none when it is added.
+// Inputs are attached to their receivers like down tracks, their packets are decoded by the mixer, not the receivers.
+type Mixer struct {
+	params Params
+	codec  Codec
+
+	lock    sync.Mutex
+	inputs  map[livekit.TrackID]*input
+	outputs map[string]*output
+	frames  map[livekit.TrackID][]int16
+	levels  map[livekit.TrackID]float64
+	mixed   []int32
+	pcm     []int16
+	payload []byte
+
+	stopped core.Fuse
+}
+
+func New(params Params) (*Mixer, error) {
+	codec, err := getCodec(params.Codec.MimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Mixer{
+		params:  params,
+		codec:   codec,
+		inputs:  make(map[livekit.TrackID]*input),
+		outputs: make(map[string]*output),
+		frames:  make(map[livekit.TrackID][]int16),
+		levels:  make(map[livekit.TrackID]float64),
+		mixed:   make([]int32, FrameSamples),
+		pcm:     make([]int16, FrameSamples),
+		payload: make([]byte, maxPayloadSize),
+	}, nil
+}
+
+func (m *Mixer) Start() {
+	go m.worker()
+}
+
+func (m *Mixer) Stop() {
+	m.stopped.Once(func() {
+		m.lock.Lock()
+		inputs := m.inputs
+		m.inputs = make(map[livekit.TrackID]*input)
+		m.outputs = make(map[string]*output)
+		m.lock.Unlock()
+
+		for _, in := range inputs {
+			in.detach()
+		}
+	})
+}
+
+// AddInput makes the audio of a track available to outputs until it is removed or its receiver is closed
+func (m *Mixer) AddInput(receiver sfu.TrackReceiver) error {
+	if m.stopped.IsBroken() {
+		return nil
+	}
+	if strings.EqualFold(receiver.Codec().MimeType, sfu.MimeTypeAudioRed) {
+		receiver = receiver.GetPrimaryReceiverForRed()
+	}
+	if !strings.EqualFold(receiver.Codec().MimeType, m.codec.MimeType()) {
+		return ErrUnsupportedCodec
+	}
+
+	decoder, err := m.codec.NewDecoder()
+	if err != nil {
+		return err
+	}
+
+	in := newInput(receiver.TrackID(), decoder, m.params.Logger)
+	m.lock.Lock()
+	existing := m.inputs[in.trackID]
+	m.inputs[in.trackID] = in
+	m.lock.Unlock()
+	if existing != nil {
+		existing.detach()
+	}
+
+	in.receiver = receiver
+	if err := receiver.AddDownTrack(in); err != nil {
+		m.RemoveInput(in.trackID)
+		return err
+	}
+	return nil
+}
+
+func (m *Mixer) RemoveInput(trackID livekit.TrackID) {
+	m.lock.Lock()
+	in := m.inputs[trackID]
+	delete(m.inputs, trackID)
+	m.lock.Unlock()
+
+	if in != nil {
+		in.detach()
+	}
+}
+
+func (m *Mixer) NumInputs() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return len(m.inputs)
+}
+
+// AddOutput adds an output mixing no input until SetOutputInputs is called
+func (m *Mixer) AddOutput(id string, params OutputParams) error {
+	encoder, err := m.codec.NewEncoder()
+	if err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.outputs[id]; ok {
+		return ErrOutputExists
+	}
+	m.outputs[id] = &output{
+		params:  params,
+		encoder: encoder,
+	}
+	return nil
+}
+
+func (m *Mixer) RemoveOutput(id string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.outputs, id)
+}
+
+// SetOutputInputs sets the tracks mixed into an output
+func (m *Mixer) SetOutputInputs(id string, trackIDs []livekit.TrackID) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	out := m.outputs[id]
+	if out == nil {
+		return
+	}
+	out.inputs = make(map[livekit.TrackID]bool, len(trackIDs))
+	for _, trackID := range trackIDs {
+		out.inputs[trackID] = true
+	}
+}
+
+func (m *Mixer) worker() {
+	ticker := time.NewTicker(FrameDuration)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stopped.Watch():
+			return
+		case <-ticker.C:
+			for _, mp := range m.mix() {
+				if mp.output.params.OnPacket != nil {
+					mp.output.params.OnPacket(mp.pkt)
+				}
+			}
+		}
+	}
+}
+
+type mixedPacket struct {
+	output *output
+	pkt    *rtp.Packet
+}
+
+// mix decodes the next frame of each input and mixes it for each active output, outputs without audio are skipped,
+// so silence is not sent
+func (m *Mixer) mix() []mixedPacket {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	clear(m.frames)
+	clear(m.levels)
+	for trackID, in := range m.inputs {
+		if in.IsClosed() {
+			// receiver closed, e.g. the track was unpublished
+			delete(m.inputs, trackID)
+			continue
+		}
+		if frame := in.nextFrame(); frame != nil {
+			m.frames[trackID] = frame
+			m.levels[trackID] = frameRMS(frame)
+		}
+	}
+
+	var packets []mixedPacket
+	for _, out := range m.outputs {
+		out.timestamp += FrameSamples
+		if out.params.IsActive != nil && !out.params.IsActive() {
+			out.sending = false
+			continue
+		}
+		if pkt := m.mixOutput(out); pkt != nil {
+			packets = append(packets, mixedPacket{output: out, pkt: pkt})
+		}
+	}
+	return packets
+}
+
+func (m *Mixer) mixOutput(out *output) *rtp.Packet {
+	clear(m.mixed)
+	mixed := false
+	var contributors []utils.Contributor
+	for trackID, frame := range m.frames {
+		if !out.inputs[trackID] {
+			continue
+		}
+		mixed = true
+		// contributing sources are the audible inputs, loudest first
+		if level := m.levels[trackID]; level >= audibleRMS {
+			contributors = append(contributors, utils.Contributor{CSRC: utils.TrackCSRC(trackID), Level: level})
+		}
+		for i, s := range frame {
+			m.mixed[i] += int32(s)
+		}
+	}
+	if !mixed {
+		out.sending = false
+		return nil
+	}
+
+	for i, s := range m.mixed {
+		m.pcm[i] = int16(min(max(s, math.MinInt16), math.MaxInt16))
+	}
+	n, err := out.encoder.Encode(m.pcm, m.payload)
+	if err != nil {
+		m.params.Logger.Warnw("could not encode mixed audio", err)
+		return nil
+	}
+
+	out.sequenceNumber++
+	pkt := &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         !out.sending,
+			PayloadType:    uint8(m.params.Codec.PayloadType),
+			SequenceNumber: out.sequenceNumber,
+			Timestamp:      out.timestamp,
+			SSRC:           out.params.SSRC,
+			CSRC:           utils.CSRCList(contributors),
+		},
+		Payload: append([]byte(nil), m.payload[:n]...),
+	}
+	out.sending = true
+	return pkt
+}
+
+func frameRMS(frame []int16) float64 {
+	var sum float64
+	for _, s := range frame {
+		sum += float64(s) * float64(s)
+	}
+	return math.Sqrt(sum / float64(len(frame)))
+}
+
+// output is a mix of some of the inputs, encoded into an RTP stream
+type output struct {
+	params  OutputParams
+	encoder Encoder
+	inputs  map[livekit.TrackID]bool
+
+	sequenceNumber uint16
+	timestamp      uint32
+	sending        bool
+}
+
+// input queues the packets of a track, they are decoded into frames by the mixer
+type input struct {
+	trackID      livekit.TrackID
+	subscriberID livekit.ParticipantID
+	decoder      Decoder
+	logger       logger.Logger
+	receiver     sfu.TrackReceiver
+	closed       atomic.Bool
+
+	lock     sync.Mutex
+	payloads [][]byte
+
+	// only accessed by the mixer
+	decoded []int16
+	frames  [][]int16
+}
+
+func newInput(trackID livekit.TrackID, decoder Decoder, logger logger.Logger) *input {
+	return &input{
+		trackID:      trackID,
+		subscriberID: livekit.ParticipantID(guid.New(subscriberIDPrefix)),
+		decoder:      decoder,
+		logger:       logger,
+		decoded:      make([]int16, maxDecodedSamples),
+	}
+}
+
+func (in *input) detach() {
+	if in.receiver != nil {
+		in.receiver.DeleteDownTrack(in.subscriberID)
+	}
+	in.Close()
+}
+
+func (in *input) nextPayload() []byte {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+
+	if len(in.payloads) == 0 {
+		return nil
+	}
+	payload := in.payloads[0]
+	in.payloads = in.payloads[1:]
+	return payload
+}
+
+// nextFrame decodes queued packets until a frame is available
+func (in *input) nextFrame() []int16 {
+	for len(in.frames) == 0 {
+		payload := in.nextPayload()
+		if payload == nil {
+			return nil
+		}
+		n, err := in.decoder.Decode(payload, in.decoded)
+		if err != nil {
+			in.logger.Debugw("could not decode audio for mixing", "error", err, "trackID", in.trackID)
+			continue
+		}
+		for i := 0; i+FrameSamples <= n; i += FrameSamples {
+			in.frames = append(in.frames, append([]int16(nil), in.decoded[i:i+FrameSamples]...))
+		}
+	}
+
+	frame := in.frames[0]
+	in.frames = in.frames[1:]
+	return frame
+}
+
+func (in *input) WriteRTP(p *buffer.ExtPacket, _layer int32) error {
+	if in.closed.Load() || len(p.Packet.Payload) == 0 {
+		return nil
+	}
+
+	payload := append([]byte(nil), p.Packet.Payload...)
+	in.lock.Lock()
+	in.payloads = append(in.payloads, payload)
+	if len(in.payloads) > maxQueuedPackets {
+		in.payloads = in.payloads[len(in.payloads)-maxQueuedPackets:]
+	}
+	in.lock.Unlock()
+	return nil
+}
+
+func (in *input) UpTrackLayersChange() {}
+
+func (in *input) UpTrackBitrateAvailabilityChange() {}
+
+func (in *input) UpTrackMaxPublishedLayerChange(_maxPublishedLayer int32) {}
+
+func (in *input) UpTrackMaxTemporalLayerSeenChange(_maxTemporalLayerSeen int32) {}
+
+func (in *input) UpTrackSilencePause(_paused bool) {}
+
+func (in *input) UpTrackBitrateReport(_availableLayers []int32, _bitrates sfu.Bitrates) {}
+
+func (in *input) Close() {
+	in.closed.Store(true)
+}
+
+func (in *input) IsClosed() bool {
+	return in.closed.Load()
+}
+
+func (in *input) ID() string {
+	return string(in.subscriberID) + "_" + string(in.trackID)
+}
+
+func (in *input) SubscriberID() livekit.ParticipantID {
+	return in.subscriberID
+}
+
+func (in *input) TrackInfoAvailable() {}
+
+func (in *input) HandleRTCPSenderReportData(
+	_payloadType webrtc.PayloadType,
+	_isSVC bool,
+	_layer int32,
+	_publisherSRData *buffer.RTCPSenderReportData,
+) error {
+	return nil
+}
+
+func (in *input) Resync() {}
@@ -0,0 +1,241 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mixer
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/utils"
+)
+
+const mimeTypePCM = "audio/L16"
+
+// pcmCodec carries big endian samples as is
+type pcmCodec struct{}
+
+func (pcmCodec) MimeType() string { return mimeTypePCM }
+
+func (pcmCodec) NewDecoder() (Decoder, error) { return pcmCodec{}, nil }
+
+func (pcmCodec) NewEncoder() (Encoder, error) { return pcmCodec{}, nil }
+
+func (pcmCodec) Decode(payload []byte, pcm []int16) (int, error) {
+	n := min(len(payload)/2, len(pcm))
+	for i := 0; i < n; i++ {
+		pcm[i] = int16(binary.BigEndian.Uint16(payload[2*i:]))
+	}
+	return n, nil
+}
+
+func (pcmCodec) Encode(pcm []int16, out []byte) (int, error) {
+	for i, s := range pcm {
+		binary.BigEndian.PutUint16(out[2*i:], uint16(s))
+	}
+	return 2 * len(pcm), nil
+}
+
+func pcmPacket(value int16, frames int) *buffer.ExtPacket {
+	payload := make([]byte, 2*frames*FrameSamples)
+	for i := 0; i < len(payload); i += 2 {
+		binary.BigEndian.PutUint16(payload[i:], uint16(value))
+	}
+	return &buffer.ExtPacket{Packet: &rtp.Packet{Payload: payload}}
+}
+
+const testOutput = "out"
+
+// newTestMixer returns a mixer with an output mixing all inputs
+func newTestMixer(t *testing.T, trackIDs ...string) (*Mixer, []*input) {
+	RegisterCodec(pcmCodec{})
+	m, err := New(Params{
+		Codec:  webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: mimeTypePCM}, PayloadType: 96},
+		Logger: logger.GetLogger(),
+	})
+	require.NoError(t, err)
+	require.NoError(t, m.AddOutput(testOutput, OutputParams{SSRC: 1234}))
+
+	// inputs are added without a receiver, packets are written directly
+	var inputs []*input
+	var ids []livekit.TrackID
+	for _, trackID := range trackIDs {
+		in := newInput(livekit.TrackID(trackID), pcmCodec{}, logger.GetLogger())
+		m.inputs[in.trackID] = in
+		inputs = append(inputs, in)
+		ids = append(ids, in.trackID)
+	}
+	m.SetOutputInputs(testOutput, ids)
+	return m, inputs
+}
+
+// mixOutput mixes the next frame, returns the packet of an output
+func mixOutput(m *Mixer, id string) *rtp.Packet {
+	for _, mp := range m.mix() {
+		if mp.output == m.outputs[id] {
+			return mp.pkt
+		}
+	}
+	return nil
+}
+
+func TestMixer(t *testing.T) {
+	t.Run("requires registered codec", func(t *testing.T) {
+		_, err := New(Params{
+			Codec:  webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: "audio/unknown"}},
+			Logger: logger.GetLogger(),
+		})
+		require.ErrorIs(t, err, ErrNoCodec)
+		require.False(t, HasCodec("audio/unknown"))
+	})
+
+	t.Run("sums inputs", func(t *testing.T) {
+		m, inputs := newTestMixer(t, "TR_a", "TR_b")
+		require.NoError(t, inputs[0].WriteRTP(pcmPacket(1000, 1), 0))
+		require.NoError(t, inputs[1].WriteRTP(pcmPacket(-300, 1), 0))
+
+		pkt := mixOutput(m, testOutput)
+		require.NotNil(t, pkt)
+		require.True(t, pkt.Marker)
+		require.Equal(t, uint8(96), pkt.PayloadType)
+		require.Equal(t, uint32(1234), pkt.SSRC)
+		require.Len(t, pkt.Payload, 2*FrameSamples)
+		require.Equal(t, int16(700), int16(binary.BigEndian.Uint16(pkt.Payload)))
+		// loudest first
+		require.Equal(t, []uint32{utils.TrackCSRC("TR_a"), utils.TrackCSRC("TR_b")}, pkt.CSRC)
+
+		// only one input has audio
+		require.NoError(t, inputs[0].WriteRTP(pcmPacket(500, 1), 0))
+		next := mixOutput(m, testOutput)
+		require.NotNil(t, next)
+		require.False(t, next.Marker)
+		require.Equal(t, pkt.SequenceNumber+1, next.SequenceNumber)
+		require.Equal(t, pkt.Timestamp+FrameSamples, next.Timestamp)
+		require.Equal(t, int16(500), int16(binary.BigEndian.Uint16(next.Payload)))
+		require.Equal(t, []uint32{utils.TrackCSRC("TR_a")}, next.CSRC)
+	})
+
+	t.Run("lists audible inputs", func(t *testing.T) {
+		m, inputs := newTestMixer(t, "TR_a", "TR_b", "TR_c")
+		require.NoError(t, inputs[0].WriteRTP(pcmPacket(200, 1), 0))
+		require.NoError(t, inputs[1].WriteRTP(pcmPacket(-4000, 1), 0))
+		// below the audible level, mixed but not listed
+		require.NoError(t, inputs[2].WriteRTP(pcmPacket(20, 1), 0))
+
+		pkt := mixOutput(m, testOutput)
+		require.NotNil(t, pkt)
+		require.Equal(t, int16(-3780), int16(binary.BigEndian.Uint16(pkt.Payload)))
+		require.Equal(t, []uint32{utils.TrackCSRC("TR_b"), utils.TrackCSRC("TR_a")}, pkt.CSRC)
+
+		// silence is still sent, without contributing sources
+		require.NoError(t, inputs[2].WriteRTP(pcmPacket(20, 1), 0))
+		pkt = mixOutput(m, testOutput)
+		require.NotNil(t, pkt)
+		require.Empty(t, pkt.CSRC)
+	})
+
+	t.Run("outputs mix their inputs", func(t *testing.T) {
+		m, inputs := newTestMixer(t, "TR_a", "TR_b")
+		require.NoError(t, m.AddOutput("a", OutputParams{SSRC: 1}))
+		require.ErrorIs(t, m.AddOutput("a", OutputParams{}), ErrOutputExists)
+		require.NoError(t, m.AddOutput("none", OutputParams{SSRC: 2}))
+		require.NoError(t, m.AddOutput("inactive", OutputParams{SSRC: 3, IsActive: func() bool { return false }}))
+		m.SetOutputInputs("a", []livekit.TrackID{"TR_a"})
+		m.SetOutputInputs("inactive", []livekit.TrackID{"TR_a", "TR_b"})
+
+		require.NoError(t, inputs[0].WriteRTP(pcmPacket(1000, 1), 0))
+		require.NoError(t, inputs[1].WriteRTP(pcmPacket(-300, 1), 0))
+		packets := make(map[uint32]*rtp.Packet)
+		for _, mp := range m.mix() {
+			packets[mp.pkt.SSRC] = mp.pkt
+		}
+		require.Len(t, packets, 2)
+		require.Equal(t, int16(700), int16(binary.BigEndian.Uint16(packets[1234].Payload)))
+		require.Equal(t, int16(1000), int16(binary.BigEndian.Uint16(packets[1].Payload)))
+		require.Equal(t, []uint32{utils.TrackCSRC("TR_a")}, packets[1].CSRC)
+
+		m.RemoveOutput("a")
+		require.NoError(t, inputs[0].WriteRTP(pcmPacket(1000, 1), 0))
+		packets = make(map[uint32]*rtp.Packet)
+		for _, mp := range m.mix() {
+			packets[mp.pkt.SSRC] = mp.pkt
+		}
+		require.Len(t, packets, 1)
+		require.NotNil(t, packets[1234])
+	})
+
+	t.Run("clips", func(t *testing.T) {
+		m, inputs := newTestMixer(t, "TR_a", "TR_b")
+		require.NoError(t, inputs[0].WriteRTP(pcmPacket(30000, 1), 0))
+		require.NoError(t, inputs[1].WriteRTP(pcmPacket(30000, 1), 0))
+
+		pkt := mixOutput(m, testOutput)
+		require.NotNil(t, pkt)
+		require.Equal(t, int16(math.MaxInt16), int16(binary.BigEndian.Uint16(pkt.Payload)))
+	})
+
+	t.Run("skips silence", func(t *testing.T) {
+		m, inputs := newTestMixer(t, "TR_a")
+		require.NoError(t, inputs[0].WriteRTP(pcmPacket(100, 1), 0))
+		first := mixOutput(m, testOutput)
+		require.NotNil(t, first)
+
+		require.Nil(t, mixOutput(m, testOutput))
+
+		// timestamp advances over the gap and the marker is set when resuming
+		require.NoError(t, inputs[0].WriteRTP(pcmPacket(100, 1), 0))
+		pkt := mixOutput(m, testOutput)
+		require.NotNil(t, pkt)
+		require.True(t, pkt.Marker)
+		require.Equal(t, first.SequenceNumber+1, pkt.SequenceNumber)
+		require.Equal(t, first.Timestamp+2*FrameSamples, pkt.Timestamp)
+	})
+
+	t.Run("splits long packets and bounds queue", func(t *testing.T) {
+		_, inputs := newTestMixer(t, "TR_a")
+		in := inputs[0]
+		require.NoError(t, in.WriteRTP(pcmPacket(1, 3), 0))
+		for range 3 {
+			require.Equal(t, int16(1), in.nextFrame()[0])
+		}
+		require.Nil(t, in.nextFrame())
+
+		for i := range maxQueuedPackets + 2 {
+			require.NoError(t, in.WriteRTP(pcmPacket(int16(i), 1), 0))
+		}
+		require.Len(t, in.payloads, maxQueuedPackets)
+		require.Equal(t, int16(2), in.nextFrame()[0])
+	})
+
+	t.Run("removes closed inputs", func(t *testing.T) {
+		m, inputs := newTestMixer(t, "TR_a", "TR_b")
+		inputs[1].Close()
+		require.NoError(t, inputs[0].WriteRTP(pcmPacket(1, 1), 0))
+		require.NotNil(t, mixOutput(m, testOutput))
+		require.Equal(t, 1, m.NumInputs())
+
+		m.RemoveInput("TR_a")
+		require.Equal(t, 0, m.NumInputs())
+		require.True(t, inputs[0].IsClosed())
+	})
+}
@@ -19,20 +19,23 @@ import (
 	"io"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/livekit/protocol/logger"
 	"github.com/pion/rtp"
 )
 
 type Base struct {
 	logger logger.Logger
+	clock  clock.Clock
 
 	packetTime *PacketTime
 }
 
-func NewBase(logger logger.Logger) *Base {
+func NewBase(logger logger.Logger, clock clock.Clock) *Base {
 	return &Base{
 		logger:     logger,
-		packetTime: NewPacketTime(),
+		clock:      clock,
+		packetTime: NewPacketTime(clock),
 	}
 }
 
@@ -18,6 +18,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/gammazero/deque"
 	"github.com/livekit/protocol/logger"
 )
@@ -38,9 +39,9 @@ type LeakyBucket struct {
 	isStopped bool
 }
 
-func NewLeakyBucket(logger logger.Logger, interval time.Duration, bitrate int) *LeakyBucket {
+func NewLeakyBucket(logger logger.Logger, clock clock.Clock, interval time.Duration, bitrate int) *LeakyBucket {
 	l := &LeakyBucket{
-		Base:     NewBase(logger),
+		Base:     NewBase(logger, clock),
 		logger:   logger,
 		interval: interval,
 		bitrate:  bitrate,
@@ -91,7 +92,7 @@ func (l *LeakyBucket) sendWorker() {
 	bitrate := l.bitrate
 	l.lock.RUnlock()
 
-	timer := time.NewTimer(interval)
+	timer := l.clock.Timer(interval)
 	overage := 0
 
 	for {
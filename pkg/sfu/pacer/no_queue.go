@@ -17,6 +17,7 @@ package pacer
 import (
 	"sync"
 
+	"github.com/benbjohnson/clock"
 	"github.com/gammazero/deque"
 	"github.com/livekit/protocol/logger"
 )
@@ -32,9 +33,9 @@ type NoQueue struct {
 	isStopped bool
 }
 
-func NewNoQueue(logger logger.Logger) *NoQueue {
+func NewNoQueue(logger logger.Logger, clock clock.Clock) *NoQueue {
 	n := &NoQueue{
-		Base:   NewBase(logger),
+		Base:   NewBase(logger, clock),
 		logger: logger,
 		wake:   make(chan struct{}, 1),
 	}
@@ -16,21 +16,25 @@ package pacer
 
 import (
 	"time"
+
+	"github.com/benbjohnson/clock"
 )
 
 type PacketTime struct {
+	clock    clock.Clock
 	baseTime time.Time
 }
 
-func NewPacketTime() *PacketTime {
+func NewPacketTime(clock clock.Clock) *PacketTime {
 	return &PacketTime{
-		baseTime: time.Now(),
+		clock:    clock,
+		baseTime: clock.Now(),
 	}
 }
 
 func (p *PacketTime) Get() time.Time {
 	// construct current time based on monotonic clock
-	return p.baseTime.Add(time.Since(p.baseTime))
+	return p.baseTime.Add(p.clock.Since(p.baseTime))
 }
 
 // ------------------------------------------------
@@ -0,0 +1,306 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sfu
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/pacer"
+)
+
+// The pipeline benchmarks push a synthetic three layer VP8 simulcast stream through
+// buffer -> forwarder -> downtrack in-process, one frame on every layer per op.
+// Besides ns/op and allocations, they report:
+//   - pkts/s: incoming packets processed per second, all layers are fanned out to every downtrack as the receiver does
+//   - buffer-ns/pkt: time to write a packet into and read it from the buffer
+//   - forwarder-ns/pkt: time a standalone forwarder takes to translate a packet
+//   - downtrack-ns/pkt: time one downtrack takes to forward a packet, including its forwarder and the pacer write
+//
+// Run with: go test ./pkg/sfu -run ^$ -bench Pipeline -benchmem
+
+const (
+	benchSSRCBase        = 1000
+	benchPayloadSize     = 1000
+	benchKeyFrameEvery   = 60
+	benchTimestampStride = 3000 // 30 fps at 90 kHz
+)
+
+// packets per frame for each spatial layer
+var benchPacketsPerFrame = []int{1, 2, 4}
+
+func BenchmarkPipeline(b *testing.B) {
+	for _, downTracks := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("%d-Downtracks", downTracks), func(b *testing.B) {
+			benchmarkPipeline(b, downTracks)
+		})
+	}
+}
+
+func benchmarkPipeline(b *testing.B, numDownTracks int) {
+	p := newBenchPipeline(b, numDownTracks)
+
+	// the first frame only has a key frame on the top layer for downtracks to start on it,
+	// switching layers later would need sender reports to align timestamps
+	p.pushFrame(nil)
+	for _, dt := range p.downTracks {
+		require.Equal(b, int32(2), dt.forwarder.CurrentLayer().Spatial)
+	}
+
+	var stats benchStageStats
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.pushFrame(&stats)
+	}
+	b.StopTimer()
+
+	b.ReportMetric(float64(stats.packets)/b.Elapsed().Seconds(), "pkts/s")
+	b.ReportMetric(float64(stats.buffer.Nanoseconds())/float64(stats.packets), "buffer-ns/pkt")
+	b.ReportMetric(float64(stats.forwarder.Nanoseconds())/float64(stats.packets), "forwarder-ns/pkt")
+	b.ReportMetric(float64(stats.downTrack.Nanoseconds())/float64(stats.packets*numDownTracks), "downtrack-ns/pkt")
+	// only the top layer is forwarded
+	require.Equal(b, (b.N+1)*benchPacketsPerFrame[2]*numDownTracks, p.writer.packets)
+}
+
+type benchStageStats struct {
+	packets   int
+	buffer    time.Duration
+	forwarder time.Duration
+	downTrack time.Duration
+}
+
+type benchPipeline struct {
+	b          *testing.B
+	buffers    []*buffer.Buffer
+	readBufs   [][]byte
+	forwarder  *Forwarder
+	downTracks []*DownTrack
+	writer     *benchWriteStream
+
+	frame     int
+	sns       []uint16
+	timestamp uint32
+	payload   []byte
+	raw       []byte
+}
+
+func newBenchPipeline(b *testing.B, numDownTracks int) *benchPipeline {
+	codec := webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{
+			MimeType:     webrtc.MimeTypeVP8,
+			ClockRate:    90000,
+			RTCPFeedback: []webrtc.RTCPFeedback{{Type: "nack"}},
+		},
+		PayloadType: 96,
+	}
+	p := &benchPipeline{
+		b:       b,
+		writer:  &benchWriteStream{},
+		sns:     make([]uint16, len(benchPacketsPerFrame)),
+		payload: make([]byte, benchPayloadSize),
+		raw:     make([]byte, 1500),
+	}
+
+	for layer := range benchPacketsPerFrame {
+		buff := buffer.NewBuffer(uint32(benchSSRCBase+layer), 500, 200)
+		buff.Bind(webrtc.RTPParameters{Codecs: []webrtc.RTPCodecParameters{codec}}, codec.RTPCodecCapability, 0)
+		b.Cleanup(func() { _ = buff.Close() })
+		p.buffers = append(p.buffers, buff)
+		p.readBufs = append(p.readBufs, make([]byte, 1500))
+	}
+
+	p.forwarder = NewForwarder(webrtc.RTPCodecTypeVideo, logger.GetLogger(), true, nil)
+	p.forwarder.DetermineCodec(codec.RTPCodecCapability, nil)
+	receiver := &benchReceiver{codec: codec}
+	setupBenchLayers(p.forwarder)
+	al, brs := receiver.GetLayeredBitrate()
+	p.forwarder.AllocateOptimal(al, brs, true)
+
+	bf := buffer.NewFactoryOfBufferFactory(500, 200).CreateBufferFactory()
+	pc := pacer.NewPassThrough(logger.GetLogger(), clock.New())
+	for i := 0; i < numDownTracks; i++ {
+		dt, err := NewDownTrack(DowntrackParams{
+			Codecs:        []webrtc.RTPCodecParameters{codec},
+			Source:        livekit.TrackSource_CAMERA,
+			Receiver:      receiver,
+			BufferFactory: bf,
+			SubID:         livekit.ParticipantID(fmt.Sprintf("PA_bench%d", i)),
+			StreamID:      "bench",
+			MaxTrack:      500,
+			Pacer:         pc,
+			Logger:        logger.GetLogger(),
+		})
+		require.NoError(b, err)
+		_, err = dt.Bind(&benchTrackLocalContext{
+			codec:  codec,
+			ssrc:   webrtc.SSRC(2000 + i),
+			writer: p.writer,
+		})
+		require.NoError(b, err)
+		dt.SetConnected()
+		setupBenchLayers(dt.forwarder)
+		dt.AllocateOptimal(true)
+		b.Cleanup(func() { dt.CloseWithFlush(false) })
+		p.downTracks = append(p.downTracks, dt)
+	}
+	return p
+}
+
+// setupBenchLayers subscribes to the top spatial layer, the stream has no temporal layers
+func setupBenchLayers(f *Forwarder) {
+	maxLayer := int32(len(benchPacketsPerFrame) - 1)
+	f.SetMaxPublishedLayer(maxLayer)
+	f.SetMaxTemporalLayerSeen(0)
+	f.SetMaxSpatialLayer(maxLayer)
+	f.SetMaxTemporalLayer(0)
+}
+
+// pushFrame sends one frame on every layer through the pipeline, timing each stage when stats is given
+func (p *benchPipeline) pushFrame(stats *benchStageStats) {
+	p.timestamp += benchTimestampStride
+
+	for layer, numPackets := range benchPacketsPerFrame {
+		keyFrame := p.frame%benchKeyFrameEvery == 0 && (p.frame != 0 || layer == len(benchPacketsPerFrame)-1)
+		for i := 0; i < numPackets; i++ {
+			raw := p.marshalPacket(layer, i == 0, i == numPackets-1, keyFrame)
+
+			start := time.Now()
+			_, err := p.buffers[layer].Write(raw)
+			require.NoError(p.b, err)
+			extPkt, err := p.buffers[layer].ReadExtended(p.readBufs[layer])
+			require.NoError(p.b, err)
+			bufferDone := time.Now()
+
+			_, err = p.forwarder.GetTranslationParams(extPkt, int32(layer))
+			require.NoError(p.b, err)
+			forwarderDone := time.Now()
+
+			for _, dt := range p.downTracks {
+				_ = dt.WriteRTP(extPkt, int32(layer))
+			}
+
+			if stats != nil {
+				stats.packets++
+				stats.buffer += bufferDone.Sub(start)
+				stats.forwarder += forwarderDone.Sub(bufferDone)
+				stats.downTrack += time.Since(forwarderDone)
+			}
+		}
+	}
+	p.frame++
+}
+
+func (p *benchPipeline) marshalPacket(layer int, startOfFrame bool, endOfFrame bool, keyFrame bool) []byte {
+	p.sns[layer]++
+
+	// VP8 payload descriptor with a 15 bit picture id, followed by the payload header
+	descriptor := byte(0x80)
+	if startOfFrame {
+		descriptor |= 0x10
+	}
+	pictureID := uint16(p.frame) & 0x7fff
+	p.payload[0] = descriptor
+	p.payload[1] = 0x80
+	p.payload[2] = 0x80 | byte(pictureID>>8)
+	p.payload[3] = byte(pictureID)
+	if keyFrame {
+		p.payload[4] = 0x00
+	} else {
+		p.payload[4] = 0x01
+	}
+
+	pkt := rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         endOfFrame,
+			PayloadType:    96,
+			SequenceNumber: p.sns[layer],
+			Timestamp:      p.timestamp,
+			SSRC:           uint32(benchSSRCBase + layer),
+		},
+		Payload: p.payload,
+	}
+	n, err := pkt.MarshalTo(p.raw)
+	require.NoError(p.b, err)
+	return p.raw[:n]
+}
+
+// ---------------------------------------------
+
+// benchReceiver implements the parts of TrackReceiver used by downtracks when forwarding
+type benchReceiver struct {
+	TrackReceiver
+
+	codec webrtc.RTPCodecParameters
+}
+
+func (r *benchReceiver) TrackID() livekit.TrackID                               { return "TR_bench" }
+func (r *benchReceiver) StreamID() string                                       { return "bench" }
+func (r *benchReceiver) Codec() webrtc.RTPCodecParameters                       { return r.codec }
+func (r *benchReceiver) HeaderExtensions() []webrtc.RTPHeaderExtensionParameter { return nil }
+func (r *benchReceiver) IsClosed() bool                                         { return false }
+func (r *benchReceiver) SendPLI(_ int32, _ bool)                                {}
+func (r *benchReceiver) DeleteDownTrack(_ livekit.ParticipantID)                {}
+func (r *benchReceiver) TrackInfo() *livekit.TrackInfo                          { return nil }
+
+func (r *benchReceiver) GetLayeredBitrate() ([]int32, Bitrates) {
+	var brs Bitrates
+	for layer := range benchPacketsPerFrame {
+		brs[layer][0] = int64(benchPacketsPerFrame[layer]) * benchPayloadSize * 8 * 30
+	}
+	return []int32{0, 1, 2}, brs
+}
+
+type benchTrackLocalContext struct {
+	codec  webrtc.RTPCodecParameters
+	ssrc   webrtc.SSRC
+	writer *benchWriteStream
+}
+
+func (c *benchTrackLocalContext) CodecParameters() []webrtc.RTPCodecParameters {
+	return []webrtc.RTPCodecParameters{c.codec}
+}
+func (c *benchTrackLocalContext) HeaderExtensions() []webrtc.RTPHeaderExtensionParameter { return nil }
+func (c *benchTrackLocalContext) SSRC() webrtc.SSRC                                      { return c.ssrc }
+func (c *benchTrackLocalContext) WriteStream() webrtc.TrackLocalWriter                   { return c.writer }
+func (c *benchTrackLocalContext) ID() string                                             { return fmt.Sprintf("bench%d", c.ssrc) }
+func (c *benchTrackLocalContext) RTCPReader() interceptor.RTCPReader                     { return nil }
+
+// benchWriteStream discards forwarded packets, counting them
+type benchWriteStream struct {
+	packets int
+}
+
+func (w *benchWriteStream) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
+	w.packets++
+	return header.MarshalSize() + len(payload), nil
+}
+
+func (w *benchWriteStream) Write(b []byte) (int, error) {
+	w.packets++
+	return len(b), nil
+}
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v3"
 	"go.uber.org/atomic"
 	"google.golang.org/protobuf/proto"
@@ -35,6 +36,7 @@ import (
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
 	"github.com/livekit/livekit-server/pkg/sfu/connectionquality"
 	dd "github.com/livekit/livekit-server/pkg/sfu/rtpextension/dependencydescriptor"
+	"github.com/livekit/livekit-server/pkg/sfu/videohealth"
 )
 
 var (
@@ -83,6 +85,7 @@ type TrackReceiver interface {
 	GetTemporalLayerFpsForSpatial(layer int32) []float32
 
 	GetTrackStats() *livekit.RTPStats
+	GetTrackStatsWithLayers() *buffer.RTPStatsWithLayers
 }
 
 // WebRTCReceiver receives a media track
@@ -130,6 +133,15 @@ type WebRTCReceiver struct {
 	redPktWriter    func(pkt *buffer.ExtPacket, spatialLayer int32) int
 
 	forwardStats *ForwardStats
+
+	silenceDetector *audio.SilenceDetector
+	silencePaused   atomic.Bool
+
+	freezeTimeout  time.Duration
+	frozenLayers   atomic.Int32
+	onFreezeChange func(frozen bool)
+
+	packetInterceptor func(pkt *rtp.Packet) bool
 }
 
 type ReceiverOpts func(w *WebRTCReceiver) *WebRTCReceiver
@@ -177,6 +189,24 @@ func WithForwardStats(forwardStats *ForwardStats) ReceiverOpts {
 	}
 }
 
+// WithFreezeDetection reports video as frozen when packets of a layer keep arriving without its timestamp
+// advancing for the timeout
+func WithFreezeDetection(timeout time.Duration) ReceiverOpts {
+	return func(w *WebRTCReceiver) *WebRTCReceiver {
+		w.freezeTimeout = timeout
+		return w
+	}
+}
+
+// WithPacketInterceptor calls f with every packet before it is buffered and forwarded. f may modify the packet in
+// place, returning false drops it
+func WithPacketInterceptor(f func(pkt *rtp.Packet) bool) ReceiverOpts {
+	return func(w *WebRTCReceiver) *WebRTCReceiver {
+		w.packetInterceptor = f
+		return w
+	}
+}
+
 func WithEverHasDownTrackAdded(f func()) ReceiverOpts {
 	return func(w *WebRTCReceiver) *WebRTCReceiver {
 		w.onDownTrackEverAdded = f
@@ -211,6 +241,14 @@ func NewWebRTCReceiver(
 	}
 	w.trackInfo.Store(proto.Clone(trackInfo).(*livekit.TrackInfo))
 
+	if sp := w.audioConfig.SilencePause; sp.Enabled && w.kind == webrtc.RTPCodecTypeAudio {
+		w.silenceDetector = audio.NewSilenceDetector(audio.SilenceDetectorParams{
+			SilentLevel: sp.SilentLevel,
+			ResumeLevel: sp.ResumeLevel,
+			PauseAfter:  sp.PauseAfter,
+		})
+	}
+
 	w.downTrackSpreader = NewDownTrackSpreader(DownTrackSpreaderParams{
 		Threshold: w.lbThreshold,
 		Logger:    logger,
@@ -257,6 +295,12 @@ func (w *WebRTCReceiver) OnStatsUpdate(fn func(w *WebRTCReceiver, stat *livekit.
 	w.onStatsUpdate = fn
 }
 
+func (w *WebRTCReceiver) OnFreezeChange(fn func(frozen bool)) {
+	w.bufferMu.Lock()
+	w.onFreezeChange = fn
+	w.bufferMu.Unlock()
+}
+
 func (w *WebRTCReceiver) OnMaxLayerChange(fn func(maxLayer int32)) {
 	w.bufferMu.Lock()
 	w.onMaxLayerChange = fn
@@ -342,6 +386,9 @@ func (w *WebRTCReceiver) AddUpTrack(track *webrtc.TrackRemote, buff *buffer.Buff
 		SmoothIntervals: w.audioConfig.SmoothIntervals,
 	})
 	buff.SetAudioLossProxying(w.audioConfig.EnableLossProxying)
+	if w.packetInterceptor != nil {
+		buff.SetPacketInterceptor(w.packetInterceptor)
+	}
 	buff.OnRtcpFeedback(w.sendRTCP)
 	buff.OnRtcpSenderReport(func() {
 		srData := buff.GetSenderReportData()
@@ -417,6 +464,9 @@ func (w *WebRTCReceiver) AddDownTrack(track TrackSender) error {
 	track.TrackInfoAvailable()
 	track.UpTrackMaxPublishedLayerChange(w.streamTrackerManager.GetMaxPublishedLayer())
 	track.UpTrackMaxTemporalLayerSeenChange(w.streamTrackerManager.GetMaxTemporalLayerSeen())
+	if w.silencePaused.Load() {
+		track.UpTrackSilencePause(true)
+	}
 
 	w.downTrackSpreader.Store(track)
 	w.logger.Debugw("downtrack added", "subscriberID", track.SubscriberID())
@@ -605,6 +655,24 @@ func (w *WebRTCReceiver) GetTrackStats() *livekit.RTPStats {
 	return buffer.AggregateRTPStats(stats)
 }
 
+func (w *WebRTCReceiver) GetTrackStatsWithLayers() *buffer.RTPStatsWithLayers {
+	w.bufferMu.RLock()
+	defer w.bufferMu.RUnlock()
+
+	layers := make(map[int32]*livekit.RTPStats, len(w.buffers))
+	for layer, buff := range w.buffers {
+		if buff == nil {
+			continue
+		}
+
+		if s := buff.GetStats(); s != nil {
+			layers[int32(layer)] = s
+		}
+	}
+
+	return buffer.NewRTPStatsWithLayers(layers)
+}
+
 func (w *WebRTCReceiver) GetAudioLevel() (float64, bool) {
 	if w.Kind() == webrtc.RTPCodecTypeVideo {
 		return 0, false
@@ -674,6 +742,11 @@ func (w *WebRTCReceiver) forwardRTP(layer int32) {
 	pktBuf := make([]byte, bucket.MaxPktSize)
 	tracker := w.streamTrackerManager.GetTracker(layer)
 
+	var freezeDetector *videohealth.FreezeDetector
+	if w.freezeTimeout > 0 && w.kind == webrtc.RTPCodecTypeVideo {
+		freezeDetector = videohealth.NewFreezeDetector(w.freezeTimeout)
+	}
+
 	defer func() {
 		w.closeOnce.Do(func() {
 			w.closed.Store(true)
@@ -686,6 +759,11 @@ func (w *WebRTCReceiver) forwardRTP(layer int32) {
 			}
 		})
 
+		// a layer that stops while frozen no longer holds the track frozen
+		if freezeDetector != nil && freezeDetector.Frozen() {
+			w.updateFrozenLayers(false)
+		}
+
 		w.streamTrackerManager.RemoveTracker(layer)
 		if w.isSVC {
 			w.streamTrackerManager.RemoveAllTrackers()
@@ -713,6 +791,10 @@ func (w *WebRTCReceiver) forwardRTP(layer int32) {
 			}
 		}
 
+		if w.silenceDetector != nil {
+			// down tracks drop packets while paused
+			w.updateSilencePause(pkt)
+		}
 		writeCount := w.downTrackSpreader.Broadcast(func(dt TrackSender) {
 			_ = dt.WriteRTP(pkt, spatialLayer)
 		})
@@ -725,6 +807,12 @@ func (w *WebRTCReceiver) forwardRTP(layer int32) {
 			w.forwardStats.Update(pkt.Arrival, time.Now().UnixNano())
 		}
 
+		if freezeDetector != nil && len(pkt.Packet.Payload) != 0 {
+			if frozen, changed := freezeDetector.Observe(pkt.Packet.Timestamp, pkt.Arrival); changed {
+				w.updateFrozenLayers(frozen)
+			}
+		}
+
 		if spatialTracker != nil {
 			spatialTracker.Observe(
 				pkt.Temporal,
@@ -738,6 +826,51 @@ func (w *WebRTCReceiver) forwardRTP(layer int32) {
 	}
 }
 
+// updateSilencePause observes the audio level of a packet, notifies down tracks when the track becomes silent or
+// stops being silent
+func (w *WebRTCReceiver) updateSilencePause(pkt *buffer.ExtPacket) {
+	level := uint8(0)
+	if pkt.AudioLevelExt != nil {
+		level = pkt.AudioLevelExt.Level
+	}
+	paused, changed := w.silenceDetector.Observe(level, len(pkt.Packet.Payload), pkt.Arrival)
+	if !changed {
+		return
+	}
+
+	w.logger.Debugw("silence pause changed", "paused", paused)
+	w.silencePaused.Store(paused)
+	w.downTrackSpreader.Broadcast(func(dt TrackSender) {
+		dt.UpTrackSilencePause(paused)
+	})
+	if pr := w.primaryReceiver.Load(); pr != nil {
+		pr.silencePause(paused)
+	}
+	if rr := w.redReceiver.Load(); rr != nil {
+		rr.silencePause(paused)
+	}
+}
+
+func (w *WebRTCReceiver) updateFrozenLayers(frozen bool) {
+	var changed bool
+	if frozen {
+		changed = w.frozenLayers.Inc() == 1
+	} else {
+		changed = w.frozenLayers.Dec() == 0
+	}
+	if !changed {
+		return
+	}
+
+	w.logger.Debugw("video freeze changed", "frozen", frozen)
+	w.bufferMu.RLock()
+	onFreezeChange := w.onFreezeChange
+	w.bufferMu.RUnlock()
+	if onFreezeChange != nil {
+		onFreezeChange(frozen)
+	}
+}
+
 // closeTracks close all tracks from Receiver
 func (w *WebRTCReceiver) closeTracks() {
 	w.connectionStats.Close()
@@ -764,12 +897,17 @@ func (w *WebRTCReceiver) DebugInfo() map[string]interface{} {
 	upTrackInfo := make([]map[string]interface{}, 0, len(w.upTracks))
 	for layer, ut := range w.upTracks {
 		if ut != nil {
-			upTrackInfo = append(upTrackInfo, map[string]interface{}{
+			layerInfo := map[string]interface{}{
 				"Layer": layer,
 				"SSRC":  ut.SSRC(),
 				"Msid":  ut.Msid(),
 				"RID":   ut.RID(),
-			})
+			}
+			if buff := w.buffers[layer]; buff != nil {
+				layerInfo["RTCPReports"] = buff.GetRTCPReports()
+				layerInfo["InterArrivalHistogram"] = buff.GetInterArrivalHistogram()
+			}
+			upTrackInfo = append(upTrackInfo, layerInfo)
 		}
 	}
 	w.bufferMu.RUnlock()
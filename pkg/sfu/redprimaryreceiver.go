@@ -123,6 +123,12 @@ func (r *RedPrimaryReceiver) DeleteDownTrack(subscriberID livekit.ParticipantID)
 	r.logger.Debugw("red primary receiver downtrack deleted", "subscriberID", subscriberID)
 }
 
+func (r *RedPrimaryReceiver) silencePause(paused bool) {
+	r.downTrackSpreader.Broadcast(func(dt TrackSender) {
+		dt.UpTrackSilencePause(paused)
+	})
+}
+
 func (r *RedPrimaryReceiver) IsClosed() bool {
 	return r.closed.Load()
 }
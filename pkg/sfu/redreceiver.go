@@ -118,6 +118,12 @@ func (r *RedReceiver) CanClose() bool {
 	return r.closed.Load() || r.downTrackSpreader.DownTrackCount() == 0
 }
 
+func (r *RedReceiver) silencePause(paused bool) {
+	r.downTrackSpreader.Broadcast(func(dt TrackSender) {
+		dt.UpTrackSilencePause(paused)
+	})
+}
+
 func (r *RedReceiver) IsClosed() bool {
 	return r.closed.Load()
 }
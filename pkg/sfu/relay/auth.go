@@ -0,0 +1,74 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relay
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+const (
+	controlTimestampSize = 8
+	controlMaxSkew       = 30 * time.Second
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid relay control signature")
+	ErrStaleControl     = errors.New("stale relay control packet")
+)
+
+// signControl builds the payload of a control packet. The payload carries the sending node
+// and a timestamp, authenticated with a secret shared by all nodes of the deployment.
+//
+// layout: timestamp (8 bytes) | node id | HMAC-SHA256 over packet type, track id, timestamp and node id
+func signControl(secret []byte, packetType PacketType, trackID livekit.TrackID, nodeID livekit.NodeID, at time.Time) []byte {
+	payload := make([]byte, controlTimestampSize, controlTimestampSize+len(nodeID)+sha256.Size)
+	binary.BigEndian.PutUint64(payload, uint64(at.UnixNano()))
+	payload = append(payload, nodeID...)
+	return append(payload, controlMAC(secret, packetType, trackID, payload)...)
+}
+
+// verifyControl validates a control packet payload and returns the sending node
+func verifyControl(secret []byte, pkt *Packet, now time.Time) (livekit.NodeID, error) {
+	if len(pkt.Payload) < controlTimestampSize+sha256.Size {
+		return "", ErrPacketTooShort
+	}
+
+	signed := pkt.Payload[:len(pkt.Payload)-sha256.Size]
+	mac := pkt.Payload[len(pkt.Payload)-sha256.Size:]
+	if !hmac.Equal(mac, controlMAC(secret, pkt.Type, pkt.TrackID, signed)) {
+		return "", ErrInvalidSignature
+	}
+
+	at := time.Unix(0, int64(binary.BigEndian.Uint64(signed)))
+	if skew := now.Sub(at); skew > controlMaxSkew || skew < -controlMaxSkew {
+		return "", ErrStaleControl
+	}
+
+	return livekit.NodeID(signed[controlTimestampSize:]), nil
+}
+
+func controlMAC(secret []byte, packetType PacketType, trackID livekit.TrackID, signed []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte{byte(packetType)})
+	h.Write([]byte(trackID))
+	h.Write(signed)
+	return h.Sum(nil)
+}
@@ -0,0 +1,167 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relay
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+
+	"go.uber.org/atomic"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// MinSecretLength is the minimum length of the secret shared by all nodes
+	MinSecretLength = 32
+
+	saltSize    = 4
+	counterSize = 8
+	nonceSize   = saltSize + counterSize
+
+	replayWindowSize = 64
+
+	keyInfo = "livekit relay packet key"
+)
+
+var (
+	ErrSecretTooShort  = errors.New("relay secret too short")
+	ErrUnauthenticated = errors.New("unauthenticated relay packet")
+	ErrReplayed        = errors.New("replayed relay packet")
+)
+
+// packetCipher encrypts and authenticates relay packets with AES-GCM, using a key derived from the
+// secret shared by all nodes. The nonce is a random salt, chosen when the transport starts, followed
+// by a packet counter. Receivers keep a replay window per sender.
+type packetCipher struct {
+	aead    cipher.AEAD
+	salt    [saltSize]byte
+	counter atomic.Uint64
+
+	lock    sync.Mutex
+	windows map[string]*replayWindow
+}
+
+func newPacketCipher(secret []byte) (*packetCipher, error) {
+	if len(secret) < MinSecretLength {
+		return nil, ErrSecretTooShort
+	}
+
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, []byte(keyInfo)), key); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &packetCipher{
+		aead:    aead,
+		windows: make(map[string]*replayWindow),
+	}
+	if _, err := rand.Read(c.salt[:]); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *packetCipher) Overhead() int {
+	return nonceSize + c.aead.Overhead()
+}
+
+// Seal appends the encrypted packet to dst
+func (c *packetCipher) Seal(dst []byte, plaintext []byte) []byte {
+	var nonce [nonceSize]byte
+	copy(nonce[:], c.salt[:])
+	binary.BigEndian.PutUint64(nonce[saltSize:], c.counter.Inc())
+
+	dst = append(dst, nonce[:]...)
+	return c.aead.Seal(dst, nonce[:], plaintext, nil)
+}
+
+// Open authenticates and decrypts a packet received from the given address
+func (c *packetCipher) Open(from string, buf []byte) ([]byte, error) {
+	if len(buf) < c.Overhead() {
+		return nil, ErrPacketTooShort
+	}
+
+	nonce := buf[:nonceSize]
+	plaintext, err := c.aead.Open(nil, nonce, buf[nonceSize:], nil)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+
+	var salt [saltSize]byte
+	copy(salt[:], nonce)
+	counter := binary.BigEndian.Uint64(nonce[saltSize:])
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	// only authenticated packets create a window, so the map is bounded by the number of nodes
+	w := c.windows[from]
+	if w == nil || w.salt != salt {
+		// new sender, or sender restarted
+		w = &replayWindow{salt: salt}
+		c.windows[from] = w
+	}
+	if !w.accept(counter) {
+		return nil, ErrReplayed
+	}
+	return plaintext, nil
+}
+
+// ------------------------------------------------
+
+type replayWindow struct {
+	salt    [saltSize]byte
+	highest uint64
+	seen    uint64
+}
+
+func (w *replayWindow) accept(counter uint64) bool {
+	switch {
+	case counter > w.highest:
+		shift := counter - w.highest
+		if shift >= replayWindowSize {
+			w.seen = 0
+		} else {
+			w.seen <<= shift
+		}
+		w.seen |= 1
+		w.highest = counter
+		return true
+
+	case w.highest-counter >= replayWindowSize:
+		return false
+
+	default:
+		bit := uint64(1) << (w.highest - counter)
+		if w.seen&bit != 0 {
+			return false
+		}
+		w.seen |= bit
+		return true
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relay
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPacketCipher(t *testing.T) {
+	_, err := newPacketCipher([]byte("short"))
+	require.ErrorIs(t, err, ErrSecretTooShort)
+
+	sender, err := newPacketCipher([]byte(testSecret))
+	require.NoError(t, err)
+	receiver, err := newPacketCipher([]byte(testSecret))
+	require.NoError(t, err)
+
+	sealed := sender.Seal(nil, []byte("packet"))
+	require.Len(t, sealed, len("packet")+sender.Overhead())
+	opened, err := receiver.Open("127.0.0.1:7890", sealed)
+	require.NoError(t, err)
+	require.Equal(t, []byte("packet"), opened)
+
+	// replayed
+	_, err = receiver.Open("127.0.0.1:7890", sealed)
+	require.ErrorIs(t, err, ErrReplayed)
+
+	// out of order within the window
+	first := sender.Seal(nil, []byte("first"))
+	second := sender.Seal(nil, []byte("second"))
+	_, err = receiver.Open("127.0.0.1:7890", second)
+	require.NoError(t, err)
+	_, err = receiver.Open("127.0.0.1:7890", first)
+	require.NoError(t, err)
+
+	// tampered
+	tampered := sender.Seal(nil, []byte("packet"))
+	tampered[len(tampered)-1] ^= 1
+	_, err = receiver.Open("127.0.0.1:7890", tampered)
+	require.ErrorIs(t, err, ErrUnauthenticated)
+
+	// sealed with another secret
+	other, err := newPacketCipher([]byte("another secret of at least 32 characters"))
+	require.NoError(t, err)
+	_, err = receiver.Open("127.0.0.1:7890", other.Seal(nil, []byte("packet")))
+	require.ErrorIs(t, err, ErrUnauthenticated)
+
+	// plaintext
+	_, err = receiver.Open("127.0.0.1:7890", []byte("plaintext relay packet from a spoofed address"))
+	require.ErrorIs(t, err, ErrUnauthenticated)
+}
+
+func TestReplayWindow(t *testing.T) {
+	var w replayWindow
+	require.True(t, w.accept(1))
+	require.True(t, w.accept(100))
+	require.False(t, w.accept(100))
+	require.True(t, w.accept(99))
+	require.False(t, w.accept(100-replayWindowSize))
+	require.True(t, w.accept(100+replayWindowSize))
+	require.False(t, w.accept(99))
+}
@@ -0,0 +1,514 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relay
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/frostbyte73/core"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+const (
+	keepaliveInterval = 2 * time.Second
+	senderTimeout     = 4 * keepaliveInterval
+
+	// how long a removed sender is retained to seed stats of a re-established relay
+	removedSenderTTL = 30 * time.Second
+
+	// receivers without local subscribers are released after this long
+	receiverIdleTimeout = senderTimeout
+)
+
+var (
+	ErrTrackNotFound  = errors.New("track not found")
+	ErrRelayDisabled  = errors.New("relay is disabled")
+	ErrManagerStopped = errors.New("relay manager stopped")
+	ErrUnknownNode    = errors.New("relay peer is not a known node")
+)
+
+type senderKey struct {
+	trackID livekit.TrackID
+	nodeID  livekit.NodeID
+}
+
+type senderInfo struct {
+	sender   *Sender
+	addr     string
+	receiver sfu.TrackReceiver
+	lastSeen time.Time
+}
+
+type removedSender struct {
+	sender    *Sender
+	removedAt time.Time
+}
+
+type receiverInfo struct {
+	receiver   *Receiver
+	originAddr string
+	idleSince  time.Time
+}
+
+type ManagerParams struct {
+	Config         config.RelayConfig
+	NodeID         livekit.NodeID
+	TrackersConfig config.StreamTrackersConfig
+	BufferFactory  *buffer.FactoryOfBufferFactory
+	Transport      Transport
+	Logger         logger.Logger
+
+	// secret shared by all nodes, used to authenticate control packets
+	Secret []byte
+	// looks up registered nodes, control packets are only accepted from known nodes
+	GetNode func(nodeID livekit.NodeID) (*livekit.Node, error)
+	// resolves a locally published track for relaying to other nodes
+	ReceiverProvider func(trackID livekit.TrackID) sfu.TrackReceiver
+}
+
+// Manager relays published tracks between nodes so that a room can have participants on
+// multiple nodes. Publishing nodes maintain a single sender per (track, remote node) while
+// subscribing nodes maintain a single receiver per track which is shared by all local subscribers.
+type Manager struct {
+	params ManagerParams
+
+	lock           sync.RWMutex
+	senders        map[senderKey]*senderInfo
+	removedSenders map[senderKey]removedSender
+	receivers      map[livekit.TrackID]*receiverInfo
+
+	stopped core.Fuse
+}
+
+func NewManager(params ManagerParams) *Manager {
+	m := &Manager{
+		params:         params,
+		senders:        make(map[senderKey]*senderInfo),
+		removedSenders: make(map[senderKey]removedSender),
+		receivers:      make(map[livekit.TrackID]*receiverInfo),
+	}
+	params.Transport.OnPacket(m.handlePacket)
+	return m
+}
+
+func (m *Manager) Start() error {
+	if !m.params.Config.Enabled {
+		return ErrRelayDisabled
+	}
+
+	if err := m.params.Transport.Start(); err != nil {
+		return err
+	}
+
+	go m.worker()
+	return nil
+}
+
+func (m *Manager) Stop() {
+	m.stopped.Break()
+
+	m.lock.Lock()
+	senders := m.senders
+	receivers := m.receivers
+	m.senders = make(map[senderKey]*senderInfo)
+	m.removedSenders = make(map[senderKey]removedSender)
+	m.receivers = make(map[livekit.TrackID]*receiverInfo)
+	m.lock.Unlock()
+
+	for _, si := range senders {
+		si.receiver.DeleteDownTrack(si.sender.SubscriberID())
+		si.sender.Close()
+	}
+	for trackID, ri := range receivers {
+		m.sendControl(ri.originAddr, PacketTypeUnsubscribe, trackID)
+		ri.receiver.Close()
+	}
+
+	m.params.Transport.Close()
+}
+
+// AddrForNode returns the relay address of a node, all nodes of a deployment use the same relay port
+func (m *Manager) AddrForNode(node *livekit.Node) string {
+	return net.JoinHostPort(node.Ip, strconv.Itoa(int(m.params.Config.Port)))
+}
+
+type SubscribeParams struct {
+	OriginNode       *livekit.Node
+	TrackInfo        *livekit.TrackInfo
+	Codec            webrtc.RTPCodecParameters
+	HeaderExtensions []webrtc.RTPHeaderExtensionParameter
+
+	// called when the receiver is closed, either on unsubscribe or when released for being idle
+	OnClose func()
+}
+
+// Subscribe returns a receiver for a track published on another node. Only one receiver is
+// created per track, repeated calls return the existing receiver.
+func (m *Manager) Subscribe(params SubscribeParams) (*Receiver, error) {
+	if !m.params.Config.Enabled {
+		return nil, ErrRelayDisabled
+	}
+	if m.stopped.IsBroken() {
+		return nil, ErrManagerStopped
+	}
+
+	trackID := livekit.TrackID(params.TrackInfo.Sid)
+	originAddr := m.AddrForNode(params.OriginNode)
+
+	m.lock.Lock()
+	if ri := m.receivers[trackID]; ri != nil && !ri.receiver.IsClosed() {
+		m.lock.Unlock()
+		return ri.receiver, nil
+	}
+
+	lgr := m.params.Logger.WithValues("trackID", trackID, "originNodeID", params.OriginNode.Id)
+	var receiver *Receiver
+	receiver = NewReceiver(ReceiverParams{
+		TrackInfo:        params.TrackInfo,
+		Codec:            params.Codec,
+		HeaderExtensions: params.HeaderExtensions,
+		OriginNodeID:     livekit.NodeID(params.OriginNode.Id),
+		BufferFactory:    m.params.BufferFactory.CreateBufferFactory(),
+		TrackersConfig:   m.params.TrackersConfig,
+		Logger:           lgr,
+		SendPLI: func(layer int32, force bool) {
+			m.sendPLI(originAddr, trackID, layer, force)
+		},
+		OnClose: func() {
+			m.lock.Lock()
+			if ri := m.receivers[trackID]; ri != nil && ri.receiver == receiver {
+				delete(m.receivers, trackID)
+			}
+			m.lock.Unlock()
+
+			if params.OnClose != nil {
+				params.OnClose()
+			}
+		},
+	})
+	m.receivers[trackID] = &receiverInfo{
+		receiver:   receiver,
+		originAddr: originAddr,
+	}
+	m.lock.Unlock()
+
+	lgr.Infow("subscribing to relayed track")
+	m.sendControl(originAddr, PacketTypeSubscribe, trackID)
+	return receiver, nil
+}
+
+// Unsubscribe stops relaying of a track to this node
+func (m *Manager) Unsubscribe(trackID livekit.TrackID) {
+	m.lock.Lock()
+	ri := m.receivers[trackID]
+	delete(m.receivers, trackID)
+	m.lock.Unlock()
+
+	if ri == nil {
+		return
+	}
+
+	m.sendControl(ri.originAddr, PacketTypeUnsubscribe, trackID)
+	ri.receiver.Close()
+}
+
+func (m *Manager) GetReceiver(trackID livekit.TrackID) *Receiver {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	if ri := m.receivers[trackID]; ri != nil {
+		return ri.receiver
+	}
+	return nil
+}
+
+func (m *Manager) sendControl(addr string, packetType PacketType, trackID livekit.TrackID) {
+	err := m.params.Transport.Send(addr, &Packet{
+		Type:    packetType,
+		TrackID: trackID,
+		Payload: signControl(m.params.Secret, packetType, trackID, m.params.NodeID, time.Now()),
+	})
+	if err != nil {
+		m.params.Logger.Debugw("could not send relay control packet", "error", err, "type", packetType, "trackID", trackID)
+	}
+}
+
+func (m *Manager) sendPLI(addr string, trackID livekit.TrackID, layer int32, force bool) {
+	payload := []byte{0}
+	if force {
+		payload[0] = 1
+	}
+	_ = m.params.Transport.Send(addr, &Packet{
+		Type:    PacketTypePLI,
+		Layer:   layer,
+		TrackID: trackID,
+		Payload: payload,
+	})
+}
+
+func (m *Manager) handlePacket(from net.Addr, pkt *Packet) {
+	switch pkt.Type {
+	case PacketTypeRTP, PacketTypeSenderReport:
+		m.lock.RLock()
+		ri := m.receivers[pkt.TrackID]
+		m.lock.RUnlock()
+		// media is only accepted from the node the track is being relayed from
+		if ri != nil && isFromAddr(from, ri.originAddr) {
+			ri.receiver.HandlePacket(pkt)
+		}
+
+	case PacketTypePLI:
+		m.lock.RLock()
+		var receiver sfu.TrackReceiver
+		for key, si := range m.senders {
+			if key.trackID == pkt.TrackID && isFromAddr(from, si.addr) {
+				receiver = si.receiver
+				break
+			}
+		}
+		m.lock.RUnlock()
+		if receiver != nil {
+			receiver.SendPLI(pkt.Layer, len(pkt.Payload) > 0 && pkt.Payload[0] == 1)
+		}
+
+	case PacketTypeSubscribe, PacketTypeUnsubscribe:
+		node, err := m.authenticate(from, pkt)
+		if err != nil {
+			m.params.Logger.Debugw("dropping relay control packet", "error", err, "type", pkt.Type, "from", from)
+			return
+		}
+
+		if pkt.Type == PacketTypeSubscribe {
+			m.handleSubscribe(node, pkt.TrackID)
+		} else {
+			m.handleUnsubscribe(pkt.TrackID, livekit.NodeID(node.Id))
+		}
+	}
+}
+
+// authenticate validates a control packet and returns the registered node that sent it
+func (m *Manager) authenticate(from net.Addr, pkt *Packet) (*livekit.Node, error) {
+	nodeID, err := verifyControl(m.params.Secret, pkt, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if nodeID == m.params.NodeID {
+		return nil, ErrUnknownNode
+	}
+
+	node, err := m.params.GetNode(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if node == nil || !isFromAddr(from, m.AddrForNode(node)) {
+		return nil, ErrUnknownNode
+	}
+	return node, nil
+}
+
+func (m *Manager) handleSubscribe(node *livekit.Node, trackID livekit.TrackID) {
+	nodeID := livekit.NodeID(node.Id)
+	addr := m.AddrForNode(node)
+	key := senderKey{trackID: trackID, nodeID: nodeID}
+
+	m.lock.Lock()
+	si := m.senders[key]
+	if si != nil && !si.sender.IsClosed() && !si.receiver.IsClosed() {
+		// refresh of an existing relay
+		si.lastSeen = time.Now()
+		m.lock.Unlock()
+		return
+	}
+	seed := m.removedSenders[key].sender
+	m.lock.Unlock()
+
+	receiver := m.params.ReceiverProvider(trackID)
+	if receiver == nil {
+		m.params.Logger.Debugw("relay requested for unknown track", "trackID", trackID, "nodeID", nodeID)
+		return
+	}
+
+	var sender *Sender
+	sender = NewSender(SenderParams{
+		TrackID:   trackID,
+		NodeID:    nodeID,
+		Addr:      addr,
+		ClockRate: receiver.Codec().ClockRate,
+		Transport: m.params.Transport,
+		Logger:    m.params.Logger.WithValues("trackID", trackID, "remoteNodeID", nodeID),
+		OnClose: func() {
+			// sender is also closed by the receiver when the published track goes away
+			m.removeSender(key, sender)
+		},
+	})
+	if si != nil {
+		seed = si.sender
+	}
+	sender.Seed(seed)
+
+	m.lock.Lock()
+	if existing := m.senders[key]; existing != si {
+		// lost a race with another subscribe for the same relay
+		m.lock.Unlock()
+		return
+	}
+	m.senders[key] = &senderInfo{
+		sender:   sender,
+		addr:     addr,
+		receiver: receiver,
+		lastSeen: time.Now(),
+	}
+	delete(m.removedSenders, key)
+	m.lock.Unlock()
+
+	if si != nil {
+		// replacing a stale relay, detach it from the track it was sending
+		si.receiver.DeleteDownTrack(si.sender.SubscriberID())
+		si.sender.Close()
+	}
+
+	if err := receiver.AddDownTrack(sender); err != nil {
+		m.params.Logger.Warnw("could not relay track", err, "trackID", trackID, "nodeID", nodeID)
+		m.removeSender(key, sender)
+		return
+	}
+	m.params.Logger.Infow("relaying track", "trackID", trackID, "nodeID", nodeID, "addr", addr)
+}
+
+func (m *Manager) handleUnsubscribe(trackID livekit.TrackID, nodeID livekit.NodeID) {
+	key := senderKey{trackID: trackID, nodeID: nodeID}
+
+	m.lock.RLock()
+	si := m.senders[key]
+	m.lock.RUnlock()
+
+	if si != nil {
+		m.removeSender(key, si.sender)
+	}
+}
+
+func (m *Manager) removeSender(key senderKey, sender *Sender) {
+	m.lock.Lock()
+	si := m.senders[key]
+	if si == nil || si.sender != sender {
+		m.lock.Unlock()
+		return
+	}
+	delete(m.senders, key)
+	m.removedSenders[key] = removedSender{
+		sender:    sender,
+		removedAt: time.Now(),
+	}
+	m.lock.Unlock()
+
+	si.receiver.DeleteDownTrack(sender.SubscriberID())
+	sender.Close()
+}
+
+func (m *Manager) worker() {
+	ticker := time.NewTicker(keepaliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stopped.Watch():
+			return
+
+		case <-ticker.C:
+			m.refreshReceivers()
+			m.expireSenders()
+		}
+	}
+}
+
+// refreshReceivers re-subscribes to all relayed tracks, keeping them alive on the origin nodes.
+// Receivers which have not had any local subscribers for a while are released.
+func (m *Manager) refreshReceivers() {
+	m.lock.Lock()
+	refresh := make(map[livekit.TrackID]string, len(m.receivers))
+	var idle []livekit.TrackID
+	for trackID, ri := range m.receivers {
+		if ri.receiver.NumDownTracks() != 0 {
+			ri.idleSince = time.Time{}
+		} else if ri.idleSince.IsZero() {
+			ri.idleSince = time.Now()
+		} else if time.Since(ri.idleSince) > receiverIdleTimeout {
+			idle = append(idle, trackID)
+			continue
+		}
+		refresh[trackID] = ri.originAddr
+	}
+	m.lock.Unlock()
+
+	for trackID, addr := range refresh {
+		m.sendControl(addr, PacketTypeSubscribe, trackID)
+	}
+	for _, trackID := range idle {
+		m.params.Logger.Debugw("releasing idle relay", "trackID", trackID)
+		m.Unsubscribe(trackID)
+	}
+}
+
+// expireSenders removes relays which have not been refreshed by the remote node
+func (m *Manager) expireSenders() {
+	m.lock.Lock()
+	var expired []senderKey
+	for key, si := range m.senders {
+		if time.Since(si.lastSeen) > senderTimeout || si.receiver.IsClosed() {
+			expired = append(expired, key)
+		}
+	}
+	for key, rs := range m.removedSenders {
+		if time.Since(rs.removedAt) > removedSenderTTL {
+			delete(m.removedSenders, key)
+		}
+	}
+	m.lock.Unlock()
+
+	for _, key := range expired {
+		m.lock.RLock()
+		si := m.senders[key]
+		m.lock.RUnlock()
+		if si != nil {
+			m.params.Logger.Infow("relay expired", "trackID", key.trackID, "nodeID", key.nodeID)
+			m.removeSender(key, si.sender)
+		}
+	}
+}
+
+// isFromAddr checks that a packet originated from the host of a relay address
+func isFromAddr(from net.Addr, addr string) bool {
+	udpAddr, ok := from.(*net.UDPAddr)
+	if !ok {
+		return false
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	return udpAddr.IP.Equal(net.ParseIP(host))
+}
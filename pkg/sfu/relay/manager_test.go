@@ -0,0 +1,275 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relay
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+const testSecret = "relay secret shared by test nodes"
+
+var opusCodec = webrtc.RTPCodecParameters{
+	RTPCodecCapability: webrtc.RTPCodecCapability{
+		MimeType:  webrtc.MimeTypeOpus,
+		ClockRate: 48000,
+		Channels:  2,
+	},
+	PayloadType: 111,
+}
+
+type testNode struct {
+	node    *livekit.Node
+	manager *Manager
+}
+
+func newTestNodes(t *testing.T, originProvider func(trackID livekit.TrackID) sfu.TrackReceiver) (*testNode, *testNode) {
+	// nodes use the same relay port, so each one gets its own loopback address
+	probe, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.2")})
+	if err != nil {
+		t.Skip("second loopback address not available", err)
+	}
+	port := probe.LocalAddr().(*net.UDPAddr).Port
+	_ = probe.Close()
+
+	conf := config.RelayConfig{
+		Enabled: true,
+		Port:    uint32(port),
+	}
+	nodes := map[livekit.NodeID]*livekit.Node{
+		"ND_origin":     {Id: "ND_origin", Ip: "127.0.0.1"},
+		"ND_subscriber": {Id: "ND_subscriber", Ip: "127.0.0.2"},
+	}
+	newNode := func(nodeID livekit.NodeID, provider func(trackID livekit.TrackID) sfu.TrackReceiver) *testNode {
+		node := nodes[nodeID]
+		lgr := logger.GetLogger().WithValues("nodeID", nodeID)
+		m := NewManager(ManagerParams{
+			Config:        conf,
+			NodeID:        nodeID,
+			BufferFactory: buffer.NewFactoryOfBufferFactory(500, 200),
+			Transport: NewUDPTransport(UDPTransportParams{
+				BindAddress: node.Ip,
+				Port:        port,
+				Secret:      []byte(testSecret),
+				Logger:      lgr,
+			}),
+			Logger: lgr,
+			Secret: []byte(testSecret),
+			GetNode: func(nodeID livekit.NodeID) (*livekit.Node, error) {
+				return nodes[nodeID], nil
+			},
+			ReceiverProvider: provider,
+		})
+		require.NoError(t, m.Start())
+		t.Cleanup(m.Stop)
+		return &testNode{node: node, manager: m}
+	}
+
+	return newNode("ND_origin", originProvider), newNode("ND_subscriber", func(livekit.TrackID) sfu.TrackReceiver { return nil })
+}
+
+func TestManager(t *testing.T) {
+	trackInfo := &livekit.TrackInfo{
+		Sid:  "TR_audio",
+		Type: livekit.TrackType_AUDIO,
+	}
+
+	// the origin track is fed directly, standing in for a locally published track
+	var plis atomic.Int32
+	published := NewReceiver(ReceiverParams{
+		TrackInfo:     trackInfo,
+		Codec:         opusCodec,
+		BufferFactory: buffer.NewFactoryOfBufferFactory(500, 200).CreateBufferFactory(),
+		Logger:        logger.GetLogger(),
+		SendPLI: func(layer int32, force bool) {
+			plis.Inc()
+		},
+	})
+	t.Cleanup(published.Close)
+
+	origin, subscriber := newTestNodes(t, func(trackID livekit.TrackID) sfu.TrackReceiver {
+		if trackID == livekit.TrackID(trackInfo.Sid) {
+			return published
+		}
+		return nil
+	})
+
+	relayed, err := subscriber.manager.Subscribe(SubscribeParams{
+		OriginNode: origin.node,
+		TrackInfo:  trackInfo,
+		Codec:      opusCodec,
+	})
+	require.NoError(t, err)
+
+	sink := newTestSender("PA_sub")
+	require.NoError(t, relayed.AddDownTrack(sink))
+
+	key := senderKey{trackID: livekit.TrackID(trackInfo.Sid), nodeID: "ND_subscriber"}
+	require.Eventually(t, func() bool {
+		return published.NumDownTracks() == 1
+	}, 5*time.Second, 10*time.Millisecond, "origin did not start relaying")
+
+	t.Run("rtp delivery", func(t *testing.T) {
+		for sn := uint16(1); sn <= 10; sn++ {
+			published.HandlePacket(&Packet{
+				Type:    PacketTypeRTP,
+				TrackID: livekit.TrackID(trackInfo.Sid),
+				Payload: marshalRTP(t, sn),
+			})
+		}
+
+		require.Eventually(t, func() bool {
+			return sink.packets.Load() >= 9
+		}, 5*time.Second, 10*time.Millisecond, "relayed packets not delivered")
+	})
+
+	t.Run("pli forwarding", func(t *testing.T) {
+		relayed.SendPLI(0, true)
+
+		require.Eventually(t, func() bool {
+			return plis.Load() == 1
+		}, 5*time.Second, 10*time.Millisecond, "PLI not forwarded to origin")
+	})
+
+	t.Run("keepalive expiry", func(t *testing.T) {
+		origin.manager.lock.Lock()
+		si := origin.manager.senders[key]
+		require.NotNil(t, si)
+		si.lastSeen = time.Now().Add(-2 * senderTimeout)
+		origin.manager.lock.Unlock()
+
+		origin.manager.expireSenders()
+
+		require.True(t, si.sender.IsClosed())
+		require.Equal(t, 0, published.NumDownTracks())
+		origin.manager.lock.RLock()
+		require.NotContains(t, origin.manager.senders, key)
+		require.Contains(t, origin.manager.removedSenders, key)
+		origin.manager.lock.RUnlock()
+
+		// next keepalive re-establishes the relay, seeded from the expired sender
+		subscriber.manager.refreshReceivers()
+		require.Eventually(t, func() bool {
+			return published.NumDownTracks() == 1
+		}, 5*time.Second, 10*time.Millisecond, "relay not re-established")
+		origin.manager.lock.RLock()
+		require.NotContains(t, origin.manager.removedSenders, key)
+		origin.manager.lock.RUnlock()
+	})
+
+	t.Run("unauthenticated subscribe", func(t *testing.T) {
+		conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: int(origin.manager.params.Config.Port)})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		// correctly formed, but signed with the wrong secret
+		pkt := &Packet{
+			Type:    PacketTypeSubscribe,
+			TrackID: livekit.TrackID(trackInfo.Sid),
+			Payload: signControl([]byte("guess"), PacketTypeSubscribe, livekit.TrackID(trackInfo.Sid), "ND_attacker", time.Now()),
+		}
+		buf, err := pkt.Marshal()
+		require.NoError(t, err)
+		_, err = conn.Write(buf)
+		require.NoError(t, err)
+
+		time.Sleep(100 * time.Millisecond)
+		origin.manager.lock.RLock()
+		require.NotContains(t, origin.manager.senders, senderKey{trackID: livekit.TrackID(trackInfo.Sid), nodeID: "ND_attacker"})
+		origin.manager.lock.RUnlock()
+	})
+
+	t.Run("unsubscribe", func(t *testing.T) {
+		subscriber.manager.Unsubscribe(livekit.TrackID(trackInfo.Sid))
+		require.True(t, relayed.IsClosed())
+		require.True(t, sink.IsClosed())
+
+		require.Eventually(t, func() bool {
+			return published.NumDownTracks() == 0
+		}, 5*time.Second, 10*time.Millisecond, "origin did not stop relaying")
+	})
+}
+
+func TestManagerDisabled(t *testing.T) {
+	m := NewManager(ManagerParams{
+		Transport: NewUDPTransport(UDPTransportParams{Logger: logger.GetLogger()}),
+		Logger:    logger.GetLogger(),
+	})
+	require.ErrorIs(t, m.Start(), ErrRelayDisabled)
+
+	_, err := m.Subscribe(SubscribeParams{
+		OriginNode: &livekit.Node{Id: "ND_origin"},
+		TrackInfo:  &livekit.TrackInfo{Sid: "TR_audio"},
+	})
+	require.ErrorIs(t, err, ErrRelayDisabled)
+}
+
+func marshalRTP(t *testing.T, sn uint16) []byte {
+	pkt := rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    uint8(opusCodec.PayloadType),
+			SequenceNumber: sn,
+			Timestamp:      uint32(sn) * 960,
+			SSRC:           1234,
+		},
+		Payload: []byte{0xf8, 0xff, 0xfe},
+	}
+	buf, err := pkt.Marshal()
+	require.NoError(t, err)
+	return buf
+}
+
+// ------------------------------------------------
+
+type testSender struct {
+	subscriberID livekit.ParticipantID
+	packets      atomic.Int32
+	closed       atomic.Bool
+}
+
+func newTestSender(subscriberID livekit.ParticipantID) *testSender {
+	return &testSender{subscriberID: subscriberID}
+}
+
+func (s *testSender) UpTrackLayersChange()                           {}
+func (s *testSender) UpTrackBitrateAvailabilityChange()              {}
+func (s *testSender) UpTrackMaxPublishedLayerChange(_ int32)         {}
+func (s *testSender) UpTrackMaxTemporalLayerSeenChange(_ int32)      {}
+func (s *testSender) UpTrackSilencePause(_ bool)                     {}
+func (s *testSender) UpTrackBitrateReport(_ []int32, _ sfu.Bitrates) {}
+func (s *testSender) TrackInfoAvailable()                            {}
+func (s *testSender) Resync()                                        {}
+func (s *testSender) ID() string                                     { return string(s.subscriberID) }
+func (s *testSender) SubscriberID() livekit.ParticipantID            { return s.subscriberID }
+func (s *testSender) Close()                                         { s.closed.Store(true) }
+func (s *testSender) IsClosed() bool                                 { return s.closed.Load() }
+func (s *testSender) WriteRTP(_ *buffer.ExtPacket, _ int32) error    { s.packets.Inc(); return nil }
+func (s *testSender) HandleRTCPSenderReportData(_ webrtc.PayloadType, _ bool, _ int32, _ *buffer.RTCPSenderReportData) error {
+	return nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relay
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+const (
+	packetVersion = 1
+
+	// version + type + layer + track id length
+	packetHeaderSize = 4
+
+	senderReportSize = 20
+)
+
+var (
+	ErrPacketTooShort     = errors.New("relay packet too short")
+	ErrUnsupportedVersion = errors.New("unsupported relay packet version")
+	ErrTrackIDTooLong     = errors.New("track id too long")
+)
+
+type PacketType uint8
+
+const (
+	PacketTypeRTP PacketType = iota + 1
+	PacketTypeSenderReport
+	PacketTypePLI
+	PacketTypeSubscribe
+	PacketTypeUnsubscribe
+)
+
+func (p PacketType) String() string {
+	switch p {
+	case PacketTypeRTP:
+		return "RTP"
+	case PacketTypeSenderReport:
+		return "SENDER_REPORT"
+	case PacketTypePLI:
+		return "PLI"
+	case PacketTypeSubscribe:
+		return "SUBSCRIBE"
+	case PacketTypeUnsubscribe:
+		return "UNSUBSCRIBE"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Packet is the unit exchanged between nodes relaying media of a track.
+//
+// Wire format:
+//
+//	0                   1                   2                   3
+//	0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|    version    |     type      |     layer     | track id len  |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                   track id ...  |         payload ...         |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+type Packet struct {
+	Type    PacketType
+	Layer   int32
+	TrackID livekit.TrackID
+	Payload []byte
+}
+
+func (p *Packet) MarshalSize() int {
+	return packetHeaderSize + len(p.TrackID) + len(p.Payload)
+}
+
+func (p *Packet) Marshal() ([]byte, error) {
+	buf := make([]byte, p.MarshalSize())
+	n, err := p.MarshalTo(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+func (p *Packet) MarshalTo(buf []byte) (int, error) {
+	if len(p.TrackID) > 255 {
+		return 0, ErrTrackIDTooLong
+	}
+	if len(buf) < p.MarshalSize() {
+		return 0, ErrPacketTooShort
+	}
+
+	buf[0] = packetVersion
+	buf[1] = byte(p.Type)
+	buf[2] = byte(int8(p.Layer))
+	buf[3] = byte(len(p.TrackID))
+	n := packetHeaderSize
+	n += copy(buf[n:], p.TrackID)
+	n += copy(buf[n:], p.Payload)
+	return n, nil
+}
+
+// Unmarshal parses a relay packet, Payload references the given buffer
+func (p *Packet) Unmarshal(buf []byte) error {
+	if len(buf) < packetHeaderSize {
+		return ErrPacketTooShort
+	}
+	if buf[0] != packetVersion {
+		return ErrUnsupportedVersion
+	}
+
+	trackIDLen := int(buf[3])
+	if len(buf) < packetHeaderSize+trackIDLen {
+		return ErrPacketTooShort
+	}
+
+	p.Type = PacketType(buf[1])
+	p.Layer = int32(int8(buf[2]))
+	p.TrackID = livekit.TrackID(buf[packetHeaderSize : packetHeaderSize+trackIDLen])
+	p.Payload = buf[packetHeaderSize+trackIDLen:]
+	return nil
+}
+
+// ------------------------------------------------
+
+func marshalSenderReport(srData *buffer.RTCPSenderReportData) []byte {
+	buf := make([]byte, senderReportSize)
+	binary.BigEndian.PutUint32(buf[0:], srData.RTPTimestamp)
+	binary.BigEndian.PutUint64(buf[4:], uint64(srData.NTPTimestamp))
+	binary.BigEndian.PutUint32(buf[12:], srData.Packets)
+	binary.BigEndian.PutUint32(buf[16:], srData.Octets)
+	return buf
+}
+
+func unmarshalSenderReport(buf []byte) (rtpTime uint32, ntpTime uint64, packets uint32, octets uint32, err error) {
+	if len(buf) < senderReportSize {
+		err = ErrPacketTooShort
+		return
+	}
+
+	rtpTime = binary.BigEndian.Uint32(buf[0:])
+	ntpTime = binary.BigEndian.Uint64(buf[4:])
+	packets = binary.BigEndian.Uint32(buf[12:])
+	octets = binary.BigEndian.Uint32(buf[16:])
+	return
+}
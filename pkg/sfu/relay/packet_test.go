@@ -0,0 +1,142 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relay
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/mediatransportutil"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+func TestPacket(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		pkt := &Packet{
+			Type:    PacketTypeRTP,
+			Layer:   2,
+			TrackID: "TR_video",
+			Payload: []byte{1, 2, 3, 4, 5},
+		}
+		buf, err := pkt.Marshal()
+		require.NoError(t, err)
+		require.Len(t, buf, pkt.MarshalSize())
+
+		var decoded Packet
+		require.NoError(t, decoded.Unmarshal(buf))
+		require.Equal(t, *pkt, decoded)
+	})
+
+	t.Run("negative layer", func(t *testing.T) {
+		pkt := &Packet{Type: PacketTypePLI, Layer: -1, TrackID: "TR_video"}
+		buf, err := pkt.Marshal()
+		require.NoError(t, err)
+
+		var decoded Packet
+		require.NoError(t, decoded.Unmarshal(buf))
+		require.Equal(t, int32(-1), decoded.Layer)
+		require.Empty(t, decoded.Payload)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		pkt := &Packet{Type: PacketTypeRTP, TrackID: "TR_video", Payload: []byte{1}}
+		buf, err := pkt.Marshal()
+		require.NoError(t, err)
+
+		var decoded Packet
+		require.ErrorIs(t, decoded.Unmarshal(buf[:packetHeaderSize-1]), ErrPacketTooShort)
+		require.ErrorIs(t, decoded.Unmarshal(buf[:packetHeaderSize+2]), ErrPacketTooShort)
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		pkt := &Packet{Type: PacketTypeRTP, TrackID: "TR_video"}
+		buf, err := pkt.Marshal()
+		require.NoError(t, err)
+		buf[0] = packetVersion + 1
+
+		var decoded Packet
+		require.ErrorIs(t, decoded.Unmarshal(buf), ErrUnsupportedVersion)
+	})
+
+	t.Run("track id too long", func(t *testing.T) {
+		pkt := &Packet{Type: PacketTypeRTP, TrackID: livekit.TrackID(strings.Repeat("a", 256))}
+		_, err := pkt.Marshal()
+		require.ErrorIs(t, err, ErrTrackIDTooLong)
+
+		pkt.TrackID = livekit.TrackID(strings.Repeat("a", 255))
+		_, err = pkt.Marshal()
+		require.NoError(t, err)
+	})
+}
+
+func TestSenderReport(t *testing.T) {
+	srData := &buffer.RTCPSenderReportData{
+		RTPTimestamp: 0xdeadbeef,
+		NTPTimestamp: mediatransportutil.ToNtpTime(time.Now()),
+		Packets:      1234,
+		Octets:       567890,
+	}
+	buf := marshalSenderReport(srData)
+	require.Len(t, buf, senderReportSize)
+
+	rtpTime, ntpTime, packets, octets, err := unmarshalSenderReport(buf)
+	require.NoError(t, err)
+	require.Equal(t, srData.RTPTimestamp, rtpTime)
+	require.Equal(t, uint64(srData.NTPTimestamp), ntpTime)
+	require.Equal(t, srData.Packets, packets)
+	require.Equal(t, srData.Octets, octets)
+
+	_, _, _, _, err = unmarshalSenderReport(buf[:senderReportSize-1])
+	require.ErrorIs(t, err, ErrPacketTooShort)
+}
+
+func TestControlSignature(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Now()
+	pkt := &Packet{
+		Type:    PacketTypeSubscribe,
+		TrackID: "TR_video",
+		Payload: signControl(secret, PacketTypeSubscribe, "TR_video", "ND_a", now),
+	}
+
+	nodeID, err := verifyControl(secret, pkt, now)
+	require.NoError(t, err)
+	require.Equal(t, livekit.NodeID("ND_a"), nodeID)
+
+	_, err = verifyControl([]byte("other"), pkt, now)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	// signature covers the packet type and track
+	tampered := *pkt
+	tampered.Type = PacketTypeUnsubscribe
+	_, err = verifyControl(secret, &tampered, now)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	tampered = *pkt
+	tampered.TrackID = "TR_other"
+	_, err = verifyControl(secret, &tampered, now)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = verifyControl(secret, pkt, now.Add(2*controlMaxSkew))
+	require.ErrorIs(t, err, ErrStaleControl)
+
+	_, err = verifyControl(secret, &Packet{Type: PacketTypeSubscribe, Payload: []byte{1}}, now)
+	require.ErrorIs(t, err, ErrPacketTooShort)
+}
@@ -0,0 +1,456 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relay
+
+import (
+	"sync"
+
+	"github.com/pion/rtp"
+	"github.com/pion/transport/v2/packetio"
+	"github.com/pion/webrtc/v3"
+	"go.uber.org/atomic"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/mediatransportutil/pkg/bucket"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+type ReceiverParams struct {
+	TrackInfo        *livekit.TrackInfo
+	Codec            webrtc.RTPCodecParameters
+	HeaderExtensions []webrtc.RTPHeaderExtensionParameter
+	OriginNodeID     livekit.NodeID
+	BufferFactory    *buffer.Factory
+	TrackersConfig   config.StreamTrackersConfig
+	Logger           logger.Logger
+
+	// used to request key frames from the origin node
+	SendPLI func(layer int32, force bool)
+	OnClose func()
+}
+
+// Receiver is the remote node end of a relayed track. It is fed packets from the relay
+// transport and presents itself as a regular track receiver to local subscribers.
+type Receiver struct {
+	params ReceiverParams
+
+	trackInfo atomic.Pointer[livekit.TrackInfo]
+	isSVC     bool
+
+	bufferMu sync.RWMutex
+	buffers  [buffer.DefaultMaxLayerSpatial + 1]*buffer.Buffer
+
+	streamTrackerManager *sfu.StreamTrackerManager
+	downTrackSpreader    *sfu.DownTrackSpreader
+
+	closeOnce sync.Once
+	closed    atomic.Bool
+}
+
+func NewReceiver(params ReceiverParams) *Receiver {
+	r := &Receiver{
+		params: params,
+		isSVC:  buffer.IsSvcCodec(params.Codec.MimeType),
+	}
+	r.trackInfo.Store(proto.Clone(params.TrackInfo).(*livekit.TrackInfo))
+
+	r.downTrackSpreader = sfu.NewDownTrackSpreader(sfu.DownTrackSpreaderParams{
+		Logger: params.Logger,
+	})
+
+	r.streamTrackerManager = sfu.NewStreamTrackerManager(params.Logger, params.TrackInfo, r.isSVC, params.Codec.ClockRate, params.TrackersConfig)
+	r.streamTrackerManager.SetListener(r)
+	return r
+}
+
+func (r *Receiver) OriginNodeID() livekit.NodeID {
+	return r.params.OriginNodeID
+}
+
+// HandlePacket processes a packet received from the origin node
+func (r *Receiver) HandlePacket(pkt *Packet) {
+	if r.closed.Load() {
+		return
+	}
+
+	switch pkt.Type {
+	case PacketTypeRTP:
+		buff, err := r.getOrCreateBuffer(pkt.Layer, pkt.Payload)
+		if err != nil {
+			r.params.Logger.Debugw("could not get relay buffer", "error", err, "layer", pkt.Layer)
+			return
+		}
+		_, _ = buff.Write(pkt.Payload)
+
+	case PacketTypeSenderReport:
+		buff := r.getBuffer(pkt.Layer)
+		if buff == nil {
+			return
+		}
+		rtpTime, ntpTime, packets, octets, err := unmarshalSenderReport(pkt.Payload)
+		if err != nil {
+			return
+		}
+		buff.SetSenderReportData(rtpTime, ntpTime, packets, octets)
+	}
+}
+
+func (r *Receiver) getOrCreateBuffer(layer int32, raw []byte) (*buffer.Buffer, error) {
+	if r.isSVC {
+		layer = 0
+	}
+	if layer < 0 || int(layer) >= len(r.buffers) {
+		return nil, sfu.ErrBufferNotFound
+	}
+
+	r.bufferMu.RLock()
+	buff := r.buffers[layer]
+	r.bufferMu.RUnlock()
+	if buff != nil {
+		return buff, nil
+	}
+
+	var hdr rtp.Header
+	if _, err := hdr.Unmarshal(raw); err != nil {
+		return nil, err
+	}
+
+	r.bufferMu.Lock()
+	if r.buffers[layer] != nil {
+		buff = r.buffers[layer]
+		r.bufferMu.Unlock()
+		return buff, nil
+	}
+
+	buff = r.params.BufferFactory.GetOrNew(packetio.RTPBufferPacket, hdr.SSRC).(*buffer.Buffer)
+	buff.SetLogger(r.params.Logger.WithValues("layer", layer))
+	codec := r.params.Codec
+	// retransmissions are not relayed, disable NACK generation on the relayed stream
+	codec.RTCPFeedback = nil
+	buff.Bind(webrtc.RTPParameters{
+		HeaderExtensions: r.params.HeaderExtensions,
+		Codecs:           []webrtc.RTPCodecParameters{codec},
+	}, codec.RTPCodecCapability, 0)
+	buff.OnRtcpSenderReport(func() {
+		srData := buff.GetSenderReportData()
+		r.downTrackSpreader.Broadcast(func(dt sfu.TrackSender) {
+			_ = dt.HandleRTCPSenderReportData(r.params.Codec.PayloadType, r.isSVC, layer, srData)
+		})
+	})
+	r.buffers[layer] = buff
+	r.bufferMu.Unlock()
+
+	if r.Kind() == webrtc.RTPCodecTypeVideo {
+		r.streamTrackerManager.AddTracker(layer)
+	}
+
+	go r.forwardRTP(layer, buff)
+	return buff, nil
+}
+
+func (r *Receiver) getBuffer(layer int32) *buffer.Buffer {
+	if r.isSVC {
+		layer = 0
+	}
+	if layer < 0 || int(layer) >= len(r.buffers) {
+		return nil
+	}
+
+	r.bufferMu.RLock()
+	defer r.bufferMu.RUnlock()
+
+	return r.buffers[layer]
+}
+
+func (r *Receiver) forwardRTP(layer int32, buff *buffer.Buffer) {
+	pktBuf := make([]byte, bucket.MaxPktSize)
+	tracker := r.streamTrackerManager.GetTracker(layer)
+
+	defer r.streamTrackerManager.RemoveTracker(layer)
+
+	for {
+		pkt, err := buff.ReadExtended(pktBuf)
+		if err != nil {
+			return
+		}
+
+		spatialTracker := tracker
+		spatialLayer := layer
+		if pkt.Spatial >= 0 {
+			spatialLayer = pkt.Spatial
+			spatialTracker = r.streamTrackerManager.GetTracker(pkt.Spatial)
+			if spatialTracker == nil {
+				spatialTracker = r.streamTrackerManager.AddTracker(pkt.Spatial)
+			}
+		}
+
+		r.downTrackSpreader.Broadcast(func(dt sfu.TrackSender) {
+			_ = dt.WriteRTP(pkt, spatialLayer)
+		})
+
+		if spatialTracker != nil {
+			spatialTracker.Observe(
+				pkt.Temporal,
+				len(pkt.RawPacket),
+				len(pkt.Packet.Payload),
+				pkt.Packet.Marker,
+				pkt.Packet.Timestamp,
+				pkt.DependencyDescriptor,
+			)
+		}
+	}
+}
+
+func (r *Receiver) Close() {
+	r.closeOnce.Do(func() {
+		r.closed.Store(true)
+
+		r.bufferMu.Lock()
+		buffers := r.buffers
+		r.buffers = [buffer.DefaultMaxLayerSpatial + 1]*buffer.Buffer{}
+		r.bufferMu.Unlock()
+		for _, buff := range buffers {
+			if buff != nil {
+				_ = buff.Close()
+			}
+		}
+
+		r.streamTrackerManager.Close()
+
+		downTracks := r.downTrackSpreader.ResetAndGetDownTracks()
+		for _, dt := range downTracks {
+			dt.Close()
+		}
+
+		if r.params.OnClose != nil {
+			r.params.OnClose()
+		}
+	})
+}
+
+func (r *Receiver) Kind() webrtc.RTPCodecType {
+	if r.TrackInfo().Type == livekit.TrackType_AUDIO {
+		return webrtc.RTPCodecTypeAudio
+	}
+	return webrtc.RTPCodecTypeVideo
+}
+
+// ------------------------------------------------
+// sfu.TrackReceiver
+
+func (r *Receiver) TrackID() livekit.TrackID {
+	return livekit.TrackID(r.TrackInfo().Sid)
+}
+
+func (r *Receiver) StreamID() string {
+	return r.TrackInfo().Stream
+}
+
+func (r *Receiver) Codec() webrtc.RTPCodecParameters {
+	return r.params.Codec
+}
+
+func (r *Receiver) HeaderExtensions() []webrtc.RTPHeaderExtensionParameter {
+	return r.params.HeaderExtensions
+}
+
+func (r *Receiver) IsClosed() bool {
+	return r.closed.Load()
+}
+
+func (r *Receiver) ReadRTP(buf []byte, layer uint8, esn uint64) (int, error) {
+	b := r.getBuffer(int32(layer))
+	if b == nil {
+		return 0, sfu.ErrBufferNotFound
+	}
+
+	return b.GetPacket(buf, esn)
+}
+
+func (r *Receiver) GetLayeredBitrate() ([]int32, sfu.Bitrates) {
+	return r.streamTrackerManager.GetLayeredBitrate()
+}
+
+func (r *Receiver) GetAudioLevel() (float64, bool) {
+	if r.Kind() == webrtc.RTPCodecTypeVideo {
+		return 0, false
+	}
+
+	if b := r.getBuffer(0); b != nil {
+		return b.GetAudioLevel()
+	}
+	return 0, false
+}
+
+func (r *Receiver) SendPLI(layer int32, force bool) {
+	if r.params.SendPLI != nil {
+		r.params.SendPLI(layer, force)
+	}
+}
+
+func (r *Receiver) SetUpTrackPaused(paused bool) {
+	r.streamTrackerManager.SetPaused(paused)
+
+	r.bufferMu.RLock()
+	for _, buff := range r.buffers {
+		if buff != nil {
+			buff.SetPaused(paused)
+		}
+	}
+	r.bufferMu.RUnlock()
+}
+
+func (r *Receiver) SetMaxExpectedSpatialLayer(layer int32) {
+	r.streamTrackerManager.SetMaxExpectedSpatialLayer(layer)
+}
+
+func (r *Receiver) AddDownTrack(track sfu.TrackSender) error {
+	if r.closed.Load() {
+		return sfu.ErrReceiverClosed
+	}
+
+	track.TrackInfoAvailable()
+	track.UpTrackMaxPublishedLayerChange(r.streamTrackerManager.GetMaxPublishedLayer())
+	track.UpTrackMaxTemporalLayerSeenChange(r.streamTrackerManager.GetMaxTemporalLayerSeen())
+
+	r.downTrackSpreader.Store(track)
+	r.params.Logger.Debugw("relay downtrack added", "subscriberID", track.SubscriberID())
+	return nil
+}
+
+func (r *Receiver) DeleteDownTrack(subscriberID livekit.ParticipantID) {
+	if r.closed.Load() {
+		return
+	}
+
+	r.downTrackSpreader.Free(subscriberID)
+	r.params.Logger.Debugw("relay downtrack deleted", "subscriberID", subscriberID)
+}
+
+func (r *Receiver) NumDownTracks() int {
+	return r.downTrackSpreader.DownTrackCount()
+}
+
+func (r *Receiver) DebugInfo() map[string]interface{} {
+	return map[string]interface{}{
+		"Relay":        true,
+		"OriginNodeID": r.params.OriginNodeID,
+		"SVC":          r.isSVC,
+		"DownTracks":   r.downTrackSpreader.DownTrackCount(),
+	}
+}
+
+func (r *Receiver) TrackInfo() *livekit.TrackInfo {
+	return r.trackInfo.Load()
+}
+
+func (r *Receiver) UpdateTrackInfo(ti *livekit.TrackInfo) {
+	r.trackInfo.Store(proto.Clone(ti).(*livekit.TrackInfo))
+	r.streamTrackerManager.UpdateTrackInfo(ti)
+}
+
+func (r *Receiver) GetPrimaryReceiverForRed() sfu.TrackReceiver {
+	return r
+}
+
+func (r *Receiver) GetRedReceiver() sfu.TrackReceiver {
+	return r
+}
+
+func (r *Receiver) GetTemporalLayerFpsForSpatial(layer int32) []float32 {
+	b := r.getBuffer(layer)
+	if b == nil {
+		return nil
+	}
+
+	if !r.isSVC {
+		return b.GetTemporalLayerFpsForSpatial(0)
+	}
+	return b.GetTemporalLayerFpsForSpatial(layer)
+}
+
+func (r *Receiver) GetTrackStats() *livekit.RTPStats {
+	r.bufferMu.RLock()
+	defer r.bufferMu.RUnlock()
+
+	stats := make([]*livekit.RTPStats, 0, len(r.buffers))
+	for _, buff := range r.buffers {
+		if buff == nil {
+			continue
+		}
+
+		if s := buff.GetStats(); s != nil {
+			stats = append(stats, s)
+		}
+	}
+	return buffer.AggregateRTPStats(stats)
+}
+
+func (r *Receiver) GetTrackStatsWithLayers() *buffer.RTPStatsWithLayers {
+	r.bufferMu.RLock()
+	defer r.bufferMu.RUnlock()
+
+	layers := make(map[int32]*livekit.RTPStats, len(r.buffers))
+	for layer, buff := range r.buffers {
+		if buff == nil {
+			continue
+		}
+
+		if s := buff.GetStats(); s != nil {
+			layers[int32(layer)] = s
+		}
+	}
+	return buffer.NewRTPStatsWithLayers(layers)
+}
+
+// ------------------------------------------------
+// sfu.StreamTrackerManagerListener
+
+func (r *Receiver) OnAvailableLayersChanged() {
+	r.downTrackSpreader.Broadcast(func(dt sfu.TrackSender) {
+		dt.UpTrackLayersChange()
+	})
+}
+
+func (r *Receiver) OnBitrateAvailabilityChanged() {
+	r.downTrackSpreader.Broadcast(func(dt sfu.TrackSender) {
+		dt.UpTrackBitrateAvailabilityChange()
+	})
+}
+
+func (r *Receiver) OnMaxPublishedLayerChanged(maxPublishedLayer int32) {
+	r.downTrackSpreader.Broadcast(func(dt sfu.TrackSender) {
+		dt.UpTrackMaxPublishedLayerChange(maxPublishedLayer)
+	})
+}
+
+func (r *Receiver) OnMaxTemporalLayerSeenChanged(maxTemporalLayerSeen int32) {
+	r.downTrackSpreader.Broadcast(func(dt sfu.TrackSender) {
+		dt.UpTrackMaxTemporalLayerSeenChange(maxTemporalLayerSeen)
+	})
+}
+
+func (r *Receiver) OnMaxAvailableLayerChanged(_maxAvailableLayer int32) {}
+
+func (r *Receiver) OnBitrateReport(availableLayers []int32, bitrates sfu.Bitrates) {
+	r.downTrackSpreader.Broadcast(func(dt sfu.TrackSender) {
+		dt.UpTrackBitrateReport(availableLayers, bitrates)
+	})
+}
@@ -0,0 +1,185 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relay
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pion/webrtc/v3"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+const subscriberIDPrefix = "RELAY_"
+
+var ErrSenderClosed = errors.New("relay sender closed")
+
+// SubscriberIDForNode returns the down track key used for relaying to a node,
+// keying on the node ensures a single copy of a track is sent to a remote node
+// irrespective of the number of subscribers on that node.
+func SubscriberIDForNode(nodeID livekit.NodeID) livekit.ParticipantID {
+	return livekit.ParticipantID(subscriberIDPrefix + string(nodeID))
+}
+
+type SenderParams struct {
+	TrackID   livekit.TrackID
+	NodeID    livekit.NodeID
+	Addr      string
+	ClockRate uint32
+	Transport Transport
+	Logger    logger.Logger
+	OnClose   func()
+}
+
+// Sender forwards packets of a local track to a remote node, it is attached to the
+// track receiver like any other down track.
+type Sender struct {
+	params SenderParams
+
+	rtpStats [buffer.DefaultMaxLayerSpatial + 1]*buffer.RTPStatsSender
+	closed   atomic.Bool
+}
+
+func NewSender(params SenderParams) *Sender {
+	s := &Sender{
+		params: params,
+	}
+	for i := range s.rtpStats {
+		s.rtpStats[i] = buffer.NewRTPStatsSender(buffer.RTPStatsParams{
+			ClockRate: params.ClockRate,
+			Logger:    params.Logger.WithValues("layer", i),
+		})
+	}
+	return s
+}
+
+// Seed carries over stats of a previous sender to the same node so that counters are continuous
+// when a relay is re-established.
+func (s *Sender) Seed(from *Sender) {
+	if from == nil {
+		return
+	}
+
+	for i := range s.rtpStats {
+		s.rtpStats[i].Seed(from.rtpStats[i])
+	}
+}
+
+func (s *Sender) NodeID() livekit.NodeID {
+	return s.params.NodeID
+}
+
+func (s *Sender) UpTrackLayersChange() {}
+
+func (s *Sender) UpTrackBitrateAvailabilityChange() {}
+
+func (s *Sender) UpTrackMaxPublishedLayerChange(_maxPublishedLayer int32) {}
+
+func (s *Sender) UpTrackMaxTemporalLayerSeenChange(_maxTemporalLayerSeen int32) {}
+
+func (s *Sender) UpTrackSilencePause(_paused bool) {}
+
+func (s *Sender) UpTrackBitrateReport(_availableLayers []int32, _bitrates sfu.Bitrates) {}
+
+func (s *Sender) WriteRTP(p *buffer.ExtPacket, layer int32) error {
+	if s.closed.Load() {
+		return ErrSenderClosed
+	}
+
+	err := s.params.Transport.Send(s.params.Addr, &Packet{
+		Type:    PacketTypeRTP,
+		Layer:   layer,
+		TrackID: s.params.TrackID,
+		Payload: p.RawPacket,
+	})
+	if err != nil {
+		return err
+	}
+
+	if layer >= 0 && int(layer) < len(s.rtpStats) {
+		hdrSize := p.Packet.Header.MarshalSize()
+		payloadSize := len(p.RawPacket) - hdrSize
+		if len(p.Packet.Payload) == 0 {
+			s.rtpStats[layer].Update(p.Arrival, p.ExtSequenceNumber, p.ExtTimestamp, p.Packet.Marker, hdrSize, 0, payloadSize)
+		} else {
+			s.rtpStats[layer].Update(p.Arrival, p.ExtSequenceNumber, p.ExtTimestamp, p.Packet.Marker, hdrSize, payloadSize, 0)
+		}
+	}
+	return nil
+}
+
+func (s *Sender) Close() {
+	if s.closed.Swap(true) {
+		return
+	}
+
+	for _, rtpStats := range s.rtpStats {
+		rtpStats.Stop()
+	}
+
+	if s.params.OnClose != nil {
+		s.params.OnClose()
+	}
+}
+
+func (s *Sender) IsClosed() bool {
+	return s.closed.Load()
+}
+
+func (s *Sender) ID() string {
+	return fmt.Sprintf("%s%s_%s", subscriberIDPrefix, s.params.NodeID, s.params.TrackID)
+}
+
+func (s *Sender) SubscriberID() livekit.ParticipantID {
+	return SubscriberIDForNode(s.params.NodeID)
+}
+
+func (s *Sender) TrackInfoAvailable() {}
+
+func (s *Sender) HandleRTCPSenderReportData(
+	_payloadType webrtc.PayloadType,
+	_isSVC bool,
+	layer int32,
+	publisherSRData *buffer.RTCPSenderReportData,
+) error {
+	if s.closed.Load() || publisherSRData == nil {
+		return nil
+	}
+
+	return s.params.Transport.Send(s.params.Addr, &Packet{
+		Type:    PacketTypeSenderReport,
+		Layer:   layer,
+		TrackID: s.params.TrackID,
+		Payload: marshalSenderReport(publisherSRData),
+	})
+}
+
+func (s *Sender) Resync() {}
+
+func (s *Sender) GetTrackStats() *livekit.RTPStats {
+	stats := make([]*livekit.RTPStats, 0, len(s.rtpStats))
+	for _, rtpStats := range s.rtpStats {
+		if rtpStats.IsActive() {
+			stats = append(stats, rtpStats.ToProto())
+		}
+	}
+	return buffer.AggregateRTPStats(stats)
+}
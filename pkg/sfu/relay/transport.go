@@ -0,0 +1,185 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relay
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"go.uber.org/atomic"
+
+	"github.com/livekit/mediatransportutil/pkg/bucket"
+	"github.com/livekit/protocol/logger"
+)
+
+var ErrTransportClosed = errors.New("relay transport closed")
+
+// Transport moves relay packets between nodes
+type Transport interface {
+	Start() error
+	Close()
+
+	LocalAddr() net.Addr
+	Send(addr string, pkt *Packet) error
+	OnPacket(fn func(from net.Addr, pkt *Packet))
+}
+
+// ------------------------------------------------
+
+type UDPTransportParams struct {
+	BindAddress string
+	Port        int
+	// secret shared by all nodes, packets are encrypted and authenticated with a key derived from it
+	Secret []byte
+	Logger logger.Logger
+}
+
+type UDPTransport struct {
+	params UDPTransportParams
+
+	conn   *net.UDPConn
+	cipher *packetCipher
+	closed atomic.Bool
+
+	lock     sync.RWMutex
+	addrs    map[string]*net.UDPAddr
+	onPacket func(from net.Addr, pkt *Packet)
+}
+
+func NewUDPTransport(params UDPTransportParams) *UDPTransport {
+	return &UDPTransport{
+		params: params,
+		addrs:  make(map[string]*net.UDPAddr),
+	}
+}
+
+func (t *UDPTransport) Start() error {
+	c, err := newPacketCipher(t.params.Secret)
+	if err != nil {
+		return err
+	}
+	t.cipher = c
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{
+		IP:   net.ParseIP(t.params.BindAddress),
+		Port: t.params.Port,
+	})
+	if err != nil {
+		return err
+	}
+	t.conn = conn
+
+	t.params.Logger.Infow("relay transport listening", "addr", conn.LocalAddr())
+	go t.readWorker()
+	return nil
+}
+
+func (t *UDPTransport) Close() {
+	if t.closed.Swap(true) {
+		return
+	}
+
+	if t.conn != nil {
+		_ = t.conn.Close()
+	}
+}
+
+func (t *UDPTransport) LocalAddr() net.Addr {
+	if t.conn == nil {
+		return nil
+	}
+	return t.conn.LocalAddr()
+}
+
+func (t *UDPTransport) OnPacket(fn func(from net.Addr, pkt *Packet)) {
+	t.lock.Lock()
+	t.onPacket = fn
+	t.lock.Unlock()
+}
+
+func (t *UDPTransport) getOnPacket() func(from net.Addr, pkt *Packet) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.onPacket
+}
+
+func (t *UDPTransport) Send(addr string, pkt *Packet) error {
+	if t.closed.Load() || t.conn == nil {
+		return ErrTransportClosed
+	}
+
+	udpAddr, err := t.resolve(addr)
+	if err != nil {
+		return err
+	}
+
+	buf, err := pkt.Marshal()
+	if err != nil {
+		return err
+	}
+
+	_, err = t.conn.WriteToUDP(t.cipher.Seal(make([]byte, 0, len(buf)+t.cipher.Overhead()), buf), udpAddr)
+	return err
+}
+
+func (t *UDPTransport) resolve(addr string) (*net.UDPAddr, error) {
+	t.lock.RLock()
+	udpAddr := t.addrs[addr]
+	t.lock.RUnlock()
+	if udpAddr != nil {
+		return udpAddr, nil
+	}
+
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	t.lock.Lock()
+	t.addrs[addr] = udpAddr
+	t.lock.Unlock()
+	return udpAddr, nil
+}
+
+func (t *UDPTransport) readWorker() {
+	buf := make([]byte, bucket.MaxPktSize+packetHeaderSize+255+t.cipher.Overhead())
+	for {
+		n, from, err := t.conn.ReadFromUDP(buf)
+		if err != nil {
+			if !t.closed.Load() {
+				t.params.Logger.Warnw("relay transport read failed", err)
+			}
+			return
+		}
+
+		// packets that are not sealed with the shared secret are dropped before parsing
+		plaintext, err := t.cipher.Open(from.String(), buf[:n])
+		if err != nil {
+			t.params.Logger.Debugw("dropping unauthenticated relay packet", "error", err, "from", from)
+			continue
+		}
+
+		var pkt Packet
+		if err := pkt.Unmarshal(plaintext); err != nil {
+			t.params.Logger.Debugw("dropping invalid relay packet", "error", err, "from", from)
+			continue
+		}
+		if onPacket := t.getOnPacket(); onPacket != nil {
+			onPacket(from, &pkt)
+		}
+	}
+}
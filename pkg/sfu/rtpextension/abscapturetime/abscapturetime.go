@@ -73,6 +73,10 @@ func AbsCaptureTimeFromValue(absoluteCaptureTimestamp uint64, estimatedCaptureCl
 	}
 }
 
+func (a *AbsCaptureTime) CaptureTime() mediatransportutil.NtpTime {
+	return a.absoluteCaptureTimestamp
+}
+
 func (a *AbsCaptureTime) Rewrite(offset time.Duration) error {
 	if a.absoluteCaptureTimestamp == 0 {
 		return errInvalidData
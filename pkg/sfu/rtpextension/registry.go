@@ -0,0 +1,228 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtpextension
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3"
+
+	act "github.com/livekit/livekit-server/pkg/sfu/rtpextension/abscapturetime"
+	dd "github.com/livekit/livekit-server/pkg/sfu/rtpextension/dependencydescriptor"
+	pd "github.com/livekit/livekit-server/pkg/sfu/rtpextension/playoutdelay"
+)
+
+const VideoOrientationURI = "urn:3gpp:video-orientation"
+
+// Policy is how a header extension is handled in one direction
+type Policy string
+
+const (
+	// not negotiated
+	PolicyStrip Policy = "strip"
+	// negotiated, the value received from the publisher is sent to subscribers as is
+	PolicyForward Policy = "forward"
+	// negotiated, the value received from the publisher is sent to subscribers through the Rewrite function.
+	// subscriber direction only
+	PolicyRewrite Policy = "rewrite"
+)
+
+// Direction of media a policy applies to
+type Direction int
+
+const (
+	// from publishers to the server
+	DirectionPublisher Direction = iota
+	// from the server to subscribers
+	DirectionSubscriber
+)
+
+var (
+	ErrHeaderExtensionURIRequired = errors.New("header extension URI is required")
+	ErrHeaderExtensionNoKind      = errors.New("header extension must apply to audio or video")
+)
+
+// URIs of the extensions the server handles itself, they cannot be declared
+var builtinURIs = []string{
+	sdp.SDESMidURI,
+	sdp.SDESRTPStreamIDURI,
+	sdp.AudioLevelURI,
+	sdp.TransportCCURI,
+	sdp.ABSSendTimeURI,
+	"urn:ietf:params:rtp-hdrext:framemarking",
+	"urn:ietf:params:rtp-hdrext:sdes:repaired-rtp-stream-id",
+	dd.ExtensionURI,
+	pd.PlayoutDelayURI,
+	act.AbsCaptureTimeURI,
+}
+
+// RewriteFunc returns the value of a header extension sent to a subscriber, from the value received from the publisher
+type RewriteFunc func(payload []byte) ([]byte, error)
+
+// HeaderExtension declares an RTP header extension the server negotiates and passes between publishers and subscribers
+type HeaderExtension struct {
+	URI   string
+	Audio bool
+	Video bool
+	// defaults to strip
+	Publisher  Policy
+	Subscriber Policy
+	// required with the rewrite policy
+	Rewrite RewriteFunc
+}
+
+func (e HeaderExtension) Validate() error {
+	if e.URI == "" {
+		return ErrHeaderExtensionURIRequired
+	}
+	if slices.Contains(builtinURIs, e.URI) {
+		return fmt.Errorf("header extension %s is handled by the server", e.URI)
+	}
+	if !e.Audio && !e.Video {
+		return fmt.Errorf("%w: %s", ErrHeaderExtensionNoKind, e.URI)
+	}
+	switch e.Publisher {
+	case "", PolicyStrip, PolicyForward:
+	default:
+		return fmt.Errorf("invalid publisher policy %q of header extension %s", e.Publisher, e.URI)
+	}
+	switch e.Subscriber {
+	case "", PolicyStrip, PolicyForward:
+	case PolicyRewrite:
+		if e.Rewrite == nil {
+			return fmt.Errorf("header extension %s with rewrite policy has no rewrite function", e.URI)
+		}
+	default:
+		return fmt.Errorf("invalid subscriber policy %q of header extension %s", e.Subscriber, e.URI)
+	}
+	return nil
+}
+
+func (e HeaderExtension) Policy(direction Direction) Policy {
+	p := e.Publisher
+	if direction == DirectionSubscriber {
+		p = e.Subscriber
+	}
+	if p == "" {
+		return PolicyStrip
+	}
+	return p
+}
+
+// Forwarded is true when values received from publishers reach subscribers
+func (e HeaderExtension) Forwarded() bool {
+	return e.Policy(DirectionPublisher) != PolicyStrip && e.Policy(DirectionSubscriber) != PolicyStrip
+}
+
+func (e HeaderExtension) appliesTo(kind webrtc.RTPCodecType) bool {
+	return (kind == webrtc.RTPCodecTypeAudio && e.Audio) || (kind == webrtc.RTPCodecTypeVideo && e.Video)
+}
+
+var registered = struct {
+	lock       sync.Mutex
+	extensions []HeaderExtension
+}{
+	extensions: []HeaderExtension{
+		// opt-in, clients stop rotating frames when CVO is negotiated, which leaves egress, HLS, thumbnails and
+		// relays with sideways video
+		{
+			URI:   VideoOrientationURI,
+			Video: true,
+		},
+	},
+}
+
+// Register adds a header extension to those of every registry, for extensions that need a rewrite function.
+// It panics when the extension is invalid or already registered, it is meant to be called from init()
+func Register(ext HeaderExtension) {
+	if err := ext.Validate(); err != nil {
+		panic(err)
+	}
+
+	registered.lock.Lock()
+	defer registered.lock.Unlock()
+
+	if slices.ContainsFunc(registered.extensions, func(e HeaderExtension) bool { return e.URI == ext.URI }) {
+		panic(fmt.Sprintf("header extension %s already registered", ext.URI))
+	}
+	registered.extensions = append(registered.extensions, ext)
+}
+
+// Registry is the set of header extensions of a server, the registered ones and those declared in config
+type Registry struct {
+	extensions []HeaderExtension
+}
+
+// NewRegistry returns the registered extensions with declared applied. A declared extension replaces the
+// registered one of the same URI, keeping its rewrite function
+func NewRegistry(declared []HeaderExtension) (*Registry, error) {
+	registered.lock.Lock()
+	extensions := slices.Clone(registered.extensions)
+	registered.lock.Unlock()
+
+	for i, ext := range declared {
+		if slices.ContainsFunc(declared[:i], func(e HeaderExtension) bool { return e.URI == ext.URI }) {
+			return nil, fmt.Errorf("header extension %s declared more than once", ext.URI)
+		}
+
+		idx := slices.IndexFunc(extensions, func(e HeaderExtension) bool { return e.URI == ext.URI })
+		if idx >= 0 && ext.Rewrite == nil {
+			ext.Rewrite = extensions[idx].Rewrite
+		}
+		if err := ext.Validate(); err != nil {
+			return nil, err
+		}
+		if idx >= 0 {
+			extensions[idx] = ext
+		} else {
+			extensions = append(extensions, ext)
+		}
+	}
+	return &Registry{extensions: extensions}, nil
+}
+
+// URIs returns the extensions of kind to negotiate in direction
+func (r *Registry) URIs(kind webrtc.RTPCodecType, direction Direction) []string {
+	if r == nil {
+		return nil
+	}
+
+	var uris []string
+	for _, ext := range r.extensions {
+		if ext.appliesTo(kind) && ext.Policy(direction) != PolicyStrip {
+			uris = append(uris, ext.URI)
+		}
+	}
+	return uris
+}
+
+// Forwarded returns the extensions of kind passed from publishers to subscribers
+func (r *Registry) Forwarded(kind webrtc.RTPCodecType) []HeaderExtension {
+	if r == nil {
+		return nil
+	}
+
+	var forwarded []HeaderExtension
+	for _, ext := range r.extensions {
+		if ext.appliesTo(kind) && ext.Forwarded() {
+			forwarded = append(forwarded, ext)
+		}
+	}
+	return forwarded
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtpextension
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaderExtensionValidate(t *testing.T) {
+	require.ErrorIs(t, HeaderExtension{Video: true}.Validate(), ErrHeaderExtensionURIRequired)
+	require.ErrorIs(t, HeaderExtension{URI: "urn:example:custom"}.Validate(), ErrHeaderExtensionNoKind)
+	require.Error(t, HeaderExtension{URI: sdp.TransportCCURI, Video: true}.Validate())
+	require.Error(t, HeaderExtension{URI: "urn:example:custom", Video: true, Publisher: PolicyRewrite}.Validate())
+	require.Error(t, HeaderExtension{URI: "urn:example:custom", Video: true, Subscriber: PolicyRewrite}.Validate())
+	require.NoError(t, HeaderExtension{URI: "urn:example:custom", Audio: true, Publisher: PolicyForward}.Validate())
+}
+
+func TestRegistry(t *testing.T) {
+	r, err := NewRegistry(nil)
+	require.NoError(t, err)
+	// video orientation is opt-in
+	require.Empty(t, r.URIs(webrtc.RTPCodecTypeVideo, DirectionPublisher))
+	require.Empty(t, r.URIs(webrtc.RTPCodecTypeVideo, DirectionSubscriber))
+	require.Empty(t, r.URIs(webrtc.RTPCodecTypeAudio, DirectionSubscriber))
+	require.Empty(t, r.Forwarded(webrtc.RTPCodecTypeVideo))
+
+	r, err = NewRegistry([]HeaderExtension{
+		// video orientation received from publishers, not sent to subscribers
+		{URI: VideoOrientationURI, Video: true, Publisher: PolicyForward},
+		// received from publishers for the server only
+		{URI: "urn:example:received", Audio: true, Publisher: PolicyForward},
+		{URI: "urn:example:forwarded", Audio: true, Video: true, Publisher: PolicyForward, Subscriber: PolicyForward},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{VideoOrientationURI, "urn:example:forwarded"}, r.URIs(webrtc.RTPCodecTypeVideo, DirectionPublisher))
+	require.Equal(t, []string{"urn:example:forwarded"}, r.URIs(webrtc.RTPCodecTypeVideo, DirectionSubscriber))
+	require.Equal(t, []string{"urn:example:received", "urn:example:forwarded"}, r.URIs(webrtc.RTPCodecTypeAudio, DirectionPublisher))
+
+	forwarded := r.Forwarded(webrtc.RTPCodecTypeAudio)
+	require.Len(t, forwarded, 1)
+	require.Equal(t, "urn:example:forwarded", forwarded[0].URI)
+
+	_, err = NewRegistry([]HeaderExtension{
+		{URI: "urn:example:custom", Video: true},
+		{URI: "urn:example:custom", Audio: true},
+	})
+	require.Error(t, err)
+
+	// a nil registry has no extensions
+	var empty *Registry
+	require.Empty(t, empty.Forwarded(webrtc.RTPCodecTypeVideo))
+}
+
+func TestRegister(t *testing.T) {
+	extensions := slices.Clone(registered.extensions)
+	t.Cleanup(func() { registered.extensions = extensions })
+
+	rewrite := func(payload []byte) ([]byte, error) { return payload[:1], nil }
+	Register(HeaderExtension{URI: "urn:example:rewritten", Video: true, Publisher: PolicyForward, Subscriber: PolicyRewrite, Rewrite: rewrite})
+	require.Panics(t, func() {
+		Register(HeaderExtension{URI: "urn:example:rewritten", Video: true})
+	})
+
+	// declaring a registered extension keeps its rewrite function
+	r, err := NewRegistry([]HeaderExtension{
+		{URI: "urn:example:rewritten", Video: true, Publisher: PolicyForward, Subscriber: PolicyRewrite},
+	})
+	require.NoError(t, err)
+	forwarded := r.Forwarded(webrtc.RTPCodecTypeVideo)
+	require.Len(t, forwarded, 1)
+	payload, err := forwarded[0].Rewrite([]byte{1, 2})
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, payload)
+}
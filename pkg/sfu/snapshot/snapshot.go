@@ -0,0 +1,255 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"go.uber.org/atomic"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/vp8"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/utils/guid"
+
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+const (
+	subscriberIDPrefix = "SN_"
+	// keyframes are requested again on this interval until one is received
+	pliInterval = 500 * time.Millisecond
+	jpegQuality = 85
+)
+
+var (
+	ErrUnsupportedCodec = errors.New("snapshots are only supported for VP8 tracks")
+	ErrTimeout          = errors.New("timed out waiting for keyframe")
+	ErrUnknownFormat    = errors.New("unknown image format")
+)
+
+type Format string
+
+const (
+	FormatJPEG Format = "jpeg"
+	FormatPNG  Format = "png"
+)
+
+func (f Format) ContentType() string {
+	return "image/" + string(f)
+}
+
+// Capture waits for the next keyframe of the highest layer received, and returns it decoded.
+// The capturer is attached to the receiver like a down track for the duration of the call.
+// With requestKeyFrame, keyframes of the track are requested until one is received.
+func Capture(ctx context.Context, receiver sfu.TrackReceiver, requestKeyFrame bool) (image.Image, error) {
+	if !strings.EqualFold(receiver.Codec().MimeType, webrtc.MimeTypeVP8) {
+		return nil, ErrUnsupportedCodec
+	}
+
+	c := newCapturer(receiver.TrackID())
+	if err := receiver.AddDownTrack(c); err != nil {
+		return nil, err
+	}
+	defer func() {
+		receiver.DeleteDownTrack(c.SubscriberID())
+		c.Close()
+	}()
+
+	ticker := time.NewTicker(pliInterval)
+	defer ticker.Stop()
+
+	for {
+		if requestKeyFrame {
+			c.sendPLI(receiver)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ErrTimeout
+		case frame := <-c.frames:
+			return DecodeVP8(frame)
+		case <-ticker.C:
+		}
+	}
+}
+
+// DecodeVP8 decodes a VP8 keyframe
+func DecodeVP8(frame []byte) (image.Image, error) {
+	d := vp8.NewDecoder()
+	d.Init(bytes.NewReader(frame), len(frame))
+	if _, err := d.DecodeFrameHeader(); err != nil {
+		return nil, err
+	}
+	return d.DecodeFrame()
+}
+
+// Encode writes the image in the given format
+func Encode(w io.Writer, img image.Image, format Format) error {
+	switch format {
+	case FormatJPEG:
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: jpegQuality})
+	case FormatPNG:
+		return png.Encode(w, img)
+	default:
+		return ErrUnknownFormat
+	}
+}
+
+// Resize scales the image down to maxWidth, keeping its aspect ratio.
+// Images that are already narrower are returned as-is.
+func Resize(img image.Image, maxWidth int) image.Image {
+	bounds := img.Bounds()
+	if maxWidth <= 0 || bounds.Dx() <= maxWidth {
+		return img
+	}
+
+	height := max(1, bounds.Dy()*maxWidth/bounds.Dx())
+	dst := image.NewRGBA(image.Rect(0, 0, maxWidth, height))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// capturer assembles the first complete keyframe written to it
+type capturer struct {
+	trackID      livekit.TrackID
+	subscriberID livekit.ParticipantID
+	frames       chan []byte
+	closed       atomic.Bool
+
+	lock       sync.Mutex
+	maxLayer   int32
+	assembling bool
+	layer      int32
+	timestamp  uint64
+	nextSN     uint64
+	frame      []byte
+}
+
+func newCapturer(trackID livekit.TrackID) *capturer {
+	return &capturer{
+		trackID:      trackID,
+		subscriberID: livekit.ParticipantID(guid.New(subscriberIDPrefix)),
+		frames:       make(chan []byte, 1),
+	}
+}
+
+func (c *capturer) sendPLI(receiver sfu.TrackReceiver) {
+	c.lock.Lock()
+	layer := c.maxLayer
+	c.lock.Unlock()
+
+	receiver.SendPLI(layer, true)
+}
+
+func (c *capturer) WriteRTP(p *buffer.ExtPacket, layer int32) error {
+	vp8Packet, ok := p.Payload.(buffer.VP8)
+	if !ok || c.closed.Load() || len(p.Packet.Payload) < vp8Packet.HeaderSize {
+		return nil
+	}
+	payload := p.Packet.Payload[vp8Packet.HeaderSize:]
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if layer > c.maxLayer {
+		c.maxLayer = layer
+	}
+
+	switch {
+	case p.KeyFrame:
+		c.assembling = true
+		c.layer = layer
+		c.timestamp = p.ExtTimestamp
+		c.nextSN = p.ExtSequenceNumber + 1
+		c.frame = append(c.frame[:0], payload...)
+
+	case c.assembling && layer == c.layer:
+		if p.ExtTimestamp != c.timestamp || p.ExtSequenceNumber != c.nextSN {
+			// lost or reordered, wait for the next keyframe
+			c.assembling = false
+			return nil
+		}
+		c.nextSN++
+		c.frame = append(c.frame, payload...)
+
+	default:
+		return nil
+	}
+
+	if p.Packet.Marker {
+		c.assembling = false
+		// prefer the highest resolution when simulcast
+		if c.layer >= c.maxLayer {
+			select {
+			case c.frames <- append([]byte(nil), c.frame...):
+			default:
+			}
+		}
+	}
+	return nil
+}
+
+func (c *capturer) UpTrackLayersChange() {}
+
+func (c *capturer) UpTrackBitrateAvailabilityChange() {}
+
+func (c *capturer) UpTrackMaxPublishedLayerChange(_maxPublishedLayer int32) {}
+
+func (c *capturer) UpTrackMaxTemporalLayerSeenChange(_maxTemporalLayerSeen int32) {}
+
+func (c *capturer) UpTrackSilencePause(_paused bool) {}
+
+func (c *capturer) UpTrackBitrateReport(_availableLayers []int32, _bitrates sfu.Bitrates) {}
+
+func (c *capturer) Close() {
+	c.closed.Store(true)
+}
+
+func (c *capturer) IsClosed() bool {
+	return c.closed.Load()
+}
+
+func (c *capturer) ID() string {
+	return string(c.subscriberID) + "_" + string(c.trackID)
+}
+
+func (c *capturer) SubscriberID() livekit.ParticipantID {
+	return c.subscriberID
+}
+
+func (c *capturer) TrackInfoAvailable() {}
+
+func (c *capturer) HandleRTCPSenderReportData(
+	_payloadType webrtc.PayloadType,
+	_isSVC bool,
+	_layer int32,
+	_publisherSRData *buffer.RTCPSenderReportData,
+) error {
+	return nil
+}
+
+func (c *capturer) Resync() {}
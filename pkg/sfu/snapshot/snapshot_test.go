@@ -0,0 +1,119 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"os"
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+func packetize(t *testing.T, frame []byte, firstSN uint64) []*buffer.ExtPacket {
+	const maxPayload = 500
+
+	var packets []*buffer.ExtPacket
+	for i := 0; i < len(frame); i += maxPayload {
+		end := min(i+maxPayload, len(frame))
+		start := i == 0
+		descriptor := byte(0)
+		if start {
+			descriptor = 0x10
+		}
+		packets = append(packets, &buffer.ExtPacket{
+			ExtSequenceNumber: firstSN + uint64(len(packets)),
+			ExtTimestamp:      9000,
+			KeyFrame:          start,
+			Packet: &rtp.Packet{
+				Header:  rtp.Header{Marker: end == len(frame)},
+				Payload: append([]byte{descriptor}, frame[i:end]...),
+			},
+			Payload: buffer.VP8{S: start, HeaderSize: 1, IsKeyFrame: start},
+		})
+	}
+	return packets
+}
+
+func TestCapturer(t *testing.T) {
+	keyframe, err := os.ReadFile("testdata/keyframe.vp8")
+	require.NoError(t, err)
+
+	t.Run("assembles and decodes keyframe", func(t *testing.T) {
+		c := newCapturer("TR_test")
+		for _, p := range packetize(t, keyframe, 100) {
+			require.NoError(t, c.WriteRTP(p, 0))
+		}
+
+		var frame []byte
+		select {
+		case frame = <-c.frames:
+		default:
+			t.Fatal("keyframe not assembled")
+		}
+		require.Equal(t, keyframe, frame)
+
+		img, err := DecodeVP8(frame)
+		require.NoError(t, err)
+		require.False(t, img.Bounds().Empty())
+
+		var out bytes.Buffer
+		require.NoError(t, Encode(&out, img, FormatJPEG))
+		decoded, err := jpeg.Decode(&out)
+		require.NoError(t, err)
+		require.Equal(t, img.Bounds(), decoded.Bounds())
+	})
+
+	t.Run("drops frame with lost packets", func(t *testing.T) {
+		c := newCapturer("TR_test")
+		packets := packetize(t, keyframe, 100)
+		for i, p := range packets {
+			if i == 1 {
+				continue
+			}
+			require.NoError(t, c.WriteRTP(p, 0))
+		}
+		require.Len(t, c.frames, 0)
+	})
+
+	t.Run("prefers highest layer", func(t *testing.T) {
+		c := newCapturer("TR_test")
+		require.NoError(t, c.WriteRTP(packetize(t, keyframe, 500)[1], 2))
+		for _, p := range packetize(t, keyframe, 100) {
+			require.NoError(t, c.WriteRTP(p, 0))
+		}
+		require.Len(t, c.frames, 0)
+
+		for _, p := range packetize(t, keyframe, 200) {
+			require.NoError(t, c.WriteRTP(p, 2))
+		}
+		require.Len(t, c.frames, 1)
+	})
+}
+
+func TestResize(t *testing.T) {
+	img := image.NewYCbCr(image.Rect(0, 0, 640, 360), image.YCbCrSubsampleRatio420)
+
+	resized := Resize(img, 320)
+	require.Equal(t, image.Rect(0, 0, 320, 180), resized.Bounds())
+
+	require.Equal(t, image.Image(img), Resize(img, 1280))
+	require.Equal(t, image.Image(img), Resize(img, 0))
+}
@@ -0,0 +1,71 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streamallocator
+
+import (
+	"time"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// ------------------------------------------------
+
+// AudioOnlyDetector decides when a subscriber should fall back to audio only, with hysteresis between the
+// enter and exit capacities and a minimum duration before switching either way
+type AudioOnlyDetector struct {
+	config config.AudioOnlyConfig
+
+	audioOnly   bool
+	switchSince time.Time
+}
+
+func NewAudioOnlyDetector(config config.AudioOnlyConfig) *AudioOnlyDetector {
+	return &AudioOnlyDetector{
+		config: config,
+	}
+}
+
+func (a *AudioOnlyDetector) IsAudioOnly() bool {
+	return a.audioOnly
+}
+
+// Observe records a channel capacity estimate, returns whether the subscriber is audio only and if that changed
+func (a *AudioOnlyDetector) Observe(capacity int64, at time.Time) (bool, bool) {
+	if !a.config.Enabled || capacity <= 0 {
+		return a.audioOnly, false
+	}
+
+	var wantsSwitch bool
+	if a.audioOnly {
+		wantsSwitch = capacity > a.config.ExitCapacity
+	} else {
+		wantsSwitch = capacity < a.config.EnterCapacity
+	}
+	if !wantsSwitch {
+		a.switchSince = time.Time{}
+		return a.audioOnly, false
+	}
+
+	if a.switchSince.IsZero() {
+		a.switchSince = at
+	}
+	if at.Sub(a.switchSince) < a.config.MinDuration {
+		return a.audioOnly, false
+	}
+
+	a.audioOnly = !a.audioOnly
+	a.switchSince = time.Time{}
+	return a.audioOnly, true
+}
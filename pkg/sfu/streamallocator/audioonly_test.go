@@ -0,0 +1,80 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streamallocator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestAudioOnlyDetector(t *testing.T) {
+	conf := config.AudioOnlyConfig{
+		Enabled:       true,
+		EnterCapacity: 100_000,
+		ExitCapacity:  300_000,
+		MinDuration:   5 * time.Second,
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		a := NewAudioOnlyDetector(config.AudioOnlyConfig{})
+		now := time.Now()
+		for i := 0; i < 10; i++ {
+			audioOnly, changed := a.Observe(10_000, now.Add(time.Duration(i)*time.Second))
+			require.False(t, audioOnly)
+			require.False(t, changed)
+		}
+	})
+
+	t.Run("hysteresis", func(t *testing.T) {
+		a := NewAudioOnlyDetector(conf)
+		now := time.Now()
+
+		// short dip does not switch
+		_, changed := a.Observe(50_000, now)
+		require.False(t, changed)
+		_, changed = a.Observe(50_000, now.Add(4*time.Second))
+		require.False(t, changed)
+		_, changed = a.Observe(500_000, now.Add(5*time.Second))
+		require.False(t, changed)
+
+		// sustained low capacity switches to audio only
+		now = now.Add(10 * time.Second)
+		_, changed = a.Observe(50_000, now)
+		require.False(t, changed)
+		audioOnly, changed := a.Observe(80_000, now.Add(5*time.Second))
+		require.True(t, audioOnly)
+		require.True(t, changed)
+
+		// capacity between enter and exit keeps audio only
+		now = now.Add(10 * time.Second)
+		for i := 0; i < 10; i++ {
+			audioOnly, changed = a.Observe(200_000, now.Add(time.Duration(i)*time.Second))
+			require.True(t, audioOnly)
+			require.False(t, changed)
+		}
+
+		// sustained high capacity restores video
+		now = now.Add(20 * time.Second)
+		_, changed = a.Observe(400_000, now)
+		require.False(t, changed)
+		audioOnly, changed = a.Observe(400_000, now.Add(5*time.Second))
+		require.False(t, audioOnly)
+		require.True(t, changed)
+	})
+}
@@ -17,6 +17,8 @@ package streamallocator
 import (
 	"fmt"
 
+	"github.com/benbjohnson/clock"
+
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/protocol/logger"
 )
@@ -72,6 +74,7 @@ func (c ChannelCongestionReason) String() string {
 type ChannelObserverParams struct {
 	Name   string
 	Config config.CongestionControlChannelObserverConfig
+	Clock  clock.Clock
 }
 
 type ChannelObserver struct {
@@ -95,6 +98,7 @@ func NewChannelObserver(params ChannelObserverParams, logger logger.Logger) *Cha
 			DownwardTrendMaxWait:   params.Config.EstimateDownwardTrendMaxWait,
 			CollapseThreshold:      params.Config.EstimateCollapseThreshold,
 			ValidityWindow:         params.Config.EstimateValidityWindow,
+			Clock:                  params.Clock,
 		}),
 		nackTracker: NewNackTracker(NackTrackerParams{
 			Name:              params.Name + "-nack",
@@ -102,6 +106,7 @@ func NewChannelObserver(params ChannelObserverParams, logger logger.Logger) *Cha
 			WindowMinDuration: params.Config.NackWindowMinDuration,
 			WindowMaxDuration: params.Config.NackWindowMaxDuration,
 			RatioThreshold:    params.Config.NackRatioThreshold,
+			Clock:             params.Clock,
 		}),
 	}
 }
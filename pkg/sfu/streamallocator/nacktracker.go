@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/livekit/protocol/logger"
 )
 
@@ -29,6 +30,7 @@ type NackTrackerParams struct {
 	WindowMinDuration time.Duration
 	WindowMaxDuration time.Duration
 	RatioThreshold    float64
+	Clock             clock.Clock
 }
 
 type NackTracker struct {
@@ -45,6 +47,9 @@ type NackTracker struct {
 }
 
 func NewNackTracker(params NackTrackerParams) *NackTracker {
+	if params.Clock == nil {
+		params.Clock = clock.New()
+	}
 	return &NackTracker{
 		params: params,
 		// STREAM-ALLOCATOR-DATA history: make([]string, 0, 10),
@@ -52,7 +57,7 @@ func NewNackTracker(params NackTrackerParams) *NackTracker {
 }
 
 func (n *NackTracker) Add(packets uint32, repeatedNacks uint32) {
-	if n.params.WindowMaxDuration != 0 && !n.windowStartTime.IsZero() && time.Since(n.windowStartTime) > n.params.WindowMaxDuration {
+	if n.params.WindowMaxDuration != 0 && !n.windowStartTime.IsZero() && n.params.Clock.Since(n.windowStartTime) > n.params.WindowMaxDuration {
 		// STREAM-ALLOCATOR-DATA n.updateHistory()
 
 		n.windowStartTime = time.Time{}
@@ -67,7 +72,7 @@ func (n *NackTracker) Add(packets uint32, repeatedNacks uint32) {
 	// or isolated losses
 	//
 	if n.repeatedNacks == 0 && repeatedNacks != 0 {
-		n.windowStartTime = time.Now()
+		n.windowStartTime = n.params.Clock.Now()
 	}
 
 	if !n.windowStartTime.IsZero() {
@@ -89,7 +94,7 @@ func (n *NackTracker) GetRatio() float64 {
 }
 
 func (n *NackTracker) IsTriggered() bool {
-	if n.params.WindowMinDuration != 0 && !n.windowStartTime.IsZero() && time.Since(n.windowStartTime) > n.params.WindowMinDuration {
+	if n.params.WindowMinDuration != 0 && !n.windowStartTime.IsZero() && n.params.Clock.Since(n.windowStartTime) > n.params.WindowMinDuration {
 		return n.GetRatio() > n.params.RatioThreshold
 	}
 
@@ -99,7 +104,7 @@ func (n *NackTracker) IsTriggered() bool {
 func (n *NackTracker) ToString() string {
 	window := ""
 	if !n.windowStartTime.IsZero() {
-		now := time.Now()
+		now := n.params.Clock.Now()
 		elapsed := now.Sub(n.windowStartTime).Seconds()
 		window = fmt.Sprintf("t: %+v|%+v|%.2fs", n.windowStartTime.Format(time.UnixDate), now.Format(time.UnixDate), elapsed)
 	}
@@ -0,0 +1,54 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streamallocator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+)
+
+func TestNackTracker(t *testing.T) {
+	clk := clock.NewMock()
+	n := NewNackTracker(NackTrackerParams{
+		Name:              "test",
+		Logger:            logger.GetLogger(),
+		WindowMinDuration: 500 * time.Millisecond,
+		WindowMaxDuration: time.Second,
+		RatioThreshold:    0.1,
+		Clock:             clk,
+	})
+
+	// window does not start without repeated NACKs
+	n.Add(100, 0)
+	clk.Add(600 * time.Millisecond)
+	require.False(t, n.IsTriggered())
+
+	// not triggered before minimum window duration
+	n.Add(100, 20)
+	require.False(t, n.IsTriggered())
+	clk.Add(600 * time.Millisecond)
+	require.True(t, n.IsTriggered())
+
+	// window resets after maximum duration
+	clk.Add(500 * time.Millisecond)
+	n.Add(100, 0)
+	require.Zero(t, n.GetRatio())
+	require.False(t, n.IsTriggered())
+}
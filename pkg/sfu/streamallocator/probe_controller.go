@@ -18,6 +18,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/benbjohnson/clock"
+
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/protocol/logger"
 )
@@ -27,6 +29,7 @@ import (
 type ProbeControllerParams struct {
 	Config config.CongestionControlProbeConfig
 	Prober *Prober
+	Clock  clock.Clock
 	Logger logger.Logger
 }
 
@@ -47,6 +50,9 @@ type ProbeController struct {
 }
 
 func NewProbeController(params ProbeControllerParams) *ProbeController {
+	if params.Clock == nil {
+		params.Clock = clock.New()
+	}
 	p := &ProbeController{
 		params:        params,
 		probeDuration: params.Config.MinDuration,
@@ -60,7 +66,7 @@ func (p *ProbeController) Reset() {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
-	p.lastProbeStartTime = time.Now()
+	p.lastProbeStartTime = p.params.Clock.Now()
 
 	p.resetProbeIntervalLocked()
 	p.resetProbeDurationLocked()
@@ -94,7 +100,7 @@ func (p *ProbeController) CheckProbe(trend ChannelTrend, highestEstimate int64)
 	}
 
 	switch {
-	case !p.probeTrendObserved && time.Since(p.lastProbeStartTime) > p.params.Config.TrendWait:
+	case !p.probeTrendObserved && p.params.Clock.Since(p.lastProbeStartTime) > p.params.Config.TrendWait:
 		//
 		// More of a safety net.
 		// In rare cases, the estimate gets stuck. Prevent from probe running amok
@@ -167,7 +173,7 @@ func (p *ProbeController) MaybeFinalizeProbe(
 		)
 	}
 
-	if !p.probeEndTime.IsZero() && time.Now().After(p.probeEndTime) {
+	if !p.probeEndTime.IsZero() && p.params.Clock.Now().After(p.probeEndTime) {
 		// finalize aborted or non-failing but non-goal-reached probe cluster
 		return true, p.finalizeProbeLocked(trend), false
 	}
@@ -206,7 +212,7 @@ func (p *ProbeController) InitProbe(probeGoalDeltaBps int64, expectedBandwidthUs
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
-	p.lastProbeStartTime = time.Now()
+	p.lastProbeStartTime = p.params.Clock.Now()
 
 	// overshoot a bit to account for noise (in measurement/estimate etc)
 	desiredIncreaseBps := (probeGoalDeltaBps * p.params.Config.OveragePct) / 100
@@ -294,7 +300,7 @@ func (p *ProbeController) CanProbe() bool {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
 
-	return time.Since(p.lastProbeStartTime) >= p.probeInterval && p.probeClusterId == ProbeClusterIdInvalid
+	return p.params.Clock.Since(p.lastProbeStartTime) >= p.probeInterval && p.probeClusterId == ProbeClusterIdInvalid
 }
 
 // ------------------------------------------------
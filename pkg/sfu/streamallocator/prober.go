@@ -127,6 +127,7 @@ import (
 	"github.com/gammazero/deque"
 	"go.uber.org/atomic"
 
+	"github.com/benbjohnson/clock"
 	"github.com/livekit/protocol/logger"
 )
 
@@ -137,11 +138,13 @@ type ProberListener interface {
 }
 
 type ProberParams struct {
+	Clock  clock.Clock
 	Logger logger.Logger
 }
 
 type Prober struct {
 	logger logger.Logger
+	clock  clock.Clock
 
 	clusterId atomic.Uint32
 
@@ -156,8 +159,12 @@ type Prober struct {
 }
 
 func NewProber(params ProberParams) *Prober {
+	if params.Clock == nil {
+		params.Clock = clock.New()
+	}
 	p := &Prober{
 		logger: params.Logger,
+		clock:  params.Clock,
 	}
 	p.clusters.SetMinCapacity(2)
 	return p
@@ -215,7 +222,7 @@ func (p *Prober) AddCluster(mode ProbeClusterMode, desiredRateBps int, expectedR
 	}
 
 	clusterId := ProbeClusterId(p.clusterId.Inc())
-	cluster := NewCluster(clusterId, mode, desiredRateBps, expectedRateBps, minDuration, maxDuration)
+	cluster := NewCluster(p.clock, clusterId, mode, desiredRateBps, expectedRateBps, minDuration, maxDuration)
 	p.logger.Debugw("cluster added", "cluster", cluster.String())
 
 	p.pushBackClusterAndMaybeStart(cluster)
@@ -329,7 +336,7 @@ func (p *Prober) run() {
 		return
 	}
 
-	timer := time.NewTimer(cluster.GetSleepDuration())
+	timer := p.clock.Timer(cluster.GetSleepDuration())
 	for {
 		<-timer.C
 
@@ -408,7 +415,8 @@ type clusterBucket struct {
 }
 
 type Cluster struct {
-	lock sync.RWMutex
+	lock  sync.RWMutex
+	clock clock.Clock
 
 	id           ProbeClusterId
 	mode         ProbeClusterMode
@@ -424,8 +432,9 @@ type Cluster struct {
 	startTime         time.Time
 }
 
-func NewCluster(id ProbeClusterId, mode ProbeClusterMode, desiredRateBps int, expectedRateBps int, minDuration time.Duration, maxDuration time.Duration) *Cluster {
+func NewCluster(clock clock.Clock, id ProbeClusterId, mode ProbeClusterMode, desiredRateBps int, expectedRateBps int, minDuration time.Duration, maxDuration time.Duration) *Cluster {
 	c := &Cluster{
+		clock:       clock,
 		id:          id,
 		mode:        mode,
 		minDuration: minDuration,
@@ -483,7 +492,7 @@ func (c *Cluster) Start() {
 	defer c.lock.Unlock()
 
 	if c.startTime.IsZero() {
-		c.startTime = time.Now()
+		c.startTime = c.clock.Now()
 	}
 }
 
@@ -513,7 +522,7 @@ func (c *Cluster) IsFinished() bool {
 	defer c.lock.RUnlock()
 
 	// if already past deadline, end the cluster
-	timeElapsed := time.Since(c.startTime)
+	timeElapsed := c.clock.Since(c.startTime)
 	if timeElapsed > c.maxDuration {
 		return true
 	}
@@ -534,13 +543,13 @@ func (c *Cluster) GetInfo() ProbeClusterInfo {
 	return ProbeClusterInfo{
 		Id:        c.id,
 		BytesSent: c.bytesSentProbe + c.bytesSentNonProbe,
-		Duration:  time.Since(c.startTime),
+		Duration:  c.clock.Since(c.startTime),
 	}
 }
 
 func (c *Cluster) Process(pl ProberListener) {
 	c.lock.RLock()
-	timeElapsed := time.Since(c.startTime)
+	timeElapsed := c.clock.Since(c.startTime)
 
 	// Calculate number of probe bytes that should have been sent since start.
 	// Overall goal is to send desired number of probe bytes in minDuration.
@@ -578,7 +587,7 @@ func (c *Cluster) Process(pl ProberListener) {
 func (c *Cluster) String() string {
 	activeTimeMs := int64(0)
 	if !c.startTime.IsZero() {
-		activeTimeMs = time.Since(c.startTime).Milliseconds()
+		activeTimeMs = c.clock.Since(c.startTime).Milliseconds()
 	}
 
 	return fmt.Sprintf("id: %d, mode: %s, bytes: desired %d / probe %d / non-probe %d / remaining: %d, time(ms): active %d / min %d / max %d",
@@ -0,0 +1,168 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streamallocator
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	probeResultsHistory = 10
+)
+
+// ------------------------------------------------
+
+type ProbeDecision int
+
+const (
+	// probe failed, e.g. aborted or channel congested, probe interval backs off
+	ProbeDecisionFailed ProbeDecision = iota
+	// probe did not fail, but did not estimate more than the committed channel capacity
+	ProbeDecisionCapacityKept
+	// committed channel capacity raised to the highest estimate during the probe
+	ProbeDecisionCapacityRaised
+	// estimate reached the probe goal, committed channel capacity raised
+	ProbeDecisionGoalReached
+)
+
+func (p ProbeDecision) String() string {
+	switch p {
+	case ProbeDecisionFailed:
+		return "FAILED"
+	case ProbeDecisionCapacityKept:
+		return "CAPACITY_KEPT"
+	case ProbeDecisionCapacityRaised:
+		return "CAPACITY_RAISED"
+	case ProbeDecisionGoalReached:
+		return "GOAL_REACHED"
+	default:
+		return fmt.Sprintf("%d", int(p))
+	}
+}
+
+// ------------------------------------------------
+
+// ProbeResult is the outcome of a padding probe of a transport
+type ProbeResult struct {
+	ClusterId ProbeClusterId
+	StartedAt time.Time
+	Duration  time.Duration
+	// rate the probe tried to reach, expected usage and the desired increase
+	GoalBps int64
+	// estimated rate sent during the probe cluster, the expected media usage plus the padding sent
+	EstimatedSentBps int64
+	// highest estimate during the probe
+	AchievedBps int64
+	// committed channel capacity after the probe
+	CommittedBps int64
+	// ratio of repeated NACKs to packets during the probe, a proxy for loss
+	NackRatio float64
+	Decision  ProbeDecision
+
+	expectedUsageBps  int64
+	paddingBytesSent  int
+	paddingSentPeriod time.Duration
+}
+
+func newProbeResult(clusterId ProbeClusterId, startedAt time.Time, goalBps int64, expectedUsageBps int64) *ProbeResult {
+	return &ProbeResult{
+		ClusterId:        clusterId,
+		StartedAt:        startedAt,
+		GoalBps:          goalBps,
+		expectedUsageBps: expectedUsageBps,
+	}
+}
+
+func (p *ProbeResult) DebugInfo() map[string]interface{} {
+	return map[string]interface{}{
+		"ClusterId":        p.ClusterId,
+		"StartedAt":        p.StartedAt.String(),
+		"Duration":         p.Duration.String(),
+		"GoalBps":          p.GoalBps,
+		"EstimatedSentBps": p.EstimatedSentBps,
+		"AchievedBps":      p.AchievedBps,
+		"CommittedBps":     p.CommittedBps,
+		"NackRatio":        p.NackRatio,
+		"Decision":         p.Decision.String(),
+	}
+}
+
+func (p *ProbeResult) setClusterInfo(info ProbeClusterInfo) {
+	if info.Id != p.ClusterId {
+		return
+	}
+	p.paddingBytesSent = info.BytesSent
+	p.paddingSentPeriod = info.Duration
+}
+
+func (p *ProbeResult) finalize(
+	endedAt time.Time,
+	highestEstimate int64,
+	committedBps int64,
+	nackRatio float64,
+	isNotFailing bool,
+	isGoalReached bool,
+) {
+	p.Duration = endedAt.Sub(p.StartedAt)
+	p.EstimatedSentBps = p.expectedUsageBps
+	if p.paddingSentPeriod > 0 {
+		p.EstimatedSentBps += int64(float64(p.paddingBytesSent*8) / p.paddingSentPeriod.Seconds())
+	}
+	p.AchievedBps = highestEstimate
+	p.NackRatio = nackRatio
+
+	switch {
+	case !isNotFailing:
+		p.Decision = ProbeDecisionFailed
+	case isGoalReached:
+		p.Decision = ProbeDecisionGoalReached
+	case highestEstimate > committedBps:
+		p.Decision = ProbeDecisionCapacityRaised
+	default:
+		p.Decision = ProbeDecisionCapacityKept
+	}
+	if p.Decision != ProbeDecisionFailed && highestEstimate > committedBps {
+		committedBps = highestEstimate
+	}
+	p.CommittedBps = committedBps
+}
+
+// ------------------------------------------------
+
+// ProbeResults holds the results of the most recent probes
+type ProbeResults struct {
+	lock    sync.RWMutex
+	results []ProbeResult
+}
+
+func (p *ProbeResults) add(result ProbeResult) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.results) == probeResultsHistory {
+		p.results = append(p.results[:0], p.results[1:]...)
+	}
+	p.results = append(p.results, result)
+}
+
+// Get returns the results, oldest first
+func (p *ProbeResults) Get() []ProbeResult {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return append([]ProbeResult(nil), p.results...)
+}
@@ -0,0 +1,76 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streamallocator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeResult(t *testing.T) {
+	now := time.Now()
+
+	t.Run("rates", func(t *testing.T) {
+		p := newProbeResult(1, now, 1_500_000, 1_000_000)
+		// info of another cluster is ignored
+		p.setClusterInfo(ProbeClusterInfo{Id: 2, BytesSent: 1_000_000, Duration: time.Second})
+		p.setClusterInfo(ProbeClusterInfo{Id: 1, BytesSent: 25_000, Duration: 500 * time.Millisecond})
+		p.finalize(now.Add(2*time.Second), 1_300_000, 1_100_000, 0.01, true, false)
+
+		require.Equal(t, 2*time.Second, p.Duration)
+		require.Equal(t, int64(1_400_000), p.EstimatedSentBps)
+		require.Equal(t, int64(1_300_000), p.AchievedBps)
+		require.Equal(t, int64(1_300_000), p.CommittedBps)
+		require.Equal(t, 0.01, p.NackRatio)
+		require.Equal(t, ProbeDecisionCapacityRaised, p.Decision)
+	})
+
+	t.Run("decisions", func(t *testing.T) {
+		testCases := []struct {
+			name          string
+			highest       int64
+			isNotFailing  bool
+			isGoalReached bool
+			decision      ProbeDecision
+			committed     int64
+		}{
+			{"failed", 2_000_000, false, false, ProbeDecisionFailed, 1_000_000},
+			{"kept", 900_000, true, false, ProbeDecisionCapacityKept, 1_000_000},
+			{"raised", 1_200_000, true, false, ProbeDecisionCapacityRaised, 1_200_000},
+			{"goal reached", 2_000_000, true, true, ProbeDecisionGoalReached, 2_000_000},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				p := newProbeResult(1, now, 1_500_000, 1_000_000)
+				p.finalize(now.Add(time.Second), tc.highest, 1_000_000, 0, tc.isNotFailing, tc.isGoalReached)
+				require.Equal(t, tc.decision, p.Decision)
+				require.Equal(t, tc.committed, p.CommittedBps)
+			})
+		}
+	})
+
+	t.Run("history", func(t *testing.T) {
+		var results ProbeResults
+		for i := 1; i <= probeResultsHistory+2; i++ {
+			results.add(ProbeResult{ClusterId: ProbeClusterId(i)})
+		}
+		got := results.Get()
+		require.Len(t, got, probeResultsHistory)
+		require.Equal(t, ProbeClusterId(3), got[0].ClusterId)
+		require.Equal(t, ProbeClusterId(probeResultsHistory+2), got[probeResultsHistory-1].ClusterId)
+	})
+}
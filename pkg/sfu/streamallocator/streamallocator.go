@@ -20,6 +20,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/pion/interceptor/pkg/cc"
 	"github.com/pion/rtcp"
 	"github.com/pion/webrtc/v3"
@@ -85,6 +86,7 @@ const (
 	streamAllocatorSignalResume
 	streamAllocatorSignalSetAllowPause
 	streamAllocatorSignalSetChannelCapacity
+	streamAllocatorSignalSetMaxChannelCapacity
 	// STREAM-ALLOCATOR-DATA streamAllocatorSignalNACK
 	// STREAM-ALLOCATOR-DATA streamAllocatorSignalRTCPReceiverReport
 )
@@ -111,6 +113,8 @@ func (s streamAllocatorSignal) String() string {
 		return "SET_ALLOW_PAUSE"
 	case streamAllocatorSignalSetChannelCapacity:
 		return "SET_CHANNEL_CAPACITY"
+	case streamAllocatorSignalSetMaxChannelCapacity:
+		return "SET_MAX_CHANNEL_CAPACITY"
 		/* STREAM-ALLOCATOR-DATA
 		case streamAllocatorSignalNACK:
 			return "NACK"
@@ -139,6 +143,8 @@ func (e Event) String() string {
 
 type StreamAllocatorParams struct {
 	Config config.CongestionControlConfig
+	// source of current time, defaults to the system clock, can be mocked in tests
+	Clock  clock.Clock
 	Logger logger.Logger
 }
 
@@ -146,6 +152,7 @@ type StreamAllocator struct {
 	params StreamAllocatorParams
 
 	onStreamStateChange func(update *StreamStateUpdate) error
+	onProbeResult       func(result ProbeResult)
 
 	bwe cc.BandwidthEstimator
 
@@ -154,12 +161,19 @@ type StreamAllocator struct {
 	lastReceivedEstimate      int64
 	committedChannelCapacity  int64
 	overriddenChannelCapacity int64
+	// upper bound of channel capacity, regardless of estimate, 0 when not bounded
+	maxChannelCapacity int64
 
 	probeController *ProbeController
 
 	prober *Prober
+	// result of the running probe, completed when the probe is done
+	probeResult  *ProbeResult
+	probeResults ProbeResults
 
 	channelObserver *ChannelObserver
+
+	audioOnly *AudioOnlyDetector
 	// STREAM-ALLOCATOR-DATA rateMonitor     *RateMonitor
 
 	videoTracksMu        sync.RWMutex
@@ -175,14 +189,19 @@ type StreamAllocator struct {
 }
 
 func NewStreamAllocator(params StreamAllocatorParams) *StreamAllocator {
+	if params.Clock == nil {
+		params.Clock = clock.New()
+	}
 	s := &StreamAllocator{
 		params:     params,
 		allowPause: params.Config.AllowPause,
 		prober: NewProber(ProberParams{
+			Clock:  params.Clock,
 			Logger: params.Logger,
 		}),
 		// STREAM-ALLOCATOR-DATA rateMonitor: NewRateMonitor(),
 		videoTracks: make(map[livekit.TrackID]*Track),
+		audioOnly:   NewAudioOnlyDetector(params.Config.AudioOnly),
 		eventsQueue: utils.NewTypedOpsQueue[Event](utils.OpsQueueParams{
 			Name:    "stream-allocator",
 			MinSize: 64,
@@ -193,6 +212,7 @@ func NewStreamAllocator(params StreamAllocatorParams) *StreamAllocator {
 	s.probeController = NewProbeController(ProbeControllerParams{
 		Config: s.params.Config.ProbeConfig,
 		Prober: s.prober,
+		Clock:  params.Clock,
 		Logger: params.Logger,
 	})
 
@@ -222,6 +242,15 @@ func (s *StreamAllocator) OnStreamStateChange(f func(update *StreamStateUpdate)
 	s.onStreamStateChange = f
 }
 
+func (s *StreamAllocator) OnProbeResult(f func(result ProbeResult)) {
+	s.onProbeResult = f
+}
+
+// GetProbeResults returns the results of the most recent probes, oldest first
+func (s *StreamAllocator) GetProbeResults() []ProbeResult {
+	return s.probeResults.Get()
+}
+
 func (s *StreamAllocator) SetBandwidthEstimator(bwe cc.BandwidthEstimator) {
 	if bwe != nil {
 		bwe.OnTargetBitrateChange(s.onTargetBitrateChange)
@@ -305,6 +334,16 @@ func (s *StreamAllocator) SetChannelCapacity(channelCapacity int64) {
 	})
 }
 
+// SetMaxChannelCapacity bounds the channel capacity tracks are allocated on, 0 to remove the bound.
+// The bound applies to exempt tracks too, and also when allocation is disabled.
+// Only video tracks are allocated, so audio is not affected
+func (s *StreamAllocator) SetMaxChannelCapacity(maxChannelCapacity int64) {
+	s.postEvent(Event{
+		Signal: streamAllocatorSignalSetMaxChannelCapacity,
+		Data:   maxChannelCapacity,
+	})
+}
+
 func (s *StreamAllocator) resetState() {
 	s.channelObserver = s.newChannelObserverNonProbe()
 	s.probeController.Reset()
@@ -558,7 +597,7 @@ func (s *StreamAllocator) maybePostEventAllocateTrack(downTrack *sfu.DownTrack)
 }
 
 func (s *StreamAllocator) ping() {
-	ticker := time.NewTicker(100 * time.Millisecond)
+	ticker := s.params.Clock.Ticker(100 * time.Millisecond)
 	defer ticker.Stop()
 
 	for {
@@ -597,6 +636,8 @@ func (s *StreamAllocator) postEvent(event Event) {
 			event.handleSignalSetAllowPause(event)
 		case streamAllocatorSignalSetChannelCapacity:
 			event.handleSignalSetChannelCapacity(event)
+		case streamAllocatorSignalSetMaxChannelCapacity:
+			event.handleSignalSetMaxChannelCapacity(event)
 			/* STREAM-ALLOCATOR-DATA
 			case streamAllocatorSignalNACK:
 				event.s.handleSignalNACK(event)
@@ -645,6 +686,8 @@ func (s *StreamAllocator) handleSignalEstimate(event Event) {
 	} else {
 		s.handleNewEstimateInNonProbe()
 	}
+
+	s.maybeUpdateAudioOnly()
 }
 
 func (s *StreamAllocator) handleSignalPeriodicPing(Event) {
@@ -691,6 +734,9 @@ func (s *StreamAllocator) handleSignalSendProbe(event Event) {
 func (s *StreamAllocator) handleSignalProbeClusterDone(event Event) {
 	info, _ := event.Data.(ProbeClusterInfo)
 	s.probeController.ProbeClusterDone(info)
+	if s.probeResult != nil {
+		s.probeResult.setClusterInfo(info)
+	}
 }
 
 func (s *StreamAllocator) handleSignalResume(event Event) {
@@ -720,6 +766,17 @@ func (s *StreamAllocator) handleSignalSetChannelCapacity(event Event) {
 	}
 }
 
+func (s *StreamAllocator) handleSignalSetMaxChannelCapacity(event Event) {
+	maxChannelCapacity := event.Data.(int64)
+	if maxChannelCapacity == s.maxChannelCapacity {
+		return
+	}
+
+	s.params.Logger.Debugw("setting max channel capacity", "old", s.maxChannelCapacity, "new", maxChannelCapacity)
+	s.maxChannelCapacity = maxChannelCapacity
+	s.allocateAllTracks()
+}
+
 /* STREAM-ALLOCATOR-DATA
 func (s *StreamAllocator) handleSignalNACK(event Event) {
 	nackInfos := event.Data.([]sfu.NackInfo)
@@ -864,10 +921,79 @@ func (s *StreamAllocator) handleNewEstimateInNonProbe() {
 	s.allocateAllTracks()
 }
 
+func (s *StreamAllocator) maybeUpdateAudioOnly() {
+	// while audio only, a successful probe raises committed capacity above the estimate of the audio only traffic
+	capacity := s.lastReceivedEstimate
+	if s.audioOnly.IsAudioOnly() && s.committedChannelCapacity > capacity {
+		capacity = s.committedChannelCapacity
+	}
+
+	audioOnly, changed := s.audioOnly.Observe(capacity, s.params.Clock.Now())
+	if !changed {
+		return
+	}
+
+	if audioOnly {
+		s.params.Logger.Infow(
+			"stream allocator: low channel capacity, dropping video",
+			"capacity(bps)", capacity,
+			"committed(bps)", s.committedChannelCapacity,
+		)
+	} else {
+		s.params.Logger.Infow(
+			"stream allocator: channel capacity recovered, restoring video",
+			"capacity(bps)", capacity,
+			"committed(bps)", s.committedChannelCapacity,
+		)
+		s.committedChannelCapacity = capacity
+	}
+
+	s.probeController.AbortProbe()
+	s.allocateAllTracks()
+}
+
+func (s *StreamAllocator) pauseAllTracks() {
+	update := NewStreamStateUpdate()
+	for _, track := range s.getTracks() {
+		allocation := track.Pause()
+		updateStreamStateChange(track, allocation, update)
+	}
+	s.maybeSendUpdate(update)
+
+	s.adjustState()
+}
+
+func (s *StreamAllocator) allocateAllTracksOptimal() {
+	update := NewStreamStateUpdate()
+	for _, track := range s.getTracks() {
+		allocation := track.AllocateOptimal(FlagAllowOvershootWhileOptimal)
+		updateStreamStateChange(track, allocation, update)
+	}
+	s.maybeSendUpdate(update)
+
+	s.adjustState()
+}
+
 func (s *StreamAllocator) allocateTrack(track *Track) {
 	// abort any probe that may be running when a track specific change needs allocation
 	s.probeController.AbortProbe()
 
+	if s.audioOnly.IsAudioOnly() {
+		update := NewStreamStateUpdate()
+		allocation := track.Pause()
+		updateStreamStateChange(track, allocation, update)
+		s.maybeSendUpdate(update)
+
+		s.adjustState()
+		return
+	}
+
+	if s.maxChannelCapacity > 0 {
+		// all tracks share the bound
+		s.allocateAllTracks()
+		return
+	}
+
 	// if not deficient, free pass allocate track
 	if !s.params.Config.Enabled || s.state == streamAllocatorStateStable || !track.IsManaged() {
 		update := NewStreamStateUpdate()
@@ -1021,6 +1147,7 @@ func (s *StreamAllocator) allocateTrack(track *Track) {
 
 func (s *StreamAllocator) onProbeDone(isNotFailing bool, isGoalReached bool) {
 	highestEstimateInProbe := s.channelObserver.GetHighestEstimate()
+	s.completeProbeResult(highestEstimateInProbe, isNotFailing, isGoalReached)
 
 	//
 	// Reset estimator at the end of a probe irrespective of probe result to get fresh readings.
@@ -1054,7 +1181,33 @@ func (s *StreamAllocator) onProbeDone(isNotFailing bool, isGoalReached bool) {
 	s.maybeBoostDeficientTracks()
 }
 
+func (s *StreamAllocator) completeProbeResult(highestEstimate int64, isNotFailing bool, isGoalReached bool) {
+	if s.probeResult == nil {
+		return
+	}
+
+	s.probeResult.finalize(
+		s.params.Clock.Now(),
+		highestEstimate,
+		s.committedChannelCapacity,
+		s.channelObserver.GetNackRatio(),
+		isNotFailing,
+		isGoalReached,
+	)
+	result := *s.probeResult
+	s.probeResult = nil
+
+	s.probeResults.add(result)
+	if s.onProbeResult != nil {
+		s.onProbeResult(result)
+	}
+}
+
 func (s *StreamAllocator) maybeBoostDeficientTracks() {
+	if s.audioOnly.IsAudioOnly() {
+		return
+	}
+
 	availableChannelCapacity := s.getAvailableHeadroom(false)
 	if availableChannelCapacity <= 0 {
 		return
@@ -1096,8 +1249,17 @@ boost_loop:
 }
 
 func (s *StreamAllocator) allocateAllTracks() {
-	if !s.params.Config.Enabled {
-		// nothing else to do when disabled
+	if s.audioOnly.IsAudioOnly() {
+		// video of all tracks, including exempt ones, is dropped till channel capacity recovers,
+		// also when allocation is disabled
+		s.pauseAllTracks()
+		return
+	}
+
+	bounded := s.maxChannelCapacity > 0
+	if !s.params.Config.Enabled && !bounded {
+		// without allocation, tracks stream optimally, which also restores them after audio only
+		s.allocateAllTracksOptimal()
 		return
 	}
 
@@ -1120,10 +1282,11 @@ func (s *StreamAllocator) allocateAllTracks() {
 	//
 	// This pass is to find out if there is any leftover channel capacity after allocating exempt tracks.
 	// Exempt tracks are given optimal allocation (i. e. no bandwidth constraint) so that they do not fail allocation.
+	// When the channel capacity is bounded, exempt tracks are allocated with the managed ones within the bound.
 	//
 	videoTracks := s.getTracks()
 	for _, track := range videoTracks {
-		if track.IsManaged() {
+		if track.IsManaged() || bounded {
 			continue
 		}
 
@@ -1142,7 +1305,7 @@ func (s *StreamAllocator) allocateAllTracks() {
 	if availableChannelCapacity == 0 && s.allowPause {
 		// nothing left for managed tracks, pause them all
 		for _, track := range videoTracks {
-			if !track.IsManaged() {
+			if !track.IsManaged() && !bounded {
 				continue
 			}
 
@@ -1150,7 +1313,7 @@ func (s *StreamAllocator) allocateAllTracks() {
 			updateStreamStateChange(track, allocation, update)
 		}
 	} else {
-		sorted := s.getSorted()
+		sorted := s.getSorted(bounded)
 		for _, track := range sorted {
 			track.ProvisionalAllocatePrepare()
 		}
@@ -1221,6 +1384,12 @@ func (s *StreamAllocator) getAvailableChannelCapacity(allowOverride bool) int64
 			"override", availableChannelCapacity,
 		)
 	}
+	if s.maxChannelCapacity > 0 {
+		// without a committed estimate, or when allocation is disabled, tracks are allocated on the bound
+		if !s.params.Config.Enabled || availableChannelCapacity == 0 || availableChannelCapacity > s.maxChannelCapacity {
+			availableChannelCapacity = s.maxChannelCapacity
+		}
+	}
 
 	return availableChannelCapacity
 }
@@ -1277,6 +1446,7 @@ func (s *StreamAllocator) newChannelObserverProbe() *ChannelObserver {
 		ChannelObserverParams{
 			Name:   "probe",
 			Config: s.params.Config.ChannelObserverProbeConfig,
+			Clock:  s.params.Clock,
 		},
 		s.params.Logger,
 	)
@@ -1287,6 +1457,7 @@ func (s *StreamAllocator) newChannelObserverNonProbe() *ChannelObserver {
 		ChannelObserverParams{
 			Name:   "non-probe",
 			Config: s.params.Config.ChannelObserverNonProbeConfig,
+			Clock:  s.params.Clock,
 		},
 		s.params.Logger,
 	)
@@ -1295,6 +1466,7 @@ func (s *StreamAllocator) newChannelObserverNonProbe() *ChannelObserver {
 func (s *StreamAllocator) initProbe(probeGoalDeltaBps int64) {
 	expectedBandwidthUsage := s.getExpectedBandwidthUsage()
 	probeClusterId, probeGoalBps := s.probeController.InitProbe(probeGoalDeltaBps, expectedBandwidthUsage)
+	s.probeResult = newProbeResult(probeClusterId, s.params.Clock.Now(), probeGoalBps, expectedBandwidthUsage)
 
 	channelState := ""
 	if s.channelObserver != nil {
@@ -1320,6 +1492,10 @@ func (s *StreamAllocator) maybeProbe() {
 		// do not probe if channel capacity is overridden
 		return
 	}
+	if s.maxChannelCapacity > 0 && s.committedChannelCapacity >= s.maxChannelCapacity {
+		// no use for more capacity than allowed
+		return
+	}
 	if !s.probeController.CanProbe() {
 		return
 	}
@@ -1374,11 +1550,11 @@ func (s *StreamAllocator) getTracks() []*Track {
 	return tracks
 }
 
-func (s *StreamAllocator) getSorted() TrackSorter {
+func (s *StreamAllocator) getSorted(includeExempt bool) TrackSorter {
 	s.videoTracksMu.RLock()
 	var trackSorter TrackSorter
 	for _, track := range s.videoTracks {
-		if !track.IsManaged() {
+		if !track.IsManaged() && !includeExempt {
 			continue
 		}
 
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/livekit/protocol/logger"
 )
 
@@ -79,6 +80,7 @@ type TrendDetectorParams struct {
 	DownwardTrendMaxWait   time.Duration
 	CollapseThreshold      time.Duration
 	ValidityWindow         time.Duration
+	Clock                  clock.Clock
 }
 
 type TrendDetector struct {
@@ -94,9 +96,12 @@ type TrendDetector struct {
 }
 
 func NewTrendDetector(params TrendDetectorParams) *TrendDetector {
+	if params.Clock == nil {
+		params.Clock = clock.New()
+	}
 	return &TrendDetector{
 		params:    params,
-		startTime: time.Now(),
+		startTime: params.Clock.Now(),
 		direction: TrendDirectionNeutral,
 	}
 }
@@ -106,7 +111,7 @@ func (t *TrendDetector) Seed(value int64) {
 		return
 	}
 
-	t.samples = append(t.samples, trendDetectorSample{value: value, at: time.Now()})
+	t.samples = append(t.samples, trendDetectorSample{value: value, at: t.params.Clock.Now()})
 }
 
 func (t *TrendDetector) AddValue(value int64) {
@@ -134,11 +139,11 @@ func (t *TrendDetector) AddValue(value int64) {
 	if len(t.samples) != 0 {
 		lastSample = &t.samples[len(t.samples)-1]
 	}
-	if lastSample != nil && lastSample.value == value && t.params.CollapseThreshold > 0 && time.Since(lastSample.at) < t.params.CollapseThreshold {
+	if lastSample != nil && lastSample.value == value && t.params.CollapseThreshold > 0 && t.params.Clock.Since(lastSample.at) < t.params.CollapseThreshold {
 		return
 	}
 
-	t.samples = append(t.samples, trendDetectorSample{value: value, at: time.Now()})
+	t.samples = append(t.samples, trendDetectorSample{value: value, at: t.params.Clock.Now()})
 	t.prune()
 	t.updateDirection()
 }
@@ -160,7 +165,7 @@ func (t *TrendDetector) HasEnoughSamples() bool {
 }
 
 func (t *TrendDetector) ToString() string {
-	now := time.Now()
+	now := t.params.Clock.Now()
 	elapsed := now.Sub(t.startTime).Seconds()
 	return fmt.Sprintf("n: %s, t: %+v|%+v|%.2fs, v: %d|%d|%d|%s|%.2f",
 		t.params.Name,
@@ -179,7 +184,7 @@ func (t *TrendDetector) prune() {
 
 	// 2. drop samples that are too old
 	if len(t.samples) != 0 && t.params.ValidityWindow > 0 {
-		cutoffTime := time.Now().Add(-t.params.ValidityWindow)
+		cutoffTime := t.params.Clock.Now().Add(-t.params.ValidityWindow)
 		cutoffIndex := -1
 		for i := 0; i < len(t.samples); i++ {
 			if t.samples[i].at.After(cutoffTime) {
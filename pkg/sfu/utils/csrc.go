@@ -0,0 +1,51 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"cmp"
+	"hash/crc32"
+	"slices"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// MaxCSRCs is the number of contributing sources an RTP header can list
+const MaxCSRCs = 15
+
+// TrackCSRC is the contributing source of a track in streams generated from several tracks, like mixed audio.
+// It is derived from the track ID, so it is the same for every subscriber
+func TrackCSRC(trackID livekit.TrackID) uint32 {
+	return crc32.ChecksumIEEE([]byte(trackID))
+}
+
+// Contributor is a contributing source of a generated packet, ranked against the others by Level
+type Contributor struct {
+	CSRC  uint32
+	Level float64
+}
+
+// CSRCList lists the highest ranked contributing sources that fit an RTP header, contributors of equal level keep
+// their order
+func CSRCList(contributors []Contributor) []uint32 {
+	slices.SortStableFunc(contributors, func(a, b Contributor) int {
+		return cmp.Compare(b.Level, a.Level)
+	})
+	csrcs := make([]uint32, min(len(contributors), MaxCSRCs))
+	for i := range csrcs {
+		csrcs[i] = contributors[i].CSRC
+	}
+	return csrcs
+}
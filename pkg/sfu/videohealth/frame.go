@@ -0,0 +1,116 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package videohealth
+
+import (
+	"image"
+	"image/color"
+)
+
+const (
+	// frames are compared on a grid of luma averages, tolerating encoder noise
+	gridWidth  = 32
+	gridHeight = 18
+	// maximum mean absolute luma difference between frames considered identical
+	identicalThreshold = 1.0
+)
+
+type FrameAnalyzerParams struct {
+	// mean luma, 0-255, at or below which a frame is black
+	BlackLevel uint8
+	// identical frames in a row after which a track is frozen
+	FrozenFrames int
+}
+
+// FrameAnalyzer detects black and frozen video from decoded frames sampled over time, e.g. periodic snapshots
+type FrameAnalyzer struct {
+	params FrameAnalyzerParams
+
+	prev      []float64
+	identical int
+}
+
+func NewFrameAnalyzer(params FrameAnalyzerParams) *FrameAnalyzer {
+	return &FrameAnalyzer{
+		params: params,
+	}
+}
+
+// Analyze returns whether the frame is black, and whether the track is frozen with this frame
+func (a *FrameAnalyzer) Analyze(img image.Image) (bool, bool) {
+	grid := lumaGrid(img)
+
+	mean := 0.0
+	for _, l := range grid {
+		mean += l
+	}
+	mean /= float64(len(grid))
+	black := mean <= float64(a.params.BlackLevel)
+
+	if a.prev != nil && difference(a.prev, grid) <= identicalThreshold {
+		a.identical++
+	} else {
+		a.identical = 0
+	}
+	a.prev = grid
+
+	// a black frame is reported as black, not frozen
+	frozen := !black && a.params.FrozenFrames > 0 && a.identical >= a.params.FrozenFrames-1
+	return black, frozen
+}
+
+func lumaGrid(img image.Image) []float64 {
+	bounds := img.Bounds()
+	grid := make([]float64, gridWidth*gridHeight)
+	counts := make([]int, gridWidth*gridHeight)
+	if bounds.Empty() {
+		return grid
+	}
+
+	ycbcr, isYCbCr := img.(*image.YCbCr)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		gy := (y - bounds.Min.Y) * gridHeight / bounds.Dy()
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			gx := (x - bounds.Min.X) * gridWidth / bounds.Dx()
+
+			var luma uint8
+			if isYCbCr {
+				luma = ycbcr.Y[ycbcr.YOffset(x, y)]
+			} else {
+				luma = color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y
+			}
+			grid[gy*gridWidth+gx] += float64(luma)
+			counts[gy*gridWidth+gx]++
+		}
+	}
+	for i, c := range counts {
+		if c != 0 {
+			grid[i] /= float64(c)
+		}
+	}
+	return grid
+}
+
+func difference(a, b []float64) float64 {
+	diff := 0.0
+	for i := range a {
+		d := a[i] - b[i]
+		if d < 0 {
+			d = -d
+		}
+		diff += d
+	}
+	return diff / float64(len(a))
+}
@@ -0,0 +1,61 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package videohealth
+
+import (
+	"time"
+)
+
+// FreezeDetector detects a video layer whose packets keep arriving without its RTP timestamp advancing.
+// It is not safe for concurrent use, packets are observed from the forwarding goroutine of the layer.
+type FreezeDetector struct {
+	timeout time.Duration
+
+	initialized bool
+	lastTS      uint32
+	since       int64
+	frozen      bool
+}
+
+func NewFreezeDetector(timeout time.Duration) *FreezeDetector {
+	return &FreezeDetector{
+		timeout: timeout,
+	}
+}
+
+// Observe records a media packet, and returns whether the layer is frozen and if that changed with this packet
+func (d *FreezeDetector) Observe(ts uint32, arrivalTime int64) (bool, bool) {
+	if !d.initialized || ts != d.lastTS {
+		d.initialized = true
+		d.lastTS = ts
+		d.since = arrivalTime
+		if d.frozen {
+			d.frozen = false
+			return false, true
+		}
+		return false, false
+	}
+
+	if d.frozen || time.Duration(arrivalTime-d.since) < d.timeout {
+		return d.frozen, false
+	}
+	d.frozen = true
+	return true, true
+}
+
+// Frozen returns whether the layer was frozen at the last observed packet
+func (d *FreezeDetector) Frozen() bool {
+	return d.frozen
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package videohealth
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreezeDetector(t *testing.T) {
+	d := NewFreezeDetector(time.Second)
+	clock := time.Now()
+
+	// frames advance the timestamp
+	for i := 0; i < 10; i++ {
+		frozen, changed := d.Observe(uint32(i*3000), clock.UnixNano())
+		require.False(t, frozen)
+		require.False(t, changed)
+		clock = clock.Add(100 * time.Millisecond)
+	}
+
+	// packets keep arriving with the timestamp of the last frame
+	clock = clock.Add(-100 * time.Millisecond)
+	frozen, _ := d.Observe(27000, clock.Add(900*time.Millisecond).UnixNano())
+	require.False(t, frozen)
+	frozen, changed := d.Observe(27000, clock.Add(time.Second).UnixNano())
+	require.True(t, frozen)
+	require.True(t, changed)
+	require.True(t, d.Frozen())
+	frozen, changed = d.Observe(27000, clock.Add(2*time.Second).UnixNano())
+	require.True(t, frozen)
+	require.False(t, changed)
+
+	frozen, changed = d.Observe(30000, clock.Add(2*time.Second).UnixNano())
+	require.False(t, frozen)
+	require.True(t, changed)
+	require.False(t, d.Frozen())
+}
+
+func newFrame(luma func(x, y int) uint8) *image.YCbCr {
+	img := image.NewYCbCr(image.Rect(0, 0, 64, 36), image.YCbCrSubsampleRatio420)
+	for y := 0; y < 36; y++ {
+		for x := 0; x < 64; x++ {
+			img.Y[img.YOffset(x, y)] = luma(x, y)
+		}
+	}
+	return img
+}
+
+func TestFrameAnalyzer(t *testing.T) {
+	params := FrameAnalyzerParams{BlackLevel: 20, FrozenFrames: 3}
+
+	t.Run("black", func(t *testing.T) {
+		a := NewFrameAnalyzer(params)
+		black, frozen := a.Analyze(newFrame(func(_, _ int) uint8 { return 16 }))
+		require.True(t, black)
+		require.False(t, frozen)
+
+		black, frozen = a.Analyze(newFrame(func(_, _ int) uint8 { return 16 }))
+		require.True(t, black)
+		require.False(t, frozen)
+	})
+
+	t.Run("frozen", func(t *testing.T) {
+		a := NewFrameAnalyzer(params)
+		still := newFrame(func(x, y int) uint8 { return uint8(64 + x + y) })
+		for i := 0; i < 2; i++ {
+			black, frozen := a.Analyze(still)
+			require.False(t, black)
+			require.False(t, frozen)
+		}
+		black, frozen := a.Analyze(still)
+		require.False(t, black)
+		require.True(t, frozen)
+
+		// motion thaws it
+		_, frozen = a.Analyze(newFrame(func(x, y int) uint8 { return uint8(128 + x - y) }))
+		require.False(t, frozen)
+	})
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+var (
+	promAgentDispatchLatency *prometheus.HistogramVec
+	promAgentActiveJobs      *prometheus.GaugeVec
+	promAgentJobFailures     *prometheus.CounterVec
+	promAgentJobRetries      prometheus.Counter
+)
+
+func initAgentStats(nodeID string, nodeType livekit.NodeType) {
+	promAgentDispatchLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "agent",
+		Name:        "dispatch_latency_seconds",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Help:        "Time from launching an agent job to a worker accepting it.",
+		Buckets:     []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10},
+	}, []string{"job_type"})
+	promAgentActiveJobs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "agent",
+		Name:        "active_jobs",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Help:        "Agent jobs running on workers connected to this node.",
+	}, []string{"namespace"})
+	promAgentJobFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "agent",
+		Name:        "job_failures",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Help:        "Agent jobs that could not be dispatched or that failed on their worker.",
+	}, []string{"reason"})
+	promAgentJobRetries = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "agent",
+		Name:        "job_retries",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Help:        "Agent job assignments retried on another worker after a worker turned the job down.",
+	})
+
+	prometheus.MustRegister(promAgentDispatchLatency)
+	prometheus.MustRegister(promAgentActiveJobs)
+	prometheus.MustRegister(promAgentJobFailures)
+	prometheus.MustRegister(promAgentJobRetries)
+}
+
+// agent metrics are also recorded by the agent test server, which does not initialize prometheus
+
+func RecordAgentDispatchLatency(jobType livekit.JobType, latency time.Duration) {
+	if initialized.Load() {
+		promAgentDispatchLatency.WithLabelValues(jobType.String()).Observe(latency.Seconds())
+	}
+}
+
+func AddAgentJob(namespace string) {
+	if initialized.Load() {
+		promAgentActiveJobs.WithLabelValues(namespace).Inc()
+	}
+}
+
+func SubAgentJob(namespace string) {
+	if initialized.Load() {
+		promAgentActiveJobs.WithLabelValues(namespace).Dec()
+	}
+}
+
+func RecordAgentJobFailure(reason string) {
+	if initialized.Load() {
+		promAgentJobFailures.WithLabelValues(reason).Inc()
+	}
+}
+
+func RecordAgentJobRetry() {
+	if initialized.Load() {
+		promAgentJobRetries.Inc()
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+var (
+	promAudioLoudness   prometheus.Histogram
+	promAudioSamplePeak prometheus.Histogram
+)
+
+func initAudioStats(nodeID string, nodeType livekit.NodeType) {
+	promAudioLoudness = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "audio",
+		Name:        "integrated_loudness_lufs",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Help:        "Integrated loudness of audio tracks, recorded when they are unpublished.",
+		Buckets:     []float64{-60, -50, -40, -35, -30, -27, -24, -21, -18, -15, -10},
+	})
+	promAudioSamplePeak = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "audio",
+		Name:        "sample_peak_dbfs",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Help:        "Sample peak of audio tracks, recorded when they are unpublished. Peaks near 0 indicate clipping.",
+		Buckets:     []float64{-40, -30, -20, -12, -6, -3, -1, -0.1},
+	})
+
+	prometheus.MustRegister(promAudioLoudness)
+	prometheus.MustRegister(promAudioSamplePeak)
+}
+
+func RecordAudioLoudness(integrated float64, samplePeak float64) {
+	if initialized.Load() {
+		promAudioLoudness.Observe(integrated)
+		promAudioSamplePeak.Observe(samplePeak)
+	}
+}
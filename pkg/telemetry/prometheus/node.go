@@ -15,6 +15,7 @@
 package prometheus
 
 import (
+	"math"
 	"time"
 
 	"github.com/mackerelio/go-osstat/memory"
@@ -22,6 +23,7 @@ import (
 	"go.uber.org/atomic"
 
 	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/utils"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/rpc"
 	"github.com/livekit/protocol/utils/hwstats"
@@ -126,6 +128,11 @@ func Init(nodeID string, nodeType livekit.NodeType) error {
 	initRoomStats(nodeID, nodeType)
 	rpc.InitPSRPCStats(prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()})
 	initQualityStats(nodeID, nodeType)
+	initRedisStats(nodeID, nodeType)
+	initTURNStats(nodeID, nodeType)
+	initAgentStats(nodeID, nodeType)
+	initAudioStats(nodeID, nodeType)
+	initVideoStats(nodeID, nodeType)
 
 	var err error
 	cpuStats, err = hwstats.NewCPUStats(nil)
@@ -157,6 +164,13 @@ func GetUpdatedNodeStats(prev *livekit.NodeStats, prevAverage *livekit.NodeStats
 		memTotal = memInfo.Total
 		memUsed = memInfo.Used
 	}
+	// inside a container, report the cgroup limit rather than the host's memory
+	if limit := utils.GetCgroupLimits().Memory; limit > 0 && (memTotal == 0 || limit < memTotal) {
+		memTotal = limit
+		if usage, ok := utils.GetCgroupMemoryUsage(); ok {
+			memUsed = usage
+		}
+	}
 
 	// do not error out, and use the information if it is available
 	sysPackets, sysDroppedPackets, _ := getTCStats()
@@ -226,7 +240,7 @@ func GetUpdatedNodeStats(prev *livekit.NodeStats, prevAverage *livekit.NodeStats
 		ParticipantSignalConnectedPerSec: prevAverage.ParticipantSignalConnectedPerSec,
 		ParticipantRtcInitPerSec:         prevAverage.ParticipantRtcInitPerSec,
 		ParticipantRtcConnectedPerSec:    prevAverage.ParticipantRtcConnectedPerSec,
-		NumCpus:                          uint32(cpuStats.NumCPU()), // this will round down to the nearest integer
+		NumCpus:                          uint32(math.Ceil(cpuStats.NumCPU())), // round up so fractional quotas do not report zero
 		CpuLoad:                          float32(cpuLoad),
 		MemoryTotal:                      memTotal,
 		MemoryUsed:                       memUsed,
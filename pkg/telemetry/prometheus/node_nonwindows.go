@@ -19,11 +19,12 @@
 package prometheus
 
 import (
-	"runtime"
 	"sync"
 
 	"github.com/mackerelio/go-osstat/cpu"
 	"github.com/mackerelio/go-osstat/loadavg"
+
+	"github.com/livekit/livekit-server/pkg/utils"
 )
 
 var (
@@ -50,7 +51,7 @@ func getCPUStats() (cpuLoad float32, numCPUs uint32, err error) {
 	lastCPUIdle = cpuInfo.Idle
 	cpuStatsLock.Unlock()
 
-	numCPUs = uint32(runtime.NumCPU())
+	numCPUs = uint32(utils.NumCPUCeil())
 
 	return
 }
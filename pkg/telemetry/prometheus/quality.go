@@ -15,15 +15,21 @@
 package prometheus
 
 import (
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/livekit/protocol/livekit"
 )
 
 var (
-	qualityRating prometheus.Histogram
-	qualityScore  prometheus.Histogram
-	qualityDrop   *prometheus.CounterVec
+	qualityRating        prometheus.Histogram
+	qualityScore         prometheus.Histogram
+	qualityDrop          *prometheus.CounterVec
+	qualityLatency       prometheus.Histogram
+	qualityAVSync        prometheus.Histogram
+	qualityAVSyncChanges *prometheus.CounterVec
+	qualityProbe         *prometheus.CounterVec
 )
 
 func initQualityStats(nodeID string, nodeType livekit.NodeType) {
@@ -47,10 +53,44 @@ func initQualityStats(nodeID string, nodeType livekit.NodeType) {
 		Name:        "drop",
 		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
 	}, []string{"direction"})
+	qualityLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "quality",
+		Name:        "latency_ms",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Help:        "Estimated glass to glass latency of subscribed tracks.",
+		Buckets:     []float64{50, 100, 150, 200, 300, 400, 600, 800, 1000, 1500, 2000},
+	})
+	qualityAVSync = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "quality",
+		Name:        "av_sync_drift_ms",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Help:        "Absolute drift between audio and video of a publisher as forwarded to subscribers.",
+		Buckets:     []float64{10, 20, 40, 60, 80, 100, 150, 200, 300, 500, 1000},
+	})
+	qualityAVSyncChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "quality",
+		Name:        "av_sync_drift_changes",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Help:        "Publisher camera tracks whose drift from audio on a subscriber exceeded the threshold, or recovered.",
+	}, []string{"state"})
+	qualityProbe = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "quality",
+		Name:        "bwe_probes",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Help:        "Bandwidth probes of subscriber transports, by the decision taken at the end of the probe.",
+	}, []string{"decision"})
 
 	prometheus.MustRegister(qualityRating)
 	prometheus.MustRegister(qualityScore)
 	prometheus.MustRegister(qualityDrop)
+	prometheus.MustRegister(qualityLatency)
+	prometheus.MustRegister(qualityAVSync)
+	prometheus.MustRegister(qualityAVSyncChanges)
+	prometheus.MustRegister(qualityProbe)
 }
 
 func RecordQuality(rating livekit.ConnectionQuality, score float32, numUpDrops int, numDownDrops int) {
@@ -59,3 +99,26 @@ func RecordQuality(rating livekit.ConnectionQuality, score float32, numUpDrops i
 	qualityDrop.WithLabelValues("up").Add(float64(numUpDrops))
 	qualityDrop.WithLabelValues("down").Add(float64(numDownDrops))
 }
+
+func RecordLatency(latency time.Duration) {
+	qualityLatency.Observe(float64(latency.Milliseconds()))
+}
+
+func RecordAVSyncDrift(drift time.Duration) {
+	if drift < 0 {
+		drift = -drift
+	}
+	qualityAVSync.Observe(float64(drift.Milliseconds()))
+}
+
+func RecordAVSyncDriftChange(exceeded bool) {
+	state := "recovered"
+	if exceeded {
+		state = "exceeded"
+	}
+	qualityAVSyncChanges.WithLabelValues(state).Inc()
+}
+
+func RecordProbeResult(decision string) {
+	qualityProbe.WithLabelValues(decision).Inc()
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+var (
+	promRedisFailovers prometheus.Counter
+	promRedisErrors    prometheus.Counter
+	promRedisAvailable prometheus.Gauge
+)
+
+func initRedisStats(nodeID string, nodeType livekit.NodeType) {
+	promRedisFailovers = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "redis",
+		Name:        "failovers",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Help:        "Number of times the Redis master changed.",
+	})
+	promRedisErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "redis",
+		Name:        "connection_errors",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Help:        "Number of Redis commands or dials failing due to connection errors.",
+	})
+	promRedisAvailable = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "redis",
+		Name:        "available",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Help:        "1 when Redis is reachable, 0 while connections are failing.",
+	})
+
+	prometheus.MustRegister(promRedisFailovers)
+	prometheus.MustRegister(promRedisErrors)
+	prometheus.MustRegister(promRedisAvailable)
+
+	promRedisAvailable.Set(1)
+}
+
+// redis metrics are also recorded by CLI commands, which do not initialize prometheus
+
+func RecordRedisFailover() {
+	if initialized.Load() {
+		promRedisFailovers.Inc()
+	}
+}
+
+func RecordRedisError() {
+	if initialized.Load() {
+		promRedisErrors.Inc()
+	}
+}
+
+func SetRedisAvailable(available bool) {
+	if !initialized.Load() {
+		return
+	}
+	if available {
+		promRedisAvailable.Set(1)
+	} else {
+		promRedisAvailable.Set(0)
+	}
+}
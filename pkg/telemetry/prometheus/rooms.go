@@ -133,6 +133,11 @@ func RoomEnded(startedAt time.Time) {
 	roomCurrent.Dec()
 }
 
+// CurrentCounts returns the participants and tracks currently accounted for on this node
+func CurrentCounts() (participants, tracksIn, tracksOut int32) {
+	return participantCurrent.Load(), trackPublishedCurrent.Load(), trackSubscribedCurrent.Load()
+}
+
 func AddParticipant() {
 	promParticipantCurrent.Add(1)
 	participantCurrent.Inc()
@@ -0,0 +1,82 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package prometheus
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+var (
+	promTURNServerHealthy     *prometheus.GaugeVec
+	promTURNServerRTT         *prometheus.GaugeVec
+	promTURNServerProbeErrors *prometheus.CounterVec
+)
+
+func initTURNStats(nodeID string, nodeType livekit.NodeType) {
+	promTURNServerHealthy = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "turn_server",
+		Name:        "healthy",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Help:        "1 when the external TURN server answers probes from this node, 0 when it is dropped from client configuration.",
+	}, []string{"server"})
+	promTURNServerRTT = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "turn_server",
+		Name:        "rtt_ms",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Help:        "Round trip time of the last successful probe of the external TURN server.",
+	}, []string{"server"})
+	promTURNServerProbeErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "turn_server",
+		Name:        "probe_errors",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Help:        "Number of failed probes of the external TURN server.",
+	}, []string{"server"})
+
+	prometheus.MustRegister(promTURNServerHealthy)
+	prometheus.MustRegister(promTURNServerRTT)
+	prometheus.MustRegister(promTURNServerProbeErrors)
+}
+
+func RecordTURNServerProbe(server string, healthy bool, rtt time.Duration, err error) {
+	if !initialized.Load() {
+		return
+	}
+	if healthy {
+		promTURNServerHealthy.WithLabelValues(server).Set(1)
+	} else {
+		promTURNServerHealthy.WithLabelValues(server).Set(0)
+	}
+	if err != nil {
+		promTURNServerProbeErrors.WithLabelValues(server).Inc()
+	} else {
+		promTURNServerRTT.WithLabelValues(server).Set(float64(rtt.Milliseconds()))
+	}
+}
+
+// RemoveTURNServer drops the metrics of a TURN server removed from configuration
+func RemoveTURNServer(server string) {
+	if !initialized.Load() {
+		return
+	}
+	promTURNServerHealthy.DeleteLabelValues(server)
+	promTURNServerRTT.DeleteLabelValues(server)
+	promTURNServerProbeErrors.DeleteLabelValues(server)
+}
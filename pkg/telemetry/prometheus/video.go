@@ -0,0 +1,66 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+var (
+	promVideoHealthChanges *prometheus.CounterVec
+	promVideoHealthTracks  *prometheus.GaugeVec
+)
+
+func initVideoStats(nodeID string, nodeType livekit.NodeType) {
+	promVideoHealthChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "video",
+		Name:        "track_health_changes",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Help:        "Video tracks detected as frozen or black, or recovering (ok).",
+	}, []string{"health"})
+	promVideoHealthTracks = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "video",
+		Name:        "track_health",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Help:        "Published video tracks with health detection, by current health.",
+	}, []string{"health"})
+
+	prometheus.MustRegister(promVideoHealthChanges)
+	prometheus.MustRegister(promVideoHealthTracks)
+}
+
+func AddVideoHealthTrack(health string) {
+	if initialized.Load() {
+		promVideoHealthTracks.WithLabelValues(health).Inc()
+	}
+}
+
+func RemoveVideoHealthTrack(health string) {
+	if initialized.Load() {
+		promVideoHealthTracks.WithLabelValues(health).Dec()
+	}
+}
+
+func RecordVideoHealthChange(from string, to string) {
+	if initialized.Load() {
+		promVideoHealthChanges.WithLabelValues(to).Inc()
+		promVideoHealthTracks.WithLabelValues(from).Dec()
+		promVideoHealthTracks.WithLabelValues(to).Inc()
+	}
+}
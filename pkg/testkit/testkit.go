@@ -0,0 +1,344 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package testkit boots a fully wired LiveKit server in-process so that
+// integration tests can run without docker-compose or an external redis.
+package testkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/twitchtv/twirp"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+	testclient "github.com/livekit/livekit-server/test/client"
+	"github.com/livekit/mediatransportutil/pkg/rtcconfig"
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils/guid"
+)
+
+const (
+	DefaultAPIKey    = "testkit"
+	DefaultAPISecret = "testkitSecretExtendTo32BytesAsThatIsMinimum"
+
+	loopbackAddress = "127.0.0.1"
+	defaultTimeout  = 10 * time.Second
+)
+
+var (
+	ErrServerStartTimeout = errors.New("server did not start before timeout")
+
+	initOnce sync.Once
+)
+
+type Options struct {
+	APIKey    string
+	APISecret string
+	// StartTimeout bounds how long Start waits for the server to accept requests
+	StartTimeout time.Duration
+	// ConfigUpdater is applied after the defaults, ports and keys have been set
+	ConfigUpdater func(conf *config.Config)
+}
+
+type Server struct {
+	*service.LivekitServer
+
+	conf       *config.Config
+	apiKey     string
+	apiSecret  string
+	roomClient livekit.RoomService
+	errChan    chan error
+
+	lock         sync.Mutex
+	participants []*Participant
+}
+
+// Start creates a single node server backed by the in-memory store, listening on free loopback ports.
+func Start(opts *Options) (*Server, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	apiKey, apiSecret := opts.APIKey, opts.APISecret
+	if apiKey == "" || apiSecret == "" {
+		apiKey, apiSecret = DefaultAPIKey, DefaultAPISecret
+	}
+	timeout := opts.StartTimeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	initOnce.Do(func() {
+		prometheus.Init("testkit", livekit.NodeType_SERVER)
+	})
+
+	conf, err := config.NewConfig("", true, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	ports, err := freePorts()
+	if err != nil {
+		return nil, err
+	}
+	conf.BindAddresses = []string{loopbackAddress}
+	conf.Port = uint32(ports[0])
+	conf.RTC.TCPPort = uint32(ports[1])
+	conf.RTC.UDPPort = rtcconfig.PortRange{Start: ports[2]}
+	conf.RTC.NodeIP = loopbackAddress
+	conf.RTC.UseExternalIP = false
+	conf.RTC.EnableLoopbackCandidate = true
+	conf.Keys = map[string]string{apiKey: apiSecret}
+	if opts.ConfigUpdater != nil {
+		opts.ConfigUpdater(conf)
+	}
+
+	currentNode, err := routing.NewLocalNode(conf)
+	if err != nil {
+		return nil, err
+	}
+	currentNode.Id = guid.New(guid.NodePrefix)
+
+	ls, err := service.InitializeServer(conf, currentNode)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		LivekitServer: ls,
+		conf:          conf,
+		apiKey:        apiKey,
+		apiSecret:     apiSecret,
+		errChan:       make(chan error, 1),
+	}
+	s.roomClient = livekit.NewRoomServiceJSONClient(s.URL(), &http.Client{})
+
+	go func() {
+		if err := ls.Start(); err != nil {
+			logger.Errorw("testkit server returned error", err)
+			s.errChan <- err
+		}
+	}()
+
+	if err := s.waitUntilRunning(timeout); err != nil {
+		ls.Stop(true)
+		return nil, err
+	}
+	return s, nil
+}
+
+// New starts a server for the duration of the test, failing the test if it cannot be started
+func New(t testing.TB, opts *Options) *Server {
+	t.Helper()
+	s, err := Start(opts)
+	if err != nil {
+		t.Fatalf("could not start testkit server: %v", err)
+	}
+	t.Cleanup(s.Stop)
+	return s
+}
+
+// Stop disconnects all participants created through the server and shuts it down
+func (s *Server) Stop() {
+	s.lock.Lock()
+	participants := s.participants
+	s.participants = nil
+	s.lock.Unlock()
+
+	for _, p := range participants {
+		p.Stop()
+	}
+	s.LivekitServer.Stop(true)
+}
+
+func (s *Server) Config() *config.Config {
+	return s.conf
+}
+
+func (s *Server) APIKey() string {
+	return s.apiKey
+}
+
+func (s *Server) APISecret() string {
+	return s.apiSecret
+}
+
+func (s *Server) URL() string {
+	return fmt.Sprintf("http://%s:%d", loopbackAddress, s.conf.Port)
+}
+
+func (s *Server) WSURL() string {
+	return fmt.Sprintf("ws://%s:%d", loopbackAddress, s.conf.Port)
+}
+
+// Token returns a join token for the room, customizer may be nil
+func (s *Server) Token(room, identity string, customizer func(token *auth.AccessToken, grant *auth.VideoGrant)) (string, error) {
+	at := auth.NewAccessToken(s.apiKey, s.apiSecret).
+		SetIdentity(identity).
+		SetName(identity)
+	grant := &auth.VideoGrant{RoomJoin: true, Room: room}
+	if customizer != nil {
+		customizer(at, grant)
+	}
+	at.AddGrant(grant)
+	return at.ToJWT()
+}
+
+// AdminContext returns a context authorized for RoomService calls, room scopes admin operations
+func (s *Server) AdminContext(ctx context.Context, room string) (context.Context, error) {
+	at := auth.NewAccessToken(s.apiKey, s.apiSecret).
+		AddGrant(&auth.VideoGrant{RoomCreate: true, RoomList: true, RoomAdmin: true, Room: room})
+	token, err := at.ToJWT()
+	if err != nil {
+		return nil, err
+	}
+	header := make(http.Header)
+	testclient.SetAuthorizationToken(header, token)
+	return twirp.WithHTTPRequestHeaders(ctx, header)
+}
+
+// RoomClient returns a RoomService client, requests need to be made with AdminContext
+func (s *Server) RoomClient() livekit.RoomService {
+	return s.roomClient
+}
+
+func (s *Server) CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest) (*livekit.Room, error) {
+	actx, err := s.AdminContext(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	return s.roomClient.CreateRoom(actx, req)
+}
+
+func (s *Server) ListParticipants(ctx context.Context, room string) ([]*livekit.ParticipantInfo, error) {
+	actx, err := s.AdminContext(ctx, room)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.roomClient.ListParticipants(actx, &livekit.ListParticipantsRequest{Room: room})
+	if err != nil {
+		return nil, err
+	}
+	return res.Participants, nil
+}
+
+// Join connects a fake participant to the room and waits until its transports are connected
+func (s *Server) Join(room, identity string, opts *testclient.Options) (*Participant, error) {
+	var customizer func(token *auth.AccessToken, grant *auth.VideoGrant)
+	if opts != nil {
+		customizer = opts.TokenCustomizer
+	}
+	token, err := s.Token(room, identity, customizer)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := testclient.NewWebSocketConn(s.WSURL(), token, opts)
+	if err != nil {
+		return nil, err
+	}
+	c, err := testclient.NewRTCClient(conn, opts)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	go c.Run()
+
+	p := &Participant{RTCClient: c, identity: identity}
+	if err := c.WaitUntilConnected(); err != nil {
+		c.Stop()
+		return nil, err
+	}
+
+	s.lock.Lock()
+	s.participants = append(s.participants, p)
+	s.lock.Unlock()
+	return p, nil
+}
+
+func (s *Server) waitUntilRunning(timeout time.Duration) error {
+	deadline := time.After(timeout)
+	for {
+		select {
+		case err := <-s.errChan:
+			return err
+		case <-deadline:
+			return ErrServerStartTimeout
+		case <-time.After(10 * time.Millisecond):
+			if !s.IsRunning() {
+				continue
+			}
+			res, err := http.Get(s.URL())
+			if err != nil {
+				continue
+			}
+			_ = res.Body.Close()
+			if res.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+	}
+}
+
+// ------------------------------------------------
+
+type Participant struct {
+	*testclient.RTCClient
+
+	identity string
+}
+
+func (p *Participant) Identity() string {
+	return p.identity
+}
+
+// PublishSyntheticTrack publishes an opus or vp8 track fed with generated frames at the given bitrate (bps)
+func (p *Participant) PublishSyntheticTrack(trackType livekit.TrackType, name string, bitrate int) (*testclient.TrackWriter, error) {
+	mime := "audio/opus"
+	if trackType == livekit.TrackType_VIDEO {
+		mime = "video/vp8"
+	}
+	return p.AddSyntheticTrack(mime, guid.New(guid.TrackPrefix), name, bitrate)
+}
+
+// ------------------------------------------------
+
+// freePorts reserves an HTTP, RTC TCP and RTC UDP port on loopback
+func freePorts() ([]int, error) {
+	var ports []int
+	for i := 0; i < 2; i++ {
+		l, err := net.Listen("tcp", loopbackAddress+":0")
+		if err != nil {
+			return nil, err
+		}
+		defer l.Close()
+		ports = append(ports, l.Addr().(*net.TCPAddr).Port)
+	}
+	c, err := net.ListenPacket("udp", loopbackAddress+":0")
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return append(ports, c.LocalAddr().(*net.UDPAddr).Port), nil
+}
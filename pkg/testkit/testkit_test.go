@@ -0,0 +1,69 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testkit_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/testkit"
+	"github.com/livekit/livekit-server/pkg/testutils"
+	"github.com/livekit/protocol/livekit"
+)
+
+func TestServer(t *testing.T) {
+	s := testkit.New(t, nil)
+
+	room, err := s.CreateRoom(context.Background(), &livekit.CreateRoomRequest{Name: "testkit"})
+	require.NoError(t, err)
+	require.Equal(t, "testkit", room.Name)
+
+	alice, err := s.Join("testkit", "alice", nil)
+	require.NoError(t, err)
+	bob, err := s.Join("testkit", "bob", nil)
+	require.NoError(t, err)
+	require.Equal(t, "alice", alice.Identity())
+
+	writer, err := alice.PublishSyntheticTrack(livekit.TrackType_AUDIO, "synthetic", 32_000)
+	require.NoError(t, err)
+	defer writer.Stop()
+
+	testutils.WithTimeout(t, func() string {
+		tracks := bob.SubscribedTracks()[alice.ID()]
+		if len(tracks) != 1 {
+			return fmt.Sprintf("expected 1 subscribed track, got %d", len(tracks))
+		}
+		if bob.BytesReceived() == 0 {
+			return "no media received"
+		}
+		return ""
+	})
+
+	participants, err := s.ListParticipants(context.Background(), "testkit")
+	require.NoError(t, err)
+	require.Len(t, participants, 2)
+}
+
+func TestServerParallel(t *testing.T) {
+	s1 := testkit.New(t, nil)
+	s2 := testkit.New(t, &testkit.Options{APIKey: "other", APISecret: "otherSecretExtendTo32BytesAsThatIsMinimum"})
+	require.NotEqual(t, s1.URL(), s2.URL())
+
+	_, err := s2.Join("room", "carol", nil)
+	require.NoError(t, err)
+}
@@ -0,0 +1,146 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/livekit/protocol/logger"
+)
+
+// cert-manager and kubernetes secret mounts replace the certificate and key in several steps, wait for them to settle
+const certReloadDebounce = 500 * time.Millisecond
+
+// CertReloader serves a TLS certificate that is reloaded when its files change, so certificates can be
+// rotated without dropping connections. Established connections keep the certificate they were accepted with
+type CertReloader struct {
+	name     string
+	certFile string
+	keyFile  string
+
+	lock     sync.Mutex
+	cert     atomic.Pointer[tls.Certificate]
+	contents []byte
+
+	watcher *fsnotify.Watcher
+}
+
+func NewCertReloader(name, certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{
+		name:     name,
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	// the directories are watched, as mounted secrets are updated by replacing a symlink
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		for _, dir := range []string{filepath.Dir(certFile), filepath.Dir(keyFile)} {
+			if err = watcher.Add(dir); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		logger.Warnw("could not watch certificate files, reload with SIGHUP", err, "cert", name)
+		if watcher != nil {
+			_ = watcher.Close()
+		}
+		return r, nil
+	}
+	r.watcher = watcher
+	go r.watch()
+	return r, nil
+}
+
+func (r *CertReloader) Name() string {
+	return r.name
+}
+
+func (r *CertReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// Reload loads the certificate when its files have changed. The current certificate is kept when loading fails
+func (r *CertReloader) Reload() (bool, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	certPEM, err := os.ReadFile(r.certFile)
+	if err != nil {
+		return false, err
+	}
+	keyPEM, err := os.ReadFile(r.keyFile)
+	if err != nil {
+		return false, err
+	}
+	contents := append(certPEM, keyPEM...)
+	if r.cert.Load() != nil && bytes.Equal(contents, r.contents) {
+		return false, nil
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return false, err
+	}
+	r.cert.Store(&cert)
+	r.contents = contents
+	if cert.Leaf != nil {
+		logger.Infow("loaded certificate", "cert", r.name, "subject", cert.Leaf.Subject.String(), "notAfter", cert.Leaf.NotAfter)
+	}
+	return true, nil
+}
+
+func (r *CertReloader) Stop() {
+	if r.watcher != nil {
+		_ = r.watcher.Close()
+	}
+}
+
+func (r *CertReloader) watch() {
+	var debounce <-chan time.Time
+	for {
+		select {
+		case _, ok := <-r.watcher.Events:
+			if !ok {
+				return
+			}
+			debounce = time.After(certReloadDebounce)
+
+		case err, ok := <-r.watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Warnw("certificate watcher error", err, "cert", r.name)
+
+		case <-debounce:
+			debounce = nil
+			if _, err := r.Reload(); err != nil {
+				logger.Warnw("could not reload certificate, keeping current certificate", err, "cert", r.name)
+			}
+		}
+	}
+}
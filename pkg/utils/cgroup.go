@@ -0,0 +1,130 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// values at or above this are what cgroup v1 reports when memory is unlimited
+const cgroupUnlimitedMemory = uint64(1) << 62
+
+// CgroupLimits are the CPU and memory limits imposed on this process by its cgroup.
+// Zero values mean no limit is set, or that cgroups are not available.
+type CgroupLimits struct {
+	CPUs   float64
+	Memory uint64
+}
+
+// GetCgroupLimits reads the cgroup v2 limits, falling back to cgroup v1
+func GetCgroupLimits() CgroupLimits {
+	return readCgroupLimits(cgroupRoot)
+}
+
+// GetCgroupMemoryUsage returns the memory currently charged to this process's cgroup
+func GetCgroupMemoryUsage() (uint64, bool) {
+	return readCgroupMemoryUsage(cgroupRoot)
+}
+
+// NumCPU returns the number of CPUs available to this process, taking the cgroup CPU quota into account.
+// The result may be fractional, e.g. 1.5 for a container limited to one and a half cores.
+func NumCPU() float64 {
+	numCPU := float64(runtime.NumCPU())
+	if cpus := GetCgroupLimits().CPUs; cpus > 0 && cpus < numCPU {
+		return cpus
+	}
+	return numCPU
+}
+
+// NumCPUCeil is NumCPU rounded up, for places that need a whole number of cores
+func NumCPUCeil() int {
+	return int(math.Ceil(NumCPU()))
+}
+
+func readCgroupLimits(root string) CgroupLimits {
+	var limits CgroupLimits
+
+	// cgroup v2: "<quota> <period>" or "max <period>"
+	if fields := readCgroupFields(filepath.Join(root, "cpu.max")); len(fields) == 2 {
+		limits.CPUs = cpuQuota(fields[0], fields[1])
+	} else {
+		for _, dir := range []string{"cpu", "cpu,cpuacct"} {
+			quota := readCgroupFields(filepath.Join(root, dir, "cpu.cfs_quota_us"))
+			period := readCgroupFields(filepath.Join(root, dir, "cpu.cfs_period_us"))
+			if len(quota) == 1 && len(period) == 1 {
+				limits.CPUs = cpuQuota(quota[0], period[0])
+				break
+			}
+		}
+	}
+
+	if fields := readCgroupFields(filepath.Join(root, "memory.max")); len(fields) == 1 {
+		limits.Memory = memoryValue(fields[0])
+	} else if fields := readCgroupFields(filepath.Join(root, "memory", "memory.limit_in_bytes")); len(fields) == 1 {
+		limits.Memory = memoryValue(fields[0])
+	}
+
+	return limits
+}
+
+func readCgroupMemoryUsage(root string) (uint64, bool) {
+	for _, path := range []string{
+		filepath.Join(root, "memory.current"),
+		filepath.Join(root, "memory", "memory.usage_in_bytes"),
+	} {
+		if fields := readCgroupFields(path); len(fields) == 1 {
+			if usage, err := strconv.ParseUint(fields[0], 10, 64); err == nil {
+				return usage, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func readCgroupFields(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(data))
+}
+
+func cpuQuota(quota, period string) float64 {
+	q, err := strconv.ParseInt(quota, 10, 64)
+	if err != nil || q <= 0 {
+		// "max" in v2 and -1 in v1 mean no quota
+		return 0
+	}
+	p, err := strconv.ParseInt(period, 10, 64)
+	if err != nil || p <= 0 {
+		return 0
+	}
+	return float64(q) / float64(p)
+}
+
+func memoryValue(value string) uint64 {
+	limit, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || limit >= cgroupUnlimitedMemory {
+		return 0
+	}
+	return limit
+}
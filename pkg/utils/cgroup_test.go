@@ -0,0 +1,87 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeCgroupFiles(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return root
+}
+
+func TestCgroupLimits(t *testing.T) {
+	t.Run("v2 with limits", func(t *testing.T) {
+		root := writeCgroupFiles(t, map[string]string{
+			"cpu.max":        "150000 100000\n",
+			"memory.max":     "2147483648\n",
+			"memory.current": "536870912\n",
+		})
+		require.Equal(t, CgroupLimits{CPUs: 1.5, Memory: 2 << 30}, readCgroupLimits(root))
+
+		usage, ok := readCgroupMemoryUsage(root)
+		require.True(t, ok)
+		require.Equal(t, uint64(512<<20), usage)
+	})
+
+	t.Run("v2 unlimited", func(t *testing.T) {
+		root := writeCgroupFiles(t, map[string]string{
+			"cpu.max":    "max 100000\n",
+			"memory.max": "max\n",
+		})
+		require.Equal(t, CgroupLimits{}, readCgroupLimits(root))
+	})
+
+	t.Run("v1 with limits", func(t *testing.T) {
+		root := writeCgroupFiles(t, map[string]string{
+			"cpu,cpuacct/cpu.cfs_quota_us":  "50000\n",
+			"cpu,cpuacct/cpu.cfs_period_us": "100000\n",
+			"memory/memory.limit_in_bytes":  "1073741824\n",
+			"memory/memory.usage_in_bytes":  "1048576\n",
+		})
+		require.Equal(t, CgroupLimits{CPUs: 0.5, Memory: 1 << 30}, readCgroupLimits(root))
+
+		usage, ok := readCgroupMemoryUsage(root)
+		require.True(t, ok)
+		require.Equal(t, uint64(1<<20), usage)
+	})
+
+	t.Run("v1 unlimited", func(t *testing.T) {
+		root := writeCgroupFiles(t, map[string]string{
+			"cpu/cpu.cfs_quota_us":         "-1\n",
+			"cpu/cpu.cfs_period_us":        "100000\n",
+			"memory/memory.limit_in_bytes": "9223372036854771712\n",
+		})
+		require.Equal(t, CgroupLimits{}, readCgroupLimits(root))
+	})
+
+	t.Run("no cgroups", func(t *testing.T) {
+		root := t.TempDir()
+		require.Equal(t, CgroupLimits{}, readCgroupLimits(root))
+
+		_, ok := readCgroupMemoryUsage(root)
+		require.False(t, ok)
+	})
+}
@@ -23,6 +23,7 @@ const (
 	ComponentAPI       = "api"
 	ComponentTransport = "transport"
 	ComponentSFU       = "sfu"
+	ComponentRelay     = "relay"
 	// transport subcomponents
 	ComponentCongestionControl = "cc"
 )
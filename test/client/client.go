@@ -89,7 +89,10 @@ type RTCClient struct {
 	lastPackets   map[livekit.ParticipantID]*rtp.Packet
 	bytesReceived map[livekit.ParticipantID]uint64
 
-	subscriptionResponse atomic.Pointer[livekit.SubscriptionResponse]
+	subscriptionResponse         atomic.Pointer[livekit.SubscriptionResponse]
+	subscriptionPermissionUpdate atomic.Pointer[livekit.SubscriptionPermissionUpdate]
+	leave                        atomic.Pointer[livekit.LeaveRequest]
+	localUpdate                  atomic.Pointer[livekit.ParticipantInfo]
 }
 
 var (
@@ -116,6 +119,8 @@ type Options struct {
 	TokenCustomizer           func(token *auth.AccessToken, grants *auth.VideoGrant)
 	SignalRequestInterceptor  SignalRequestInterceptor
 	SignalResponseInterceptor SignalResponseInterceptor
+	// resume the session of this participant instead of joining, set by RTCClient.Resume
+	ResumeParticipantID livekit.ParticipantID
 }
 
 func NewWebSocketConn(host, token string, opts *Options) (*websocket.Conn, error) {
@@ -132,6 +137,9 @@ func NewWebSocketConn(host, token string, opts *Options) (*websocket.Conn, error
 		if opts.Publish != "" {
 			connectUrl += encodeQueryParam("publish", opts.Publish)
 		}
+		if opts.ResumeParticipantID != "" {
+			connectUrl += "&reconnect=1" + encodeQueryParam("sid", string(opts.ResumeParticipantID))
+		}
 		if opts.ClientInfo != nil {
 			if opts.ClientInfo.DeviceModel != "" {
 				connectUrl += encodeQueryParam("device_model", opts.ClientInfo.DeviceModel)
@@ -309,7 +317,8 @@ func (c *RTCClient) ID() livekit.ParticipantID {
 
 // create an offer for the server
 func (c *RTCClient) Run() error {
-	c.conn.SetCloseHandler(func(code int, text string) error {
+	conn := c.getConn()
+	conn.SetCloseHandler(func(code int, text string) error {
 		// when closed, stop connection
 		logger.Infow("connection closed", "code", code, "text", text)
 		c.Stop()
@@ -318,7 +327,7 @@ func (c *RTCClient) Run() error {
 
 	// run the session
 	for {
-		res, err := c.ReadResponse()
+		res, err := c.readResponse(conn)
 		if errors.Is(io.EOF, err) {
 			return nil
 		} else if err != nil {
@@ -385,6 +394,8 @@ func (c *RTCClient) handleSignalResponse(res *livekit.SignalResponse) error {
 				} else {
 					delete(c.remoteParticipants, livekit.ParticipantID(p.Sid))
 				}
+			} else {
+				c.localUpdate.Store(p)
 			}
 		}
 		c.lock.Unlock()
@@ -416,6 +427,10 @@ func (c *RTCClient) handleSignalResponse(res *livekit.SignalResponse) error {
 		c.pongReceivedAt.Store(msg.Pong)
 	case *livekit.SignalResponse_SubscriptionResponse:
 		c.subscriptionResponse.Store(msg.SubscriptionResponse)
+	case *livekit.SignalResponse_SubscriptionPermissionUpdate:
+		c.subscriptionPermissionUpdate.Store(msg.SubscriptionPermissionUpdate)
+	case *livekit.SignalResponse_Leave:
+		c.leave.Store(msg.Leave)
 	}
 	return nil
 }
@@ -445,10 +460,43 @@ func (c *RTCClient) WaitUntilConnected() error {
 	}
 }
 
+// Resume drops the signal connection and resumes the session on a new one, the peer connections are kept as they are
+func (c *RTCClient) Resume(host, token string, opts *Options) error {
+	// without a leave, the server treats it as a dropped signal connection
+	_ = c.getConn().Close()
+
+	var resumeOpts Options
+	if opts != nil {
+		resumeOpts = *opts
+	}
+	resumeOpts.ResumeParticipantID = c.ID()
+	conn, err := NewWebSocketConn(host, token, &resumeOpts)
+	if err != nil {
+		return err
+	}
+
+	c.wsLock.Lock()
+	c.conn = conn
+	c.wsLock.Unlock()
+
+	go c.Run()
+	return nil
+}
+
+func (c *RTCClient) getConn() *websocket.Conn {
+	c.wsLock.Lock()
+	defer c.wsLock.Unlock()
+	return c.conn
+}
+
 func (c *RTCClient) ReadResponse() (*livekit.SignalResponse, error) {
+	return c.readResponse(c.getConn())
+}
+
+func (c *RTCClient) readResponse(conn *websocket.Conn) (*livekit.SignalResponse, error) {
 	for {
 		// handle special messages and pass on the rest
-		messageType, payload, err := c.conn.ReadMessage()
+		messageType, payload, err := conn.ReadMessage()
 		if err != nil {
 			return nil, err
 		}
@@ -460,7 +508,7 @@ func (c *RTCClient) ReadResponse() (*livekit.SignalResponse, error) {
 		msg := &livekit.SignalResponse{}
 		switch messageType {
 		case websocket.PingMessage:
-			_ = c.conn.WriteMessage(websocket.PongMessage, nil)
+			_ = conn.WriteMessage(websocket.PongMessage, nil)
 			continue
 		case websocket.BinaryMessage:
 			// protobuf encoded
@@ -507,7 +555,7 @@ func (c *RTCClient) Stop() {
 	})
 	c.publisherFullyEstablished.Store(false)
 	c.subscriberFullyEstablished.Store(false)
-	_ = c.conn.Close()
+	_ = c.getConn().Close()
 	c.publisher.Close()
 	c.subscriber.Close()
 	c.cancel()
@@ -527,6 +575,21 @@ func (c *RTCClient) GetSubscriptionResponseAndClear() *livekit.SubscriptionRespo
 	return c.subscriptionResponse.Swap(nil)
 }
 
+// GetSubscriptionPermissionUpdate returns the last change of permission to subscribe to a track, if any
+func (c *RTCClient) GetSubscriptionPermissionUpdate() *livekit.SubscriptionPermissionUpdate {
+	return c.subscriptionPermissionUpdate.Load()
+}
+
+// GetLeave returns the leave request sent by the server, if any
+func (c *RTCClient) GetLeave() *livekit.LeaveRequest {
+	return c.leave.Load()
+}
+
+// GetLocalParticipantUpdate returns the last update of this participant sent by the server, if any
+func (c *RTCClient) GetLocalParticipantUpdate() *livekit.ParticipantInfo {
+	return c.localUpdate.Load()
+}
+
 func (c *RTCClient) SendPing() error {
 	return c.SendRequest(&livekit.SignalRequest{
 		Message: &livekit.SignalRequest_Ping{
@@ -592,6 +655,34 @@ func (c *RTCClient) hasPrimaryEverConnected() bool {
 }
 
 func (c *RTCClient) AddTrack(track *webrtc.TrackLocalStaticSample, path string) (writer *TrackWriter, err error) {
+	return c.addTrack(track, path, 0)
+}
+
+// AddSyntheticTrack publishes a track fed with generated frames at the given bitrate (bps)
+func (c *RTCClient) AddSyntheticTrack(mime string, id string, label string, bitrate int) (writer *TrackWriter, err error) {
+	track, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: mime}, id, label)
+	if err != nil {
+		return
+	}
+
+	return c.addTrack(track, "", bitrate)
+}
+
+// AddRTPTrack publishes a track to which the caller writes RTP packets directly
+func (c *RTCClient) AddRTPTrack(mime string, id string, label string) (track *webrtc.TrackLocalStaticRTP, err error) {
+	track, err = webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: mime}, id, label)
+	if err != nil {
+		return
+	}
+
+	if _, err = c.addTrack(track, "", 0); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// addTrack publishes the track, tracks with samples get a writer which is started once connected
+func (c *RTCClient) addTrack(track webrtc.TrackLocal, path string, bitrate int) (writer *TrackWriter, err error) {
 	trackType := livekit.TrackType_AUDIO
 	if track.Kind() == webrtc.RTPCodecTypeVideo {
 		trackType = livekit.TrackType_VIDEO
@@ -633,7 +724,12 @@ func (c *RTCClient) AddTrack(track *webrtc.TrackLocalStaticSample, path string)
 	c.localTracks[ti.Sid] = track
 	c.trackSenders[ti.Sid] = sender
 	c.publisher.Negotiate(false)
-	writer = NewTrackWriter(c.ctx, track, path)
+	sampleTrack, ok := track.(*webrtc.TrackLocalStaticSample)
+	if !ok {
+		return
+	}
+	writer = NewTrackWriter(c.ctx, sampleTrack, path)
+	writer.bitrate = bitrate
 
 	// write tracks only after connection established
 	if c.hasPrimaryEverConnected() {
@@ -727,6 +823,10 @@ func (c *RTCClient) LastAnswer() *webrtc.SessionDescription {
 	return c.lastAnswer.Load()
 }
 
+func (c *RTCClient) WaitUntilPublisherConnected() error {
+	return c.ensurePublisherConnected()
+}
+
 func (c *RTCClient) ensurePublisherConnected() error {
 	if c.publisher.HasEverConnected() {
 		return nil
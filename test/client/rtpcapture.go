@@ -0,0 +1,290 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	pcapMagicMicroseconds = 0xa1b2c3d4
+	pcapMagicNanoseconds  = 0xa1b23c4d
+	pcapngMagic           = 0x0a0d0d0a
+
+	linkTypeNull     = 0
+	linkTypeEthernet = 1
+	linkTypeRaw      = 101
+	linkTypeLinuxSLL = 113
+	linkTypeLoop     = 108
+	linkTypeIPv4     = 228
+	linkTypeIPv6     = 229
+	linkTypeSLL2     = 276
+
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86dd
+	etherTypeVLAN = 0x8100
+
+	ipProtocolUDP = 17
+
+	rtpDumpPrefix     = "#!rtpplay1.0"
+	rtpDumpHeaderSize = 16
+)
+
+var (
+	ErrUnsupportedCaptureFormat = errors.New("unsupported capture format, expected pcap or rtpdump")
+	ErrUnsupportedLinkType      = errors.New("unsupported pcap link type")
+)
+
+// CapturedRTPPacket is an RTP packet read from a capture, with its arrival time relative to the first packet
+type CapturedRTPPacket struct {
+	Offset time.Duration
+	Packet *rtp.Packet
+}
+
+// ReadRTPCapture reads RTP packets from a pcap (UDP over IPv4/IPv6) or rtpdump file in capture order.
+// Packets which are not RTP, like RTCP or STUN, are skipped.
+func ReadRTPCapture(path string) ([]*CapturedRTPPacket, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	r := bufio.NewReader(f)
+	magic, err := r.Peek(len(rtpDumpPrefix))
+	if err != nil {
+		return nil, ErrUnsupportedCaptureFormat
+	}
+	if string(magic) == rtpDumpPrefix {
+		return readRTPDump(r)
+	}
+	return readPcap(r)
+}
+
+func readRTPDump(r *bufio.Reader) ([]*CapturedRTPPacket, error) {
+	// text line with source address followed by binary header with start time and source
+	if _, err := r.ReadString('\n'); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(r, make([]byte, rtpDumpHeaderSize)); err != nil {
+		return nil, err
+	}
+
+	var packets []*CapturedRTPPacket
+	hdr := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, hdr); err != nil {
+			if errors.Is(err, io.EOF) {
+				return packets, nil
+			}
+			return nil, err
+		}
+		length := binary.BigEndian.Uint16(hdr[0:2])
+		packetLength := binary.BigEndian.Uint16(hdr[2:4])
+		offset := time.Duration(binary.BigEndian.Uint32(hdr[4:8])) * time.Millisecond
+		if length < 8 {
+			return nil, fmt.Errorf("invalid rtpdump record length: %d", length)
+		}
+
+		data := make([]byte, length-8)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		// packet length is 0 for RTCP
+		if packetLength == 0 {
+			continue
+		}
+
+		if pkt := parseRTP(data); pkt != nil {
+			packets = append(packets, &CapturedRTPPacket{Offset: offset, Packet: pkt})
+		}
+	}
+}
+
+func readPcap(r *bufio.Reader) ([]*CapturedRTPPacket, error) {
+	hdr := make([]byte, 24)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, ErrUnsupportedCaptureFormat
+	}
+
+	var order binary.ByteOrder
+	isNanoseconds := false
+	switch {
+	case binary.LittleEndian.Uint32(hdr) == pcapMagicMicroseconds:
+		order = binary.LittleEndian
+	case binary.BigEndian.Uint32(hdr) == pcapMagicMicroseconds:
+		order = binary.BigEndian
+	case binary.LittleEndian.Uint32(hdr) == pcapMagicNanoseconds:
+		order, isNanoseconds = binary.LittleEndian, true
+	case binary.BigEndian.Uint32(hdr) == pcapMagicNanoseconds:
+		order, isNanoseconds = binary.BigEndian, true
+	case binary.BigEndian.Uint32(hdr) == pcapngMagic:
+		return nil, fmt.Errorf("%w: pcapng is not supported, convert with `editcap -F pcap`", ErrUnsupportedCaptureFormat)
+	default:
+		return nil, ErrUnsupportedCaptureFormat
+	}
+	linkType := order.Uint32(hdr[20:24]) & 0x0fffffff
+
+	var (
+		packets []*CapturedRTPPacket
+		first   time.Time
+	)
+	recordHdr := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(r, recordHdr); err != nil {
+			if errors.Is(err, io.EOF) {
+				return packets, nil
+			}
+			return nil, err
+		}
+		sec := int64(order.Uint32(recordHdr[0:4]))
+		frac := int64(order.Uint32(recordHdr[4:8]))
+		if !isNanoseconds {
+			frac *= 1000
+		}
+		at := time.Unix(sec, frac)
+
+		data := make([]byte, order.Uint32(recordHdr[8:12]))
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+
+		payload, err := udpPayload(linkType, data)
+		if err != nil {
+			return nil, err
+		}
+		pkt := parseRTP(payload)
+		if pkt == nil {
+			continue
+		}
+
+		if len(packets) == 0 {
+			first = at
+		}
+		packets = append(packets, &CapturedRTPPacket{Offset: at.Sub(first), Packet: pkt})
+	}
+}
+
+// udpPayload returns the UDP payload of a captured frame, nil if it is not an unfragmented UDP datagram
+func udpPayload(linkType uint32, frame []byte) ([]byte, error) {
+	var (
+		etherType uint16
+		ip        []byte
+	)
+	switch linkType {
+	case linkTypeEthernet:
+		if len(frame) < 14 {
+			return nil, nil
+		}
+		etherType = binary.BigEndian.Uint16(frame[12:14])
+		ip = frame[14:]
+		if etherType == etherTypeVLAN && len(frame) >= 18 {
+			etherType = binary.BigEndian.Uint16(frame[16:18])
+			ip = frame[18:]
+		}
+	case linkTypeLinuxSLL:
+		if len(frame) < 16 {
+			return nil, nil
+		}
+		etherType = binary.BigEndian.Uint16(frame[14:16])
+		ip = frame[16:]
+	case linkTypeSLL2:
+		if len(frame) < 20 {
+			return nil, nil
+		}
+		etherType = binary.BigEndian.Uint16(frame[0:2])
+		ip = frame[20:]
+	case linkTypeNull, linkTypeLoop:
+		// address family in host byte order, use IP version instead
+		if len(frame) < 4 {
+			return nil, nil
+		}
+		ip = frame[4:]
+	case linkTypeRaw, linkTypeIPv4, linkTypeIPv6:
+		ip = frame
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedLinkType, linkType)
+	}
+
+	if len(ip) == 0 {
+		return nil, nil
+	}
+	if etherType == 0 {
+		switch ip[0] >> 4 {
+		case 4:
+			etherType = etherTypeIPv4
+		case 6:
+			etherType = etherTypeIPv6
+		}
+	}
+
+	var udp []byte
+	switch etherType {
+	case etherTypeIPv4:
+		if len(ip) < 20 || ip[9] != ipProtocolUDP {
+			return nil, nil
+		}
+		// skip fragments
+		if binary.BigEndian.Uint16(ip[6:8])&0x3fff != 0 {
+			return nil, nil
+		}
+		headerLength := int(ip[0]&0x0f) * 4
+		if len(ip) < headerLength {
+			return nil, nil
+		}
+		udp = ip[headerLength:]
+	case etherTypeIPv6:
+		// extension headers are not supported
+		if len(ip) < 40 || ip[6] != ipProtocolUDP {
+			return nil, nil
+		}
+		udp = ip[40:]
+	default:
+		return nil, nil
+	}
+
+	if len(udp) < 8 {
+		return nil, nil
+	}
+	length := int(binary.BigEndian.Uint16(udp[4:6]))
+	if length < 8 || length > len(udp) {
+		return nil, nil
+	}
+	return udp[8:length], nil
+}
+
+// parseRTP returns the packet if data is RTP, nil otherwise
+func parseRTP(data []byte) *rtp.Packet {
+	// RTP version 2, RTCP packet types 192-223 are demultiplexed as in RFC 5761
+	if len(data) < 12 || data[0]>>6 != 2 || (data[1] >= 192 && data[1] <= 223) {
+		return nil
+	}
+
+	pkt := &rtp.Packet{}
+	if err := pkt.Unmarshal(data); err != nil {
+		return nil
+	}
+	return pkt
+}
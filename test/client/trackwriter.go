@@ -30,6 +30,10 @@ import (
 	"github.com/livekit/protocol/logger"
 )
 
+const (
+	syntheticVideoFPS = 30
+)
+
 // Writes a file to an RTP track.
 // makes it easier to debug and create RTP streams
 type TrackWriter struct {
@@ -38,6 +42,7 @@ type TrackWriter struct {
 	track    *webrtc.TrackLocalStaticSample
 	filePath string
 	mime     string
+	bitrate  int
 
 	ogg       *oggreader.OggReader
 	ivfheader *ivfreader.IVFFileHeader
@@ -58,7 +63,11 @@ func NewTrackWriter(ctx context.Context, track *webrtc.TrackLocalStaticSample, f
 
 func (w *TrackWriter) Start() error {
 	if w.filePath == "" {
-		go w.writeNull()
+		if w.bitrate > 0 {
+			go w.writeSynthetic()
+		} else {
+			go w.writeNull()
+		}
 		return nil
 	}
 
@@ -115,6 +124,54 @@ func (w *TrackWriter) writeNull() {
 	}
 }
 
+// writeSynthetic generates frames sized to match the configured bitrate.
+// Payloads carry a moving byte pattern and are not decodable media, but they
+// exercise the forwarding path the same way real frames of that size would.
+func (w *TrackWriter) writeSynthetic() {
+	defer w.onWriteComplete()
+
+	frameDuration := 20 * time.Millisecond
+	keyFrameInterval := 0
+	if w.track.Kind() == webrtc.RTPCodecTypeVideo {
+		frameDuration = time.Second / syntheticVideoFPS
+		keyFrameInterval = syntheticVideoFPS * 2
+	}
+	frameSize := max(w.bitrate*int(frameDuration/time.Millisecond)/8000, 8)
+
+	ticker := time.NewTicker(frameDuration)
+	defer ticker.Stop()
+
+	for frameNum := 0; ; frameNum++ {
+		select {
+		case <-ticker.C:
+			size := frameSize
+			isKeyFrame := keyFrameInterval != 0 && frameNum%keyFrameInterval == 0
+			if isKeyFrame {
+				size *= 4
+			}
+			data := make([]byte, size)
+			for i := range data {
+				data[i] = byte(frameNum + i)
+			}
+			switch {
+			case strings.EqualFold(w.mime, webrtc.MimeTypeVP8):
+				// VP8 frame tag, P bit is 0 for key frames
+				if isKeyFrame {
+					data[0] = 0x0
+				} else {
+					data[0] = 0x1
+				}
+			case strings.EqualFold(w.mime, webrtc.MimeTypeOpus):
+				// TOC byte: CELT-only fullband 20ms, mono, one frame
+				data[0] = 0xf8
+			}
+			_ = w.track.WriteSample(media.Sample{Data: data, Duration: frameDuration})
+		case <-w.ctx.Done():
+			return
+		}
+	}
+}
+
 func (w *TrackWriter) writeOgg() {
 	// Keep track of last granule, the difference is the amount of samples in the buffer
 	var lastGranule uint64
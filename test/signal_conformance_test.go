@@ -0,0 +1,228 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/testutils"
+	testclient "github.com/livekit/livekit-server/test/client"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files with the recorded signal messages")
+
+const (
+	signalConformanceGolden = "testdata/signal_conformance.golden"
+	// time given to the server to finish sending the messages of a step once its expected state is reached
+	signalSettleDelay = 500 * time.Millisecond
+)
+
+// signalRecorder records the signal responses received by clients between steps of a scenario.
+// The server sends messages from independent queues, so messages within a step are compared as a set.
+type signalRecorder struct {
+	lock     sync.Mutex
+	messages map[string][]string
+	steps    []string
+}
+
+func newSignalRecorder() *signalRecorder {
+	return &signalRecorder{
+		messages: make(map[string][]string),
+	}
+}
+
+func (r *signalRecorder) interceptor(identity string) testclient.SignalResponseInterceptor {
+	return func(msg *livekit.SignalResponse, next testclient.SignalResponseHandler) error {
+		if line := formatSignalResponse(msg); line != "" {
+			r.lock.Lock()
+			r.messages[identity] = append(r.messages[identity], line)
+			r.lock.Unlock()
+		}
+		return next(msg)
+	}
+}
+
+// step closes the current step, recording the distinct messages each client received during it
+func (r *signalRecorder) step(name string) {
+	time.Sleep(signalSettleDelay)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.steps = append(r.steps, "== "+name)
+	identities := make([]string, 0, len(r.messages))
+	for identity := range r.messages {
+		identities = append(identities, identity)
+	}
+	slices.Sort(identities)
+	for _, identity := range identities {
+		lines := slices.Clone(r.messages[identity])
+		slices.Sort(lines)
+		for _, line := range slices.Compact(lines) {
+			r.steps = append(r.steps, identity+": "+line)
+		}
+	}
+	clear(r.messages)
+}
+
+func (r *signalRecorder) String() string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return strings.Join(r.steps, "\n") + "\n"
+}
+
+// formatSignalResponse renders the parts of a signal response that are stable across runs,
+// messages that depend on timing or media (trickle, speakers, quality...) are left out
+func formatSignalResponse(res *livekit.SignalResponse) string {
+	switch msg := res.Message.(type) {
+	case *livekit.SignalResponse_Join:
+		others := make([]string, 0, len(msg.Join.OtherParticipants))
+		for _, p := range msg.Join.OtherParticipants {
+			others = append(others, formatParticipant(p))
+		}
+		slices.Sort(others)
+		return fmt.Sprintf("join %s others=[%s]", msg.Join.Participant.Identity, strings.Join(others, " "))
+	case *livekit.SignalResponse_Reconnect:
+		return "reconnect"
+	case *livekit.SignalResponse_Offer:
+		return "offer"
+	case *livekit.SignalResponse_Answer:
+		return "answer"
+	case *livekit.SignalResponse_Update:
+		participants := make([]string, 0, len(msg.Update.Participants))
+		for _, p := range msg.Update.Participants {
+			participants = append(participants, formatParticipant(p))
+		}
+		slices.Sort(participants)
+		return fmt.Sprintf("update [%s]", strings.Join(participants, " "))
+	case *livekit.SignalResponse_TrackPublished:
+		return fmt.Sprintf("track_published %s %s", msg.TrackPublished.Track.Type, msg.TrackPublished.Track.Name)
+	case *livekit.SignalResponse_TrackUnpublished:
+		return "track_unpublished"
+	case *livekit.SignalResponse_Leave:
+		return fmt.Sprintf("leave %s %s", msg.Leave.Action, msg.Leave.Reason)
+	case *livekit.SignalResponse_Mute:
+		return fmt.Sprintf("mute %t", msg.Mute.Muted)
+	case *livekit.SignalResponse_SubscriptionResponse:
+		return fmt.Sprintf("subscription_response %s", msg.SubscriptionResponse.Err)
+	case *livekit.SignalResponse_RequestResponse:
+		return fmt.Sprintf("request_response %s", msg.RequestResponse.Reason)
+	default:
+		return ""
+	}
+}
+
+func formatParticipant(p *livekit.ParticipantInfo) string {
+	tracks := make([]string, 0, len(p.Tracks))
+	for _, t := range p.Tracks {
+		tracks = append(tracks, t.Name)
+	}
+	slices.Sort(tracks)
+	return fmt.Sprintf("%s(%s)", p.Identity, strings.Join(tracks, ","))
+}
+
+// TestSignalConformance drives a session through the signal protocol state machine and compares the messages
+// clients receive at each step to testdata/signal_conformance.golden, run with -update to accept changes
+func TestSignalConformance(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+		return
+	}
+
+	_, finish := setupSingleNodeTest("TestSignalConformance")
+	defer finish()
+
+	rec := newSignalRecorder()
+	connect := func(identity string) *testclient.RTCClient {
+		return createRTCClient(identity, defaultServerPort, &testclient.Options{
+			AutoSubscribe:             true,
+			SignalResponseInterceptor: rec.interceptor(identity),
+		})
+	}
+
+	alice := connect("alice")
+	waitUntilConnected(t, alice)
+	rec.step("alice joins")
+
+	bob := connect("bob")
+	defer bob.Stop()
+	waitUntilConnected(t, bob)
+	testutils.WithTimeout(t, func() string {
+		if len(alice.RemoteParticipants()) != 1 {
+			return "alice did not see bob"
+		}
+		return ""
+	})
+	rec.step("bob joins")
+
+	audio, err := alice.AddStaticTrack("audio/opus", "audio", "mic")
+	require.NoError(t, err)
+	defer audio.Stop()
+	waitForSubscribedTracks(t, bob, alice, 1)
+	rec.step("alice publishes audio")
+
+	video, err := alice.AddStaticTrack("video/vp8", "video", "camera")
+	require.NoError(t, err)
+	defer video.Stop()
+	waitForSubscribedTracks(t, bob, alice, 2)
+	rec.step("alice publishes video, renegotiating")
+
+	err = bob.Resume(fmt.Sprintf("ws://localhost:%d", defaultServerPort), joinToken(testRoom, "bob", nil), &testclient.Options{
+		AutoSubscribe: true,
+	})
+	require.NoError(t, err)
+	rec.step("bob resumes")
+
+	alice.Stop()
+	testutils.WithTimeout(t, func() string {
+		if len(bob.RemoteParticipants()) != 0 {
+			return "bob still sees alice"
+		}
+		return ""
+	})
+	rec.step("alice leaves")
+
+	recorded := rec.String()
+	if *updateGolden {
+		require.NoError(t, os.MkdirAll(filepath.Dir(signalConformanceGolden), 0755))
+		require.NoError(t, os.WriteFile(signalConformanceGolden, []byte(recorded), 0644))
+		return
+	}
+
+	golden, err := os.ReadFile(signalConformanceGolden)
+	require.NoError(t, err, "golden file missing, run with -update to record it")
+	require.Equal(t, string(golden), recorded, "signal messages changed, run with -update if the change is intended")
+}
+
+func waitForSubscribedTracks(t *testing.T, subscriber *testclient.RTCClient, publisher *testclient.RTCClient, count int) {
+	testutils.WithTimeout(t, func() string {
+		if n := len(subscriber.SubscribedTracks()[publisher.ID()]); n != count {
+			return fmt.Sprintf("subscribed to %d tracks, expected %d", n, count)
+		}
+		return ""
+	})
+}
@@ -16,6 +16,7 @@ package test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
@@ -26,6 +27,8 @@ import (
 	"github.com/pion/webrtc/v3"
 	"github.com/stretchr/testify/require"
 	"github.com/thoas/go-funk"
+	"github.com/twitchtv/twirp"
+	"google.golang.org/protobuf/encoding/protojson"
 
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
@@ -365,6 +368,41 @@ func TestPingPong(t *testing.T) {
 	}, time.Second, 10*time.Millisecond)
 }
 
+func TestSingleNodeRemoveParticipantReason(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+		return
+	}
+
+	_, finish := setupSingleNodeTest("TestSingleNodeRemoveParticipantReason")
+	defer finish()
+
+	c1 := createRTCClient("remove_reason", defaultServerPort, nil)
+	defer c1.Stop()
+	waitUntilConnected(t, c1)
+
+	header := http.Header{}
+	testclient.SetAuthorizationToken(header, adminRoomToken(testRoom))
+	header.Set("X-LiveKit-Remove-Reason", `{"reason": "UNKNOWN"}`)
+	ctx, err := twirp.WithHTTPRequestHeaders(context.Background(), header)
+	require.NoError(t, err)
+	_, err = roomClient.RemoveParticipant(ctx, &livekit.RoomParticipantIdentity{Room: testRoom, Identity: "remove_reason"})
+	var twErr twirp.Error
+	require.ErrorAs(t, err, &twErr)
+	require.Equal(t, twirp.InvalidArgument, twErr.Code())
+
+	header.Set("X-LiveKit-Remove-Reason", `{"reason": "DUPLICATE_IDENTITY", "message": "signed in on another device"}`)
+	ctx, err = twirp.WithHTTPRequestHeaders(context.Background(), header)
+	require.NoError(t, err)
+	_, err = roomClient.RemoveParticipant(ctx, &livekit.RoomParticipantIdentity{Room: testRoom, Identity: "remove_reason"})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return c1.GetLeave() != nil
+	}, waitTimeout, waitTick)
+	require.Equal(t, livekit.DisconnectReason_DUPLICATE_IDENTITY, c1.GetLeave().Reason)
+}
+
 func TestSingleNodeJoinAfterClose(t *testing.T) {
 	if testing.Short() {
 		t.SkipNow()
@@ -498,6 +536,110 @@ func TestSingleNodeUpdateSubscriptionPermissions(t *testing.T) {
 	})
 }
 
+func TestSingleNodeAPISubscriptionPermission(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+		return
+	}
+	_, finish := setupSingleNodeTest("TestSingleNodeAPISubscriptionPermission")
+	defer finish()
+
+	pub := createRTCClient("pub", defaultServerPort, nil)
+	allowed := createRTCClient("allowed", defaultServerPort, nil)
+	denied := createRTCClient("denied", defaultServerPort, nil)
+	defer pub.Stop()
+	defer allowed.Stop()
+	defer denied.Stop()
+	waitUntilConnected(t, pub, allowed, denied)
+
+	writers := publishTracksForClients(t, pub)
+	defer stopWriters(writers...)
+
+	testutils.WithTimeout(t, func() string {
+		if len(denied.SubscribedTracks()[pub.ID()]) != 2 {
+			return "denied did not subscribe to the published tracks"
+		}
+		return ""
+	})
+	pubRemote := denied.GetRemoteParticipant(pub.ID())
+	require.NotNil(t, pubRemote)
+	trackSid := pubRemote.Tracks[0].Sid
+
+	do := func(method string, body string) (*http.Response, error) {
+		url := fmt.Sprintf("http://localhost:%d/room/subscription_permission?room=%s&identity=pub", defaultServerPort, testRoom)
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		testclient.SetAuthorizationToken(req.Header, adminRoomToken(testRoom))
+		return http.DefaultClient.Do(req)
+	}
+
+	res, err := do(http.MethodPost, fmt.Sprintf(`{"trackPermissions": [{"participantIdentity": "allowed", "trackSids": [%q]}]}`, trackSid))
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	testutils.WithTimeout(t, func() string {
+		update := denied.GetSubscriptionPermissionUpdate()
+		if update == nil || update.Allowed {
+			return "denied was not notified of the revoked permission"
+		}
+		return ""
+	})
+	// allowed keeps the track it was given permission to
+	if update := allowed.GetSubscriptionPermissionUpdate(); update != nil && update.TrackSid == trackSid {
+		require.True(t, update.Allowed)
+	}
+
+	// the publisher cannot lift the restrictions set through the API
+	require.NoError(t, pub.SendRequest(&livekit.SignalRequest{
+		Message: &livekit.SignalRequest_SubscriptionPermission{
+			SubscriptionPermission: &livekit.SubscriptionPermission{AllParticipants: true},
+		},
+	}))
+	var permissions struct {
+		Publisher json.RawMessage `json:"publisher"`
+		Admin     json.RawMessage `json:"admin"`
+	}
+	testutils.WithTimeout(t, func() string {
+		res, err := do(http.MethodGet, "")
+		if err != nil {
+			return err.Error()
+		}
+		defer res.Body.Close()
+		if err := json.NewDecoder(res.Body).Decode(&permissions); err != nil {
+			return err.Error()
+		}
+		if len(permissions.Publisher) == 0 || string(permissions.Publisher) == "null" {
+			return "publisher permission not updated"
+		}
+		return ""
+	})
+	admin := &livekit.SubscriptionPermission{}
+	require.NoError(t, protojson.Unmarshal(permissions.Admin, admin))
+	require.False(t, admin.AllParticipants)
+	require.Len(t, admin.TrackPermissions, 1)
+	require.Equal(t, "allowed", admin.TrackPermissions[0].ParticipantIdentity)
+
+	time.Sleep(500 * time.Millisecond)
+	update := denied.GetSubscriptionPermissionUpdate()
+	require.Equal(t, trackSid, update.TrackSid)
+	require.False(t, update.Allowed)
+
+	res, err = do(http.MethodPost, `{"allParticipants": true}`)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	testutils.WithTimeout(t, func() string {
+		if update := denied.GetSubscriptionPermissionUpdate(); !update.Allowed {
+			return "denied was not notified of the granted permission"
+		}
+		return ""
+	})
+}
+
 // TestDeviceCodecOverride checks that codecs that are incompatible with a device is not
 // negotiated by the server
 func TestDeviceCodecOverride(t *testing.T) {